@@ -5,17 +5,54 @@ import (
 	"GURLS-Bot/internal/config"
 	"GURLS-Bot/internal/grpc/client"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	lg "log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
+// exitTokenRevoked is the exit code once Telegram rejected the bot token,
+// telling the orchestrator a restart with a new secret is needed.
+const exitTokenRevoked = 3
+
 func main() {
+	if err := run(); err != nil {
+		lg.Printf("ERROR: %v", err)
+		if errors.Is(err, bot.ErrTokenRevoked) {
+			os.Exit(exitTokenRevoked)
+		}
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	dryRun := flag.Bool("dry-run", false, "read messages from stdin and print replies instead of using Telegram (same as ENV=repl)")
+	validate := flag.Bool("validate-config", false, "check the configuration and exit without contacting Telegram")
+	dial := flag.Bool("dial", false, "with -validate-config, also connect to the backend addresses")
+	flag.Parse()
+	if *validate {
+		return validateConfig(os.Stdout, *dial)
+	}
+	if os.Getenv("ENV") == "repl" {
+		*dryRun = true
+	}
+	if *dryRun && os.Getenv("TELEGRAM_TOKEN") == "" {
+		// The token is required by the config but unused without Telegram
+		os.Setenv("TELEGRAM_TOKEN", "dry-run")
+	}
+
 	cfg := config.MustLoad()
-	
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	// Initialize logger
 	var log *zap.Logger
 	var err error
@@ -25,7 +62,7 @@ func main() {
 		log, err = zap.NewDevelopment()
 	}
 	if err != nil {
-		lg.Fatalf("failed to create logger: %v", err)
+		return fmt.Errorf("failed to create logger: %w", err)
 	}
 	defer func() {
 		if err := log.Sync(); err != nil {
@@ -39,31 +76,97 @@ func main() {
 	backendClient, err := client.NewBackendClient(
 		cfg.GRPCClient.BackendAddress,
 		cfg.GRPCClient.Timeout,
+		cfg.GRPCClient.HealthTimeout,
 		log,
 	)
 	if err != nil {
-		log.Fatal("failed to connect to backend", zap.Error(err))
+		log.Error("failed to connect to backend", zap.Error(err))
+		return err
 	}
 	defer backendClient.Close()
+	backendClient.LogSlowCalls(cfg.GRPCClient.SlowCallThreshold, log.Named("slow_calls"))
+
+	if health, err := backendClient.HealthCheck(context.Background()); err != nil {
+		log.Warn("backend health check failed", zap.Error(err))
+	} else {
+		log.Info("connected to backend",
+			zap.String("address", cfg.GRPCClient.BackendAddress),
+			zap.Bool("serving", health.Serving),
+			zap.Stringer("state", health.State),
+			zap.Bool("connection_only", health.ConnectionOnly),
+			zap.Duration("latency", health.Latency),
+		)
+	}
 
 	// Initialize Telegram bot
-	telegramBot, err := bot.New(cfg, log, backendClient)
+	var telegramBot *bot.Bot
+	if *dryRun {
+		log.Info("dry-run mode: reading messages from stdin")
+		telegramBot, err = bot.NewDryRun(cfg, log, backendClient, os.Stdin, os.Stdout)
+	} else {
+		telegramBot, err = bot.New(cfg, log, backendClient)
+	}
 	if err != nil {
-		log.Fatal("failed to initialize bot", zap.Error(err))
+		log.Error("failed to initialize bot", zap.Error(err))
+		return err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// A shutdown signal or a failing component stops all components
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		// The bot stopping on its own, e.g. at the end of dry-run input,
+		// stops everything else
+		defer stop()
+		return telegramBot.Run(ctx)
+	})
 
-	// Start bot
-	telegramBot.Start(ctx)
+	// Reload runtime configuration on SIGHUP
+	g.Go(func() error {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-hup:
+				telegramBot.Reload()
+				if cfg.Telegram.OnRevoked == config.OnRevokedWait {
+					reloadToken(telegramBot, log)
+				}
+			}
+		}
+	})
 
-	// Wait for shutdown signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 	log.Info("shutting down GURLS-Bot...")
 
-	cancel()
+	done := make(chan error, 1)
+	go func() { done <- g.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Error("bot stopped with error", zap.Error(err))
+			return err
+		}
+	case <-time.After(cfg.ShutdownTimeout):
+		log.Error("shutdown timed out", zap.Duration("timeout", cfg.ShutdownTimeout))
+		return errors.New("shutdown timed out")
+	}
 	log.Info("bot stopped")
-}
\ No newline at end of file
+	return nil
+}
+
+// reloadToken reads the configuration again and hands its token to the bot,
+// for deployments that swap secrets in place of restarting.
+func reloadToken(telegramBot *bot.Bot, log *zap.Logger) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Error("failed to reload configuration", zap.Error(err))
+		return
+	}
+	telegramBot.SetToken(cfg.Telegram.Token)
+}
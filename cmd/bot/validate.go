@@ -0,0 +1,140 @@
+package main
+
+import (
+	"GURLS-Bot/internal/bot"
+	"GURLS-Bot/internal/config"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// validateConfig loads and checks the configuration without contacting
+// Telegram and writes a report to out. Backend hosts are resolved; with dial
+// set each backend address must also accept a TCP connection.
+func validateConfig(out io.Writer, dial bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(out, "FAIL %v\n", err)
+		return err
+	}
+
+	var problems []error
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, unjoin(err)...)
+	}
+	// Validate already reports a template file that can't be read
+	if _, err := os.Stat(cfg.Messages.TemplateFile); cfg.Messages.TemplateFile != "" && err == nil {
+		if err := bot.CheckMessageTemplates(cfg.Messages.TemplateFile); err != nil {
+			problems = append(problems, fmt.Errorf("messages.template_file: %w", err))
+		}
+	}
+	if addrs, err := config.BackendAddresses(cfg.GRPCClient.BackendAddress); err == nil {
+		problems = append(problems, checkBackend(addrs, cfg, dial)...)
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(out, "FAIL %v\n", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d configuration problem(s)", len(problems))
+	}
+	fmt.Fprintln(out, "OK configuration is valid")
+	return nil
+}
+
+// checkBackend resolves and optionally dials every backend address.
+func checkBackend(addrs []string, cfg *config.Config, dial bool) []error {
+	var errs []error
+	for _, addr := range addrs {
+		if err := checkBackendAddress(addr, cfg.GRPCClient.Timeout, dial); err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", addr, err))
+		}
+	}
+	return errs
+}
+
+// checkBackendAddress checks addr the way gRPC connects to it: host:port
+// pairs and dns and passthrough targets are resolved and dialed over TCP,
+// dns targets naming a DNS server are resolved through it, and unix targets
+// are dialed as sockets.
+func checkBackendAddress(addr string, timeout time.Duration, dial bool) error {
+	resolver := net.DefaultResolver
+	if scheme, rest, ok := strings.Cut(addr, "://"); ok {
+		authority, endpoint, _ := strings.Cut(rest, "/")
+		switch scheme {
+		case "dns":
+			if authority != "" {
+				resolver = dnsServer(authority)
+			}
+			addr = endpoint
+		case "passthrough":
+			addr = endpoint
+		case "unix":
+			return checkUnixSocket(rest, timeout, dial)
+		default:
+			return fmt.Errorf("unsupported target scheme %q", scheme)
+		}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ips, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return err
+	}
+	if !dial {
+		return nil
+	}
+	// The address the system resolver would find may differ from the one of
+	// a named DNS server, so the resolved one is dialed
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ips[0], port), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dnsServer is a resolver asking the DNS server at authority, port 53
+// unless given.
+func dnsServer(authority string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(authority); err != nil {
+		authority = net.JoinHostPort(authority, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, authority)
+		},
+	}
+}
+
+// checkUnixSocket checks that the socket at path exists and, with dial set,
+// accepts a connection.
+func checkUnixSocket(path string, timeout time.Duration, dial bool) error {
+	if !dial {
+		_, err := os.Stat(path)
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// unjoin splits an error made by errors.Join into its parts.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
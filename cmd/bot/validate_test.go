@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// listen returns the address of a TCP listener accepting backend dials.
+func listen(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+// refused returns a TCP address nothing listens on.
+func refused(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestValidateConfig(t *testing.T) {
+	up, down := listen(t), refused(t)
+	_, upPort, _ := net.SplitHostPort(up)
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		dial bool
+		// want are the FAIL lines expected, none for a valid configuration
+		want []string
+	}{
+		{name: "valid", env: map[string]string{"GRPC_BACKEND_ADDRESS": up}, dial: true},
+		{name: "valid dns target", env: map[string]string{"GRPC_BACKEND_ADDRESS": "dns:///localhost:" + upPort}, dial: true},
+		{name: "valid passthrough target", env: map[string]string{"GRPC_BACKEND_ADDRESS": "passthrough:///" + up}, dial: true},
+		{name: "valid replicas", env: map[string]string{"GRPC_BACKEND_ADDRESS": up + "," + up}, dial: true},
+		{name: "refused not dialed", env: map[string]string{"GRPC_BACKEND_ADDRESS": down}},
+		{
+			name: "empty token",
+			env:  map[string]string{"TELEGRAM_TOKEN": " "},
+			want: []string{"telegram.token is empty"},
+		},
+		{
+			name: "several problems",
+			env:  map[string]string{"TELEGRAM_TOKEN": " ", "QUEUE_OFFER_TTL": "0s", "SHUTDOWN_TIMEOUT": "-1s"},
+			want: []string{"telegram.token is empty", "shutdown_timeout must be positive", "queue.offer_ttl must be positive"},
+		},
+		{
+			name: "address without port",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "backend"},
+			want: []string{"grpc_client.backend_address"},
+		},
+		{
+			name: "unresolvable host",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "backend.invalid:50051"},
+			want: []string{"backend backend.invalid:50051"},
+		},
+		{
+			name: "refused",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": down},
+			dial: true,
+			want: []string{"backend " + down + ": dial tcp"},
+		},
+		{
+			name: "refused replica",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": up + "," + down},
+			dial: true,
+			want: []string{"backend " + down},
+		},
+		{
+			name: "refused dns target",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "dns:///" + down},
+			dial: true,
+			want: []string{"backend " + down + ": dial tcp"},
+		},
+		{
+			name: "unresolvable dns target",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "dns:///backend.invalid:50051"},
+			want: []string{"backend backend.invalid:50051"},
+		},
+		{
+			name: "unreachable dns server",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "dns://" + down + "/backend.test:50051"},
+			want: []string{"backend dns://" + down + "/backend.test:50051"},
+		},
+		{
+			name: "refused passthrough target",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "passthrough:///" + down},
+			dial: true,
+			want: []string{"backend passthrough:///" + down + ": dial tcp"},
+		},
+		{
+			name: "missing unix socket",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "unix:///nonexistent/backend.sock"},
+			want: []string{"backend unix:///nonexistent/backend.sock"},
+		},
+		{
+			name: "unknown scheme",
+			env:  map[string]string{"GRPC_BACKEND_ADDRESS": "xds:///backend"},
+			want: []string{`unsupported target scheme "xds"`},
+		},
+		{name: "valid features", env: map[string]string{"FEATURES": "inline:false,safe_browsing:false"}},
+		{
+			name: "unknown feature",
+			env:  map[string]string{"FEATURES": "inline:false,teleport:true"},
+			want: []string{`features: unknown flag "teleport"`},
+		},
+		{
+			name: "missing template file",
+			env:  map[string]string{"MESSAGES_TEMPLATE_FILE": "missing.tmpl"},
+			want: []string{"messages.template_file"},
+		},
+		{
+			name: "broken template file",
+			env:  map[string]string{"MESSAGES_TEMPLATE_FILE": "broken.tmpl"},
+			want: []string{"messages.template_file"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile("broken.tmpl", []byte(`{{define "welcome"}}{{.Missing`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "none.yml"))
+			t.Setenv("TELEGRAM_TOKEN", "test")
+			t.Setenv("GRPC_BACKEND_ADDRESS", up)
+			t.Setenv("GRPC_CLIENT_TIMEOUT", "1s")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var out bytes.Buffer
+			err := validateConfig(&out, tt.dial)
+			var fails []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if fail, ok := strings.CutPrefix(line, "FAIL "); ok {
+					fails = append(fails, fail)
+				}
+			}
+			if (err != nil) != (len(tt.want) > 0) {
+				t.Errorf("validateConfig = %v, report:\n%s", err, out.String())
+			}
+			if len(fails) != len(tt.want) {
+				t.Fatalf("got %d problems, want %d; report:\n%s", len(fails), len(tt.want), out.String())
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(fails[i], want) {
+					t.Errorf("problem %d = %q, want %q", i+1, fails[i], want)
+				}
+			}
+		})
+	}
+}
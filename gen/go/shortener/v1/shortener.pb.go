@@ -24,12 +24,23 @@ const (
 )
 
 type CreateLinkRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalUrl   string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
-	UserTgId      int64                  `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
-	Title         *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
-	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
-	CustomAlias   *string                `protobuf:"bytes,5,opt,name=custom_alias,json=customAlias,proto3,oneof" json:"custom_alias,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	UserTgId    int64                  `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	Title       *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	ExpiresAt   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
+	CustomAlias *string                `protobuf:"bytes,5,opt,name=custom_alias,json=customAlias,proto3,oneof" json:"custom_alias,omitempty"`
+	// Host of the short domain to create the link on; the backend default is used when unset.
+	Domain *string `protobuf:"bytes,6,opt,name=domain,proto3,oneof" json:"domain,omitempty"`
+	// Where the link was created, e.g. "bot_message", "bot_inline", "bot_import" or "web".
+	Source *string `protobuf:"bytes,7,opt,name=source,proto3,oneof" json:"source,omitempty"`
+	// Record only the click count, without per-device or per-country breakdowns.
+	MinimalAnalytics *bool `protobuf:"varint,8,opt,name=minimal_analytics,json=minimalAnalytics,proto3,oneof" json:"minimal_analytics,omitempty"`
+	// When the link starts redirecting; it exists but doesn't redirect before.
+	NotBefore *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=not_before,json=notBefore,proto3,oneof" json:"not_before,omitempty"`
+	// Style of the generated alias: "random", "words" (e.g. blue-fast-otter)
+	// or "numeric". Ignored with custom_alias; the backend's own when unset.
+	AliasStyle    *string `protobuf:"bytes,10,opt,name=alias_style,json=aliasStyle,proto3,oneof" json:"alias_style,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -99,9 +110,50 @@ func (x *CreateLinkRequest) GetCustomAlias() string {
 	return ""
 }
 
+func (x *CreateLinkRequest) GetDomain() string {
+	if x != nil && x.Domain != nil {
+		return *x.Domain
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetSource() string {
+	if x != nil && x.Source != nil {
+		return *x.Source
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetMinimalAnalytics() bool {
+	if x != nil && x.MinimalAnalytics != nil {
+		return *x.MinimalAnalytics
+	}
+	return false
+}
+
+func (x *CreateLinkRequest) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *CreateLinkRequest) GetAliasStyle() string {
+	if x != nil && x.AliasStyle != nil {
+		return *x.AliasStyle
+	}
+	return ""
+}
+
 type CreateLinkResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Alias string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	// Echoes the activation time the link was scheduled for. Backends that
+	// don't schedule links leave it unset, and the link is active right away.
+	NotBefore *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=not_before,json=notBefore,proto3,oneof" json:"not_before,omitempty"`
+	// Echoes the style the alias was generated in. Backends without styles
+	// leave it unset.
+	AliasStyle    *string `protobuf:"bytes,3,opt,name=alias_style,json=aliasStyle,proto3,oneof" json:"alias_style,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -143,9 +195,28 @@ func (x *CreateLinkResponse) GetAlias() string {
 	return ""
 }
 
+func (x *CreateLinkResponse) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *CreateLinkResponse) GetAliasStyle() string {
+	if x != nil && x.AliasStyle != nil {
+		return *x.AliasStyle
+	}
+	return ""
+}
+
 type GetLinkStatsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Alias string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	// Limits the counts to clicks at or after from and before to. Backends
+	// without ranges ignore them and count all clicks, leaving range_applied
+	// unset.
+	From          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3,oneof" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3,oneof" json:"to,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -187,6 +258,20 @@ func (x *GetLinkStatsRequest) GetAlias() string {
 	return ""
 }
 
+func (x *GetLinkStatsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *GetLinkStatsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
 type GetLinkStatsResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	OriginalUrl    string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
@@ -194,8 +279,23 @@ type GetLinkStatsResponse struct {
 	Title          *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
 	ExpiresAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
 	ClicksByDevice map[string]int64       `protobuf:"bytes,5,rep,name=clicks_by_device,json=clicksByDevice,proto3" json:"clicks_by_device,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	Domain         *string                `protobuf:"bytes,6,opt,name=domain,proto3,oneof" json:"domain,omitempty"`
+	Source         *string                `protobuf:"bytes,7,opt,name=source,proto3,oneof" json:"source,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3,oneof" json:"created_at,omitempty"`
+	// Whether only the click count is recorded for the link.
+	MinimalAnalytics *bool `protobuf:"varint,9,opt,name=minimal_analytics,json=minimalAnalytics,proto3,oneof" json:"minimal_analytics,omitempty"`
+	// The owner of the link. Only returned to calls made on behalf of an
+	// admin, which carry the x-admin-tg-id metadata.
+	OwnerTgId *int64 `protobuf:"varint,10,opt,name=owner_tg_id,json=ownerTgId,proto3,oneof" json:"owner_tg_id,omitempty"`
+	// Whether the counts are limited to the range requested.
+	RangeApplied *bool `protobuf:"varint,11,opt,name=range_applied,json=rangeApplied,proto3,oneof" json:"range_applied,omitempty"`
+	// When the link starts redirecting, for links scheduled to.
+	NotBefore *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=not_before,json=notBefore,proto3,oneof" json:"not_before,omitempty"`
+	// When the link was last clicked; unset for links never clicked and by
+	// backends that don't keep it.
+	LastClickAt   *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=last_click_at,json=lastClickAt,proto3,oneof" json:"last_click_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetLinkStatsResponse) Reset() {
@@ -263,6 +363,62 @@ func (x *GetLinkStatsResponse) GetClicksByDevice() map[string]int64 {
 	return nil
 }
 
+func (x *GetLinkStatsResponse) GetDomain() string {
+	if x != nil && x.Domain != nil {
+		return *x.Domain
+	}
+	return ""
+}
+
+func (x *GetLinkStatsResponse) GetSource() string {
+	if x != nil && x.Source != nil {
+		return *x.Source
+	}
+	return ""
+}
+
+func (x *GetLinkStatsResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetMinimalAnalytics() bool {
+	if x != nil && x.MinimalAnalytics != nil {
+		return *x.MinimalAnalytics
+	}
+	return false
+}
+
+func (x *GetLinkStatsResponse) GetOwnerTgId() int64 {
+	if x != nil && x.OwnerTgId != nil {
+		return *x.OwnerTgId
+	}
+	return 0
+}
+
+func (x *GetLinkStatsResponse) GetRangeApplied() bool {
+	if x != nil && x.RangeApplied != nil {
+		return *x.RangeApplied
+	}
+	return false
+}
+
+func (x *GetLinkStatsResponse) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
+func (x *GetLinkStatsResponse) GetLastClickAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastClickAt
+	}
+	return nil
+}
+
 type DeleteLinkRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
@@ -308,8 +464,13 @@ func (x *DeleteLinkRequest) GetAlias() string {
 }
 
 type ListUserLinksRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserTgId      int64                  `protobuf:"varint,1,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserTgId int64                  `protobuf:"varint,1,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	// page_size caps the links returned; 0 returns all of them. Backends
+	// without pagination ignore it and return all links.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token is the next_page_token of the previous page.
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -351,11 +512,30 @@ func (x *ListUserLinksRequest) GetUserTgId() int64 {
 	return 0
 }
 
+func (x *ListUserLinksRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListUserLinksRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
 type LinkInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
-	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
-	Title         *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Alias            string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	OriginalUrl      string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Title            *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Domain           *string                `protobuf:"bytes,4,opt,name=domain,proto3,oneof" json:"domain,omitempty"`
+	Source           *string                `protobuf:"bytes,5,opt,name=source,proto3,oneof" json:"source,omitempty"`
+	MinimalAnalytics *bool                  `protobuf:"varint,6,opt,name=minimal_analytics,json=minimalAnalytics,proto3,oneof" json:"minimal_analytics,omitempty"`
+	// Set for links scheduled to start redirecting later.
+	NotBefore     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=not_before,json=notBefore,proto3,oneof" json:"not_before,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -411,9 +591,39 @@ func (x *LinkInfo) GetTitle() string {
 	return ""
 }
 
+func (x *LinkInfo) GetDomain() string {
+	if x != nil && x.Domain != nil {
+		return *x.Domain
+	}
+	return ""
+}
+
+func (x *LinkInfo) GetSource() string {
+	if x != nil && x.Source != nil {
+		return *x.Source
+	}
+	return ""
+}
+
+func (x *LinkInfo) GetMinimalAnalytics() bool {
+	if x != nil && x.MinimalAnalytics != nil {
+		return *x.MinimalAnalytics
+	}
+	return false
+}
+
+func (x *LinkInfo) GetNotBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NotBefore
+	}
+	return nil
+}
+
 type ListUserLinksResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Links         []*LinkInfo            `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Links []*LinkInfo            `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	// next_page_token fetches the page after this one; empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -455,6 +665,13 @@ func (x *ListUserLinksResponse) GetLinks() []*LinkInfo {
 	return nil
 }
 
+func (x *ListUserLinksResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 type RecordClickRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
@@ -507,63 +724,1362 @@ func (x *RecordClickRequest) GetDeviceType() string {
 	return ""
 }
 
-var File_v1_shortener_proto protoreflect.FileDescriptor
+type ResolveLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_v1_shortener_proto_rawDesc = "" +
-	"\n" +
-	"\x12v1/shortener.proto\x12\fshortener.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x81\x02\n" +
-	"\x11CreateLinkRequest\x12!\n" +
-	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12\x1c\n" +
-	"\n" +
-	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x12\x19\n" +
-	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01\x12>\n" +
-	"\n" +
-	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\texpiresAt\x88\x01\x01\x12&\n" +
-	"\fcustom_alias\x18\x05 \x01(\tH\x02R\vcustomAlias\x88\x01\x01B\b\n" +
-	"\x06_titleB\r\n" +
-	"\v_expires_atB\x0f\n" +
-	"\r_custom_alias\"*\n" +
-	"\x12CreateLinkResponse\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias\"+\n" +
-	"\x13GetLinkStatsRequest\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias\"\xf3\x02\n" +
-	"\x14GetLinkStatsResponse\x12!\n" +
-	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12\x1f\n" +
-	"\vclick_count\x18\x02 \x01(\x03R\n" +
-	"clickCount\x12\x19\n" +
-	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01\x12>\n" +
-	"\n" +
-	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\texpiresAt\x88\x01\x01\x12`\n" +
-	"\x10clicks_by_device\x18\x05 \x03(\v26.shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntryR\x0eclicksByDevice\x1aA\n" +
-	"\x13ClicksByDeviceEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01B\b\n" +
-	"\x06_titleB\r\n" +
-	"\v_expires_at\")\n" +
-	"\x11DeleteLinkRequest\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias\"4\n" +
-	"\x14ListUserLinksRequest\x12\x1c\n" +
-	"\n" +
-	"user_tg_id\x18\x01 \x01(\x03R\buserTgId\"h\n" +
-	"\bLinkInfo\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x19\n" +
-	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01B\b\n" +
-	"\x06_title\"E\n" +
-	"\x15ListUserLinksResponse\x12,\n" +
-	"\x05links\x18\x01 \x03(\v2\x16.shortener.v1.LinkInfoR\x05links\"K\n" +
-	"\x12RecordClickRequest\x12\x14\n" +
-	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1f\n" +
-	"\vdevice_type\x18\x02 \x01(\tR\n" +
-	"deviceType2\x9d\x03\n" +
-	"\tShortener\x12O\n" +
-	"\n" +
-	"CreateLink\x12\x1f.shortener.v1.CreateLinkRequest\x1a .shortener.v1.CreateLinkResponse\x12U\n" +
-	"\fGetLinkStats\x12!.shortener.v1.GetLinkStatsRequest\x1a\".shortener.v1.GetLinkStatsResponse\x12E\n" +
-	"\n" +
-	"DeleteLink\x12\x1f.shortener.v1.DeleteLinkRequest\x1a\x16.google.protobuf.Empty\x12X\n" +
-	"\rListUserLinks\x12\".shortener.v1.ListUserLinksRequest\x1a#.shortener.v1.ListUserLinksResponse\x12G\n" +
-	"\vRecordClick\x12 .shortener.v1.RecordClickRequest\x1a\x16.google.protobuf.EmptyB!Z\x1fgen/go/shortener/v1;shortenerv1b\x06proto3"
+func (x *ResolveLinkRequest) Reset() {
+	*x = ResolveLinkRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkRequest) ProtoMessage() {}
+
+func (x *ResolveLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkRequest.ProtoReflect.Descriptor instead.
+func (*ResolveLinkRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ResolveLinkRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type ResolveLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl   string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
+	Active        bool                   `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLinkResponse) Reset() {
+	*x = ResolveLinkResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkResponse) ProtoMessage() {}
+
+func (x *ResolveLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkResponse.ProtoReflect.Descriptor instead.
+func (*ResolveLinkResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ResolveLinkResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *ResolveLinkResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *ResolveLinkResponse) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+// GenerateLinkTokenRequest asks for a single-use token linking a web
+// dashboard session to a Telegram user.
+type GenerateLinkTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserTgId      int64                  `protobuf:"varint,1,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateLinkTokenRequest) Reset() {
+	*x = GenerateLinkTokenRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateLinkTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateLinkTokenRequest) ProtoMessage() {}
+
+func (x *GenerateLinkTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateLinkTokenRequest.ProtoReflect.Descriptor instead.
+func (*GenerateLinkTokenRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GenerateLinkTokenRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+type GenerateLinkTokenResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Token string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// Dashboard URL completing the link when opened.
+	LoginUrl      string                 `protobuf:"bytes,2,opt,name=login_url,json=loginUrl,proto3" json:"login_url,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateLinkTokenResponse) Reset() {
+	*x = GenerateLinkTokenResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateLinkTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateLinkTokenResponse) ProtoMessage() {}
+
+func (x *GenerateLinkTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateLinkTokenResponse.ProtoReflect.Descriptor instead.
+func (*GenerateLinkTokenResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GenerateLinkTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *GenerateLinkTokenResponse) GetLoginUrl() string {
+	if x != nil {
+		return x.LoginUrl
+	}
+	return ""
+}
+
+func (x *GenerateLinkTokenResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type GetLinkTokenStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLinkTokenStatusRequest) Reset() {
+	*x = GetLinkTokenStatusRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLinkTokenStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkTokenStatusRequest) ProtoMessage() {}
+
+func (x *GetLinkTokenStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkTokenStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetLinkTokenStatusRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetLinkTokenStatusRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type GetLinkTokenStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Completed     bool                   `protobuf:"varint,1,opt,name=completed,proto3" json:"completed,omitempty"`
+	Expired       bool                   `protobuf:"varint,2,opt,name=expired,proto3" json:"expired,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLinkTokenStatusResponse) Reset() {
+	*x = GetLinkTokenStatusResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLinkTokenStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkTokenStatusResponse) ProtoMessage() {}
+
+func (x *GetLinkTokenStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkTokenStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetLinkTokenStatusResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetLinkTokenStatusResponse) GetCompleted() bool {
+	if x != nil {
+		return x.Completed
+	}
+	return false
+}
+
+func (x *GetLinkTokenStatusResponse) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+type DisconnectDashboardRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserTgId      int64                  `protobuf:"varint,1,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectDashboardRequest) Reset() {
+	*x = DisconnectDashboardRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectDashboardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectDashboardRequest) ProtoMessage() {}
+
+func (x *DisconnectDashboardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectDashboardRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectDashboardRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DisconnectDashboardRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+type DisconnectDashboardResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WasConnected  bool                   `protobuf:"varint,1,opt,name=was_connected,json=wasConnected,proto3" json:"was_connected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectDashboardResponse) Reset() {
+	*x = DisconnectDashboardResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectDashboardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectDashboardResponse) ProtoMessage() {}
+
+func (x *DisconnectDashboardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectDashboardResponse.ProtoReflect.Descriptor instead.
+func (*DisconnectDashboardResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DisconnectDashboardResponse) GetWasConnected() bool {
+	if x != nil {
+		return x.WasConnected
+	}
+	return false
+}
+
+// RenameLink moves a link to a new alias, keeping its click history.
+type RenameLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	NewAlias      string                 `protobuf:"bytes,2,opt,name=new_alias,json=newAlias,proto3" json:"new_alias,omitempty"`
+	UserTgId      int64                  `protobuf:"varint,3,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameLinkRequest) Reset() {
+	*x = RenameLinkRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameLinkRequest) ProtoMessage() {}
+
+func (x *RenameLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameLinkRequest.ProtoReflect.Descriptor instead.
+func (*RenameLinkRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RenameLinkRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *RenameLinkRequest) GetNewAlias() string {
+	if x != nil {
+		return x.NewAlias
+	}
+	return ""
+}
+
+func (x *RenameLinkRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+type RenameLinkResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Alias  string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Domain *string                `protobuf:"bytes,2,opt,name=domain,proto3,oneof" json:"domain,omitempty"`
+	// Whether the old alias keeps redirecting to the link.
+	OldAliasRedirects bool `protobuf:"varint,3,opt,name=old_alias_redirects,json=oldAliasRedirects,proto3" json:"old_alias_redirects,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RenameLinkResponse) Reset() {
+	*x = RenameLinkResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameLinkResponse) ProtoMessage() {}
+
+func (x *RenameLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameLinkResponse.ProtoReflect.Descriptor instead.
+func (*RenameLinkResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RenameLinkResponse) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *RenameLinkResponse) GetDomain() string {
+	if x != nil && x.Domain != nil {
+		return *x.Domain
+	}
+	return ""
+}
+
+func (x *RenameLinkResponse) GetOldAliasRedirects() bool {
+	if x != nil {
+		return x.OldAliasRedirects
+	}
+	return false
+}
+
+// SetLinkExpiry changes when a link expires.
+type SetLinkExpiryRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Alias    string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	UserTgId int64                  `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	// The new expiry; unset means the link never expires.
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLinkExpiryRequest) Reset() {
+	*x = SetLinkExpiryRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLinkExpiryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkExpiryRequest) ProtoMessage() {}
+
+func (x *SetLinkExpiryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkExpiryRequest.ProtoReflect.Descriptor instead.
+func (*SetLinkExpiryRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SetLinkExpiryRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *SetLinkExpiryRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+func (x *SetLinkExpiryRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type SetLinkExpiryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=expires_at,json=expiresAt,proto3,oneof" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLinkExpiryResponse) Reset() {
+	*x = SetLinkExpiryResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLinkExpiryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkExpiryResponse) ProtoMessage() {}
+
+func (x *SetLinkExpiryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkExpiryResponse.ProtoReflect.Descriptor instead.
+func (*SetLinkExpiryResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SetLinkExpiryResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type GetAliasRulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAliasRulesRequest) Reset() {
+	*x = GetAliasRulesRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAliasRulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAliasRulesRequest) ProtoMessage() {}
+
+func (x *GetAliasRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAliasRulesRequest.ProtoReflect.Descriptor instead.
+func (*GetAliasRulesRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+// GetAliasRulesResponse describes the custom aliases the backend accepts.
+type GetAliasRulesResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	MinLength int32                  `protobuf:"varint,1,opt,name=min_length,json=minLength,proto3" json:"min_length,omitempty"`
+	MaxLength int32                  `protobuf:"varint,2,opt,name=max_length,json=maxLength,proto3" json:"max_length,omitempty"`
+	// Characters allowed besides ASCII letters and digits, e.g. "-_".
+	Symbols       string `protobuf:"bytes,3,opt,name=symbols,proto3" json:"symbols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAliasRulesResponse) Reset() {
+	*x = GetAliasRulesResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAliasRulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAliasRulesResponse) ProtoMessage() {}
+
+func (x *GetAliasRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAliasRulesResponse.ProtoReflect.Descriptor instead.
+func (*GetAliasRulesResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetAliasRulesResponse) GetMinLength() int32 {
+	if x != nil {
+		return x.MinLength
+	}
+	return 0
+}
+
+func (x *GetAliasRulesResponse) GetMaxLength() int32 {
+	if x != nil {
+		return x.MaxLength
+	}
+	return 0
+}
+
+func (x *GetAliasRulesResponse) GetSymbols() string {
+	if x != nil {
+		return x.Symbols
+	}
+	return ""
+}
+
+// UpdateLink points a link at a new destination, keeping its alias and
+// click history.
+type UpdateLinkRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Alias    string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	UserTgId int64                  `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	// The new destination; empty keeps the current one.
+	OriginalUrl string `protobuf:"bytes,3,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// Turns minimal analytics on or off; unset keeps the current setting.
+	MinimalAnalytics *bool `protobuf:"varint,4,opt,name=minimal_analytics,json=minimalAnalytics,proto3,oneof" json:"minimal_analytics,omitempty"`
+	// The new title; unset keeps the current one, empty removes it.
+	Title         *string `protobuf:"bytes,5,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLinkRequest) Reset() {
+	*x = UpdateLinkRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLinkRequest) ProtoMessage() {}
+
+func (x *UpdateLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLinkRequest.ProtoReflect.Descriptor instead.
+func (*UpdateLinkRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UpdateLinkRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *UpdateLinkRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+func (x *UpdateLinkRequest) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *UpdateLinkRequest) GetMinimalAnalytics() bool {
+	if x != nil && x.MinimalAnalytics != nil {
+		return *x.MinimalAnalytics
+	}
+	return false
+}
+
+func (x *UpdateLinkRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+type UpdateLinkResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// The analytics setting in effect; unset from backends that can only set
+	// it when a link is created.
+	MinimalAnalytics *bool `protobuf:"varint,2,opt,name=minimal_analytics,json=minimalAnalytics,proto3,oneof" json:"minimal_analytics,omitempty"`
+	// The title in effect; unset from backends that can only set it when a
+	// link is created.
+	Title         *string `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLinkResponse) Reset() {
+	*x = UpdateLinkResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLinkResponse) ProtoMessage() {}
+
+func (x *UpdateLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLinkResponse.ProtoReflect.Descriptor instead.
+func (*UpdateLinkResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UpdateLinkResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *UpdateLinkResponse) GetMinimalAnalytics() bool {
+	if x != nil && x.MinimalAnalytics != nil {
+		return *x.MinimalAnalytics
+	}
+	return false
+}
+
+func (x *UpdateLinkResponse) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+// TransferLink hands a link over to another user, keeping its alias and
+// click history.
+type TransferLinkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Alias string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	// The current owner.
+	UserTgId      int64 `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	NewOwnerTgId  int64 `protobuf:"varint,3,opt,name=new_owner_tg_id,json=newOwnerTgId,proto3" json:"new_owner_tg_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferLinkRequest) Reset() {
+	*x = TransferLinkRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLinkRequest) ProtoMessage() {}
+
+func (x *TransferLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferLinkRequest.ProtoReflect.Descriptor instead.
+func (*TransferLinkRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TransferLinkRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *TransferLinkRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+func (x *TransferLinkRequest) GetNewOwnerTgId() int64 {
+	if x != nil {
+		return x.NewOwnerTgId
+	}
+	return 0
+}
+
+type TransferLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        *string                `protobuf:"bytes,1,opt,name=domain,proto3,oneof" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferLinkResponse) Reset() {
+	*x = TransferLinkResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferLinkResponse) ProtoMessage() {}
+
+func (x *TransferLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferLinkResponse.ProtoReflect.Descriptor instead.
+func (*TransferLinkResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TransferLinkResponse) GetDomain() string {
+	if x != nil && x.Domain != nil {
+		return *x.Domain
+	}
+	return ""
+}
+
+// SetLinkPreview sets the preview social networks and messengers show for
+// the short link itself, instead of the one of its destination.
+type SetLinkPreviewRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Alias    string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	UserTgId int64                  `protobuf:"varint,2,opt,name=user_tg_id,json=userTgId,proto3" json:"user_tg_id,omitempty"`
+	// The custom preview; unset removes it.
+	Preview       *LinkPreview `protobuf:"bytes,3,opt,name=preview,proto3" json:"preview,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLinkPreviewRequest) Reset() {
+	*x = SetLinkPreviewRequest{}
+	mi := &file_v1_shortener_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLinkPreviewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkPreviewRequest) ProtoMessage() {}
+
+func (x *SetLinkPreviewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkPreviewRequest.ProtoReflect.Descriptor instead.
+func (*SetLinkPreviewRequest) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *SetLinkPreviewRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *SetLinkPreviewRequest) GetUserTgId() int64 {
+	if x != nil {
+		return x.UserTgId
+	}
+	return 0
+}
+
+func (x *SetLinkPreviewRequest) GetPreview() *LinkPreview {
+	if x != nil {
+		return x.Preview
+	}
+	return nil
+}
+
+type LinkPreview struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Title       string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// The image, JPEG or PNG; empty for none.
+	Image []byte `protobuf:"bytes,3,opt,name=image,proto3" json:"image,omitempty"`
+	// The MIME type of image.
+	ImageType     string `protobuf:"bytes,4,opt,name=image_type,json=imageType,proto3" json:"image_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkPreview) Reset() {
+	*x = LinkPreview{}
+	mi := &file_v1_shortener_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkPreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkPreview) ProtoMessage() {}
+
+func (x *LinkPreview) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkPreview.ProtoReflect.Descriptor instead.
+func (*LinkPreview) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *LinkPreview) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *LinkPreview) GetImageType() string {
+	if x != nil {
+		return x.ImageType
+	}
+	return ""
+}
+
+type SetLinkPreviewResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLinkPreviewResponse) Reset() {
+	*x = SetLinkPreviewResponse{}
+	mi := &file_v1_shortener_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLinkPreviewResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLinkPreviewResponse) ProtoMessage() {}
+
+func (x *SetLinkPreviewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_shortener_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLinkPreviewResponse.ProtoReflect.Descriptor instead.
+func (*SetLinkPreviewResponse) Descriptor() ([]byte, []int) {
+	return file_v1_shortener_proto_rawDescGZIP(), []int{29}
+}
+
+var File_v1_shortener_proto protoreflect.FileDescriptor
+
+const file_v1_shortener_proto_rawDesc = "" +
+	"\n" +
+	"\x12v1/shortener.proto\x12\fshortener.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x9e\x04\n" +
+	"\x11CreateLinkRequest\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01\x12>\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\texpiresAt\x88\x01\x01\x12&\n" +
+	"\fcustom_alias\x18\x05 \x01(\tH\x02R\vcustomAlias\x88\x01\x01\x12\x1b\n" +
+	"\x06domain\x18\x06 \x01(\tH\x03R\x06domain\x88\x01\x01\x12\x1b\n" +
+	"\x06source\x18\a \x01(\tH\x04R\x06source\x88\x01\x01\x120\n" +
+	"\x11minimal_analytics\x18\b \x01(\bH\x05R\x10minimalAnalytics\x88\x01\x01\x12>\n" +
+	"\n" +
+	"not_before\x18\t \x01(\v2\x1a.google.protobuf.TimestampH\x06R\tnotBefore\x88\x01\x01\x12$\n" +
+	"\valias_style\x18\n" +
+	" \x01(\tH\aR\n" +
+	"aliasStyle\x88\x01\x01B\b\n" +
+	"\x06_titleB\r\n" +
+	"\v_expires_atB\x0f\n" +
+	"\r_custom_aliasB\t\n" +
+	"\a_domainB\t\n" +
+	"\a_sourceB\x14\n" +
+	"\x12_minimal_analyticsB\r\n" +
+	"\v_not_beforeB\x0e\n" +
+	"\f_alias_style\"\xaf\x01\n" +
+	"\x12CreateLinkResponse\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12>\n" +
+	"\n" +
+	"not_before\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\tnotBefore\x88\x01\x01\x12$\n" +
+	"\valias_style\x18\x03 \x01(\tH\x01R\n" +
+	"aliasStyle\x88\x01\x01B\r\n" +
+	"\v_not_beforeB\x0e\n" +
+	"\f_alias_style\"\xa1\x01\n" +
+	"\x13GetLinkStatsRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x123\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\x04from\x88\x01\x01\x12/\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\x02to\x88\x01\x01B\a\n" +
+	"\x05_fromB\x05\n" +
+	"\x03_to\"\xf1\x06\n" +
+	"\x14GetLinkStatsResponse\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12\x1f\n" +
+	"\vclick_count\x18\x02 \x01(\x03R\n" +
+	"clickCount\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01\x12>\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\texpiresAt\x88\x01\x01\x12`\n" +
+	"\x10clicks_by_device\x18\x05 \x03(\v26.shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntryR\x0eclicksByDevice\x12\x1b\n" +
+	"\x06domain\x18\x06 \x01(\tH\x02R\x06domain\x88\x01\x01\x12\x1b\n" +
+	"\x06source\x18\a \x01(\tH\x03R\x06source\x88\x01\x01\x12>\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampH\x04R\tcreatedAt\x88\x01\x01\x120\n" +
+	"\x11minimal_analytics\x18\t \x01(\bH\x05R\x10minimalAnalytics\x88\x01\x01\x12#\n" +
+	"\vowner_tg_id\x18\n" +
+	" \x01(\x03H\x06R\townerTgId\x88\x01\x01\x12(\n" +
+	"\rrange_applied\x18\v \x01(\bH\aR\frangeApplied\x88\x01\x01\x12>\n" +
+	"\n" +
+	"not_before\x18\f \x01(\v2\x1a.google.protobuf.TimestampH\bR\tnotBefore\x88\x01\x01\x12C\n" +
+	"\rlast_click_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampH\tR\vlastClickAt\x88\x01\x01\x1aA\n" +
+	"\x13ClicksByDeviceEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01B\b\n" +
+	"\x06_titleB\r\n" +
+	"\v_expires_atB\t\n" +
+	"\a_domainB\t\n" +
+	"\a_sourceB\r\n" +
+	"\v_created_atB\x14\n" +
+	"\x12_minimal_analyticsB\x0e\n" +
+	"\f_owner_tg_idB\x10\n" +
+	"\x0e_range_appliedB\r\n" +
+	"\v_not_beforeB\x10\n" +
+	"\x0e_last_click_at\")\n" +
+	"\x11DeleteLinkRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\"p\n" +
+	"\x14ListUserLinksRequest\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x01 \x01(\x03R\buserTgId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"\xcf\x02\n" +
+	"\bLinkInfo\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x00R\x05title\x88\x01\x01\x12\x1b\n" +
+	"\x06domain\x18\x04 \x01(\tH\x01R\x06domain\x88\x01\x01\x12\x1b\n" +
+	"\x06source\x18\x05 \x01(\tH\x02R\x06source\x88\x01\x01\x120\n" +
+	"\x11minimal_analytics\x18\x06 \x01(\bH\x03R\x10minimalAnalytics\x88\x01\x01\x12>\n" +
+	"\n" +
+	"not_before\x18\a \x01(\v2\x1a.google.protobuf.TimestampH\x04R\tnotBefore\x88\x01\x01B\b\n" +
+	"\x06_titleB\t\n" +
+	"\a_domainB\t\n" +
+	"\a_sourceB\x14\n" +
+	"\x12_minimal_analyticsB\r\n" +
+	"\v_not_before\"m\n" +
+	"\x15ListUserLinksResponse\x12,\n" +
+	"\x05links\x18\x01 \x03(\v2\x16.shortener.v1.LinkInfoR\x05links\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"K\n" +
+	"\x12RecordClickRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1f\n" +
+	"\vdevice_type\x18\x02 \x01(\tR\n" +
+	"deviceType\"*\n" +
+	"\x12ResolveLinkRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\"\x9f\x01\n" +
+	"\x13ResolveLinkResponse\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12>\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\texpiresAt\x88\x01\x01\x12\x16\n" +
+	"\x06active\x18\x03 \x01(\bR\x06activeB\r\n" +
+	"\v_expires_at\"8\n" +
+	"\x18GenerateLinkTokenRequest\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x01 \x01(\x03R\buserTgId\"\x89\x01\n" +
+	"\x19GenerateLinkTokenResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1b\n" +
+	"\tlogin_url\x18\x02 \x01(\tR\bloginUrl\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"1\n" +
+	"\x19GetLinkTokenStatusRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"T\n" +
+	"\x1aGetLinkTokenStatusResponse\x12\x1c\n" +
+	"\tcompleted\x18\x01 \x01(\bR\tcompleted\x12\x18\n" +
+	"\aexpired\x18\x02 \x01(\bR\aexpired\":\n" +
+	"\x1aDisconnectDashboardRequest\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x01 \x01(\x03R\buserTgId\"B\n" +
+	"\x1bDisconnectDashboardResponse\x12#\n" +
+	"\rwas_connected\x18\x01 \x01(\bR\fwasConnected\"d\n" +
+	"\x11RenameLinkRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1b\n" +
+	"\tnew_alias\x18\x02 \x01(\tR\bnewAlias\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x03 \x01(\x03R\buserTgId\"\x82\x01\n" +
+	"\x12RenameLinkResponse\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1b\n" +
+	"\x06domain\x18\x02 \x01(\tH\x00R\x06domain\x88\x01\x01\x12.\n" +
+	"\x13old_alias_redirects\x18\x03 \x01(\bR\x11oldAliasRedirectsB\t\n" +
+	"\a_domain\"\x99\x01\n" +
+	"\x14SetLinkExpiryRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x12>\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\texpiresAt\x88\x01\x01B\r\n" +
+	"\v_expires_at\"f\n" +
+	"\x15SetLinkExpiryResponse\x12>\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\texpiresAt\x88\x01\x01B\r\n" +
+	"\v_expires_at\"\x16\n" +
+	"\x14GetAliasRulesRequest\"o\n" +
+	"\x15GetAliasRulesResponse\x12\x1d\n" +
+	"\n" +
+	"min_length\x18\x01 \x01(\x05R\tminLength\x12\x1d\n" +
+	"\n" +
+	"max_length\x18\x02 \x01(\x05R\tmaxLength\x12\x18\n" +
+	"\asymbols\x18\x03 \x01(\tR\asymbols\"\xd7\x01\n" +
+	"\x11UpdateLinkRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x12!\n" +
+	"\foriginal_url\x18\x03 \x01(\tR\voriginalUrl\x120\n" +
+	"\x11minimal_analytics\x18\x04 \x01(\bH\x00R\x10minimalAnalytics\x88\x01\x01\x12\x19\n" +
+	"\x05title\x18\x05 \x01(\tH\x01R\x05title\x88\x01\x01B\x14\n" +
+	"\x12_minimal_analyticsB\b\n" +
+	"\x06_title\"\xa4\x01\n" +
+	"\x12UpdateLinkResponse\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x120\n" +
+	"\x11minimal_analytics\x18\x02 \x01(\bH\x00R\x10minimalAnalytics\x88\x01\x01\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x01R\x05title\x88\x01\x01B\x14\n" +
+	"\x12_minimal_analyticsB\b\n" +
+	"\x06_title\"p\n" +
+	"\x13TransferLinkRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x12%\n" +
+	"\x0fnew_owner_tg_id\x18\x03 \x01(\x03R\fnewOwnerTgId\">\n" +
+	"\x14TransferLinkResponse\x12\x1b\n" +
+	"\x06domain\x18\x01 \x01(\tH\x00R\x06domain\x88\x01\x01B\t\n" +
+	"\a_domain\"\x80\x01\n" +
+	"\x15SetLinkPreviewRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12\x1c\n" +
+	"\n" +
+	"user_tg_id\x18\x02 \x01(\x03R\buserTgId\x123\n" +
+	"\apreview\x18\x03 \x01(\v2\x19.shortener.v1.LinkPreviewR\apreview\"z\n" +
+	"\vLinkPreview\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05image\x18\x03 \x01(\fR\x05image\x12\x1d\n" +
+	"\n" +
+	"image_type\x18\x04 \x01(\tR\timageType\"\x18\n" +
+	"\x16SetLinkPreviewResponse2\xb6\n" +
+	"\n" +
+	"\tShortener\x12O\n" +
+	"\n" +
+	"CreateLink\x12\x1f.shortener.v1.CreateLinkRequest\x1a .shortener.v1.CreateLinkResponse\x12U\n" +
+	"\fGetLinkStats\x12!.shortener.v1.GetLinkStatsRequest\x1a\".shortener.v1.GetLinkStatsResponse\x12E\n" +
+	"\n" +
+	"DeleteLink\x12\x1f.shortener.v1.DeleteLinkRequest\x1a\x16.google.protobuf.Empty\x12X\n" +
+	"\rListUserLinks\x12\".shortener.v1.ListUserLinksRequest\x1a#.shortener.v1.ListUserLinksResponse\x12G\n" +
+	"\vRecordClick\x12 .shortener.v1.RecordClickRequest\x1a\x16.google.protobuf.Empty\x12R\n" +
+	"\vResolveLink\x12 .shortener.v1.ResolveLinkRequest\x1a!.shortener.v1.ResolveLinkResponse\x12d\n" +
+	"\x11GenerateLinkToken\x12&.shortener.v1.GenerateLinkTokenRequest\x1a'.shortener.v1.GenerateLinkTokenResponse\x12g\n" +
+	"\x12GetLinkTokenStatus\x12'.shortener.v1.GetLinkTokenStatusRequest\x1a(.shortener.v1.GetLinkTokenStatusResponse\x12j\n" +
+	"\x13DisconnectDashboard\x12(.shortener.v1.DisconnectDashboardRequest\x1a).shortener.v1.DisconnectDashboardResponse\x12O\n" +
+	"\n" +
+	"RenameLink\x12\x1f.shortener.v1.RenameLinkRequest\x1a .shortener.v1.RenameLinkResponse\x12X\n" +
+	"\rSetLinkExpiry\x12\".shortener.v1.SetLinkExpiryRequest\x1a#.shortener.v1.SetLinkExpiryResponse\x12X\n" +
+	"\rGetAliasRules\x12\".shortener.v1.GetAliasRulesRequest\x1a#.shortener.v1.GetAliasRulesResponse\x12O\n" +
+	"\n" +
+	"UpdateLink\x12\x1f.shortener.v1.UpdateLinkRequest\x1a .shortener.v1.UpdateLinkResponse\x12U\n" +
+	"\fTransferLink\x12!.shortener.v1.TransferLinkRequest\x1a\".shortener.v1.TransferLinkResponse\x12[\n" +
+	"\x0eSetLinkPreview\x12#.shortener.v1.SetLinkPreviewRequest\x1a$.shortener.v1.SetLinkPreviewResponseB!Z\x1fgen/go/shortener/v1;shortenerv1b\x06proto3"
 
 var (
 	file_v1_shortener_proto_rawDescOnce sync.Once
@@ -577,41 +2093,95 @@ func file_v1_shortener_proto_rawDescGZIP() []byte {
 	return file_v1_shortener_proto_rawDescData
 }
 
-var file_v1_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_v1_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_v1_shortener_proto_goTypes = []any{
-	(*CreateLinkRequest)(nil),     // 0: shortener.v1.CreateLinkRequest
-	(*CreateLinkResponse)(nil),    // 1: shortener.v1.CreateLinkResponse
-	(*GetLinkStatsRequest)(nil),   // 2: shortener.v1.GetLinkStatsRequest
-	(*GetLinkStatsResponse)(nil),  // 3: shortener.v1.GetLinkStatsResponse
-	(*DeleteLinkRequest)(nil),     // 4: shortener.v1.DeleteLinkRequest
-	(*ListUserLinksRequest)(nil),  // 5: shortener.v1.ListUserLinksRequest
-	(*LinkInfo)(nil),              // 6: shortener.v1.LinkInfo
-	(*ListUserLinksResponse)(nil), // 7: shortener.v1.ListUserLinksResponse
-	(*RecordClickRequest)(nil),    // 8: shortener.v1.RecordClickRequest
-	nil,                           // 9: shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntry
-	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
-	(*emptypb.Empty)(nil),         // 11: google.protobuf.Empty
+	(*CreateLinkRequest)(nil),           // 0: shortener.v1.CreateLinkRequest
+	(*CreateLinkResponse)(nil),          // 1: shortener.v1.CreateLinkResponse
+	(*GetLinkStatsRequest)(nil),         // 2: shortener.v1.GetLinkStatsRequest
+	(*GetLinkStatsResponse)(nil),        // 3: shortener.v1.GetLinkStatsResponse
+	(*DeleteLinkRequest)(nil),           // 4: shortener.v1.DeleteLinkRequest
+	(*ListUserLinksRequest)(nil),        // 5: shortener.v1.ListUserLinksRequest
+	(*LinkInfo)(nil),                    // 6: shortener.v1.LinkInfo
+	(*ListUserLinksResponse)(nil),       // 7: shortener.v1.ListUserLinksResponse
+	(*RecordClickRequest)(nil),          // 8: shortener.v1.RecordClickRequest
+	(*ResolveLinkRequest)(nil),          // 9: shortener.v1.ResolveLinkRequest
+	(*ResolveLinkResponse)(nil),         // 10: shortener.v1.ResolveLinkResponse
+	(*GenerateLinkTokenRequest)(nil),    // 11: shortener.v1.GenerateLinkTokenRequest
+	(*GenerateLinkTokenResponse)(nil),   // 12: shortener.v1.GenerateLinkTokenResponse
+	(*GetLinkTokenStatusRequest)(nil),   // 13: shortener.v1.GetLinkTokenStatusRequest
+	(*GetLinkTokenStatusResponse)(nil),  // 14: shortener.v1.GetLinkTokenStatusResponse
+	(*DisconnectDashboardRequest)(nil),  // 15: shortener.v1.DisconnectDashboardRequest
+	(*DisconnectDashboardResponse)(nil), // 16: shortener.v1.DisconnectDashboardResponse
+	(*RenameLinkRequest)(nil),           // 17: shortener.v1.RenameLinkRequest
+	(*RenameLinkResponse)(nil),          // 18: shortener.v1.RenameLinkResponse
+	(*SetLinkExpiryRequest)(nil),        // 19: shortener.v1.SetLinkExpiryRequest
+	(*SetLinkExpiryResponse)(nil),       // 20: shortener.v1.SetLinkExpiryResponse
+	(*GetAliasRulesRequest)(nil),        // 21: shortener.v1.GetAliasRulesRequest
+	(*GetAliasRulesResponse)(nil),       // 22: shortener.v1.GetAliasRulesResponse
+	(*UpdateLinkRequest)(nil),           // 23: shortener.v1.UpdateLinkRequest
+	(*UpdateLinkResponse)(nil),          // 24: shortener.v1.UpdateLinkResponse
+	(*TransferLinkRequest)(nil),         // 25: shortener.v1.TransferLinkRequest
+	(*TransferLinkResponse)(nil),        // 26: shortener.v1.TransferLinkResponse
+	(*SetLinkPreviewRequest)(nil),       // 27: shortener.v1.SetLinkPreviewRequest
+	(*LinkPreview)(nil),                 // 28: shortener.v1.LinkPreview
+	(*SetLinkPreviewResponse)(nil),      // 29: shortener.v1.SetLinkPreviewResponse
+	nil,                                 // 30: shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntry
+	(*timestamppb.Timestamp)(nil),       // 31: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),               // 32: google.protobuf.Empty
 }
 var file_v1_shortener_proto_depIdxs = []int32{
-	10, // 0: shortener.v1.CreateLinkRequest.expires_at:type_name -> google.protobuf.Timestamp
-	10, // 1: shortener.v1.GetLinkStatsResponse.expires_at:type_name -> google.protobuf.Timestamp
-	9,  // 2: shortener.v1.GetLinkStatsResponse.clicks_by_device:type_name -> shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntry
-	6,  // 3: shortener.v1.ListUserLinksResponse.links:type_name -> shortener.v1.LinkInfo
-	0,  // 4: shortener.v1.Shortener.CreateLink:input_type -> shortener.v1.CreateLinkRequest
-	2,  // 5: shortener.v1.Shortener.GetLinkStats:input_type -> shortener.v1.GetLinkStatsRequest
-	4,  // 6: shortener.v1.Shortener.DeleteLink:input_type -> shortener.v1.DeleteLinkRequest
-	5,  // 7: shortener.v1.Shortener.ListUserLinks:input_type -> shortener.v1.ListUserLinksRequest
-	8,  // 8: shortener.v1.Shortener.RecordClick:input_type -> shortener.v1.RecordClickRequest
-	1,  // 9: shortener.v1.Shortener.CreateLink:output_type -> shortener.v1.CreateLinkResponse
-	3,  // 10: shortener.v1.Shortener.GetLinkStats:output_type -> shortener.v1.GetLinkStatsResponse
-	11, // 11: shortener.v1.Shortener.DeleteLink:output_type -> google.protobuf.Empty
-	7,  // 12: shortener.v1.Shortener.ListUserLinks:output_type -> shortener.v1.ListUserLinksResponse
-	11, // 13: shortener.v1.Shortener.RecordClick:output_type -> google.protobuf.Empty
-	9,  // [9:14] is the sub-list for method output_type
-	4,  // [4:9] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	31, // 0: shortener.v1.CreateLinkRequest.expires_at:type_name -> google.protobuf.Timestamp
+	31, // 1: shortener.v1.CreateLinkRequest.not_before:type_name -> google.protobuf.Timestamp
+	31, // 2: shortener.v1.CreateLinkResponse.not_before:type_name -> google.protobuf.Timestamp
+	31, // 3: shortener.v1.GetLinkStatsRequest.from:type_name -> google.protobuf.Timestamp
+	31, // 4: shortener.v1.GetLinkStatsRequest.to:type_name -> google.protobuf.Timestamp
+	31, // 5: shortener.v1.GetLinkStatsResponse.expires_at:type_name -> google.protobuf.Timestamp
+	30, // 6: shortener.v1.GetLinkStatsResponse.clicks_by_device:type_name -> shortener.v1.GetLinkStatsResponse.ClicksByDeviceEntry
+	31, // 7: shortener.v1.GetLinkStatsResponse.created_at:type_name -> google.protobuf.Timestamp
+	31, // 8: shortener.v1.GetLinkStatsResponse.not_before:type_name -> google.protobuf.Timestamp
+	31, // 9: shortener.v1.GetLinkStatsResponse.last_click_at:type_name -> google.protobuf.Timestamp
+	31, // 10: shortener.v1.LinkInfo.not_before:type_name -> google.protobuf.Timestamp
+	6,  // 11: shortener.v1.ListUserLinksResponse.links:type_name -> shortener.v1.LinkInfo
+	31, // 12: shortener.v1.ResolveLinkResponse.expires_at:type_name -> google.protobuf.Timestamp
+	31, // 13: shortener.v1.GenerateLinkTokenResponse.expires_at:type_name -> google.protobuf.Timestamp
+	31, // 14: shortener.v1.SetLinkExpiryRequest.expires_at:type_name -> google.protobuf.Timestamp
+	31, // 15: shortener.v1.SetLinkExpiryResponse.expires_at:type_name -> google.protobuf.Timestamp
+	28, // 16: shortener.v1.SetLinkPreviewRequest.preview:type_name -> shortener.v1.LinkPreview
+	0,  // 17: shortener.v1.Shortener.CreateLink:input_type -> shortener.v1.CreateLinkRequest
+	2,  // 18: shortener.v1.Shortener.GetLinkStats:input_type -> shortener.v1.GetLinkStatsRequest
+	4,  // 19: shortener.v1.Shortener.DeleteLink:input_type -> shortener.v1.DeleteLinkRequest
+	5,  // 20: shortener.v1.Shortener.ListUserLinks:input_type -> shortener.v1.ListUserLinksRequest
+	8,  // 21: shortener.v1.Shortener.RecordClick:input_type -> shortener.v1.RecordClickRequest
+	9,  // 22: shortener.v1.Shortener.ResolveLink:input_type -> shortener.v1.ResolveLinkRequest
+	11, // 23: shortener.v1.Shortener.GenerateLinkToken:input_type -> shortener.v1.GenerateLinkTokenRequest
+	13, // 24: shortener.v1.Shortener.GetLinkTokenStatus:input_type -> shortener.v1.GetLinkTokenStatusRequest
+	15, // 25: shortener.v1.Shortener.DisconnectDashboard:input_type -> shortener.v1.DisconnectDashboardRequest
+	17, // 26: shortener.v1.Shortener.RenameLink:input_type -> shortener.v1.RenameLinkRequest
+	19, // 27: shortener.v1.Shortener.SetLinkExpiry:input_type -> shortener.v1.SetLinkExpiryRequest
+	21, // 28: shortener.v1.Shortener.GetAliasRules:input_type -> shortener.v1.GetAliasRulesRequest
+	23, // 29: shortener.v1.Shortener.UpdateLink:input_type -> shortener.v1.UpdateLinkRequest
+	25, // 30: shortener.v1.Shortener.TransferLink:input_type -> shortener.v1.TransferLinkRequest
+	27, // 31: shortener.v1.Shortener.SetLinkPreview:input_type -> shortener.v1.SetLinkPreviewRequest
+	1,  // 32: shortener.v1.Shortener.CreateLink:output_type -> shortener.v1.CreateLinkResponse
+	3,  // 33: shortener.v1.Shortener.GetLinkStats:output_type -> shortener.v1.GetLinkStatsResponse
+	32, // 34: shortener.v1.Shortener.DeleteLink:output_type -> google.protobuf.Empty
+	7,  // 35: shortener.v1.Shortener.ListUserLinks:output_type -> shortener.v1.ListUserLinksResponse
+	32, // 36: shortener.v1.Shortener.RecordClick:output_type -> google.protobuf.Empty
+	10, // 37: shortener.v1.Shortener.ResolveLink:output_type -> shortener.v1.ResolveLinkResponse
+	12, // 38: shortener.v1.Shortener.GenerateLinkToken:output_type -> shortener.v1.GenerateLinkTokenResponse
+	14, // 39: shortener.v1.Shortener.GetLinkTokenStatus:output_type -> shortener.v1.GetLinkTokenStatusResponse
+	16, // 40: shortener.v1.Shortener.DisconnectDashboard:output_type -> shortener.v1.DisconnectDashboardResponse
+	18, // 41: shortener.v1.Shortener.RenameLink:output_type -> shortener.v1.RenameLinkResponse
+	20, // 42: shortener.v1.Shortener.SetLinkExpiry:output_type -> shortener.v1.SetLinkExpiryResponse
+	22, // 43: shortener.v1.Shortener.GetAliasRules:output_type -> shortener.v1.GetAliasRulesResponse
+	24, // 44: shortener.v1.Shortener.UpdateLink:output_type -> shortener.v1.UpdateLinkResponse
+	26, // 45: shortener.v1.Shortener.TransferLink:output_type -> shortener.v1.TransferLinkResponse
+	29, // 46: shortener.v1.Shortener.SetLinkPreview:output_type -> shortener.v1.SetLinkPreviewResponse
+	32, // [32:47] is the sub-list for method output_type
+	17, // [17:32] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_v1_shortener_proto_init() }
@@ -620,15 +2190,24 @@ func file_v1_shortener_proto_init() {
 		return
 	}
 	file_v1_shortener_proto_msgTypes[0].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[1].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[2].OneofWrappers = []any{}
 	file_v1_shortener_proto_msgTypes[3].OneofWrappers = []any{}
 	file_v1_shortener_proto_msgTypes[6].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[10].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[18].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[19].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[20].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[23].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[24].OneofWrappers = []any{}
+	file_v1_shortener_proto_msgTypes[26].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_shortener_proto_rawDesc), len(file_v1_shortener_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,11 +20,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Shortener_CreateLink_FullMethodName    = "/shortener.v1.Shortener/CreateLink"
-	Shortener_GetLinkStats_FullMethodName  = "/shortener.v1.Shortener/GetLinkStats"
-	Shortener_DeleteLink_FullMethodName    = "/shortener.v1.Shortener/DeleteLink"
-	Shortener_ListUserLinks_FullMethodName = "/shortener.v1.Shortener/ListUserLinks"
-	Shortener_RecordClick_FullMethodName   = "/shortener.v1.Shortener/RecordClick"
+	Shortener_CreateLink_FullMethodName          = "/shortener.v1.Shortener/CreateLink"
+	Shortener_GetLinkStats_FullMethodName        = "/shortener.v1.Shortener/GetLinkStats"
+	Shortener_DeleteLink_FullMethodName          = "/shortener.v1.Shortener/DeleteLink"
+	Shortener_ListUserLinks_FullMethodName       = "/shortener.v1.Shortener/ListUserLinks"
+	Shortener_RecordClick_FullMethodName         = "/shortener.v1.Shortener/RecordClick"
+	Shortener_ResolveLink_FullMethodName         = "/shortener.v1.Shortener/ResolveLink"
+	Shortener_GenerateLinkToken_FullMethodName   = "/shortener.v1.Shortener/GenerateLinkToken"
+	Shortener_GetLinkTokenStatus_FullMethodName  = "/shortener.v1.Shortener/GetLinkTokenStatus"
+	Shortener_DisconnectDashboard_FullMethodName = "/shortener.v1.Shortener/DisconnectDashboard"
+	Shortener_RenameLink_FullMethodName          = "/shortener.v1.Shortener/RenameLink"
+	Shortener_SetLinkExpiry_FullMethodName       = "/shortener.v1.Shortener/SetLinkExpiry"
+	Shortener_GetAliasRules_FullMethodName       = "/shortener.v1.Shortener/GetAliasRules"
+	Shortener_UpdateLink_FullMethodName          = "/shortener.v1.Shortener/UpdateLink"
+	Shortener_TransferLink_FullMethodName        = "/shortener.v1.Shortener/TransferLink"
+	Shortener_SetLinkPreview_FullMethodName      = "/shortener.v1.Shortener/SetLinkPreview"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -36,6 +46,16 @@ type ShortenerClient interface {
 	DeleteLink(ctx context.Context, in *DeleteLinkRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	ListUserLinks(ctx context.Context, in *ListUserLinksRequest, opts ...grpc.CallOption) (*ListUserLinksResponse, error)
 	RecordClick(ctx context.Context, in *RecordClickRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ResolveLink(ctx context.Context, in *ResolveLinkRequest, opts ...grpc.CallOption) (*ResolveLinkResponse, error)
+	GenerateLinkToken(ctx context.Context, in *GenerateLinkTokenRequest, opts ...grpc.CallOption) (*GenerateLinkTokenResponse, error)
+	GetLinkTokenStatus(ctx context.Context, in *GetLinkTokenStatusRequest, opts ...grpc.CallOption) (*GetLinkTokenStatusResponse, error)
+	DisconnectDashboard(ctx context.Context, in *DisconnectDashboardRequest, opts ...grpc.CallOption) (*DisconnectDashboardResponse, error)
+	RenameLink(ctx context.Context, in *RenameLinkRequest, opts ...grpc.CallOption) (*RenameLinkResponse, error)
+	SetLinkExpiry(ctx context.Context, in *SetLinkExpiryRequest, opts ...grpc.CallOption) (*SetLinkExpiryResponse, error)
+	GetAliasRules(ctx context.Context, in *GetAliasRulesRequest, opts ...grpc.CallOption) (*GetAliasRulesResponse, error)
+	UpdateLink(ctx context.Context, in *UpdateLinkRequest, opts ...grpc.CallOption) (*UpdateLinkResponse, error)
+	TransferLink(ctx context.Context, in *TransferLinkRequest, opts ...grpc.CallOption) (*TransferLinkResponse, error)
+	SetLinkPreview(ctx context.Context, in *SetLinkPreviewRequest, opts ...grpc.CallOption) (*SetLinkPreviewResponse, error)
 }
 
 type shortenerClient struct {
@@ -96,6 +116,106 @@ func (c *shortenerClient) RecordClick(ctx context.Context, in *RecordClickReques
 	return out, nil
 }
 
+func (c *shortenerClient) ResolveLink(ctx context.Context, in *ResolveLinkRequest, opts ...grpc.CallOption) (*ResolveLinkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResolveLinkResponse)
+	err := c.cc.Invoke(ctx, Shortener_ResolveLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GenerateLinkToken(ctx context.Context, in *GenerateLinkTokenRequest, opts ...grpc.CallOption) (*GenerateLinkTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateLinkTokenResponse)
+	err := c.cc.Invoke(ctx, Shortener_GenerateLinkToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetLinkTokenStatus(ctx context.Context, in *GetLinkTokenStatusRequest, opts ...grpc.CallOption) (*GetLinkTokenStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLinkTokenStatusResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkTokenStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) DisconnectDashboard(ctx context.Context, in *DisconnectDashboardRequest, opts ...grpc.CallOption) (*DisconnectDashboardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisconnectDashboardResponse)
+	err := c.cc.Invoke(ctx, Shortener_DisconnectDashboard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RenameLink(ctx context.Context, in *RenameLinkRequest, opts ...grpc.CallOption) (*RenameLinkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenameLinkResponse)
+	err := c.cc.Invoke(ctx, Shortener_RenameLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) SetLinkExpiry(ctx context.Context, in *SetLinkExpiryRequest, opts ...grpc.CallOption) (*SetLinkExpiryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLinkExpiryResponse)
+	err := c.cc.Invoke(ctx, Shortener_SetLinkExpiry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetAliasRules(ctx context.Context, in *GetAliasRulesRequest, opts ...grpc.CallOption) (*GetAliasRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAliasRulesResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetAliasRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) UpdateLink(ctx context.Context, in *UpdateLinkRequest, opts ...grpc.CallOption) (*UpdateLinkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateLinkResponse)
+	err := c.cc.Invoke(ctx, Shortener_UpdateLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) TransferLink(ctx context.Context, in *TransferLinkRequest, opts ...grpc.CallOption) (*TransferLinkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferLinkResponse)
+	err := c.cc.Invoke(ctx, Shortener_TransferLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) SetLinkPreview(ctx context.Context, in *SetLinkPreviewRequest, opts ...grpc.CallOption) (*SetLinkPreviewResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLinkPreviewResponse)
+	err := c.cc.Invoke(ctx, Shortener_SetLinkPreview_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility.
@@ -105,6 +225,16 @@ type ShortenerServer interface {
 	DeleteLink(context.Context, *DeleteLinkRequest) (*emptypb.Empty, error)
 	ListUserLinks(context.Context, *ListUserLinksRequest) (*ListUserLinksResponse, error)
 	RecordClick(context.Context, *RecordClickRequest) (*emptypb.Empty, error)
+	ResolveLink(context.Context, *ResolveLinkRequest) (*ResolveLinkResponse, error)
+	GenerateLinkToken(context.Context, *GenerateLinkTokenRequest) (*GenerateLinkTokenResponse, error)
+	GetLinkTokenStatus(context.Context, *GetLinkTokenStatusRequest) (*GetLinkTokenStatusResponse, error)
+	DisconnectDashboard(context.Context, *DisconnectDashboardRequest) (*DisconnectDashboardResponse, error)
+	RenameLink(context.Context, *RenameLinkRequest) (*RenameLinkResponse, error)
+	SetLinkExpiry(context.Context, *SetLinkExpiryRequest) (*SetLinkExpiryResponse, error)
+	GetAliasRules(context.Context, *GetAliasRulesRequest) (*GetAliasRulesResponse, error)
+	UpdateLink(context.Context, *UpdateLinkRequest) (*UpdateLinkResponse, error)
+	TransferLink(context.Context, *TransferLinkRequest) (*TransferLinkResponse, error)
+	SetLinkPreview(context.Context, *SetLinkPreviewRequest) (*SetLinkPreviewResponse, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -130,6 +260,36 @@ func (UnimplementedShortenerServer) ListUserLinks(context.Context, *ListUserLink
 func (UnimplementedShortenerServer) RecordClick(context.Context, *RecordClickRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RecordClick not implemented")
 }
+func (UnimplementedShortenerServer) ResolveLink(context.Context, *ResolveLinkRequest) (*ResolveLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveLink not implemented")
+}
+func (UnimplementedShortenerServer) GenerateLinkToken(context.Context, *GenerateLinkTokenRequest) (*GenerateLinkTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateLinkToken not implemented")
+}
+func (UnimplementedShortenerServer) GetLinkTokenStatus(context.Context, *GetLinkTokenStatusRequest) (*GetLinkTokenStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkTokenStatus not implemented")
+}
+func (UnimplementedShortenerServer) DisconnectDashboard(context.Context, *DisconnectDashboardRequest) (*DisconnectDashboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectDashboard not implemented")
+}
+func (UnimplementedShortenerServer) RenameLink(context.Context, *RenameLinkRequest) (*RenameLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameLink not implemented")
+}
+func (UnimplementedShortenerServer) SetLinkExpiry(context.Context, *SetLinkExpiryRequest) (*SetLinkExpiryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLinkExpiry not implemented")
+}
+func (UnimplementedShortenerServer) GetAliasRules(context.Context, *GetAliasRulesRequest) (*GetAliasRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAliasRules not implemented")
+}
+func (UnimplementedShortenerServer) UpdateLink(context.Context, *UpdateLinkRequest) (*UpdateLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLink not implemented")
+}
+func (UnimplementedShortenerServer) TransferLink(context.Context, *TransferLinkRequest) (*TransferLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferLink not implemented")
+}
+func (UnimplementedShortenerServer) SetLinkPreview(context.Context, *SetLinkPreviewRequest) (*SetLinkPreviewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLinkPreview not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 func (UnimplementedShortenerServer) testEmbeddedByValue()                   {}
 
@@ -241,6 +401,186 @@ func _Shortener_RecordClick_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_ResolveLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ResolveLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ResolveLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ResolveLink(ctx, req.(*ResolveLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GenerateLinkToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateLinkTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GenerateLinkToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GenerateLinkToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GenerateLinkToken(ctx, req.(*GenerateLinkTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetLinkTokenStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinkTokenStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkTokenStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkTokenStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkTokenStatus(ctx, req.(*GetLinkTokenStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_DisconnectDashboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectDashboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).DisconnectDashboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_DisconnectDashboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).DisconnectDashboard(ctx, req.(*DisconnectDashboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RenameLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RenameLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RenameLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RenameLink(ctx, req.(*RenameLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SetLinkExpiry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLinkExpiryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetLinkExpiry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetLinkExpiry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetLinkExpiry(ctx, req.(*SetLinkExpiryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetAliasRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAliasRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetAliasRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetAliasRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetAliasRules(ctx, req.(*GetAliasRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_UpdateLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_UpdateLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateLink(ctx, req.(*UpdateLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_TransferLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).TransferLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_TransferLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).TransferLink(ctx, req.(*TransferLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SetLinkPreview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLinkPreviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetLinkPreview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetLinkPreview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetLinkPreview(ctx, req.(*SetLinkPreviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -268,6 +608,46 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RecordClick",
 			Handler:    _Shortener_RecordClick_Handler,
 		},
+		{
+			MethodName: "ResolveLink",
+			Handler:    _Shortener_ResolveLink_Handler,
+		},
+		{
+			MethodName: "GenerateLinkToken",
+			Handler:    _Shortener_GenerateLinkToken_Handler,
+		},
+		{
+			MethodName: "GetLinkTokenStatus",
+			Handler:    _Shortener_GetLinkTokenStatus_Handler,
+		},
+		{
+			MethodName: "DisconnectDashboard",
+			Handler:    _Shortener_DisconnectDashboard_Handler,
+		},
+		{
+			MethodName: "RenameLink",
+			Handler:    _Shortener_RenameLink_Handler,
+		},
+		{
+			MethodName: "SetLinkExpiry",
+			Handler:    _Shortener_SetLinkExpiry_Handler,
+		},
+		{
+			MethodName: "GetAliasRules",
+			Handler:    _Shortener_GetAliasRules_Handler,
+		},
+		{
+			MethodName: "UpdateLink",
+			Handler:    _Shortener_UpdateLink_Handler,
+		},
+		{
+			MethodName: "TransferLink",
+			Handler:    _Shortener_TransferLink_Handler,
+		},
+		{
+			MethodName: "SetLinkPreview",
+			Handler:    _Shortener_SetLinkPreview_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "v1/shortener.proto",
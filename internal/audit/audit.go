@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the log.
+const (
+	// Inspect is looking up a link of any user.
+	Inspect = "inspect"
+	// Disable is making a link of another user expire now.
+	Disable = "disable"
+	// Delete is deleting a link of another user.
+	Delete = "delete"
+	// Report is a user reporting a link as abusive; its entries have a
+	// ReporterID instead of an AdminID.
+	Report = "report"
+
+	// The life of a dangerous admin command held back until confirmed; its
+	// entries have a Command instead of an Alias.
+
+	// ConfirmRequested is the admin being asked to type a phrase back.
+	ConfirmRequested = "confirm_requested"
+	// Confirmed is the admin typing the phrase back in time.
+	Confirmed = "confirmed"
+	// ConfirmFailed is the command being dropped unconfirmed; Reason says
+	// why.
+	ConfirmFailed = "confirm_failed"
+	// Approved is another admin approving a confirmed command.
+	Approved = "approved"
+	// Rejected is another admin rejecting a confirmed command.
+	Rejected = "rejected"
+	// Executed is a confirmed command being run.
+	Executed = "executed"
+)
+
+// Entry is one admin action.
+type Entry struct {
+	At      time.Time `json:"at"`
+	AdminID int64     `json:"admin_id"`
+	// ReporterID is the user who made a report.
+	ReporterID int64  `json:"reporter_id,omitempty"`
+	Action     string `json:"action"`
+	Alias      string `json:"alias"`
+	// Command is the admin command an entry of a confirmation is about,
+	// with its arguments.
+	Command string `json:"command,omitempty"`
+	// Reason is the justification the admin gave, e.g. an abuse report ID.
+	Reason string `json:"reason"`
+}
+
+// Log is an append-only file of admin actions, one JSON object per line.
+// Entries are never rewritten, so the file can be shipped and rotated by
+// external tools; it is reopened for every entry.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open checks that the log at path can be written, creating it if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path}, f.Close()
+}
+
+// Record appends e to the log and syncs it to disk, so an action is never
+// carried out without its entry.
+func (l *Log) Record(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,180 @@
+package blocklist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+// List matches hosts against blocked domain patterns. A pattern is either an
+// exact host ("example.com") or a suffix pattern ("*.example.com") matching
+// the domain itself and all of its subdomains. Patterns from the config are
+// fixed; patterns from the file can be reloaded and edited at runtime.
+type List struct {
+	mu      sync.RWMutex
+	static  []string
+	file    string
+	entries []string
+}
+
+// New creates a list from the config patterns and the optional pattern file.
+func New(patterns []string, file string) (*List, error) {
+	l := &List{file: file}
+	for _, p := range patterns {
+		norm, err := Normalize(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist pattern %q: %w", p, err)
+		}
+		l.static = append(l.static, norm)
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload re-reads the pattern file.
+func (l *List) Reload() error {
+	if l.file == "" {
+		return nil
+	}
+	f, err := os.Open(l.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open blocklist file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		norm, err := Normalize(line)
+		if err != nil {
+			return fmt.Errorf("invalid blocklist pattern %q: %w", line, err)
+		}
+		entries = append(entries, norm)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read blocklist file: %w", err)
+	}
+
+	l.mu.Lock()
+	l.entries = entries
+	l.mu.Unlock()
+	return nil
+}
+
+// Blocked reports whether host matches any pattern.
+func (l *List) Blocked(host string) bool {
+	host, err := normalizeHost(host)
+	if err != nil || host == "" {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, p := range slices.Concat(l.static, l.entries) {
+		if match(p, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add adds a pattern and persists the file. It reports whether the pattern was new.
+func (l *List) Add(pattern string) (bool, error) {
+	norm, err := Normalize(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if slices.Contains(l.entries, norm) || slices.Contains(l.static, norm) {
+		return false, nil
+	}
+	l.entries = append(l.entries, norm)
+	return true, l.saveLocked()
+}
+
+// Remove removes a runtime pattern and persists the file. It reports whether
+// the pattern was present; patterns from the config can't be removed.
+func (l *List) Remove(pattern string) (bool, error) {
+	norm, err := Normalize(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := slices.Index(l.entries, norm)
+	if i < 0 {
+		return false, nil
+	}
+	l.entries = slices.Delete(l.entries, i, i+1)
+	return true, l.saveLocked()
+}
+
+// Entries returns all patterns.
+func (l *List) Entries() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Concat(l.static, l.entries)
+}
+
+func (l *List) saveLocked() error {
+	if l.file == "" {
+		return errors.New("blocklist file is not configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(l.file), 0o755); err != nil {
+		return err
+	}
+	data := strings.Join(l.entries, "\n") + "\n"
+	tmp := l.file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.file)
+}
+
+// Normalize lower-cases a pattern and converts IDN hosts to punycode.
+func Normalize(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	wildcard := strings.HasPrefix(pattern, "*.")
+	host, err := normalizeHost(strings.TrimPrefix(pattern, "*."))
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		return "", errors.New("empty pattern")
+	}
+	if wildcard {
+		return "*." + host, nil
+	}
+	return host, nil
+}
+
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	return idna.Lookup.ToASCII(host)
+}
+
+func match(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/audit"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Stages of a pending command.
+const (
+	// pendingPhrase waits for the admin to type the phrase back.
+	pendingPhrase = iota + 1
+	// pendingApproval waits for another admin to approve.
+	pendingApproval
+)
+
+// confirmPhraseWords are what confirmation phrases are made of. There are
+// 32 of them, so a random byte picks one without bias.
+var confirmPhraseWords = []string{
+	"amber", "anchor", "aspen", "badger", "basalt", "beacon", "birch", "canyon",
+	"cedar", "comet", "copper", "coral", "delta", "ember", "falcon", "fjord",
+	"glacier", "granite", "harbor", "heron", "indigo", "juniper", "lantern", "marble",
+	"meadow", "nickel", "orchid", "otter", "pebble", "quartz", "walnut", "willow",
+}
+
+// confirmPhraseLen is the number of words of a confirmation phrase.
+const confirmPhraseLen = 3
+
+// pendingCommand is a dangerous admin command held back until confirmed.
+// Pending commands live in memory; each stage ends with a timer, see
+// expireConfirmation.
+type pendingCommand struct {
+	// Request is the command as sent, run as is once confirmed.
+	Request *Request
+	Phrase  string
+	Stage   int
+}
+
+// confirmedKeyType marks the context of a command replayed once confirmed.
+type confirmedKeyType struct{}
+
+// checkConfirmConfig checks that the commands to confirm are admin commands,
+// and that there is another admin to approve them when required.
+func (b *Bot) checkConfirmConfig() error {
+	for _, name := range b.config.Confirm.Commands {
+		if route := b.router.commands[name]; route == nil || !route.AdminOnly {
+			return fmt.Errorf("confirm command %q is not an admin command", name)
+		}
+	}
+	if b.config.Confirm.SecondAdmin && len(b.config.Confirm.Commands) > 0 && len(b.config.Telegram.AdminChatIDs) < 2 {
+		return fmt.Errorf("confirmation by a second admin needs at least two admin chats")
+	}
+	return nil
+}
+
+// confirmMiddleware holds back the commands listed in Confirm.Commands
+// until the admin confirms them, see requestConfirmation. Bare commands
+// only show their usage or state, so they run right away.
+func (b *Bot) confirmMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		if req.Route == nil || req.Callback != nil || strings.TrimSpace(req.Args) == "" ||
+			!slices.Contains(b.config.Confirm.Commands, req.Route.Name) || ctx.Value(confirmedKeyType{}) != nil {
+			return next(ctx, req)
+		}
+		return b.requestConfirmation(req)
+	}
+}
+
+// requestConfirmation asks the admin of req to type a random phrase back
+// within Confirm.Timeout before the command runs.
+func (b *Bot) requestConfirmation(req *Request) error {
+	id := newPayloadToken()
+	p := &pendingCommand{Request: req, Phrase: confirmPhrase(), Stage: pendingPhrase}
+	if !b.recordConfirmation(req.UserID, audit.ConfirmRequested, p, "") {
+		return b.reply(req.ChatID, msgAuditFailed, nil)
+	}
+	if err := b.startDialog(req.ChatID, UserState{State: StateWaitingForConfirmPhrase, Payload: confirmPayload{ID: id}}); err != nil {
+		return err
+	}
+	b.confirmations.Set(id, p)
+	time.AfterFunc(b.config.Confirm.Timeout, func() { b.expireConfirmation(id, pendingPhrase) })
+	data := b.confirmData(p, 0, b.config.Confirm.Timeout)
+	return b.replyWithKeyboard(req.ChatID, msgConfirmCommand, data, b.createCancelKeyboard())
+}
+
+// handleConfirmPhraseInput runs the pending command id once text is its
+// phrase, or asks another admin to approve it first. Any other text drops
+// the command: the admin has to send it again.
+func (b *Bot) handleConfirmPhraseInput(ctx context.Context, msg *tgbotapi.Message, id, text string) error {
+	chatID := msg.Chat.ID
+	b.resetUserState(chatID)
+	p, ok := b.takePending(id, pendingPhrase)
+	if !ok {
+		// The timer got to it first
+		return b.reply(chatID, msgConfirmClosed, nil)
+	}
+	if strings.Join(strings.Fields(strings.ToLower(text)), " ") != p.Phrase {
+		b.recordConfirmation(p.Request.UserID, audit.ConfirmFailed, p, "wrong phrase")
+		return b.reply(chatID, msgConfirmWrongPhrase, b.confirmData(p, 0, 0))
+	}
+	if !b.recordConfirmation(p.Request.UserID, audit.Confirmed, p, "") {
+		return b.reply(chatID, msgAuditFailed, nil)
+	}
+	if b.config.Confirm.SecondAdmin {
+		return b.requestApproval(id, p)
+	}
+	return b.runConfirmed(ctx, p)
+}
+
+// requestApproval asks the other admins to approve p; the first to answer
+// decides.
+func (b *Bot) requestApproval(id string, p *pendingCommand) error {
+	approval := *p
+	approval.Stage = pendingApproval
+	b.confirmations.Set(id, &approval)
+
+	data := b.confirmData(p, p.Request.UserID, b.config.Confirm.ApprovalTimeout)
+	asked := 0
+	for _, chatID := range b.config.Telegram.AdminChatIDs {
+		if chatID == p.Request.UserID {
+			continue
+		}
+		msg := tgbotapi.NewMessage(chatID, b.render(msgApproveCommand, data))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(chatPayloads(chatID), "Approve", actionApproveCommand, id),
+			b.payloadButton(chatPayloads(chatID), "Reject", actionRejectCommand, id),
+		))
+		if _, err := b.send(msg, b.notification(chatID)); err != nil {
+			b.log.Warn("failed to ask admin for approval", zap.Int64("chat_id", chatID), zap.Error(err))
+			continue
+		}
+		asked++
+	}
+	if asked == 0 {
+		if _, ok := b.takePending(id, pendingApproval); ok {
+			b.recordConfirmation(p.Request.UserID, audit.ConfirmFailed, p, "no admin to approve")
+		}
+		return b.reply(p.Request.ChatID, msgConfirmNoApprover, data)
+	}
+	time.AfterFunc(b.config.Confirm.ApprovalTimeout, func() { b.expireConfirmation(id, pendingApproval) })
+	return b.reply(p.Request.ChatID, msgConfirmAwaitingApproval, data)
+}
+
+// approveCommand runs the pending command of an approval button, telling
+// the admin who sent it.
+func (b *Bot) approveCommand(ctx context.Context, r *Request) error {
+	if p, ok := b.confirmations.Get(r.Args); ok && p.Request.UserID == r.UserID {
+		r.Answer.alert(b.render(msgApproveOwnCommand, nil))
+		return nil
+	}
+	p, ok := b.takePending(r.Args, pendingApproval)
+	if !ok {
+		return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgConfirmClosed, nil))
+	}
+	if !b.recordConfirmation(r.UserID, audit.Approved, p, "") {
+		b.confirmations.Set(r.Args, p)
+		r.Answer.alert(b.render(msgAuditFailed, nil))
+		return nil
+	}
+	if err := b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgCommandApproved, b.confirmData(p, p.Request.UserID, 0))); err != nil {
+		b.log.Warn("failed to close approval request", zap.Error(err))
+	}
+	if err := b.reply(p.Request.ChatID, msgCommandApprovedBy, b.confirmData(p, r.UserID, 0)); err != nil {
+		b.log.Warn("failed to tell admin of approval", zap.Int64("chat_id", p.Request.ChatID), zap.Error(err))
+	}
+	return b.runConfirmed(ctx, p)
+}
+
+// rejectCommand drops the pending command of an approval button, telling
+// the admin who sent it.
+func (b *Bot) rejectCommand(r *Request) error {
+	p, ok := b.takePending(r.Args, pendingApproval)
+	if !ok {
+		return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgConfirmClosed, nil))
+	}
+	// The command is dropped either way
+	b.recordConfirmation(r.UserID, audit.Rejected, p, "")
+	if err := b.reply(p.Request.ChatID, msgCommandRejectedBy, b.confirmData(p, r.UserID, 0)); err != nil {
+		b.log.Warn("failed to tell admin of rejection", zap.Int64("chat_id", p.Request.ChatID), zap.Error(err))
+	}
+	return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgCommandRejected, b.confirmData(p, p.Request.UserID, 0)))
+}
+
+// runConfirmed runs a confirmed command through the router again, past
+// confirmMiddleware, once it is in the audit log.
+func (b *Bot) runConfirmed(ctx context.Context, p *pendingCommand) error {
+	req := p.Request
+	if !b.recordConfirmation(req.UserID, audit.Executed, p, "") {
+		return b.reply(req.ChatID, msgAuditFailed, nil)
+	}
+	ctx = context.WithValue(ctx, confirmedKeyType{}, true)
+	return b.router.HandleCommand(ctx, req.Route.Name, req).asError()
+}
+
+// expireConfirmation drops the pending command id if it is still at stage
+// once its time is up. A phrase prompt the admin already left, by canceling
+// or starting another dialog, is dropped quietly.
+func (b *Bot) expireConfirmation(id string, stage int) {
+	p, ok := b.takePending(id, stage)
+	if !ok {
+		return
+	}
+	chatID := p.Request.ChatID
+	name := msgApprovalTimedOut
+	if stage == pendingPhrase {
+		if payload, ok := b.getUserState(chatID).Payload.(confirmPayload); !ok || payload.ID != id {
+			b.recordConfirmation(p.Request.UserID, audit.ConfirmFailed, p, "abandoned")
+			return
+		}
+		b.resetUserState(chatID)
+		name = msgConfirmExpired
+	}
+	b.recordConfirmation(p.Request.UserID, audit.ConfirmFailed, p, "expired")
+	if err := b.reply(chatID, name, b.confirmData(p, 0, 0)); err != nil {
+		b.log.Warn("failed to tell admin of expired confirmation", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+}
+
+// takePending removes and returns the pending command id if it is at stage,
+// so that of a phrase, a button and a timer, only the first acts on it.
+func (b *Bot) takePending(id string, stage int) (*pendingCommand, bool) {
+	var taken *pendingCommand
+	b.confirmations.Update(id, func(p *pendingCommand, ok bool) (*pendingCommand, bool) {
+		if ok && p.Stage == stage {
+			taken = p
+			return nil, false
+		}
+		return p, ok
+	})
+	return taken, taken != nil
+}
+
+// recordConfirmation writes a step of the confirmation of p, taken by
+// adminID, to the audit log and reports whether that worked.
+func (b *Bot) recordConfirmation(adminID int64, action string, p *pendingCommand, reason string) bool {
+	entry := audit.Entry{At: time.Now(), AdminID: adminID, Action: action, Command: commandLine(p.Request), Reason: reason}
+	if err := b.audit.Record(entry); err != nil {
+		b.log.Error("failed to write audit log", zap.Error(err), zap.String("action", action), zap.String("command", p.Request.Route.Name))
+		return false
+	}
+	b.log.Info("admin command confirmation",
+		zap.String("action", action),
+		zap.String("command", p.Request.Route.Name),
+		zap.Int64("admin_id", adminID),
+		zap.String("reason", reason))
+	return true
+}
+
+// confirmData describes p, naming the admin adminID, if any, and giving
+// timeout, if any, as what is left to answer.
+func (b *Bot) confirmData(p *pendingCommand, adminID int64, timeout time.Duration) confirmData {
+	data := confirmData{
+		Command: commandLine(p.Request),
+		Name:    "/" + p.Request.Route.Name,
+		Phrase:  p.Phrase,
+	}
+	if adminID != 0 {
+		u, _ := b.users.Get(adminID)
+		u.ID = adminID
+		data.Admin = userLabel(u)
+	}
+	if timeout > 0 {
+		data.Timeout = formatRemaining(timeout)
+	}
+	return data
+}
+
+// commandLine returns the command of req as sent, with its arguments.
+func commandLine(req *Request) string {
+	return strings.TrimSpace("/" + req.Route.Name + " " + req.Args)
+}
+
+// confirmPhrase returns a random phrase of confirmPhraseLen words.
+func confirmPhrase() string {
+	buf := make([]byte, confirmPhraseLen)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	words := make([]string, len(buf))
+	for i, n := range buf {
+		words[i] = confirmPhraseWords[int(n)%len(confirmPhraseWords)]
+	}
+	return strings.Join(words, " ")
+}
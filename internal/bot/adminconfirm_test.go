@@ -0,0 +1,277 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/audit"
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/telegramtest"
+	"bufio"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// approver is the second admin of the confirmation tests.
+const approver = 2002
+
+// confirmConfig returns a configuration where user is an admin whose /block
+// commands must be confirmed, and approver another admin.
+func confirmConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user, approver}
+	cfg.Confirm.Commands = []string{"block"}
+	return cfg
+}
+
+// requestBlock sends /block of host and returns the phrase to confirm it.
+func (e *e2e) requestBlock(host string) string {
+	e.tg.SendMessage(user, "/block "+host)
+	prompt := e.tg.WaitText(user, "You are about to run")
+	text := prompt.Text()
+	return text[strings.LastIndex(text, "\n")+1:]
+}
+
+// blocked reports whether host is on the blocklist.
+func (e *e2e) blocked(host string) bool {
+	return slices.Contains(e.bot.blocklist.Entries(), host)
+}
+
+// auditActions returns the actions of the audit log, with their reason
+// after a colon if any.
+func auditActions(t *testing.T, cfg *config.Config) []string {
+	t.Helper()
+	f, err := os.Open(cfg.Audit.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var actions []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("bad audit entry %q: %v", scanner.Text(), err)
+		}
+		if entry.Command != "" {
+			action := entry.Action
+			if entry.Reason != "" {
+				action += ":" + entry.Reason
+			}
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func TestConfirmPhrase(t *testing.T) {
+	cfg := confirmConfig(t)
+	e := startBot(t, cfg)
+
+	phrase := e.requestBlock("bad.example")
+	if len(strings.Fields(phrase)) != confirmPhraseLen {
+		t.Fatalf("phrase %q", phrase)
+	}
+	if e.blocked("bad.example") {
+		t.Fatal("command ran before it was confirmed")
+	}
+	// Case and spacing don't matter
+	e.tg.SendMessage(user, "  "+strings.ToUpper(strings.ReplaceAll(phrase, " ", "   ")))
+	e.tg.WaitText(user, "Blocked bad.example")
+
+	want := []string{audit.ConfirmRequested, audit.Confirmed, audit.Executed}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestConfirmBareCommandRuns(t *testing.T) {
+	e := startBot(t, confirmConfig(t))
+
+	e.tg.SendMessage(user, "/block")
+	e.tg.WaitText(user, "Usage: /block")
+}
+
+func TestConfirmWrongPhrase(t *testing.T) {
+	cfg := confirmConfig(t)
+	e := startBot(t, cfg)
+
+	phrase := e.requestBlock("bad.example")
+	e.tg.SendMessage(user, "amber amber amber amber")
+	e.tg.WaitText(user, "The phrase doesn't match, so /block was dropped")
+
+	// The right phrase is too late now: it is taken for a plain message
+	e.tg.SendMessage(user, phrase)
+	e.tg.SendMessage(user, "/blocklist")
+	e.tg.WaitText(user, "The blocklist is empty")
+	if e.blocked("bad.example") {
+		t.Error("command ran after a wrong phrase")
+	}
+
+	want := []string{audit.ConfirmRequested, audit.ConfirmFailed + ":wrong phrase"}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestConfirmExpired(t *testing.T) {
+	cfg := confirmConfig(t)
+	cfg.Confirm.Timeout = 100 * time.Millisecond
+	e := startBot(t, cfg)
+
+	phrase := e.requestBlock("bad.example")
+	e.tg.WaitText(user, "The phrase wasn't sent in time, so /block was dropped")
+
+	e.tg.SendMessage(user, phrase)
+	e.tg.SendMessage(user, "/blocklist")
+	e.tg.WaitText(user, "The blocklist is empty")
+	if e.blocked("bad.example") {
+		t.Error("command ran after its phrase expired")
+	}
+
+	want := []string{audit.ConfirmRequested, audit.ConfirmFailed + ":expired"}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestConfirmAbandoned(t *testing.T) {
+	cfg := confirmConfig(t)
+	cfg.Confirm.Timeout = 300 * time.Millisecond
+	e := startBot(t, cfg)
+
+	e.tg.SendMessage(user, "/block bad.example")
+	e.press(t, e.tg.WaitText(user, "You are about to run"), "Cancel")
+	e.answer()
+	// Left prompts expire quietly
+	time.Sleep(2 * cfg.Confirm.Timeout)
+	want := []string{audit.ConfirmRequested, audit.ConfirmFailed + ":abandoned"}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+	for _, r := range e.tg.Requests("sendMessage") {
+		if strings.Contains(r.Text(), "wasn't sent in time") {
+			t.Errorf("told of the expiry of an abandoned prompt: %q", r.Text())
+		}
+	}
+}
+
+// confirmForApproval requests /block of host and types its phrase, and
+// returns the approval request sent to approver.
+func (e *e2e) confirmForApproval(host string) telegramtest.Request {
+	e.tg.SendMessage(user, e.requestBlock(host))
+	// Approvers are asked before the admin is told
+	approval := e.tg.WaitText(approver, "/block "+host)
+	e.tg.WaitText(user, "runs once another admin approves it")
+	return approval
+}
+
+func TestConfirmSecondAdminApproves(t *testing.T) {
+	cfg := confirmConfig(t)
+	cfg.Confirm.SecondAdmin = true
+	e := startBot(t, cfg)
+
+	approval := e.confirmForApproval("bad.example")
+	if e.blocked("bad.example") {
+		t.Fatal("command ran before it was approved")
+	}
+
+	// The admin who sent the command can't approve it
+	e.pressAs(t, user, approval, "Approve")
+	if alert := e.answer(); !strings.Contains(alert.Param("text"), "Another admin has to approve") {
+		t.Errorf("alert = %q", alert.Param("text"))
+	}
+
+	e.pressAs(t, approver, approval, "Approve")
+	e.tg.WaitText(approver, "You approved /block")
+	e.tg.WaitText(user, "approved /block, running it")
+	e.tg.WaitText(user, "Blocked bad.example")
+
+	want := []string{audit.ConfirmRequested, audit.Confirmed, audit.Approved, audit.Executed}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+
+	// A second press finds the request answered
+	e.pressAs(t, approver, approval, "Approve")
+	e.tg.WaitText(approver, "already answered or has expired")
+}
+
+func TestConfirmSecondAdminRejects(t *testing.T) {
+	cfg := confirmConfig(t)
+	cfg.Confirm.SecondAdmin = true
+	e := startBot(t, cfg)
+
+	approval := e.confirmForApproval("bad.example")
+	e.pressAs(t, approver, approval, "Reject")
+	e.tg.WaitText(approver, "You rejected /block")
+	e.tg.WaitText(user, "rejected /block, so it was dropped")
+	if e.blocked("bad.example") {
+		t.Error("rejected command ran")
+	}
+
+	want := []string{audit.ConfirmRequested, audit.Confirmed, audit.Rejected}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestConfirmApprovalTimesOut(t *testing.T) {
+	cfg := confirmConfig(t)
+	cfg.Confirm.SecondAdmin = true
+	cfg.Confirm.ApprovalTimeout = 100 * time.Millisecond
+	e := startBot(t, cfg)
+
+	approval := e.confirmForApproval("bad.example")
+	e.tg.WaitText(user, "No admin approved /block in time")
+
+	e.pressAs(t, approver, approval, "Approve")
+	e.tg.WaitText(approver, "already answered or has expired")
+	if e.blocked("bad.example") {
+		t.Error("command ran after its approval expired")
+	}
+
+	want := []string{audit.ConfirmRequested, audit.Confirmed, audit.ConfirmFailed + ":expired"}
+	if got := auditActions(t, cfg); !slices.Equal(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestCheckConfirmConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*config.Config)
+		wantErr string
+	}{
+		{"defaults", func(*config.Config) {}, ""},
+		{"not an admin command", func(c *config.Config) { c.Confirm.Commands = []string{"shorten"} }, `confirm command "shorten" is not an admin command`},
+		{"unknown command", func(c *config.Config) { c.Confirm.Commands = []string{"nope"} }, `confirm command "nope" is not an admin command`},
+		{"second admin missing", func(c *config.Config) {
+			c.Confirm.SecondAdmin = true
+			c.Telegram.AdminChatIDs = []int64{user}
+		}, "needs at least two admin chats"},
+		{"second admin without commands", func(c *config.Config) {
+			c.Confirm.SecondAdmin = true
+			c.Confirm.Commands = nil
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			tt.edit(cfg)
+			b := &Bot{log: zap.NewNop(), config: cfg}
+			b.router = b.newRouter()
+			err := b.checkConfirmConfig()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("err = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
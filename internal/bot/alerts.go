@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/version"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backendMonitor turns the outcomes of backend calls into at most one alert
+// per outage: one when calls have failed for the threshold without a
+// success in between, and one when a call succeeds again.
+type backendMonitor struct {
+	mu           sync.Mutex
+	alertAfter   time.Duration
+	failingSince time.Time
+	alerted      bool
+}
+
+type backendEvent int
+
+const (
+	backendNoEvent backendEvent = iota
+	backendDown
+	backendRecovered
+)
+
+func newBackendMonitor(alertAfter time.Duration) *backendMonitor {
+	return &backendMonitor{alertAfter: alertAfter}
+}
+
+// Record notes the outcome of a call at now and returns the alert to send,
+// along with how long the backend has been failing.
+func (m *backendMonitor) Record(err error, now time.Time) (backendEvent, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !isOutage(err) {
+		// Errors like NotFound mean the backend is answering
+		since := m.failingSince
+		wasAlerted := m.alerted
+		m.failingSince = time.Time{}
+		m.alerted = false
+		if wasAlerted {
+			return backendRecovered, now.Sub(since)
+		}
+		return backendNoEvent, 0
+	}
+
+	if m.failingSince.IsZero() {
+		m.failingSince = now
+	}
+	if !m.alerted && now.Sub(m.failingSince) >= m.alertAfter {
+		m.alerted = true
+		return backendDown, now.Sub(m.failingSince)
+	}
+	return backendNoEvent, 0
+}
+
+// isOutage reports whether err means the backend could not be reached.
+func isOutage(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// observeBackend is registered with the backend client. It alerts admins
+// about outages, notices methods the backend doesn't implement and adds the
+// call to the timings of the handler that made it.
+func (b *Bot) observeBackend(ctx context.Context, method string, latency time.Duration, err error) {
+	timingsFrom(ctx).addBackend(latency)
+	b.observeCapability(method, err)
+	event, since := b.backendMonitor.Record(err, time.Now())
+	switch event {
+	case backendDown:
+		b.log.Error("backend calls failing", zap.Duration("for", since), zap.String("method", method), zap.Error(err))
+		b.notifyAdmins(b.render(msgAdminBackendDown, backendDownData{For: since.Round(time.Second), Error: err.Error()}))
+	case backendRecovered:
+		b.log.Info("backend recovered", zap.Duration("after", since))
+		b.notifyAdmins(b.render(msgAdminBackendRecovered, backendDownData{For: since.Round(time.Second)}))
+	}
+}
+
+// notifyAdmins sends text to every admin chat. It only talks to Telegram and
+// ignores failures, so it is safe to use while the backend or Telegram
+// itself is in trouble.
+func (b *Bot) notifyAdmins(text string) {
+	for _, chatID := range b.config.Telegram.AdminChatIDs {
+		if _, err := b.send(tgbotapi.NewMessage(chatID, text)); err != nil {
+			b.log.Debug("failed to notify admin", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
+	}
+}
+
+// startupNotice renders the message sent to admins when the bot starts.
+func (b *Bot) startupNotice() string {
+	return b.render(msgAdminStarted, b.buildInfo())
+}
+
+// buildInfo describes the running build and deployment.
+func (b *Bot) buildInfo() startupData {
+	return startupData{
+		Version:  version.String(),
+		Env:      b.config.Env,
+		Backend:  backendHosts(b.config.GRPCClient.BackendAddress),
+		Features: b.features.String(),
+	}
+}
+
+// backendHosts returns the hosts of the backend address without ports.
+func backendHosts(address string) string {
+	addrs, err := config.BackendAddresses(address)
+	if err != nil {
+		return address
+	}
+	hosts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = addr
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			hosts[i] = host
+		}
+	}
+	return strings.Join(hosts, ", ")
+}
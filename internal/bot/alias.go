@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	errEmptyAlias   = errors.New("alias is empty")
+	errInvalidAlias = errors.New("alias is invalid")
+	errForeignLink  = errors.New("short URL belongs to another domain")
+)
+
+// resolveAlias accepts either a bare alias or a full short URL built from the
+// configured BaseURL and returns the alias.
+func (b *Bot) resolveAlias(arg string) (string, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return "", errEmptyAlias
+	}
+
+	alias := arg
+	if strings.Contains(arg, "://") || strings.ContainsAny(arg, "./") {
+		var err error
+		if alias, err = b.aliasFromShortURL(arg); err != nil {
+			return "", err
+		}
+	}
+
+	if !b.isAlias(alias) {
+		return "", errInvalidAlias
+	}
+	return alias, nil
+}
+
+// aliasFromShortURL extracts the alias from a short URL, comparing scheme and
+// host case-insensitively and tolerating trailing slashes.
+func (b *Bot) aliasFromShortURL(raw string) (string, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", errInvalidAlias
+	}
+	domain, ok := b.findDomain(u.Host)
+	if !ok {
+		return "", errForeignLink
+	}
+	base, err := url.Parse(domain.BaseURL)
+	if err != nil {
+		return "", errInvalidAlias
+	}
+
+	basePath := strings.Trim(base.Path, "/")
+	path := strings.Trim(u.Path, "/")
+	if basePath != "" {
+		if !strings.HasPrefix(path, basePath+"/") {
+			return "", errForeignLink
+		}
+		path = strings.TrimPrefix(path, basePath+"/")
+	}
+	return path, nil
+}
+
+// aliasErrorTemplate returns the message template and data for a
+// resolveAlias error.
+func (b *Bot) aliasErrorTemplate(err error, command string) (string, any) {
+	switch {
+	case errors.Is(err, errEmptyAlias):
+		return msgInvalidCommandFormat, commandData{Command: command}
+	case errors.Is(err, errForeignLink):
+		return msgNotOurLink, nil
+	default:
+		return msgInvalidAliasFormat, b.aliasRules().data()
+	}
+}
+
+// isOwnShortURL reports whether raw points to the bot's short link domain.
+func (b *Bot) isOwnShortURL(raw string) bool {
+	_, err := b.aliasFromShortURL(raw)
+	return !errors.Is(err, errForeignLink)
+}
+
+// handleOwnShortURL shows stats for a pasted short URL owned by the user,
+// instead of shortening an already short link.
+func (b *Bot) handleOwnShortURL(ctx context.Context, to payloadKey, raw string) error {
+	alias, err := b.aliasFromShortURL(raw)
+	if err != nil || !b.isAlias(alias) {
+		return b.reply(to.chatID, msgAlreadyShortLink, nil)
+	}
+
+	owned, err := b.ownsLink(ctx, to.chatID, alias)
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+	}
+	if !owned {
+		return b.reply(to.chatID, msgAlreadyShortLink, nil)
+	}
+	return b.showStats(ctx, to, alias, nil)
+}
+
+// ownsLink reports whether alias is among the user's links.
+func (b *Bot) ownsLink(ctx context.Context, userID int64, alias string) (bool, error) {
+	res, err := b.listUserLinks(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, link := range res.GetLinks() {
+		if link.GetAlias() == alias {
+			return true, nil
+		}
+	}
+	return false, nil
+}
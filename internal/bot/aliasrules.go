@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// aliasRules are the custom aliases the backend accepts: MinLength to
+// MaxLength ASCII letters, digits and Symbols.
+type aliasRules struct {
+	MinLength int
+	MaxLength int
+	Symbols   string
+	regex     *regexp.Regexp
+}
+
+// defaultAliasRules apply when the backend can't tell its rules.
+var defaultAliasRules = mustAliasRules(1, 20, "-")
+
+// symbolNames are the names of symbols in user-facing rule descriptions.
+var symbolNames = map[rune]string{
+	'-': "hyphens",
+	'_': "underscores",
+	'.': "dots",
+	'~': "tildes",
+}
+
+// newAliasRules compiles alias rules, rejecting rules that make no sense or
+// would allow characters that can't appear in a URL path as they are.
+func newAliasRules(minLength, maxLength int, symbols string) (*aliasRules, error) {
+	if minLength < 1 || maxLength < minLength {
+		return nil, fmt.Errorf("invalid alias length %d-%d", minLength, maxLength)
+	}
+	var class strings.Builder
+	for _, r := range symbols {
+		if _, ok := symbolNames[r]; !ok {
+			return nil, fmt.Errorf("unsupported alias symbol %q", r)
+		}
+		if r != '_' {
+			class.WriteRune('\\')
+		}
+		class.WriteRune(r)
+	}
+	regex, err := regexp.Compile(fmt.Sprintf(`^[a-zA-Z0-9%s]{%d,%d}$`, class.String(), minLength, maxLength))
+	if err != nil {
+		return nil, err
+	}
+	return &aliasRules{MinLength: minLength, MaxLength: maxLength, Symbols: symbols, regex: regex}, nil
+}
+
+func mustAliasRules(minLength, maxLength int, symbols string) *aliasRules {
+	rules, err := newAliasRules(minLength, maxLength, symbols)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// Match reports whether alias follows the rules.
+func (r *aliasRules) Match(alias string) bool {
+	return r.regex.MatchString(alias)
+}
+
+// data describes the rules to message templates.
+func (r *aliasRules) data() aliasRulesData {
+	allowed := []string{"letters", "numbers"}
+	for _, s := range r.Symbols {
+		allowed = append(allowed, symbolNames[s])
+	}
+	allowed[len(allowed)-1] = "and " + allowed[len(allowed)-1]
+	length := fmt.Sprintf("%d-%d characters", r.MinLength, r.MaxLength)
+	if r.MinLength == r.MaxLength {
+		length = fmt.Sprintf("%d characters", r.MinLength)
+	}
+	return aliasRulesData{Allowed: strings.Join(allowed, ", "), Length: length}
+}
+
+// aliasRules returns the alias rules in effect.
+func (b *Bot) aliasRules() *aliasRules {
+	if rules := b.backendAliasRules.Load(); rules != nil {
+		return rules
+	}
+	return defaultAliasRules
+}
+
+// generatedAliasRules cover the aliases the backend generates in any style,
+// which may be longer than custom aliases are allowed to be, as word
+// aliases are.
+var generatedAliasRules = mustAliasRules(1, 64, "-")
+
+// isAlias reports whether s can be the alias of an existing link. Links
+// created before the backend's current rules took effect, and generated
+// ones, may only follow the compiled-in ones.
+func (b *Bot) isAlias(s string) bool {
+	return b.aliasRules().Match(s) || generatedAliasRules.Match(s)
+}
+
+// validateCustomAlias replies in chatID when alias can't be used as a custom
+// alias and reports whether it can.
+func (b *Bot) validateCustomAlias(chatID int64, alias string) (bool, error) {
+	if rules := b.aliasRules(); !rules.Match(alias) {
+		return false, b.reply(chatID, msgInvalidAliasFormat, rules.data())
+	}
+	if b.isSelfTestAlias(alias) {
+		return false, b.reply(chatID, msgAliasReserved, aliasData{Alias: b.config.SelfTest.AliasPrefix})
+	}
+	return true, nil
+}
+
+// loadAliasRules asks the backend for its alias rules, keeping the rules in
+// effect when it can't tell.
+func (b *Bot) loadAliasRules(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, b.config.GRPCClient.Timeout)
+	defer cancel()
+	res, err := b.grpcClient.GetAliasRules(ctx)
+	if err != nil {
+		return
+	}
+	rules, err := newAliasRules(int(res.GetMinLength()), int(res.GetMaxLength()), res.GetSymbols())
+	if err != nil {
+		b.log.Warn("ignoring invalid alias rules from backend", zap.Error(err))
+		return
+	}
+	if old := b.backendAliasRules.Swap(rules); old == nil || old.regex.String() != rules.regex.String() {
+		b.log.Info("alias rules loaded from backend",
+			zap.Int("min_length", rules.MinLength),
+			zap.Int("max_length", rules.MaxLength),
+			zap.String("symbols", rules.Symbols))
+	}
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"slices"
+)
+
+// Styles of the aliases the backend generates.
+const (
+	aliasStyleRandom  = "random"
+	aliasStyleWords   = "words"
+	aliasStyleNumeric = "numeric"
+)
+
+// aliasStyles lists the alias styles in the order the settings button
+// cycles through them.
+var aliasStyles = []string{aliasStyleRandom, aliasStyleWords, aliasStyleNumeric}
+
+// linkAliasStyles stands for choosing the style of generated aliases
+// through CreateLink among the capabilities. Backends without styles
+// generate their own and leave alias_style out of the response, which is
+// only noticed on use.
+const linkAliasStyles = shortenerv1.Shortener_CreateLink_FullMethodName + "#alias_style"
+
+// aliasStyle returns the style of the aliases generated for the user of p:
+// theirs, or the deployment's default.
+func (b *Bot) aliasStyle(p prefs.Prefs) string {
+	if slices.Contains(aliasStyles, p.Defaults.AliasStyle) {
+		return p.Defaults.AliasStyle
+	}
+	return b.config.Links.AliasStyle
+}
+
+// nextAliasStyle returns the style after current in the settings cycle.
+func nextAliasStyle(current string) string {
+	i := slices.Index(aliasStyles, current)
+	return aliasStyles[(i+1)%len(aliasStyles)]
+}
+
+// checkAliasStyle learns from a link created with a generated alias whether
+// the backend generated it in the style asked for.
+func (b *Bot) checkAliasStyle(req *shortenerv1.CreateLinkRequest, res *shortenerv1.CreateLinkResponse) {
+	if req.AliasStyle == nil || req.CustomAlias != nil {
+		return
+	}
+	if b.capabilities.Set(linkAliasStyles, res.AliasStyle != nil) {
+		b.capabilitiesChanged()
+	}
+}
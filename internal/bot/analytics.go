@@ -0,0 +1,51 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// linkAnalyticsUpdate stands for changing minimal analytics through
+// UpdateLink among the capabilities. Backends that can only set it on
+// creation leave it out of the response, which is only noticed on use.
+const linkAnalyticsUpdate = shortenerv1.Shortener_UpdateLink_FullMethodName + "#minimal_analytics"
+
+// toggleAnalytics turns minimal analytics of a link on or off and redraws
+// the stats message the button was pressed on.
+func (b *Bot) toggleAnalytics(ctx context.Context, r *Request) error {
+	alias := r.Args
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	minimal := !stats.GetMinimalAnalytics()
+	res, err := b.grpcClient.UpdateLink(ctx, &shortenerv1.UpdateLinkRequest{Alias: alias, UserTgId: r.UserID, MinimalAnalytics: &minimal})
+	if err != nil {
+		b.log.Error("gRPC UpdateLink failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	if b.capabilities.Set(linkAnalyticsUpdate, res.MinimalAnalytics != nil) {
+		b.capabilitiesChanged()
+	}
+	if res.MinimalAnalytics == nil {
+		r.Answer.alert(b.render(msgAnalyticsCreationOnly, nil))
+		return b.refreshStatsKeyboard(r, alias)
+	}
+
+	stats.MinimalAnalytics = res.MinimalAnalytics
+	if res.GetMinimalAnalytics() {
+		r.Answer.toast(b.render(msgToastAnalyticsMinimal, nil))
+	} else {
+		r.Answer.toast(b.render(msgToastAnalyticsFull, nil))
+	}
+	text, keyboard := b.renderStats(r.Payloads(), b.outputStyle(r.ChatID), alias, stats)
+	edit := tgbotapi.NewEditMessageTextAndMarkup(r.ChatID, r.Message.MessageID, text, keyboard)
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, nil)
+}
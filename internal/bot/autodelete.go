@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/client"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// messageKind groups messages that can be auto-deleted.
+type messageKind int
+
+const (
+	kindError messageKind = iota + 1
+	kindPrompt
+	kindNotice
+)
+
+// transientMessages lists the messages that may be auto-deleted. Messages
+// carrying created short URLs or stats must never be added here.
+var transientMessages = map[string]messageKind{
+	msgInvalidShortenFormat:     kindError,
+	msgUnknownCommand:           kindError,
+	msgInvalidCommandFormat:     kindError,
+	msgInvalidAliasFormat:       kindError,
+	msgAliasReserved:            kindError,
+	msgBroadcastUsage:           kindError,
+	msgInternalError:            kindError,
+	msgFeatureUnavailable:       kindError,
+	msgFeatureDisabled:          kindError,
+	msgAuditFailed:              kindError,
+	msgInvalidTimezone:          kindError,
+	msgInspectUsage:             kindError,
+	msgLinkNotFound:             kindError,
+	msgAliasTaken:               kindError,
+	msgAliasGenerationFailed:    kindError,
+	msgInvalidArgument:          kindError,
+	msgInvalidRequest:           kindError,
+	msgResourceExhausted:        kindError,
+	msgServiceUnavailable:       kindError,
+	msgRequestTimeout:           kindError,
+	msgPermissionDenied:         kindError,
+	msgUnauthenticated:          kindError,
+	msgNotOurLink:               kindError,
+	msgAlreadyShortLink:         kindError,
+	msgFollowRedirectFailed:     kindError,
+	msgBlockedDomain:            kindError,
+	msgRateLimited:              kindError,
+	msgPrivateChatOnly:          kindError,
+	msgReplyHasNoURL:            kindError,
+	msgUTMInvalidValue:          kindError,
+	msgInvalidExpiry:            kindError,
+	msgSameAlias:                kindError,
+	msgSettingsFileTooLarge:     kindError,
+	msgSettingsFileNewer:        kindError,
+	msgSettingsFileInvalid:      kindError,
+	msgSettingsFileForeignLinks: kindError,
+	msgInvalidCampaignName:      kindError,
+	msgInvalidCampaignDates:     kindError,
+	msgCampaignExists:           kindError,
+	msgCampaignLimitReached:     kindError,
+	msgNotBeforePast:            kindError,
+	msgNotBeforeAfterExpiry:     kindError,
+	msgInvalidNotBefore:         kindError,
+	msgCampaignNotFound:         kindError,
+	msgConfirmWrongPhrase:       kindError,
+
+	msgUseShortenCommand:      kindPrompt,
+	msgSendCustomAlias:        kindPrompt,
+	msgSendUrlWithAlias:       kindPrompt,
+	msgSendNewAlias:           kindPrompt,
+	msgSendShortenOptions:     kindPrompt,
+	msgUTMSendURL:             kindPrompt,
+	msgUTMSource:              kindPrompt,
+	msgUTMMedium:              kindPrompt,
+	msgUTMCampaign:            kindPrompt,
+	msgUTMPressCreate:         kindPrompt,
+	msgChooseDomain:           kindPrompt,
+	msgSendURLForDomain:       kindPrompt,
+	msgSendTitle:              kindPrompt,
+	msgSendPath:               kindPrompt,
+	msgSendNotBefore:          kindPrompt,
+	msgSendSettingsFile:       kindPrompt,
+	msgSendPreviewTitle:       kindPrompt,
+	msgSendPreviewDescription: kindPrompt,
+	msgSendPreviewImage:       kindPrompt,
+	msgConfirmCommand:         kindPrompt,
+
+	msgButtonExpired:           kindNotice,
+	msgQueueOfferExpired:       kindNotice,
+	msgAlreadyQueued:           kindNotice,
+	msgQueueFull:               kindNotice,
+	msgEditedLinkUnchanged:     kindNotice,
+	msgDialogReset:             kindNotice,
+	msgShortenOptionsIgnored:   kindNotice,
+	msgInspectCancelled:        kindNotice,
+	msgSettingsImportCancelled: kindNotice,
+	msgBulkCancelled:           kindNotice,
+	msgSettingsImportUnchanged: kindNotice,
+	msgCampaignUsage:           kindNotice,
+	msgReportUsage:             kindNotice,
+	msgTimezoneUsage:           kindNotice,
+	msgConfirmClosed:           kindNotice,
+}
+
+// deleteScheduler deletes bot messages after a delay. Pending deletions live
+// in memory only and are lost on restart.
+type deleteScheduler struct {
+	mu     sync.Mutex
+	timers map[messageKey]*time.Timer
+}
+
+func newDeleteScheduler() *deleteScheduler {
+	return &deleteScheduler{timers: make(map[messageKey]*time.Timer)}
+}
+
+// Schedule runs fn for the message after delay, replacing an earlier schedule.
+func (s *deleteScheduler) Schedule(key messageKey, delay time.Duration, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.timers[key]; ok {
+		t.Stop()
+	}
+	s.timers[key] = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		delete(s.timers, key)
+		s.mu.Unlock()
+		fn()
+	})
+}
+
+// autoDeleteAfter returns how long the message rendered from the named
+// template stays in the chat, or 0 if it is kept.
+func (b *Bot) autoDeleteAfter(name string) time.Duration {
+	cfg := b.config.AutoDelete
+	if !cfg.Enabled {
+		return 0
+	}
+	switch transientMessages[name] {
+	case kindError:
+		if cfg.Errors {
+			return cfg.After
+		}
+	case kindPrompt:
+		if cfg.Prompts {
+			return cfg.After
+		}
+	case kindNotice:
+		if cfg.Notices {
+			return cfg.After
+		}
+	}
+	return 0
+}
+
+// scheduleDelete deletes sent after the auto-delete delay of its template.
+func (b *Bot) scheduleDelete(name string, sent tgbotapi.Message) {
+	delay := b.autoDeleteAfter(name)
+	if delay <= 0 {
+		return
+	}
+	key := messageKey{sent.Chat.ID, sent.MessageID}
+	b.deletions.Schedule(key, delay, func() {
+		_, err := b.api.Request(tgbotapi.NewDeleteMessage(key.chatID, key.messageID))
+		switch {
+		case err == nil:
+		case isUndeletable(err):
+			// Gone already, or older than Telegram allows bots to delete
+			b.log.Debug("transient message can't be deleted", zap.Int64("chat_id", key.chatID), zap.Error(err))
+		default:
+			b.log.Warn("failed to delete transient message", zap.Int64("chat_id", key.chatID), zap.Error(err))
+		}
+	})
+}
+
+func isUndeletable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "message can't be deleted") ||
+		strings.Contains(msg, "message to delete not found")
+}
+
+// reply sends the message rendered from the named template and schedules it
+// for deletion if it is transient.
+func (b *Bot) reply(chatID int64, name string, data any, opts ...sendOption) error {
+	sent, err := b.send(tgbotapi.NewMessage(chatID, b.render(name, data)), opts...)
+	if err != nil {
+		return err
+	}
+	b.scheduleDelete(name, sent)
+	return nil
+}
+
+// replyWithKeyboard is reply with an inline keyboard.
+func (b *Bot) replyWithKeyboard(chatID int64, name string, data any, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	msg := tgbotapi.NewMessage(chatID, b.render(name, data))
+	msg.ReplyMarkup = keyboard
+	sent, err := b.send(msg)
+	if err != nil {
+		return err
+	}
+	b.scheduleDelete(name, sent)
+	return nil
+}
+
+// replyGRPCError reports a backend error. Admins also get the request ID of
+// the failed call so they can find it in the logs.
+func (b *Bot) replyGRPCError(chatID int64, err error, alias string) error {
+	name, data := grpcErrorTemplate(err, alias)
+	text := b.render(name, data)
+	if id := client.RequestID(err); id != "" && b.isAdmin(chatID) {
+		text += "\n\n" + b.render(msgErrorRef, refData{Ref: shortRequestID(id)})
+	}
+	sent, err := b.send(tgbotapi.NewMessage(chatID, text))
+	if err != nil {
+		return err
+	}
+	b.scheduleDelete(name, sent)
+	return nil
+}
+
+// replyAliasError reports a resolveAlias error.
+func (b *Bot) replyAliasError(chatID int64, err error, command string) error {
+	name, data := b.aliasErrorTemplate(err, command)
+	return b.reply(chatID, name, data)
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// handleAutoShortenCommand turns auto-shortening of a group on or off. Only
+// the group's administrators may do so.
+func (b *Bot) handleAutoShortenCommand(ctx context.Context, r *Request) error {
+	var on bool
+	switch strings.ToLower(strings.TrimSpace(r.Args)) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return b.reply(r.ChatID, msgAutoShortenUsage, nil)
+	}
+
+	isAdmin, err := b.isChatAdmin(r.ChatID, r.UserID)
+	if err != nil {
+		b.log.Error("failed to get chat administrators", zap.Int64("chat_id", r.ChatID), zap.Error(err))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	if !isAdmin {
+		return b.reply(r.ChatID, msgAutoShortenAdminsOnly, nil)
+	}
+
+	err = b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		p.AutoShorten = on
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save auto-shorten setting", zap.Error(err))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	if on {
+		return b.sendMessage(r.ChatID, b.render(msgAutoShortenOn, nil), false)
+	}
+	return b.sendMessage(r.ChatID, b.render(msgAutoShortenOff, nil), false)
+}
+
+// isChatAdmin reports whether userID administers chatID.
+func (b *Bot) isChatAdmin(chatID, userID int64) (bool, error) {
+	admins, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, admin := range admins {
+		if admin.User != nil && admin.User.ID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// addressedToBot reports whether a group message mentions the bot or
+// replies to one of its messages.
+func (b *Bot) addressedToBot(msg *tgbotapi.Message) bool {
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil && strings.EqualFold(reply.From.UserName, b.username) {
+		return true
+	}
+	if b.username == "" {
+		return false
+	}
+	mention := "@" + strings.ToLower(b.username)
+	return strings.Contains(strings.ToLower(msg.Text), mention) ||
+		strings.Contains(strings.ToLower(msg.Caption), mention)
+}
+
+// autoShorten shortens the URLs of a group message on behalf of its author
+// and replies to it with the short links. URLs that can't be shortened are
+// skipped quietly, except for refusals the group should see.
+func (b *Bot) autoShorten(ctx context.Context, msg *tgbotapi.Message) error {
+	if msg.From == nil || msg.From.IsBot {
+		return nil
+	}
+	links, err := b.shortenPostedURLs(ctx, msg, msg.Chat.ID, msg.From.ID, sourceBotMessage)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	return b.replyAutoShortened(msg, links)
+}
+
+// shortenPostedURLs shortens the URLs of a message on behalf of ownerID,
+// without asking anything. Refusals, such as an unsafe URL or a full
+// quota, are sent to noticeChatID; other failures skip the URL quietly.
+func (b *Bot) shortenPostedURLs(ctx context.Context, msg *tgbotapi.Message, noticeChatID, ownerID int64, source string) ([]autoShortenedLink, error) {
+	chatID := msg.Chat.ID
+	var links []autoShortenedLink
+	for _, u := range extractURLs(msg) {
+		if b.isOwnShortURL(u) || b.isBlockedURL(u) || b.isKnownShortener(u) {
+			continue
+		}
+		if ok, err := b.checkURLSafety(ctx, noticeChatID, ownerID, u); !ok {
+			if err != nil {
+				return links, err
+			}
+			continue
+		}
+		if ok, err := b.checkQuota(ctx, noticeChatID, ownerID); !ok {
+			return links, err
+		}
+
+		req := &shortenerv1.CreateLinkRequest{OriginalUrl: u, UserTgId: ownerID, Source: linkSource(source)}
+		// Only non-interactive defaults apply; nobody is asked in a group
+		userPrefs := b.prefs.Get(ownerID)
+		if domain := b.defaultDomain(userPrefs); domain != "" {
+			req.Domain = &domain
+		}
+		req.ExpiresAt = expiresAt(userPrefs.Defaults.Expiry)
+		style := b.aliasStyle(userPrefs)
+		req.AliasStyle = &style
+
+		res, err := b.createLinkWithRetry(ctx, req)
+		if err != nil {
+			b.log.Warn("auto-shortening failed", zap.Int64("chat_id", chatID), zap.Error(err))
+			continue
+		}
+		b.countCreation(ownerID)
+		host := u
+		if parsed, err := url.Parse(u); err == nil && parsed.Hostname() != "" {
+			host = parsed.Hostname()
+		}
+		links = append(links, autoShortenedLink{Host: host, ShortURL: b.shortURLOn(req.GetDomain(), res.GetAlias())})
+	}
+	return links, nil
+}
+
+// replyAutoShortened replies to msg with the short links made of its URLs.
+func (b *Bot) replyAutoShortened(msg *tgbotapi.Message, links []autoShortenedLink) error {
+	text := b.render(msgAutoShortened, autoShortenedData{Links: links})
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.DisableWebPagePreview = true
+	_, err := b.send(reply, replyTo(msg.MessageID), silent(true), b.linkContent())
+	return err
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// isBlockedURL reports whether the URL's host is on the blocklist.
+func (b *Bot) isBlockedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return b.blocklist.Blocked(u.Hostname())
+}
+
+func (b *Bot) handleBlockCommand(ctx context.Context, req *Request) error {
+	pattern := strings.TrimSpace(req.Args)
+	if pattern == "" {
+		return b.reply(req.ChatID, msgBlockUsage, nil)
+	}
+	added, err := b.blocklist.Add(pattern)
+	if err != nil {
+		b.log.Error("failed to add blocklist entry", zap.String("pattern", pattern), zap.Error(err))
+		return b.reply(req.ChatID, msgBlocklistError, errorData{Error: err.Error()})
+	}
+	if !added {
+		return b.reply(req.ChatID, msgBlocklistExists, patternData{Pattern: pattern})
+	}
+	b.log.Info("blocklist entry added", zap.String("pattern", pattern), zap.Int64("admin_id", req.UserID))
+	return b.reply(req.ChatID, msgBlocklistAdded, patternData{Pattern: pattern})
+}
+
+func (b *Bot) handleUnblockCommand(ctx context.Context, req *Request) error {
+	pattern := strings.TrimSpace(req.Args)
+	if pattern == "" {
+		return b.reply(req.ChatID, msgUnblockUsage, nil)
+	}
+	removed, err := b.blocklist.Remove(pattern)
+	if err != nil {
+		b.log.Error("failed to remove blocklist entry", zap.String("pattern", pattern), zap.Error(err))
+		return b.reply(req.ChatID, msgBlocklistError, errorData{Error: err.Error()})
+	}
+	if !removed {
+		return b.reply(req.ChatID, msgBlocklistMissing, patternData{Pattern: pattern})
+	}
+	b.log.Info("blocklist entry removed", zap.String("pattern", pattern), zap.Int64("admin_id", req.UserID))
+	return b.reply(req.ChatID, msgBlocklistRemoved, patternData{Pattern: pattern})
+}
+
+func (b *Bot) handleBlocklistCommand(ctx context.Context, req *Request) error {
+	entries := b.blocklist.Entries()
+	if len(entries) == 0 {
+		return b.reply(req.ChatID, msgBlocklistEmpty, nil)
+	}
+	return b.sendMessage(req.ChatID, b.render(msgBlocklistHeader, nil)+"\n"+strings.Join(entries, "\n"), false)
+}
@@ -2,481 +2,1760 @@ package bot
 
 import (
 	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/audit"
+	"GURLS-Bot/internal/blocklist"
+	"GURLS-Bot/internal/broadcast"
 	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/features"
 	"GURLS-Bot/internal/grpc/client"
+	"GURLS-Bot/internal/keyboards"
+	"GURLS-Bot/internal/maintenance"
+	"GURLS-Bot/internal/monitor"
+	"GURLS-Bot/internal/outbox"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/reconcile"
+	"GURLS-Bot/internal/reports"
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/ttlmap"
+	"GURLS-Bot/internal/urlcheck"
+	"GURLS-Bot/internal/usage"
+	"GURLS-Bot/internal/users"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// Bot message constants
+// Callback data constants
 const (
-	msgHelp = `URL Shortener Bot
-
-Create and manage short links efficiently.
-Select an action below:`
-	msgUseShortenCommand         = "Send a URL to create a short link or use the buttons below:"
-	msgInvalidShortenFormat      = "Invalid format. Please send a valid URL (e.g., https://example.com)"
-	msgLinkSuccessfullyShortened = "Link created successfully.\n\nShort URL: %s"
-	msgLinkStats                 = "Link Statistics: %s%s\n\nOriginal URL: %s\nTotal Clicks: %d\nExpires: %s%s"
-	msgUnknownCommand            = "Unknown command. Use /start to see available options."
-	msgInvalidCommandFormat      = "Invalid command format. Use: /%s <alias>"
-	msgLinkNotFound              = "Link with alias '%s' not found."
-	msgInternalError             = "Internal error occurred. Please try again later."
-	msgLinkDeleted               = "Link '%s' has been deleted successfully."
-	msgMyLinksHeader             = "Your Links:"
-	msgNoLinks                   = "You have no links yet.\nCreate your first link!"
-	msgAliasTaken                = "Alias '%s' is already taken. Please choose another one."
-
-	// Callback data constants
-	callbackCreateLink   = "create_link"
-	callbackMyLinks      = "my_links"  
-	callbackHelp         = "help"
-	callbackCancel       = "cancel"
-	callbackCustomAlias  = "custom_alias"
-
-	// Additional messages
-	msgSendCustomAlias   = "Send your custom alias (letters, numbers, hyphens only):"
-	msgSendUrlWithAlias  = "Now send the URL you want to shorten with alias '%s':"
+	callbackCreateLink             = "create_link"
+	callbackMyLinks                = "my_links"
+	callbackHelp                   = "help"
+	callbackCancel                 = "cancel"
+	callbackCustomAlias            = "custom_alias"
+	callbackScheduleLink           = "schedule_link"
+	callbackQueueLink              = "queue_link"
+	callbackUTM                    = "utm"
+	callbackUTMSkip                = "utm_skip"
+	callbackUTMCreate              = "utm_create"
+	callbackSettings               = "settings"
+	callbackToggleAutoTitle        = "toggle_auto_title"
+	callbackToggleAskExpiry        = "toggle_ask_expiry"
+	callbackToggleSound            = "toggle_sound"
+	callbackChooseDomain           = "choose_domain"
+	callbackForgetMe               = "forget_me"
+	callbackToggleLinkStyle        = "toggle_link_style"
+	callbackToggleCleanup          = "toggle_cleanup"
+	callbackCleanupDeleteAll       = "cleanup_delete_all"
+	callbackCleanupCancel          = "cleanup_cancel"
+	callbackToggleConfirm          = "toggle_confirm"
+	callbackToggleQuickActions     = "toggle_quick_actions"
+	callbackTogglePreview          = "toggle_preview"
+	callbackToggleMinimalAnalytics = "toggle_minimal_analytics"
+	callbackTogglePlainOutput      = "toggle_plain_output"
+	callbackCycleLanguage          = "cycle_language"
+	callbackCycleAliasStyle        = "cycle_alias_style"
+	callbackExpiring               = "expiring"
+	callbackClearHistory           = "clear_history"
+	callbackClearHistoryConfirm    = "clear_history_confirm"
+	callbackInspectCancel          = "inspect_cancel"
+	callbackImportCancel           = "import_cancel"
+	callbackCampaignDone           = "campaign_done"
+	callbackBulkCancel             = "bulk_cancel"
+	callbackPreviewSkip            = "preview_skip"
+
+	// Callback actions carrying a payload, see encodeCallbackData
+	actionStats            = "st"
+	actionDelete           = "dl"
+	actionListDelete       = "ld"
+	actionShortenURL       = "su"
+	actionForceLink        = "fl"
+	actionFollowLink       = "fr"
+	actionUTMValue         = "uv"
+	actionPin              = "pn"
+	actionUnpin            = "up"
+	actionDefaultExpiry    = "de"
+	actionPickExpiry       = "pe"
+	actionPickDomain       = "pd"
+	actionDefaultDomain    = "dd"
+	actionCleanupKeep      = "ck"
+	actionCleanupDelete    = "cx"
+	actionCleanupDeleteAll = "ca"
+	actionRename           = "rn"
+	actionConfirmShorten   = "cs"
+	actionShortenOptions   = "so"
+	actionShortenTitled    = "sf"
+	actionCreatePreviewed  = "cp"
+	actionMyLinksPage      = "mp"
+	actionIgnoreURL        = "iu"
+	actionCopyText         = "ct"
+	actionSnippetStyle     = "ss"
+	actionSnapshot         = "sn"
+	actionReplaceSnapshot  = "rs"
+	actionCompareSnapshot  = "cm"
+	actionExtend           = "ex"
+	actionExtendBy         = "eb"
+	actionHistoryPage      = "hp"
+	actionRefreshStats     = "rf"
+	actionCancelBroadcast  = "cb"
+	actionMonitor          = "mo"
+	actionUnmonitor        = "um"
+	actionNewDestination   = "nd"
+	actionUseRedirect      = "ur"
+	actionDisableLink      = "di"
+	actionToggleAnalytics  = "ta"
+	actionTransfer         = "tf"
+	actionTransferAccept   = "xa"
+	actionTransferDecline  = "xd"
+	actionInspectAsk       = "ia"
+	actionInspectDo        = "id"
+	actionReportInspect    = "ri"
+	actionAddTitle         = "at"
+	actionSetExpiry        = "sx"
+	actionSetExpiryTo      = "sy"
+	actionShortenFrom      = "sa"
+	actionImportSettings   = "is"
+	actionCheckNow         = "cn"
+	actionCampaignReport   = "cr"
+	actionCampaignPick     = "cg"
+	actionCampaignToggle   = "cq"
+	actionBulkActions      = "ba"
+	actionBulkAsk          = "bq"
+	actionBulkRun          = "br"
+	actionEditPreview      = "pv"
+	actionRemovePreview    = "pr"
+	actionCompareWith      = "cw"
+	actionCompareLinks     = "cl"
+	actionPeek             = "pk"
+	actionApproveCommand   = "ac"
+	actionRejectCommand    = "rc"
 )
 
 var (
-	urlRegex       = regexp.MustCompile(`https?://\S+`)
-	titleRegex     = regexp.MustCompile(`title="([^"]+)"`)
-	expiresInRegex = regexp.MustCompile(`expires_in=([\w\d]+)`)
-	aliasRegex     = regexp.MustCompile(`alias=([\w\-]+)`)
-	customAliasRegex = regexp.MustCompile(`^[a-zA-Z0-9\-]{1,20}$`)
+	urlRegex      = regexp.MustCompile(`https?://\S+`)
+	utmValueRegex = regexp.MustCompile(`^[\w\-.+]{1,100}$`)
 )
 
-// User state management
-type UserState struct {
-	State       string
-	CustomAlias string
-}
+// userStateTTL is how long an unfinished dialog, such as the create wizard,
+// waits for the user's next message.
+const userStateTTL = 24 * time.Hour
 
-const (
-	StateNormal           = "normal"
-	StateWaitingForAlias  = "waiting_for_alias"
-	StateWaitingForURL    = "waiting_for_url"
-)
+// telegramAPI is the part of the Telegram Bot API the bot uses. It is
+// implemented by *tgbotapi.BotAPI and by the console used in dry-run mode.
+type telegramAPI interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+	GetChatAdministrators(config tgbotapi.ChatAdministratorsConfig) ([]tgbotapi.ChatMember, error)
+	GetFileDirectURL(fileID string) (string, error)
+	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	StopReceivingUpdates()
+}
 
 type Bot struct {
-	api        *tgbotapi.BotAPI
-	log        *zap.Logger
-	config     *config.Config
-	grpcClient *client.BackendClient
-	userStates map[int64]*UserState
+	api            telegramAPI
+	log            *zap.Logger
+	config         *config.Config
+	grpcClient     *client.BackendClient
+	userStates     *ttlmap.Map[int64, UserState]
+	createQueue    *createQueue
+	pendingQueue   *ttlmap.Map[int64, *queuedLink]
+	payloads       *payloadStore
+	router         *Router
+	recentMessages *ttlmap.Map[messageKey, messageOutcome]
+	urlChecker     urlcheck.Checker
+	abuse          *abuseTracker
+	blocklist      *blocklist.List
+	dailyCreations *dailyCounter
+	utmDefaults    *utmDefaults
+	recentLinks    *recentLinks
+	linkLists      *linkLists
+	linkStats      *linkStats
+	healthBadges   *healthBadges
+	bulkJobs       *bulkJobs
+	seenUpdates    *updateDeduper
+	prefs          *prefs.Store
+	users          *users.Store
+	connectPolls   *connectPolls
+	deletions      *deleteScheduler
+	messages       *messageTemplates
+	backendMonitor *backendMonitor
+	capabilities   *capabilities
+	sendQueue      *sendQueue
+	timings        *activeTimings
+	broadcasts     *broadcast.Store
+	monitors       *monitor.Store
+	transfers      *ttlmap.Map[string, *transferOffer]
+	confirmations  *ttlmap.Map[string, *pendingCommand]
+	replyOptions   *ttlmap.Map[int64, replyOptions]
+	keyboards      *keyboards.Store
+	reconciliation *reconcile.Store
+	audit          *audit.Log
+	outbox         *outbox.Store
+	abuseReports   *reports.Store
+	maintenance    *maintenance.Store
+	// usage counts command and button usage; nil unless Usage.Enabled
+	usage    *usage.Store
+	features features.Set
+	// tokens watches the Bot API token; nil in dry-run mode
+	tokens *tokenClient
+	// polls watches the getUpdates long polls; nil in dry-run mode
+	polls *pollClient
+	// lastUpdate is when the last update came in, in Unix nanoseconds
+	lastUpdate atomic.Int64
+	// broadcastWake signals the broadcast worker that a job was added
+	broadcastWake chan struct{}
+	// backendAliasRules are the alias rules reported by the backend, if any
+	backendAliasRules atomic.Pointer[aliasRules]
+	// username is the bot's Telegram username, used to spot mentions
+	username string
+	// db is what the stores above keep their state in; Run closes it
+	db store.Store
 }
 
 func New(cfg *config.Config, log *zap.Logger, grpcClient *client.BackendClient) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(cfg.Telegram.Token)
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.Telegram.Token, cfg.Telegram.APIEndpoint)
 	if err != nil {
+		if classifyTelegramError(err) == telegramErrorUnauthorized {
+			return nil, fmt.Errorf("%w: %v", ErrTokenRevoked, err)
+		}
 		return nil, err
 	}
+	polls := newPollClient(api.Client)
+	tokens := newTokenClient(legacyForwardClient{next: polls}, cfg.Telegram.Token)
+	api.Client = tokens
 	log.Info("authorized on account", zap.String("username", api.Self.UserName))
-	return &Bot{
-		api:        api, 
-		log:        log, 
-		config:     cfg, 
-		grpcClient: grpcClient,
-		userStates: make(map[int64]*UserState),
-	}, nil
-}
-
-func (b *Bot) Start(ctx context.Context) {
-	b.log.Info("starting bot")
-	updates := b.getUpdatesChannel()
-	go func() {
+	b, err := newBot(api, api.Self.UserName, cfg, log, grpcClient)
+	if err != nil {
+		return nil, err
+	}
+	b.tokens = tokens
+	b.polls = polls
+	return b, nil
+}
+
+// newBot creates a bot talking to Telegram through api.
+func newBot(api telegramAPI, username string, cfg *config.Config, log *zap.Logger, grpcClient *client.BackendClient) (b *Bot, err error) {
+	if !slices.Contains(aliasStyles, cfg.Links.AliasStyle) {
+		return nil, fmt.Errorf("invalid alias style %q, use one of %v", cfg.Links.AliasStyle, aliasStyles)
+	}
+	opened := time.Now()
+	db, err := openStore(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
+
+	queue, err := newCreateQueue(cfg.Queue, db)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := blocklist.New(cfg.Blocklist.Hosts, cfg.Blocklist.File)
+	if err != nil {
+		return nil, err
+	}
+
+	userPrefs, err := prefs.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := users.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcasts, err := broadcast.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	monitors, err := monitor.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked, err := keyboards.Open(db, cfg.Keyboards.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation, err := reconcile.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog, err := audit.Open(cfg.Audit.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := outbox.Open(db, cfg.Outbox.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	abuseReports, err := reports.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := maintenance.Open(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var usageRollups *usage.Store
+	if cfg.Usage.Enabled {
+		// Days are counted in the server's time zone
+		usageRollups, err = usage.Open(db, time.Local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	abuse, err := newAbuseTracker(cfg.SafeBrowsing.BanAfter, db)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyCreations, err := newDailyCounter(db)
+	if err != nil {
+		return nil, err
+	}
+
+	logQuarantined(db, opened, log)
+
+	messages, err := newMessageTemplates(cfg.Messages.TemplateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	b = &Bot{
+		api:            api,
+		log:            log,
+		config:         cfg,
+		grpcClient:     grpcClient,
+		userStates:     ttlmap.New[int64, UserState](userStateTTL, 0),
+		createQueue:    queue,
+		pendingQueue:   ttlmap.New[int64, *queuedLink](cfg.Queue.OfferTTL, maxQueueOffers),
+		payloads:       newPayloadStore(payloadTTL),
+		recentMessages: ttlmap.New[messageKey, messageOutcome](cfg.Telegram.EditMaxAge, 0),
+		abuse:          abuse,
+		blocklist:      blocked,
+		dailyCreations: dailyCreations,
+		utmDefaults:    newUTMDefaults(),
+		recentLinks:    newRecentLinks(cfg.Telegram.DedupWindow),
+		linkLists:      newLinkLists(inlineLinksTTL),
+		linkStats:      newLinkStats(linkStatsTTL),
+		healthBadges:   newHealthBadges(healthBadgesTTL),
+		bulkJobs:       newBulkJobs(),
+		seenUpdates:    newUpdateDeduper(maxSeenUpdateIDs),
+		prefs:          userPrefs,
+		users:          registry,
+		connectPolls:   newConnectPolls(),
+		deletions:      newDeleteScheduler(),
+		messages:       messages,
+		backendMonitor: newBackendMonitor(cfg.Telegram.BackendAlertAfter),
+		capabilities:   newCapabilities(),
+		sendQueue:      newSendQueue(cfg.SendQueue.Size, cfg.SendQueue.Rate, cfg.SendQueue.Retries, cfg.SendQueue.MaxRetryWait, log),
+		timings:        newActiveTimings(),
+		broadcasts:     broadcasts,
+		broadcastWake:  make(chan struct{}, 1),
+		monitors:       monitors,
+		transfers:      ttlmap.New[string, *transferOffer](cfg.Transfer.OfferTTL, 0),
+		confirmations:  ttlmap.New[string, *pendingCommand](cfg.Confirm.Timeout+cfg.Confirm.ApprovalTimeout, 0),
+		replyOptions:   ttlmap.New[int64, replyOptions](payloadTTL, 0),
+		keyboards:      tracked,
+		reconciliation: reconciliation,
+		audit:          auditLog,
+		outbox:         notifications,
+		abuseReports:   abuseReports,
+		maintenance:    window,
+		usage:          usageRollups,
+		features:       features.New(cfg.Features),
+		username:       username,
+		db:             db,
+	}
+	if grpcClient != nil {
+		grpcClient.Observe(b.observeBackend)
+		grpcClient.RefuseMutations(b.refuseMutations)
+	}
+	if cfg.SafeBrowsing.Enabled && b.features.Enabled(features.SafeBrowsing) {
+		b.urlChecker = urlcheck.NewCached(urlcheck.NewSafeBrowsing(cfg.SafeBrowsing.APIKey), cfg.SafeBrowsing.CacheTTL)
+	}
+	b.router = b.newRouter()
+	if err := b.checkConfirmConfig(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Run receives and handles updates along with the bot's background jobs
+// until ctx is done or updates run out, then waits for the jobs to stop.
+func (b *Bot) Run(ctx context.Context) error {
+	b.log.Info("starting bot", zap.Stringer("features", b.features))
+	defer func() {
+		// Last, once the jobs flushed their stores on their way out
+		if err := b.db.Close(); err != nil {
+			b.log.Error("failed to close the store", zap.Error(err))
+		}
+	}()
+	if b.grpcClient != nil {
+		b.probeCapabilities(ctx)
+		b.logCapabilities()
+		b.loadAliasRules(ctx)
+	}
+	b.publishCommands()
+	b.notifyAdmins(b.startupNotice())
+	defer func() {
+		// Best effort; a crash never gets here
+		b.notifyAdmins(b.render(msgAdminStopping, nil))
+	}()
+	if b.grpcClient != nil && b.config.SelfTest.OnStartup {
+		if err := b.startupSelfTest(ctx); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		b.runCreateQueue(ctx)
+		return nil
+	})
+	if b.tokens != nil {
+		g.Go(func() error {
+			return b.watchToken(ctx)
+		})
+	}
+	if b.polls != nil {
+		g.Go(func() error {
+			b.watchUpdates(ctx)
+			return nil
+		})
+	}
+	if b.grpcClient != nil {
+		g.Go(func() error {
+			b.runCapabilityProbes(ctx)
+			return nil
+		})
+	}
+	g.Go(func() error {
+		b.users.Run(ctx, b.config.Users.FlushInterval, func(err error) {
+			b.log.Error("failed to save user registry", zap.Error(err))
+		})
+		return nil
+	})
+	g.Go(func() error {
+		b.keyboards.Run(ctx, b.config.Keyboards.Interval, func(err error) {
+			b.log.Error("failed to save tracked keyboards", zap.Error(err))
+		})
+		return nil
+	})
+	g.Go(func() error {
+		b.runKeyboardCleanup(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		b.runCleanupSuggestions(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		b.runBroadcasts(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		b.runOutbox(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		b.runMaintenance(ctx)
+		return nil
+	})
+	if b.usage != nil {
+		g.Go(func() error {
+			b.usage.Run(ctx, b.config.Usage.FlushInterval, b.config.Usage.RetentionDays, func(err error) {
+				b.log.Error("failed to save usage rollups", zap.Error(err))
+			})
+			return nil
+		})
+		if b.config.Usage.SummaryInterval > 0 {
+			g.Go(func() error {
+				b.runUsageSummaries(ctx)
+				return nil
+			})
+		}
+	}
+	if b.grpcClient != nil && b.features.Enabled(features.Monitor) {
+		g.Go(func() error {
+			b.runDestinationChecks(ctx)
+			return nil
+		})
+	}
+	if b.grpcClient != nil && b.config.Reconcile.Enabled {
+		g.Go(func() error {
+			b.runReconciliation(ctx)
+			return nil
+		})
+	}
+	for name, cache := range b.caches() {
+		g.Go(func() error {
+			cache.Run(ctx, b.config.Caches.SweepInterval, b.cacheSwept(name))
+			return nil
+		})
+	}
+	g.Go(func() error {
+		updates := b.getUpdatesChannel()
 		for {
 			select {
 			case <-ctx.Done():
 				b.log.Info("stopping bot...")
 				b.api.StopReceivingUpdates()
-				return
-			case update := <-updates:
-				b.processUpdate(update)
+				return nil
+			case update, ok := <-updates:
+				if !ok {
+					b.log.Info("no more updates, stopping bot...")
+					cancel()
+					return nil
+				}
+				b.updatesReceived()
+				b.processUpdate(ctx, update)
 			}
 		}
-	}()
+	})
+	err := g.Wait()
+	// Bulk jobs stop early once ctx is done; the db stays open until they have
+	b.bulkJobs.Wait()
+	return err
 }
 
-func (b *Bot) processUpdate(update tgbotapi.Update) {
+func (b *Bot) processUpdate(ctx context.Context, update tgbotapi.Update) {
+	if !b.seenUpdates.First(update.UpdateID) {
+		b.log.Debug("skipping redelivered update", zap.Int("update_id", update.UpdateID))
+		return
+	}
+
+	b.touchUser(update)
+
+	// All backend calls made for this update share one request ID
+	ctx = client.WithRequestID(ctx, client.NewRequestID())
+
 	if update.CallbackQuery != nil {
-		if err := b.handleCallbackQuery(update.CallbackQuery); err != nil {
-			b.log.Error("failed to handle callback query", zap.Error(err))
+		b.logOutcome(b.handleCallbackQuery(ctx, update.CallbackQuery), "callback query")
+		return
+	}
+
+	if update.InlineQuery != nil {
+		if err := b.handleInlineQuery(ctx, update.InlineQuery); err != nil {
+			b.log.Error("failed to answer inline query", zap.Error(err))
 		}
 		return
 	}
-	
-	if update.Message == nil {
+
+	if update.ChannelPost != nil {
+		b.logOutcome(outcomeOf(b.handleChannelPost(ctx, update.ChannelPost)), "channel post")
 		return
 	}
-	
-	if update.Message.IsCommand() {
-		if err := b.handleCommand(update.Message); err != nil {
-			b.log.Error("failed to handle command", zap.String("command", update.Message.Command()), zap.Error(err))
-		}
+
+	if update.EditedMessage != nil {
+		b.logOutcome(outcomeOf(b.handleEditedMessage(ctx, update.EditedMessage)), "edited message")
+		return
+	}
+
+	if update.Message == nil {
 		return
 	}
-	
-	if err := b.handleMessage(update.Message); err != nil {
-		b.log.Error("failed to handle message", zap.Error(err))
+
+	if update.Message.IsCommand() && !b.takesPath(update.Message) {
+		b.logOutcome(b.handleCommand(ctx, update.Message), "command", zap.String("command", update.Message.Command()))
+		return
 	}
+
+	b.logOutcome(outcomeOf(b.handleMessage(ctx, update.Message)), "message")
 }
 
-func (b *Bot) handleCommand(msg *tgbotapi.Message) error {
-	switch msg.Command() {
-	case "start":
-		return b.sendMessageWithKeyboard(msg.Chat.ID, msgHelp, b.createMainKeyboard())
-	case "shorten":
-		return b.handleShortenCommand(msg.Chat.ID, msg.CommandArguments())
-	case "stats":
-		return b.handleStatsCommand(msg.Chat.ID, msg.CommandArguments())
-	case "delete":
-		return b.handleDeleteCommand(msg.Chat.ID, msg.CommandArguments())
-	case "my_links":
-		return b.handleMyLinksCommand(msg.Chat.ID)
-	default:
-		return b.sendMessage(msg.Chat.ID, msgUnknownCommand, false)
+// newRouter registers all commands and callbacks.
+func (b *Bot) newRouter() *Router {
+	r := NewRouter()
+	r.Use(b.recoverMiddleware, b.latencyMiddleware, b.logMiddleware, b.accessMiddleware, b.confirmMiddleware, b.usageMiddleware)
+
+	r.Command("start", func(ctx context.Context, req *Request) error {
+		return b.handleStartCommand(ctx, req)
+	}, describe("Main menu"))
+	r.Command("shorten", func(ctx context.Context, req *Request) error {
+		if strings.TrimSpace(req.Args) == "" && req.Message.ReplyToMessage != nil {
+			return b.shortenReplied(ctx, req.Payloads(), req.UserID, req.Message.ReplyToMessage)
+		}
+		return b.handleShortenCommand(ctx, req.Payloads(), req.Args)
+	}, describe("Shorten a URL"), mutates())
+	r.Command("stats", func(ctx context.Context, req *Request) error {
+		return b.handleStatsCommand(ctx, req.Payloads(), req.Args)
+	}, describe("Statistics of a link"))
+	r.Command("compare", b.handleCompareCommand, describe("Compare the stats of two links"))
+	r.Command("delete", func(ctx context.Context, req *Request) error {
+		return b.handleDeleteCommand(ctx, req.ChatID, req.Args)
+	}, describe("Delete a link"), mutates())
+	r.Command("expand", func(ctx context.Context, req *Request) error {
+		return b.handleExpandCommand(ctx, req.ChatID, req.Args)
+	}, rateLimit(expandRateLimit, expandRateLimitWindow), needs(featureExpand), describe("Show where a short link leads"))
+	r.Command("settings", func(ctx context.Context, req *Request) error {
+		return b.handleSettings(req.Payloads(), 0)
+	}, describe("Link creation defaults"))
+	r.Command("connect", b.handleConnectCommand, privateOnly(), needs(featureDashboard), describe("Log in to the web dashboard"))
+	r.Command("disconnect", b.handleDisconnectCommand, privateOnly(), needs(featureDashboard), describe("Unlink the web dashboard"))
+	r.Command("forget_me", func(ctx context.Context, req *Request) error {
+		return b.sendMessageWithKeyboard(req.ChatID, b.render(msgForgetMeConfirm, nil), b.createForgetMeKeyboard())
+	}, privateOnly(), describe("Delete your data"))
+	r.Command("my_links", func(ctx context.Context, req *Request) error {
+		return b.handleMyLinksCommand(ctx, req.Payloads())
+	}, describe("List your links"))
+	r.Command("expiring", func(ctx context.Context, req *Request) error {
+		return b.handleExpiringCommand(ctx, req.Payloads())
+	}, describe("List your links expiring soon"))
+	r.Command("timezone", b.handleTimezoneCommand, describe("Time zone for dates"))
+	r.Command("export_settings", b.handleExportSettingsCommand, privateOnly(), describe("Save your settings to a file"))
+	r.Command("import_settings", b.handleImportSettingsCommand, privateOnly(), describe("Load settings from a file"))
+	r.Command("campaign", b.handleCampaignCommand, describe("Group links and report on them"))
+	r.Command("history", func(ctx context.Context, req *Request) error {
+		return b.handleHistoryCommand(ctx, req.Payloads())
+	}, describe("Your recent actions"))
+	r.Command("autoshorten", b.handleAutoShortenCommand, groupOnly(), describe("Shorten every URL posted here"))
+	r.Command("channel", b.handleChannelCommand, describe("Shorten the URLs posted in a channel"))
+	r.Command("block", b.handleBlockCommand, adminOnly(), describe("Block a domain"))
+	r.Command("unblock", b.handleUnblockCommand, adminOnly(), describe("Unblock a domain"))
+	r.Command("blocklist", b.handleBlocklistCommand, adminOnly(), describe("Show blocked domains"))
+	r.Command("ping", b.handlePingCommand, adminOnly(), describe("Backend health"))
+	r.Command("version", b.handleVersionCommand, adminOnly(), describe("Build and feature flags"))
+	r.Command("admin_stats", b.handleAdminStatsCommand, adminOnly(), describe("Handler latency"))
+	r.Command("broadcast", b.handleBroadcastCommand, adminOnly(), describe("Send a message to all users"))
+	r.Command("selftest", b.handleSelfTestCommand, adminOnly(), describe("Create, read and delete a test link"))
+	r.Command("maintenance", b.handleMaintenanceCommand, adminOnly(), describe("Plan or end backend maintenance"))
+	r.Command("inspect", b.handleInspectCommand, adminOnly(), describe("Look up any link for an abuse report"))
+	r.Command("report", b.handleReportCommand, rateLimit(reportRateLimit, reportRateLimitWindow), describe("Report a malicious short link"))
+	r.UnknownCommand(func(ctx context.Context, req *Request) error {
+		return b.reply(req.ChatID, msgUnknownCommand, nil)
+	})
+
+	r.Callback(callbackCreateLink, func(ctx context.Context, req *Request) error {
+		return b.promptNewLink(req.ChatID)
+	}, mutates())
+	r.Callback(callbackMyLinks, func(ctx context.Context, req *Request) error {
+		return b.handleMyLinksCommand(ctx, req.Payloads())
+	})
+	r.Callback(actionMyLinksPage, func(ctx context.Context, req *Request) error {
+		return b.showMyLinksPage(ctx, req)
+	})
+	r.Callback(callbackExpiring, func(ctx context.Context, req *Request) error {
+		return b.handleExpiringCommand(ctx, req.Payloads())
+	})
+	r.Callback(actionExtend, func(ctx context.Context, req *Request) error {
+		return b.handleExtend(ctx, req)
+	}, needs(featureExtend), mutates())
+	r.Callback(actionAddTitle, func(ctx context.Context, req *Request) error {
+		return b.startAddTitle(req)
+	}, needs(featureTitleUpdate), onExpired(b.linkShortcutExpired), mutates())
+	r.Callback(actionSetExpiry, func(ctx context.Context, req *Request) error {
+		return b.offerExpiry(req)
+	}, needs(featureExtend), onExpired(b.linkShortcutExpired), mutates())
+	r.Callback(actionSetExpiryTo, b.setExpiry, needs(featureExtend), onExpired(b.linkShortcutExpired), mutates())
+	r.Callback(actionShortenFrom, func(ctx context.Context, req *Request) error {
+		return b.startShortenFrom(req)
+	}, onExpired(b.shortenFromExpired), mutates())
+	r.Callback(actionExtendBy, func(ctx context.Context, req *Request) error {
+		return b.handleExtendBy(ctx, req)
+	}, needs(featureExtend), mutates())
+	r.Callback(actionBulkActions, func(ctx context.Context, req *Request) error {
+		return b.showBulkActions(req)
+	})
+	r.Callback(actionBulkAsk, func(ctx context.Context, req *Request) error {
+		return b.confirmBulkAction(req)
+	}, mutates())
+	r.Callback(actionBulkRun, b.runBulkAction, mutates())
+	r.Callback(callbackBulkCancel, func(ctx context.Context, req *Request) error {
+		return b.editMessageText(req.ChatID, req.Message.MessageID, b.render(msgBulkCancelled, nil))
+	})
+	r.Callback(callbackHelp, func(ctx context.Context, req *Request) error {
+		return b.sendMessageWithKeyboard(req.ChatID, b.mainMenuText(req.ChatID), b.createMainKeyboard())
+	})
+	r.Callback(actionStats, func(ctx context.Context, req *Request) error {
+		return b.showStats(ctx, req.Payloads(), req.Args, req.Answer)
+	})
+	r.Callback(actionRefreshStats, b.refreshStats)
+	r.Callback(actionPeek, b.peekLink)
+	r.Callback(actionCancelBroadcast, func(ctx context.Context, req *Request) error {
+		return b.cancelBroadcast(req)
+	}, adminOnly())
+	r.Callback(actionInspectAsk, func(ctx context.Context, req *Request) error {
+		return b.confirmInspectAction(req)
+	}, adminOnly())
+	r.Callback(actionInspectDo, b.runInspectAction, adminOnly())
+	r.Callback(actionReportInspect, b.inspectReported, adminOnly())
+	r.Callback(actionApproveCommand, b.approveCommand, adminOnly())
+	r.Callback(actionRejectCommand, func(ctx context.Context, req *Request) error {
+		return b.rejectCommand(req)
+	}, adminOnly())
+	r.Callback(actionImportSettings, func(ctx context.Context, req *Request) error {
+		return b.importSettings(req)
+	})
+	r.Callback(actionCampaignReport, func(ctx context.Context, req *Request) error {
+		return b.reportCampaign(ctx, req.ChatID, req.Args)
+	})
+	r.Callback(actionCampaignPick, func(ctx context.Context, req *Request) error {
+		return b.pickCampaign(req)
+	})
+	r.Callback(actionCampaignToggle, func(ctx context.Context, req *Request) error {
+		return b.toggleCampaign(req)
+	})
+	r.Callback(callbackCampaignDone, func(ctx context.Context, req *Request) error {
+		b.dropKeyboard(req.ChatID, req.Message.MessageID)
+		return nil
+	})
+	r.Callback(callbackImportCancel, func(ctx context.Context, req *Request) error {
+		return b.editMessageText(req.ChatID, req.Message.MessageID, b.render(msgSettingsImportCancelled, nil))
+	})
+	r.Callback(callbackInspectCancel, func(ctx context.Context, req *Request) error {
+		return b.editMessageText(req.ChatID, req.Message.MessageID, b.render(msgInspectCancelled, nil))
+	}, adminOnly())
+	r.Callback(actionListDelete, func(ctx context.Context, req *Request) error {
+		return b.deleteFromMyLinks(ctx, req.Payloads(), req.Message.MessageID, req.Args, req.Answer)
+	}, mutates())
+	r.Callback(actionDelete, func(ctx context.Context, req *Request) error {
+		return b.deleteLink(ctx, req.ChatID, req.Args, req.Answer)
+	}, mutates())
+	r.Callback(actionConfirmShorten, func(ctx context.Context, req *Request) error {
+		return b.handleConfirmShorten(ctx, req)
+	}, mutates())
+	r.Callback(actionShortenOptions, func(ctx context.Context, req *Request) error {
+		return b.handleShortenOptions(req)
+	}, mutates())
+	r.Callback(actionCreatePreviewed, func(ctx context.Context, req *Request) error {
+		return b.handleCreatePreviewed(ctx, req)
+	}, mutates())
+	r.Callback(actionShortenTitled, func(ctx context.Context, req *Request) error {
+		return b.handleShortenTitled(ctx, req)
+	}, mutates())
+	r.Callback(actionIgnoreURL, func(ctx context.Context, req *Request) error {
+		return b.handleIgnoreURL(req)
+	})
+	r.Callback(actionCopyText, func(ctx context.Context, req *Request) error {
+		return b.showSnippet(ctx, req.Payloads(), 0, req.Args, snippetStyles[0].Name, req.Answer)
+	})
+	r.Callback(actionSnippetStyle, func(ctx context.Context, req *Request) error {
+		return b.handleSnippetStyle(ctx, req)
+	})
+	r.Callback(actionSnapshot, func(ctx context.Context, req *Request) error {
+		return b.takeSnapshot(ctx, req, true)
+	})
+	r.Callback(actionReplaceSnapshot, func(ctx context.Context, req *Request) error {
+		return b.takeSnapshot(ctx, req, false)
+	})
+	r.Callback(actionCompareSnapshot, func(ctx context.Context, req *Request) error {
+		return b.compareToSnapshot(ctx, req)
+	})
+	r.Callback(actionCompareWith, func(ctx context.Context, req *Request) error {
+		return b.startCompare(ctx, req)
+	})
+	r.Callback(actionCompareLinks, b.compareRecent)
+	r.Callback(actionRename, func(ctx context.Context, req *Request) error {
+		return b.startRename(req.ChatID, req.Args)
+	}, needs(featureRename), mutates())
+	r.Callback(actionHistoryPage, func(ctx context.Context, req *Request) error {
+		return b.showHistoryPage(ctx, req)
+	})
+	r.Callback(callbackClearHistory, func(ctx context.Context, req *Request) error {
+		return b.sendMessageWithKeyboard(req.ChatID, b.render(msgClearHistoryConfirm, nil), b.createClearHistoryKeyboard())
+	})
+	r.Callback(callbackClearHistoryConfirm, func(ctx context.Context, req *Request) error {
+		return b.clearHistory(req)
+	})
+	r.Callback(callbackForgetMe, func(ctx context.Context, req *Request) error {
+		return b.forgetUser(req.UserID, req.ChatID, req.Message.MessageID)
+	})
+	r.Callback(callbackSettings, func(ctx context.Context, req *Request) error {
+		return b.handleSettings(req.Payloads(), 0)
+	})
+	r.Callback(actionDefaultExpiry, func(ctx context.Context, req *Request) error {
+		expiry, err := parseExpiry(req.Args)
+		if err != nil {
+			req.Answer.alert(b.render(msgButtonExpired, nil))
+			return nil
+		}
+		return b.updateDefaults(req, "default expiry", func(d *prefs.CreationDefaults) error {
+			d.Expiry = expiry
+			return nil
+		})
+	})
+	r.Callback(callbackToggleAutoTitle, func(ctx context.Context, req *Request) error {
+		return b.updateDefaults(req, "auto title", func(d *prefs.CreationDefaults) error {
+			d.AutoTitle = !d.AutoTitle
+			return nil
+		})
+	})
+	r.Callback(callbackToggleAskExpiry, func(ctx context.Context, req *Request) error {
+		return b.updateDefaults(req, "ask for expiry", func(d *prefs.CreationDefaults) error {
+			d.AskExpiry = !d.AskExpiry
+			return nil
+		})
+	})
+	r.Callback(callbackTogglePreview, func(ctx context.Context, req *Request) error {
+		return b.updateDefaults(req, "preview before create", func(d *prefs.CreationDefaults) error {
+			d.Preview = !d.Preview
+			return nil
+		})
+	})
+	r.Callback(callbackToggleMinimalAnalytics, func(ctx context.Context, req *Request) error {
+		return b.updateDefaults(req, "minimal analytics", func(d *prefs.CreationDefaults) error {
+			d.MinimalAnalytics = !d.MinimalAnalytics
+			return nil
+		})
+	})
+	r.Callback(callbackChooseDomain, func(ctx context.Context, req *Request) error {
+		return b.showDomainPicker(req.Payloads())
+	})
+	r.Callback(actionPickDomain, func(ctx context.Context, req *Request) error {
+		return b.pickDomain(req.ChatID, req.Args, req.Answer)
+	})
+	r.Callback(actionDefaultDomain, func(ctx context.Context, req *Request) error {
+		if _, ok := b.findDomain(req.Args); !ok {
+			req.Answer.alert(b.render(msgButtonExpired, nil))
+			return nil
+		}
+		return b.updateDefaults(req, "default domain", func(d *prefs.CreationDefaults) error {
+			d.Domain = req.Args
+			return nil
+		})
+	})
+	r.Callback(callbackToggleLinkStyle, func(ctx context.Context, req *Request) error {
+		style := linkStyleCard
+		if b.linkStyle(req.ChatID) == linkStyleCard {
+			style = linkStyleCompact
+		}
+		return b.updateSettings(req, "link style", func(p *prefs.Prefs) error {
+			p.LinkStyle = style
+			return nil
+		})
+	})
+	r.Callback(callbackToggleSound, func(ctx context.Context, req *Request) error {
+		return b.updateSettings(req, "notification sound", func(p *prefs.Prefs) error {
+			p.NotificationSound = !p.NotificationSound
+			return nil
+		})
+	})
+	r.Callback(callbackTogglePlainOutput, func(ctx context.Context, req *Request) error {
+		return b.updateSettings(req, "plain output", func(p *prefs.Prefs) error {
+			p.PlainOutput = !p.PlainOutput
+			return nil
+		})
+	})
+	r.Callback(callbackCycleLanguage, func(ctx context.Context, req *Request) error {
+		return b.updateSettings(req, "number and date format", func(p *prefs.Prefs) error {
+			p.Language = nextLanguage(p.Language)
+			return nil
+		})
+	})
+	r.Callback(callbackCycleAliasStyle, func(ctx context.Context, req *Request) error {
+		return b.updateDefaults(req, "alias style", func(d *prefs.CreationDefaults) error {
+			d.AliasStyle = nextAliasStyle(b.aliasStyle(b.prefs.Get(req.ChatID)))
+			return nil
+		})
+	}, needs(featureAliasStyle))
+	r.Callback(callbackToggleConfirm, func(ctx context.Context, req *Request) error {
+		return b.updateSettings(req, "confirm before shortening", func(p *prefs.Prefs) error {
+			p.ConfirmShorten = !p.ConfirmShorten
+			return nil
+		})
+	})
+	r.Callback(callbackToggleQuickActions, func(ctx context.Context, req *Request) error {
+		show := !b.prefs.Get(req.ChatID).QuickActions
+		err := b.updateSettings(req, "quick actions keyboard", func(p *prefs.Prefs) error {
+			p.QuickActions = show
+			return nil
+		})
+		if err != nil || b.prefs.Get(req.ChatID).QuickActions != show {
+			return err
+		}
+		return b.sendQuickActionsKeyboard(req.ChatID, show)
+	})
+	r.Callback(callbackToggleCleanup, func(ctx context.Context, req *Request) error {
+		return b.updateSettings(req, "cleanup suggestions", func(p *prefs.Prefs) error {
+			p.Cleanup = !p.Cleanup
+			return nil
+		})
+	})
+	r.Callback(actionCleanupKeep, func(ctx context.Context, req *Request) error {
+		return b.keepCleanupLink(req)
+	})
+	r.Callback(actionCleanupDelete, b.deleteCleanupLink, mutates())
+	r.Callback(callbackCleanupDeleteAll, func(ctx context.Context, req *Request) error {
+		return b.confirmCleanupDeletion(req)
+	}, mutates())
+	r.Callback(actionCleanupDeleteAll, b.deleteCleanupLinks, mutates())
+	r.Callback(callbackCleanupCancel, func(ctx context.Context, req *Request) error {
+		return b.editMessageText(req.ChatID, req.Message.MessageID, b.render(msgCleanupCancelled, nil))
+	})
+	r.Callback(actionPickExpiry, func(ctx context.Context, req *Request) error {
+		return b.handlePickExpiry(ctx, req)
+	})
+	r.Callback(actionPin, func(ctx context.Context, req *Request) error {
+		return b.setPinned(req.Payloads(), req.Message.MessageID, req.Args, true, req.Answer)
+	})
+	r.Callback(actionUnpin, func(ctx context.Context, req *Request) error {
+		return b.setPinned(req.Payloads(), req.Message.MessageID, req.Args, false, req.Answer)
+	})
+	r.Callback(actionMonitor, func(ctx context.Context, req *Request) error {
+		return b.setMonitored(ctx, req, req.Args, true)
+	}, needs(featureMonitor))
+	r.Callback(actionUnmonitor, func(ctx context.Context, req *Request) error {
+		return b.setMonitored(ctx, req, req.Args, false)
+	}, needs(featureMonitor))
+	r.Callback(actionCheckNow, b.checkNow, rateLimit(checkNowRateLimit, checkNowRateLimitWindow), needs(featureMonitor))
+	r.Callback(actionNewDestination, func(ctx context.Context, req *Request) error {
+		return b.startNewDestination(req.ChatID, req.Args)
+	}, mutates())
+	r.Callback(actionUseRedirect, b.useRedirect, mutates())
+	r.Callback(actionDisableLink, b.disableLink, mutates())
+	r.Callback(actionToggleAnalytics, b.toggleAnalytics, mutates())
+	r.Callback(actionTransfer, func(ctx context.Context, req *Request) error {
+		return b.startTransfer(req.ChatID, req.Args)
+	}, needs(featureTransfer), mutates())
+	r.Callback(actionTransferAccept, b.acceptTransfer, needs(featureTransfer), mutates())
+	r.Callback(actionTransferDecline, func(ctx context.Context, req *Request) error {
+		return b.declineTransfer(req)
+	}, needs(featureTransfer))
+	r.Callback(callbackCustomAlias, func(ctx context.Context, req *Request) error {
+		if err := b.startDialog(req.ChatID, UserState{State: StateWaitingForAlias}); err != nil {
+			return err
+		}
+		return b.reply(req.ChatID, msgSendCustomAlias, b.aliasRules().data())
+	}, mutates())
+	r.Callback(callbackScheduleLink, func(ctx context.Context, req *Request) error {
+		return b.promptNotBefore(req)
+	}, needs(featureScheduling), mutates())
+	r.Callback(actionEditPreview, func(ctx context.Context, req *Request) error {
+		return b.startEditPreview(req)
+	}, needs(featurePreview), mutates())
+	r.Callback(actionRemovePreview, b.removeLinkPreview, needs(featurePreview), mutates())
+	r.Callback(callbackPreviewSkip, b.skipPreviewStep, needs(featurePreview))
+	r.Callback(callbackQueueLink, func(ctx context.Context, req *Request) error {
+		return b.handleQueueCallback(req.ChatID, req.Answer)
+	}, mutates())
+	r.Callback(actionShortenURL, func(ctx context.Context, req *Request) error {
+		return b.handleShortenCommand(ctx, req.Payloads(), req.Args)
+	}, mutates())
+	r.Callback(actionForceLink, func(ctx context.Context, req *Request) error {
+		return b.handlePendingLink(ctx, req, false)
+	}, mutates())
+	r.Callback(actionFollowLink, func(ctx context.Context, req *Request) error {
+		return b.handlePendingLink(ctx, req, true)
+	}, mutates())
+	r.Callback(callbackUTM, func(ctx context.Context, req *Request) error {
+		return b.startUTMWizard(req.ChatID)
+	}, mutates())
+	r.Callback(actionUTMValue, func(ctx context.Context, req *Request) error {
+		return b.setUTMValue(req.Payloads(), req.Args)
+	})
+	r.Callback(callbackUTMSkip, func(ctx context.Context, req *Request) error {
+		return b.setUTMValue(req.Payloads(), "")
+	})
+	r.Callback(callbackUTMCreate, func(ctx context.Context, req *Request) error {
+		return b.createUTMLink(ctx, req.Payloads())
+	}, mutates())
+	r.Callback(callbackCancel, func(ctx context.Context, req *Request) error {
+		b.pendingQueue.Delete(req.ChatID)
+		b.resetUserState(req.ChatID)
+		return b.sendMessageWithKeyboard(req.ChatID, b.mainMenuText(req.ChatID), b.createMainKeyboard())
+	})
+
+	return r
+}
+
+func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) Outcome {
+	req := &Request{
+		ChatID:  msg.Chat.ID,
+		Args:    msg.CommandArguments(),
+		Message: msg,
+	}
+	if msg.From != nil {
+		req.UserID = msg.From.ID
 	}
+	return b.router.HandleCommand(ctx, msg.Command(), req)
 }
 
 // Handle shorten command with URL parsing
-func (b *Bot) handleShortenCommand(chatID int64, args string) error {
-	urlMatch := urlRegex.FindString(args)
-	if urlMatch == "" {
-		return b.sendMessage(chatID, msgInvalidShortenFormat, true)
+func (b *Bot) handleShortenCommand(ctx context.Context, to payloadKey, args string) error {
+	_, err := b.shorten(ctx, to, args)
+	return err
+}
+
+// shorten creates a link from the URL and options in text, see
+// parseShortenArgs, and reports
+// whether a link was created.
+func (b *Bot) shorten(ctx context.Context, to payloadKey, text string) (bool, error) {
+	args := parseShortenArgs(text)
+	if args.URL == "" {
+		return false, b.reply(to.chatID, msgInvalidShortenFormat, nil)
 	}
 
-	req := &shortenerv1.CreateLinkRequest{OriginalUrl: urlMatch, UserTgId: chatID}
+	req := &shortenerv1.CreateLinkRequest{OriginalUrl: args.URL, UserTgId: to.chatID, Source: linkSource(sourceBotMessage)}
+	if style, ok := args.Options[optStyle]; ok {
+		switch {
+		case !b.supports(featureAliasStyle):
+			args.Warnings = append(args.Warnings, "ignored: style — the link service can't choose alias styles")
+		case args.Options[optAlias] != "":
+			args.Warnings = append(args.Warnings, "ignored: style — a custom alias is given")
+		case style != b.aliasStyle(b.prefs.Get(to.chatID)):
+			req.AliasStyle = &style
+		}
+		// The user's default goes without saying
+		if req.AliasStyle == nil {
+			delete(args.Options, optStyle)
+		}
+	}
+	opts := createOptions{summary: args.Summary()}
 
-	if titleMatch := titleRegex.FindStringSubmatch(args); len(titleMatch) > 1 {
-		title := titleMatch[1]
+	if title, ok := args.Options[optTitle]; ok {
 		req.Title = &title
 	}
-	if aliasMatch := aliasRegex.FindStringSubmatch(args); len(aliasMatch) > 1 {
-		alias := aliasMatch[1]
+	if alias, ok := args.Options[optAlias]; ok {
+		if ok, err := b.validateCustomAlias(to.chatID, alias); !ok {
+			return false, err
+		}
 		req.CustomAlias = &alias
 	}
-	if expiresInMatch := expiresInRegex.FindStringSubmatch(args); len(expiresInMatch) > 1 {
-		duration, err := time.ParseDuration(expiresInMatch[1])
+	if analytics, ok := args.Options[optAnalytics]; ok {
+		minimal := analytics == "minimal"
+		req.MinimalAnalytics = &minimal
+	}
+	if value, ok := args.Options[optExpiresIn]; ok {
+		expiry, err := parseExpiry(value)
+		if err != nil {
+			return false, b.reply(to.chatID, msgInvalidExpiry, nil)
+		}
+		req.ExpiresAt = expiresAt(expiry)
+		opts.explicitExpiry = true
+	}
+	if value, ok := args.Options[optNotBefore]; ok {
+		notBefore, err := parseNotBefore(value, b.userLocation(to.chatID))
+		if err != nil {
+			return false, b.reply(to.chatID, msgInvalidNotBefore, nil)
+		}
+		req.NotBefore = protoTimestamp(notBefore)
+	}
+
+	// Warnings go out on their own, so they reach the user whatever becomes
+	// of the link
+	if len(args.Warnings) > 0 {
+		if err := b.reply(to.chatID, msgShortenOptionsIgnored, shortenWarningsData{Warnings: args.Warnings}); err != nil {
+			return false, err
+		}
+	}
+	return b.createLink(ctx, to, req, opts)
+}
+
+// createOptions is what the user chose for a link beyond the request.
+type createOptions struct {
+	// explicitExpiry marks an expiry chosen by the user, where a nil
+	// ExpiresAt means "never".
+	explicitExpiry bool
+	// summary lists the /shorten options applied, for the success message.
+	summary string
+}
+
+// createLink runs the pre-creation checks, applies the user's creation
+// defaults and creates the link. It reports whether a link was created.
+func (b *Bot) createLink(ctx context.Context, to payloadKey, req *shortenerv1.CreateLinkRequest, opts createOptions) (bool, error) {
+	if b.isBlockedURL(req.GetOriginalUrl()) {
+		return false, b.reply(to.chatID, msgBlockedDomain, nil)
+	}
+	if ok, err := b.checkURLSafety(ctx, to.chatID, req.GetUserTgId(), req.GetOriginalUrl()); !ok {
+		return false, err
+	}
+	if ok, err := b.checkQuota(ctx, to.chatID, req.GetUserTgId()); !ok {
+		return false, err
+	}
+	if ok, err := b.applyCreationDefaults(ctx, to, req, opts.explicitExpiry); !ok {
+		return false, err
+	}
+	if ok, err := b.checkNotBefore(to.chatID, req); !ok {
+		return false, err
+	}
+	if b.isKnownShortener(req.GetOriginalUrl()) {
+		return false, b.warnShortener(to, req)
+	}
+	return b.submitOrPreview(ctx, to, req, opts.summary)
+}
+
+// maxAliasRetries bounds how often a creation is retried when the alias
+// generated by the backend collides with an existing one.
+const maxAliasRetries = 3
+
+// createLinkWithRetry calls CreateLink, retrying collisions of generated
+// aliases. Custom alias collisions are returned as is. Created links are
+// recorded in the user's history.
+func (b *Bot) createLinkWithRetry(ctx context.Context, req *shortenerv1.CreateLinkRequest) (*shortenerv1.CreateLinkResponse, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := b.grpcClient.CreateLink(ctx, req)
+		if err == nil && req.NotBefore != nil {
+			err = b.checkScheduled(ctx, res)
+		}
 		if err == nil {
-			req.ExpiresAt = timestamppb.New(time.Now().Add(duration))
+			b.checkAliasStyle(req, res)
+			b.recordHistory(req.GetUserTgId(), prefs.HistoryEntry{Action: historyCreated, Alias: res.GetAlias()})
+		}
+		if status.Code(err) != codes.AlreadyExists || req.CustomAlias != nil || attempt > maxAliasRetries {
+			return res, err
 		}
+		b.log.Warn("generated alias collided, retrying", zap.Int("attempt", attempt))
+	}
+}
+
+// submitLink calls the backend and replies in to.chatID with the created short
+// URL, along with the options summary, or the error. It reports whether a
+// link was created.
+func (b *Bot) submitLink(ctx context.Context, to payloadKey, req *shortenerv1.CreateLinkRequest, summary string) (bool, error) {
+	key := recentLinkKey(req.GetUserTgId(), req.GetOriginalUrl(), req.GetCustomAlias()+"@"+req.GetDomain())
+	if alias, ok := b.recentLinks.Get(key); ok {
+		shortURL := b.shortURLOn(req.GetDomain(), alias)
+		message := b.render(msgLinkAlreadyCreated, linkData{ShortURL: shortURL})
+		card := newLinkCard(shortURL, req)
+		card.Repeat = true
+		return true, b.sendCreatedLink(to.chatID, message, card, b.createLinkActionsKeyboard(to, alias, req))
 	}
 
-	res, err := b.grpcClient.CreateLink(context.Background(), req)
+	res, err := b.createLinkWithRetry(ctx, req)
 	if err != nil {
-		if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
-			return b.sendMessage(chatID, fmt.Sprintf(msgAliasTaken, *req.CustomAlias), false)
+		// The failure is returned along with the reply about it, so the
+		// outcome tells that running the request again may help
+		failed := fmt.Errorf("gRPC CreateLink failed: %w", err)
+		switch status.Code(err) {
+		case codes.Unavailable:
+			return false, errors.Join(failed, b.offerQueue(to.chatID, req))
+		case codes.ResourceExhausted:
+			return false, errors.Join(failed, b.sendMessageWithKeyboard(to.chatID, b.render(msgResourceExhausted, nil), b.createQuotaKeyboard()))
 		}
-		b.log.Error("gRPC CreateLink failed", zap.Error(err))
-		return b.sendMessage(chatID, msgInternalError, false)
+		return false, errors.Join(failed, b.replyGRPCError(to.chatID, err, req.GetCustomAlias()))
 	}
-	shortURL := fmt.Sprintf("%s/%s", b.config.HTTPServer.BaseURL, res.GetAlias())
-	message := fmt.Sprintf(msgLinkSuccessfullyShortened, shortURL)
-	return b.sendMessageWithKeyboard(chatID, message, b.createLinkActionsKeyboard(res.GetAlias()))
+	b.countCreation(req.GetUserTgId())
+	b.recentLinks.Put(key, res.GetAlias())
+	b.linkLists.Forget(req.GetUserTgId())
+	shortURL := b.shortURLOn(req.GetDomain(), res.GetAlias())
+	message := b.render(msgLinkSuccessfullyShortened, linkData{ShortURL: shortURL, Options: summary})
+	card := newLinkCard(shortURL, req)
+	card.Options = summary
+	return true, b.sendCreatedLink(to.chatID, message, card, b.createLinkActionsKeyboard(to, res.GetAlias(), req))
 }
 
-func (b *Bot) handleMyLinksCommand(chatID int64) error {
-	req := &shortenerv1.ListUserLinksRequest{UserTgId: chatID}
-	res, err := b.grpcClient.ListUserLinks(context.Background(), req)
+func (b *Bot) handleMyLinksCommand(ctx context.Context, to payloadKey) error {
+	text, keyboard, err := b.buildMyLinks(ctx, to, linkCursor{})
 	if err != nil {
 		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
-		return b.sendMessage(chatID, msgInternalError, false)
-	}
-	if len(res.Links) == 0 {
-		return b.sendMessageWithKeyboard(chatID, msgNoLinks, b.createMainKeyboard())
-	}
-
-	var builder strings.Builder
-	builder.WriteString(msgMyLinksHeader)
-	
-	var keyboardRows [][]tgbotapi.InlineKeyboardButton
-	
-	for i, link := range res.Links {
-		title := link.GetOriginalUrl()
-		if link.Title != nil && *link.Title != "" {
-			title = *link.Title
-		}
-		
-		// Limit title length for clean display
-		if len(title) > 50 {
-			title = title[:47] + "..."
-		}
-		
-		builder.WriteString(fmt.Sprintf("\n\n%d. %s\n   %s/%s", i+1, title, b.config.HTTPServer.BaseURL, link.Alias))
-		
-		// Add action buttons for each link
-		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Stats", "stats_"+link.Alias),
-			tgbotapi.NewInlineKeyboardButtonData("Delete", "delete_"+link.Alias),
-		))
+		return b.replyGRPCError(to.chatID, err, "")
 	}
-	
-	// Add navigation buttons
-	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("Create Link", callbackCreateLink),
-	))
-	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("Main Menu", callbackHelp),
-	))
-	
-	keyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboardRows}
-	return b.sendMessageWithKeyboard(chatID, builder.String(), keyboard)
+	return b.sendMessageWithKeyboard(to.chatID, text, keyboard, b.linkContent())
 }
 
-func (b *Bot) handleStatsCommand(chatID int64, alias string) error {
-	alias = strings.TrimSpace(alias)
-	if alias == "" {
-		return b.sendMessage(chatID, fmt.Sprintf(msgInvalidCommandFormat, "stats"), false)
+// showMyLinksPage replaces a my_links message with the page the pressed
+// button points at.
+func (b *Bot) showMyLinksPage(ctx context.Context, r *Request) error {
+	var at linkCursor
+	if err := json.Unmarshal([]byte(r.Args), &at); err != nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	text, keyboard, err := b.buildMyLinks(ctx, r.Payloads(), at)
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+		r.Answer.alert(b.mapGRPCError(err, ""))
+		return nil
 	}
+	return b.editMessageWithKeyboard(r.ChatID, r.Message.MessageID, text, keyboard)
+}
 
-	req := &shortenerv1.GetLinkStatsRequest{Alias: alias}
-	res, err := b.grpcClient.GetLinkStats(context.Background(), req)
+// buildMyLinks renders the page of the user's link list starting at at
+// together with its keyboard. The first page starts with the pinned links,
+// which are left out of all pages.
+func (b *Bot) buildMyLinks(ctx context.Context, to payloadKey, at linkCursor) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	var links, pinnedLinks []*shortenerv1.LinkInfo
+	var next *linkCursor
+	first := at == linkCursor{}
+	err := b.withChatAction(ctx, to.chatID, tgbotapi.ChatTyping, func() (err error) {
+		links, next, err = b.listUserLinksPage(ctx, to.chatID, at, b.config.Links.PageSize)
+		if err == nil && first {
+			pinnedLinks = b.pinnedLinks(ctx, to.chatID)
+		}
+		return err
+	})
 	if err != nil {
-		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
-			return b.sendMessage(chatID, fmt.Sprintf(msgLinkNotFound, alias), false)
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	if first && len(links) == 0 && len(pinnedLinks) == 0 {
+		return b.render(msgNoLinks, nil), b.createMainKeyboard(), nil
+	}
+
+	var pinned, others linkListSection
+	for _, link := range pinnedLinks {
+		pinned.Items = append(pinned.Items, b.myLinksItem(to, link, true))
+	}
+	pins := b.prefs.Get(to.chatID).Pinned
+	for _, link := range links {
+		if !slices.Contains(pins, link.Alias) {
+			others.Items = append(others.Items, b.myLinksItem(to, link, false))
 		}
-		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
-		return b.sendMessage(chatID, msgInternalError, false)
 	}
+	if len(pinned.Items) > 0 {
+		pinned.Header = b.render(msgPinnedHeader, nil)
+		if len(others.Items) > 0 {
+			others.Header = b.render(msgOtherLinksHeader, nil)
+		}
+	}
+
+	// Add navigation buttons
+	var pages []tgbotapi.InlineKeyboardButton
+	if !first {
+		pages = append(pages, b.payloadButton(to, "« First page", actionMyLinksPage, "{}"))
+	}
+	if next != nil {
+		payload, err := json.Marshal(next)
+		if err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, err
+		}
+		// Backend page tokens don't fit into the callback data
+		pages = append(pages, b.storedPayloadButton(to, "Next »", actionMyLinksPage, string(payload)))
+	}
+	var nav [][]tgbotapi.InlineKeyboardButton
+	if len(pages) > 0 {
+		nav = append(nav, pages)
+	}
+	nav = append(nav,
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Create Link", callbackCreateLink),
+			b.callbackButton("Expiring soon", callbackExpiring),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Main Menu", callbackHelp),
+		),
+	)
+
+	header := b.render(msgMyLinksHeader, nil)
+	if len(pages) > 0 {
+		header += " " + b.render(msgMyLinksPage, pageData{Page: at.number()})
+	}
+	text, keyboard := b.renderLinkList(to, b.outputStyle(to.chatID), "my_links", header, []linkListSection{pinned, others}, nav)
+	return text, keyboard, nil
+}
+
+// myLinksItem is link in /my_links, where deleting updates the list in place.
+// Links whose destination is broken can be checked again right away.
+func (b *Bot) myLinksItem(to payloadKey, link *shortenerv1.LinkInfo, pinned bool) linkListItem {
+	// The icon alone would leave an empty reply option in plain output
+	peek := "ℹ"
+	if b.outputStyle(to.chatID) == stylePlain {
+		peek = "Details"
+	}
+	item := linkListItem{
+		Link:   link,
+		Pinned: pinned,
+		Health: b.linkHealth(to.chatID, link.Alias),
+		Actions: []tgbotapi.InlineKeyboardButton{
+			b.payloadButton(to, peek, actionPeek, link.Alias),
+			b.payloadButton(to, "Stats", actionStats, link.Alias),
+			b.payloadButton(to, "Delete", actionListDelete, link.Alias),
+		},
+	}
+	if item.Health == monitor.HealthBroken {
+		item.Actions = append(item.Actions, b.payloadButton(to, "Check now", actionCheckNow, link.Alias))
+	}
+	return item
+}
+
+// deleteFromMyLinks deletes alias and refreshes the my_links message it was
+// requested from, reporting the result as a callback toast.
+func (b *Bot) deleteFromMyLinks(ctx context.Context, to payloadKey, messageID int, alias string, answer *callbackAnswer) error {
+	err := b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC DeleteLink failed", zap.Error(err), zap.String("alias", alias))
+		answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	b.unpin(to.chatID, alias)
+	b.staleKeyboards(alias)
+	if err := b.monitors.Remove(alias); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	b.recordHistory(to.chatID, prefs.HistoryEntry{Action: historyDeleted, Alias: alias})
+	answer.toast(b.render(msgToastDeleted, linkData{ShortURL: displayURL(b.shortURL(alias))}))
 
-	expiresText := "Never"
-	if res.ExpiresAt != nil {
-		expiresText = res.ExpiresAt.AsTime().Format("2006-01-02 15:04 MST")
+	text, keyboard, err := b.buildMyLinks(ctx, to, linkCursor{})
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+		return b.replyGRPCError(to.chatID, err, "")
 	}
+	return b.editMessageWithKeyboard(to.chatID, messageID, text, keyboard)
+}
 
-	var titleText string
-	if res.Title != nil && *res.Title != "" {
-		titleText = fmt.Sprintf("\nTitle: %s", *res.Title)
+func (b *Bot) handleStatsCommand(ctx context.Context, to payloadKey, args string) error {
+	alias, err := b.resolveAlias(args)
+	if err != nil {
+		return b.replyAliasError(to.chatID, err, "stats")
 	}
+	return b.showStats(ctx, to, alias, nil)
+}
 
-	deviceStatsBuilder := &strings.Builder{}
-	if len(res.ClicksByDevice) > 0 {
-		deviceStatsBuilder.WriteString("\n\nBy Device:")
-		for device, count := range res.ClicksByDevice {
-			deviceStatsBuilder.WriteString(fmt.Sprintf("\n- %s: %d", device, count))
+// showStats sends statistics for alias. When invoked from a button, the
+// outcome is also reported through answer.
+func (b *Bot) showStats(ctx context.Context, to payloadKey, alias string, answer *callbackAnswer) error {
+	req := &shortenerv1.GetLinkStatsRequest{Alias: alias}
+	var res *shortenerv1.GetLinkStatsResponse
+	err := b.withChatAction(ctx, to.chatID, tgbotapi.ChatTyping, func() (err error) {
+		res, err = b.grpcClient.GetLinkStats(ctx, req)
+		return err
+	})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		if answer != nil {
+			answer.alert(b.mapGRPCError(err, alias))
+			return nil
 		}
+		return b.replyGRPCError(to.chatID, err, alias)
 	}
+	b.linkStats.Set(alias, res)
+	answer.toast(b.render(msgToastStatsRefreshed, nil))
 
-	responseText := fmt.Sprintf("Link Statistics: %s%s\n\nOriginal URL: %s\nTotal Clicks: %d\nExpires: %s%s",
-		alias, titleText, res.OriginalUrl, res.ClickCount, expiresText, deviceStatsBuilder.String())
+	text, keyboard := b.renderStats(to, b.outputStyle(to.chatID), alias, res)
+	return b.sendMessageWithKeyboard(to.chatID, text, keyboard, b.linkContent())
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Delete", "delete_"+alias),
-		),
+// Create keyboard for link statistics
+func (b *Bot) createStatsKeyboard(to payloadKey, alias string) tgbotapi.InlineKeyboardMarkup {
+	pin := b.payloadButton(to, "Pin", actionPin, alias)
+	if b.isPinned(to.chatID, alias) {
+		pin = b.payloadButton(to, "Unpin", actionUnpin, alias)
+	}
+	manage := tgbotapi.NewInlineKeyboardRow(pin)
+	if b.supports(featureRename) {
+		manage = append(manage, b.payloadButton(to, "Rename", actionRename, alias))
+	}
+	manage = append(manage, b.payloadButton(to, "Delete", actionDelete, alias))
+	snapshots := tgbotapi.NewInlineKeyboardRow(b.payloadButton(to, "Snapshot", actionSnapshot, alias))
+	if _, ok := b.snapshot(to.chatID, alias); ok {
+		snapshots = append(snapshots, b.payloadButton(to, "Compare to snapshot", actionCompareSnapshot, alias))
+	}
+	if b.supports(featureMonitor) {
+		monitoring := b.payloadButton(to, "Monitor: off", actionMonitor, alias)
+		if b.monitors.Has(alias) {
+			monitoring = b.payloadButton(to, "Monitor: on", actionUnmonitor, alias)
+		}
+		snapshots = append(snapshots, monitoring)
+		if b.linkHealth(to.chatID, alias) == monitor.HealthBroken {
+			snapshots = append(snapshots, b.payloadButton(to, "Check now", actionCheckNow, alias))
+		}
+	}
+	if b.supports(featureAnalyticsUpdate) {
+		manage = append(manage, b.payloadButton(to, "Analytics", actionToggleAnalytics, alias))
+	}
+	if b.supports(featureTransfer) {
+		snapshots = append(snapshots, b.payloadButton(to, "Transfer", actionTransfer, alias))
+	}
+	share := tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(to, "Add to campaign", actionCampaignPick, alias),
+		b.payloadButton(to, "Compare with…", actionCompareWith, alias),
+	)
+	if b.supports(featurePreview) {
+		share = append(share, b.payloadButton(to, "Edit preview", actionEditPreview, alias))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		manage,
+		snapshots,
+		share,
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("My Links", callbackMyLinks),
-			tgbotapi.NewInlineKeyboardButtonData("Menu", callbackHelp),
+			b.payloadButton(to, "Copy text", actionCopyText, alias),
+			b.callbackButton("My Links", callbackMyLinks),
+			b.callbackButton("Menu", callbackHelp),
 		),
 	)
-	return b.sendMessageWithKeyboard(chatID, responseText, keyboard)
 }
 
-func (b *Bot) handleDeleteCommand(chatID int64, alias string) error {
-	alias = strings.TrimSpace(alias)
-	if alias == "" {
-		return b.sendMessage(chatID, fmt.Sprintf(msgInvalidCommandFormat, "delete"), false)
+func (b *Bot) handleDeleteCommand(ctx context.Context, chatID int64, args string) error {
+	alias, err := b.resolveAlias(args)
+	if err != nil {
+		return b.replyAliasError(chatID, err, "delete")
 	}
+	return b.deleteLink(ctx, chatID, alias, nil)
+}
+
+// deleteLink deletes alias and confirms it. When invoked from a button, the
+// outcome is also reported through answer.
+func (b *Bot) deleteLink(ctx context.Context, chatID int64, alias string, answer *callbackAnswer) error {
 	req := &shortenerv1.DeleteLinkRequest{Alias: alias}
-	err := b.grpcClient.DeleteLink(context.Background(), req)
+	err := b.grpcClient.DeleteLink(ctx, req)
 	if err != nil {
-		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
-			return b.sendMessage(chatID, fmt.Sprintf(msgLinkNotFound, alias), false)
-		}
 		b.log.Error("gRPC DeleteLink failed", zap.Error(err), zap.String("alias", alias))
-		return b.sendMessage(chatID, msgInternalError, false)
+		if answer != nil {
+			answer.alert(b.mapGRPCError(err, alias))
+			return nil
+		}
+		return b.replyGRPCError(chatID, err, alias)
 	}
-	responseText := fmt.Sprintf(msgLinkDeleted, alias)
+	b.unpin(chatID, alias)
+	b.staleKeyboards(alias)
+	b.recordHistory(chatID, prefs.HistoryEntry{Action: historyDeleted, Alias: alias})
+	answer.toast(b.render(msgToastDeleted, linkData{ShortURL: displayURL(b.shortURL(alias))}))
+	responseText := b.render(msgLinkDeleted, aliasData{Alias: alias})
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Create Link", callbackCreateLink),
+			b.callbackButton("Create Link", callbackCreateLink),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("My Links", callbackMyLinks),
-			tgbotapi.NewInlineKeyboardButtonData("Menu", callbackHelp),
+			b.callbackButton("My Links", callbackMyLinks),
+			b.callbackButton("Menu", callbackHelp),
 		),
 	)
 	return b.sendMessageWithKeyboard(chatID, responseText, keyboard)
 }
 
-func (b *Bot) handleMessage(msg *tgbotapi.Message) error {
+func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) error {
 	userID := msg.Chat.ID
+	to := messagePayloads(msg)
 	state := b.getUserState(userID)
-	
+	// Photos and documents carry their text in the caption
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	if !msg.Chat.IsPrivate() && state.State == StateNormal {
+		// Channel posts forwarded to a discussion group come from no one
+		if msg.IsAutomaticForward {
+			return b.handleChannelForward(ctx, msg)
+		}
+		if b.prefs.Get(msg.Chat.ID).AutoShorten {
+			return b.autoShorten(ctx, msg)
+		}
+		if !b.addressedToBot(msg) {
+			return nil
+		}
+	}
+
 	switch state.State {
 	case StateWaitingForAlias:
-		return b.handleCustomAliasInput(userID, msg.Text)
+		return b.handleCustomAliasInput(userID, text)
 	case StateWaitingForURL:
-		return b.handleURLInputWithAlias(userID, msg.Text, state.CustomAlias)
+		if urls := extractURLs(msg); len(urls) > 0 && !urlRegex.MatchString(text) {
+			text = urls[0]
+		}
+		link := state.Payload.(linkPayload)
+		return b.handleURLInputWithAlias(ctx, to, withPrefix(link.Prefix, text), link)
+	case StateWaitingForNotBefore:
+		return b.handleNotBeforeInput(userID, state.Payload.(linkPayload), text)
+	case StateWaitingForUTMURL:
+		return b.handleUTMURLInput(to, text)
+	case StateWaitingForUTMSource, StateWaitingForUTMMedium, StateWaitingForUTMCampaign:
+		return b.setUTMValue(to, strings.TrimSpace(text))
+	case StateConfirmUTM:
+		return b.reply(userID, msgUTMPressCreate, nil)
+	case StateWaitingForNewAlias:
+		return b.handleNewAliasInput(ctx, to, state.Payload.(renamePayload).Alias, text)
+	case StateWaitingForShortenOptions:
+		return b.handleShortenOptionsInput(ctx, to, state.Payload.(shortenOptionsPayload).URL, text)
+	case StateWaitingForNewDestination:
+		return b.handleNewDestinationInput(ctx, to, state.Payload.(destinationPayload).Alias, text)
+	case StateWaitingForTransferRecipient:
+		return b.handleTransferRecipientInput(ctx, msg, state.Payload.(transferPayload).Alias)
+	case StateWaitingForTitle:
+		return b.handleTitleInput(ctx, to, state.Payload.(titlePayload).Alias, text)
+	case StateWaitingForSettingsFile:
+		return b.handleSettingsFileInput(ctx, msg)
+	case StateWaitingForPreviewTitle:
+		return b.handlePreviewTitleInput(userID, state.Payload.(previewPayload), text)
+	case StateWaitingForPreviewDescription:
+		return b.handlePreviewDescriptionInput(userID, state.Payload.(previewPayload), text)
+	case StateWaitingForPreviewImage:
+		return b.handlePreviewImageInput(ctx, msg, state.Payload.(previewPayload))
+	case StateWaitingForCompareAlias:
+		return b.handleCompareAliasInput(ctx, msg, state.Payload.(comparePayload).Alias)
+	case StateWaitingForConfirmPhrase:
+		return b.handleConfirmPhraseInput(ctx, msg, state.Payload.(confirmPayload).ID, text)
 	default:
+		if ok, err := b.pressReplyOption(ctx, msg); ok {
+			return err
+		}
+		if ok, err := b.handleQuickAction(ctx, msg); ok {
+			return err
+		}
+		if origin, ok := forwardOriginOf(msg); ok && msg.Chat.IsPrivate() {
+			return b.handleForwardedMessage(ctx, msg, origin)
+		}
 		// Default behavior - check if it's a URL
-		if urlRegex.MatchString(msg.Text) {
-			return b.handleShortenCommand(userID, msg.Text)
+		var created bool
+		var err error
+		textURL := urlRegex.FindString(text)
+		switch urls := extractURLs(msg); {
+		case textURL != "" && b.config.HTTPServer.DetectOwnLinks && b.isOwnShortURL(textURL):
+			return b.handleOwnShortURL(ctx, to, textURL)
+		case textURL != "" && b.confirmsShortening(msg):
+			return b.confirmShorten(to, textURL, text)
+		case textURL != "":
+			created, err = b.shorten(ctx, to, text)
+		case len(urls) > 0 && b.confirmsShortening(msg):
+			return b.confirmShorten(to, urls[0], urls[0])
+		case len(urls) > 0:
+			created, err = b.createLink(ctx, to, &shortenerv1.CreateLinkRequest{OriginalUrl: urls[0], UserTgId: to.chatID, Source: linkSource(sourceBotMessage)}, createOptions{})
+		default:
+			// Media without a URL is only answered in private chats
+			if msg.Text == "" && !msg.Chat.IsPrivate() {
+				return nil
+			}
+			b.recentMessages.Set(messageKey{userID, msg.MessageID}, outcomeRejected)
+			return b.sendMessageWithKeyboard(userID, b.render(msgUseShortenCommand, nil), b.createMainKeyboard())
 		}
-		return b.sendMessageWithKeyboard(userID, msgUseShortenCommand, b.createMainKeyboard())
+		switch {
+		case created:
+			b.recentMessages.Set(messageKey{userID, msg.MessageID}, outcomeLinked)
+		case outcomeOf(err).Retryable():
+			// Editing the message tries again
+			b.recentMessages.Set(messageKey{userID, msg.MessageID}, outcomeRejected)
+		}
+		return err
 	}
 }
 
-func (b *Bot) sendMessage(chatID int64, text string, useMarkdown bool) error {
+func (b *Bot) sendMessage(chatID int64, text string, useMarkdown bool, opts ...sendOption) error {
 	reply := tgbotapi.NewMessage(chatID, text)
 	if useMarkdown {
 		reply.ParseMode = tgbotapi.ModeMarkdown
 	}
-	_, err := b.api.Send(reply)
+	_, err := b.send(reply, opts...)
 	return err
 }
 
 // Handle callback queries from inline buttons
-func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) error {
-	// Answer callback to remove loading spinner
-	answerCallback := tgbotapi.NewCallback(callback.ID, "")
-	if _, err := b.api.Request(answerCallback); err != nil {
-		b.log.Error("failed to answer callback", zap.Error(err))
-	}
-
-	switch {
-	case callback.Data == callbackCreateLink:
-		return b.sendMessageWithKeyboard(callback.Message.Chat.ID, "Send a URL to create a short link:", b.createCreateLinkKeyboard())
-	case callback.Data == callbackMyLinks:
-		return b.handleMyLinksCommand(callback.Message.Chat.ID)
-	case callback.Data == callbackHelp:
-		return b.sendMessageWithKeyboard(callback.Message.Chat.ID, msgHelp, b.createMainKeyboard())
-	case strings.HasPrefix(callback.Data, "stats_"):
-		alias := strings.TrimPrefix(callback.Data, "stats_")
-		return b.handleStatsCommand(callback.Message.Chat.ID, alias)
-	case strings.HasPrefix(callback.Data, "delete_"):
-		alias := strings.TrimPrefix(callback.Data, "delete_")
-		return b.handleDeleteCommand(callback.Message.Chat.ID, alias)
-	case callback.Data == callbackCustomAlias:
-		b.setUserState(callback.Message.Chat.ID, StateWaitingForAlias, "")
-		return b.sendMessage(callback.Message.Chat.ID, msgSendCustomAlias, false)
-	}
-	
-	return nil
+func (b *Bot) handleCallbackQuery(ctx context.Context, callback *tgbotapi.CallbackQuery) Outcome {
+	// Answer after the action completes so its result can be shown as a toast,
+	// falling back to an empty answer if processing takes too long.
+	answer := newCallbackAnswer(callback.ID)
+	if callback.ID == "" && callback.Message != nil {
+		answer.chatID = callback.Message.Chat.ID
+	}
+	timer := time.AfterFunc(callbackAnswerTimeout, func() { b.answerCallback(answer) })
+	defer func() {
+		timer.Stop()
+		b.answerCallback(answer)
+	}()
+
+	if callback.Message == nil {
+		return Outcome{}
+	}
+	chatID := callback.Message.Chat.ID
+	if b.keyboards.Expired(chatID, callback.Message.MessageID) {
+		answer.alert(b.render(msgMenuExpired, nil))
+		return Outcome{}
+	}
+	action, payload, err := b.decodeCallbackData(payloadKey{chatID: chatID, userID: callback.From.ID}, callback.Data)
+	req := &Request{
+		ChatID:   chatID,
+		UserID:   callback.From.ID,
+		Args:     payload,
+		Message:  callback.Message,
+		Callback: callback,
+		Answer:   answer,
+	}
+	if err != nil {
+		if handled, outcome := b.router.HandleExpiredCallback(ctx, action, req); handled {
+			return outcome
+		}
+		answer.alert(b.render(msgButtonExpired, nil))
+		return Outcome{}
+	}
+	return b.router.HandleCallback(ctx, action, req)
 }
 
 // Create main menu keyboard
 func (b *Bot) createMainKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Create Link", callbackCreateLink),
+			b.callbackButton("Create Link", callbackCreateLink),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("My Links", callbackMyLinks),
+			b.callbackButton("My Links", callbackMyLinks),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Help", callbackHelp),
+			b.callbackButton("Settings", callbackSettings),
+			b.callbackButton("Help", callbackHelp),
 		),
 	)
 }
 
 // Create keyboard for successfully created link
-func (b *Bot) createLinkActionsKeyboard(alias string) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
+func (b *Bot) createLinkActionsKeyboard(to payloadKey, alias string, req *shortenerv1.CreateLinkRequest) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Statistics", "stats_"+alias),
-			tgbotapi.NewInlineKeyboardButtonData("Delete", "delete_"+alias),
+			b.payloadButton(to, "Statistics", actionStats, alias),
+			b.payloadButton(to, "Copy text", actionCopyText, alias),
+			b.payloadButton(to, "Delete", actionDelete, alias),
 		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("My Links", callbackMyLinks),
-			tgbotapi.NewInlineKeyboardButtonData("Create Another", callbackCreateLink),
-		),
-	)
+	}
+	rows = append(rows, b.createShortcutRows(to, alias, req)...)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("My Links", callbackMyLinks),
+		b.callbackButton("Create Another", callbackCreateLink),
+	))
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
 }
 
 // Create link creation options keyboard
 func (b *Bot) createCreateLinkKeyboard() tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Use Custom Alias", callbackCustomAlias),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Add UTM Tags", callbackUTM),
+		),
+	}
+	if b.multipleDomains() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Choose Domain", callbackChooseDomain),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Back to Menu", callbackHelp),
+	))
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// Create keyboard with a single cancel button for wizard prompts
+func (b *Bot) createCancelKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Use Custom Alias", callbackCustomAlias),
+			b.callbackButton("Cancel", callbackCancel),
 		),
+	)
+}
+
+// Create keyboard offering to queue a link while the backend is unavailable
+func (b *Bot) createQueueOfferKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Back to Menu", callbackHelp),
+			b.callbackButton("Queue", callbackQueueLink),
+			b.callbackButton("Cancel", callbackCancel),
 		),
 	)
 }
 
 // Send message with inline keyboard
-func (b *Bot) sendMessageWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+func (b *Bot) sendMessageWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup, opts ...sendOption) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
-	_, err := b.api.Send(msg)
+	_, err := b.send(msg, opts...)
 	return err
 }
 
-// User state management methods
-func (b *Bot) getUserState(userID int64) *UserState {
-	if state, exists := b.userStates[userID]; exists {
-		return state
-	}
-	return &UserState{State: StateNormal}
+// Edit an existing message's text and inline keyboard, sending a new
+// message when it can't be edited
+func (b *Bot) editMessageWithKeyboard(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	return b.editMessage(edit, nil, func() error {
+		return b.sendMessageWithKeyboard(chatID, text, keyboard)
+	})
 }
 
-func (b *Bot) setUserState(userID int64, state string, customAlias string) {
-	b.userStates[userID] = &UserState{
-		State:       state,
-		CustomAlias: customAlias,
-	}
+// Replace an existing message's text, dropping its inline keyboard, or send
+// the text anew when the message can't be edited
+func (b *Bot) editMessageText(chatID int64, messageID int, text string) error {
+	return b.editMessage(tgbotapi.NewEditMessageText(chatID, messageID, text), nil, func() error {
+		return b.sendMessage(chatID, text, false)
+	})
 }
 
-func (b *Bot) resetUserState(userID int64) {
-	delete(b.userStates, userID)
+// shortURL returns the public short URL for alias on the default domain.
+func (b *Bot) shortURL(alias string) string {
+	return b.shortURLOn("", alias)
+}
+
+// displayURL strips the scheme from a URL for compact display.
+func displayURL(u string) string {
+	if i := strings.Index(u, "://"); i >= 0 {
+		return u[i+3:]
+	}
+	return u
 }
 
 // Handle custom alias input
 func (b *Bot) handleCustomAliasInput(userID int64, alias string) error {
 	alias = strings.TrimSpace(alias)
-	
-	if !customAliasRegex.MatchString(alias) {
-		return b.sendMessage(userID, "Invalid alias format. Use only letters, numbers, and hyphens (1-20 characters).", false)
+
+	if ok, err := b.validateCustomAlias(userID, alias); !ok {
+		return err
+	}
+
+	b.advanceUserState(userID, UserState{State: StateWaitingForURL, Payload: linkPayload{CustomAlias: alias}})
+	if b.supports(featureScheduling) {
+		return b.replyWithKeyboard(userID, msgSendUrlWithAlias, aliasData{Alias: alias}, b.scheduleKeyboard())
 	}
-	
-	b.setUserState(userID, StateWaitingForURL, alias)
-	return b.sendMessage(userID, fmt.Sprintf(msgSendUrlWithAlias, alias), false)
+	return b.reply(userID, msgSendUrlWithAlias, aliasData{Alias: alias})
 }
 
-// Handle URL input with custom alias
-func (b *Bot) handleURLInputWithAlias(userID int64, text string, customAlias string) error {
-	defer b.resetUserState(userID)
-	
+// Handle URL input with custom alias, chosen domain and/or activation time
+func (b *Bot) handleURLInputWithAlias(ctx context.Context, to payloadKey, text string, link linkPayload) error {
+	defer b.resetUserState(to.chatID)
+
 	urlMatch := urlRegex.FindString(text)
 	if urlMatch == "" {
-		return b.sendMessage(userID, msgInvalidShortenFormat, false)
+		return b.reply(to.chatID, msgInvalidShortenFormat, nil)
 	}
-	
+
 	req := &shortenerv1.CreateLinkRequest{
 		OriginalUrl: urlMatch,
-		UserTgId:    userID,
-		CustomAlias: &customAlias,
+		UserTgId:    to.chatID,
+		Source:      linkSource(sourceBotMessage),
 	}
-	
-	res, err := b.grpcClient.CreateLink(context.Background(), req)
-	if err != nil {
-		if st, ok := status.FromError(err); ok && st.Code() == codes.AlreadyExists {
-			return b.sendMessage(userID, fmt.Sprintf(msgAliasTaken, customAlias), false)
-		}
-		b.log.Error("gRPC CreateLink failed", zap.Error(err))
-		return b.sendMessage(userID, msgInternalError, false)
+	if link.CustomAlias != "" {
+		req.CustomAlias = &link.CustomAlias
+	}
+	if link.Domain != "" {
+		req.Domain = &link.Domain
+	}
+	req.NotBefore = protoTimestamp(link.NotBefore)
+
+	_, err := b.createLink(ctx, to, req, createOptions{})
+	return err
+}
+
+// Reload re-reads runtime configuration files: the blocklist and the message
+// templates. A file that fails to load keeps its previous contents.
+func (b *Bot) Reload() {
+	if err := b.blocklist.Reload(); err != nil {
+		b.log.Error("failed to reload blocklist", zap.Error(err))
+	} else {
+		b.log.Info("blocklist reloaded")
+	}
+	if err := b.messages.Reload(); err != nil {
+		b.log.Error("failed to reload message templates", zap.Error(err))
+	} else {
+		b.log.Info("message templates reloaded")
 	}
-	
-	shortURL := fmt.Sprintf("%s/%s", b.config.HTTPServer.BaseURL, res.GetAlias())
-	message := fmt.Sprintf(msgLinkSuccessfullyShortened, shortURL)
-	return b.sendMessageWithKeyboard(userID, message, b.createLinkActionsKeyboard(res.GetAlias()))
 }
 
 func (b *Bot) getUpdatesChannel() tgbotapi.UpdatesChannel {
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	u.Timeout = updatesPollTimeout
 	return b.api.GetUpdatesChan(u)
 }
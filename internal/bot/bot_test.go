@@ -0,0 +1,56 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"strconv"
+	"testing"
+)
+
+// collideFirst generates aliases colliding with "taken" the first n times.
+func collideFirst(n int) backendtest.Option {
+	return backendtest.Aliases(func(i int) string {
+		if i <= n {
+			return "taken"
+		}
+		return "free" + strconv.Itoa(i)
+	})
+}
+
+func TestCreateLinkRetriesGeneratedCollision(t *testing.T) {
+	e := startBot(t, nil, collideFirst(2))
+	e.backend.Add(backendtest.Link{Alias: "taken", OriginalURL: "https://example.com/", OwnerID: 2002})
+
+	e.tg.SendMessage(user, "/shorten https://example.org/x")
+	e.tg.WaitText(user, "http://localhost:8080/free3")
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 3 {
+		t.Errorf("CreateLink called %d times, want 3", len(calls))
+	}
+}
+
+func TestCreateLinkGeneratedCollisionGivesUp(t *testing.T) {
+	e := startBot(t, nil, collideFirst(100))
+	e.backend.Add(backendtest.Link{Alias: "taken", OriginalURL: "https://example.com/", OwnerID: 2002})
+
+	// The failed creation has no response to read the alias from
+	e.tg.SendMessage(user, "/shorten https://example.org/x")
+	e.tg.WaitText(user, "Couldn't generate a free short alias")
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != maxAliasRetries+1 {
+		t.Errorf("CreateLink called %d times, want %d", len(calls), maxAliasRetries+1)
+	}
+
+	// The bot keeps working
+	e.tg.SendMessage(user, "/start")
+	e.tg.WaitText(user, "Select an action")
+}
+
+func TestCreateLinkCustomAliasCollision(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "taken", OriginalURL: "https://example.com/", OwnerID: 2002})
+
+	e.tg.SendMessage(user, "/shorten https://example.org/x alias=taken")
+	e.tg.WaitText(user, "Alias 'taken' is already taken")
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 1 {
+		t.Errorf("CreateLink called %d times, want no retry", len(calls))
+	}
+}
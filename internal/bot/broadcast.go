@@ -0,0 +1,239 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/broadcast"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// broadcastOutcome is what happened to a broadcast message.
+type broadcastOutcome int
+
+const (
+	broadcastSent broadcastOutcome = iota
+	broadcastBlocked
+	broadcastFailed
+)
+
+// handleBroadcastCommand starts sending the text after the command to every
+// user who hasn't blocked the bot.
+func (b *Bot) handleBroadcastCommand(ctx context.Context, r *Request) error {
+	text := strings.TrimSpace(r.Args)
+	if text == "" {
+		return b.reply(r.ChatID, msgBroadcastUsage, nil)
+	}
+
+	var targets []int64
+	for _, u := range b.users.List() {
+		if u.BlockedAt.IsZero() {
+			targets = append(targets, u.ID)
+		}
+	}
+	return b.startBroadcast(r.ChatID, text, targets)
+}
+
+// startBroadcast queues text for targets, showing the progress in the
+// admin chat.
+func (b *Bot) startBroadcast(adminChatID int64, text string, targets []int64) error {
+	job := broadcast.Job{
+		ID:          newPayloadToken(),
+		AdminChatID: adminChatID,
+		Text:        text,
+		Targets:     targets,
+		CreatedAt:   time.Now(),
+	}
+	progress := tgbotapi.NewMessage(adminChatID, b.render(msgBroadcastProgress, b.broadcastData(job)))
+	progress.ReplyMarkup = b.createBroadcastKeyboard(adminChatID, job.ID)
+	sent, err := b.send(progress)
+	if err != nil {
+		return err
+	}
+	job.ProgressMessageID = sent.MessageID
+	if err := b.broadcasts.Add(job); err != nil {
+		b.log.Error("failed to save broadcast", zap.Error(err))
+		return b.editMessageText(adminChatID, sent.MessageID, b.render(msgInternalError, nil))
+	}
+	b.log.Info("broadcast started", zap.String("id", job.ID), zap.Int("targets", len(targets)))
+	select {
+	case b.broadcastWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Create keyboard under the broadcast progress message
+func (b *Bot) createBroadcastKeyboard(chatID int64, id string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(chatPayloads(chatID), "Cancel broadcast", actionCancelBroadcast, id),
+	))
+}
+
+// cancelBroadcast asks the worker to stop the broadcast at its next checkpoint.
+func (b *Bot) cancelBroadcast(r *Request) error {
+	ok, err := b.broadcasts.Cancel(r.Args)
+	if err != nil {
+		b.log.Error("failed to save broadcast", zap.Error(err))
+	}
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	r.Answer.toast(b.render(msgToastBroadcastCancelling, nil))
+	return nil
+}
+
+// runBroadcasts sends the stored broadcasts one after another, resuming
+// those a restart interrupted, until ctx is done.
+func (b *Bot) runBroadcasts(ctx context.Context) {
+	for {
+		job, ok := b.broadcasts.Next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.broadcastWake:
+			}
+			continue
+		}
+		if job.Cursor > 0 {
+			b.log.Info("resuming broadcast", zap.String("id", job.ID), zap.Int("cursor", job.Cursor))
+		}
+		if !b.processBroadcast(ctx, job) {
+			return
+		}
+	}
+}
+
+// processBroadcast sends job at the configured rate, saving its progress
+// every CheckpointEvery messages. It returns false when ctx is done before
+// the job is finished.
+func (b *Bot) processBroadcast(ctx context.Context, job broadcast.Job) bool {
+	cfg := b.config.Broadcast
+	limiter := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+	defer limiter.Stop()
+	lastProgress := time.Now()
+
+	for !job.Done() {
+		select {
+		case <-ctx.Done():
+		case <-limiter.C:
+		}
+		outcome, ok := b.sendBroadcast(ctx, job.Targets[job.Cursor], job.Text)
+		if !ok {
+			if _, err := b.broadcasts.Checkpoint(job); err != nil {
+				b.log.Error("failed to save broadcast", zap.Error(err))
+			}
+			return false
+		}
+		switch outcome {
+		case broadcastSent:
+			job.Sent++
+		case broadcastBlocked:
+			job.Blocked++
+		case broadcastFailed:
+			job.Failed++
+		}
+		job.Cursor++
+
+		if job.Cursor%cfg.CheckpointEvery != 0 && !job.Done() {
+			continue
+		}
+		cancelled, err := b.broadcasts.Checkpoint(job)
+		if err != nil {
+			b.log.Error("failed to save broadcast", zap.Error(err))
+		}
+		if cancelled {
+			job.Cancelled = true
+			break
+		}
+		if time.Since(lastProgress) >= cfg.ProgressInterval && !job.Done() {
+			lastProgress = time.Now()
+			edit := tgbotapi.NewEditMessageTextAndMarkup(job.AdminChatID, job.ProgressMessageID,
+				b.render(msgBroadcastProgress, b.broadcastData(job)), b.createBroadcastKeyboard(job.AdminChatID, job.ID))
+			if err := b.editMessage(edit, nil, nil); err != nil {
+				b.log.Debug("failed to update broadcast progress", zap.Error(err))
+			}
+		}
+	}
+
+	b.finishBroadcast(job)
+	return true
+}
+
+// sendBroadcast sends text to chatID as a notification, waiting out flood
+// limits. It returns false when ctx is done before the message is sent.
+func (b *Bot) sendBroadcast(ctx context.Context, chatID int64, text string) (broadcastOutcome, bool) {
+	for ctx.Err() == nil {
+		sent, err := b.send(tgbotapi.NewMessage(chatID, text), b.notification(chatID))
+		if wait, ok := retryAfter(err); ok {
+			b.log.Warn("broadcast hit the flood limit", zap.Duration("retry_after", wait))
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+			continue
+		}
+		switch {
+		case isBlockedError(err):
+			return broadcastBlocked, true
+		case err != nil:
+			b.log.Debug("broadcast message failed", zap.Int64("chat_id", chatID), zap.Error(err))
+			return broadcastFailed, true
+		case sent.MessageID == 0:
+			// Skipped, the user is known to have blocked the bot
+			return broadcastBlocked, true
+		}
+		return broadcastSent, true
+	}
+	return 0, false
+}
+
+// finishBroadcast replaces the progress message with the summary and drops
+// the job.
+func (b *Bot) finishBroadcast(job broadcast.Job) {
+	name := msgBroadcastDone
+	if job.Cancelled {
+		name = msgBroadcastCancelled
+	}
+	text := b.render(name, b.broadcastData(job))
+	edit := tgbotapi.NewEditMessageText(job.AdminChatID, job.ProgressMessageID, text)
+	err := b.editMessage(edit, nil, func() error {
+		return b.sendMessage(job.AdminChatID, text, false)
+	})
+	if err != nil {
+		b.log.Warn("failed to report broadcast result", zap.Error(err))
+	}
+	if err := b.broadcasts.Remove(job.ID); err != nil {
+		b.log.Error("failed to save broadcast", zap.Error(err))
+	}
+	b.log.Info("broadcast finished", zap.String("id", job.ID), zap.Bool("cancelled", job.Cancelled),
+		zap.Int("sent", job.Sent), zap.Int("blocked", job.Blocked), zap.Int("failed", job.Failed))
+}
+
+func (b *Bot) broadcastData(job broadcast.Job) broadcastData {
+	return broadcastData{
+		Done:    groupDigits(job.Cursor),
+		Total:   groupDigits(len(job.Targets)),
+		Sent:    groupDigits(job.Sent),
+		Blocked: groupDigits(job.Blocked),
+		Failed:  groupDigits(job.Failed),
+	}
+}
+
+// groupDigits formats n with its digits in groups of three: "9 800".
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	var sb strings.Builder
+	for i, d := range s {
+		if i > 0 && (len(s)-i)%3 == 0 && s[i-1] != '-' {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String()
+}
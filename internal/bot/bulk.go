@@ -0,0 +1,399 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Bulk operations on the links of the "Expiring soon" view. Campaigns are
+// the bot's tags for links: a link can be tagged with any number of them.
+const (
+	bulkExtend             = "extend"
+	bulkDisable            = "disable"
+	bulkAddToCampaign      = "campaign_add"
+	bulkRemoveFromCampaign = "campaign_remove"
+)
+
+// bulkFeature returns the feature op needs, if any.
+func bulkFeature(op string) string {
+	if op == bulkExtend || op == bulkDisable {
+		return featureExtend
+	}
+	return ""
+}
+
+const (
+	// bulkExtendBy is how far the bulk extension pushes back each expiry.
+	bulkExtendBy = 7 * 24 * time.Hour
+	// bulkDeadline bounds a whole bulk operation; links not done by then
+	// count as failed and can be retried.
+	bulkDeadline = 2 * time.Minute
+	// bulkProgressInterval is how often the progress message is updated.
+	bulkProgressInterval = 2 * time.Second
+)
+
+// bulkJob is a bulk operation on a snapshot of the listed links, carried by
+// the buttons of the bulk actions message.
+type bulkJob struct {
+	// Op is one of the bulk operations; empty until one is picked.
+	Op string `json:"op,omitempty"`
+	// Campaign is the campaign bulkAddToCampaign and
+	// bulkRemoveFromCampaign tag the links with or untag them from.
+	Campaign string     `json:"campaign,omitempty"`
+	Links    []bulkLink `json:"links"`
+}
+
+// bulkJobs runs bulk operations in the background, one per user at a
+// time, so a long one doesn't hold up the updates that follow.
+type bulkJobs struct {
+	mu      sync.Mutex
+	running map[int64]bool
+	wg      sync.WaitGroup
+}
+
+func newBulkJobs() *bulkJobs {
+	return &bulkJobs{running: make(map[int64]bool)}
+}
+
+// Start runs fn in the background for userID. It reports false, not
+// running fn, while a job of userID is still running. fn may call release
+// to let the next job of userID start before it returns.
+func (j *bulkJobs) Start(userID int64, fn func(release func())) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running[userID] {
+		return false
+	}
+	j.running[userID] = true
+	j.wg.Add(1)
+	release := sync.OnceFunc(func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		delete(j.running, userID)
+	})
+	go func() {
+		defer j.wg.Done()
+		defer release()
+		fn(release)
+	}()
+	return true
+}
+
+// Wait waits for the running jobs to finish.
+func (j *bulkJobs) Wait() {
+	j.wg.Wait()
+}
+
+type bulkLink struct {
+	Alias string `json:"alias"`
+	// ExpiresAt is the expiry the link was listed with, or for an extension
+	// the expiry to set. Links found at or past it are skipped, so running
+	// the job again only updates the links it missed.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// bulkActionsButton offers the bulk operations on the links listed in the
+// "Expiring soon" view.
+func (b *Bot) bulkActionsButton(to payloadKey, links []bulkLink) (tgbotapi.InlineKeyboardButton, bool) {
+	payload, err := json.Marshal(bulkJob{Links: links})
+	if err != nil {
+		b.log.Error("failed to encode bulk job", zap.Error(err))
+		return tgbotapi.InlineKeyboardButton{}, false
+	}
+	return b.storedPayloadButton(to, "Bulk actions", actionBulkActions, string(payload)), true
+}
+
+// decodeBulkJob decodes the payload of a bulk actions button, alerting when
+// it expired.
+func (b *Bot) decodeBulkJob(r *Request) (bulkJob, bool) {
+	var job bulkJob
+	if err := json.Unmarshal([]byte(r.Args), &job); err != nil || len(job.Links) == 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return bulkJob{}, false
+	}
+	return job, true
+}
+
+// showBulkActions presents the operations available for the listed links.
+func (b *Bot) showBulkActions(r *Request) error {
+	job, ok := b.decodeBulkJob(r)
+	if !ok {
+		return nil
+	}
+	button := func(text, op, campaign string) tgbotapi.InlineKeyboardButton {
+		payload, err := json.Marshal(bulkJob{Op: op, Campaign: campaign, Links: job.Links})
+		if err != nil {
+			b.log.Error("failed to encode bulk job", zap.Error(err))
+		}
+		return b.storedPayloadButton(r.Payloads(), text, actionBulkAsk, string(payload))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if b.supports(featureExtend) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			button("Extend all by a week", bulkExtend, ""),
+			button("Disable all", bulkDisable, ""),
+		))
+	}
+	for _, c := range b.prefs.Get(r.ChatID).Campaigns {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			button("Add all to "+c.Name, bulkAddToCampaign, c.Name),
+			button("Remove all from "+c.Name, bulkRemoveFromCampaign, c.Name),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(b.callbackButton("Cancel", callbackBulkCancel)))
+	text := b.render(msgBulkActions, bulkData{Count: len(job.Links), Window: formatRemaining(b.config.Expiring.Window)})
+	return b.sendMessageWithKeyboard(r.ChatID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// hasBulkActions reports whether any bulk operation is available in
+// chatID.
+func (b *Bot) hasBulkActions(chatID int64) bool {
+	return b.supports(featureExtend) || len(b.prefs.Get(chatID).Campaigns) > 0
+}
+
+// bulkAvailable alerts and reports false when the operation of job isn't
+// available.
+func (b *Bot) bulkAvailable(r *Request, job bulkJob) bool {
+	if feature := bulkFeature(job.Op); feature != "" && !b.supports(feature) {
+		r.Answer.alert(b.render(msgFeatureUnavailable, nil))
+		return false
+	}
+	return true
+}
+
+// confirmBulkAction asks to confirm the picked operation with the number of
+// links it affects. An extension's target expiries are fixed here, so a
+// retry doesn't extend the links already done once more.
+func (b *Bot) confirmBulkAction(r *Request) error {
+	job, ok := b.decodeBulkJob(r)
+	if !ok {
+		return nil
+	}
+	if !b.bulkAvailable(r, job) {
+		return nil
+	}
+	var text, label string
+	switch job.Op {
+	case bulkExtend:
+		now := time.Now()
+		for i, l := range job.Links {
+			job.Links[i].ExpiresAt = later(l.ExpiresAt, now).Add(bulkExtendBy)
+		}
+		text, label = b.render(msgBulkConfirmExtend, countData{Count: len(job.Links)}), "Yes, extend"
+	case bulkDisable:
+		text, label = b.render(msgBulkConfirmDisable, countData{Count: len(job.Links)}), "Yes, disable"
+	case bulkAddToCampaign:
+		text, label = b.render(msgBulkConfirmAdd, bulkCampaignData{Count: len(job.Links), Campaign: job.Campaign}), "Yes, add"
+	case bulkRemoveFromCampaign:
+		text, label = b.render(msgBulkConfirmRemove, bulkCampaignData{Count: len(job.Links), Campaign: job.Campaign}), "Yes, remove"
+	default:
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.storedPayloadButton(r.Payloads(), label, actionBulkRun, string(payload)),
+		b.callbackButton("Cancel", callbackBulkCancel),
+	))
+	edit := tgbotapi.NewEditMessageTextAndMarkup(r.ChatID, r.Message.MessageID, text, keyboard)
+	return b.editMessage(edit, r.Answer, func() error {
+		return b.sendMessageWithKeyboard(r.ChatID, edit.Text, keyboard)
+	})
+}
+
+// runBulkAction starts a confirmed operation in the background, one per
+// user at a time; runBulkJob reports on it in place of the confirmation.
+func (b *Bot) runBulkAction(ctx context.Context, r *Request) error {
+	job, ok := b.decodeBulkJob(r)
+	if !ok {
+		return nil
+	}
+	switch job.Op {
+	case bulkExtend, bulkDisable, bulkAddToCampaign, bulkRemoveFromCampaign:
+	default:
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	if !b.bulkAvailable(r, job) {
+		return nil
+	}
+	to, msgID := r.Payloads(), r.Message.MessageID
+	started := b.bulkJobs.Start(r.UserID, func(release func()) {
+		b.runBulkJob(ctx, to, r.UserID, msgID, job, release)
+	})
+	if !started {
+		r.Answer.alert(b.render(msgBulkRunning, nil))
+	}
+	return nil
+}
+
+// runBulkJob applies job for userID with bounded concurrency, showing its
+// progress in message msgID and then a summary naming the links that
+// failed, with a button to retry them. It calls release once the links are
+// done, so the retry button works as soon as it shows.
+func (b *Bot) runBulkJob(ctx context.Context, to payloadKey, userID int64, msgID int, job bulkJob, release func()) {
+	var done atomic.Int64
+	progress := func() {
+		text := b.render(msgBulkProgress, bulkProgressData{Op: job.Op, Campaign: job.Campaign, Done: int(done.Load()), Total: len(job.Links)})
+		if err := b.editMessage(tgbotapi.NewEditMessageText(to.chatID, msgID, text), nil, nil); err != nil {
+			b.log.Debug("failed to update bulk progress", zap.Error(err))
+		}
+	}
+	progress()
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(bulkProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				progress()
+			}
+		}
+	}()
+	results := fanOut(ctx, fanOutOptions{Workers: b.config.Expiring.Workers, Deadline: bulkDeadline}, job.Links,
+		func(ctx context.Context, link bulkLink) (bool, error) {
+			defer done.Add(1)
+			return b.applyBulk(ctx, to.chatID, userID, job, link)
+		})
+	close(stop)
+	<-stopped
+	release()
+
+	summary := bulkDoneData{Op: job.Op, Campaign: job.Campaign}
+	retry := bulkJob{Op: job.Op, Campaign: job.Campaign}
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			b.log.Warn("bulk operation failed", zap.String("op", job.Op), zap.String("alias", res.Item.Alias), zap.Error(res.Err))
+			summary.Failed = append(summary.Failed, res.Item.Alias)
+			if outcomeOf(res.Err).Retryable() {
+				retry.Links = append(retry.Links, res.Item)
+			}
+		case res.Value:
+			summary.Updated++
+		default:
+			summary.Skipped++
+		}
+	}
+	if job.Op == bulkDisable && summary.Updated > 0 {
+		b.healthBadges.Forget(to.chatID)
+	}
+	b.log.Info("bulk operation finished",
+		zap.Int64("user_id", userID),
+		zap.String("op", job.Op),
+		zap.Int("updated", summary.Updated),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("failed", len(summary.Failed)))
+
+	row := tgbotapi.NewInlineKeyboardRow(b.callbackButton("Expiring soon", callbackExpiring))
+	if len(retry.Links) > 0 {
+		payload, err := json.Marshal(retry)
+		if err != nil {
+			b.log.Error("failed to encode bulk job", zap.Error(err))
+		} else {
+			row = append([]tgbotapi.InlineKeyboardButton{b.storedPayloadButton(to, "Retry failed", actionBulkRun, string(payload))}, row...)
+		}
+	}
+	text := b.render(msgBulkDone, summary)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	edit := tgbotapi.NewEditMessageTextAndMarkup(to.chatID, msgID, text, keyboard)
+	err := b.editMessage(edit, nil, func() error {
+		return b.sendMessageWithKeyboard(to.chatID, text, keyboard)
+	})
+	if err != nil {
+		b.log.Warn("failed to report bulk operation", zap.Int64("chat_id", to.chatID), zap.Error(err))
+	}
+}
+
+// applyBulk applies job to link for userID in chatID and reports whether
+// it changed anything: links already extended to the target expiry,
+// already expired for a disable, or already tagged or untagged, are
+// skipped.
+func (b *Bot) applyBulk(ctx context.Context, chatID, userID int64, job bulkJob, link bulkLink) (bool, error) {
+	op := job.Op
+	if op == bulkAddToCampaign || op == bulkRemoveFromCampaign {
+		return b.applyBulkCampaign(chatID, op, job.Campaign, link.Alias)
+	}
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: link.Alias})
+	if err != nil {
+		return false, err
+	}
+	current := protoTime(stats.GetExpiresAt())
+	expiresAt := time.Now()
+	if op == bulkExtend {
+		if current == nil || !current.Before(link.ExpiresAt) {
+			return false, nil
+		}
+		expiresAt = link.ExpiresAt
+	} else if current != nil && !current.After(expiresAt) {
+		return false, nil
+	}
+	_, err = b.grpcClient.SetLinkExpiry(ctx, &shortenerv1.SetLinkExpiryRequest{
+		Alias:     link.Alias,
+		UserTgId:  userID,
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
+	if err != nil {
+		return false, err
+	}
+	if op == bulkDisable {
+		if err := b.monitors.Remove(link.Alias); err != nil {
+			b.log.Error("failed to save monitored links", zap.Error(err))
+		}
+	}
+	return true, nil
+}
+
+// applyBulkCampaign adds alias to the campaign name of chatID or removes
+// it, reporting false when it already was or wasn't in it.
+func (b *Bot) applyBulkCampaign(chatID int64, op, name, alias string) (bool, error) {
+	changed := false
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		i := campaignIndex(*p, name)
+		if i < 0 {
+			return errNoCampaign
+		}
+		c := &p.Campaigns[i]
+		j := slices.Index(c.Aliases, alias)
+		switch {
+		case op == bulkAddToCampaign && j < 0:
+			if len(c.Aliases) >= b.config.Campaigns.MaxLinks {
+				return errCampaignFull
+			}
+			c.Aliases = append(c.Aliases, alias)
+			changed = true
+		case op == bulkRemoveFromCampaign && j >= 0:
+			c.Aliases = slices.Delete(c.Aliases, j, j+1)
+			changed = true
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// later returns the later of a and b.
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
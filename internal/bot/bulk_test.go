@@ -0,0 +1,182 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/telegramtest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// addExpiring gives user links of aliases expiring tomorrow, and a
+// campaign spring holding campaign.
+func (e *e2e) addExpiring(t *testing.T, aliases, campaign []string) {
+	t.Helper()
+	for _, alias := range aliases {
+		e.backend.Add(backendtest.Link{
+			Alias:       alias,
+			OriginalURL: "https://example.com/" + alias,
+			OwnerID:     user,
+			ExpiresAt:   timestamppb.New(time.Now().Add(24 * time.Hour)),
+		})
+	}
+	err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+		p.Campaigns = []prefs.Campaign{{Name: "spring", Aliases: campaign}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bulkActions opens the bulk actions of /expiring.
+func (e *e2e) bulkActions(t *testing.T) telegramtest.Request {
+	t.Helper()
+	e.tg.SendMessage(user, "/expiring")
+	e.press(t, e.tg.WaitText(user, "Links expiring within"), "Bulk actions")
+	return e.tg.WaitText(user, "What should happen to the")
+}
+
+// campaignAliases returns the links of the campaign spring, sorted.
+func (e *e2e) campaignAliases(t *testing.T) []string {
+	t.Helper()
+	p := e.bot.prefs.Get(user)
+	if i := campaignIndex(p, "spring"); i >= 0 {
+		return slices.Sorted(slices.Values(p.Campaigns[i].Aliases))
+	}
+	t.Fatal("campaign spring lost")
+	return nil
+}
+
+func TestE2EBulkCampaignActions(t *testing.T) {
+	e := startBot(t, nil, backendtest.Without(shortenerv1.Shortener_SetLinkExpiry_FullMethodName))
+	e.addExpiring(t, []string{"a", "b", "c"}, []string{"a"})
+
+	actions := e.bulkActions(t)
+	// The backend can't change expiries; campaigns are still offered
+	if _, ok := actions.Button("Extend all by a week"); ok {
+		t.Errorf("expiry actions offered without SetLinkExpiry: %v", actions.Buttons())
+	}
+	if _, ok := actions.Button("Remove all from spring"); !ok {
+		t.Errorf("no campaign actions: %v", actions.Buttons())
+	}
+	e.press(t, actions, "Add all to spring")
+	e.press(t, e.tg.WaitText(user, "Add 3 links to the campaign spring?"), "Yes, add")
+	e.tg.WaitText(user, "Adding links to spring: 0 / 3 done")
+	e.tg.WaitText(user, "Added 2 links to spring. 1 already were and were left alone.")
+	if got := e.campaignAliases(t); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("campaign holds %v, want a, b and c", got)
+	}
+
+	e.press(t, e.bulkActions(t), "Remove all from spring")
+	e.press(t, e.tg.WaitText(user, "Remove 3 links from the campaign spring?"), "Yes, remove")
+	e.tg.WaitText(user, "Removed 3 links from spring.")
+	if got := e.campaignAliases(t); len(got) != 0 {
+		t.Errorf("campaign holds %v, want it emptied", got)
+	}
+}
+
+func TestE2EBulkRetryFailed(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Campaigns.MaxLinks = 3
+	// One at a time, c is the one left out
+	cfg.Expiring.Workers = 1
+	e := startBot(t, cfg)
+	e.addExpiring(t, []string{"a", "b", "c"}, []string{"old"})
+
+	e.press(t, e.bulkActions(t), "Add all to spring")
+	e.press(t, e.tg.WaitText(user, "Add 3 links"), "Yes, add")
+	done := e.tg.WaitText(user, "Added 2 links to spring.")
+	if !strings.Contains(done.Text(), "1 failed: c") {
+		t.Errorf("summary %q doesn't name the failed link", done.Text())
+	}
+
+	// Making room lets the retry add only the link left out
+	err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+		p.Campaigns[0].Aliases = slices.DeleteFunc(p.Campaigns[0].Aliases, func(a string) bool { return a == "old" })
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.press(t, done, "Retry failed")
+	retried := e.tg.WaitText(user, "Added 1 link to spring.")
+	if _, ok := retried.Button("Retry failed"); ok {
+		t.Errorf("retry offered again after it succeeded: %v", retried.Buttons())
+	}
+	if got := e.campaignAliases(t); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("campaign holds %v, want a, b and c", got)
+	}
+}
+
+func TestE2EBulkRunsInBackground(t *testing.T) {
+	e := startBot(t, nil)
+	e.addExpiring(t, []string{"a", "b"}, nil)
+	confirm := func() telegramtest.Request {
+		e.press(t, e.bulkActions(t), "Add all to spring")
+		return e.tg.WaitText(user, "Add 2 links")
+	}
+	first := confirm()
+
+	// A job of user still running holds back the next one
+	release := make(chan struct{})
+	if !e.bot.bulkJobs.Start(user, func(func()) { <-release }) {
+		t.Fatal("job refused with none running")
+	}
+	e.press(t, first, "Yes, add")
+	e.tg.Wait("answerCallbackQuery", func(r telegramtest.Request) bool {
+		return strings.Contains(r.Param("text"), "A bulk action is still running")
+	})
+	// Updates are still handled meanwhile
+	second := confirm()
+	close(release)
+	e.bot.bulkJobs.Wait()
+	if got := e.campaignAliases(t); len(got) != 0 {
+		t.Errorf("campaign holds %v while the job was refused", got)
+	}
+
+	e.press(t, second, "Yes, add")
+	e.tg.WaitText(user, "Added 2 links to spring.")
+}
+
+func TestBulkJobsOnePerUser(t *testing.T) {
+	jobs := newBulkJobs()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if !jobs.Start(1, func(func()) { close(started); <-release }) {
+		t.Fatal("first job refused")
+	}
+	<-started
+	if jobs.Start(1, func(func()) { t.Error("second job of a user ran") }) {
+		t.Error("second job of a user started while the first runs")
+	}
+	ran := make(chan struct{})
+	if !jobs.Start(2, func(func()) { close(ran) }) {
+		t.Error("job of another user refused")
+	}
+	<-ran
+
+	close(release)
+	jobs.Wait()
+	ran = make(chan struct{})
+	if !jobs.Start(1, func(func()) { close(ran) }) {
+		t.Error("job refused after the previous one finished")
+	}
+	<-ran
+
+	// A released job lets the next one start while it finishes
+	finish := make(chan struct{})
+	if !jobs.Start(1, func(release func()) { release(); <-finish }) {
+		t.Fatal("job refused")
+	}
+	for !jobs.Start(1, func(func()) {}) {
+		time.Sleep(time.Millisecond)
+	}
+	close(finish)
+	jobs.Wait()
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"context"
+	"expvar"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sweptCache is an in-memory structure whose expired entries are removed in
+// the background.
+type sweptCache interface {
+	Len() int
+	Run(ctx context.Context, interval time.Duration, swept func(size int))
+}
+
+// caches returns the bot's in-memory caches by name. Each one is published
+// as a gauge under cache_size.
+func (b *Bot) caches() map[string]sweptCache {
+	caches := map[string]sweptCache{
+		"user_states":       b.userStates,
+		"callback_payloads": b.payloads.users,
+		"recent_links":      b.recentLinks.aliases,
+		"link_lists":        b.linkLists.lists,
+		"link_stats":        b.linkStats.stats,
+		"health_badges":     b.healthBadges.owners,
+		"transfer_offers":   b.transfers,
+		"confirmations":     b.confirmations,
+		"reply_options":     b.replyOptions,
+		"pending_queue":     b.pendingQueue,
+		"recent_messages":   b.recentMessages,
+		"seen_updates":      b.seenUpdates.ids,
+		"abuse_flags":       b.abuse.flags,
+		"daily_counts":      b.dailyCreations.cache,
+	}
+	for _, route := range append(b.router.Commands(), b.router.Callbacks()...) {
+		if route.RateLimit != nil {
+			caches["rate_limit_"+route.Name] = route.RateLimit.hits
+		}
+	}
+	for name, cache := range caches {
+		metrics.CacheSizes.Set(name, expvar.Func(func() any { return cache.Len() }))
+	}
+	return caches
+}
+
+// cacheSwept returns the sweep callback of the named cache, which warns
+// once the cache grows past Caches.WarnSize.
+func (b *Bot) cacheSwept(name string) func(size int) {
+	warned := false
+	return func(size int) {
+		switch {
+		case size > b.config.Caches.WarnSize && !warned:
+			warned = true
+			b.log.Warn("in-memory cache is unusually large",
+				zap.String("cache", name),
+				zap.Int("size", size),
+				zap.Int("warn_size", b.config.Caches.WarnSize))
+		case size <= b.config.Caches.WarnSize && warned:
+			warned = false
+			b.log.Info("in-memory cache is back to normal size", zap.String("cache", name), zap.Int("size", size))
+		}
+	}
+}
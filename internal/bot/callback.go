@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// callbackAnswerTimeout is how long a callback may be processed before it is
+// answered without text, so the client-side spinner doesn't time out.
+const callbackAnswerTimeout = 10 * time.Second
+
+// callbackAnswer collects the text shown to the user when a callback query is
+// answered. All methods are safe to call on a nil receiver, which lets handlers
+// shared with commands ignore the callback case.
+type callbackAnswer struct {
+	mu        sync.Mutex
+	once      sync.Once
+	id        string
+	text      string
+	showAlert bool
+	// chatID is set for buttons pressed by a numbered reply, which have
+	// no query to answer; the text is sent to the chat instead.
+	chatID int64
+}
+
+func newCallbackAnswer(id string) *callbackAnswer {
+	return &callbackAnswer{id: id}
+}
+
+// toast sets a short confirmation shown as a notification.
+func (a *callbackAnswer) toast(text string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.text, a.showAlert = text, false
+}
+
+// note sets a toast unless something else is to be shown already.
+func (a *callbackAnswer) note(text string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.text == "" {
+		a.text, a.showAlert = text, false
+	}
+}
+
+// alert sets an error shown as a modal alert.
+func (a *callbackAnswer) alert(text string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.text, a.showAlert = text, true
+}
+
+// answerCallback answers the callback query once; later calls are no-ops.
+func (b *Bot) answerCallback(a *callbackAnswer) {
+	a.once.Do(func() {
+		a.mu.Lock()
+		cfg := tgbotapi.NewCallback(a.id, a.text)
+		cfg.ShowAlert = a.showAlert
+		a.mu.Unlock()
+
+		if a.chatID != 0 {
+			if cfg.Text != "" {
+				if err := b.sendMessage(a.chatID, cfg.Text, false); err != nil {
+					b.log.Error("failed to send callback answer", zap.Error(err))
+				}
+			}
+			return
+		}
+
+		if _, err := b.api.Request(cfg); err != nil {
+			b.log.Error("failed to answer callback", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/ttlmap"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Telegram rejects inline buttons whose callback data exceeds 64 bytes.
+const maxCallbackDataLen = 64
+
+const (
+	// callbackPayloadSep separates an action code from an inline payload.
+	callbackPayloadSep = ":"
+	// callbackTokenSep separates an action code from a payload store token.
+	callbackTokenSep = "#"
+
+	payloadTTL         = 24 * time.Hour
+	maxPayloadsPerUser = 200
+)
+
+var errCallbackExpired = errors.New("callback payload expired")
+
+// legacyCallbackPrefixes maps callback data prefixes used before the codec
+// was introduced, so buttons on old messages keep working.
+var legacyCallbackPrefixes = map[string]string{
+	"list_delete_": actionListDelete,
+	"stats_":       actionStats,
+	"delete_":      actionDelete,
+}
+
+// payloadKey addresses stored payloads: a button's stored payload only
+// decodes for the user the button was made for, in the chat it was sent to,
+// so members of a group can't press each other's buttons.
+type payloadKey struct {
+	chatID int64
+	// userID is zero for buttons anyone in the chat may press
+	userID int64
+}
+
+// chatPayloads is the payloadKey of buttons anyone in chatID may press, for
+// messages answering no one in particular, such as notifications. In a
+// private chat that is its user.
+func chatPayloads(chatID int64) payloadKey {
+	return payloadKey{chatID: chatID}
+}
+
+// messagePayloads is the payloadKey of buttons answering msg, which only its
+// sender can press.
+func messagePayloads(msg *tgbotapi.Message) payloadKey {
+	to := chatPayloads(msg.Chat.ID)
+	if msg.From != nil {
+		to.userID = msg.From.ID
+	}
+	return to
+}
+
+type payloadEntry struct {
+	payload string
+	expires time.Time
+}
+
+// payloadStore keeps callback payloads that don't fit into callback data,
+// addressed by short tokens per user and chat. The payloads of a user in a
+// chat are forgotten together once all of them expired.
+type payloadStore struct {
+	// mu guards the per-user maps; users guards itself
+	mu    sync.Mutex
+	ttl   time.Duration
+	users *ttlmap.Map[payloadKey, map[string]payloadEntry]
+}
+
+func newPayloadStore(ttl time.Duration) *payloadStore {
+	return &payloadStore{ttl: ttl, users: ttlmap.New[payloadKey, map[string]payloadEntry](ttl, 0)}
+}
+
+// Put stores payload for key and returns its token.
+func (s *payloadStore) Put(key payloadKey, payload string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	user, ok := s.users.Get(key)
+	if !ok {
+		user = make(map[string]payloadEntry)
+	}
+	for token, entry := range user {
+		if now.After(entry.expires) {
+			delete(user, token)
+		}
+	}
+	if len(user) >= maxPayloadsPerUser {
+		var oldest string
+		for token, entry := range user {
+			if oldest == "" || entry.expires.Before(user[oldest].expires) {
+				oldest = token
+			}
+		}
+		delete(user, oldest)
+	}
+
+	token := newPayloadToken()
+	user[token] = payloadEntry{payload: payload, expires: now.Add(s.ttl)}
+	// Kept as long as its newest payload
+	s.users.Set(key, user)
+	return token
+}
+
+// Get returns the payload stored under token for key.
+func (s *payloadStore) Get(key payloadKey, token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, _ := s.users.Get(key)
+	entry, ok := user[token]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.payload, true
+}
+
+func newPayloadToken() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// encodeCallbackData builds callback data for action, inlining payload when it
+// fits and storing it in the payload store otherwise.
+func (b *Bot) encodeCallbackData(to payloadKey, action, payload string) string {
+	if payload == "" {
+		return action
+	}
+	if data := action + callbackPayloadSep + payload; len(data) <= maxCallbackDataLen {
+		return data
+	}
+	return action + callbackTokenSep + b.payloads.Put(to, payload)
+}
+
+// decodeCallbackData splits callback data pressed by from into its action
+// and payload.
+func (b *Bot) decodeCallbackData(from payloadKey, data string) (action, payload string, err error) {
+	if i := strings.IndexAny(data, callbackPayloadSep+callbackTokenSep); i >= 0 {
+		action, rest := data[:i], data[i+1:]
+		if data[i:i+1] == callbackPayloadSep {
+			return action, rest, nil
+		}
+		payload, ok := b.payloads.Get(from, rest)
+		if !ok {
+			payload, ok = b.payloads.Get(chatPayloads(from.chatID), rest)
+		}
+		if !ok {
+			return action, "", errCallbackExpired
+		}
+		return action, payload, nil
+	}
+
+	for prefix, action := range legacyCallbackPrefixes {
+		if strings.HasPrefix(data, prefix) {
+			return action, strings.TrimPrefix(data, prefix), nil
+		}
+	}
+	return data, "", nil
+}
+
+// callbackButton creates a button triggering action without payload.
+func (b *Bot) callbackButton(text, action string) tgbotapi.InlineKeyboardButton {
+	b.checkCallbackData(action)
+	return tgbotapi.NewInlineKeyboardButtonData(text, action)
+}
+
+// payloadButton creates a button triggering action with payload, for the
+// user of to.
+func (b *Bot) payloadButton(to payloadKey, text, action, payload string) tgbotapi.InlineKeyboardButton {
+	data := b.encodeCallbackData(to, action, payload)
+	b.checkCallbackData(data)
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
+
+// storedPayloadButton is payloadButton for payloads that must expire with the
+// payload store even when they would fit into the callback data.
+func (b *Bot) storedPayloadButton(to payloadKey, text, action, payload string) tgbotapi.InlineKeyboardButton {
+	data := action + callbackTokenSep + b.payloads.Put(to, payload)
+	b.checkCallbackData(data)
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
+
+// checkCallbackData reports callback data exceeding Telegram's limit, which
+// would make Telegram refuse the whole keyboard.
+func (b *Bot) checkCallbackData(data string) {
+	if len(data) <= maxCallbackDataLen {
+		return
+	}
+	b.log.Error("callback data exceeds Telegram limit", zap.String("data", data), zap.Int("length", len(data)))
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCallbackActionsFitWithStoredPayload(t *testing.T) {
+	b := &Bot{log: zap.NewNop()}
+	token := newPayloadToken()
+	for _, route := range b.newRouter().Callbacks() {
+		if strings.ContainsAny(route.Name, callbackPayloadSep+callbackTokenSep) {
+			t.Errorf("callback action %q contains a payload separator", route.Name)
+		}
+		if data := route.Name + callbackTokenSep + token; len(data) > maxCallbackDataLen {
+			t.Errorf("callback action %q makes %d bytes of callback data with a stored payload", route.Name, len(data))
+		}
+	}
+}
+
+func TestEncodeCallbackDataStoresLongPayloads(t *testing.T) {
+	b := &Bot{payloads: newPayloadStore(time.Hour)}
+	to := payloadKey{chatID: 42, userID: 42}
+	for _, payload := range []string{"", "abc", strings.Repeat("x", maxCallbackDataLen), "https://example.com/" + strings.Repeat("a", 200)} {
+		data := b.encodeCallbackData(to, actionShortenURL, payload)
+		if len(data) > maxCallbackDataLen {
+			t.Errorf("encodeCallbackData(%q) = %d bytes, over the limit", payload, len(data))
+		}
+		action, got, err := b.decodeCallbackData(to, data)
+		if err != nil || action != actionShortenURL || got != payload {
+			t.Errorf("decodeCallbackData(%q) = %q, %q, %v; want %q, %q", data, action, got, err, actionShortenURL, payload)
+		}
+	}
+}
+
+func TestDecodeCallbackDataExpiredToken(t *testing.T) {
+	b := &Bot{payloads: newPayloadStore(time.Hour)}
+	data := b.encodeCallbackData(payloadKey{chatID: 1, userID: 1}, actionStats, strings.Repeat("x", maxCallbackDataLen))
+	if _, _, err := b.decodeCallbackData(payloadKey{chatID: 2, userID: 2}, data); err != errCallbackExpired {
+		t.Errorf("token of another user decoded with %v, want errCallbackExpired", err)
+	}
+}
+
+func TestDecodeCallbackDataInGroup(t *testing.T) {
+	b := &Bot{payloads: newPayloadStore(time.Hour)}
+	const chat = -100
+	alice := payloadKey{chatID: chat, userID: 1}
+	bob := payloadKey{chatID: chat, userID: 2}
+	payload := strings.Repeat("x", maxCallbackDataLen)
+
+	data := b.encodeCallbackData(alice, actionStats, payload)
+	if _, _, err := b.decodeCallbackData(bob, data); err != errCallbackExpired {
+		t.Errorf("token of another group member decoded with %v, want errCallbackExpired", err)
+	}
+	if _, got, err := b.decodeCallbackData(alice, data); err != nil || got != payload {
+		t.Errorf("own token decoded to %q, %v", got, err)
+	}
+	if _, _, err := b.decodeCallbackData(payloadKey{chatID: 1, userID: 1}, data); err != errCallbackExpired {
+		t.Errorf("group token decoded in a private chat with %v, want errCallbackExpired", err)
+	}
+
+	shared := b.encodeCallbackData(chatPayloads(chat), actionStats, payload)
+	for _, member := range []payloadKey{alice, bob} {
+		if _, got, err := b.decodeCallbackData(member, shared); err != nil || got != payload {
+			t.Errorf("shared token decoded for user %d to %q, %v", member.userID, got, err)
+		}
+	}
+}
+
+func TestCheckCallbackDataLogsOversize(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	b := &Bot{log: zap.New(core)}
+
+	b.callbackButton("ok", actionStats)
+	if logs.Len() != 0 {
+		t.Fatalf("short callback data logged: %v", logs.All())
+	}
+	b.callbackButton("too long", strings.Repeat("x", maxCallbackDataLen+1))
+	if logs.FilterMessage("callback data exceeds Telegram limit").Len() != 1 {
+		t.Errorf("oversize callback data not logged, got %v", logs.All())
+	}
+}
+
+func TestStoredPayloadButtonLogsOversize(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	b := &Bot{log: zap.New(core), payloads: newPayloadStore(time.Hour)}
+	to := payloadKey{chatID: 1, userID: 1}
+
+	b.storedPayloadButton(to, "ok", actionStats, strings.Repeat("x", 500))
+	if logs.Len() != 0 {
+		t.Fatalf("short callback data logged: %v", logs.All())
+	}
+	b.storedPayloadButton(to, "too long", strings.Repeat("x", maxCallbackDataLen), "payload")
+	if logs.FilterMessage("callback data exceeds Telegram limit").Len() != 1 {
+		t.Errorf("oversize callback data not logged, got %v", logs.All())
+	}
+}
+
+func TestE2EStoredPayloadButtonsInGroup(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.sendGroupMessage("/shorten https://example.com/page")
+	created := e.tg.WaitText(group, "Link created successfully")
+
+	// Another member pressing the button made for user finds it expired
+	e.pressAs(t, user+1, created, "Add title")
+	if toast := e.answer(); !strings.Contains(toast.Param("text"), "This shortcut expired") {
+		t.Errorf("toast = %q, want the shortcut expired", toast.Param("text"))
+	}
+
+	e.press(t, created, "Add title")
+	e.tg.WaitText(group, "Send the title for")
+}
@@ -0,0 +1,335 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"cmp"
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// linkStatsRange stands for counting clicks in a date range through
+// GetLinkStats among the capabilities. Backends without ranges count all
+// clicks and leave range_applied unset, which is only noticed on use.
+const linkStatsRange = shortenerv1.Shortener_GetLinkStats_FullMethodName + "#range"
+
+// campaignDateLayout is how campaign dates are typed.
+const campaignDateLayout = "2006-01-02"
+
+// campaignNameRegex is what campaign names look like: one word, so they can
+// be typed after /campaign report.
+var campaignNameRegex = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+var (
+	errCampaignExists   = errors.New("campaign exists")
+	errTooManyCampaigns = errors.New("campaign limit reached")
+	errCampaignFull     = errors.New("campaign is full")
+	errNoCampaign       = errors.New("no such campaign")
+)
+
+// campaignIndex returns the index of the campaign of p called name,
+// ignoring case, or -1.
+func campaignIndex(p prefs.Prefs, name string) int {
+	return slices.IndexFunc(p.Campaigns, func(c prefs.Campaign) bool {
+		return strings.EqualFold(c.Name, name)
+	})
+}
+
+// handleCampaignCommand manages campaigns: "create <name> [start] [end]",
+// "report <name>" and "delete <name>". Without arguments it lists them.
+func (b *Bot) handleCampaignCommand(ctx context.Context, r *Request) error {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(r.Args), " ")
+	args := strings.Fields(rest)
+	switch {
+	case sub == "":
+		return b.listCampaigns(r.Payloads())
+	case strings.EqualFold(sub, "create") && len(args) >= 1 && len(args) <= 3:
+		return b.createCampaign(r.ChatID, args[0], args[1:])
+	case strings.EqualFold(sub, "report") && len(args) == 1:
+		return b.reportCampaign(ctx, r.ChatID, args[0])
+	case strings.EqualFold(sub, "delete") && len(args) == 1:
+		return b.deleteCampaign(r.ChatID, args[0])
+	}
+	return b.reply(r.ChatID, msgCampaignUsage, nil)
+}
+
+// listCampaigns shows the campaigns of to.chatID with a report button each.
+func (b *Bot) listCampaigns(to payloadKey) error {
+	p := b.prefs.Get(to.chatID)
+	if len(p.Campaigns) == 0 {
+		return b.reply(to.chatID, msgNoCampaigns, nil)
+	}
+	data := campaignsData{Locale: b.formatterFor(to.chatID)}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, c := range p.Campaigns {
+		data.Campaigns = append(data.Campaigns, newCampaignData(c))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(to, "Report "+c.Name, actionCampaignReport, c.Name),
+		))
+	}
+	return b.replyWithKeyboard(to.chatID, msgCampaigns, data, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// createCampaign creates the campaign name, counting clicks from the first
+// of dates and up to the second, both inclusive, when given.
+func (b *Bot) createCampaign(chatID int64, name string, dates []string) error {
+	if !campaignNameRegex.MatchString(name) {
+		return b.reply(chatID, msgInvalidCampaignName, nil)
+	}
+	c := prefs.Campaign{Name: name}
+	loc := b.userLocation(chatID)
+	for i, date := range dates {
+		t, err := time.ParseInLocation(campaignDateLayout, date, loc)
+		if err != nil {
+			return b.reply(chatID, msgInvalidCampaignDates, nil)
+		}
+		if i == 0 {
+			c.Start = t
+		} else {
+			c.End = t.AddDate(0, 0, 1)
+		}
+	}
+	if !c.End.IsZero() && !c.End.After(c.Start) {
+		return b.reply(chatID, msgInvalidCampaignDates, nil)
+	}
+
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		if campaignIndex(*p, name) >= 0 {
+			return errCampaignExists
+		}
+		if len(p.Campaigns) >= b.config.Campaigns.MaxPerUser {
+			return errTooManyCampaigns
+		}
+		p.Campaigns = append(p.Campaigns, c)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errCampaignExists):
+		return b.reply(chatID, msgCampaignExists, campaignData{Name: name})
+	case errors.Is(err, errTooManyCampaigns):
+		return b.reply(chatID, msgCampaignLimitReached, limitData{Limit: b.config.Campaigns.MaxPerUser})
+	case err != nil:
+		b.log.Error("failed to save preferences", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	data := newCampaignData(c)
+	data.Locale = b.formatterFor(chatID)
+	return b.reply(chatID, msgCampaignCreated, data)
+}
+
+// deleteCampaign deletes the campaign name. Its links stay as they are.
+func (b *Bot) deleteCampaign(chatID int64, name string) error {
+	var deleted prefs.Campaign
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		i := campaignIndex(*p, name)
+		if i < 0 {
+			return errNoCampaign
+		}
+		deleted = p.Campaigns[i]
+		p.Campaigns = slices.Delete(p.Campaigns, i, i+1)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNoCampaign):
+		return b.reply(chatID, msgCampaignNotFound, campaignData{Name: name})
+	case err != nil:
+		b.log.Error("failed to save preferences", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	return b.reply(chatID, msgCampaignDeleted, newCampaignData(deleted))
+}
+
+// pickCampaign offers the campaigns of the user for alias, those it is in
+// marked, pressing one adding or removing it.
+func (b *Bot) pickCampaign(r *Request) error {
+	alias := r.Args
+	if len(b.prefs.Get(r.ChatID).Campaigns) == 0 {
+		r.Answer.alert(b.render(msgNoCampaigns, nil))
+		return nil
+	}
+	return b.replyWithKeyboard(r.ChatID, msgPickCampaign, linkData{ShortURL: displayURL(b.shortURL(alias))}, b.createCampaignKeyboard(r.Payloads(), alias))
+}
+
+// createCampaignKeyboard lists the campaigns of to.chatID to add alias to or
+// remove it from.
+func (b *Bot) createCampaignKeyboard(to payloadKey, alias string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, c := range b.prefs.Get(to.chatID).Campaigns {
+		label := c.Name
+		if slices.Contains(c.Aliases, alias) {
+			label = "✓ " + c.Name
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, label, actionCampaignToggle, c.Name+"\n"+alias),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(b.callbackButton("Done", callbackCampaignDone)))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// toggleCampaign adds a link to a campaign or removes it, as picked on the
+// campaign keyboard.
+func (b *Bot) toggleCampaign(r *Request) error {
+	name, alias, ok := strings.Cut(r.Args, "\n")
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	var added bool
+	err := b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		i := campaignIndex(*p, name)
+		if i < 0 {
+			return errNoCampaign
+		}
+		c := &p.Campaigns[i]
+		if j := slices.Index(c.Aliases, alias); j >= 0 {
+			c.Aliases = slices.Delete(c.Aliases, j, j+1)
+			return nil
+		}
+		if len(c.Aliases) >= b.config.Campaigns.MaxLinks {
+			return errCampaignFull
+		}
+		c.Aliases = append(c.Aliases, alias)
+		added = true
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNoCampaign):
+		r.Answer.alert(b.render(msgCampaignNotFound, campaignData{Name: name}))
+		return nil
+	case errors.Is(err, errCampaignFull):
+		r.Answer.alert(b.render(msgCampaignFull, limitData{Limit: b.config.Campaigns.MaxLinks}))
+		return nil
+	case err != nil:
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	if added {
+		r.Answer.toast(b.render(msgToastAddedToCampaign, campaignData{Name: name}))
+	} else {
+		r.Answer.toast(b.render(msgToastRemovedFromCampaign, campaignData{Name: name}))
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(r.ChatID, r.Message.MessageID, b.createCampaignKeyboard(r.Payloads(), alias))
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// renameInCampaigns keeps a renamed link in the campaigns it was in.
+func (b *Bot) renameInCampaigns(chatID int64, alias, newAlias string) {
+	in := slices.ContainsFunc(b.prefs.Get(chatID).Campaigns, func(c prefs.Campaign) bool {
+		return slices.Contains(c.Aliases, alias)
+	})
+	if !in {
+		return
+	}
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		for _, c := range p.Campaigns {
+			for i, a := range c.Aliases {
+				if a == alias {
+					c.Aliases[i] = newAlias
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+	}
+}
+
+// reportCampaign sums up the clicks of the links of the campaign name,
+// within its dates when the backend can count by date, with what each
+// link contributed. Links that can't be fetched are listed as such.
+func (b *Bot) reportCampaign(ctx context.Context, chatID int64, name string) error {
+	p := b.prefs.Get(chatID)
+	i := campaignIndex(p, name)
+	if i < 0 {
+		return b.reply(chatID, msgCampaignNotFound, campaignData{Name: name})
+	}
+	c := p.Campaigns[i]
+	if len(c.Aliases) == 0 {
+		return b.reply(chatID, msgCampaignEmpty, campaignData{Name: c.Name})
+	}
+
+	dated := !c.Start.IsZero() || !c.End.IsZero()
+	ranged := dated && b.supports(featureStatsRange)
+	var results fanOutResults[string, *shortenerv1.GetLinkStatsResponse]
+	b.withChatAction(ctx, chatID, tgbotapi.ChatTyping, func() error {
+		results = fanOut(ctx, fanOutOptions{Workers: b.config.Campaigns.Workers}, c.Aliases,
+			func(ctx context.Context, alias string) (*shortenerv1.GetLinkStatsResponse, error) {
+				req := &shortenerv1.GetLinkStatsRequest{Alias: alias}
+				if ranged {
+					req.From = protoTimestamp(c.Start)
+					req.To = protoTimestamp(c.End)
+				}
+				return b.grpcClient.GetLinkStats(ctx, req)
+			})
+		return nil
+	})
+
+	data := campaignReportData{campaignData: newCampaignData(c), Ranged: ranged}
+	data.Locale = b.formatterFor(chatID)
+	for _, res := range results {
+		link := campaignLinkData{ShortURL: displayURL(b.shortURL(res.Item))}
+		switch {
+		case status.Code(res.Err) == codes.NotFound:
+			link.Deleted = true
+		case res.Err != nil:
+			b.log.Debug("failed to fetch campaign link", zap.String("alias", res.Item), zap.Error(res.Err))
+			link.Unavailable = true
+		default:
+			link.ShortURL = displayURL(b.shortURLOn(res.Value.GetDomain(), res.Item))
+			link.Clicks = res.Value.GetClickCount()
+			data.Total += link.Clicks
+			if ranged && !res.Value.GetRangeApplied() {
+				data.Ranged = false
+			}
+		}
+		data.Links = append(data.Links, link)
+	}
+	if ranged && len(results.Succeeded()) > 0 && b.capabilities.Set(linkStatsRange, data.Ranged) {
+		b.capabilitiesChanged()
+	}
+	data.Dated = dated
+	for i := range data.Links {
+		if data.Total > 0 {
+			data.Links[i].Share = int(data.Links[i].Clicks * 100 / data.Total)
+		}
+	}
+	slices.SortStableFunc(data.Links, func(x, y campaignLinkData) int {
+		return cmp.Compare(y.Clicks, x.Clicks)
+	})
+	return b.sendMessage(chatID, b.render(msgCampaignReport, data), false, b.linkContent())
+}
+
+// newCampaignData describes c for messages.
+func newCampaignData(c prefs.Campaign) campaignData {
+	data := campaignData{Name: c.Name, Links: len(c.Aliases)}
+	if !c.Start.IsZero() {
+		data.Start = &c.Start
+	}
+	if !c.End.IsZero() {
+		// The last day counted, as typed
+		last := c.End.AddDate(0, 0, -1)
+		data.End = &last
+	}
+	return data
+}
+
+// protoTimestamp converts t, leaving the zero time unset.
+func protoTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/features"
+	"GURLS-Bot/internal/grpc/client"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Features that need backend methods older backends may lack, or that can
+// be turned off per deployment, or both.
+const (
+	featureExpand    = "expand"
+	featureDashboard = "dashboard"
+	featureRename    = "rename"
+	featureExtend    = "extend"
+	// featureUpdateDestination is pointing a link at a new URL.
+	featureUpdateDestination = "update_destination"
+	// featureAnalyticsUpdate is changing the analytics of existing links;
+	// without it they are only chosen on creation.
+	featureAnalyticsUpdate = "analytics_update"
+	// featureTitleUpdate is changing the title of existing links.
+	featureTitleUpdate = "title_update"
+	// featureStatsRange is counting clicks within dates; without it
+	// campaign reports count all clicks.
+	featureStatsRange = "stats_range"
+	// featureScheduling is creating links that go live later.
+	featureScheduling = "scheduling"
+	// featurePreview is setting the preview shown for a short link.
+	featurePreview = "preview"
+	// featureAliasStyle is choosing the style of generated aliases.
+	featureAliasStyle = "alias_style"
+	featureTransfer   = features.Transfer
+	// featurePagination is fetching link lists page by page; without it
+	// they are paged from the full list.
+	featurePagination = "pagination"
+	featureInline     = features.Inline
+	featureMonitor    = features.Monitor
+)
+
+// featureMethods lists the backend methods each feature needs.
+var featureMethods = map[string][]string{
+	featureExpand: {shortenerv1.Shortener_ResolveLink_FullMethodName},
+	featureDashboard: {
+		shortenerv1.Shortener_GenerateLinkToken_FullMethodName,
+		shortenerv1.Shortener_GetLinkTokenStatus_FullMethodName,
+		shortenerv1.Shortener_DisconnectDashboard_FullMethodName,
+	},
+	featureRename:            {shortenerv1.Shortener_RenameLink_FullMethodName},
+	featureExtend:            {shortenerv1.Shortener_SetLinkExpiry_FullMethodName},
+	featurePagination:        {listPagination},
+	featureUpdateDestination: {shortenerv1.Shortener_UpdateLink_FullMethodName},
+	featureAnalyticsUpdate:   {shortenerv1.Shortener_UpdateLink_FullMethodName, linkAnalyticsUpdate},
+	featureTransfer:          {shortenerv1.Shortener_TransferLink_FullMethodName},
+	featureTitleUpdate:       {shortenerv1.Shortener_UpdateLink_FullMethodName, linkTitleUpdate},
+	featureStatsRange:        {shortenerv1.Shortener_GetLinkStats_FullMethodName, linkStatsRange},
+	featureScheduling:        {linkScheduling},
+	featurePreview:           {shortenerv1.Shortener_SetLinkPreview_FullMethodName},
+	featureAliasStyle:        {linkAliasStyles},
+}
+
+// capabilities tracks the backend methods known to be unimplemented. Methods
+// are assumed to be supported until a probe or a call says otherwise.
+type capabilities struct {
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+func newCapabilities() *capabilities {
+	return &capabilities{missing: make(map[string]bool)}
+}
+
+// Set records whether method is implemented and reports whether that changed.
+func (c *capabilities) Set(method string, implemented bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.missing[method] == !implemented {
+		return false
+	}
+	if implemented {
+		delete(c.missing, method)
+	} else {
+		c.missing[method] = true
+	}
+	return true
+}
+
+// Supports reports whether all of methods are implemented.
+func (c *capabilities) Supports(methods ...string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, method := range methods {
+		if c.missing[method] {
+			return false
+		}
+	}
+	return true
+}
+
+// supports reports whether feature is enabled in this deployment and the
+// backend implements what it needs.
+func (b *Bot) supports(feature string) bool {
+	return b.features.Enabled(feature) && b.capabilities.Supports(featureMethods[feature]...)
+}
+
+// degradedFeatures returns the features the backend doesn't support, sorted.
+// Features turned off by flags aren't degraded.
+func (b *Bot) degradedFeatures() []string {
+	var degraded []string
+	for feature, methods := range featureMethods {
+		if !b.capabilities.Supports(methods...) {
+			degraded = append(degraded, feature)
+		}
+	}
+	slices.Sort(degraded)
+	return degraded
+}
+
+// probeCapabilities asks the backend which optional methods it implements
+// and reports whether anything changed. When it can't tell, e.g. for lack
+// of gRPC reflection, methods keep their last known state and are learned
+// from regular calls.
+func (b *Bot) probeCapabilities(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, b.config.GRPCClient.Timeout)
+	implemented, err := b.grpcClient.Methods(ctx)
+	cancel()
+	if err != nil {
+		b.log.Debug("capability probe failed", zap.Error(err))
+		return false
+	}
+	changed := false
+	for _, method := range client.ProbedMethods() {
+		if b.capabilities.Set(method, implemented[method]) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// runCapabilityProbes re-probes the backend and reloads its alias rules
+// periodically, so upgrades and downgrades are picked up without a restart.
+func (b *Bot) runCapabilityProbes(ctx context.Context) {
+	ticker := time.NewTicker(b.config.GRPCClient.CapabilityRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if b.probeCapabilities(ctx) {
+				b.capabilitiesChanged()
+			}
+			b.loadAliasRules(ctx)
+		}
+	}
+}
+
+// observeCapability learns from a regular call whether method is
+// implemented.
+func (b *Bot) observeCapability(method string, err error) {
+	if isOutage(err) || status.Code(err) == codes.Canceled {
+		return
+	}
+	if b.capabilities.Set(method, status.Code(err) != codes.Unimplemented) {
+		b.capabilitiesChanged()
+	}
+}
+
+// capabilitiesChanged logs the new state and updates the command menu,
+// which leaves out unsupported commands.
+func (b *Bot) capabilitiesChanged() {
+	b.logCapabilities()
+	b.publishCommands()
+}
+
+// logCapabilities logs the features disabled for lack of backend support.
+func (b *Bot) logCapabilities() {
+	if degraded := b.degradedFeatures(); len(degraded) > 0 {
+		b.log.Warn("backend lacks methods, features disabled", zap.Strings("features", degraded))
+		return
+	}
+	b.log.Info("backend supports all features")
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/telegramtest"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// menuCommands returns the commands of a published command menu.
+func menuCommands(t *testing.T, r telegramtest.Request) []string {
+	t.Helper()
+	var commands []tgbotapi.BotCommand
+	if err := json.Unmarshal([]byte(r.Param("commands")), &commands); err != nil {
+		t.Fatalf("bad commands %q: %v", r.Param("commands"), err)
+	}
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.Command
+	}
+	return names
+}
+
+// waitMenu waits for a command menu published for everyone whose commands
+// include /command or, unless offered, leave it out. Menus are published
+// once per language, so earlier ones may still be pending.
+func (e *e2e) waitMenu(t *testing.T, command string, offered bool) {
+	t.Helper()
+	e.tg.Wait("setMyCommands", func(r telegramtest.Request) bool {
+		return strings.Contains(r.Param("scope"), `"default"`) && slices.Contains(menuCommands(t, r), command) == offered
+	})
+}
+
+// statsButtons shows the stats of alias and returns their buttons.
+func (e *e2e) statsButtons(alias string) []string {
+	e.tg.SendMessage(user, "/stats "+alias)
+	var texts []string
+	for _, b := range e.tg.WaitText(user, "Link Statistics: "+alias).Buttons() {
+		texts = append(texts, b.Text)
+	}
+	return texts
+}
+
+func TestCapabilitiesFullBackend(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "mine", OriginalURL: "https://example.com/", OwnerID: user})
+
+	e.waitMenu(t, "expand", true)
+	if buttons := e.statsButtons("mine"); !slices.Contains(buttons, "Rename") {
+		t.Errorf("stats buttons %v lack Rename", buttons)
+	}
+	if degraded := e.bot.degradedFeatures(); len(degraded) != 0 {
+		t.Errorf("degraded features = %v, want none", degraded)
+	}
+}
+
+func TestCapabilitiesProbedMissingMethods(t *testing.T) {
+	e := startBot(t, nil, backendtest.Without(
+		shortenerv1.Shortener_ResolveLink_FullMethodName,
+		shortenerv1.Shortener_RenameLink_FullMethodName,
+	))
+	e.backend.Add(backendtest.Link{Alias: "mine", OriginalURL: "https://example.com/", OwnerID: user})
+
+	// The menu is first published after the probe
+	e.waitMenu(t, "expand", false)
+	for _, r := range e.tg.Requests("setMyCommands") {
+		if slices.Contains(menuCommands(t, r), "expand") {
+			t.Errorf("menu %v offers /expand", menuCommands(t, r))
+		}
+	}
+	if buttons := e.statsButtons("mine"); slices.Contains(buttons, "Rename") {
+		t.Errorf("stats buttons %v offer Rename", buttons)
+	}
+	if degraded := e.bot.degradedFeatures(); !slices.Contains(degraded, featureExpand) || !slices.Contains(degraded, featureRename) {
+		t.Errorf("degraded features = %v", degraded)
+	}
+
+	e.tg.SendMessage(user, "/expand mine")
+	e.tg.WaitText(user, "not available yet")
+	if calls := e.backend.Calls(shortenerv1.Shortener_ResolveLink_FullMethodName); len(calls) != 0 {
+		t.Errorf("ResolveLink called %d times though known missing", len(calls))
+	}
+}
+
+func TestCapabilitiesLearnedFromCalls(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "mine", OriginalURL: "https://example.com/", OwnerID: user})
+	// Reflection lists the method, yet calls are refused, as behind a
+	// proxy that strips it
+	e.backend.Fail(shortenerv1.Shortener_ResolveLink_FullMethodName, status.Error(codes.Unimplemented, "unknown method"))
+	e.waitMenu(t, "expand", true)
+
+	// The first call falls back to the stats of the link
+	e.tg.SendMessage(user, "/expand mine")
+	e.tg.WaitText(user, "leads to")
+	e.waitMenu(t, "expand", false)
+	if e.bot.supports(featureExpand) {
+		t.Error("expand still supported after an Unimplemented call")
+	}
+
+	e.tg.SendMessage(user, "/expand mine")
+	e.tg.WaitText(user, "not available yet")
+	if calls := e.backend.Calls(shortenerv1.Shortener_ResolveLink_FullMethodName); len(calls) != 1 {
+		t.Errorf("ResolveLink called %d times, want once", len(calls))
+	}
+}
@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Ways the short links of a channel post are published.
+const (
+	channelModeEdit  = "edit"
+	channelModeReply = "reply"
+)
+
+// errNoChannel means a command naming no channel didn't come from the
+// discussion group of one.
+var errNoChannel = errors.New("no channel given")
+
+const (
+	// maxPostLen and maxCaptionLen are Telegram's limits on the text and
+	// the caption of a message, in UTF-16 code units.
+	maxPostLen    = 4096
+	maxCaptionLen = 1024
+)
+
+// handleChannelCommand turns shortening of a channel's posts on or off:
+// /channel [<@channel or ID>] edit|reply|off. In the channel's discussion
+// group the channel may be left out. Only administrators of the channel
+// may do so, and the links are created on their account.
+func (b *Bot) handleChannelCommand(ctx context.Context, r *Request) error {
+	args := strings.Fields(r.Args)
+	var target, mode string
+	switch len(args) {
+	case 1:
+		mode = args[0]
+	case 2:
+		target, mode = args[0], args[1]
+	default:
+		return b.reply(r.ChatID, msgChannelUsage, nil)
+	}
+	mode = strings.ToLower(mode)
+	if mode != channelModeEdit && mode != channelModeReply && mode != "off" {
+		return b.reply(r.ChatID, msgChannelUsage, nil)
+	}
+
+	channel, err := b.resolveChannel(r, target)
+	if err != nil {
+		b.log.Info("failed to resolve channel", zap.String("channel", target), zap.Error(err))
+		return b.reply(r.ChatID, msgChannelNotFound, nil)
+	}
+	if channel.Type != "channel" {
+		return b.reply(r.ChatID, msgChannelUsage, nil)
+	}
+	admins, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: channel.ID},
+	})
+	if err != nil {
+		b.log.Info("failed to get channel administrators", zap.Int64("channel_id", channel.ID), zap.Error(err))
+		return b.reply(r.ChatID, msgChannelNotFound, nil)
+	}
+	var isAdmin, canEdit bool
+	for _, admin := range admins {
+		switch {
+		case admin.User == nil:
+		case admin.User.ID == r.UserID:
+			isAdmin = true
+		case admin.User.IsBot && strings.EqualFold(admin.User.UserName, b.username):
+			canEdit = admin.CanEditMessages
+		}
+	}
+	if !isAdmin {
+		return b.reply(r.ChatID, msgChannelAdminsOnly, nil)
+	}
+
+	data := channelData{Title: channel.Title, Mode: mode}
+	setup := &prefs.ChannelShortening{OwnerID: r.UserID, Mode: mode, DiscussionID: channel.LinkedChatID}
+	switch mode {
+	case channelModeEdit:
+		if !canEdit {
+			return b.reply(r.ChatID, msgChannelCantEdit, nil)
+		}
+	case channelModeReply:
+		if channel.LinkedChatID == 0 {
+			return b.reply(r.ChatID, msgChannelNoDiscussion, nil)
+		}
+	default:
+		setup = nil
+	}
+	err = b.prefs.Update(channel.ID, func(p *prefs.Prefs) error {
+		p.Channel = setup
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save channel setting", zap.Error(err))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	b.log.Info("channel shortening changed",
+		zap.Int64("channel_id", channel.ID),
+		zap.Int64("user_id", r.UserID),
+		zap.String("mode", mode))
+	if setup == nil {
+		return b.reply(r.ChatID, msgChannelOff, data)
+	}
+	return b.reply(r.ChatID, msgChannelOn, data)
+}
+
+// resolveChannel finds the channel named by target, an @username or a chat
+// ID, or without a target the channel of the discussion group r comes from.
+func (b *Bot) resolveChannel(r *Request, target string) (tgbotapi.Chat, error) {
+	var chat tgbotapi.ChatConfig
+	switch {
+	case strings.HasPrefix(target, "@"):
+		chat.SuperGroupUsername = target
+	case target != "":
+		id, err := strconv.ParseInt(target, 10, 64)
+		if err != nil {
+			return tgbotapi.Chat{}, err
+		}
+		chat.ChatID = id
+	default:
+		if r.IsPrivate() {
+			return tgbotapi.Chat{}, errNoChannel
+		}
+		group, err := b.getChat(tgbotapi.ChatConfig{ChatID: r.ChatID})
+		if err != nil {
+			return tgbotapi.Chat{}, err
+		}
+		if group.LinkedChatID == 0 {
+			return tgbotapi.Chat{}, errNoChannel
+		}
+		chat.ChatID = group.LinkedChatID
+	}
+	return b.getChat(chat)
+}
+
+// getChat fetches the full description of a chat.
+func (b *Bot) getChat(chat tgbotapi.ChatConfig) (tgbotapi.Chat, error) {
+	resp, err := b.api.Request(tgbotapi.ChatInfoConfig{ChatConfig: chat})
+	if err != nil {
+		return tgbotapi.Chat{}, err
+	}
+	var info tgbotapi.Chat
+	if err := json.Unmarshal(resp.Result, &info); err != nil {
+		return tgbotapi.Chat{}, err
+	}
+	return info, nil
+}
+
+// handleChannelPost shortens the URLs of a post in a channel set to
+// channelModeEdit and appends the short links to it. A post that would
+// grow past Telegram's limits, or can't be edited, gets them in a
+// follow-up post instead. Refusals go to the owner privately.
+func (b *Bot) handleChannelPost(ctx context.Context, post *tgbotapi.Message) error {
+	setup := b.prefs.Get(post.Chat.ID).Channel
+	if setup == nil || setup.Mode != channelModeEdit {
+		return nil
+	}
+	links, err := b.shortenPostedURLs(ctx, post, setup.OwnerID, setup.OwnerID, sourceBotChannel)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+
+	suffix := "\n\n" + b.render(msgAutoShortened, autoShortenedData{Links: links})
+	var edit tgbotapi.Chattable
+	switch {
+	case post.Text != "" && utf16Len(post.Text+suffix) <= maxPostLen:
+		e := tgbotapi.NewEditMessageText(post.Chat.ID, post.MessageID, post.Text+suffix)
+		e.Entities = post.Entities
+		e.ReplyMarkup = post.ReplyMarkup
+		edit = e
+	case post.Caption != "" && utf16Len(post.Caption+suffix) <= maxCaptionLen:
+		e := tgbotapi.NewEditMessageCaption(post.Chat.ID, post.MessageID, post.Caption+suffix)
+		e.CaptionEntities = post.CaptionEntities
+		e.ReplyMarkup = post.ReplyMarkup
+		edit = e
+	}
+	if edit != nil {
+		err := b.editMessage(edit, nil, nil)
+		if err == nil {
+			return nil
+		}
+		b.log.Warn("failed to edit channel post", zap.Int64("channel_id", post.Chat.ID), zap.Error(err))
+	}
+	return b.replyAutoShortened(post, links)
+}
+
+// handleChannelForward shortens the URLs of a post of a channel set to
+// channelModeReply once it is forwarded to the channel's discussion group,
+// and replies there with the short links. Forwards of other channels are
+// left alone, as they have no author to own the links.
+func (b *Bot) handleChannelForward(ctx context.Context, msg *tgbotapi.Message) error {
+	if msg.ForwardFromChat == nil {
+		return nil
+	}
+	setup := b.prefs.Get(msg.ForwardFromChat.ID).Channel
+	if setup == nil || setup.Mode != channelModeReply || setup.DiscussionID != msg.Chat.ID {
+		return nil
+	}
+	links, err := b.shortenPostedURLs(ctx, msg, setup.OwnerID, setup.OwnerID, sourceBotChannel)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	return b.replyAutoShortened(msg, links)
+}
+
+// utf16Len returns the length of s in UTF-16 code units, the unit of
+// Telegram's length limits.
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
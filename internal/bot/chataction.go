@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// chatActionRefreshInterval is how often a chat action is re-sent; Telegram
+// shows an action for about five seconds.
+const chatActionRefreshInterval = 4 * time.Second
+
+// withChatAction shows the given chat action (tgbotapi.ChatTyping,
+// tgbotapi.ChatUploadPhoto, ...) in chatID until fn returns.
+func (b *Bot) withChatAction(ctx context.Context, chatID int64, action string, fn func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	// The deferred cancel also runs when fn panics, so the refresher never outlives the handler.
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(chatActionRefreshInterval)
+		defer ticker.Stop()
+		for {
+			b.sendChatAction(chatID, action)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return fn()
+}
+
+func (b *Bot) sendChatAction(chatID int64, action string) {
+	if _, err := b.api.Request(tgbotapi.NewChatAction(chatID, action)); err != nil {
+		b.log.Debug("failed to send chat action", zap.Int64("chat_id", chatID), zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,293 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// cleanupCheckInterval is how often the job looks for users due for
+// suggestions; each user gets them at most once per Cleanup.Interval.
+const cleanupCheckInterval = time.Hour
+
+// cleanupDeletion is the payload of the button confirming "Delete all listed".
+type cleanupDeletion struct {
+	// MessageID is the suggestion message the links were listed in.
+	MessageID int      `json:"message_id"`
+	Aliases   []string `json:"aliases"`
+}
+
+// runCleanupSuggestions periodically sends cleanup suggestions to the users
+// who opted in, until ctx is done.
+func (b *Bot) runCleanupSuggestions(ctx context.Context) {
+	ticker := time.NewTicker(cleanupCheckInterval)
+	defer ticker.Stop()
+	for {
+		b.suggestCleanups(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// suggestCleanups sends suggestions to every opted-in user due for them.
+func (b *Bot) suggestCleanups(ctx context.Context, now time.Time) {
+	for _, user := range b.users.List() {
+		if ctx.Err() != nil {
+			return
+		}
+		p := b.prefs.Get(user.ID)
+		if !p.Cleanup || !user.BlockedAt.IsZero() || now.Sub(p.CleanupAt) < b.config.Cleanup.Interval {
+			continue
+		}
+		if err := b.suggestCleanup(ctx, user.ID, now); err != nil {
+			b.log.Warn("failed to suggest cleanup", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
+}
+
+// suggestCleanup lists the dead links of userID, if any, in their private
+// chat. The scan counts as done even when nothing is found, so the user's
+// links are looked at once per interval at most.
+func (b *Bot) suggestCleanup(ctx context.Context, userID int64, now time.Time) error {
+	res, err := b.listUserLinks(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	kept := b.prefs.Get(userID).CleanupKept
+	var candidates []*shortenerv1.LinkInfo
+	for _, link := range res.Links {
+		if at, ok := kept[link.Alias]; ok && now.Sub(at) < b.config.Cleanup.KeepFor {
+			continue
+		}
+		candidates = append(candidates, link)
+	}
+
+	results := fanOut(ctx, fanOutOptions{Workers: b.config.Cleanup.Workers}, candidates,
+		func(ctx context.Context, link *shortenerv1.LinkInfo) (*shortenerv1.GetLinkStatsResponse, error) {
+			return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: link.Alias})
+		})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var links []cleanupLink
+	var aliases []string
+	for _, r := range results.Succeeded() {
+		link, ok := b.deadLink(r.Item, r.Value, now)
+		if !ok {
+			continue
+		}
+		links = append(links, link)
+		aliases = append(aliases, r.Item.Alias)
+		if len(links) == b.config.Cleanup.MaxListed {
+			break
+		}
+	}
+	if failed := results.Failed(); len(failed) > 0 {
+		b.log.Debug("cleanup scan incomplete", zap.Int64("user_id", userID), zap.Int("failed", len(failed)))
+	}
+
+	err = b.prefs.Update(userID, func(p *prefs.Prefs) error {
+		p.CleanupAt = now
+		// Expired cool-downs are forgotten
+		for alias, at := range p.CleanupKept {
+			if now.Sub(at) >= b.config.Cleanup.KeepFor {
+				delete(p.CleanupKept, alias)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	text := b.render(msgCleanupSuggestions, cleanupData{
+		Locale:   b.formatterFor(userID),
+		Links:    links,
+		KeepDays: int(b.config.Cleanup.KeepFor / (24 * time.Hour)),
+	})
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ReplyMarkup = b.createCleanupKeyboard(userID, aliases)
+	msg.DisableWebPagePreview = true
+	key := fmt.Sprintf("cleanup/%d/%s", userID, now.Format(time.DateOnly))
+	return b.sendCritical(key, msg, b.notification(userID), b.linkContent())
+}
+
+// deadLink reports whether link is expired or went without clicks for
+// Cleanup.IdleAfter since its creation. Links the backend doesn't report a
+// creation time for are only suggested once expired.
+func (b *Bot) deadLink(link *shortenerv1.LinkInfo, stats *shortenerv1.GetLinkStatsResponse, now time.Time) (cleanupLink, bool) {
+	dead := cleanupLink{ShortURL: displayURL(b.shortURLOn(link.GetDomain(), link.Alias)), Title: displayTitle(link.GetTitle())}
+	if expired := protoTime(stats.GetExpiresAt()); expired != nil && expired.Before(now) {
+		dead.ExpiredAt = expired
+		return dead, true
+	}
+	if created := protoTime(stats.GetCreatedAt()); stats.GetClickCount() == 0 && created != nil && now.Sub(*created) >= b.config.Cleanup.IdleAfter {
+		dead.CreatedAt = created
+		return dead, true
+	}
+	return cleanupLink{}, false
+}
+
+// createCleanupKeyboard offers Keep and Delete for every listed alias.
+func (b *Bot) createCleanupKeyboard(chatID int64, aliases []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, alias := range aliases {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(chatPayloads(chatID), "Keep "+alias, actionCleanupKeep, alias),
+			b.payloadButton(chatPayloads(chatID), "Delete "+alias, actionCleanupDelete, alias),
+		))
+	}
+	nav := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Delete all listed", callbackCleanupDeleteAll),
+	)}
+	return b.fitKeyboard("cleanup", nav, rows)
+}
+
+// keepCleanupLink suppresses suggestions for alias for Cleanup.KeepFor.
+func (b *Bot) keepCleanupLink(r *Request) error {
+	err := b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		if p.CleanupKept == nil {
+			p.CleanupKept = make(map[string]time.Time)
+		}
+		p.CleanupKept[r.Args] = time.Now()
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	r.Answer.toast(b.render(msgToastKept, nil))
+	return b.dropCleanupRow(r)
+}
+
+// deleteCleanupLink deletes alias from the suggestion message.
+func (b *Bot) deleteCleanupLink(ctx context.Context, r *Request) error {
+	alias := r.Args
+	if err := b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: alias}); err != nil {
+		b.log.Error("gRPC DeleteLink failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	b.unpin(r.ChatID, alias)
+	b.staleKeyboards(alias)
+	b.recordHistory(r.ChatID, prefs.HistoryEntry{Action: historyDeleted, Alias: alias})
+	r.Answer.toast(b.render(msgToastDeleted, linkData{ShortURL: displayURL(b.shortURL(alias))}))
+	return b.dropCleanupRow(r)
+}
+
+// dropCleanupRow removes the row of the pressed button from the suggestion
+// message, or closes it when no links are left.
+func (b *Bot) dropCleanupRow(r *Request) error {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if markup := r.Message.ReplyMarkup; markup != nil {
+		for _, row := range markup.InlineKeyboard {
+			if !rowHasCallback(row, r.Callback.Data) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	if len(b.cleanupAliases(r.Payloads(), rows)) == 0 {
+		return b.editMessage(tgbotapi.NewEditMessageText(r.ChatID, r.Message.MessageID, b.render(msgCleanupDone, nil)), nil, nil)
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(r.ChatID, r.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// confirmCleanupDeletion asks to confirm deleting every link still listed
+// in the suggestion message.
+func (b *Bot) confirmCleanupDeletion(r *Request) error {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if markup := r.Message.ReplyMarkup; markup != nil {
+		rows = markup.InlineKeyboard
+	}
+	aliases := b.cleanupAliases(r.Payloads(), rows)
+	if len(aliases) == 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	payload, err := json.Marshal(cleanupDeletion{MessageID: r.Message.MessageID, Aliases: aliases})
+	if err != nil {
+		return err
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(r.Payloads(), "Yes, delete", actionCleanupDeleteAll, string(payload)),
+		b.callbackButton("Cancel", callbackCleanupCancel),
+	))
+	return b.sendMessageWithKeyboard(r.ChatID, b.render(msgCleanupConfirmDelete, countData{Count: len(aliases)}), keyboard)
+}
+
+// deleteCleanupLinks deletes the confirmed links and reports the outcome in
+// place of the confirmation.
+func (b *Bot) deleteCleanupLinks(ctx context.Context, r *Request) error {
+	var deletion cleanupDeletion
+	if err := json.Unmarshal([]byte(r.Args), &deletion); err != nil || len(deletion.Aliases) == 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+
+	results := fanOut(ctx, fanOutOptions{Workers: b.config.Cleanup.Workers}, deletion.Aliases,
+		func(ctx context.Context, alias string) (struct{}, error) {
+			return struct{}{}, b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: alias})
+		})
+	for _, res := range results.Succeeded() {
+		b.unpin(r.ChatID, res.Item)
+		b.staleKeyboards(res.Item)
+		b.recordHistory(r.ChatID, prefs.HistoryEntry{Action: historyDeleted, Alias: res.Item})
+	}
+	for _, res := range results.Failed() {
+		b.log.Warn("cleanup deletion failed", zap.String("alias", res.Item), zap.Error(res.Err))
+	}
+
+	text := b.render(msgCleanupDeleted, cleanupDeletedData{Deleted: len(results.Succeeded()), Failed: len(results.Failed())})
+	if err := b.editMessageText(r.ChatID, r.Message.MessageID, text); err != nil {
+		return err
+	}
+	if len(results.Failed()) == 0 {
+		done := tgbotapi.NewEditMessageText(r.ChatID, deletion.MessageID, b.render(msgCleanupDone, nil))
+		if err := b.editMessage(done, nil, nil); err != nil {
+			b.log.Debug("failed to close cleanup suggestions", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// cleanupAliases returns the aliases of the Delete buttons in rows.
+func (b *Bot) cleanupAliases(to payloadKey, rows [][]tgbotapi.InlineKeyboardButton) []string {
+	var aliases []string
+	for _, row := range rows {
+		for _, button := range row {
+			if button.CallbackData == nil {
+				continue
+			}
+			action, alias, err := b.decodeCallbackData(to, *button.CallbackData)
+			if err == nil && action == actionCleanupDelete && alias != "" {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases
+}
+
+func rowHasCallback(row []tgbotapi.InlineKeyboardButton, data string) bool {
+	for _, button := range row {
+		if button.CallbackData != nil && *button.CallbackData == data {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// commandScope is a Telegram command menu together with the rule deciding
+// which routes belong to it.
+type commandScope struct {
+	name     string
+	scope    tgbotapi.BotCommandScope
+	includes func(r *Route) bool
+}
+
+// commandScopes lists the menus the bot publishes. Telegram shows the most
+// specific one: an admin's private chat gets the admin menu, other private
+// chats and groups get theirs, and the default covers anything else.
+func (b *Bot) commandScopes() []commandScope {
+	private := func(r *Route) bool { return !r.AdminOnly && !r.GroupOnly }
+	group := func(r *Route) bool { return !r.AdminOnly && !r.PrivateOnly }
+	scopes := []commandScope{
+		{"default", tgbotapi.NewBotCommandScopeDefault(), func(r *Route) bool { return private(r) && group(r) }},
+		{"all_private_chats", tgbotapi.NewBotCommandScopeAllPrivateChats(), private},
+		{"all_group_chats", tgbotapi.NewBotCommandScopeAllGroupChats(), group},
+	}
+	for _, chatID := range b.config.Telegram.AdminChatIDs {
+		scopes = append(scopes, commandScope{"admin", tgbotapi.NewBotCommandScopeChat(chatID), func(r *Route) bool { return !r.GroupOnly }})
+	}
+	return scopes
+}
+
+// publishCommands sets the command menu of every scope from the router,
+// leaving out commands the backend doesn't support. Scopes left without
+// commands are deleted so that removed commands don't
+// linger in clients. Menus of chats since removed from AdminChatIDs are not
+// tracked and have to be deleted by hand. Failures are only logged.
+func (b *Bot) publishCommands() {
+	for _, s := range b.commandScopes() {
+		var commands []tgbotapi.BotCommand
+		for _, r := range b.router.Commands() {
+			if r.Description != "" && s.includes(r) && (r.Feature == "" || b.supports(r.Feature)) {
+				commands = append(commands, tgbotapi.BotCommand{Command: r.Name, Description: r.Description})
+			}
+		}
+
+		var err error
+		if len(commands) == 0 {
+			_, err = b.api.Request(tgbotapi.NewDeleteMyCommandsWithScope(s.scope))
+		} else {
+			_, err = b.api.Request(tgbotapi.NewSetMyCommandsWithScope(s.scope, commands...))
+		}
+		if err != nil {
+			b.log.Warn("failed to publish commands", zap.String("scope", s.name), zap.Int64("chat_id", s.scope.ChatID), zap.Error(err))
+		}
+	}
+}
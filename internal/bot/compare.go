@@ -0,0 +1,198 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/locale"
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// compareRecentLinks caps the recent links offered to compare a link with.
+const compareRecentLinks = 6
+
+// compareRow is a row of a link comparison.
+type compareRow struct {
+	Label string
+	// value returns the text of the row for s and its score, the higher
+	// leading. ranked is false when s lacks the data, which leaves the row
+	// without a leader.
+	value func(f locale.Formatter, now time.Time, s statsData) (text string, score int64, ranked bool)
+}
+
+// compareRows are the rows of a link comparison, in order.
+var compareRows = []compareRow{
+	{Label: "Total clicks", value: func(f locale.Formatter, _ time.Time, s statsData) (string, int64, bool) {
+		return f.Number(s.Clicks), s.Clicks, true
+	}},
+	{Label: "Last click", value: func(_ locale.Formatter, now time.Time, s statsData) (string, int64, bool) {
+		switch {
+		case s.LastClickAt != nil:
+			return formatRemaining(now.Sub(*s.LastClickAt)) + " ago", s.LastClickAt.Unix(), true
+		case s.Clicks == 0:
+			return "never", 0, true
+		}
+		return "—", 0, false
+	}},
+	{Label: "Top device", value: func(f locale.Formatter, _ time.Time, s statsData) (string, int64, bool) {
+		if len(s.ClicksByDevice) == 0 {
+			return "—", 0, false
+		}
+		// Ties go to the device first in alphabetical order
+		devices := slices.Sorted(maps.Keys(s.ClicksByDevice))
+		top := slices.MaxFunc(devices, func(a, b string) int {
+			return cmp.Compare(s.ClicksByDevice[a], s.ClicksByDevice[b])
+		})
+		n := s.ClicksByDevice[top]
+		return top + " (" + f.Number(n) + ")", n, true
+	}},
+}
+
+// compareStats lays out the comparison of the stats of two links row by
+// row, marking the leader of every row both links have data for.
+func compareStats(f locale.Formatter, now time.Time, a, b statsData) []compareRowData {
+	rows := make([]compareRowData, 0, len(compareRows))
+	for _, row := range compareRows {
+		textA, scoreA, rankedA := row.value(f, now, a)
+		textB, scoreB, rankedB := row.value(f, now, b)
+		data := compareRowData{Label: row.Label}
+		data.Cells[0] = compareCell{Text: textA, Leads: rankedA && rankedB && scoreA > scoreB}
+		data.Cells[1] = compareCell{Text: textB, Leads: rankedA && rankedB && scoreB > scoreA}
+		rows = append(rows, data)
+	}
+	return rows
+}
+
+// handleCompareCommand compares two links of the user: /compare <alias1>
+// <alias2>.
+func (b *Bot) handleCompareCommand(ctx context.Context, r *Request) error {
+	args := strings.Fields(r.Args)
+	if len(args) != 2 {
+		return b.reply(r.ChatID, msgCompareUsage, nil)
+	}
+	var aliases [2]string
+	for i, arg := range args {
+		alias, err := b.resolveAlias(arg)
+		if err != nil {
+			return b.replyAliasError(r.ChatID, err, "compare")
+		}
+		aliases[i] = alias
+	}
+	return b.compareLinks(ctx, r.ChatID, r.UserID, aliases[0], aliases[1])
+}
+
+// startCompare asks which link to compare the one of the stats message
+// with, offering the user's recent links.
+func (b *Bot) startCompare(ctx context.Context, r *Request) error {
+	alias := r.Args
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForCompareAlias, Payload: comparePayload{Alias: alias}}); err != nil {
+		return err
+	}
+	links, err := b.linkLists.Get(r.UserID, func() ([]*shortenerv1.LinkInfo, error) {
+		res, err := b.listUserLinks(ctx, r.UserID)
+		return res.GetLinks(), err
+	})
+	if err != nil {
+		// The user can still send the alias
+		b.log.Warn("gRPC ListUserLinks failed", zap.Error(err))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	// The newest links come last
+	for _, link := range slices.Backward(links) {
+		if len(rows) == compareRecentLinks {
+			break
+		}
+		if link.GetAlias() == alias {
+			continue
+		}
+		label := displayTitle(link.GetTitle())
+		if label == "" {
+			label = displayURL(b.shortURLOn(link.GetDomain(), link.GetAlias()))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(r.Payloads(), label, actionCompareLinks, alias+"\n"+link.GetAlias()),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(b.callbackButton("Cancel", callbackCancel)))
+	return b.replyWithKeyboard(r.ChatID, msgSendCompareAlias, linkData{ShortURL: displayURL(b.shortURL(alias))}, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// handleCompareAliasInput compares the link of the dialog with the one
+// sent. The user stays at the prompt until a valid alias comes.
+func (b *Bot) handleCompareAliasInput(ctx context.Context, msg *tgbotapi.Message, alias string) error {
+	other, err := b.resolveAlias(msg.Text)
+	if err != nil {
+		return b.replyAliasError(msg.Chat.ID, err, "compare")
+	}
+	b.resetUserState(msg.Chat.ID)
+	return b.compareLinks(ctx, msg.Chat.ID, msg.From.ID, alias, other)
+}
+
+// compareRecent compares the two links of a recent link button.
+func (b *Bot) compareRecent(ctx context.Context, r *Request) error {
+	alias, other, ok := strings.Cut(r.Args, "\n")
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	if payload, ok := b.getUserState(r.ChatID).Payload.(comparePayload); ok && payload.Alias == alias {
+		b.resetUserState(r.ChatID)
+	}
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	return b.compareLinks(ctx, r.ChatID, r.UserID, alias, other)
+}
+
+// compareLinks sends the comparison of two links of userID, side by side.
+// Both links must belong to the user; their stats are fetched concurrently.
+func (b *Bot) compareLinks(ctx context.Context, chatID, userID int64, first, second string) error {
+	if first == second {
+		return b.reply(chatID, msgCompareSameLink, nil)
+	}
+	aliases := []string{first, second}
+	links, err := b.listUserLinks(ctx, userID)
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+		return b.replyGRPCError(chatID, err, "")
+	}
+	for _, alias := range aliases {
+		if !slices.ContainsFunc(links.GetLinks(), func(link *shortenerv1.LinkInfo) bool { return link.GetAlias() == alias }) {
+			return b.reply(chatID, msgCompareNotOwned, linkData{ShortURL: displayURL(b.shortURL(alias))})
+		}
+	}
+
+	var results fanOutResults[string, *shortenerv1.GetLinkStatsResponse]
+	b.withChatAction(ctx, chatID, tgbotapi.ChatTyping, func() error {
+		results = fanOut(ctx, fanOutOptions{}, aliases, func(ctx context.Context, alias string) (*shortenerv1.GetLinkStatsResponse, error) {
+			return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+		})
+		return nil
+	})
+	if failed := results.Failed(); len(failed) > 0 {
+		res := failed[0]
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(res.Err), zap.String("alias", res.Item))
+		return b.replyGRPCError(chatID, res.Err, res.Item)
+	}
+
+	statsA, _ := newStatsData(first, results[0].Value)
+	statsB, _ := newStatsData(second, results[1].Value)
+	data := compareData{
+		First:  displayURL(b.shortURLOn(results[0].Value.GetDomain(), first)),
+		Second: displayURL(b.shortURLOn(results[1].Value.GetDomain(), second)),
+		Rows:   compareStats(b.formatterFor(chatID), time.Now(), statsA, statsB),
+	}
+	name := msgCompareLinks
+	if b.outputStyle(chatID) == stylePlain {
+		name = msgCompareLinksPlain
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(payloadKey{chatID: chatID, userID: userID}, "Stats A", actionStats, first),
+		b.payloadButton(payloadKey{chatID: chatID, userID: userID}, "Stats B", actionStats, second),
+	))
+	return b.sendMessageWithKeyboard(chatID, b.render(name, data), keyboard, b.linkContent())
+}
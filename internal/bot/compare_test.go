@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/locale"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// compareRowsOf builds the expected rows of a comparison, each given as
+// the texts of A and B with a trophy appended to the leader.
+func compareRowsOf(rows ...[2]string) []compareRowData {
+	labels := []string{"Total clicks", "Last click", "Top device"}
+	data := make([]compareRowData, len(rows))
+	for i, row := range rows {
+		data[i].Label = labels[i]
+		for j, text := range row {
+			text, leads := strings.CutSuffix(text, " 🏆")
+			data[i].Cells[j] = compareCell{Text: text, Leads: leads}
+		}
+	}
+	return data
+}
+
+func TestCompareStats(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+	tests := []struct {
+		name string
+		a, b statsData
+		want []compareRowData
+	}{
+		{
+			name: "full data",
+			a:    statsData{Clicks: 1500, LastClickAt: at(2 * time.Hour), ClicksByDevice: map[string]int64{"mobile": 1000, "desktop": 500}},
+			b:    statsData{Clicks: 40, LastClickAt: at(5 * time.Minute), ClicksByDevice: map[string]int64{"desktop": 30, "mobile": 10}},
+			want: compareRowsOf(
+				[2]string{"1,500 🏆", "40"},
+				[2]string{"2h ago", "5m ago 🏆"},
+				[2]string{"mobile (1,000) 🏆", "desktop (30)"},
+			),
+		},
+		{
+			name: "devices of one link only",
+			a:    statsData{Clicks: 10, LastClickAt: at(time.Hour), ClicksByDevice: map[string]int64{"mobile": 10}},
+			b:    statsData{Clicks: 20, LastClickAt: at(time.Hour)},
+			want: compareRowsOf(
+				[2]string{"10", "20 🏆"},
+				[2]string{"1h ago", "1h ago"},
+				[2]string{"mobile (10)", "—"},
+			),
+		},
+		{
+			name: "devices of neither link",
+			a:    statsData{Clicks: 3, LastClickAt: at(48 * time.Hour)},
+			b:    statsData{Clicks: 3, LastClickAt: at(50 * time.Hour)},
+			want: compareRowsOf(
+				[2]string{"3", "3"},
+				[2]string{"2d ago 🏆", "2d 2h ago"},
+				[2]string{"—", "—"},
+			),
+		},
+		{
+			name: "never clicked against clicked",
+			a:    statsData{},
+			b:    statsData{Clicks: 1, LastClickAt: at(time.Minute), ClicksByDevice: map[string]int64{"tablet": 1}},
+			want: compareRowsOf(
+				[2]string{"0", "1 🏆"},
+				[2]string{"never", "1m ago 🏆"},
+				[2]string{"—", "tablet (1)"},
+			),
+		},
+		{
+			name: "never clicked, both",
+			a:    statsData{},
+			b:    statsData{},
+			want: compareRowsOf(
+				[2]string{"0", "0"},
+				[2]string{"never", "never"},
+				[2]string{"—", "—"},
+			),
+		},
+		{
+			// Backends without last click times still count clicks
+			name: "last click unknown",
+			a:    statsData{Clicks: 5},
+			b:    statsData{Clicks: 2, LastClickAt: at(time.Hour)},
+			want: compareRowsOf(
+				[2]string{"5 🏆", "2"},
+				[2]string{"—", "1h ago"},
+				[2]string{"—", "—"},
+			),
+		},
+		{
+			name: "device tie within a link",
+			a:    statsData{Clicks: 4, LastClickAt: at(time.Hour), ClicksByDevice: map[string]int64{"mobile": 2, "desktop": 2}},
+			b:    statsData{Clicks: 4, LastClickAt: at(time.Hour), ClicksByDevice: map[string]int64{"mobile": 2, "desktop": 2}},
+			want: compareRowsOf(
+				[2]string{"4", "4"},
+				[2]string{"1h ago", "1h ago"},
+				[2]string{"desktop (2)", "desktop (2)"},
+			),
+		},
+		{
+			name: "minimal analytics against full",
+			a:    statsData{Clicks: 7, MinimalAnalytics: true},
+			b:    statsData{Clicks: 7, LastClickAt: at(time.Hour), ClicksByDevice: map[string]int64{"desktop": 7}},
+			want: compareRowsOf(
+				[2]string{"7", "7"},
+				[2]string{"—", "1h ago"},
+				[2]string{"—", "desktop (7)"},
+			),
+		},
+	}
+	f := locale.New("en", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareStats(f, now, tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareStats() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			// Swapping the links swaps the cells
+			swapped := compareStats(f, now, tt.b, tt.a)
+			for i, row := range swapped {
+				if row.Cells[0] != tt.want[i].Cells[1] || row.Cells[1] != tt.want[i].Cells[0] {
+					t.Errorf("row %s not symmetric: %+v", row.Label, row.Cells)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareTemplates(t *testing.T) {
+	messages, err := newMessageTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bot{log: zap.NewNop(), messages: messages}
+	data := compareData{
+		First:  "localhost:8080/a",
+		Second: "localhost:8080/b",
+		Rows: compareRowsOf(
+			[2]string{"10", "20 🏆"},
+			[2]string{"never", "—"},
+			[2]string{"mobile (10)", "—"},
+		),
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{msgCompareLinks, "Comparing localhost:8080/a (A) with localhost:8080/b (B)\n\n" +
+			"Total clicks: A 10 | B 20 🏆\n" +
+			"Last click: A never | B —\n" +
+			"Top device: A mobile (10) | B —"},
+		{msgCompareLinksPlain, "Comparison of link A, localhost:8080/a, with link B, localhost:8080/b.\n" +
+			"Total clicks: A 10; B 20, leads.\n" +
+			"Last click: A never; B —.\n" +
+			"Top device: A mobile (10); B —."},
+	}
+	for _, tt := range tests {
+		if got := b.render(tt.name, data); got != tt.want {
+			t.Errorf("%s =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// confirmsShortening reports whether URLs pasted in msg are previewed
+// before shortening. Groups are never asked.
+func (b *Bot) confirmsShortening(msg *tgbotapi.Message) bool {
+	return msg.Chat.IsPrivate() && b.prefs.Get(msg.Chat.ID).ConfirmShorten
+}
+
+// confirmShorten offers to shorten a pasted URL instead of creating the link
+// right away. args is what the link is created from when confirmed, the URL
+// optionally followed by /shorten options. The buttons expire with the
+// payload store.
+func (b *Bot) confirmShorten(to payloadKey, url, args string) error {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Shorten", actionConfirmShorten, args),
+			b.storedPayloadButton(to, "Shorten with options", actionShortenOptions, url),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Ignore", actionIgnoreURL, url),
+		),
+	)
+	msg := tgbotapi.NewMessage(to.chatID, b.render(msgConfirmShorten, urlData{URL: url}))
+	msg.ReplyMarkup = keyboard
+	msg.DisableWebPagePreview = true
+	_, err := b.send(msg)
+	return err
+}
+
+// handleConfirmShorten creates the link of a confirmed preview.
+func (b *Bot) handleConfirmShorten(ctx context.Context, r *Request) error {
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	_, err := b.shorten(ctx, r.Payloads(), r.Args)
+	return err
+}
+
+// handleShortenOptions asks for the /shorten options of a previewed URL.
+func (b *Bot) handleShortenOptions(r *Request) error {
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForShortenOptions, Payload: shortenOptionsPayload{URL: r.Args}}); err != nil {
+		return err
+	}
+	data := shortenOptionsPromptData{URL: r.Args, AliasStyles: b.supports(featureAliasStyle)}
+	return b.replyWithKeyboard(r.ChatID, msgSendShortenOptions, data, b.createCancelKeyboard())
+}
+
+// handleShortenOptionsInput creates the pending link with the options sent.
+func (b *Bot) handleShortenOptionsInput(ctx context.Context, to payloadKey, url, text string) error {
+	b.resetUserState(to.chatID)
+	_, err := b.shorten(ctx, to, url+" "+text)
+	return err
+}
+
+// handleIgnoreURL closes a preview without creating a link.
+func (b *Bot) handleIgnoreURL(r *Request) error {
+	edit := tgbotapi.NewEditMessageText(r.ChatID, r.Message.MessageID, b.render(msgShortenIgnored, urlData{URL: r.Args}))
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// dropKeyboard removes the buttons of a message so they can't be pressed
+// twice. Failures are only logged.
+func (b *Bot) dropKeyboard(chatID int64, messageID int) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	if err := b.editMessage(edit, nil, nil); err != nil {
+		b.log.Debug("failed to remove keyboard", zap.Int("message_id", messageID), zap.Error(err))
+	}
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/telegramtest"
+	"testing"
+)
+
+// turnOnConfirm turns on confirm before shortening from /settings.
+func (e *e2e) turnOnConfirm(t *testing.T) {
+	t.Helper()
+	e.tg.SendMessage(user, "/settings")
+	settings := e.tg.Wait("sendMessage", func(r telegramtest.Request) bool {
+		_, ok := r.Button("Confirm before shortening: off")
+		return ok
+	})
+	e.press(t, settings, "Confirm before shortening: off")
+	e.answer()
+	e.tg.Wait("editMessageText", func(r telegramtest.Request) bool {
+		_, ok := r.Button("Confirm before shortening: on")
+		return ok
+	})
+}
+
+// created returns how many links the backend was asked to create.
+func (e *e2e) created() int {
+	return len(e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName))
+}
+
+func TestConfirmShortenOff(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "https://example.com/a")
+	e.tg.WaitText(user, "Link created successfully")
+	if e.created() != 1 {
+		t.Errorf("%d links created, want the pasted URL shortened right away", e.created())
+	}
+}
+
+func TestConfirmShortenOn(t *testing.T) {
+	e := startBot(t, nil)
+	e.turnOnConfirm(t)
+
+	e.tg.SendMessage(user, "https://example.com/a")
+	preview := e.tg.WaitText(user, "Shorten https://example.com/a?")
+	if e.created() != 0 {
+		t.Fatal("link created before it was confirmed")
+	}
+	e.press(t, preview, "Shorten")
+	e.tg.WaitText(user, "Link created successfully")
+	if got := e.lastCreated(t).GetOriginalUrl(); got != "https://example.com/a" {
+		t.Errorf("created %q, want the previewed URL", got)
+	}
+
+	// /shorten never asks
+	e.tg.SendMessage(user, "/shorten https://example.com/b")
+	e.tg.WaitText(user, "Link created successfully")
+	if got := e.lastCreated(t).GetOriginalUrl(); got != "https://example.com/b" {
+		t.Errorf("created %q, want /shorten's URL", got)
+	}
+}
+
+func TestConfirmShortenWithOptions(t *testing.T) {
+	e := startBot(t, nil)
+	e.turnOnConfirm(t)
+
+	e.tg.SendMessage(user, "https://example.com/a")
+	e.press(t, e.tg.WaitText(user, "Shorten https://example.com/a?"), "Shorten with options")
+	e.tg.WaitText(user, "Send options for https://example.com/a")
+	e.tg.SendMessage(user, "alias=mine")
+	e.tg.WaitText(user, "Link created successfully")
+	if req := e.lastCreated(t); req.GetOriginalUrl() != "https://example.com/a" || req.GetCustomAlias() != "mine" {
+		t.Errorf("created %q as %q, want the previewed URL with its options", req.GetOriginalUrl(), req.GetCustomAlias())
+	}
+}
+
+func TestConfirmShortenIgnore(t *testing.T) {
+	e := startBot(t, nil)
+	e.turnOnConfirm(t)
+
+	e.tg.SendMessage(user, "https://example.com/a")
+	e.press(t, e.tg.WaitText(user, "Shorten https://example.com/a?"), "Ignore")
+	e.tg.WaitText(user, "Not shortened: https://example.com/a")
+	if e.created() != 0 {
+		t.Error("ignored URL shortened")
+	}
+}
+
+func TestConfirmShortenExpired(t *testing.T) {
+	e := startBot(t, nil)
+	e.turnOnConfirm(t)
+
+	e.tg.SendMessage(user, "https://example.com/a")
+	preview := e.tg.WaitText(user, "Shorten https://example.com/a?")
+	// What the payload store's ttl does to a stale preview
+	e.bot.payloads.users.Delete(payloadKey{chatID: user, userID: user})
+
+	e.press(t, preview, "Shorten")
+	if alert := e.answer(); alert.Param("text") != "This button has expired. Please open the menu again." {
+		t.Errorf("alert = %q, want the button expired", alert.Param("text"))
+	}
+	if e.created() != 0 {
+		t.Error("stale preview shortened")
+	}
+}
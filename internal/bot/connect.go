@@ -0,0 +1,135 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// linkTokenTTL is the lifetime the backend enforces for linking tokens.
+	linkTokenTTL          = 10 * time.Minute
+	linkTokenPollInterval = 3 * time.Second
+)
+
+// connectPolls tracks the pending dashboard link of each user, so that a new
+// /connect supersedes the previous one.
+type connectPolls struct {
+	mu      sync.Mutex
+	cancels map[int64]context.CancelFunc
+}
+
+func newConnectPolls() *connectPolls {
+	return &connectPolls{cancels: make(map[int64]context.CancelFunc)}
+}
+
+// Start cancels the user's previous poll and returns a context for a new one.
+func (p *connectPolls) Start(ctx context.Context, userID int64) (context.Context, context.CancelFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.cancels[userID]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancels[userID] = cancel
+	return ctx, cancel
+}
+
+// Stop cancels the user's poll, if any.
+func (p *connectPolls) Stop(userID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.cancels[userID]; ok {
+		cancel()
+		delete(p.cancels, userID)
+	}
+}
+
+// handleConnectCommand issues a one-time dashboard login URL and waits for
+// the backend to report that it was used.
+func (b *Bot) handleConnectCommand(ctx context.Context, r *Request) error {
+	res, err := b.grpcClient.GenerateLinkToken(ctx, &shortenerv1.GenerateLinkTokenRequest{UserTgId: r.UserID})
+	if err != nil {
+		return b.replyGRPCError(r.ChatID, err, "")
+	}
+
+	// The backend enforces the TTL; the bot never shows a longer one
+	expires := time.Now().Add(linkTokenTTL)
+	if res.ExpiresAt != nil && res.ExpiresAt.AsTime().Before(expires) {
+		expires = res.ExpiresAt.AsTime()
+	}
+	if res.GetToken() == "" || res.GetLoginUrl() == "" || !time.Now().Before(expires) {
+		b.log.Error("backend returned an unusable link token")
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL("Open dashboard", res.GetLoginUrl()),
+		),
+	)
+	minutes := int(time.Until(expires).Round(time.Minute) / time.Minute)
+	text := b.render(msgConnectLink, connectData{Locale: b.formatterFor(r.ChatID), Minutes: max(minutes, 1), ExpiresAt: expires})
+	if err := b.sendMessageWithKeyboard(r.ChatID, text, keyboard); err != nil {
+		return err
+	}
+
+	pollCtx, cancel := b.connectPolls.Start(ctx, r.UserID)
+	go func() {
+		defer cancel()
+		b.awaitDashboardLink(pollCtx, r.ChatID, res.GetToken(), expires)
+	}()
+	return nil
+}
+
+// awaitDashboardLink polls the token status until it's used or expires.
+func (b *Bot) awaitDashboardLink(ctx context.Context, chatID int64, token string, expires time.Time) {
+	ctx, cancel := context.WithDeadline(ctx, expires)
+	defer cancel()
+
+	ticker := time.NewTicker(linkTokenPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				b.notifyConnect(chatID, b.render(msgConnectExpired, nil))
+			}
+			return
+		case <-ticker.C:
+		}
+
+		res, err := b.grpcClient.GetLinkTokenStatus(ctx, &shortenerv1.GetLinkTokenStatusRequest{Token: token})
+		if err != nil {
+			continue
+		}
+		switch {
+		case res.GetCompleted():
+			b.notifyConnect(chatID, b.render(msgConnectCompleted, nil))
+			return
+		case res.GetExpired():
+			b.notifyConnect(chatID, b.render(msgConnectExpired, nil))
+			return
+		}
+	}
+}
+
+func (b *Bot) notifyConnect(chatID int64, text string) {
+	_ = b.sendMessage(chatID, text, false, b.notification(chatID), noWait())
+}
+
+// handleDisconnectCommand revokes the dashboard association.
+func (b *Bot) handleDisconnectCommand(ctx context.Context, r *Request) error {
+	b.connectPolls.Stop(r.UserID)
+	res, err := b.grpcClient.DisconnectDashboard(ctx, &shortenerv1.DisconnectDashboardRequest{UserTgId: r.UserID})
+	if err != nil {
+		return b.replyGRPCError(r.ChatID, err, "")
+	}
+	if !res.GetWasConnected() {
+		return b.reply(r.ChatID, msgNotConnected, nil)
+	}
+	return b.reply(r.ChatID, msgDisconnected, nil)
+}
@@ -0,0 +1,281 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/grpc/client"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// consoleUserID is the user and private chat of the dry-run console.
+	consoleUserID = 1
+	// consoleBotUsername is the bot's username in the dry-run console.
+	consoleBotUsername = "gurls_dry_run_bot"
+)
+
+// NewDryRun creates a bot that reads messages from in and prints what it
+// would send to out instead of talking to Telegram. Lines starting with a
+// slash are commands, "#N" presses button N of the last keyboard, anything
+// else is a plain message. The bot stops when in is exhausted.
+func NewDryRun(cfg *config.Config, log *zap.Logger, grpcClient *client.BackendClient, in io.Reader, out io.Writer) (*Bot, error) {
+	return newBot(newConsole(in, out), consoleBotUsername, cfg, log, grpcClient)
+}
+
+// console implements telegramAPI on top of a reader and a writer.
+type console struct {
+	in  io.Reader
+	out io.Writer
+
+	mu            sync.Mutex
+	nextMessageID int
+	nextUpdateID  int
+	// buttons are the callback buttons of the last keyboard shown, by number
+	buttons []consoleButton
+	// keyboards are the keyboards shown by message, attached to button
+	// presses like Telegram does
+	keyboards map[int]tgbotapi.InlineKeyboardMarkup
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type consoleButton struct {
+	messageID int
+	data      string
+}
+
+func newConsole(in io.Reader, out io.Writer) *console {
+	return &console{in: in, out: out, keyboards: make(map[int]tgbotapi.InlineKeyboardMarkup), stop: make(chan struct{})}
+}
+
+func (c *console) GetUpdatesChan(tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	ch := make(chan tgbotapi.Update)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(c.in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			update, ok := c.update(line)
+			if !ok {
+				continue
+			}
+			// The empty update that follows is ignored by the bot, but as
+			// the channel is unbuffered it can only be delivered once the
+			// real one has been handled, so the next line sees its replies
+			// and "#N" refers to the keyboard it produced
+			for _, u := range []tgbotapi.Update{update, {}} {
+				select {
+				case ch <- u:
+				case <-c.stop:
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+func (c *console) StopReceivingUpdates() {
+	c.closeOnce.Do(func() { close(c.stop) })
+}
+
+// update turns an input line into the update Telegram would deliver.
+func (c *console) update(line string) (tgbotapi.Update, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextUpdateID++
+	from := &tgbotapi.User{ID: consoleUserID, FirstName: "Console", UserName: "console"}
+	chat := &tgbotapi.Chat{ID: consoleUserID, Type: "private"}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(line, "#")); strings.HasPrefix(line, "#") && err == nil {
+		if n < 1 || n > len(c.buttons) {
+			fmt.Fprintf(c.out, "no button #%d\n", n)
+			return tgbotapi.Update{}, false
+		}
+		button := c.buttons[n-1]
+		message := &tgbotapi.Message{MessageID: button.messageID, Chat: chat, Date: int(time.Now().Unix())}
+		if keyboard, ok := c.keyboards[button.messageID]; ok {
+			message.ReplyMarkup = &keyboard
+		}
+		return tgbotapi.Update{
+			UpdateID: c.nextUpdateID,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID:      strconv.Itoa(c.nextUpdateID),
+				From:    from,
+				Message: message,
+				Data:    button.data,
+			},
+		}, true
+	}
+
+	c.nextMessageID++
+	msg := &tgbotapi.Message{
+		MessageID: c.nextMessageID,
+		From:      from,
+		Chat:      chat,
+		Date:      int(time.Now().Unix()),
+		Text:      line,
+	}
+	if strings.HasPrefix(line, "/") {
+		command, _, _ := strings.Cut(line, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	return tgbotapi.Update{UpdateID: c.nextUpdateID, Message: msg}, true
+}
+
+func (c *console) Send(ch tgbotapi.Chattable) (tgbotapi.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch m := ch.(type) {
+	case tgbotapi.MessageConfig:
+		return c.printMessage(m.ChatID, m.Text, m.ReplyMarkup), nil
+	case tgbotapi.EditMessageTextConfig:
+		fmt.Fprintf(c.out, "bot (edit #%d)> %s\n", m.MessageID, indent(m.Text))
+		if m.ReplyMarkup != nil {
+			c.printKeyboard(m.MessageID, *m.ReplyMarkup)
+		} else {
+			// Editing the text without a keyboard removes it
+			delete(c.keyboards, m.MessageID)
+		}
+		return tgbotapi.Message{MessageID: m.MessageID, Chat: &tgbotapi.Chat{ID: m.ChatID}, Text: m.Text}, nil
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		fmt.Fprintf(c.out, "bot (edit #%d keyboard)\n", m.MessageID)
+		if m.ReplyMarkup != nil {
+			c.printKeyboard(m.MessageID, *m.ReplyMarkup)
+		}
+		return tgbotapi.Message{MessageID: m.MessageID, Chat: &tgbotapi.Chat{ID: m.ChatID}}, nil
+	case tgbotapi.DocumentConfig:
+		// Files are shown by their name, followed by the caption
+		var name string
+		if m.File.NeedsUpload() {
+			name, _, _ = m.File.UploadData()
+		} else {
+			name = m.File.SendData()
+		}
+		fmt.Fprintf(c.out, "bot (file %s)\n", name)
+		return c.printMessage(m.ChatID, m.Caption, m.ReplyMarkup), nil
+	default:
+		c.printRequest(ch)
+		return tgbotapi.Message{}, nil
+	}
+}
+
+func (c *console) Request(ch tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch m := ch.(type) {
+	case tgbotapi.CallbackConfig:
+		if m.Text != "" {
+			fmt.Fprintf(c.out, "bot (popup)> %s\n", m.Text)
+		}
+	case tgbotapi.ChatActionConfig, tgbotapi.SetMyCommandsConfig, tgbotapi.DeleteMyCommandsConfig:
+	default:
+		c.printRequest(ch)
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+// GetChatAdministrators reports the console user as the only administrator.
+func (c *console) GetChatAdministrators(tgbotapi.ChatAdministratorsConfig) ([]tgbotapi.ChatMember, error) {
+	return []tgbotapi.ChatMember{{User: &tgbotapi.User{ID: consoleUserID}, Status: "creator"}}, nil
+}
+
+// GetFileDirectURL fails, as files can't be sent to the console.
+func (c *console) GetFileDirectURL(string) (string, error) {
+	return "", errors.New("files aren't supported in dry-run mode")
+}
+
+// MakeRequest handles sendMessage, used for protected messages.
+func (c *console) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if endpoint != "sendMessage" {
+		fmt.Fprintf(c.out, "bot (%s)\n", endpoint)
+		return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+	}
+	chatID, _ := strconv.ParseInt(params["chat_id"], 10, 64)
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	_ = json.Unmarshal([]byte(params["reply_markup"]), &keyboard)
+	sent := c.printMessage(chatID, params["text"]+"\n[protected]", keyboard)
+	result, err := json.Marshal(sent)
+	if err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: result}, nil
+}
+
+func (c *console) printMessage(chatID int64, text string, markup any) tgbotapi.Message {
+	c.nextMessageID++
+	fmt.Fprintf(c.out, "bot (#%d)> %s\n", c.nextMessageID, indent(text))
+	switch keyboard := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		c.printKeyboard(c.nextMessageID, keyboard)
+	case tgbotapi.ReplyKeyboardMarkup:
+		for _, row := range keyboard.Keyboard {
+			var cells []string
+			for _, button := range row {
+				cells = append(cells, fmt.Sprintf("{%s}", button.Text))
+			}
+			fmt.Fprintf(c.out, "      %s\n", strings.Join(cells, " "))
+		}
+	case tgbotapi.ReplyKeyboardRemove:
+		fmt.Fprintln(c.out, "      {keyboard removed}")
+	}
+	return tgbotapi.Message{MessageID: c.nextMessageID, Chat: &tgbotapi.Chat{ID: chatID}, Text: text, Date: int(time.Now().Unix())}
+}
+
+// printKeyboard lists the buttons row by row and numbers the callback ones.
+func (c *console) printKeyboard(messageID int, keyboard tgbotapi.InlineKeyboardMarkup) {
+	if len(keyboard.InlineKeyboard) == 0 {
+		delete(c.keyboards, messageID)
+		return
+	}
+	c.keyboards[messageID] = keyboard
+	c.buttons = c.buttons[:0]
+	for _, row := range keyboard.InlineKeyboard {
+		var cells []string
+		for _, button := range row {
+			switch {
+			case button.CallbackData != nil:
+				c.buttons = append(c.buttons, consoleButton{messageID: messageID, data: *button.CallbackData})
+				cells = append(cells, fmt.Sprintf("[#%d %s]", len(c.buttons), button.Text))
+			case button.URL != nil:
+				cells = append(cells, fmt.Sprintf("[%s -> %s]", button.Text, *button.URL))
+			default:
+				cells = append(cells, fmt.Sprintf("[%s]", button.Text))
+			}
+		}
+		fmt.Fprintf(c.out, "      %s\n", strings.Join(cells, " "))
+	}
+}
+
+func (c *console) printRequest(ch tgbotapi.Chattable) {
+	switch m := ch.(type) {
+	case tgbotapi.DeleteMessageConfig:
+		fmt.Fprintf(c.out, "bot (delete #%d)\n", m.MessageID)
+	default:
+		fmt.Fprintf(c.out, "bot (%T)\n", ch)
+	}
+}
+
+// indent aligns continuation lines with the first line of a message.
+func indent(text string) string {
+	return strings.ReplaceAll(text, "\n", "\n      ")
+}
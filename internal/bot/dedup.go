@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/ttlmap"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRecentLinks   = 1000
+	maxSeenUpdateIDs = 1000
+	seenUpdateTTL    = 24 * time.Hour
+)
+
+// recentLinks remembers links created moments ago so that a re-sent URL
+// returns the existing short link instead of a second alias. When full, the
+// oldest link is forgotten.
+type recentLinks struct {
+	window  time.Duration
+	aliases *ttlmap.Map[string, string]
+}
+
+func newRecentLinks(window time.Duration) *recentLinks {
+	return &recentLinks{window: window, aliases: ttlmap.New[string, string](window, maxRecentLinks)}
+}
+
+// Get returns the alias created for key within the dedup window.
+func (r *recentLinks) Get(key string) (string, bool) {
+	if r.window <= 0 {
+		return "", false
+	}
+	return r.aliases.Get(key)
+}
+
+// Put records alias for key.
+func (r *recentLinks) Put(key, alias string) {
+	if r.window <= 0 {
+		return
+	}
+	r.aliases.Set(key, alias)
+}
+
+// Forget drops the entry for key.
+func (r *recentLinks) Forget(key string) {
+	r.aliases.Delete(key)
+}
+
+// recentLinkKey identifies a creation request by owner, normalized URL and
+// custom alias.
+func recentLinkKey(ownerID int64, rawURL, customAlias string) string {
+	return strings.Join([]string{
+		strconv.FormatInt(ownerID, 10),
+		normalizeURL(rawURL),
+		strings.ToLower(customAlias),
+	}, "\x00")
+}
+
+// normalizeURL lowercases the scheme and host and drops a bare trailing slash
+// and the fragment, so trivially different spellings of a URL compare equal.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	if u.Path == "/" {
+		u.Path = ""
+	}
+	return u.String()
+}
+
+// updateDeduper drops Telegram updates that were already processed.
+// Telegram redelivers unconfirmed updates for up to a day; when full, the
+// oldest update ID is forgotten.
+type updateDeduper struct {
+	ids *ttlmap.Map[int, struct{}]
+}
+
+func newUpdateDeduper(size int) *updateDeduper {
+	return &updateDeduper{ids: ttlmap.New[int, struct{}](seenUpdateTTL, size)}
+}
+
+// First records updateID and reports whether it hasn't been seen before.
+func (d *updateDeduper) First(updateID int) bool {
+	first := false
+	d.ids.Update(updateID, func(_ struct{}, seen bool) (struct{}, bool) {
+		first = !seen
+		return struct{}{}, true
+	})
+	return first
+}
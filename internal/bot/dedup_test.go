@@ -0,0 +1,27 @@
+package bot
+
+import "testing"
+
+func TestUpdateDeduper(t *testing.T) {
+	d := newUpdateDeduper(3)
+	for _, id := range []int{1, 2, 3} {
+		if !d.First(id) {
+			t.Errorf("First(%d) = false for a new update", id)
+		}
+	}
+	if d.First(2) {
+		t.Error("First(2) = true for a redelivered update")
+	}
+
+	// A full deduper forgets the oldest update
+	d.First(4)
+	if d.ids.Len() != 3 {
+		t.Errorf("%d update IDs kept, want 3", d.ids.Len())
+	}
+	if !d.First(1) {
+		t.Error("oldest update still remembered")
+	}
+	if d.First(4) {
+		t.Error("newest update forgotten")
+	}
+}
@@ -0,0 +1,320 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	titleFetchTimeout = 3 * time.Second
+	titleFetchLimit   = 64 << 10
+	maxAutoTitleLen   = 100
+
+	expiryNever = "never"
+)
+
+// expiryPresets are the expiry choices offered in the wizard and settings.
+var expiryPresets = []string{"1d", "7d", "30d", "90d", expiryNever}
+
+var (
+	errInvalidExpiry = errors.New("invalid expiry")
+	htmlTitleRegex   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// parseExpiry parses an expiry such as "90m", "12h" or "30d". "never" and
+// "0" yield zero, meaning the link never expires.
+func parseExpiry(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == expiryNever || s == "0" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errInvalidExpiry
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errInvalidExpiry
+	}
+	return d, nil
+}
+
+// formatExpiry renders d the way parseExpiry accepts it.
+func formatExpiry(d time.Duration) string {
+	switch {
+	case d == 0:
+		return expiryNever
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	default:
+		return d.String()
+	}
+}
+
+// expiresAt converts an expiry to the request timestamp; nil means never.
+func expiresAt(d time.Duration) *timestamppb.Timestamp {
+	if d == 0 {
+		return nil
+	}
+	return timestamppb.New(time.Now().Add(d))
+}
+
+// applyCreationDefaults fills in the user's creation defaults. An explicit
+// expiry, including "never", always wins over the default. It reports false
+// when the user has to pick an expiry first.
+func (b *Bot) applyCreationDefaults(ctx context.Context, to payloadKey, req *shortenerv1.CreateLinkRequest, explicitExpiry bool) (bool, error) {
+	userPrefs := b.prefs.Get(req.GetUserTgId())
+	defaults := userPrefs.Defaults
+
+	if req.Domain == nil {
+		if domain := b.defaultDomain(userPrefs); domain != "" {
+			req.Domain = &domain
+		}
+	}
+
+	if !explicitExpiry && req.ExpiresAt == nil {
+		if defaults.AskExpiry {
+			return false, b.askExpiry(to, req)
+		}
+		req.ExpiresAt = expiresAt(defaults.Expiry)
+	}
+
+	if req.AliasStyle == nil && req.CustomAlias == nil {
+		style := b.aliasStyle(userPrefs)
+		req.AliasStyle = &style
+	}
+
+	if req.MinimalAnalytics == nil && defaults.MinimalAnalytics {
+		req.MinimalAnalytics = &defaults.MinimalAnalytics
+	}
+
+	if defaults.AutoTitle && req.GetTitle() == "" {
+		if title := b.fetchTitle(ctx, req.GetOriginalUrl()); title != "" {
+			req.Title = &title
+		}
+	}
+	return true, nil
+}
+
+// pendingExpiry is the callback payload of an expiry preset button.
+type pendingExpiry struct {
+	Link   *queuedLink `json:"link"`
+	Expiry string      `json:"expiry"`
+}
+
+// askExpiry offers the expiry presets for req.
+func (b *Bot) askExpiry(to payloadKey, req *shortenerv1.CreateLinkRequest) error {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, preset := range expiryPresets {
+		payload, err := json.Marshal(pendingExpiry{Link: newQueuedLink(to.chatID, req), Expiry: preset})
+		if err != nil {
+			return err
+		}
+		label := preset
+		if preset == expiryNever {
+			label = "Never"
+		}
+		row = append(row, b.payloadButton(to, label, actionPickExpiry, string(payload)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return b.sendMessageWithKeyboard(to.chatID, b.render(msgAskExpiry, urlData{URL: req.GetOriginalUrl()}), keyboard)
+}
+
+// handlePickExpiry creates the pending link with the chosen expiry.
+func (b *Bot) handlePickExpiry(ctx context.Context, r *Request) error {
+	var pending pendingExpiry
+	if err := json.Unmarshal([]byte(r.Args), &pending); err != nil || pending.Link == nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	expiry, err := parseExpiry(pending.Expiry)
+	if err != nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	req := pending.Link.request()
+	req.ExpiresAt = expiresAt(expiry)
+	_, err = b.createLink(ctx, r.Payloads(), req, createOptions{explicitExpiry: true})
+	return err
+}
+
+// fetchTitle returns the HTML title of rawURL, or "" when it can't be fetched.
+func (b *Bot) fetchTitle(ctx context.Context, rawURL string) string {
+	ctx, cancel := context.WithTimeout(ctx, titleFetchTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		b.log.Debug("failed to fetch page title", zap.String("url", rawURL), zap.Error(err))
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, titleFetchLimit))
+	if err != nil {
+		return ""
+	}
+	m := htmlTitleRegex.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	if r := []rune(title); len(r) > maxAutoTitleLen {
+		title = string(r[:maxAutoTitleLen-3]) + "..."
+	}
+	return title
+}
+
+// handleSettings shows the settings menu, editing messageID when non-zero.
+func (b *Bot) handleSettings(to payloadKey, messageID int) error {
+	userPrefs := b.prefs.Get(to.chatID)
+	defaults := userPrefs.Defaults
+	data := settingsData{
+		Expiry:           formatExpiry(defaults.Expiry),
+		AutoTitle:        defaults.AutoTitle,
+		AskExpiry:        defaults.AskExpiry,
+		Preview:          defaults.Preview,
+		Sound:            userPrefs.NotificationSound,
+		LinkStyle:        b.linkStyle(to.chatID),
+		Cleanup:          userPrefs.Cleanup,
+		Confirm:          userPrefs.ConfirmShorten,
+		Quick:            userPrefs.QuickActions,
+		Plain:            userPrefs.PlainOutput,
+		MinimalAnalytics: defaults.MinimalAnalytics,
+		Language:         languageSetting(userPrefs),
+		Timezone:         timezoneSetting(userPrefs),
+	}
+	if b.supports(featureAliasStyle) {
+		data.AliasStyle = b.aliasStyle(userPrefs)
+	}
+	text := b.render(msgSettings, data)
+
+	var presets []tgbotapi.InlineKeyboardButton
+	for _, preset := range expiryPresets {
+		label := preset
+		if preset == formatExpiry(defaults.Expiry) {
+			label = "* " + label
+		}
+		presets = append(presets, b.payloadButton(to, label, actionDefaultExpiry, preset))
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		presets,
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Auto title: "+onOff(defaults.AutoTitle), callbackToggleAutoTitle),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Ask for expiry: "+onOff(defaults.AskExpiry), callbackToggleAskExpiry),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Preview before create: "+onOff(defaults.Preview), callbackTogglePreview),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Minimal analytics: "+onOff(defaults.MinimalAnalytics), callbackToggleMinimalAnalytics),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Notification sound: "+onOff(userPrefs.NotificationSound), callbackToggleSound),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Link style: "+b.linkStyle(to.chatID), callbackToggleLinkStyle),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Confirm before shortening: "+onOff(userPrefs.ConfirmShorten), callbackToggleConfirm),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Cleanup suggestions: "+onOff(userPrefs.Cleanup), callbackToggleCleanup),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Quick actions keyboard: "+onOff(userPrefs.QuickActions), callbackToggleQuickActions),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Plain output for screen readers: "+onOff(userPrefs.PlainOutput), callbackTogglePlainOutput),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Number and date format: "+languageSetting(userPrefs), callbackCycleLanguage),
+		),
+	}
+	if data.AliasStyle != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Alias style: "+data.AliasStyle, callbackCycleAliasStyle),
+		))
+	}
+	if b.multipleDomains() {
+		current := b.defaultDomain(userPrefs)
+		for i, d := range b.shortDomains() {
+			host := domainHost(d.BaseURL)
+			label := "Domain: " + d.Label
+			if host == current || (current == "" && i == 0) {
+				label = "* " + label
+			}
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				b.payloadButton(to, label, actionDefaultDomain, host),
+			))
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Back to Menu", callbackHelp),
+	))
+	keyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+	if messageID != 0 {
+		return b.editMessageWithKeyboard(to.chatID, messageID, text, keyboard)
+	}
+	return b.sendMessageWithKeyboard(to.chatID, text, keyboard)
+}
+
+// updateDefaults changes the creation defaults and refreshes the settings menu.
+func (b *Bot) updateDefaults(r *Request, setting string, fn func(d *prefs.CreationDefaults) error) error {
+	return b.updateSettings(r, setting, func(p *prefs.Prefs) error {
+		return fn(&p.Defaults)
+	})
+}
+
+// updateSettings changes the user's preferences, records the change of
+// setting in the history and refreshes the settings menu.
+func (b *Bot) updateSettings(r *Request, setting string, fn func(p *prefs.Prefs) error) error {
+	err := b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		if err := fn(p); err != nil {
+			return err
+		}
+		b.appendHistory(p, prefs.HistoryEntry{Action: historySettings, Setting: setting})
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	r.Answer.toast(b.render(msgToastSettingsSaved, nil))
+	return b.handleSettings(r.Payloads(), r.Message.MessageID)
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
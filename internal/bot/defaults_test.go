@@ -0,0 +1,154 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// setDefaults sets the creation defaults of user.
+func (e *e2e) setDefaults(t *testing.T, defaults prefs.CreationDefaults) {
+	t.Helper()
+	err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+		p.Defaults = defaults
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lastCreated returns the last CreateLink request the backend received.
+func (e *e2e) lastCreated(t *testing.T) *shortenerv1.CreateLinkRequest {
+	t.Helper()
+	calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName)
+	if len(calls) == 0 {
+		t.Fatal("no link created")
+	}
+	return calls[len(calls)-1].(*shortenerv1.CreateLinkRequest)
+}
+
+// checkExpiry checks that req expires in about want from now; zero means
+// never.
+func checkExpiry(t *testing.T, req *shortenerv1.CreateLinkRequest, want time.Duration) {
+	t.Helper()
+	if want == 0 {
+		if req.ExpiresAt != nil {
+			t.Errorf("link expires at %v, want never", req.ExpiresAt.AsTime())
+		}
+		return
+	}
+	if req.ExpiresAt == nil {
+		t.Fatalf("link never expires, want in %v", want)
+	}
+	if got := time.Until(req.ExpiresAt.AsTime()); got > want || got < want-time.Minute {
+		t.Errorf("link expires in %v, want %v", got, want)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{in: "30d", want: 30 * day},
+		{in: " 12H ", want: 12 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "never"},
+		{in: "0"},
+		{in: "0d", err: true},
+		{in: "-1h", err: true},
+		{in: "soon", err: true},
+	} {
+		got, err := parseExpiry(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseExpiry(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+		if err == nil {
+			if back, _ := parseExpiry(formatExpiry(got)); back != got {
+				t.Errorf("formatExpiry(%v) = %q doesn't parse back", got, formatExpiry(got))
+			}
+		}
+	}
+}
+
+func TestCreationDefaultExpiry(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		defaults prefs.CreationDefaults
+		message  string
+		want     time.Duration
+	}{
+		{name: "no default", message: "/shorten https://example.com/a", want: 0},
+		{name: "default", defaults: prefs.CreationDefaults{Expiry: 30 * day}, message: "/shorten https://example.com/a", want: 30 * day},
+		{name: "pasted URL", defaults: prefs.CreationDefaults{Expiry: 30 * day}, message: "https://example.com/a", want: 30 * day},
+		{name: "explicit beats default", defaults: prefs.CreationDefaults{Expiry: 30 * day}, message: "/shorten https://example.com/a expires_in=1d", want: day},
+		{name: "explicit never beats default", defaults: prefs.CreationDefaults{Expiry: 30 * day}, message: "/shorten https://example.com/a expires_in=never", want: 0},
+		{name: "explicit skips asking", defaults: prefs.CreationDefaults{AskExpiry: true, Expiry: 30 * day}, message: "/shorten https://example.com/a expires_in=12h", want: 12 * time.Hour},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := startBot(t, nil)
+			e.setDefaults(t, tt.defaults)
+
+			e.tg.SendMessage(user, tt.message)
+			e.tg.WaitText(user, "Link created successfully")
+			checkExpiry(t, e.lastCreated(t), tt.want)
+		})
+	}
+}
+
+func TestCreationDefaultAskExpiry(t *testing.T) {
+	for _, tt := range []struct {
+		button string
+		want   time.Duration
+	}{
+		{button: "7d", want: 7 * day},
+		// Never is a choice, not the lack of one
+		{button: "Never", want: 0},
+	} {
+		t.Run(tt.button, func(t *testing.T) {
+			e := startBot(t, nil)
+			e.setDefaults(t, prefs.CreationDefaults{AskExpiry: true, Expiry: 30 * day})
+
+			e.tg.SendMessage(user, "/shorten https://example.com/a")
+			ask := e.tg.WaitText(user, "When should the link to https://example.com/a expire?")
+			if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 0 {
+				t.Fatalf("link created before the expiry was picked")
+			}
+			e.press(t, ask, tt.button)
+			e.tg.WaitText(user, "Link created successfully")
+			checkExpiry(t, e.lastCreated(t), tt.want)
+		})
+	}
+}
+
+func TestCreationDefaultAutoTitle(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><head><title>\n  Example &amp; page </title></head></html>")
+	}))
+	defer page.Close()
+
+	e := startBot(t, nil)
+	e.setDefaults(t, prefs.CreationDefaults{AutoTitle: true})
+
+	e.tg.SendMessage(user, "/shorten "+page.URL+"/a")
+	e.tg.WaitText(user, "Link created successfully")
+	if got := e.lastCreated(t).GetTitle(); got != "Example & page" {
+		t.Errorf("title = %q, want the page title", got)
+	}
+
+	// An explicit title is kept
+	e.tg.SendMessage(user, "/shorten "+page.URL+"/b title=Mine")
+	e.tg.WaitText(user, "Link created successfully")
+	if got := e.lastCreated(t).GetTitle(); got != "Mine" {
+		t.Errorf("title = %q, want the explicit one", got)
+	}
+}
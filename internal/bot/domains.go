@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/prefs"
+	"fmt"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// shortDomains returns the configured short domains, falling back to the
+// single BaseURL.
+func (b *Bot) shortDomains() []config.ShortDomain {
+	if len(b.config.HTTPServer.Domains) > 0 {
+		return b.config.HTTPServer.Domains
+	}
+	return []config.ShortDomain{{Label: domainHost(b.config.HTTPServer.BaseURL), BaseURL: b.config.HTTPServer.BaseURL}}
+}
+
+// multipleDomains reports whether users can choose between short domains.
+func (b *Bot) multipleDomains() bool {
+	return len(b.shortDomains()) > 1
+}
+
+// findDomain returns the configured domain with the given host.
+func (b *Bot) findDomain(host string) (config.ShortDomain, bool) {
+	for _, d := range b.shortDomains() {
+		if strings.EqualFold(domainHost(d.BaseURL), host) {
+			return d, true
+		}
+	}
+	return config.ShortDomain{}, false
+}
+
+// domainHost returns the host of baseURL, which identifies a domain in
+// requests to the backend.
+func domainHost(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return u.Host
+}
+
+// shortURLOn returns the public short URL for alias on the domain with the
+// given host, or on the default domain when host is empty or unknown.
+func (b *Bot) shortURLOn(host, alias string) string {
+	baseURL := b.shortDomains()[0].BaseURL
+	if d, ok := b.findDomain(host); ok && host != "" {
+		baseURL = d.BaseURL
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL, "/"), alias)
+}
+
+// defaultDomain returns the user's default domain host if it's still configured.
+func (b *Bot) defaultDomain(p prefs.Prefs) string {
+	if !b.multipleDomains() || p.Defaults.Domain == "" {
+		return ""
+	}
+	if _, ok := b.findDomain(p.Defaults.Domain); !ok {
+		return ""
+	}
+	return p.Defaults.Domain
+}
+
+// showDomainPicker asks which domain the next link should be created on.
+func (b *Bot) showDomainPicker(to payloadKey) error {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, d := range b.shortDomains() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(to, d.Label, actionPickDomain, domainHost(d.BaseURL)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(b.callbackButton("Cancel", callbackCancel)))
+	return b.replyWithKeyboard(to.chatID, msgChooseDomain, nil, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// pickDomain remembers the chosen domain and waits for the URL.
+func (b *Bot) pickDomain(chatID int64, host string, answer *callbackAnswer) error {
+	d, ok := b.findDomain(host)
+	if !ok {
+		answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	// A custom alias sent before is kept
+	link, _ := b.getUserState(chatID).Payload.(linkPayload)
+	link.Domain = host
+	if err := b.startDialog(chatID, UserState{State: StateWaitingForURL, Payload: link}); err != nil {
+		return err
+	}
+	return b.reply(chatID, msgSendURLForDomain, domainData{Domain: d.Label})
+}
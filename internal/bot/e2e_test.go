@@ -0,0 +1,326 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/features"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/telegramtest"
+	"GURLS-Bot/internal/version"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// e2e is a bot running against a fake Bot API server and an in-memory
+// backend.
+type e2e struct {
+	tg      *telegramtest.Server
+	backend *backendtest.Backend
+	bot     *Bot
+}
+
+// testConfig returns the default configuration, with every file the bot
+// keeps in a temporary directory.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	// Relative paths of the defaults land in the temporary directory
+	t.Chdir(t.TempDir())
+	t.Setenv("TELEGRAM_TOKEN", telegramtest.Token)
+	var cfg config.Config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		t.Fatalf("failed to read default config: %v", err)
+	}
+	return &cfg
+}
+
+// startBot runs a bot configured by cfg, nil for the defaults, until the
+// test ends.
+func startBot(t *testing.T, cfg *config.Config, opts ...backendtest.Option) *e2e {
+	t.Helper()
+	if cfg == nil {
+		cfg = testConfig(t)
+	}
+	tg := telegramtest.NewServer(t)
+	backend, grpcClient := backendtest.Start(t, opts...)
+	cfg.Telegram.APIEndpoint = tg.Endpoint()
+
+	b, err := New(cfg, zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel)), grpcClient)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return &e2e{tg: tg, backend: backend, bot: b}
+}
+
+// user is the Telegram user the end-to-end tests chat as.
+const user = 1001
+
+// press presses the button of req whose text contains text.
+func (e *e2e) press(t *testing.T, req telegramtest.Request, text string) {
+	t.Helper()
+	e.pressAs(t, user, req, text)
+}
+
+// pressAs is press by userID.
+func (e *e2e) pressAs(t *testing.T, userID int64, req telegramtest.Request, text string) {
+	t.Helper()
+	data, ok := req.Button(text)
+	if !ok {
+		t.Fatalf("no %q button in %q: %v", text, req.Text(), req.Buttons())
+	}
+	e.tg.PressButton(userID, req, data)
+}
+
+// answer waits for the answer of the next callback query, toast or alert.
+func (e *e2e) answer() telegramtest.Request {
+	return e.tg.Wait("answerCallbackQuery", nil)
+}
+
+func TestE2EShortenCommand(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page title=\"My page\"")
+	r := e.tg.WaitText(user, "Link created successfully")
+	if !strings.Contains(r.Text(), "http://localhost:8080/gen1") {
+		t.Errorf("reply %q lacks the short URL", r.Text())
+	}
+	if _, ok := r.Button("Statistics"); !ok {
+		t.Errorf("reply lacks the link actions: %v", r.Buttons())
+	}
+
+	link, ok := e.backend.Link("gen1")
+	if !ok {
+		t.Fatal("link not created on the backend")
+	}
+	if link.OriginalURL != "https://example.com/page" || link.OwnerID != user || link.Title != "My page" {
+		t.Errorf("backend link = %+v", link)
+	}
+}
+
+func TestE2EShortenPlainURL(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "https://example.net/plain")
+	e.tg.WaitText(user, "http://localhost:8080/gen1")
+	if link, ok := e.backend.Link("gen1"); !ok || link.OriginalURL != "https://example.net/plain" {
+		t.Errorf("backend link = %+v, %v", link, ok)
+	}
+}
+
+func TestE2EShortenInvalidURL(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/shorten")
+	e.tg.WaitText(user, "Invalid format")
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 0 {
+		t.Errorf("CreateLink called %d times without a URL", len(calls))
+	}
+}
+
+func TestE2ECustomAliasWizard(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/start")
+	e.press(t, e.tg.WaitText(user, "Select an action"), "Create Link")
+	e.press(t, e.tg.WaitText(user, "Send a URL"), "Custom Alias")
+	e.answer()
+	e.tg.WaitText(user, "Send your custom alias")
+
+	e.tg.SendMessage(user, "bad alias!")
+	e.tg.WaitText(user, "Invalid alias format")
+
+	e.tg.SendMessage(user, "my-alias")
+	e.tg.WaitText(user, "with alias 'my-alias'")
+	e.tg.SendMessage(user, "https://example.org/x")
+	r := e.tg.WaitText(user, "Link created successfully")
+	if !strings.Contains(r.Text(), "http://localhost:8080/my-alias") {
+		t.Errorf("reply %q lacks the custom alias", r.Text())
+	}
+	if link, ok := e.backend.Link("my-alias"); !ok || link.OriginalURL != "https://example.org/x" {
+		t.Errorf("backend link = %+v, %v", link, ok)
+	}
+}
+
+func TestE2ECustomAliasTaken(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "taken", OriginalURL: "https://example.com/", OwnerID: 2002})
+
+	e.tg.SendMessage(user, "/shorten https://example.org/x alias=taken")
+	e.tg.WaitText(user, "taken")
+	if link, _ := e.backend.Link("taken"); link.OwnerID != 2002 {
+		t.Errorf("taken alias changed hands: %+v", link)
+	}
+}
+
+func TestE2EDeleteFromLinkCard(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page")
+	e.press(t, e.tg.WaitText(user, "Link created successfully"), "Delete")
+
+	if toast := e.answer(); toast.Param("text") != "Deleted localhost:8080/gen1" {
+		t.Errorf("toast = %q", toast.Param("text"))
+	}
+	e.tg.WaitText(user, "Link 'gen1' has been deleted successfully")
+	if _, ok := e.backend.Link("gen1"); ok {
+		t.Error("link still on the backend")
+	}
+}
+
+func TestE2EDeleteFromMyLinks(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(
+		backendtest.Link{Alias: "first", OriginalURL: "https://example.com/1", OwnerID: user},
+		backendtest.Link{Alias: "second", OriginalURL: "https://example.com/2", OwnerID: user},
+	)
+
+	e.tg.SendMessage(user, "/my_links")
+	list := e.tg.WaitText(user, "Your Links")
+	data, _ := list.Button("Delete")
+	e.tg.PressButton(user, list, data)
+	if toast := e.answer(); toast.Param("text") != "Deleted localhost:8080/first" {
+		t.Errorf("toast = %q", toast.Param("text"))
+	}
+
+	// The list is updated in place rather than followed by a new message
+	edited := e.tg.Wait("editMessageText", nil)
+	if edited.Param("message_id") != strconv.Itoa(list.MessageID) {
+		t.Errorf("edited message %s, want the list %d", edited.Param("message_id"), list.MessageID)
+	}
+	if strings.Contains(edited.Text(), "/first") || !strings.Contains(edited.Text(), "/second") {
+		t.Errorf("updated list = %q", edited.Text())
+	}
+
+	// Deleting the last link leaves the empty view
+	data, _ = edited.Button("Delete")
+	e.tg.PressButton(user, edited, data)
+	e.answer()
+	if last := e.tg.Wait("editMessageText", nil); !strings.Contains(last.Text(), "You have no links yet") {
+		t.Errorf("list after the last delete = %q", last.Text())
+	}
+}
+
+func TestE2EMyLinksPaging(t *testing.T) {
+	e := startBot(t, nil)
+	for i := range 12 {
+		e.backend.Add(backendtest.Link{Alias: fmt.Sprintf("l%02d", i), OriginalURL: fmt.Sprintf("https://example.com/%d", i), OwnerID: user})
+	}
+	e.backend.Add(backendtest.Link{Alias: "other", OriginalURL: "https://example.com/other", OwnerID: 2002})
+
+	e.tg.SendMessage(user, "/my_links")
+	first := e.tg.WaitText(user, "Your Links")
+	if !strings.Contains(first.Text(), "(page 1)") || !strings.Contains(first.Text(), "/l09") || strings.Contains(first.Text(), "/l10") {
+		t.Fatalf("first page = %q", first.Text())
+	}
+	if _, ok := first.Button("First page"); ok {
+		t.Error("first page offers a previous page")
+	}
+
+	e.press(t, first, "Next")
+	second := e.tg.Wait("editMessageText", nil)
+	if !strings.Contains(second.Text(), "(page 2)") || !strings.Contains(second.Text(), "/l10") || !strings.Contains(second.Text(), "/l11") {
+		t.Fatalf("second page = %q", second.Text())
+	}
+	if strings.Contains(second.Text(), "/l09") || strings.Contains(second.Text(), "/other") {
+		t.Errorf("second page lists links beyond it: %q", second.Text())
+	}
+	if _, ok := second.Button("Next"); ok {
+		t.Error("last page offers a next page")
+	}
+
+	e.press(t, second, "First page")
+	if back := e.tg.Wait("editMessageText", nil); !strings.Contains(back.Text(), "(page 1)") {
+		t.Errorf("page after going back = %q", back.Text())
+	}
+}
+
+func TestE2EPingWithoutHealthService(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+
+	// The test backend has no health service: the connection state decides
+	e.tg.SendMessage(user, "/ping")
+	e.tg.WaitText(user, "Backend: serving (READY, no health service)")
+}
+
+func TestE2EVersion(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	cfg.Features = map[string]bool{features.Monitor: false}
+	e := startBot(t, cfg)
+
+	e.tg.SendMessage(user, "/version")
+	got := e.tg.WaitText(user, "Version: "+version.String()).Text()
+	if !strings.Contains(got, "Features: inline=on monitor=off safe_browsing=on transfer=on") {
+		t.Errorf("/version = %q, want the active flags", got)
+	}
+
+	// Other users don't see the command
+	e.tg.SendMessage(user+1, "/version")
+	e.tg.WaitText(user+1, "Unknown command")
+}
+
+func TestSafeBrowsingFlag(t *testing.T) {
+	for _, on := range []bool{true, false} {
+		cfg := testConfig(t)
+		cfg.SafeBrowsing.Enabled = true
+		cfg.SafeBrowsing.APIKey = "test"
+		cfg.Features = map[string]bool{features.SafeBrowsing: on}
+		e := startBot(t, cfg)
+		if checks := e.bot.urlChecker != nil; checks != on {
+			t.Errorf("safe_browsing=%v: URLs checked = %v", on, checks)
+		}
+	}
+}
+
+func TestE2ERequestIDReachesBackend(t *testing.T) {
+	cfg := testConfig(t)
+	// The quota check lists the user's links before creating one
+	cfg.Quota.MaxActiveLinks = 10
+	e := startBot(t, cfg)
+
+	e.tg.SendMessage(user, "/shorten https://example.com/a")
+	e.tg.WaitText(user, "Link created successfully")
+	e.tg.SendMessage(user, "https://example.com/b")
+	e.tg.WaitText(user, "Link created successfully")
+
+	listed := e.backend.Metadata(shortenerv1.Shortener_ListUserLinks_FullMethodName)
+	created := e.backend.Metadata(shortenerv1.Shortener_CreateLink_FullMethodName)
+	if len(listed) != 2 || len(created) != 2 {
+		t.Fatalf("backend got %d ListUserLinks and %d CreateLink calls, want 2 each", len(listed), len(created))
+	}
+	var ids []string
+	for i := range created {
+		id := created[i].Get("x-request-id")
+		if len(id) != 1 || id[0] == "" {
+			t.Fatalf("update %d: CreateLink request ID = %q", i+1, id)
+		}
+		if got := listed[i].Get("x-request-id"); !slices.Equal(got, id) {
+			t.Errorf("update %d: ListUserLinks request ID = %q, want %q like CreateLink", i+1, got, id)
+		}
+		if got := created[i].Get("x-bot-version"); !slices.Equal(got, []string{version.String()}) {
+			t.Errorf("update %d: bot version = %q, want %q", i+1, got, version.String())
+		}
+		ids = append(ids, id[0])
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("two updates share request ID %s", ids[0])
+	}
+}
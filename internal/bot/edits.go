@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageOutcome is how the bot reacted to a plain message.
+type messageOutcome int
+
+const (
+	outcomeRejected messageOutcome = iota + 1
+	outcomeLinked
+)
+
+// messageKey identifies a message; recent messages are remembered by it
+// with the bot's reaction so edits can be handled.
+type messageKey struct {
+	chatID    int64
+	messageID int
+}
+
+// handleEditedMessage reacts to edits of recent messages: a previously
+// rejected message now containing a URL is shortened, while edits of messages
+// that already produced a link offer to shorten the new URL separately.
+func (b *Bot) handleEditedMessage(ctx context.Context, msg *tgbotapi.Message) error {
+	if time.Since(msg.Time()) > b.config.Telegram.EditMaxAge {
+		return nil
+	}
+	if b.getUserState(msg.Chat.ID).State != StateNormal {
+		return nil
+	}
+
+	outcome, ok := b.recentMessages.Get(messageKey{msg.Chat.ID, msg.MessageID})
+	if !ok {
+		return nil
+	}
+	url := urlRegex.FindString(msg.Text)
+	if url == "" {
+		return nil
+	}
+
+	switch outcome {
+	case outcomeRejected:
+		created, err := b.shorten(ctx, messagePayloads(msg), msg.Text)
+		if created {
+			b.recentMessages.Set(messageKey{msg.Chat.ID, msg.MessageID}, outcomeLinked)
+		}
+		return err
+	case outcomeLinked:
+		reply := tgbotapi.NewMessage(msg.Chat.ID, b.render(msgEditedLinkUnchanged, nil))
+		reply.ReplyToMessageID = msg.MessageID
+		reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				b.payloadButton(messagePayloads(msg), "Shorten new URL", actionShortenURL, url),
+			),
+		)
+		_, err := b.send(reply)
+		return err
+	}
+	return nil
+}
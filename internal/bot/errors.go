@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mapGRPCError translates a backend error into a message suitable for the user.
+// alias is used for messages that refer to a specific link and may be empty.
+func (b *Bot) mapGRPCError(err error, alias string) string {
+	return b.render(grpcErrorTemplate(err, alias))
+}
+
+// grpcErrorTemplate returns the message template and data for a backend error.
+func grpcErrorTemplate(err error, alias string) (string, any) {
+	var unscheduled *unscheduledLinkError
+	var maintenance *maintenanceError
+	switch {
+	case errors.As(err, &maintenance):
+		w := maintenance.window
+		return msgMaintenance, maintenanceData{End: w.End, Message: w.Message, Active: true}
+	case errors.Is(err, errSchedulingUnsupported):
+		return msgSchedulingUnsupported, nil
+	case errors.As(err, &unscheduled):
+		return msgUnscheduledLinkKept, aliasData{Alias: unscheduled.alias}
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return msgInternalError, nil
+	}
+
+	switch st.Code() {
+	case codes.NotFound:
+		return msgLinkNotFound, aliasData{Alias: alias}
+	case codes.AlreadyExists:
+		// Without a custom alias the collision is on a generated one
+		if alias == "" {
+			return msgAliasGenerationFailed, nil
+		}
+		return msgAliasTaken, aliasData{Alias: alias}
+	case codes.InvalidArgument:
+		if st.Message() == "" {
+			return msgInvalidRequest, nil
+		}
+		return msgInvalidArgument, errorData{Error: st.Message()}
+	case codes.ResourceExhausted:
+		return msgResourceExhausted, nil
+	case codes.Unavailable:
+		return msgServiceUnavailable, nil
+	case codes.Unimplemented:
+		return msgFeatureUnavailable, nil
+	case codes.DeadlineExceeded:
+		return msgRequestTimeout, nil
+	case codes.PermissionDenied:
+		return msgPermissionDenied, nil
+	case codes.Unauthenticated:
+		return msgUnauthenticated, nil
+	default:
+		return msgInternalError, nil
+	}
+}
+
+// shortRequestID returns the prefix of a request ID shown to admins; it is
+// unique enough to search the logs for.
+func shortRequestID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/maintenance"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCErrorTemplate(t *testing.T) {
+	end := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		err      error
+		alias    string
+		template string
+		text     string
+	}{
+		{"not found", status.Error(codes.NotFound, "no such link"), "abc", msgLinkNotFound, "Link with alias 'abc' not found."},
+		{"custom alias taken", status.Error(codes.AlreadyExists, "taken"), "abc", msgAliasTaken, "Alias 'abc' is already taken."},
+		{"generated alias collided", status.Error(codes.AlreadyExists, "taken"), "", msgAliasGenerationFailed, "Couldn't generate a free short alias"},
+		{"invalid argument with message", status.Error(codes.InvalidArgument, "url must be absolute"), "", msgInvalidArgument, "The request was rejected: url must be absolute"},
+		{"invalid argument without message", status.Error(codes.InvalidArgument, ""), "", msgInvalidRequest, "Please check your input"},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "quota"), "", msgResourceExhausted, "usage limit"},
+		{"unavailable", status.Error(codes.Unavailable, "down"), "", msgServiceUnavailable, "temporarily unavailable"},
+		{"unimplemented", status.Error(codes.Unimplemented, ""), "", msgFeatureUnavailable, "not available yet"},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, ""), "", msgRequestTimeout, "taking too long"},
+		{"permission denied", status.Error(codes.PermissionDenied, ""), "abc", msgPermissionDenied, "don't have permission"},
+		{"unauthenticated", status.Error(codes.Unauthenticated, ""), "", msgUnauthenticated, "could not authenticate"},
+		{"unknown code", status.Error(codes.DataLoss, "oops"), "", msgInternalError, "Internal error occurred"},
+		{"not a status", errors.New("boom"), "", msgInternalError, "Internal error occurred"},
+		{"wrapped status", fmt.Errorf("call failed: %w", status.Error(codes.NotFound, "")), "xyz", msgLinkNotFound, "'xyz' not found"},
+		{"maintenance", &maintenanceError{window: maintenance.Window{End: end, Message: "upgrade"}}, "", msgMaintenance, "upgrade"},
+		{"scheduling unsupported", errSchedulingUnsupported, "", msgSchedulingUnsupported, ""},
+		{"unscheduled link kept", &unscheduledLinkError{alias: "abc", err: errors.New("x")}, "", msgUnscheduledLinkKept, "abc"},
+	}
+
+	messages, err := newMessageTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bot{log: zap.NewNop(), messages: messages}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, _ := grpcErrorTemplate(tt.err, tt.alias)
+			if name != tt.template {
+				t.Errorf("template = %s, want %s", name, tt.template)
+			}
+			if text := b.mapGRPCError(tt.err, tt.alias); !strings.Contains(text, tt.text) || text == name {
+				t.Errorf("text = %q, want it to contain %q", text, tt.text)
+			}
+		})
+	}
+}
+
+func TestE2EStatsInvalidArgument(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "abc", OriginalURL: "https://example.com/", OwnerID: user})
+	e.backend.Fail(shortenerv1.Shortener_GetLinkStats_FullMethodName, status.Error(codes.InvalidArgument, "alias is reserved"))
+
+	e.tg.SendMessage(user, "/stats abc")
+	e.tg.WaitText(user, "The request was rejected: alias is reserved")
+}
+
+func TestE2ECreateDeadlineExceeded(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Fail(shortenerv1.Shortener_CreateLink_FullMethodName, status.Error(codes.DeadlineExceeded, ""))
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page")
+	e.tg.WaitText(user, "The request is taking too long")
+}
+
+func TestE2EDeletePermissionDeniedAlert(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page")
+	card := e.tg.WaitText(user, "Link created successfully")
+	e.backend.Fail(shortenerv1.Shortener_DeleteLink_FullMethodName, status.Error(codes.PermissionDenied, ""))
+	e.press(t, card, "Delete")
+
+	answer := e.answer()
+	if answer.Param("show_alert") != "true" || !strings.Contains(answer.Param("text"), "don't have permission") {
+		t.Errorf("callback answer = %v, want a permission alert", answer.Params)
+	}
+	if _, ok := e.backend.Link("gen1"); !ok {
+		t.Error("link deleted despite the refusal")
+	}
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// /expand can be used to enumerate aliases, so it is limited harder than other commands.
+const (
+	expandRateLimit       = 5
+	expandRateLimitWindow = time.Minute
+)
+
+// handleExpandCommand reveals where a short link leads without exposing its statistics.
+func (b *Bot) handleExpandCommand(ctx context.Context, chatID int64, args string) error {
+	alias, err := b.resolveAlias(args)
+	if err != nil {
+		return b.replyAliasError(chatID, err, "expand")
+	}
+
+	res, err := b.resolveLink(ctx, alias)
+	if err != nil {
+		b.log.Error("gRPC ResolveLink failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(chatID, err, alias)
+	}
+
+	data := expandData{
+		Locale:      b.formatterFor(chatID),
+		ShortURL:    b.shortURL(alias),
+		OriginalURL: res.GetOriginalUrl(),
+		Active:      res.GetActive(),
+	}
+	if res.ExpiresAt != nil {
+		expires := res.ExpiresAt.AsTime()
+		data.ExpiresAt = &expires
+		data.Expired = expires.Before(time.Now())
+	}
+	return b.reply(chatID, msgExpandResult, data, b.linkContent())
+}
+
+// resolveLink looks up a link's destination. Backends without ResolveLink are
+// asked for stats instead, of which only the public fields are used.
+func (b *Bot) resolveLink(ctx context.Context, alias string) (*shortenerv1.ResolveLinkResponse, error) {
+	res, err := b.grpcClient.ResolveLink(ctx, &shortenerv1.ResolveLinkRequest{Alias: alias})
+	if status.Code(err) != codes.Unimplemented {
+		return res, err
+	}
+
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		return nil, err
+	}
+	res = &shortenerv1.ResolveLinkResponse{OriginalUrl: stats.GetOriginalUrl(), ExpiresAt: stats.ExpiresAt}
+	res.Active = res.ExpiresAt == nil || res.ExpiresAt.AsTime().After(time.Now())
+	return res, nil
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// extendPresets are the extensions offered for an expiring link.
+var extendPresets = []string{"1d", "7d", "30d"}
+
+func (b *Bot) handleExpiringCommand(ctx context.Context, to payloadKey) error {
+	text, keyboard, err := b.buildExpiring(ctx, to)
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+		return b.replyGRPCError(to.chatID, err, "")
+	}
+	return b.sendMessageWithKeyboard(to.chatID, text, keyboard, b.linkContent())
+}
+
+// buildExpiring renders the links of to.chatID expiring within the configured
+// window, soonest first. Links whose stats can't be fetched are left out.
+func (b *Bot) buildExpiring(ctx context.Context, to payloadKey) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	var res *shortenerv1.ListUserLinksResponse
+	err := b.withChatAction(ctx, to.chatID, tgbotapi.ChatTyping, func() (err error) {
+		res, err = b.listUserLinks(ctx, to.chatID)
+		return err
+	})
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	results := fanOut(ctx, fanOutOptions{Workers: b.config.Expiring.Workers}, res.Links,
+		func(ctx context.Context, link *shortenerv1.LinkInfo) (*shortenerv1.GetLinkStatsResponse, error) {
+			return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: link.Alias})
+		})
+	if failed := results.Failed(); len(failed) > 0 {
+		b.log.Debug("expiring links incomplete", zap.Int64("user_id", to.chatID), zap.Int("failed", len(failed)))
+	}
+
+	type expiring struct {
+		link      *shortenerv1.LinkInfo
+		expiresAt time.Time
+	}
+	now := time.Now()
+	var links []expiring
+	for _, r := range results.Succeeded() {
+		at := protoTime(r.Value.GetExpiresAt())
+		if at != nil && at.After(now) && at.Sub(now) <= b.config.Expiring.Window {
+			links = append(links, expiring{link: r.Item, expiresAt: *at})
+		}
+	}
+	slices.SortFunc(links, func(a, b expiring) int { return a.expiresAt.Compare(b.expiresAt) })
+
+	window := windowData{Window: formatRemaining(b.config.Expiring.Window)}
+	nav := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("All Links", callbackMyLinks),
+			b.callbackButton("Main Menu", callbackHelp),
+		),
+	}
+	if len(links) == 0 {
+		return b.render(msgNoExpiringLinks, window), tgbotapi.InlineKeyboardMarkup{InlineKeyboard: nav}, nil
+	}
+
+	var section linkListSection
+	var bulk []bulkLink
+	for _, l := range links {
+		actions := tgbotapi.NewInlineKeyboardRow(b.payloadButton(to, "Stats", actionStats, l.link.Alias))
+		if b.supports(featureExtend) {
+			actions = append(actions, b.payloadButton(to, "Extend", actionExtend, l.link.Alias))
+		}
+		section.Items = append(section.Items, linkListItem{
+			Link:      l.link,
+			Pinned:    b.isPinned(to.chatID, l.link.Alias),
+			ExpiresIn: formatRemaining(l.expiresAt.Sub(now)),
+			Actions:   actions,
+		})
+		bulk = append(bulk, bulkLink{Alias: l.link.Alias, ExpiresAt: l.expiresAt})
+	}
+	if b.hasBulkActions(to.chatID) {
+		if button, ok := b.bulkActionsButton(to, bulk); ok {
+			nav = append([][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(button)}, nav...)
+		}
+	}
+	text, keyboard := b.renderLinkList(to, b.outputStyle(to.chatID), "expiring", b.render(msgExpiringHeader, window), []linkListSection{section}, nav)
+	return text, keyboard, nil
+}
+
+// formatRemaining renders d coarsely, in its two largest units: "3d 4h",
+// "5h 10m" or "12m".
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	hours := d % (24 * time.Hour) / time.Hour
+	minutes := d % time.Hour / time.Minute
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", max(minutes, 1))
+	}
+}
+
+// handleExtend offers to push back the expiry of a link.
+func (b *Bot) handleExtend(ctx context.Context, r *Request) error {
+	alias := r.Args
+	res, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	expiresAt := protoTime(res.GetExpiresAt())
+	if expiresAt == nil {
+		r.Answer.alert(b.render(msgNeverExpires, nil))
+		return nil
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, preset := range extendPresets {
+		row = append(row, b.payloadButton(r.Payloads(), "+"+preset, actionExtendBy, preset+"/"+alias))
+	}
+	text := b.render(msgExtendLink, extendData{
+		Locale:    b.formatterFor(r.ChatID),
+		ShortURL:  b.shortURLOn(res.GetDomain(), alias),
+		ExpiresAt: *expiresAt,
+	})
+	return b.sendMessageWithKeyboard(r.ChatID, text, tgbotapi.NewInlineKeyboardMarkup(row), b.linkContent())
+}
+
+// handleExtendBy extends a link by the chosen preset, counting from its
+// current expiry or from now if it has already expired.
+func (b *Bot) handleExtendBy(ctx context.Context, r *Request) error {
+	preset, alias, ok := strings.Cut(r.Args, "/")
+	by, err := parseExpiry(preset)
+	if !ok || err != nil || by == 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	current := protoTime(stats.GetExpiresAt())
+	if current == nil {
+		r.Answer.alert(b.render(msgNeverExpires, nil))
+		return nil
+	}
+
+	expiresAt := *current
+	if now := time.Now(); expiresAt.Before(now) {
+		expiresAt = now
+	}
+	expiresAt = expiresAt.Add(by)
+	res, err := b.grpcClient.SetLinkExpiry(ctx, &shortenerv1.SetLinkExpiryRequest{
+		Alias:     alias,
+		UserTgId:  r.UserID,
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
+	if err != nil {
+		b.log.Error("gRPC SetLinkExpiry failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+
+	if confirmed := protoTime(res.GetExpiresAt()); confirmed != nil {
+		expiresAt = *confirmed
+	}
+	text := b.render(msgLinkExtended, extendData{
+		Locale:    b.formatterFor(r.ChatID),
+		ShortURL:  b.shortURLOn(stats.GetDomain(), alias),
+		ExpiresAt: expiresAt,
+	})
+	edit := tgbotapi.NewEditMessageText(r.ChatID, r.Message.MessageID, text)
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, func() error {
+		return b.sendMessage(r.ChatID, text, false, b.linkContent())
+	})
+}
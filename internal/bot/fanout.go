@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fanOutOptions bounds a fan-out of backend calls. Zero values fall back to
+// the defaults below.
+type fanOutOptions struct {
+	// Workers is the maximum number of calls in flight.
+	Workers int
+	// ItemTimeout bounds a single call.
+	ItemTimeout time.Duration
+	// Deadline bounds the whole fan-out; items not finished by then fail.
+	Deadline time.Duration
+}
+
+const (
+	defaultFanOutWorkers     = 4
+	defaultFanOutItemTimeout = 5 * time.Second
+	defaultFanOutDeadline    = 20 * time.Second
+)
+
+// fanOutResult is the outcome of one item of a fan-out.
+type fanOutResult[Req, Resp any] struct {
+	Item  Req
+	Value Resp
+	Err   error
+}
+
+// fanOutResults holds the outcomes of a fan-out in input order.
+type fanOutResults[Req, Resp any] []fanOutResult[Req, Resp]
+
+// Succeeded returns the results of the items that completed without error.
+func (r fanOutResults[Req, Resp]) Succeeded() []fanOutResult[Req, Resp] {
+	var ok []fanOutResult[Req, Resp]
+	for _, res := range r {
+		if res.Err == nil {
+			ok = append(ok, res)
+		}
+	}
+	return ok
+}
+
+// Failed returns the results of the items that failed, with the reason.
+func (r fanOutResults[Req, Resp]) Failed() []fanOutResult[Req, Resp] {
+	var failed []fanOutResult[Req, Resp]
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// fanOut calls fn for every item with bounded concurrency and returns one
+// result per item, in input order. It never fails as a whole: items that
+// could not be scheduled because ctx was cancelled or the deadline passed
+// carry the context error, so callers can render partial results.
+func fanOut[Req, Resp any](ctx context.Context, opts fanOutOptions, items []Req, fn func(context.Context, Req) (Resp, error)) fanOutResults[Req, Resp] {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultFanOutWorkers
+	}
+	if opts.ItemTimeout <= 0 {
+		opts.ItemTimeout = defaultFanOutItemTimeout
+	}
+	if opts.Deadline <= 0 {
+		opts.Deadline = defaultFanOutDeadline
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Deadline)
+	defer cancel()
+
+	results := make(fanOutResults[Req, Resp], len(items))
+	for i, item := range items {
+		results[i].Item = item
+	}
+
+	sem := make(chan struct{}, opts.Workers)
+	var wg sync.WaitGroup
+	for i := range items {
+		// Stop scheduling as soon as the context is done, even when a
+		// worker slot is free
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		// The slot may have been freed by the call that cancelled
+		if err := ctx.Err(); err != nil {
+			<-sem
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(res *fanOutResult[Req, Resp]) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			itemCtx, cancel := context.WithTimeout(ctx, opts.ItemTimeout)
+			defer cancel()
+			res.Value, res.Err = fn(itemCtx, res.Item)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errFanOutItem = errors.New("item failed")
+
+// fakeStats plays a backend answering after latency, failing the items in
+// fail and hanging on the items in hang until their context ends.
+type fakeStats struct {
+	latency  time.Duration
+	fail     map[int]bool
+	hang     map[int]bool
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (f *fakeStats) call(ctx context.Context, item int) (string, error) {
+	f.calls.Add(1)
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if n <= seen || f.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	if f.hang[item] {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	select {
+	case <-time.After(f.latency):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if f.fail[item] {
+		return "", errFanOutItem
+	}
+	return "stats " + strconv.Itoa(item), nil
+}
+
+func fanOutItems(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+func TestFanOutBoundsConcurrencyAndKeepsOrder(t *testing.T) {
+	f := &fakeStats{latency: 10 * time.Millisecond, fail: map[int]bool{3: true, 7: true}}
+	results := fanOut(context.Background(), fanOutOptions{Workers: 3}, fanOutItems(10), f.call)
+
+	if got := f.maxSeen.Load(); got > 3 {
+		t.Errorf("%d calls in flight, want at most 3", got)
+	}
+	if len(results) != 10 {
+		t.Fatalf("got %d results, want 10", len(results))
+	}
+	for i, res := range results {
+		if res.Item != i {
+			t.Errorf("result %d is of item %d", i, res.Item)
+		}
+		if f.fail[i] {
+			if !errors.Is(res.Err, errFanOutItem) {
+				t.Errorf("item %d: error %v, want the backend's", i, res.Err)
+			}
+		} else if res.Err != nil || res.Value != "stats "+strconv.Itoa(i) {
+			t.Errorf("item %d = %q, %v", i, res.Value, res.Err)
+		}
+	}
+	if ok, failed := len(results.Succeeded()), len(results.Failed()); ok != 8 || failed != 2 {
+		t.Errorf("%d succeeded and %d failed, want 8 and 2", ok, failed)
+	}
+}
+
+func TestFanOutItemTimeout(t *testing.T) {
+	f := &fakeStats{hang: map[int]bool{1: true}}
+	results := fanOut(context.Background(), fanOutOptions{Workers: 2, ItemTimeout: 20 * time.Millisecond}, fanOutItems(3), f.call)
+
+	if !errors.Is(results[1].Err, context.DeadlineExceeded) {
+		t.Errorf("hanging item: error %v, want its timeout", results[1].Err)
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil {
+			t.Errorf("item %d failed with the hanging one: %v", i, results[i].Err)
+		}
+	}
+}
+
+func TestFanOutDeadlineReportsPartialResults(t *testing.T) {
+	f := &fakeStats{latency: 5 * time.Millisecond, hang: map[int]bool{2: true}}
+	start := time.Now()
+	results := fanOut(context.Background(), fanOutOptions{Workers: 1, ItemTimeout: time.Minute, Deadline: 50 * time.Millisecond}, fanOutItems(5), f.call)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fan-out took %v past its deadline", elapsed)
+	}
+	for i, res := range results {
+		switch {
+		case i < 2 && res.Err != nil:
+			t.Errorf("item %d done before the deadline failed: %v", i, res.Err)
+		case i >= 2 && !errors.Is(res.Err, context.DeadlineExceeded):
+			t.Errorf("item %d: error %v, want the deadline", i, res.Err)
+		}
+	}
+	if calls := f.calls.Load(); calls != 3 {
+		t.Errorf("%d items called, want none scheduled after the deadline", calls)
+	}
+}
+
+func TestFanOutCancelStopsScheduling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	results := fanOut(ctx, fanOutOptions{Workers: 1}, fanOutItems(5), func(ctx context.Context, item int) (string, error) {
+		calls.Add(1)
+		if item == 1 {
+			cancel()
+		}
+		return "ok", nil
+	})
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("%d items called, want scheduling stopped on cancel", got)
+	}
+	for i, res := range results[2:] {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("item %d: error %v, want context.Canceled", i+2, res.Err)
+		}
+	}
+	if len(results.Succeeded()) != 2 {
+		t.Errorf("results of the items done before cancel lost: %+v", results)
+	}
+}
@@ -0,0 +1,274 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxForwardTitleLen caps the title suggested for a link from a channel post.
+const maxForwardTitleLen = 100
+
+// Kinds of forward origins, named as in the Bot API's MessageOrigin.
+const (
+	forwardFromUser       = "user"
+	forwardFromHiddenUser = "hidden_user"
+	forwardFromChat       = "chat"
+	forwardFromChannel    = "channel"
+)
+
+// forwardOrigin is where a forwarded message was first posted.
+type forwardOrigin struct {
+	Kind string
+	// Name is the name of the user or the title of the chat.
+	Name string
+	// Username is the public username of the user or chat, if any.
+	Username string
+}
+
+// publicChannel reports whether the message was posted in a public channel.
+func (o forwardOrigin) publicChannel() bool {
+	return o.Kind == forwardFromChannel && o.Username != ""
+}
+
+// forwardOriginOf tells where msg was forwarded from and reports whether it
+// is a forward at all. Updates using forward_origin are mapped to the fields
+// read here by legacyForwardClient.
+func forwardOriginOf(msg *tgbotapi.Message) (forwardOrigin, bool) {
+	switch {
+	case msg.ForwardFromChat != nil:
+		kind := forwardFromChat
+		if msg.ForwardFromChat.IsChannel() {
+			kind = forwardFromChannel
+		}
+		return forwardOrigin{Kind: kind, Name: msg.ForwardFromChat.Title, Username: msg.ForwardFromChat.UserName}, true
+	case msg.ForwardFrom != nil:
+		name := strings.TrimSpace(msg.ForwardFrom.FirstName + " " + msg.ForwardFrom.LastName)
+		return forwardOrigin{Kind: forwardFromUser, Name: name, Username: msg.ForwardFrom.UserName}, true
+	case msg.ForwardSenderName != "" || msg.ForwardDate != 0:
+		return forwardOrigin{Kind: forwardFromHiddenUser, Name: msg.ForwardSenderName}, true
+	}
+	return forwardOrigin{}, false
+}
+
+// forwardTitle suggests a link title for a post forwarded from a public
+// channel: the channel name and the first line of the post that isn't just
+// the URL. Other forwards get none.
+func forwardTitle(origin forwardOrigin, msg *tgbotapi.Message, url string) string {
+	if !origin.publicChannel() {
+		return ""
+	}
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	title := origin.Name
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != url {
+			title += ": " + line
+			break
+		}
+	}
+	if utf8.RuneCountInString(title) > maxForwardTitleLen {
+		title = string([]rune(title)[:maxForwardTitleLen-1]) + "…"
+	}
+	return title
+}
+
+// handleForwardedMessage shortens the URLs of a forwarded message for the
+// forwarding user. For a post from a public channel, each URL is previewed
+// with a suggested title the user can keep or leave out.
+func (b *Bot) handleForwardedMessage(ctx context.Context, msg *tgbotapi.Message, origin forwardOrigin) error {
+	chatID := msg.Chat.ID
+	var urls []string
+	for _, u := range extractURLs(msg) {
+		if !b.isOwnShortURL(u) {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return b.reply(chatID, msgForwardNoURL, nil)
+	}
+
+	to := messagePayloads(msg)
+	created := false
+	// A URL failing doesn't stop the others
+	var errs error
+	for _, u := range urls {
+		var err error
+		switch title := forwardTitle(origin, msg, u); {
+		case title != "":
+			err = b.confirmForwardTitle(to, u, title)
+		case b.confirmsShortening(msg):
+			err = b.confirmShorten(to, u, u)
+		default:
+			var ok bool
+			ok, err = b.createLink(ctx, to, &shortenerv1.CreateLinkRequest{OriginalUrl: u, UserTgId: chatID, Source: linkSource(sourceBotMessage)}, createOptions{})
+			created = created || ok
+		}
+		errs = errors.Join(errs, err)
+	}
+	if created {
+		b.recentMessages.Set(messageKey{chatID, msg.MessageID}, outcomeLinked)
+	}
+	return errs
+}
+
+// confirmForwardTitle offers to shorten url with or without title.
+func (b *Bot) confirmForwardTitle(to payloadKey, url, title string) error {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Shorten with title", actionShortenTitled, url+"\n"+title),
+			b.storedPayloadButton(to, "Without title", actionConfirmShorten, url),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Ignore", actionIgnoreURL, url),
+		),
+	)
+	msg := tgbotapi.NewMessage(to.chatID, b.render(msgConfirmForwardTitle, forwardTitleData{URL: url, Title: title}))
+	msg.ReplyMarkup = keyboard
+	msg.DisableWebPagePreview = true
+	_, err := b.send(msg)
+	return err
+}
+
+// handleShortenTitled creates the link of a forwarded URL with the title
+// suggested for it.
+func (b *Bot) handleShortenTitled(ctx context.Context, r *Request) error {
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	url, title, _ := strings.Cut(r.Args, "\n")
+	req := &shortenerv1.CreateLinkRequest{OriginalUrl: url, UserTgId: r.ChatID, Source: linkSource(sourceBotMessage)}
+	if title != "" {
+		req.Title = &title
+	}
+	_, err := b.createLink(ctx, r.Payloads(), req, createOptions{})
+	return err
+}
+
+// legacyForwardClient rewrites getUpdates responses so that the
+// forward_origin of messages from Bot API 7 on also shows up as the
+// forward_* fields, which are all the Telegram library decodes.
+type legacyForwardClient struct {
+	next tgbotapi.HTTPClient
+}
+
+func (c legacyForwardClient) Do(req *http.Request) (*http.Response, error) {
+	res, err := c.next.Do(req)
+	if err != nil || !strings.HasSuffix(req.URL.Path, "/getUpdates") {
+		return res, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if rewritten, err := addLegacyForwardFields(body); err == nil {
+		body = rewritten
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	return res, nil
+}
+
+// addLegacyForwardFields fills in the forward_* fields of the messages in a
+// getUpdates response from their forward_origin. Responses without any are
+// returned as they are.
+func addLegacyForwardFields(body []byte) ([]byte, error) {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var updates []map[string]json.RawMessage
+	if err := json.Unmarshal(resp["result"], &updates); err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, update := range updates {
+		for _, field := range []string{"message", "edited_message", "channel_post", "edited_channel_post"} {
+			raw, ok := update[field]
+			if !ok {
+				continue
+			}
+			var msg map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &msg); err != nil || !legacyForwardFields(msg) {
+				continue
+			}
+			rewritten, err := json.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+			update[field] = rewritten
+			changed = true
+		}
+	}
+	if !changed {
+		return body, nil
+	}
+	result, err := json.Marshal(updates)
+	if err != nil {
+		return nil, err
+	}
+	resp["result"] = result
+	return json.Marshal(resp)
+}
+
+// legacyForwardFields sets the forward_* fields of msg from its
+// forward_origin and reports whether it did.
+func legacyForwardFields(msg map[string]json.RawMessage) bool {
+	raw, ok := msg["forward_origin"]
+	if _, legacy := msg["forward_date"]; !ok || legacy {
+		return false
+	}
+	var origin struct {
+		Type            string          `json:"type"`
+		Date            int64           `json:"date"`
+		SenderUser      json.RawMessage `json:"sender_user"`
+		SenderUserName  string          `json:"sender_user_name"`
+		SenderChat      json.RawMessage `json:"sender_chat"`
+		Chat            json.RawMessage `json:"chat"`
+		MessageID       int             `json:"message_id"`
+		AuthorSignature string          `json:"author_signature"`
+	}
+	if err := json.Unmarshal(raw, &origin); err != nil {
+		return false
+	}
+	set := func(field string, value any) {
+		msg[field], _ = json.Marshal(value)
+	}
+	switch origin.Type {
+	case forwardFromUser:
+		if len(origin.SenderUser) == 0 {
+			return false
+		}
+		msg["forward_from"] = origin.SenderUser
+	case forwardFromHiddenUser:
+		set("forward_sender_name", origin.SenderUserName)
+	case forwardFromChat:
+		if len(origin.SenderChat) == 0 {
+			return false
+		}
+		msg["forward_from_chat"] = origin.SenderChat
+	case forwardFromChannel:
+		if len(origin.Chat) == 0 {
+			return false
+		}
+		msg["forward_from_chat"] = origin.Chat
+		set("forward_from_message_id", origin.MessageID)
+	default:
+		return false
+	}
+	if origin.AuthorSignature != "" {
+		set("forward_signature", origin.AuthorSignature)
+	}
+	set("forward_date", origin.Date)
+	return true
+}
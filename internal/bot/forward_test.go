@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// decodeUpdate decodes message, the JSON of a message in a getUpdates
+// response, the way the bot does.
+func decodeUpdate(t *testing.T, message string) *tgbotapi.Message {
+	t.Helper()
+	body := []byte(`{"ok":true,"result":[{"update_id":1,"message":` + message + `}]}`)
+	rewritten, err := addLegacyForwardFields(body)
+	if err != nil {
+		t.Fatalf("addLegacyForwardFields: %v", err)
+	}
+	var resp struct {
+		Result []tgbotapi.Update `json:"result"`
+	}
+	if err := json.Unmarshal(rewritten, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Result[0].Message
+}
+
+func TestForwardOriginOf(t *testing.T) {
+	const base = `"message_id":7,"date":1700000000,"chat":{"id":1001,"type":"private"},"from":{"id":1001,"first_name":"User"},"text":"https://example.com/a"`
+	channel := forwardOrigin{Kind: forwardFromChannel, Name: "News", Username: "news"}
+	for _, tt := range []struct {
+		name    string
+		message string
+		want    forwardOrigin
+		forward bool
+	}{
+		{name: "not forwarded", message: `{` + base + `}`},
+		{
+			name:    "legacy channel",
+			message: `{` + base + `,"forward_date":1699999999,"forward_from_chat":{"id":-100,"type":"channel","title":"News","username":"news"},"forward_from_message_id":3}`,
+			want:    channel, forward: true,
+		},
+		{
+			name:    "legacy group",
+			message: `{` + base + `,"forward_date":1699999999,"forward_from_chat":{"id":-200,"type":"supergroup","title":"Chat"}}`,
+			want:    forwardOrigin{Kind: forwardFromChat, Name: "Chat"}, forward: true,
+		},
+		{
+			name:    "legacy user",
+			message: `{` + base + `,"forward_date":1699999999,"forward_from":{"id":5,"first_name":"Ada","last_name":"L","username":"ada"}}`,
+			want:    forwardOrigin{Kind: forwardFromUser, Name: "Ada L", Username: "ada"}, forward: true,
+		},
+		{
+			name:    "legacy hidden user",
+			message: `{` + base + `,"forward_date":1699999999,"forward_sender_name":"Someone"}`,
+			want:    forwardOrigin{Kind: forwardFromHiddenUser, Name: "Someone"}, forward: true,
+		},
+		{
+			name:    "origin channel",
+			message: `{` + base + `,"forward_origin":{"type":"channel","date":1699999999,"chat":{"id":-100,"type":"channel","title":"News","username":"news"},"message_id":3}}`,
+			want:    channel, forward: true,
+		},
+		{
+			name:    "origin private channel",
+			message: `{` + base + `,"forward_origin":{"type":"channel","date":1699999999,"chat":{"id":-100,"type":"channel","title":"Secret"},"message_id":3}}`,
+			want:    forwardOrigin{Kind: forwardFromChannel, Name: "Secret"}, forward: true,
+		},
+		{
+			name:    "origin chat",
+			message: `{` + base + `,"forward_origin":{"type":"chat","date":1699999999,"sender_chat":{"id":-200,"type":"supergroup","title":"Chat","username":"chat"}}}`,
+			want:    forwardOrigin{Kind: forwardFromChat, Name: "Chat", Username: "chat"}, forward: true,
+		},
+		{
+			name:    "origin user",
+			message: `{` + base + `,"forward_origin":{"type":"user","date":1699999999,"sender_user":{"id":5,"first_name":"Ada"}}}`,
+			want:    forwardOrigin{Kind: forwardFromUser, Name: "Ada"}, forward: true,
+		},
+		{
+			name:    "origin hidden user",
+			message: `{` + base + `,"forward_origin":{"type":"hidden_user","date":1699999999,"sender_user_name":"Someone"}}`,
+			want:    forwardOrigin{Kind: forwardFromHiddenUser, Name: "Someone"}, forward: true,
+		},
+		{
+			// Both shapes are sent during the transition; the legacy fields win
+			name:    "origin and legacy fields",
+			message: `{` + base + `,"forward_date":1699999999,"forward_from_chat":{"id":-100,"type":"channel","title":"News","username":"news"},"forward_origin":{"type":"hidden_user","date":1699999999,"sender_user_name":"Someone"}}`,
+			want:    channel, forward: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, forward := forwardOriginOf(decodeUpdate(t, tt.message))
+			if forward != tt.forward || got != tt.want {
+				t.Errorf("forwardOriginOf = %+v, %v; want %+v, %v", got, forward, tt.want, tt.forward)
+			}
+		})
+	}
+}
+
+func TestForwardTitle(t *testing.T) {
+	channel := forwardOrigin{Kind: forwardFromChannel, Name: "News", Username: "news"}
+	const url = "https://example.com/a"
+	for _, tt := range []struct {
+		name   string
+		origin forwardOrigin
+		msg    tgbotapi.Message
+		want   string
+	}{
+		{name: "first line", origin: channel, msg: tgbotapi.Message{Text: "Big news\nmore at " + url}, want: "News: Big news"},
+		{name: "URL line skipped", origin: channel, msg: tgbotapi.Message{Text: "\n" + url + "\n  Big news  "}, want: "News: Big news"},
+		{name: "caption", origin: channel, msg: tgbotapi.Message{Caption: "Photo of the day " + url}, want: "News: Photo of the day " + url},
+		{name: "only the URL", origin: channel, msg: tgbotapi.Message{Text: url}, want: "News"},
+		{name: "truncated", origin: channel, msg: tgbotapi.Message{Text: strings.Repeat("é", 200)}, want: "News: " + strings.Repeat("é", maxForwardTitleLen-7) + "…"},
+		{name: "private channel", origin: forwardOrigin{Kind: forwardFromChannel, Name: "Secret"}, msg: tgbotapi.Message{Text: "Big news"}},
+		{name: "user", origin: forwardOrigin{Kind: forwardFromUser, Name: "Ada", Username: "ada"}, msg: tgbotapi.Message{Text: "Big news"}},
+	} {
+		if got := forwardTitle(tt.origin, &tt.msg, url); got != tt.want {
+			t.Errorf("%s: forwardTitle = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// sendForward enqueues text forwarded by user from chat.
+func (e *e2e) sendForward(text string, from *tgbotapi.Chat) {
+	e.tg.Enqueue(tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID:       int(time.Now().UnixNano() % 1e6),
+		From:            &tgbotapi.User{ID: user, FirstName: "User", LanguageCode: "en"},
+		Chat:            &tgbotapi.Chat{ID: user, Type: "private"},
+		Date:            int(time.Now().Unix()),
+		Text:            text,
+		ForwardFromChat: from,
+		ForwardDate:     int(time.Now().Unix()),
+	}})
+}
+
+func TestE2EForwardFromPublicChannel(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.sendForward("Big news\nhttps://example.com/a", &tgbotapi.Chat{ID: -100, Type: "channel", Title: "News", UserName: "news"})
+	preview := e.tg.WaitText(user, "Suggested title: News: Big news")
+	if e.created() != 0 {
+		t.Fatal("link created before the title was confirmed")
+	}
+	e.press(t, preview, "Shorten with title")
+	e.tg.WaitText(user, "Link created successfully")
+	if req := e.lastCreated(t); req.GetOriginalUrl() != "https://example.com/a" || req.GetTitle() != "News: Big news" {
+		t.Errorf("created %q titled %q", req.GetOriginalUrl(), req.GetTitle())
+	}
+}
+
+func TestE2EForwardWithoutTitle(t *testing.T) {
+	e := startBot(t, nil)
+
+	// Posts of private channels are shortened right away
+	e.sendForward("Big news https://example.com/a", &tgbotapi.Chat{ID: -100, Type: "channel", Title: "Secret"})
+	e.tg.WaitText(user, "Link created successfully")
+	if req := e.lastCreated(t); req.GetOriginalUrl() != "https://example.com/a" || req.GetTitle() != "" {
+		t.Errorf("created %q titled %q", req.GetOriginalUrl(), req.GetTitle())
+	}
+
+	e.sendForward("No links here", &tgbotapi.Chat{ID: -100, Type: "channel", Title: "News", UserName: "news"})
+	e.tg.WaitText(user, "The forwarded message has no links to shorten.")
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/monitor"
+	"GURLS-Bot/internal/ttlmap"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// healthBadgesTTL is how long the destination health of a user's
+	// monitored links is reused for link lists.
+	healthBadgesTTL = time.Minute
+	// checkNowRateLimit caps the destination checks a user asks for, which
+	// hit the sites checked.
+	checkNowRateLimit       = 5
+	checkNowRateLimitWindow = time.Minute
+)
+
+// healthBadges caches the destination health of the links users monitor,
+// so that rendering a page of links reads the monitor store once.
+type healthBadges struct {
+	owners *ttlmap.Map[int64, map[string]monitor.Health]
+}
+
+func newHealthBadges(ttl time.Duration) *healthBadges {
+	return &healthBadges{owners: ttlmap.New[int64, map[string]monitor.Health](ttl, 0)}
+}
+
+// Get returns the health of the links ownerID monitors, loading it when
+// missing or stale.
+func (h *healthBadges) Get(ownerID int64, load func() map[string]monitor.Health) map[string]monitor.Health {
+	if health, ok := h.owners.Get(ownerID); ok {
+		return health
+	}
+	health := load()
+	h.owners.Set(ownerID, health)
+	return health
+}
+
+// Forget drops the health cached for ownerID, so a check shows up right
+// away.
+func (h *healthBadges) Forget(ownerID int64) {
+	h.owners.Delete(ownerID)
+}
+
+// linkHealth returns the badge of alias in the link lists of chatID: the
+// health of its destination, or "" when it isn't monitored.
+func (b *Bot) linkHealth(chatID int64, alias string) monitor.Health {
+	if !b.supports(featureMonitor) {
+		return ""
+	}
+	return b.healthBadges.Get(chatID, func() map[string]monitor.Health {
+		return b.monitors.HealthOf(chatID)
+	})[alias]
+}
+
+// checkNow checks the destination of a monitored link right away and shows
+// the outcome as a toast.
+func (b *Bot) checkNow(ctx context.Context, r *Request) error {
+	alias := r.Args
+	entry, ok := b.monitors.Get(alias)
+	if !ok || entry.OwnerID != r.ChatID {
+		r.Answer.alert(b.render(msgNotMonitored, nil))
+		return nil
+	}
+	b.checkDestination(ctx, b.monitorClient(), entry)
+	if err := b.monitors.Save(); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	checked, ok := b.monitors.Get(alias)
+	switch {
+	case !ok:
+		// Deleted on the backend since
+		r.Answer.alert(b.render(msgLinkNotFound, aliasData{Alias: alias}))
+	case !checked.LastCheck.After(entry.LastCheck):
+		// The backend couldn't be asked for the current destination
+		r.Answer.alert(b.render(msgServiceUnavailable, nil))
+	default:
+		r.Answer.toast(b.render(msgDestinationChecked, checkData{Health: checked.Health(), Status: checked.LastStatus}))
+	}
+	return nil
+}
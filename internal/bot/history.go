@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Actions kept in the activity history.
+const (
+	historyCreated  = "created"
+	historyDeleted  = "deleted"
+	historyRenamed  = "renamed"
+	historySettings = "settings"
+	// historyTransferred and historyReceived are the two sides of a link
+	// transfer, with the other user as Peer.
+	historyTransferred = "transferred"
+	historyReceived    = "received"
+)
+
+// historyPageSize is how many history entries a /history page shows.
+const historyPageSize = 10
+
+// historyStatsPerRow is how many Stats buttons share a keyboard row.
+const historyStatsPerRow = 3
+
+// appendHistory adds entry to the history in p, dropping the oldest entries
+// beyond the configured size.
+func (b *Bot) appendHistory(p *prefs.Prefs, entry prefs.HistoryEntry) {
+	size := b.config.Prefs.HistorySize
+	if size == 0 {
+		return
+	}
+	entry.At = time.Now()
+	p.History = append(p.History, entry)
+	if extra := len(p.History) - size; extra > 0 {
+		p.History = slices.Delete(p.History, 0, extra)
+	}
+}
+
+// recordHistory adds entry to the history of userID. The history is best
+// effort; failing to save it doesn't fail the action.
+func (b *Bot) recordHistory(userID int64, entry prefs.HistoryEntry) {
+	if b.config.Prefs.HistorySize == 0 {
+		return
+	}
+	err := b.prefs.Update(userID, func(p *prefs.Prefs) error {
+		b.appendHistory(p, entry)
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+	}
+}
+
+// historyAliases maps the aliases in history to their alias after later
+// renames.
+func historyAliases(history []prefs.HistoryEntry) func(alias string) string {
+	renamed := make(map[string]string)
+	for _, entry := range history {
+		if entry.Action == historyRenamed {
+			renamed[entry.Alias] = entry.NewAlias
+		}
+	}
+	return func(alias string) string {
+		// Bounded, as aliases can be renamed back and forth
+		for range len(renamed) {
+			next, ok := renamed[alias]
+			if !ok {
+				break
+			}
+			alias = next
+		}
+		return alias
+	}
+}
+
+func (b *Bot) handleHistoryCommand(ctx context.Context, to payloadKey) error {
+	text, keyboard := b.buildHistory(ctx, to, 0)
+	return b.sendMessageWithKeyboard(to.chatID, text, keyboard)
+}
+
+// showHistoryPage replaces the history message with another page.
+func (b *Bot) showHistoryPage(ctx context.Context, r *Request) error {
+	page, err := strconv.Atoi(r.Args)
+	if err != nil || page < 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	text, keyboard := b.buildHistory(ctx, r.Payloads(), page)
+	return b.editMessageWithKeyboard(r.ChatID, r.Message.MessageID, text, keyboard)
+}
+
+// buildHistory renders a page of the history of to.chatID, newest first. Links
+// that no longer exist are marked deleted, the others get a Stats button.
+// When the links can't be listed, neither is shown.
+func (b *Bot) buildHistory(ctx context.Context, to payloadKey, page int) (string, tgbotapi.InlineKeyboardMarkup) {
+	history := b.prefs.Get(to.chatID).History
+	if len(history) == 0 {
+		return b.render(msgNoHistory, nil), tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Main Menu", callbackHelp),
+		))
+	}
+
+	current := historyAliases(history)
+	var exists map[string]bool
+	res, err := b.listUserLinks(ctx, to.chatID)
+	if err != nil {
+		b.log.Warn("gRPC ListUserLinks failed, showing history without link state", zap.Error(err))
+	} else {
+		exists = make(map[string]bool, len(res.Links))
+		for _, link := range res.Links {
+			exists[link.Alias] = true
+		}
+	}
+
+	pages := (len(history) + historyPageSize - 1) / historyPageSize
+	page = min(page, pages-1)
+	newest := slices.Clone(history)
+	slices.Reverse(newest)
+	start := page * historyPageSize
+	entries := newest[start:min(start+historyPageSize, len(newest))]
+
+	data := historyData{Locale: b.formatterFor(to.chatID), Page: page + 1, Pages: pages}
+	var stats []tgbotapi.InlineKeyboardButton
+	for i, entry := range entries {
+		item := historyEntryData{
+			Number:   start + i + 1,
+			At:       entry.At,
+			Action:   entry.Action,
+			Alias:    entry.Alias,
+			NewAlias: entry.NewAlias,
+			Setting:  entry.Setting,
+			Peer:     entry.Peer,
+		}
+		if entry.Alias != "" && exists != nil {
+			alias := current(entry.Alias)
+			if exists[alias] {
+				stats = append(stats, b.payloadButton(to, "Stats #"+strconv.Itoa(item.Number), actionStats, alias))
+			} else if entry.Action != historyDeleted && entry.Action != historyTransferred {
+				item.Deleted = true
+			}
+		}
+		data.Entries = append(data.Entries, item)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for chunk := range slices.Chunk(stats, historyStatsPerRow) {
+		rows = append(rows, chunk)
+	}
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, b.payloadButton(to, "« Newer", actionHistoryPage, strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		nav = append(nav, b.payloadButton(to, "Older »", actionHistoryPage, strconv.Itoa(page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Clear history", callbackClearHistory),
+		b.callbackButton("Main Menu", callbackHelp),
+	))
+	return b.render(msgHistory, data), tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// Create keyboard confirming Clear history
+func (b *Bot) createClearHistoryKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Clear history", callbackClearHistoryConfirm),
+			b.callbackButton("Cancel", callbackCancel),
+		),
+	)
+}
+
+// clearHistory deletes the history of chatID from the store, like /forget_me
+// does with all of the user's data, and replaces the confirmation.
+func (b *Bot) clearHistory(r *Request) error {
+	err := b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		p.History = nil
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgHistoryCleared, nil))
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/ttlmap"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxInlineResults is how many links one inline answer holds; the rest
+	// are fetched by Telegram through next_offset.
+	maxInlineResults = 20
+	// inlineLinksTTL is how long a user's link list is reused for inline
+	// queries, which arrive with every keystroke.
+	inlineLinksTTL = time.Minute
+	// startShortenPrefix marks a /start parameter carrying a payload token
+	// of a URL typed in inline mode.
+	startShortenPrefix = "shorten_"
+)
+
+// linkLists caches the link lists of users for a short while.
+type linkLists struct {
+	lists *ttlmap.Map[int64, []*shortenerv1.LinkInfo]
+}
+
+func newLinkLists(ttl time.Duration) *linkLists {
+	return &linkLists{lists: ttlmap.New[int64, []*shortenerv1.LinkInfo](ttl, 0)}
+}
+
+// Get returns the list cached for userID, fetching it when missing or stale.
+func (l *linkLists) Get(userID int64, fetch func() ([]*shortenerv1.LinkInfo, error)) ([]*shortenerv1.LinkInfo, error) {
+	if links, ok := l.lists.Get(userID); ok {
+		return links, nil
+	}
+	links, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	l.lists.Set(userID, links)
+	return links, nil
+}
+
+// Forget drops the list cached for userID, so a change shows up right away.
+func (l *linkLists) Forget(userID int64) {
+	l.lists.Delete(userID)
+}
+
+// handleInlineQuery answers "@bot <query>". A URL is offered for shortening
+// in the bot, anything else searches the user's links. Queries go
+// unanswered while inline mode is turned off.
+func (b *Bot) handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) error {
+	if !b.supports(featureInline) {
+		return nil
+	}
+	query := strings.TrimSpace(q.Query)
+	answer := tgbotapi.InlineConfig{InlineQueryID: q.ID, IsPersonal: true}
+
+	if url := urlRegex.FindString(query); url != "" && url == query {
+		answer.SwitchPMText = b.render(msgInlineShorten, nil)
+		answer.SwitchPMParameter = startShortenPrefix + b.payloads.Put(chatPayloads(q.From.ID), url)
+		return b.answerInlineQuery(answer)
+	}
+
+	links, err := b.linkLists.Get(q.From.ID, func() ([]*shortenerv1.LinkInfo, error) {
+		res, err := b.listUserLinks(ctx, q.From.ID)
+		return res.GetLinks(), err
+	})
+	if err != nil {
+		b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+		return b.answerInlineQuery(answer)
+	}
+
+	matches := searchLinks(links, query)
+	offset, _ := strconv.Atoi(q.Offset)
+	offset = min(max(offset, 0), len(matches))
+	end := min(offset+maxInlineResults, len(matches))
+	if end < len(matches) {
+		answer.NextOffset = strconv.Itoa(end)
+	}
+	for _, link := range matches[offset:end] {
+		shortURL := b.shortURLOn(link.GetDomain(), link.GetAlias())
+		title := displayTitle(link.GetTitle())
+		if title == "" {
+			title = displayURL(link.GetOriginalUrl())
+		}
+		result := tgbotapi.NewInlineQueryResultArticle(link.GetAlias(), title, shortURL)
+		result.Description = displayURL(shortURL)
+		answer.Results = append(answer.Results, result)
+	}
+	if len(matches) == 0 {
+		answer.SwitchPMText = b.render(msgInlineNoLinks, nil)
+		answer.SwitchPMParameter = "start"
+	}
+	return b.answerInlineQuery(answer)
+}
+
+// searchLinks returns the links whose alias, title or URL contains query,
+// ignoring case. An empty query matches every link.
+func searchLinks(links []*shortenerv1.LinkInfo, query string) []*shortenerv1.LinkInfo {
+	query = strings.ToLower(query)
+	var matches []*shortenerv1.LinkInfo
+	for _, link := range links {
+		for _, field := range []string{link.GetAlias(), link.GetTitle(), link.GetOriginalUrl()} {
+			if strings.Contains(strings.ToLower(field), query) {
+				matches = append(matches, link)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// answerInlineQuery sends answer. tgbotapi leaves out a zero cache_time,
+// which Telegram takes as its five-minute default, so the request is built
+// by hand to keep results fresh.
+func (b *Bot) answerInlineQuery(answer tgbotapi.InlineConfig) error {
+	if answer.Results == nil {
+		answer.Results = []any{}
+	}
+	results, err := json.Marshal(answer.Results)
+	if err != nil {
+		return err
+	}
+	params := tgbotapi.Params{
+		"inline_query_id": answer.InlineQueryID,
+		"cache_time":      strconv.Itoa(answer.CacheTime),
+		"results":         string(results),
+	}
+	params.AddBool("is_personal", answer.IsPersonal)
+	params.AddNonEmpty("next_offset", answer.NextOffset)
+	params.AddNonEmpty("switch_pm_text", answer.SwitchPMText)
+	params.AddNonEmpty("switch_pm_parameter", answer.SwitchPMParameter)
+	_, err = b.api.MakeRequest("answerInlineQuery", params)
+	return err
+}
+
+// startShortening shortens the URL a /start parameter from inline mode
+// refers to. It reports false when the parameter is not such a reference or
+// has expired.
+func (b *Bot) startShortening(ctx context.Context, r *Request) (bool, error) {
+	token, ok := strings.CutPrefix(r.Args, startShortenPrefix)
+	if !ok {
+		return false, nil
+	}
+	url, ok := b.payloads.Get(chatPayloads(r.UserID), token)
+	if !ok {
+		return false, nil
+	}
+	_, err := b.shorten(ctx, r.Payloads(), url)
+	return true, err
+}
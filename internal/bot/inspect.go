@@ -0,0 +1,182 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/audit"
+	"GURLS-Bot/internal/grpc/client"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// inspectAction is an admin action on an inspected link, carried by the
+// buttons of /inspect and of its confirmation.
+type inspectAction struct {
+	// Action is audit.Disable or audit.Delete.
+	Action  string `json:"a"`
+	Alias   string `json:"l"`
+	OwnerID int64  `json:"o,omitempty"`
+	Reason  string `json:"r"`
+}
+
+// handleInspectCommand shows any link to an admin handling an abuse report,
+// with Disable and Delete actions. The reason is required, as every use is
+// written to the audit log.
+func (b *Bot) handleInspectCommand(ctx context.Context, req *Request) error {
+	arg, reason, _ := strings.Cut(strings.TrimSpace(req.Args), " ")
+	reason = strings.TrimSpace(reason)
+	if arg == "" || reason == "" {
+		return b.reply(req.ChatID, msgInspectUsage, nil)
+	}
+	alias, err := b.resolveAlias(arg)
+	if err != nil {
+		return b.replyAliasError(req.ChatID, err, "inspect")
+	}
+	return b.inspectLink(ctx, req.ChatID, req.UserID, alias, reason)
+}
+
+// inspectLink shows alias to the admin adminID in chatID, once the
+// inspection is in the audit log.
+func (b *Bot) inspectLink(ctx context.Context, chatID, adminID int64, alias, reason string) error {
+	if !b.recordAudit(adminID, audit.Inspect, alias, reason) {
+		return b.reply(chatID, msgAuditFailed, nil)
+	}
+
+	stats, err := b.grpcClient.GetLinkStats(client.AsAdmin(ctx, adminID), &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(chatID, err, alias)
+	}
+	data := inspectData{
+		Locale:   b.formatterFor(chatID),
+		ShortURL: displayURL(b.shortURLOn(stats.GetDomain(), alias)),
+		URL:      stats.GetOriginalUrl(),
+		Title:    displayTitle(stats.GetTitle()),
+		OwnerID:  stats.GetOwnerTgId(),
+		Clicks:   stats.GetClickCount(),
+		Reason:   reason,
+	}
+	if owner, ok := b.users.Get(data.OwnerID); ok {
+		data.Owner = peerLabel(owner)
+	}
+	if stats.CreatedAt != nil {
+		created := stats.CreatedAt.AsTime()
+		data.CreatedAt = &created
+	}
+	if stats.ExpiresAt != nil {
+		expires := stats.ExpiresAt.AsTime()
+		data.ExpiresAt = &expires
+	}
+
+	disable, err := json.Marshal(inspectAction{Action: audit.Disable, Alias: alias, OwnerID: data.OwnerID, Reason: reason})
+	if err != nil {
+		return err
+	}
+	del, err := json.Marshal(inspectAction{Action: audit.Delete, Alias: alias, OwnerID: data.OwnerID, Reason: reason})
+	if err != nil {
+		return err
+	}
+	msg := tgbotapi.NewMessage(chatID, b.render(msgInspectLink, data))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.storedPayloadButton(payloadKey{chatID: chatID, userID: adminID}, "Disable", actionInspectAsk, string(disable)),
+		b.storedPayloadButton(payloadKey{chatID: chatID, userID: adminID}, "Delete", actionInspectAsk, string(del)),
+	))
+	msg.DisableWebPagePreview = true
+	_, err = b.send(msg)
+	return err
+}
+
+// decodeInspectAction decodes the payload of an /inspect button, alerting
+// when it expired.
+func (b *Bot) decodeInspectAction(r *Request) (inspectAction, bool) {
+	var action inspectAction
+	if err := json.Unmarshal([]byte(r.Args), &action); err != nil || (action.Action != audit.Disable && action.Action != audit.Delete) {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return inspectAction{}, false
+	}
+	return action, true
+}
+
+// confirmInspectAction asks the admin to confirm disabling or deleting an
+// inspected link.
+func (b *Bot) confirmInspectAction(r *Request) error {
+	action, ok := b.decodeInspectAction(r)
+	if !ok {
+		return nil
+	}
+	name, label := msgInspectConfirmDisable, "Yes, disable"
+	if action.Action == audit.Delete {
+		name, label = msgInspectConfirmDelete, "Yes, delete"
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.storedPayloadButton(r.Payloads(), label, actionInspectDo, r.Args),
+		b.callbackButton("Cancel", callbackInspectCancel),
+	))
+	data := linkData{ShortURL: displayURL(b.shortURL(action.Alias))}
+	return b.sendMessageWithKeyboard(r.ChatID, b.render(name, data), keyboard)
+}
+
+// runInspectAction disables or deletes an inspected link once confirmed,
+// acting for the admin regardless of the owner, and reports the outcome in
+// place of the confirmation.
+func (b *Bot) runInspectAction(ctx context.Context, r *Request) error {
+	action, ok := b.decodeInspectAction(r)
+	if !ok {
+		return nil
+	}
+	if !b.recordAudit(r.UserID, action.Action, action.Alias, action.Reason) {
+		r.Answer.alert(b.render(msgAuditFailed, nil))
+		return nil
+	}
+
+	ctx = client.AsAdmin(ctx, r.UserID)
+	var err error
+	if action.Action == audit.Disable {
+		_, err = b.grpcClient.SetLinkExpiry(ctx, &shortenerv1.SetLinkExpiryRequest{
+			Alias:     action.Alias,
+			UserTgId:  action.OwnerID,
+			ExpiresAt: timestamppb.Now(),
+		})
+	} else {
+		err = b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: action.Alias})
+	}
+	if err != nil {
+		b.log.Error("admin link action failed", zap.Error(err), zap.String("action", action.Action), zap.String("alias", action.Alias))
+		r.Answer.alert(b.mapGRPCError(err, action.Alias))
+		return nil
+	}
+
+	if err := b.monitors.Remove(action.Alias); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	text := b.render(msgLinkDisabled, linkData{ShortURL: displayURL(b.shortURL(action.Alias))})
+	if action.Action == audit.Delete {
+		if action.OwnerID != 0 {
+			b.unpin(action.OwnerID, action.Alias)
+		}
+		b.staleKeyboards(action.Alias)
+		text = b.render(msgLinkDeleted, aliasData{Alias: action.Alias})
+	}
+	return b.editMessageText(r.ChatID, r.Message.MessageID, text)
+}
+
+// recordAudit writes an admin action to the audit log and reports whether
+// it was written; actions that can't be recorded must not be carried out.
+func (b *Bot) recordAudit(adminID int64, action, alias, reason string) bool {
+	entry := audit.Entry{At: time.Now(), AdminID: adminID, Action: action, Alias: alias, Reason: reason}
+	if err := b.audit.Record(entry); err != nil {
+		b.log.Error("failed to write audit log", zap.Error(err), zap.String("action", action), zap.String("alias", alias))
+		return false
+	}
+	b.log.Info("admin action",
+		zap.String("action", action),
+		zap.String("alias", alias),
+		zap.Int64("admin_id", adminID),
+		zap.String("reason", reason))
+	return true
+}
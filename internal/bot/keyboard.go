@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Limits of an inline keyboard. Telegram rejects larger markup and the
+// whole message fails to send.
+const (
+	maxKeyboardButtons    = 100
+	maxKeyboardRowButtons = 8
+	// maxKeyboardCallbackBytes bounds the callback data of all buttons
+	// together, keeping the serialized markup well below the request size
+	// Telegram accepts.
+	maxKeyboardCallbackBytes = 4096
+)
+
+// keyboardSize counts what the keyboard limits apply to.
+type keyboardSize struct {
+	Rows          int
+	Buttons       int
+	WidestRow     int
+	CallbackBytes int
+}
+
+func measureKeyboard(rows [][]tgbotapi.InlineKeyboardButton) keyboardSize {
+	size := keyboardSize{Rows: len(rows)}
+	for _, row := range rows {
+		size.Buttons += len(row)
+		size.WidestRow = max(size.WidestRow, len(row))
+		for _, button := range row {
+			if button.CallbackData != nil {
+				size.CallbackBytes += len(*button.CallbackData)
+			}
+		}
+	}
+	return size
+}
+
+// fits reports whether Telegram accepts a keyboard of this size.
+func (s keyboardSize) fits() bool {
+	return s.Buttons <= maxKeyboardButtons &&
+		s.WidestRow <= maxKeyboardRowButtons &&
+		s.CallbackBytes <= maxKeyboardCallbackBytes
+}
+
+func (s keyboardSize) fields() []zap.Field {
+	return []zap.Field{
+		zap.Int("rows", s.Rows),
+		zap.Int("buttons", s.Buttons),
+		zap.Int("widest_row", s.WidestRow),
+		zap.Int("callback_bytes", s.CallbackBytes),
+	}
+}
+
+// fitKeyboard builds a keyboard from per-item rows followed by the nav rows,
+// which are always kept. layouts are alternative item rows from the fullest
+// to the most compact; the first that fits is used. When none fits, items of
+// the last layout are dropped from the end. name identifies the keyboard in
+// the warning logged whenever it had to be reduced.
+func (b *Bot) fitKeyboard(name string, nav [][]tgbotapi.InlineKeyboardButton, layouts ...[][]tgbotapi.InlineKeyboardButton) tgbotapi.InlineKeyboardMarkup {
+	build := func(items [][]tgbotapi.InlineKeyboardButton) [][]tgbotapi.InlineKeyboardButton {
+		rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(items)+len(nav))
+		return append(append(rows, items...), nav...)
+	}
+
+	full := measureKeyboard(build(layouts[0]))
+	for i, items := range layouts {
+		rows := build(items)
+		if size := measureKeyboard(rows); size.fits() {
+			if i > 0 {
+				b.log.Warn("keyboard too large, using compact layout",
+					append(full.fields(), zap.String("keyboard", name), zap.Int("layout", i))...)
+			}
+			return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+		}
+	}
+
+	items := layouts[len(layouts)-1]
+	for len(items) > 0 && !measureKeyboard(build(items)).fits() {
+		items = items[:len(items)-1]
+	}
+	b.log.Warn("keyboard too large, dropping items",
+		append(full.fields(), zap.String("keyboard", name), zap.Int("items_kept", len(items)))...)
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: build(items)}
+}
+
+// shareURL opens Telegram's share dialog with the text filled in, the
+// plain stand-in for inline query buttons.
+const shareURL = "https://t.me/share/url?url="
+
+// basicButton returns a plain URL button standing in for button when it is
+// of a type some old clients can't show, and whether it was:
+//
+//   - a login URL button opens its page, without the automatic login
+//   - an inline query button, for any chat or the current one, opens the
+//     share dialog with the query, to pick the chat there
+//
+// Callback and URL buttons are plain already. Game and payment buttons,
+// which the bot never sends, have no stand-in.
+func basicButton(button tgbotapi.InlineKeyboardButton) (tgbotapi.InlineKeyboardButton, bool) {
+	switch {
+	case button.LoginURL != nil:
+		return tgbotapi.NewInlineKeyboardButtonURL(button.Text, button.LoginURL.URL), true
+	case button.SwitchInlineQuery != nil:
+		return tgbotapi.NewInlineKeyboardButtonURL(button.Text, shareURL+url.QueryEscape(*button.SwitchInlineQuery)), true
+	case button.SwitchInlineQueryCurrentChat != nil:
+		return tgbotapi.NewInlineKeyboardButtonURL(button.Text, shareURL+url.QueryEscape(*button.SwitchInlineQueryCurrentChat)), true
+	}
+	return button, false
+}
+
+// basicKeyboard returns keyboard with its advanced buttons replaced by
+// plain ones, and whether there were any.
+func basicKeyboard(keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.InlineKeyboardMarkup, bool) {
+	changed := false
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(keyboard.InlineKeyboard))
+	for i, row := range keyboard.InlineKeyboard {
+		rows[i] = make([]tgbotapi.InlineKeyboardButton, len(row))
+		for j, button := range row {
+			basic, ok := basicButton(button)
+			rows[i][j] = basic
+			changed = changed || ok
+		}
+	}
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}, changed
+}
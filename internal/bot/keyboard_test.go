@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/backendtest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// buttonRows returns n rows of width buttons labelled text whose callback
+// data is dataLen bytes long.
+func buttonRows(text string, n, width, dataLen int) [][]tgbotapi.InlineKeyboardButton {
+	rows := make([][]tgbotapi.InlineKeyboardButton, n)
+	for i := range rows {
+		for j := range width {
+			data := strconv.Itoa(i) + "." + strconv.Itoa(j)
+			data += strings.Repeat("x", max(dataLen-len(data), 0))
+			rows[i] = append(rows[i], tgbotapi.NewInlineKeyboardButtonData(text, data))
+		}
+	}
+	return rows
+}
+
+func TestFitKeyboard(t *testing.T) {
+	nav := buttonRows("nav", 1, 2, 1)
+	for _, tt := range []struct {
+		name  string
+		items int
+		// width and dataLen are those of the full layout; the compact one
+		// has a button of 1 byte per item
+		width, dataLen int
+		// wantRows is the number of item rows kept
+		wantRows    int
+		wantCompact bool
+		wantLog     string
+	}{
+		{name: "at button limit", items: 49, width: 2, dataLen: 1, wantRows: 49},
+		{name: "over button limit", items: 50, width: 2, dataLen: 1, wantRows: 50, wantCompact: true, wantLog: "keyboard too large, using compact layout"},
+		{name: "compact at button limit", items: 98, width: 2, dataLen: 1, wantRows: 98, wantCompact: true, wantLog: "keyboard too large, using compact layout"},
+		{name: "compact over button limit", items: 99, width: 2, dataLen: 1, wantRows: 98, wantCompact: true, wantLog: "keyboard too large, dropping items"},
+		{name: "at row width limit", items: 1, width: 8, dataLen: 1, wantRows: 1},
+		{name: "over row width limit", items: 1, width: 9, dataLen: 1, wantRows: 1, wantCompact: true, wantLog: "keyboard too large, using compact layout"},
+		// 6 nav bytes leave 4090 bytes, 2 items of 2045
+		{name: "at callback limit", items: 2, width: 1, dataLen: 2045, wantRows: 2},
+		{name: "over callback limit", items: 2, width: 1, dataLen: 2046, wantRows: 2, wantCompact: true, wantLog: "keyboard too large, using compact layout"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			b := &Bot{log: zap.New(core)}
+
+			keyboard := b.fitKeyboard("test", nav, buttonRows("full", tt.items, tt.width, tt.dataLen), buttonRows("compact", tt.items, 1, 1))
+			rows := keyboard.InlineKeyboard
+			if got := len(rows) - len(nav); got != tt.wantRows {
+				t.Errorf("%d item rows kept, want %d", got, tt.wantRows)
+			}
+			if size := measureKeyboard(rows); !size.fits() {
+				t.Errorf("keyboard of %+v doesn't fit", size)
+			}
+			if compact := rows[0][0].Text == "compact"; compact != tt.wantCompact {
+				t.Errorf("compact layout = %v, want %v", compact, tt.wantCompact)
+			}
+			if last := rows[len(rows)-1]; last[0].Text != "nav" {
+				t.Errorf("nav row lost, last row %v", last)
+			}
+			switch {
+			case tt.wantLog == "" && logs.Len() != 0:
+				t.Errorf("fitting keyboard logged %v", logs.All())
+			case tt.wantLog != "" && logs.FilterMessage(tt.wantLog).Len() != 1:
+				t.Errorf("logs = %v, want %q", logs.All(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestBasicButton(t *testing.T) {
+	query := "Check abc: https://short.test/abc"
+	callback := tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc")
+	link := tgbotapi.NewInlineKeyboardButtonURL("Open", "https://example.com/")
+	game := tgbotapi.InlineKeyboardButton{Text: "Play", CallbackGame: &tgbotapi.CallbackGame{}}
+	pay := tgbotapi.InlineKeyboardButton{Text: "Pay", Pay: true}
+	for _, tt := range []struct {
+		name    string
+		button  tgbotapi.InlineKeyboardButton
+		want    tgbotapi.InlineKeyboardButton
+		changed bool
+	}{
+		{
+			name:    "login URL",
+			button:  tgbotapi.NewInlineKeyboardButtonLoginURL("Sign in", tgbotapi.LoginURL{URL: "https://example.com/login", RequestWriteAccess: true}),
+			want:    tgbotapi.NewInlineKeyboardButtonURL("Sign in", "https://example.com/login"),
+			changed: true,
+		},
+		{
+			name:    "inline query",
+			button:  tgbotapi.NewInlineKeyboardButtonSwitch("Share", query),
+			want:    tgbotapi.NewInlineKeyboardButtonURL("Share", shareURL+"Check+abc%3A+https%3A%2F%2Fshort.test%2Fabc"),
+			changed: true,
+		},
+		{
+			name:    "inline query in the current chat",
+			button:  tgbotapi.InlineKeyboardButton{Text: "Share here", SwitchInlineQueryCurrentChat: &query},
+			want:    tgbotapi.NewInlineKeyboardButtonURL("Share here", shareURL+"Check+abc%3A+https%3A%2F%2Fshort.test%2Fabc"),
+			changed: true,
+		},
+		{name: "callback", button: callback, want: callback},
+		{name: "URL", button: link, want: link},
+		// Without a stand-in these are left for Telegram to refuse
+		{name: "game", button: game, want: game},
+		{name: "payment", button: pay, want: pay},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := basicButton(tt.button)
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("basicButton() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBasicKeyboard(t *testing.T) {
+	share := tgbotapi.NewInlineKeyboardButtonSwitch("Share", "abc")
+	plain := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc"), tgbotapi.NewInlineKeyboardButtonURL("Open", "https://example.com/")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Back", "back")),
+	)
+	if got, changed := basicKeyboard(plain); changed || !reflect.DeepEqual(got, plain) {
+		t.Errorf("basicKeyboard of plain buttons = %+v, %v; want it unchanged", got, changed)
+	}
+
+	advanced := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc"), share),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Back", "back")),
+	)
+	got, changed := basicKeyboard(advanced)
+	if !changed {
+		t.Error("basicKeyboard of an inline query button reported nothing changed")
+	}
+	want := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc"), tgbotapi.NewInlineKeyboardButtonURL("Share", shareURL+"abc")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Back", "back")),
+	)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("basicKeyboard() = %+v, want %+v", got, want)
+	}
+	// The layout passed in is left as it was, as send retries with it
+	if advanced.InlineKeyboard[0][1].SwitchInlineQuery == nil {
+		t.Error("basicKeyboard changed the keyboard passed in")
+	}
+}
+
+func TestE2EMyLinksCompactKeyboard(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Links.PageSize = 60
+	e := startBot(t, cfg)
+	for i := range 60 {
+		e.backend.Add(backendtest.Link{Alias: "l" + strconv.Itoa(i), OriginalURL: "https://example.com/" + strconv.Itoa(i), OwnerID: user})
+	}
+
+	e.tg.SendMessage(user, "/my_links")
+	list := e.tg.WaitText(user, "Your Links")
+	if n := len(list.Buttons()); n > maxKeyboardButtons {
+		t.Fatalf("my_links sent %d buttons", n)
+	}
+	if _, ok := list.Button("Manage #60"); !ok {
+		t.Fatalf("no compact Manage button for the last link: %v", list.Buttons())
+	}
+	e.press(t, list, "Manage #1")
+	e.tg.WaitText(user, "Link Statistics: l0")
+}
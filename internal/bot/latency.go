@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/client"
+	"GURLS-Bot/internal/metrics"
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// handlerTimings add up the time a handler spent waiting on the backend and
+// on Telegram.
+type handlerTimings struct {
+	backend       atomic.Int64
+	backendCalls  atomic.Int64
+	telegram      atomic.Int64
+	telegramCalls atomic.Int64
+}
+
+// addBackend adds a backend call; it is a no-op on nil timings.
+func (t *handlerTimings) addBackend(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.backend.Add(int64(d))
+	t.backendCalls.Add(1)
+}
+
+func (t *handlerTimings) addTelegram(d time.Duration) {
+	t.telegram.Add(int64(d))
+	t.telegramCalls.Add(1)
+}
+
+type timingsKeyType struct{}
+
+// timingsFrom returns the timings of the handler ctx belongs to, if any.
+func timingsFrom(ctx context.Context) *handlerTimings {
+	t, _ := ctx.Value(timingsKeyType{}).(*handlerTimings)
+	return t
+}
+
+// activeTimings tracks the timings of the handlers running for each chat.
+// Sends don't carry the handler's context, so Telegram calls are added to
+// every handler running for their chat.
+type activeTimings struct {
+	mu    sync.Mutex
+	chats map[int64][]*handlerTimings
+}
+
+func newActiveTimings() *activeTimings {
+	return &activeTimings{chats: make(map[int64][]*handlerTimings)}
+}
+
+func (a *activeTimings) start(chatID int64, t *handlerTimings) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.chats[chatID] = append(a.chats[chatID], t)
+}
+
+func (a *activeTimings) stop(chatID int64, t *handlerTimings) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	running := slices.DeleteFunc(a.chats[chatID], func(other *handlerTimings) bool { return other == t })
+	if len(running) == 0 {
+		delete(a.chats, chatID)
+		return
+	}
+	a.chats[chatID] = running
+}
+
+// addTelegram adds a Telegram call made for chatID.
+func (a *activeTimings) addTelegram(chatID int64, d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, t := range a.chats[chatID] {
+		t.addTelegram(d)
+	}
+}
+
+// routeKey names the route of req in latency metrics.
+func routeKey(req *Request) string {
+	name := "unknown"
+	if req.Route != nil {
+		name = req.Route.Name
+	}
+	if req.Callback != nil {
+		return "callback:" + name
+	}
+	return "/" + name
+}
+
+// latencyMiddleware records how long every handler takes and logs those
+// slower than Telegram.SlowHandlerThreshold with where the time went.
+func (b *Bot) latencyMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		timings := &handlerTimings{}
+		b.timings.start(req.ChatID, timings)
+		start := time.Now()
+		err := next(context.WithValue(ctx, timingsKeyType{}, timings), req)
+		elapsed := time.Since(start)
+		b.timings.stop(req.ChatID, timings)
+
+		route := routeKey(req)
+		metrics.HandlerLatency(route).Observe(elapsed)
+		if elapsed >= b.config.Telegram.SlowHandlerThreshold {
+			b.log.Warn("slow handler",
+				zap.String("route", route),
+				zap.Int64("chat_id", req.ChatID),
+				zap.Int64("user_id", req.UserID),
+				zap.String("args", req.Args),
+				zap.String("request_id", client.RequestIDFrom(ctx)),
+				zap.Duration("duration", elapsed),
+				zap.Duration("backend", time.Duration(timings.backend.Load())),
+				zap.Int64("backend_calls", timings.backendCalls.Load()),
+				zap.Duration("telegram", time.Duration(timings.telegram.Load())),
+				zap.Int64("telegram_calls", timings.telegramCalls.Load()),
+				zap.Error(err),
+			)
+		}
+		return err
+	}
+}
+
+// adminStatsSlowCalls caps the slow backend calls listed in /admin_stats.
+const adminStatsSlowCalls = 10
+
+// handleAdminStatsCommand shows the median and 95th percentile latency of
+// every route that handled requests, slowest first, the slowest recent
+// backend calls and the most used routes of the week.
+func (b *Bot) handleAdminStatsCommand(ctx context.Context, r *Request) error {
+	data := adminStatsData{Locale: b.formatterFor(r.ChatID)}
+	metrics.HandlerLatencies(func(route string, h *metrics.Histogram) {
+		data.Handlers = append(data.Handlers, handlerLatencyData{
+			Route: route,
+			Count: h.Count(),
+			P50:   h.Percentile(50).Round(time.Millisecond),
+			P95:   h.Percentile(95).Round(time.Millisecond),
+		})
+	})
+	slices.SortFunc(data.Handlers, func(a, b handlerLatencyData) int {
+		return cmp.Or(cmp.Compare(b.P95, a.P95), cmp.Compare(a.Route, b.Route))
+	})
+	if b.grpcClient != nil {
+		data.SlowCallThreshold = b.grpcClient.SlowCallThreshold()
+		calls := b.grpcClient.SlowCalls()
+		data.SlowCallCount = len(calls)
+		for _, c := range calls[:min(len(calls), adminStatsSlowCalls)] {
+			data.SlowCalls = append(data.SlowCalls, slowCallData{
+				Method:    path.Base(c.Method),
+				Duration:  c.Duration.Round(time.Millisecond),
+				Request:   c.Request,
+				RequestID: c.RequestID,
+				Peer:      c.Peer,
+				Code:      c.Code.String(),
+				At:        c.At,
+			})
+		}
+	}
+	if b.usage != nil {
+		data.UsageEnabled = true
+		data.Usage = b.usageStats(time.Now())
+	}
+	return b.reply(r.ChatID, msgAdminStats, data)
+}
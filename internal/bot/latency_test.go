@@ -0,0 +1,46 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestE2EAdminStatsSlowCalls(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg,
+		backendtest.Delay(100*time.Millisecond, shortenerv1.Shortener_GetLinkStats_FullMethodName),
+		backendtest.SlowCalls(50*time.Millisecond))
+	e.backend.Add(backendtest.Link{Alias: "abc", OriginalURL: "https://example.com/?token=secret", OwnerID: user})
+
+	e.tg.SendMessage(user, "/admin_stats")
+	e.tg.WaitText(user, "No backend calls over 50ms recently.")
+
+	e.tg.SendMessage(user, "/stats abc")
+	e.tg.WaitText(user, "Link Statistics: abc")
+	e.tg.SendMessage(user, "/admin_stats")
+	got := e.tg.WaitText(user, "Slowest recent backend calls over 50ms (of the last 1):").Text()
+	if !strings.Contains(got, "GetLinkStats: ") || !strings.Contains(got, "(alias=abc), OK") {
+		t.Errorf("/admin_stats = %q, want the stats call listed", got)
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("/admin_stats = %q leaks the link", got)
+	}
+}
+
+func TestE2EAdminStatsWithoutSlowCallLog(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg, backendtest.Delay(100*time.Millisecond, shortenerv1.Shortener_GetLinkStats_FullMethodName))
+	e.backend.Add(backendtest.Link{Alias: "abc", OriginalURL: "https://example.com/", OwnerID: user})
+
+	e.tg.SendMessage(user, "/stats abc")
+	e.tg.WaitText(user, "Link Statistics: abc")
+	e.tg.SendMessage(user, "/admin_stats")
+	if got := e.tg.WaitText(user, "Handler latency").Text(); strings.Contains(got, "backend calls") {
+		t.Errorf("/admin_stats = %q, want no slow calls section with the log off", got)
+	}
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Link styles, see config.Messages.LinkStyle.
+const (
+	linkStyleCompact = "compact"
+	linkStyleCard    = "card"
+)
+
+// linkStyle returns the link style of chatID.
+func (b *Bot) linkStyle(chatID int64) string {
+	if style := b.prefs.Get(chatID).LinkStyle; style != "" {
+		return style
+	}
+	return b.config.Messages.LinkStyle
+}
+
+// newLinkCard collects what a link card shows. Missing fields are left
+// empty and the template skips them.
+func newLinkCard(shortURL string, req *shortenerv1.CreateLinkRequest) linkCardData {
+	card := linkCardData{
+		Title:    req.GetTitle(),
+		Host:     req.GetOriginalUrl(),
+		ShortURL: shortURL,
+	}
+	if u, err := url.Parse(req.GetOriginalUrl()); err == nil && u.Hostname() != "" {
+		card.Host = u.Hostname()
+	}
+	if req.ExpiresAt != nil {
+		expires := req.ExpiresAt.AsTime()
+		card.ExpiresAt = &expires
+	}
+	if req.NotBefore != nil {
+		notBefore := req.NotBefore.AsTime()
+		card.NotBefore = &notBefore
+	}
+	return card
+}
+
+// renderLinkCard renders card in style, returning the parse mode of the
+// text. The plain card has labels in place of formatting.
+func (b *Bot) renderLinkCard(style outputStyle, card linkCardData) (text, parseMode string) {
+	if style == stylePlain {
+		return b.render(msgLinkCardPlain, card), ""
+	}
+	return b.render(msgLinkCard, card), tgbotapi.ModeHTML
+}
+
+// sendCreatedLink reports a created link in the chat's link style. compact
+// is the plain text message used when the style is compact.
+func (b *Bot) sendCreatedLink(chatID int64, compact string, card linkCardData, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	if b.linkStyle(chatID) != linkStyleCard {
+		return b.sendMessageWithKeyboard(chatID, compact, keyboard, b.linkContent())
+	}
+	card.Locale = b.formatterFor(chatID)
+	text, parseMode := b.renderLinkCard(b.outputStyle(chatID), card)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = parseMode
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = keyboard
+	_, err := b.send(msg, b.linkContent())
+	return err
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/monitor"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxListTitleLen caps the titles shown in link lists.
+const maxListTitleLen = 50
+
+// linkListItem is a link shown in a link list.
+type linkListItem struct {
+	Link   *shortenerv1.LinkInfo
+	Pinned bool
+	// ExpiresIn is the time left until the link expires, if shown.
+	ExpiresIn string
+	// Health is the destination health of a monitored link, if shown.
+	Health monitor.Health
+	// Actions are the buttons of the item in the full layout.
+	Actions []tgbotapi.InlineKeyboardButton
+}
+
+// linkListSection is a run of items, under Header if set.
+type linkListSection struct {
+	Header string
+	Items  []linkListItem
+}
+
+// renderLinkList renders sections of links in style, numbered throughout,
+// followed by the nav rows. Items get their actions as a row; the compact
+// layout has a single Manage button leading to the stats keyboard, for when
+// the list is too long. name identifies the keyboard in logs.
+func (b *Bot) renderLinkList(to payloadKey, style outputStyle, name, header string, sections []linkListSection, nav [][]tgbotapi.InlineKeyboardButton) (string, tgbotapi.InlineKeyboardMarkup) {
+	itemTemplate := msgMyLinksItem
+	if style == stylePlain {
+		itemTemplate = msgMyLinksItemPlain
+	}
+	formatter := b.formatterFor(to.chatID)
+	var builder strings.Builder
+	builder.WriteString(header)
+
+	var keyboardRows, compactRows [][]tgbotapi.InlineKeyboardButton
+	n := 0
+	for _, section := range sections {
+		if section.Header != "" {
+			builder.WriteString("\n\n" + section.Header)
+		}
+		for _, item := range section.Items {
+			n++
+			link := item.Link
+			title := link.GetOriginalUrl()
+			if t := displayTitle(link.GetTitle()); t != "" {
+				title = t
+			}
+
+			// Limit title length for clean display
+			title = truncateRunes(title, maxListTitleLen)
+
+			builder.WriteString(b.render(itemTemplate, myLinkData{
+				Locale:    formatter,
+				Number:    n,
+				Pinned:    item.Pinned,
+				Title:     title,
+				ShortURL:  b.shortURLOn(link.GetDomain(), link.Alias),
+				ExpiresIn: item.ExpiresIn,
+				Health:    item.Health,
+				Scheduled: link.GetNotBefore().AsTime().After(time.Now()),
+				// Set by backends that list it
+				MinimalAnalytics: link.GetMinimalAnalytics(),
+			}))
+
+			keyboardRows = append(keyboardRows, item.Actions)
+			compactRows = append(compactRows, tgbotapi.NewInlineKeyboardRow(
+				b.payloadButton(to, "Manage #"+strconv.Itoa(n), actionStats, link.Alias),
+			))
+		}
+	}
+
+	return builder.String(), b.fitKeyboard(name, nav, keyboardRows, compactRows)
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// listPagination stands for the page fields of ListUserLinks among the
+// capabilities. Backends without pagination ignore them and return all
+// links, which is only noticed when a page comes back too long; pages are
+// then cut from the full list.
+const listPagination = shortenerv1.Shortener_ListUserLinks_FullMethodName + "#pagination"
+
+// linkCursor is where a page of a user's links starts: a backend page token,
+// or an offset into the full list when the backend doesn't paginate.
+type linkCursor struct {
+	Token  string `json:"t,omitempty"`
+	Offset int    `json:"o,omitempty"`
+	// Page numbers the page for display, counting from 1; zero is page 1.
+	Page int `json:"p,omitempty"`
+}
+
+// number returns the display number of the page.
+func (c linkCursor) number() int {
+	return max(c.Page, 1)
+}
+
+// listUserLinksPage lists up to size links of userID starting at at, leaving
+// out self-test links. The cursor of the next page is nil after the last
+// one. Backends without pagination are paged here from the full list.
+func (b *Bot) listUserLinksPage(ctx context.Context, userID int64, at linkCursor, size int) ([]*shortenerv1.LinkInfo, *linkCursor, error) {
+	if at.Offset > 0 {
+		res, err := b.grpcClient.ListUserLinks(ctx, &shortenerv1.ListUserLinksRequest{UserTgId: userID})
+		if err != nil {
+			return nil, nil, err
+		}
+		page, next := b.pageOf(res.GetLinks(), at, size)
+		return page, next, nil
+	}
+
+	links, token, err := b.grpcClient.ListUserLinksPage(ctx, userID, int32(size), at.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.observePagination(links, token, size)
+	switch {
+	case token != "":
+		return b.withoutSelfTestLinks(links), &linkCursor{Token: token, Page: at.number() + 1}, nil
+	case len(links) > size:
+		// A longer page than asked for is the whole list
+		page, next := b.pageOf(links, at, size)
+		return page, next, nil
+	}
+	return b.withoutSelfTestLinks(links), nil, nil
+}
+
+// pageOf cuts the page at at out of all links of a user.
+func (b *Bot) pageOf(all []*shortenerv1.LinkInfo, at linkCursor, size int) ([]*shortenerv1.LinkInfo, *linkCursor) {
+	all = b.withoutSelfTestLinks(all)
+	start := min(at.Offset, len(all))
+	end := min(start+size, len(all))
+	if end == len(all) {
+		return all[start:end], nil
+	}
+	return all[start:end], &linkCursor{Offset: end, Page: at.number() + 1}
+}
+
+// observePagination records whether the backend paginates link lists, as
+// told by a page of links fetched with size and its next page token.
+func (b *Bot) observePagination(links []*shortenerv1.LinkInfo, token string, size int) {
+	var changed bool
+	switch {
+	case token != "":
+		changed = b.capabilities.Set(listPagination, true)
+	case len(links) > size:
+		changed = b.capabilities.Set(listPagination, false)
+	}
+	if changed {
+		b.capabilitiesChanged()
+	}
+}
+
+// eachUserLinksPage calls fn with the links of userID page by page, up to
+// the configured number of pages, and stops early when fn fails. A backend
+// without pagination returns all links at once; they are passed to fn
+// together, cut to the same cap. It reports whether all links were passed.
+func (b *Bot) eachUserLinksPage(ctx context.Context, userID int64, fn func(links []*shortenerv1.LinkInfo) error) (complete bool, err error) {
+	cfg := b.config.Links
+	token := ""
+	complete = true
+	for pages := 0; ; pages++ {
+		if pages == cfg.MaxPages {
+			b.log.Warn("user has more links than fetched", zap.Int64("user_id", userID), zap.Int("pages", pages))
+			return false, nil
+		}
+		links, next, err := b.grpcClient.ListUserLinksPage(ctx, userID, int32(cfg.FetchPageSize), token)
+		if err != nil {
+			return false, err
+		}
+		b.observePagination(links, next, cfg.FetchPageSize)
+		if limit := cfg.MaxPages * cfg.FetchPageSize; len(links) > limit {
+			b.log.Warn("user has more links than fetched", zap.Int64("user_id", userID), zap.Int("links", len(links)))
+			links = links[:limit]
+			complete = false
+		}
+		if err := fn(b.withoutSelfTestLinks(links)); err != nil {
+			return false, err
+		}
+		if next == "" {
+			return complete, nil
+		}
+		token = next
+	}
+}
+
+// listUserLinks lists the links of userID, leaving out self-test links left
+// behind by a failed run. Links past the page cap are left out.
+func (b *Bot) listUserLinks(ctx context.Context, userID int64) (*shortenerv1.ListUserLinksResponse, error) {
+	res := &shortenerv1.ListUserLinksResponse{}
+	_, err := b.eachUserLinksPage(ctx, userID, func(links []*shortenerv1.LinkInfo) error {
+		res.Links = append(res.Links, links...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// withoutSelfTestLinks drops the links with a self-test alias.
+func (b *Bot) withoutSelfTestLinks(links []*shortenerv1.LinkInfo) []*shortenerv1.LinkInfo {
+	return slices.DeleteFunc(links, func(link *shortenerv1.LinkInfo) bool {
+		return b.isSelfTestAlias(link.GetAlias())
+	})
+}
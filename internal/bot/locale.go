@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/locale"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// formatterFor returns how numbers and dates are written for chatID: in
+// the language chosen in the settings, or else the one of the user's
+// Telegram client, and in the chosen time zone.
+func (b *Bot) formatterFor(chatID int64) locale.Formatter {
+	p := b.prefs.Get(chatID)
+	language := p.Language
+	if language == "" {
+		if u, ok := b.users.Get(chatID); ok {
+			language = u.LanguageCode
+		}
+	}
+	return locale.New(language, p.Timezone)
+}
+
+// userLocation returns the time zone chosen by chatID, UTC by default.
+func (b *Bot) userLocation(chatID int64) *time.Location {
+	if loc, err := time.LoadLocation(b.prefs.Get(chatID).Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// languageSetting describes the language setting of p for the settings menu.
+func languageSetting(p prefs.Prefs) string {
+	if p.Language == "" {
+		return "auto"
+	}
+	return p.Language
+}
+
+// nextLanguage returns the language after current in the cycle the settings
+// button goes through: auto, then every language with locale data.
+func nextLanguage(current string) string {
+	i := slices.Index(locale.Languages, current)
+	if i == len(locale.Languages)-1 {
+		return ""
+	}
+	return locale.Languages[i+1]
+}
+
+// handleTimezoneCommand sets the time zone times are shown in, given as an
+// IANA name like Europe/Berlin. Without an argument it shows the current one.
+func (b *Bot) handleTimezoneCommand(ctx context.Context, r *Request) error {
+	name := strings.TrimSpace(r.Args)
+	if name == "" {
+		return b.reply(r.ChatID, msgTimezoneUsage, timezoneData{Timezone: timezoneSetting(b.prefs.Get(r.ChatID))})
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || strings.EqualFold(name, "local") {
+		return b.reply(r.ChatID, msgInvalidTimezone, timezoneData{Timezone: name})
+	}
+	err = b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		p.Timezone = loc.String()
+		if p.Timezone == "UTC" {
+			p.Timezone = ""
+		}
+		b.appendHistory(p, prefs.HistoryEntry{Action: historySettings, Setting: "time zone"})
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	return b.reply(r.ChatID, msgTimezoneSet, timezoneData{
+		Timezone: loc.String(),
+		Now:      b.formatterFor(r.ChatID).DateTime(time.Now()),
+	})
+}
+
+// timezoneSetting describes the time zone setting of p.
+func timezoneSetting(p prefs.Prefs) string {
+	if p.Timezone == "" {
+		return "UTC"
+	}
+	return p.Timezone
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/locale"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/users"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessagesRenderInEveryLocale(t *testing.T) {
+	m, err := newMessageTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fr has no locale data and renders plainly
+	languages := append([]string{"fr"}, locale.Languages...)
+	for _, language := range languages {
+		f := locale.New(language, "Europe/Berlin")
+		for name, data := range messageData {
+			if data != nil {
+				v := reflect.New(reflect.TypeOf(data)).Elem()
+				if field := v.FieldByName("Locale"); field.IsValid() {
+					field.Set(reflect.ValueOf(f))
+				}
+				data = v.Interface()
+			}
+			text, err := m.Render(name, data)
+			if err != nil {
+				t.Errorf("%s: %s: %v", language, name, err)
+				continue
+			}
+			if strings.Contains(text, "<no value>") {
+				t.Errorf("%s: %s renders a missing value: %q", language, name, text)
+			}
+		}
+	}
+}
+
+func TestFormatterForFallsBack(t *testing.T) {
+	e := startBot(t, nil)
+	res := &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ClickCount: 1234567}
+	for _, tt := range []struct {
+		name            string
+		client, setting string
+		want            string
+	}{
+		{name: "client language", client: "de", want: "Total Clicks: 1.234.567"},
+		{name: "setting over client", client: "de", setting: "ru", want: "Total Clicks: 1\u00a0234\u00a0567"},
+		{name: "client region", client: "en-GB", want: "Total Clicks: 1,234,567"},
+		{name: "no locale data", client: "fr", want: "Total Clicks: 1234567"},
+		{name: "unknown user", want: "Total Clicks: 1234567"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.bot.users.Forget(user); err != nil {
+				t.Fatal(err)
+			}
+			if tt.client != "" {
+				e.bot.users.Touch(user, users.Name{FirstName: "User"}, tt.client, time.Now())
+			}
+			err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+				p.Language = tt.setting
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, _ := e.bot.renderStats(payloadKey{chatID: user, userID: user}, styleRich, "abc", res)
+			if !strings.Contains(text, tt.want) {
+				t.Errorf("stats lack %q:\n%s", tt.want, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/maintenance"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maintenanceCheckInterval is how often the start and the end of the
+// maintenance window are looked for.
+const maintenanceCheckInterval = time.Minute
+
+// maintenanceError is a call changing links refused during maintenance,
+// without reaching the backend.
+type maintenanceError struct {
+	window maintenance.Window
+}
+
+func (e *maintenanceError) Error() string {
+	return fmt.Sprintf("backend maintenance until %s", e.window.End.Format(time.RFC3339))
+}
+
+// activeMaintenance returns the maintenance window in progress, if any.
+func (b *Bot) activeMaintenance() (maintenance.Window, bool) {
+	w, ok := b.maintenance.Get()
+	if !ok || !w.Active(time.Now()) {
+		return maintenance.Window{}, false
+	}
+	return w, true
+}
+
+// refuseMutations fails the backend calls that change links while
+// maintenance is in progress, whatever path they come from.
+func (b *Bot) refuseMutations() error {
+	if w, ok := b.activeMaintenance(); ok {
+		return &maintenanceError{window: w}
+	}
+	return nil
+}
+
+// maintenanceData describes w to chatID.
+func (b *Bot) maintenanceData(chatID int64, w maintenance.Window) maintenanceData {
+	return maintenanceData{
+		Locale:  b.formatterFor(chatID),
+		Start:   w.Start,
+		End:     w.End,
+		Message: w.Message,
+		Active:  w.Active(time.Now()),
+	}
+}
+
+// handleMaintenanceCommand manages the maintenance window:
+//
+//	/maintenance on <duration> [message]
+//	/maintenance at <time> <duration> [message]
+//	/maintenance off
+//
+// Without arguments it shows the current window. A window planned with
+// "at" is announced to the recently active users right away.
+func (b *Bot) handleMaintenanceCommand(ctx context.Context, r *Request) error {
+	args := strings.Fields(r.Args)
+	if len(args) == 0 {
+		w, ok := b.maintenance.Get()
+		if !ok {
+			return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+		}
+		return b.reply(r.ChatID, msgMaintenanceBanner, b.maintenanceData(r.ChatID, w))
+	}
+
+	now := time.Now()
+	w := maintenance.Window{Start: now, AdminID: r.UserID}
+	switch strings.ToLower(args[0]) {
+	case "off":
+		ok, err := b.maintenance.Clear()
+		if err != nil {
+			b.log.Error("failed to save maintenance window", zap.Error(err))
+			return b.reply(r.ChatID, msgInternalError, nil)
+		}
+		if !ok {
+			return b.reply(r.ChatID, msgMaintenanceNone, nil)
+		}
+		b.log.Info("maintenance ended early", zap.Int64("admin_id", r.UserID))
+		return b.reply(r.ChatID, msgMaintenanceOff, nil)
+	case "on":
+		args = args[1:]
+	case "at":
+		if len(args) < 3 {
+			return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+		}
+		start, rest, err := parseMaintenanceStart(args[1:], b.userLocation(r.ChatID))
+		if err != nil {
+			return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+		}
+		if !start.After(now) {
+			return b.reply(r.ChatID, msgMaintenanceInPast, nil)
+		}
+		w.Start, args = start, rest
+	default:
+		return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+	}
+	if len(args) == 0 {
+		return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+	}
+	duration, err := parseExpiry(args[0])
+	if err != nil || duration == 0 {
+		return b.reply(r.ChatID, msgMaintenanceUsage, nil)
+	}
+	w.End = w.Start.Add(duration)
+	w.Message = strings.Join(args[1:], " ")
+	w.Started = w.Active(now)
+	if err := b.maintenance.Set(w); err != nil {
+		b.log.Error("failed to save maintenance window", zap.Error(err))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	b.log.Info("maintenance set",
+		zap.Int64("admin_id", r.UserID),
+		zap.Time("start", w.Start),
+		zap.Time("end", w.End))
+	if w.Started {
+		return b.reply(r.ChatID, msgMaintenanceOn, b.maintenanceData(r.ChatID, w))
+	}
+
+	if err := b.reply(r.ChatID, msgMaintenanceScheduled, b.maintenanceData(r.ChatID, w)); err != nil {
+		return err
+	}
+	var targets []int64
+	for _, u := range b.users.List() {
+		if u.BlockedAt.IsZero() && now.Sub(u.LastSeen) <= b.config.Maintenance.AnnounceActiveWithin {
+			targets = append(targets, u.ID)
+		}
+	}
+	// The announcement is the same for everyone, so its times are in the
+	// time zone the admin planned it in
+	data := b.maintenanceData(r.ChatID, w)
+	return b.startBroadcast(r.ChatID, b.render(msgMaintenanceAnnouncement, data), targets)
+}
+
+// parseMaintenanceStart parses the start of a planned window from args,
+// given as a date and a time or joined by a T, and returns the remaining
+// arguments.
+func parseMaintenanceStart(args []string, loc *time.Location) (time.Time, []string, error) {
+	if len(args) > 1 {
+		if t, err := time.ParseInLocation("2006-01-02 15:04", args[0]+" "+args[1], loc); err == nil {
+			return t, args[2:], nil
+		}
+	}
+	t, err := parseNotBefore(args[0], loc)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return t, args[1:], nil
+}
+
+// runMaintenance tells admins when the maintenance window starts and ends,
+// and removes it once over.
+func (b *Bot) runMaintenance(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+	for {
+		b.checkMaintenance(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bot) checkMaintenance(now time.Time) {
+	w, ok := b.maintenance.Get()
+	switch {
+	case !ok:
+	case !now.Before(w.End):
+		if _, err := b.maintenance.Clear(); err != nil {
+			b.log.Error("failed to save maintenance window", zap.Error(err))
+		}
+		b.log.Info("maintenance over")
+		b.notifyAdmins(b.render(msgAdminMaintenanceEnded, nil))
+	case !w.Started && w.Active(now):
+		w.Started = true
+		if err := b.maintenance.Set(w); err != nil {
+			b.log.Error("failed to save maintenance window", zap.Error(err))
+		}
+		b.log.Info("maintenance started", zap.Time("end", w.End))
+		b.notifyAdmins(b.render(msgAdminMaintenanceStarted, maintenanceData{End: w.End, Message: w.Message, Active: true}))
+	}
+}
+
+// mainMenuText renders the main menu for chatID, below the maintenance
+// banner while maintenance is planned or in progress.
+func (b *Bot) mainMenuText(chatID int64) string {
+	text := b.render(msgHelp, nil)
+	if w, ok := b.maintenance.Get(); ok && time.Now().Before(w.End) {
+		text = b.render(msgMaintenanceBanner, b.maintenanceData(chatID, w)) + "\n\n" + text
+	}
+	return text
+}
@@ -0,0 +1,1414 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/locale"
+	"GURLS-Bot/internal/monitor"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message template names. The default texts live in templates/messages.tmpl;
+// deployments can redefine any of them in Messages.TemplateFile.
+const (
+	msgHelp                      = "help"
+	msgUseShortenCommand         = "use_shorten_command"
+	msgInvalidShortenFormat      = "invalid_shorten_format"
+	msgLinkSuccessfullyShortened = "link_successfully_shortened"
+	msgLinkAlreadyCreated        = "link_already_created"
+	msgLinkStats                 = "link_stats"
+	msgUnknownCommand            = "unknown_command"
+	msgInvalidCommandFormat      = "invalid_command_format"
+	msgInvalidAliasFormat        = "invalid_alias_format"
+	msgLinkNotFound              = "link_not_found"
+	msgInternalError             = "internal_error"
+	msgLinkDeleted               = "link_deleted"
+	msgMyLinksHeader             = "my_links_header"
+	msgNoLinks                   = "no_links"
+	msgMyLinksItem               = "my_links_item"
+	msgAliasTaken                = "alias_taken"
+	msgInvalidArgument           = "invalid_argument"
+	msgInvalidRequest            = "invalid_request"
+	msgResourceExhausted         = "resource_exhausted"
+	msgServiceUnavailable        = "service_unavailable"
+	msgRequestTimeout            = "request_timeout"
+	msgPermissionDenied          = "permission_denied"
+	msgUnauthenticated           = "unauthenticated"
+
+	// Additional messages
+	msgSendCustomAlias  = "send_custom_alias"
+	msgSendUrlWithAlias = "send_url_with_alias"
+
+	// Create queue messages
+	msgBackendUnavailableQueue = "backend_unavailable_queue"
+	msgLinkQueued              = "link_queued"
+	msgAlreadyQueued           = "already_queued"
+	msgQueueUserLimit          = "queue_user_limit"
+	msgQueueFull               = "queue_full"
+	msgQueueOfferExpired       = "queue_offer_expired"
+	msgQueuedLinkCreated       = "queued_link_created"
+	msgQueuedLinkFailed        = "queued_link_failed"
+	msgQueuedLinkExpired       = "queued_link_expired"
+
+	// Callback toasts
+	msgToastDeleted        = "toast_deleted"
+	msgToastStatsRefreshed = "toast_stats_refreshed"
+	msgToastPinned         = "toast_pinned"
+	msgToastUnpinned       = "toast_unpinned"
+	msgToastSettingsSaved  = "toast_settings_saved"
+	msgToastQueued         = "toast_queued"
+	msgButtonExpired       = "button_expired"
+	msgPrivateChatOnly     = "private_chat_only"
+
+	// Edited message replies
+	msgEditedLinkUnchanged = "edited_link_unchanged"
+	msgReplyHasNoURL       = "reply_has_no_url"
+
+	// Link validation messages
+	msgNotOurLink           = "not_our_link"
+	msgAlreadyShortLink     = "already_short_link"
+	msgThirdPartyShortLink  = "third_party_short_link"
+	msgFollowRedirectFailed = "follow_redirect_failed"
+	msgUnsafeURL            = "unsafe_url"
+	msgURLCheckUnavailable  = "url_check_unavailable"
+	msgUserBanned           = "user_banned"
+	msgBlockedDomain        = "blocked_domain"
+
+	// Expand command messages
+	msgExpandResult = "expand_result"
+	msgRateLimited  = "rate_limited"
+
+	// Blocklist admin messages
+	msgBlockUsage       = "block_usage"
+	msgUnblockUsage     = "unblock_usage"
+	msgBlocklistAdded   = "blocklist_added"
+	msgBlocklistRemoved = "blocklist_removed"
+	msgBlocklistExists  = "blocklist_exists"
+	msgBlocklistMissing = "blocklist_missing"
+	msgBlocklistError   = "blocklist_error"
+	msgBlocklistEmpty   = "blocklist_empty"
+	msgBlocklistHeader  = "blocklist_header"
+
+	// Quota messages
+	msgActiveQuotaExceeded = "active_quota_exceeded"
+	msgDailyQuotaExceeded  = "daily_quota_exceeded"
+
+	// UTM wizard messages
+	msgUTMSendURL      = "utm_send_url"
+	msgUTMSource       = "utm_source"
+	msgUTMMedium       = "utm_medium"
+	msgUTMCampaign     = "utm_campaign"
+	msgUTMInvalidValue = "utm_invalid_value"
+	msgUTMConfirm      = "utm_confirm"
+	msgUTMPressCreate  = "utm_press_create"
+
+	// Pinned links messages
+	msgPinnedHeader     = "pinned_header"
+	msgOtherLinksHeader = "other_links_header"
+	msgPinLimitReached  = "pin_limit_reached"
+
+	// Settings messages
+	msgSettings      = "settings"
+	msgAskExpiry     = "ask_expiry"
+	msgInvalidExpiry = "invalid_expiry"
+
+	// Short domain messages
+	msgChooseDomain     = "choose_domain"
+	msgSendURLForDomain = "send_url_for_domain"
+
+	// Dashboard connection messages
+	msgConnectLink      = "connect_link"
+	msgConnectCompleted = "connect_completed"
+	msgConnectExpired   = "connect_expired"
+	msgDisconnected     = "disconnected"
+	msgNotConnected     = "not_connected"
+
+	// Alias generation messages
+	msgAliasGenerationFailed = "alias_generation_failed"
+
+	// User registry
+	msgWelcomeBack     = "welcome_back"
+	msgForgetMeConfirm = "forget_me_confirm"
+	msgForgetMeDone    = "forget_me_done"
+
+	// Health
+	msgPing       = "ping"
+	msgPingFailed = "ping_failed"
+	msgVersion    = "version"
+
+	// Support
+	msgErrorRef = "error_ref"
+
+	// Admin notifications
+	msgAdminStarted          = "admin_started"
+	msgAdminStopping         = "admin_stopping"
+	msgAdminBackendDown      = "admin_backend_down"
+	msgAdminBackendRecovered = "admin_backend_recovered"
+
+	// Link cards, sent with HTML formatting; escape every value with html
+	msgLinkCard = "link_card"
+
+	// Group auto-shortening
+	msgGroupChatOnly         = "group_chat_only"
+	msgAutoShortenUsage      = "auto_shorten_usage"
+	msgAutoShortenAdminsOnly = "auto_shorten_admins_only"
+	msgAutoShortenOn         = "auto_shorten_on"
+	msgAutoShortenOff        = "auto_shorten_off"
+	msgAutoShortened         = "auto_shortened"
+
+	// Cleanup suggestions
+	msgCleanupSuggestions   = "cleanup_suggestions"
+	msgCleanupDone          = "cleanup_done"
+	msgCleanupConfirmDelete = "cleanup_confirm_delete"
+	msgCleanupDeleted       = "cleanup_deleted"
+	msgCleanupCancelled     = "cleanup_cancelled"
+	msgToastKept            = "toast_kept"
+
+	// Alias rename
+	msgSendNewAlias = "send_new_alias"
+	msgSameAlias    = "same_alias"
+	msgLinkRenamed  = "link_renamed"
+
+	// Shortening confirmation
+	msgConfirmShorten     = "confirm_shorten"
+	msgSendShortenOptions = "send_shorten_options"
+	msgShortenIgnored     = "shorten_ignored"
+
+	// Copy text snippets
+	msgSnippet        = "snippet"
+	msgSnippetPlain   = "snippet_plain"
+	msgSnippetTwitter = "snippet_twitter"
+	msgSnippetEmoji   = "snippet_emoji"
+
+	// Click snapshots
+	msgToastSnapshotSaved = "toast_snapshot_saved"
+	msgNoSnapshot         = "no_snapshot"
+	msgSnapshotDelta      = "snapshot_delta"
+
+	// Inline mode
+	msgInlineShorten = "inline_shorten"
+	msgInlineNoLinks = "inline_no_links"
+
+	// Backend capabilities
+	msgFeatureUnavailable = "feature_unavailable"
+
+	// Expiring links
+	msgExpiringHeader  = "expiring_header"
+	msgNoExpiringLinks = "no_expiring_links"
+	msgExtendLink      = "extend_link"
+	msgLinkExtended    = "link_extended"
+	msgNeverExpires    = "never_expires"
+
+	// Message edits
+	msgToastNoChanges = "toast_no_changes"
+
+	// Activity history
+	msgHistory             = "history"
+	msgNoHistory           = "no_history"
+	msgClearHistoryConfirm = "clear_history_confirm"
+	msgHistoryCleared      = "history_cleared"
+
+	// Self-test
+	msgSelfTestPassed = "self_test_passed"
+	msgSelfTestFailed = "self_test_failed"
+	msgAliasReserved  = "alias_reserved"
+
+	// Incomplete stats
+	msgStatsPending = "stats_pending"
+
+	// Broadcasts
+	msgBroadcastUsage           = "broadcast_usage"
+	msgBroadcastProgress        = "broadcast_progress"
+	msgBroadcastDone            = "broadcast_done"
+	msgBroadcastCancelled       = "broadcast_cancelled"
+	msgToastBroadcastCancelling = "toast_broadcast_cancelling"
+
+	// Personalized welcome
+	msgWelcomeSummary = "welcome_summary"
+
+	// Handler latency
+	msgAdminStats = "admin_stats"
+
+	// Forwarded messages
+	msgForwardNoURL        = "forward_no_url"
+	msgConfirmForwardTitle = "confirm_forward_title"
+
+	// Quick actions keyboard
+	msgQuickNewLink       = "quick_new_link"
+	msgQuickMyLinks       = "quick_my_links"
+	msgQuickSummary       = "quick_summary"
+	msgQuickHide          = "quick_hide"
+	msgQuickActionsShown  = "quick_actions_shown"
+	msgQuickActionsHidden = "quick_actions_hidden"
+	msgLinkSummary        = "link_summary"
+
+	// Preview before create
+	msgCreatePreview = "create_preview"
+
+	// Link list pages
+	msgMyLinksPage = "my_links_page"
+
+	// Destination monitor messages
+	msgToastMonitoring     = "toast_monitoring"
+	msgToastUnmonitored    = "toast_unmonitored"
+	msgMonitorLimitReached = "monitor_limit_reached"
+	msgDestinationFailing  = "destination_failing"
+	msgDestinationMoved    = "destination_moved"
+	msgSendNewDestination  = "send_new_destination"
+	msgInvalidDestination  = "invalid_destination"
+	msgDestinationUpdated  = "destination_updated"
+	msgLinkDisabled        = "link_disabled"
+
+	// Link analytics messages
+	msgToastAnalyticsMinimal = "toast_analytics_minimal"
+	msgToastAnalyticsFull    = "toast_analytics_full"
+	msgAnalyticsCreationOnly = "analytics_creation_only"
+
+	// Link transfer messages
+	msgSendTransferRecipient    = "send_transfer_recipient"
+	msgTransferRecipientUnknown = "transfer_recipient_unknown"
+	msgTransferToSelf           = "transfer_to_self"
+	msgTransferOffered          = "transfer_offered"
+	msgTransferUnreachable      = "transfer_unreachable"
+	msgTransferOffer            = "transfer_offer"
+	msgTransferAccepted         = "transfer_accepted"
+	msgTransferCompleted        = "transfer_completed"
+	msgTransferDeclined         = "transfer_declined"
+	msgTransferRejected         = "transfer_rejected"
+	msgTransferExpired          = "transfer_expired"
+
+	// Plain output
+	msgLinkStatsPlain   = "link_stats_plain"
+	msgMyLinksItemPlain = "my_links_item_plain"
+	msgLinkCardPlain    = "link_card_plain"
+	msgReplyOptions     = "reply_options"
+	msgNoReplyOption    = "no_reply_option"
+
+	// Stale keyboards
+	msgMenuExpired = "menu_expired"
+
+	// Dialogs
+	msgDialogReset = "dialog_reset"
+
+	// Feature flags
+	msgFeatureDisabled = "feature_disabled"
+
+	// Bot token
+	msgAdminTokenRestored = "admin_token_restored"
+
+	// Shorten options
+	msgShortenOptionsIgnored = "shorten_options_ignored"
+
+	// Link inspection
+	msgInspectUsage          = "inspect_usage"
+	msgInspectLink           = "inspect_link"
+	msgInspectConfirmDisable = "inspect_confirm_disable"
+	msgInspectConfirmDelete  = "inspect_confirm_delete"
+	msgInspectCancelled      = "inspect_cancelled"
+	msgAuditFailed           = "audit_failed"
+
+	// Time zone messages
+	msgTimezoneUsage   = "timezone_usage"
+	msgTimezoneSet     = "timezone_set"
+	msgInvalidTimezone = "invalid_timezone"
+
+	// Shortcuts under created links
+	msgSendTitle         = "send_title"
+	msgTitleSet          = "title_set"
+	msgTitleCreationOnly = "title_creation_only"
+	msgPickLinkExpiry    = "pick_link_expiry"
+	msgSendPath          = "send_path"
+	msgShortcutExpired   = "shortcut_expired"
+
+	// Settings export and import
+	msgSettingsExported         = "settings_exported"
+	msgSendSettingsFile         = "send_settings_file"
+	msgSettingsFileTooLarge     = "settings_file_too_large"
+	msgSettingsFileNewer        = "settings_file_newer"
+	msgSettingsFileInvalid      = "settings_file_invalid"
+	msgSettingsFileForeignLinks = "settings_file_foreign_links"
+	msgSettingsImportUnchanged  = "settings_import_unchanged"
+	msgSettingsImportPreview    = "settings_import_preview"
+	msgSettingsImported         = "settings_imported"
+	msgSettingsImportCancelled  = "settings_import_cancelled"
+
+	// Destination health
+	msgNotMonitored       = "not_monitored"
+	msgDestinationChecked = "destination_checked"
+
+	// Campaigns
+	msgCampaignUsage            = "campaign_usage"
+	msgNoCampaigns              = "no_campaigns"
+	msgCampaigns                = "campaigns"
+	msgInvalidCampaignName      = "invalid_campaign_name"
+	msgInvalidCampaignDates     = "invalid_campaign_dates"
+	msgCampaignExists           = "campaign_exists"
+	msgCampaignLimitReached     = "campaign_limit_reached"
+	msgCampaignCreated          = "campaign_created"
+	msgCampaignNotFound         = "campaign_not_found"
+	msgCampaignDeleted          = "campaign_deleted"
+	msgPickCampaign             = "pick_campaign"
+	msgCampaignFull             = "campaign_full"
+	msgToastAddedToCampaign     = "toast_added_to_campaign"
+	msgToastRemovedFromCampaign = "toast_removed_from_campaign"
+	msgCampaignEmpty            = "campaign_empty"
+	msgCampaignReport           = "campaign_report"
+
+	// Abuse reports
+	msgReportUsage      = "report_usage"
+	msgReportReceived   = "report_received"
+	msgAdminAbuseReport = "admin_abuse_report"
+
+	// Scheduled links
+	msgSchedulingUnsupported = "scheduling_unsupported"
+	msgNotBeforePast         = "not_before_past"
+	msgNotBeforeAfterExpiry  = "not_before_after_expiry"
+	msgInvalidNotBefore      = "invalid_not_before"
+	msgUnscheduledLinkKept   = "unscheduled_link_kept"
+	msgSendNotBefore         = "send_not_before"
+	msgSendURLScheduled      = "send_url_scheduled"
+
+	// Bulk actions
+	msgBulkActions        = "bulk_actions"
+	msgBulkConfirmExtend  = "bulk_confirm_extend"
+	msgBulkConfirmDisable = "bulk_confirm_disable"
+	msgBulkConfirmAdd     = "bulk_confirm_campaign_add"
+	msgBulkConfirmRemove  = "bulk_confirm_campaign_remove"
+	msgBulkProgress       = "bulk_progress"
+	msgBulkDone           = "bulk_done"
+	msgBulkRunning        = "bulk_running"
+	msgBulkCancelled      = "bulk_cancelled"
+
+	// Update stream health
+	msgAdminUpdatesStalled   = "admin_updates_stalled"
+	msgAdminUpdatesRecovered = "admin_updates_recovered"
+
+	// Custom link previews
+	msgSendPreviewTitle       = "send_preview_title"
+	msgSendPreviewDescription = "send_preview_description"
+	msgSendPreviewImage       = "send_preview_image"
+	msgPreviewImageTooLarge   = "preview_image_too_large"
+	msgPreviewImageInvalid    = "preview_image_invalid"
+	msgPreviewSet             = "preview_set"
+	msgPreviewRemoved         = "preview_removed"
+
+	// Usage analytics
+	msgAdminUsageSummary = "admin_usage_summary"
+
+	// Channel posts
+	msgChannelUsage        = "channel_usage"
+	msgChannelNotFound     = "channel_not_found"
+	msgChannelAdminsOnly   = "channel_admins_only"
+	msgChannelCantEdit     = "channel_cant_edit"
+	msgChannelNoDiscussion = "channel_no_discussion"
+	msgChannelOn           = "channel_on"
+	msgChannelOff          = "channel_off"
+
+	// Maintenance
+	msgMaintenance             = "maintenance"
+	msgMaintenanceBanner       = "maintenance_banner"
+	msgMaintenanceAnnouncement = "maintenance_announcement"
+	msgMaintenanceUsage        = "maintenance_usage"
+	msgMaintenanceInPast       = "maintenance_in_past"
+	msgMaintenanceOn           = "maintenance_on"
+	msgMaintenanceScheduled    = "maintenance_scheduled"
+	msgMaintenanceOff          = "maintenance_off"
+	msgMaintenanceNone         = "maintenance_none"
+	msgAdminMaintenanceStarted = "admin_maintenance_started"
+	msgAdminMaintenanceEnded   = "admin_maintenance_ended"
+
+	// Link comparison
+	msgCompareUsage      = "compare_usage"
+	msgCompareSameLink   = "compare_same_link"
+	msgCompareNotOwned   = "compare_not_owned"
+	msgSendCompareAlias  = "send_compare_alias"
+	msgCompareLinks      = "compare_links"
+	msgCompareLinksPlain = "compare_links_plain"
+
+	// Link peeks
+	msgLinkPeek        = "link_peek"
+	msgPeekNotFound    = "peek_not_found"
+	msgPeekUnavailable = "peek_unavailable"
+
+	// Admin command confirmation messages
+	msgConfirmCommand          = "confirm_command"
+	msgConfirmWrongPhrase      = "confirm_wrong_phrase"
+	msgConfirmExpired          = "confirm_expired"
+	msgConfirmNoApprover       = "confirm_no_approver"
+	msgConfirmAwaitingApproval = "confirm_awaiting_approval"
+	msgApproveCommand          = "approve_command"
+	msgCommandApproved         = "command_approved"
+	msgCommandRejected         = "command_rejected"
+	msgCommandApprovedBy       = "command_approved_by"
+	msgCommandRejectedBy       = "command_rejected_by"
+	msgApprovalTimedOut        = "approval_timed_out"
+	msgConfirmClosed           = "confirm_closed"
+	msgApproveOwnCommand       = "approve_own_command"
+)
+
+// Data passed to message templates.
+type (
+	linkData struct {
+		ShortURL string
+		// Options lists the /shorten options applied, if any.
+		Options string
+	}
+	aliasData struct {
+		Alias string
+	}
+	aliasRulesData struct {
+		// Allowed lists the allowed characters, e.g. "letters, numbers,
+		// and hyphens".
+		Allowed string
+		// Length is the allowed length, e.g. "1-20 characters".
+		Length string
+	}
+	textData struct {
+		Text string
+	}
+	snippetData struct {
+		Title    string
+		ShortURL string
+	}
+	newAliasData struct {
+		ShortURL string
+		aliasRulesData
+	}
+	renameData struct {
+		OldURL    string
+		NewURL    string
+		Redirects bool
+	}
+	urlData struct {
+		URL string
+	}
+	pageData struct {
+		Page int
+	}
+	transferData struct {
+		ShortURL string
+		URL      string
+		// Peer names the other user of the transfer.
+		Peer      string
+		ExpiresIn string
+	}
+	destinationData struct {
+		ShortURL string
+		URL      string
+		// Status is the HTTP status seen; zero when the URL can't be reached.
+		Status   int
+		Failures int
+		// Redirect is where a permanent redirect points.
+		Redirect string
+	}
+	createPreviewData struct {
+		Locale    locale.Formatter
+		URL       string
+		Title     string
+		Alias     string
+		ExpiresAt *time.Time
+		Domain    string
+		// MinimalAnalytics means only clicks will be counted.
+		MinimalAnalytics bool
+		// NotBefore is when the link goes live, if scheduled.
+		NotBefore *time.Time
+		// AliasStyle is the style of the generated alias, when not the
+		// user's default.
+		AliasStyle string
+	}
+	forwardTitleData struct {
+		URL   string
+		Title string
+	}
+	domainData struct {
+		Domain string
+	}
+	patternData struct {
+		Pattern string
+	}
+	commandData struct {
+		Command string
+	}
+	nameData struct {
+		Name string
+	}
+	adminStatsData struct {
+		Locale   locale.Formatter
+		Handlers []handlerLatencyData
+		// SlowCalls lists the slowest of the SlowCallCount recent calls
+		// taking SlowCallThreshold or longer.
+		SlowCalls         []slowCallData
+		SlowCallCount     int
+		SlowCallThreshold time.Duration
+		UsageEnabled      bool
+		Usage             []usageData
+	}
+	usageData struct {
+		Route string
+		Count int64
+		// Today is the part of Count from today, in /admin_stats.
+		Today int64
+	}
+	usageSummaryData struct {
+		// From and To are the first and last day summed up.
+		From   string
+		To     string
+		Total  int64
+		Routes []usageData
+		// More is the number of routes used but left out of Routes.
+		More int
+	}
+	handlerLatencyData struct {
+		Route string
+		Count int64
+		P50   time.Duration
+		P95   time.Duration
+	}
+	slowCallData struct {
+		Method    string
+		Duration  time.Duration
+		Request   string
+		RequestID string
+		Peer      string
+		Code      string
+		At        time.Time
+	}
+	welcomeData struct {
+		Locale      locale.Formatter
+		Name        string
+		Links       int
+		Clicks      int64
+		ClicksKnown bool
+	}
+	refData struct {
+		Ref string
+	}
+	startupData struct {
+		Version  string
+		Env      string
+		Backend  string
+		Features string
+	}
+	backendDownData struct {
+		For   time.Duration
+		Error string
+	}
+	pingData struct {
+		Serving        bool
+		State          string
+		ConnectionOnly bool
+		Latency        time.Duration
+	}
+	errorData struct {
+		Error string
+	}
+	limitData struct {
+		Limit int
+	}
+	countData struct {
+		Count int
+	}
+	cleanupData struct {
+		Locale   locale.Formatter
+		Links    []cleanupLink
+		KeepDays int
+	}
+	cleanupLink struct {
+		ShortURL  string
+		Title     string
+		ExpiredAt *time.Time
+		CreatedAt *time.Time
+	}
+	cleanupDeletedData struct {
+		Deleted int
+		Failed  int
+	}
+	quotaData struct {
+		Count int
+		Limit int
+	}
+	queueFailureData struct {
+		URL    string
+		Reason string
+	}
+	statsData struct {
+		Locale         locale.Formatter
+		Alias          string
+		Title          string
+		OriginalURL    string
+		Clicks         int64
+		ExpiresAt      *time.Time
+		ClicksByDevice map[string]int64
+		Source         string
+		// MinimalAnalytics means only clicks are counted for the link.
+		MinimalAnalytics bool
+		// NotBefore is when a scheduled link goes live, and GoesLiveIn the
+		// time left until then; unset for live links.
+		NotBefore  *time.Time
+		GoesLiveIn string
+		// LastClickAt is unset for links never clicked and by backends
+		// that don't keep it.
+		LastClickAt *time.Time
+		// ShortURL is only shown in plain output.
+		ShortURL string
+	}
+	// broadcastData holds counts formatted with groupDigits.
+	broadcastData struct {
+		Done    string
+		Total   string
+		Sent    string
+		Blocked string
+		Failed  string
+	}
+	selfTestData struct {
+		Alias    string
+		Duration time.Duration
+		// Error names the failed step and why it failed.
+		Error string
+	}
+	historyData struct {
+		Locale  locale.Formatter
+		Page    int
+		Pages   int
+		Entries []historyEntryData
+	}
+	historyEntryData struct {
+		Number   int
+		At       time.Time
+		Action   string
+		Alias    string
+		NewAlias string
+		Setting  string
+		Peer     string
+		// Deleted marks links that no longer exist.
+		Deleted bool
+	}
+	snapshotData struct {
+		Locale   locale.Formatter
+		Alias    string
+		Since    time.Time
+		Clicks   int64
+		Total    int64
+		ByDevice []deviceDelta
+	}
+	deviceDelta struct {
+		Device string
+		Delta  int64
+	}
+	myLinkData struct {
+		Locale   locale.Formatter
+		Number   int
+		Pinned   bool
+		Title    string
+		ShortURL string
+		// ExpiresIn is the time left, shown in lists of expiring links.
+		ExpiresIn        string
+		MinimalAnalytics bool
+		// Health is the destination health of monitored links.
+		Health monitor.Health
+		// Scheduled marks links that don't redirect yet.
+		Scheduled bool
+	}
+	replyOptionsData struct {
+		Options []replyOption
+	}
+	replyOption struct {
+		Number int
+		Label  string
+	}
+	numberData struct {
+		Number int
+	}
+	windowData struct {
+		Window string
+	}
+	extendData struct {
+		Locale    locale.Formatter
+		ShortURL  string
+		ExpiresAt time.Time
+	}
+	expandData struct {
+		Locale      locale.Formatter
+		ShortURL    string
+		OriginalURL string
+		Active      bool
+		Expired     bool
+		ExpiresAt   *time.Time
+	}
+	settingsData struct {
+		Expiry    string
+		AutoTitle bool
+		AskExpiry bool
+		Preview   bool
+		Sound     bool
+		LinkStyle string
+		Cleanup   bool
+		Confirm   bool
+		Quick     bool
+		// Plain is the plain output setting.
+		Plain bool
+		// MinimalAnalytics is the default for new links.
+		MinimalAnalytics bool
+		// Language is "auto" or the language chosen for numbers and dates.
+		Language string
+		// Timezone is the time zone times are shown in.
+		Timezone string
+		// AliasStyle is the style of generated aliases; empty when the
+		// backend can't choose styles.
+		AliasStyle string
+	}
+	autoShortenedData struct {
+		Links []autoShortenedLink
+	}
+	autoShortenedLink struct {
+		Host     string
+		ShortURL string
+	}
+	linkCardData struct {
+		Locale    locale.Formatter
+		Repeat    bool
+		Title     string
+		Host      string
+		ExpiresAt *time.Time
+		// NotBefore is when the link goes live, if scheduled.
+		NotBefore *time.Time
+		ShortURL  string
+		// Options lists the /shorten options applied, if any.
+		Options string
+	}
+	inspectData struct {
+		Locale   locale.Formatter
+		ShortURL string
+		URL      string
+		Title    string
+		// OwnerID is 0 when the backend doesn't report owners.
+		OwnerID int64
+		// Owner names the owner if the bot knows them.
+		Owner     string
+		CreatedAt *time.Time
+		ExpiresAt *time.Time
+		Clicks    int64
+		Reason    string
+	}
+	shortenWarningsData struct {
+		// Warnings lists what of the /shorten options was ignored or
+		// overridden, one line each.
+		Warnings []string
+	}
+	titleData struct {
+		ShortURL string
+		Title    string
+	}
+	foreignLinksData struct {
+		Aliases []string
+	}
+	// importData is what importing a settings file changes.
+	importData struct {
+		Settings  []string
+		Pins      int
+		Kept      int
+		Snapshots int
+		Campaigns int
+	}
+	checkData struct {
+		Health monitor.Health
+		// Status is the HTTP status; zero when the destination wasn't
+		// reached.
+		Status int
+	}
+	campaignData struct {
+		Locale locale.Formatter
+		Name   string
+		// Start and End are the first and last day counted, if set.
+		Start *time.Time
+		End   *time.Time
+		Links int
+	}
+	campaignsData struct {
+		Locale    locale.Formatter
+		Campaigns []campaignData
+	}
+	campaignLinkData struct {
+		ShortURL string
+		Clicks   int64
+		// Share is the percentage of the campaign clicks.
+		Share       int
+		Deleted     bool
+		Unavailable bool
+	}
+	campaignReportData struct {
+		campaignData
+		// Dated is set for campaigns with dates, Ranged when the clicks
+		// were counted within them.
+		Dated  bool
+		Ranged bool
+		Total  int64
+		Links  []campaignLinkData
+	}
+	reportData struct {
+		Locale   locale.Formatter
+		Alias    string
+		ShortURL string
+		// Count is the number of reports since Since.
+		Count int
+		Since time.Time
+		// Reports lists the latest reports, oldest first.
+		Reports []reportReasonData
+	}
+	reportReasonData struct {
+		Reporter string
+		Reason   string
+		At       time.Time
+	}
+	scheduleData struct {
+		Locale    locale.Formatter
+		NotBefore time.Time
+	}
+	bulkData struct {
+		Count  int
+		Window string
+	}
+	bulkCampaignData struct {
+		Count    int
+		Campaign string
+	}
+	bulkProgressData struct {
+		Op       string
+		Campaign string
+		Done     int
+		Total    int
+	}
+	bulkDoneData struct {
+		Op       string
+		Campaign string
+		Updated  int
+		Skipped  int
+		Failed   []string
+	}
+	previewPromptData struct {
+		ShortURL string
+		Limit    int
+	}
+	customPreviewData struct {
+		ShortURL    string
+		Title       string
+		Description string
+		Image       bool
+	}
+	channelData struct {
+		Title string
+		// Mode is channelModeEdit, channelModeReply or "off".
+		Mode string
+	}
+	maintenanceData struct {
+		Locale locale.Formatter
+		Start  time.Time
+		End    time.Time
+		// Message is the admin's note to users, if any.
+		Message string
+		Active  bool
+	}
+	// compareData is the comparison of two links, First being A and
+	// Second B.
+	compareData struct {
+		First  string
+		Second string
+		Rows   []compareRowData
+	}
+	// compareRowData is a row of a comparison, with a cell per link.
+	compareRowData struct {
+		Label string
+		Cells [2]compareCell
+	}
+	// compareCell is the value of a link in a row, and whether it leads.
+	compareCell struct {
+		Text  string
+		Leads bool
+	}
+	// shortenOptionsPromptData is the URL waiting for its /shorten options.
+	shortenOptionsPromptData struct {
+		URL string
+		// AliasStyles means alias styles can be chosen.
+		AliasStyles bool
+	}
+	// peekData is the one-line stats of a link shown in an alert.
+	peekData struct {
+		Locale locale.Formatter
+		// Name is the title of the link or else its alias.
+		Name   string
+		Clicks int64
+		// LastClick is how long ago the link was last clicked; empty when
+		// unknown.
+		LastClick string
+		ExpiresAt *time.Time
+	}
+	// confirmData is a dangerous admin command held back until confirmed.
+	confirmData struct {
+		// Command is the command with its arguments, and Name the command
+		// alone, such as /broadcast.
+		Command string
+		Name    string
+		Phrase  string
+		// Admin names the other admin of an approval: the one asking for
+		// it, or the one answering.
+		Admin   string
+		Timeout string
+	}
+	timezoneData struct {
+		Timezone string
+		// Now is the current time there, formatted for the user.
+		Now string
+	}
+	connectData struct {
+		Locale    locale.Formatter
+		Minutes   int
+		ExpiresAt time.Time
+	}
+)
+
+// messageData lists every message with the zero value of the data it is
+// rendered with, or nil for plain texts. Templates are checked against it
+// when loaded.
+var messageData = map[string]any{
+	msgHelp:                      nil,
+	msgUseShortenCommand:         nil,
+	msgInvalidShortenFormat:      nil,
+	msgLinkSuccessfullyShortened: linkData{},
+	msgLinkAlreadyCreated:        linkData{},
+	msgLinkStats:                 statsData{},
+	msgUnknownCommand:            nil,
+	msgInvalidCommandFormat:      commandData{},
+	msgInvalidAliasFormat:        aliasRulesData{},
+	msgLinkNotFound:              aliasData{},
+	msgInternalError:             nil,
+	msgLinkDeleted:               aliasData{},
+	msgMyLinksHeader:             nil,
+	msgNoLinks:                   nil,
+	msgMyLinksItem:               myLinkData{},
+	msgAliasTaken:                aliasData{},
+	msgInvalidArgument:           errorData{},
+	msgInvalidRequest:            nil,
+	msgResourceExhausted:         nil,
+	msgServiceUnavailable:        nil,
+	msgRequestTimeout:            nil,
+	msgPermissionDenied:          nil,
+	msgUnauthenticated:           nil,
+	msgSendCustomAlias:           aliasRulesData{},
+	msgSendUrlWithAlias:          aliasData{},
+	msgBackendUnavailableQueue:   nil,
+	msgLinkQueued:                urlData{},
+	msgAlreadyQueued:             nil,
+	msgQueueUserLimit:            limitData{},
+	msgQueueFull:                 nil,
+	msgQueueOfferExpired:         nil,
+	msgQueuedLinkCreated:         linkData{},
+	msgQueuedLinkFailed:          queueFailureData{},
+	msgQueuedLinkExpired:         nil,
+	msgToastDeleted:              linkData{},
+	msgToastStatsRefreshed:       nil,
+	msgToastPinned:               nil,
+	msgToastUnpinned:             nil,
+	msgToastSettingsSaved:        nil,
+	msgToastQueued:               nil,
+	msgButtonExpired:             nil,
+	msgPrivateChatOnly:           nil,
+	msgEditedLinkUnchanged:       nil,
+	msgReplyHasNoURL:             nil,
+	msgNotOurLink:                nil,
+	msgAlreadyShortLink:          nil,
+	msgThirdPartyShortLink:       domainData{},
+	msgFollowRedirectFailed:      errorData{},
+	msgUnsafeURL:                 nil,
+	msgURLCheckUnavailable:       nil,
+	msgUserBanned:                nil,
+	msgBlockedDomain:             nil,
+	msgExpandResult:              expandData{},
+	msgRateLimited:               nil,
+	msgBlockUsage:                nil,
+	msgUnblockUsage:              nil,
+	msgBlocklistAdded:            patternData{},
+	msgBlocklistRemoved:          patternData{},
+	msgBlocklistExists:           patternData{},
+	msgBlocklistMissing:          patternData{},
+	msgBlocklistError:            errorData{},
+	msgBlocklistEmpty:            nil,
+	msgBlocklistHeader:           nil,
+	msgActiveQuotaExceeded:       quotaData{},
+	msgDailyQuotaExceeded:        quotaData{},
+	msgUTMSendURL:                nil,
+	msgUTMSource:                 nil,
+	msgUTMMedium:                 nil,
+	msgUTMCampaign:               nil,
+	msgUTMInvalidValue:           nil,
+	msgUTMConfirm:                urlData{},
+	msgUTMPressCreate:            nil,
+	msgPinnedHeader:              nil,
+	msgOtherLinksHeader:          nil,
+	msgPinLimitReached:           limitData{},
+	msgSettings:                  settingsData{},
+	msgAskExpiry:                 urlData{},
+	msgInvalidExpiry:             nil,
+	msgChooseDomain:              nil,
+	msgSendURLForDomain:          domainData{},
+	msgConnectLink:               connectData{},
+	msgConnectCompleted:          nil,
+	msgConnectExpired:            nil,
+	msgDisconnected:              nil,
+	msgNotConnected:              nil,
+	msgAliasGenerationFailed:     nil,
+	msgWelcomeBack:               nameData{},
+	msgForgetMeConfirm:           nil,
+	msgForgetMeDone:              nil,
+	msgPing:                      pingData{},
+	msgPingFailed:                errorData{},
+	msgVersion:                   startupData{},
+	msgErrorRef:                  refData{},
+	msgAdminStarted:              startupData{},
+	msgAdminStopping:             nil,
+	msgAdminBackendDown:          backendDownData{},
+	msgAdminBackendRecovered:     backendDownData{},
+	msgLinkCard:                  linkCardData{},
+	msgGroupChatOnly:             nil,
+	msgAutoShortenUsage:          nil,
+	msgAutoShortenAdminsOnly:     nil,
+	msgAutoShortenOn:             nil,
+	msgAutoShortenOff:            nil,
+	msgAutoShortened:             autoShortenedData{},
+	msgCleanupSuggestions:        cleanupData{},
+	msgCleanupDone:               nil,
+	msgCleanupConfirmDelete:      countData{},
+	msgCleanupDeleted:            cleanupDeletedData{},
+	msgCleanupCancelled:          nil,
+	msgToastKept:                 nil,
+	msgSendNewAlias:              newAliasData{},
+	msgSameAlias:                 nil,
+	msgLinkRenamed:               renameData{},
+	msgConfirmShorten:            urlData{},
+	msgSendShortenOptions:        shortenOptionsPromptData{},
+	msgShortenIgnored:            urlData{},
+	msgSnippet:                   textData{},
+	msgSnippetPlain:              snippetData{},
+	msgSnippetTwitter:            snippetData{},
+	msgSnippetEmoji:              snippetData{},
+	msgToastSnapshotSaved:        nil,
+	msgNoSnapshot:                nil,
+	msgSnapshotDelta:             snapshotData{},
+	msgInlineShorten:             nil,
+	msgInlineNoLinks:             nil,
+	msgFeatureUnavailable:        nil,
+	msgExpiringHeader:            windowData{},
+	msgNoExpiringLinks:           windowData{},
+	msgExtendLink:                extendData{},
+	msgLinkExtended:              extendData{},
+	msgNeverExpires:              nil,
+	msgToastNoChanges:            nil,
+	msgHistory:                   historyData{},
+	msgNoHistory:                 nil,
+	msgClearHistoryConfirm:       nil,
+	msgHistoryCleared:            nil,
+	msgSelfTestPassed:            selfTestData{},
+	msgSelfTestFailed:            selfTestData{},
+	msgAliasReserved:             aliasData{},
+	msgStatsPending:              aliasData{},
+	msgBroadcastUsage:            nil,
+	msgBroadcastProgress:         broadcastData{},
+	msgBroadcastDone:             broadcastData{},
+	msgBroadcastCancelled:        broadcastData{},
+	msgToastBroadcastCancelling:  nil,
+	msgWelcomeSummary:            welcomeData{},
+	msgAdminStats:                adminStatsData{},
+	msgForwardNoURL:              nil,
+	msgConfirmForwardTitle:       forwardTitleData{},
+	msgQuickNewLink:              nil,
+	msgQuickMyLinks:              nil,
+	msgQuickSummary:              nil,
+	msgQuickHide:                 nil,
+	msgQuickActionsShown:         nil,
+	msgQuickActionsHidden:        nil,
+	msgLinkSummary:               welcomeData{},
+	msgCreatePreview:             createPreviewData{},
+	msgMyLinksPage:               pageData{},
+	msgToastMonitoring:           nil,
+	msgToastUnmonitored:          nil,
+	msgMonitorLimitReached:       limitData{},
+	msgDestinationFailing:        destinationData{},
+	msgDestinationMoved:          destinationData{},
+	msgSendNewDestination:        linkData{},
+	msgInvalidDestination:        nil,
+	msgDestinationUpdated:        destinationData{},
+	msgLinkDisabled:              linkData{},
+	msgToastAnalyticsMinimal:     nil,
+	msgToastAnalyticsFull:        nil,
+	msgAnalyticsCreationOnly:     nil,
+	msgSendTransferRecipient:     linkData{},
+	msgTransferRecipientUnknown:  nil,
+	msgTransferToSelf:            nil,
+	msgTransferOffered:           transferData{},
+	msgTransferUnreachable:       transferData{},
+	msgTransferOffer:             transferData{},
+	msgTransferAccepted:          transferData{},
+	msgTransferCompleted:         transferData{},
+	msgTransferDeclined:          transferData{},
+	msgTransferRejected:          transferData{},
+	msgTransferExpired:           nil,
+	msgLinkStatsPlain:            statsData{},
+	msgMyLinksItemPlain:          myLinkData{},
+	msgLinkCardPlain:             linkCardData{},
+	msgReplyOptions:              replyOptionsData{},
+	msgNoReplyOption:             numberData{},
+	msgMenuExpired:               nil,
+	msgDialogReset:               nil,
+	msgFeatureDisabled:           nil,
+	msgAdminTokenRestored:        backendDownData{},
+	msgShortenOptionsIgnored:     shortenWarningsData{},
+	msgInspectUsage:              nil,
+	msgInspectLink:               inspectData{},
+	msgInspectConfirmDisable:     linkData{},
+	msgInspectConfirmDelete:      linkData{},
+	msgInspectCancelled:          nil,
+	msgAuditFailed:               nil,
+	msgTimezoneUsage:             timezoneData{},
+	msgTimezoneSet:               timezoneData{},
+	msgInvalidTimezone:           timezoneData{},
+	msgSendTitle:                 linkData{},
+	msgTitleSet:                  titleData{},
+	msgTitleCreationOnly:         nil,
+	msgPickLinkExpiry:            linkData{},
+	msgSendPath:                  urlData{},
+	msgShortcutExpired:           nil,
+	msgSettingsExported:          nil,
+	msgSendSettingsFile:          limitData{},
+	msgSettingsFileTooLarge:      limitData{},
+	msgSettingsFileNewer:         nil,
+	msgSettingsFileInvalid:       errorData{},
+	msgSettingsFileForeignLinks:  foreignLinksData{},
+	msgSettingsImportUnchanged:   nil,
+	msgSettingsImportPreview:     importData{},
+	msgSettingsImported:          nil,
+	msgSettingsImportCancelled:   nil,
+	msgNotMonitored:              nil,
+	msgDestinationChecked:        checkData{},
+	msgCampaignUsage:             nil,
+	msgNoCampaigns:               nil,
+	msgCampaigns:                 campaignsData{},
+	msgInvalidCampaignName:       nil,
+	msgInvalidCampaignDates:      nil,
+	msgCampaignExists:            campaignData{},
+	msgCampaignLimitReached:      limitData{},
+	msgCampaignCreated:           campaignData{},
+	msgCampaignNotFound:          campaignData{},
+	msgCampaignDeleted:           campaignData{},
+	msgPickCampaign:              linkData{},
+	msgCampaignFull:              limitData{},
+	msgToastAddedToCampaign:      campaignData{},
+	msgToastRemovedFromCampaign:  campaignData{},
+	msgCampaignEmpty:             campaignData{},
+	msgCampaignReport:            campaignReportData{},
+	msgReportUsage:               nil,
+	msgReportReceived:            nil,
+	msgAdminAbuseReport:          reportData{},
+	msgSchedulingUnsupported:     nil,
+	msgNotBeforePast:             nil,
+	msgNotBeforeAfterExpiry:      nil,
+	msgInvalidNotBefore:          nil,
+	msgUnscheduledLinkKept:       aliasData{},
+	msgSendNotBefore:             timezoneData{},
+	msgSendURLScheduled:          scheduleData{},
+	msgBulkActions:               bulkData{},
+	msgBulkConfirmExtend:         countData{},
+	msgBulkConfirmDisable:        countData{},
+	msgBulkConfirmAdd:            bulkCampaignData{},
+	msgBulkConfirmRemove:         bulkCampaignData{},
+	msgBulkProgress:              bulkProgressData{},
+	msgBulkDone:                  bulkDoneData{},
+	msgBulkRunning:               nil,
+	msgBulkCancelled:             nil,
+	msgAdminUpdatesStalled:       backendDownData{},
+	msgAdminUpdatesRecovered:     backendDownData{},
+	msgSendPreviewTitle:          previewPromptData{},
+	msgSendPreviewDescription:    limitData{},
+	msgSendPreviewImage:          limitData{},
+	msgPreviewImageTooLarge:      limitData{},
+	msgPreviewImageInvalid:       nil,
+	msgPreviewSet:                customPreviewData{},
+	msgPreviewRemoved:            linkData{},
+	msgAdminUsageSummary:         usageSummaryData{},
+	msgChannelUsage:              nil,
+	msgChannelNotFound:           nil,
+	msgChannelAdminsOnly:         nil,
+	msgChannelCantEdit:           nil,
+	msgChannelNoDiscussion:       nil,
+	msgChannelOn:                 channelData{},
+	msgChannelOff:                channelData{},
+	msgMaintenance:               maintenanceData{},
+	msgMaintenanceBanner:         maintenanceData{},
+	msgMaintenanceAnnouncement:   maintenanceData{},
+	msgMaintenanceUsage:          nil,
+	msgMaintenanceInPast:         nil,
+	msgMaintenanceOn:             maintenanceData{},
+	msgMaintenanceScheduled:      maintenanceData{},
+	msgMaintenanceOff:            nil,
+	msgMaintenanceNone:           nil,
+	msgAdminMaintenanceStarted:   maintenanceData{},
+	msgAdminMaintenanceEnded:     nil,
+	msgCompareUsage:              nil,
+	msgCompareSameLink:           nil,
+	msgCompareNotOwned:           linkData{},
+	msgSendCompareAlias:          linkData{},
+	msgCompareLinks:              compareData{},
+	msgCompareLinksPlain:         compareData{},
+	msgLinkPeek:                  peekData{},
+	msgPeekNotFound:              nil,
+	msgPeekUnavailable:           nil,
+	msgConfirmCommand:            confirmData{},
+	msgConfirmWrongPhrase:        confirmData{},
+	msgConfirmExpired:            confirmData{},
+	msgConfirmNoApprover:         confirmData{},
+	msgConfirmAwaitingApproval:   confirmData{},
+	msgApproveCommand:            confirmData{},
+	msgCommandApproved:           confirmData{},
+	msgCommandRejected:           confirmData{},
+	msgCommandApprovedBy:         confirmData{},
+	msgCommandRejectedBy:         confirmData{},
+	msgApprovalTimedOut:          confirmData{},
+	msgConfirmClosed:             nil,
+	msgApproveOwnCommand:         nil,
+}
+
+//go:embed templates/messages.tmpl
+var defaultMessageTemplates string
+
+// messageTemplates holds the parsed message templates.
+type messageTemplates struct {
+	mu   sync.RWMutex
+	file string
+	tmpl *template.Template
+}
+
+// newMessageTemplates loads the default templates overridden by file, if set.
+func newMessageTemplates(file string) (*messageTemplates, error) {
+	m := &messageTemplates{file: file}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload re-reads the template file. The current templates are kept when the
+// file is invalid.
+func (m *messageTemplates) Reload() error {
+	tmpl, err := parseMessageTemplates(m.file)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.tmpl = tmpl
+	m.mu.Unlock()
+	return nil
+}
+
+// Render executes the named template with data.
+func (m *messageTemplates) Render(name string, data any) (string, error) {
+	m.mu.RLock()
+	tmpl := m.tmpl
+	m.mu.RUnlock()
+
+	var sb strings.Builder
+	if err := tmpl.ExecuteTemplate(&sb, name, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func parseMessageTemplates(file string) (*template.Template, error) {
+	tmpl, err := template.New("messages").Parse(defaultMessageTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse default message templates: %w", err)
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message templates: %w", err)
+		}
+		custom, err := template.New(filepath.Base(file)).Parse(string(data))
+		if err != nil {
+			if name := enclosingDefine(string(data), err); name != "" {
+				return nil, fmt.Errorf("failed to parse message template %q: %w", name, err)
+			}
+			return nil, fmt.Errorf("failed to parse message templates: %w", err)
+		}
+		for _, t := range custom.Templates() {
+			if t.Name() == custom.Name() {
+				continue
+			}
+			if _, ok := messageData[t.Name()]; !ok {
+				return nil, fmt.Errorf("unknown message template %q in %s", t.Name(), file)
+			}
+			if _, err := tmpl.AddParseTree(t.Name(), t.Tree); err != nil {
+				return nil, fmt.Errorf("message template %q: %w", t.Name(), err)
+			}
+		}
+	}
+
+	for name, data := range messageData {
+		t := tmpl.Lookup(name)
+		if t == nil {
+			return nil, fmt.Errorf("message template %q is missing", name)
+		}
+		if err := t.Execute(io.Discard, data); err != nil {
+			return nil, fmt.Errorf("message template %q: %w", name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+var (
+	parseErrorLineRegex = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+	defineRegex         = regexp.MustCompile(`\{\{-?\s*define\s+"([^"]+)"`)
+)
+
+// enclosingDefine returns the name of the {{define}} block that the parse
+// error err points into, or "" if it can't be determined.
+func enclosingDefine(src string, err error) string {
+	m := parseErrorLineRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return ""
+	}
+	line, _ := strconv.Atoi(m[1])
+	lines := strings.Split(src, "\n")
+	for i := min(line, len(lines)) - 1; i >= 0; i-- {
+		if d := defineRegex.FindAllStringSubmatch(lines[i], -1); d != nil {
+			return d[len(d)-1][1]
+		}
+	}
+	return ""
+}
+
+// render returns the text of the named message. Templates are validated on
+// load, so failures are only logged.
+func (b *Bot) render(name string, data any) string {
+	text, err := b.messages.Render(name, data)
+	if err != nil {
+		b.log.Error("failed to render message", zap.String("template", name), zap.Error(err))
+		return name
+	}
+	return text
+}
+
+// CheckMessageTemplates loads the built-in templates with the overrides in
+// file, reporting the error startup would fail with.
+func CheckMessageTemplates(file string) error {
+	_, err := newMessageTemplates(file)
+	return err
+}
@@ -0,0 +1,380 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/monitor"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// monitorUserAgent identifies the destination checks to the sites checked.
+const monitorUserAgent = "GURLS-Bot link monitor"
+
+// destinationCheck is the outcome of one check of a link destination.
+type destinationCheck struct {
+	// Status is the HTTP status; zero when the destination wasn't reached.
+	Status int
+	// Location is where a permanent redirect points.
+	Location string
+	Err      error
+}
+
+func (c destinationCheck) failed() bool {
+	return c.Err != nil || c.Status >= http.StatusBadRequest
+}
+
+func (c destinationCheck) moved() bool {
+	return c.Location != ""
+}
+
+// setMonitored turns the destination checks of alias on or off from the
+// stats message they were requested from. Notifications go to the user who
+// turned them on.
+func (b *Bot) setMonitored(ctx context.Context, r *Request, alias string, on bool) error {
+	if !on {
+		if err := b.monitors.Remove(alias); err != nil {
+			b.log.Error("failed to save monitored links", zap.Error(err))
+			r.Answer.alert(b.render(msgInternalError, nil))
+			return nil
+		}
+		b.healthBadges.Forget(r.ChatID)
+		r.Answer.toast(b.render(msgToastUnmonitored, nil))
+		return b.refreshStatsKeyboard(r, alias)
+	}
+
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	entry := monitor.Entry{Alias: alias, OwnerID: r.ChatID, URL: stats.GetOriginalUrl(), NextCheck: time.Now()}
+	err = b.monitors.Add(entry, b.config.Monitor.MaxPerUser)
+	switch {
+	case errors.Is(err, monitor.ErrLimit):
+		r.Answer.alert(b.render(msgMonitorLimitReached, limitData{Limit: b.config.Monitor.MaxPerUser}))
+		return nil
+	case err != nil:
+		b.log.Error("failed to save monitored links", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	b.healthBadges.Forget(r.ChatID)
+	r.Answer.toast(b.render(msgToastMonitoring, nil))
+	return b.refreshStatsKeyboard(r, alias)
+}
+
+// refreshStatsKeyboard redraws the stats keyboard r was pressed on.
+func (b *Bot) refreshStatsKeyboard(r *Request, alias string) error {
+	edit := tgbotapi.NewEditMessageReplyMarkup(r.ChatID, r.Message.MessageID, b.createStatsKeyboard(r.Payloads(), alias))
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// runDestinationChecks checks the monitored destinations as they come due
+// until ctx is done.
+func (b *Bot) runDestinationChecks(ctx context.Context) {
+	client := b.monitorClient()
+	ticker := time.NewTicker(b.config.Monitor.Poll)
+	defer ticker.Stop()
+	for {
+		b.checkDestinations(ctx, client, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitorClient returns the HTTP client destinations are checked with,
+// which doesn't follow redirects.
+func (b *Bot) monitorClient() *http.Client {
+	return &http.Client{
+		Timeout: b.config.Monitor.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkDestinations checks the destinations due at now. Hosts are checked
+// in parallel, the links on one host one after another with a pause in
+// between.
+func (b *Bot) checkDestinations(ctx context.Context, client *http.Client, now time.Time) {
+	due := b.monitors.Due(now)
+	if len(due) == 0 {
+		return
+	}
+	var hosts []string
+	byHost := make(map[string][]monitor.Entry)
+	for _, entry := range due {
+		host := domainHost(entry.URL)
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], entry)
+	}
+
+	var g errgroup.Group
+	g.SetLimit(b.config.Monitor.Workers)
+	for _, host := range hosts {
+		entries := byHost[host]
+		g.Go(func() error {
+			for i, entry := range entries {
+				if i > 0 {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-time.After(b.config.Monitor.HostDelay):
+					}
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				b.checkDestination(ctx, client, entry)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	if err := b.monitors.Save(); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+}
+
+// checkDestination checks the destination of a monitored link, tells the
+// owner about a persistent failure or a permanent redirect once, and
+// schedules the next check.
+func (b *Bot) checkDestination(ctx context.Context, client *http.Client, entry monitor.Entry) {
+	statsCtx, cancel := context.WithTimeout(ctx, b.config.GRPCClient.Timeout)
+	stats, err := b.grpcClient.GetLinkStats(statsCtx, &shortenerv1.GetLinkStatsRequest{Alias: entry.Alias})
+	cancel()
+	switch {
+	case status.Code(err) == codes.NotFound:
+		if err := b.monitors.Remove(entry.Alias); err != nil {
+			b.log.Error("failed to save monitored links", zap.Error(err))
+		}
+		return
+	case err != nil:
+		// Checked again next round, when the backend is hopefully back
+		b.log.Debug("failed to refresh monitored link", zap.String("alias", entry.Alias), zap.Error(err))
+		return
+	}
+	if dest := stats.GetOriginalUrl(); dest != entry.URL {
+		// Updated elsewhere; the old destination's record doesn't apply
+		entry = monitor.Entry{Alias: entry.Alias, OwnerID: entry.OwnerID, URL: dest}
+	}
+
+	check := b.probeDestination(ctx, client, entry.URL)
+	if ctx.Err() != nil {
+		return
+	}
+	now := time.Now()
+	entry.LastStatus = check.Status
+	entry.LastCheck = now
+	entry.NextCheck = now.Add(b.config.Monitor.Interval)
+	switch {
+	case check.moved():
+		entry.Failures = 0
+		if !entry.Alerted {
+			entry.Alerted = b.notifyDestination(entry, stats.GetDomain(), check) == nil
+		}
+	case check.failed():
+		entry.Failures++
+		entry.NextCheck = now.Add(b.monitorBackoff(entry.Failures))
+		if entry.Failures >= b.config.Monitor.Failures && !entry.Alerted {
+			entry.Alerted = b.notifyDestination(entry, stats.GetDomain(), check) == nil
+		}
+	default:
+		entry.Failures = 0
+		entry.Alerted = false
+	}
+	b.monitors.Update(entry)
+	b.healthBadges.Forget(entry.OwnerID)
+}
+
+// monitorBackoff returns when to check again after failures failed checks
+// in a row: the regular interval until the owner is told, then doubling
+// with each failure up to the configured cap.
+func (b *Bot) monitorBackoff(failures int) time.Duration {
+	cfg := b.config.Monitor
+	wait := cfg.Interval
+	for i := cfg.Failures; i <= failures && wait < cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, cfg.MaxBackoff)
+}
+
+// probeDestination asks for rawURL without following redirects or reading
+// the body, trying HEAD first and falling back to GET for servers that
+// don't support it.
+func (b *Bot) probeDestination(ctx context.Context, client *http.Client, rawURL string) destinationCheck {
+	var check destinationCheck
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return destinationCheck{Err: err}
+		}
+		req.Header.Set("User-Agent", monitorUserAgent)
+		resp, err := client.Do(req)
+		if err != nil {
+			return destinationCheck{Err: err}
+		}
+		resp.Body.Close()
+		check = destinationCheck{Status: resp.StatusCode}
+		if resp.StatusCode == http.StatusMovedPermanently || resp.StatusCode == http.StatusPermanentRedirect {
+			if location, err := resp.Location(); err == nil {
+				check.Location = location.String()
+			}
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	return check
+}
+
+// notifyDestination tells the owner of a monitored link what went wrong
+// with its destination.
+func (b *Bot) notifyDestination(entry monitor.Entry, domain string, check destinationCheck) error {
+	data := destinationData{
+		ShortURL: displayURL(b.shortURLOn(domain, entry.Alias)),
+		URL:      entry.URL,
+		Status:   check.Status,
+		Failures: entry.Failures,
+		Redirect: check.Location,
+	}
+	name := msgDestinationFailing
+	if check.moved() {
+		name = msgDestinationMoved
+	}
+	msg := tgbotapi.NewMessage(entry.OwnerID, b.render(name, data))
+	msg.ReplyMarkup = b.createDestinationKeyboard(entry.OwnerID, entry.Alias, check.Location)
+	msg.DisableWebPagePreview = true
+	// A check repeated after a restart, before the alert was recorded,
+	// doesn't alert twice; the same problem is reported once a day at most
+	key := fmt.Sprintf("destination/%s/%s/%s", entry.Alias, name, time.Now().Format(time.DateOnly))
+	err := b.sendCritical(key, msg, b.notification(entry.OwnerID))
+	if err != nil {
+		b.log.Warn("failed to notify about destination", zap.String("alias", entry.Alias), zap.Error(err))
+	}
+	return err
+}
+
+// createDestinationKeyboard offers the fixes for a failing destination the
+// backend supports. redirect is the new location of a moved destination.
+func (b *Bot) createDestinationKeyboard(chatID int64, alias, redirect string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if b.supports(featureUpdateDestination) {
+		if redirect != "" {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				b.storedPayloadButton(chatPayloads(chatID), "Use new URL", actionUseRedirect, alias+"\n"+redirect),
+			))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(chatPayloads(chatID), "Update destination", actionNewDestination, alias),
+		))
+	}
+	manage := tgbotapi.NewInlineKeyboardRow()
+	if b.supports(featureExtend) {
+		manage = append(manage, b.payloadButton(chatPayloads(chatID), "Disable link", actionDisableLink, alias))
+	}
+	manage = append(manage, b.payloadButton(chatPayloads(chatID), "Stop monitoring", actionUnmonitor, alias))
+	rows = append(rows, manage, tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(chatPayloads(chatID), "Stats", actionStats, alias),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// startNewDestination asks for the new destination of alias.
+func (b *Bot) startNewDestination(chatID int64, alias string) error {
+	if err := b.startDialog(chatID, UserState{State: StateWaitingForNewDestination, Payload: destinationPayload{Alias: alias}}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(chatID, msgSendNewDestination, linkData{ShortURL: displayURL(b.shortURL(alias))}, b.createCancelKeyboard())
+}
+
+// handleNewDestinationInput points the link at the URL sent. The user stays
+// at the prompt when the message has no URL.
+func (b *Bot) handleNewDestinationInput(ctx context.Context, to payloadKey, alias, text string) error {
+	dest := urlRegex.FindString(text)
+	if dest == "" {
+		return b.reply(to.chatID, msgInvalidDestination, nil)
+	}
+	b.resetUserState(to.chatID)
+	return b.updateDestination(ctx, to, alias, dest)
+}
+
+// useRedirect points a link at the location its destination moved to.
+func (b *Bot) useRedirect(ctx context.Context, r *Request) error {
+	alias, dest, ok := strings.Cut(r.Args, "\n")
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	return b.updateDestination(ctx, r.Payloads(), alias, dest)
+}
+
+// updateDestination points alias at dest after the checks new links go
+// through, and starts the monitoring of the link over.
+func (b *Bot) updateDestination(ctx context.Context, to payloadKey, alias, dest string) error {
+	if u, err := url.Parse(dest); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return b.reply(to.chatID, msgInvalidDestination, nil)
+	}
+	if b.isBlockedURL(dest) {
+		return b.reply(to.chatID, msgBlockedDomain, nil)
+	}
+	if ok, err := b.checkURLSafety(ctx, to.chatID, to.chatID, dest); !ok {
+		return err
+	}
+
+	res, err := b.grpcClient.UpdateLink(ctx, &shortenerv1.UpdateLinkRequest{Alias: alias, UserTgId: to.chatID, OriginalUrl: dest})
+	if err != nil {
+		b.log.Error("gRPC UpdateLink failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(to.chatID, err, alias)
+	}
+	if updated := res.GetOriginalUrl(); updated != "" {
+		dest = updated
+	}
+	if err := b.monitors.Retarget(alias, dest, time.Now().Add(b.config.Monitor.Interval)); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	b.healthBadges.Forget(to.chatID)
+	text := b.render(msgDestinationUpdated, destinationData{ShortURL: b.shortURL(alias), URL: dest})
+	return b.sendMessageWithKeyboard(to.chatID, text, b.createStatsKeyboard(to, alias), b.linkContent())
+}
+
+// disableLink makes alias expire now, so it stops redirecting while its
+// stats stay around, and stops monitoring it.
+func (b *Bot) disableLink(ctx context.Context, r *Request) error {
+	alias := r.Args
+	_, err := b.grpcClient.SetLinkExpiry(ctx, &shortenerv1.SetLinkExpiryRequest{
+		Alias:     alias,
+		UserTgId:  r.UserID,
+		ExpiresAt: timestamppb.Now(),
+	})
+	if err != nil {
+		b.log.Error("gRPC SetLinkExpiry failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	if err := b.monitors.Remove(alias); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	b.healthBadges.Forget(r.ChatID)
+	text := b.render(msgLinkDisabled, linkData{ShortURL: displayURL(b.shortURL(alias))})
+	return b.editMessageText(r.ChatID, r.Message.MessageID, text)
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"GURLS-Bot/internal/outbox"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// sendCritical sends msg, a notification that must not be lost to a
+// restart, through the outbox: it is written to disk first, and sent again
+// by runOutbox until Telegram takes it. key identifies the notification; a
+// key already in the outbox, delivered or not, isn't sent again. Failed
+// sends that are retried count as success.
+//
+// Messages whose buttons refer to in-memory state, such as transfer offers,
+// don't belong here: after a restart the buttons would be dead.
+func (b *Bot) sendCritical(key string, msg tgbotapi.MessageConfig, opts ...sendOption) error {
+	var o sendOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stored := outbox.Message{
+		ChatID:    msg.ChatID,
+		Text:      msg.Text,
+		ParseMode: msg.ParseMode,
+		NoPreview: msg.DisableWebPagePreview,
+		Protect:   o.protect,
+	}
+	if keyboard, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+		stored.Keyboard = &keyboard
+	}
+
+	added, err := b.outbox.Add(key, stored, time.Now())
+	switch {
+	case err != nil:
+		b.log.Warn("notification not kept in the outbox, sending it anyway", zap.String("key", key), zap.Error(err))
+		_, err := b.send(msg, opts...)
+		return err
+	case !added:
+		b.log.Debug("notification already in the outbox", zap.String("key", key))
+		return nil
+	}
+	metrics.OutboxDepth.Set(int64(b.outbox.Depth()))
+	_ = b.deliver(key, msg, opts...)
+	return nil
+}
+
+// deliver sends msg, claimed in the outbox under key, and marks it
+// delivered unless the failure is worth a retry. Failures are logged here.
+func (b *Bot) deliver(key string, msg tgbotapi.MessageConfig, opts ...sendOption) error {
+	_, err := b.send(msg, opts...)
+	switch {
+	case err != nil && !permanentSendError(err):
+		b.outbox.Release(key)
+		b.log.Warn("failed to send notification, will retry", zap.String("key", key), zap.Error(err))
+		return err
+	case err != nil:
+		b.log.Warn("dropping undeliverable notification", zap.String("key", key), zap.Error(err))
+	}
+	if err := b.outbox.Delivered(key, time.Now()); err != nil {
+		b.log.Error("failed to save outbox", zap.Error(err))
+	}
+	metrics.OutboxDepth.Set(int64(b.outbox.Depth()))
+	return err
+}
+
+// permanentSendError reports whether sending again can't help: the user
+// blocked the bot, or Telegram refuses the message itself.
+func permanentSendError(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+	return tgErr.Code == http.StatusBadRequest || tgErr.Code == http.StatusForbidden
+}
+
+// runOutbox sends the notifications left in the outbox by a restart, then
+// keeps retrying failed ones every Outbox.RetryInterval and drops those
+// older than Outbox.MaxAge.
+func (b *Bot) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(b.config.Outbox.RetryInterval)
+	defer ticker.Stop()
+	for {
+		b.replayOutbox(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replayOutbox sends the undelivered notifications nobody else is sending.
+func (b *Bot) replayOutbox(ctx context.Context) {
+	expired, err := b.outbox.Expire(time.Now().Add(-b.config.Outbox.MaxAge))
+	if err != nil {
+		b.log.Error("failed to save outbox", zap.Error(err))
+	}
+	if expired > 0 {
+		metrics.OutboxExpired.Add(int64(expired))
+		b.log.Warn("dropped stale notifications from the outbox", zap.Int("count", expired))
+	}
+
+	for _, entry := range b.outbox.Claim() {
+		if ctx.Err() != nil {
+			b.outbox.Release(entry.Key)
+			continue
+		}
+		msg := tgbotapi.NewMessage(entry.Message.ChatID, entry.Message.Text)
+		msg.ParseMode = entry.Message.ParseMode
+		msg.DisableWebPagePreview = entry.Message.NoPreview
+		if entry.Message.Keyboard != nil {
+			msg.ReplyMarkup = *entry.Message.Keyboard
+		}
+		if err := b.deliver(entry.Key, msg, b.notification(msg.ChatID), protected(entry.Message.Protect)); err == nil {
+			metrics.OutboxReplayed.Add(1)
+		}
+	}
+	metrics.OutboxDepth.Set(int64(b.outbox.Depth()))
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/outbox"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// waitOutboxDepth waits for the outbox to hold depth undelivered entries.
+func (e *e2e) waitOutboxDepth(t *testing.T, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for e.bot.outbox.Depth() != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox depth = %d, want %d", e.bot.outbox.Depth(), depth)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sentTexts counts the messages Telegram took for user with text.
+func (e *e2e) sentTexts(text string) int {
+	n := 0
+	for _, r := range e.tg.Requests("sendMessage") {
+		if r.ChatID() == user && r.Text() == text && r.MessageID != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOutboxReplaysAfterCrash(t *testing.T) {
+	const pending, sent = "Queued before the crash", "Sent before the crash"
+	cfg := testConfig(t)
+	// The previous process added both, then exited before sending the
+	// first
+	db, err := openStore(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifications, err := outbox.Open(db, cfg.Outbox.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{pending, sent} {
+		if _, err := notifications.Add(text, outbox.Message{ChatID: user, Text: text}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := notifications.Delivered(sent, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	e := startBot(t, cfg)
+	e.tg.WaitText(user, pending)
+	e.waitOutboxDepth(t, 0)
+
+	// The job that queued it runs again after the restart
+	for _, text := range []string{pending, sent} {
+		if err := e.bot.sendCritical(text, tgbotapi.NewMessage(user, text), e.bot.notification(user)); err != nil {
+			t.Errorf("sendCritical(%s): %v", text, err)
+		}
+	}
+	e.bot.replayOutbox(context.Background())
+	if n := e.sentTexts(pending); n != 1 {
+		t.Errorf("pending notification sent %d times, want once", n)
+	}
+	if n := e.sentTexts(sent); n != 0 {
+		t.Errorf("delivered notification sent %d times after the restart", n)
+	}
+}
+
+func TestOutboxRetriesFailedSends(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.Fail("sendMessage", http.StatusInternalServerError, "Internal Server Error")
+	if err := e.bot.sendCritical("retried", tgbotapi.NewMessage(user, "Retried"), e.bot.notification(user)); err != nil {
+		t.Errorf("sendCritical of a retried notification = %v", err)
+	}
+	if depth := e.bot.outbox.Depth(); depth != 1 {
+		t.Fatalf("outbox depth = %d after a failed send, want 1", depth)
+	}
+	e.tg.Handle("sendMessage", nil)
+	e.bot.replayOutbox(context.Background())
+	e.bot.replayOutbox(context.Background())
+	// Delivered entries aren't sent again
+	e.bot.replayOutbox(context.Background())
+	if n := e.sentTexts("Retried"); n != 1 {
+		t.Errorf("retried notification delivered %d times, want once", n)
+	}
+
+	// A blocked bot gives up right away
+	e.tg.Fail("sendMessage", http.StatusForbidden, "Forbidden: bot was blocked by the user")
+	e.bot.sendCritical("blocked", tgbotapi.NewMessage(user, "Blocked"), e.bot.notification(user))
+	if depth := e.bot.outbox.Depth(); depth != 0 {
+		t.Errorf("outbox depth = %d after a permanent failure, want 0", depth)
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// deliveryError is the failure to deliver a message to Telegram, after the
+// send queue gave up retrying it.
+type deliveryError struct {
+	err error
+}
+
+func (e *deliveryError) Error() string {
+	return "failed to deliver message: " + e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// undelivered marks err, returned for a message sent through the send
+// queue, as a delivery failure. A nil err stays nil.
+func undelivered(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &deliveryError{err: err}
+}
+
+// Outcome is how handling a request went, telling the operation it asked
+// for apart from delivering the reply about it. A handler failing to send
+// its reply after a backend call went through has an Outcome with only
+// DeliveryErr set: the call must not be repeated, and the send queue
+// already retried the reply.
+type Outcome struct {
+	// Err is the failure of the request itself, typically a backend call.
+	Err error
+	// DeliveryErr is the failure to deliver a reply.
+	DeliveryErr error
+}
+
+// outcomeOf sorts err, returned by a handler, into an Outcome. Handlers
+// reporting both kinds of failure return them joined with errors.Join.
+func outcomeOf(err error) Outcome {
+	var o Outcome
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		var delivery *deliveryError
+		switch {
+		case err == nil:
+		case errors.As(err, &delivery):
+			o.DeliveryErr = errors.Join(o.DeliveryErr, delivery.err)
+		default:
+			o.Err = errors.Join(o.Err, err)
+		}
+	}
+	return o
+}
+
+// Retryable reports whether running the request again may help. Only
+// requests that failed themselves are; running one again whose reply
+// wasn't delivered would repeat what it did.
+func (o Outcome) Retryable() bool {
+	return o.Err != nil
+}
+
+// asError turns o back into the error outcomeOf sorted, for handlers
+// passing on the outcome of another.
+func (o Outcome) asError() error {
+	return errors.Join(o.Err, undelivered(o.DeliveryErr))
+}
+
+// logOutcome logs what went wrong handling a request. Undelivered replies
+// are warnings, the request itself having gone through.
+func (b *Bot) logOutcome(o Outcome, msg string, fields ...zap.Field) {
+	if o.Err != nil {
+		b.log.Error("failed to handle "+msg, append(fields, zap.Error(o.Err))...)
+	}
+	if o.DeliveryErr != nil {
+		metrics.UndeliveredReplies.Add(1)
+		b.log.Warn("failed to deliver reply to "+msg, append(fields, zap.Error(o.DeliveryErr))...)
+	}
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/locale"
+	"GURLS-Bot/internal/ttlmap"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// linkStatsTTL is how long fetched stats are reused for peeks, which
+	// come in quick succession while scanning a list.
+	linkStatsTTL = 30 * time.Second
+	// maxAlertLen is Telegram's limit on the text of a callback alert.
+	maxAlertLen = 200
+	// minPeekNameLen is what truncation leaves of the name of a peeked
+	// link at least.
+	minPeekNameLen = 8
+)
+
+// linkStats caches the stats of links for a short while.
+type linkStats struct {
+	stats *ttlmap.Map[string, *shortenerv1.GetLinkStatsResponse]
+}
+
+func newLinkStats(ttl time.Duration) *linkStats {
+	return &linkStats{stats: ttlmap.New[string, *shortenerv1.GetLinkStatsResponse](ttl, 0)}
+}
+
+// Get returns the stats cached for alias, fetching them when missing or
+// stale. Failures aren't cached.
+func (s *linkStats) Get(alias string, fetch func() (*shortenerv1.GetLinkStatsResponse, error)) (*shortenerv1.GetLinkStatsResponse, error) {
+	if res, ok := s.stats.Get(alias); ok {
+		return res, nil
+	}
+	res, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	s.stats.Set(alias, res)
+	return res, nil
+}
+
+// Set caches stats fetched elsewhere.
+func (s *linkStats) Set(alias string, res *shortenerv1.GetLinkStatsResponse) {
+	s.stats.Set(alias, res)
+}
+
+// peekLink answers the ℹ button of a listed link with its stats on one
+// line, in an alert. No message is sent or edited.
+func (b *Bot) peekLink(ctx context.Context, r *Request) error {
+	alias := r.Args
+	res, err := b.linkStats.Get(alias, func() (*shortenerv1.GetLinkStatsResponse, error) {
+		return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	})
+	if err != nil {
+		b.log.Info("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		if status.Code(err) == codes.NotFound {
+			r.Answer.alert(b.render(msgPeekNotFound, nil))
+		} else {
+			r.Answer.alert(b.render(msgPeekUnavailable, nil))
+		}
+		return nil
+	}
+	data, _ := newStatsData(alias, res)
+	r.Answer.alert(b.renderPeek(b.formatterFor(r.ChatID), time.Now(), data))
+	return nil
+}
+
+// renderPeek renders the stats of a link on one line that fits an alert.
+// The name of the link, its title or else its alias, is what gets cut to
+// fit, down to minPeekNameLen characters; the stats themselves always fit.
+func (b *Bot) renderPeek(f locale.Formatter, now time.Time, s statsData) string {
+	data := peekData{Locale: f, Name: s.Title, Clicks: s.Clicks, ExpiresAt: s.ExpiresAt}
+	if data.Name == "" {
+		data.Name = s.Alias
+	}
+	if s.LastClickAt != nil {
+		data.LastClick = formatRemaining(now.Sub(*s.LastClickAt)) + " ago"
+	}
+	text := b.render(msgLinkPeek, data)
+	if over := utf16Len(text) - maxAlertLen; over > 0 {
+		// Names outside the BMP count twice, so cutting runes is on the
+		// safe side
+		data.Name = truncateRunes(data.Name, max(len([]rune(data.Name))-over, minPeekNameLen))
+		text = b.render(msgLinkPeek, data)
+	}
+	return truncateRunes(text, maxAlertLen)
+}
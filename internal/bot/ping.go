@@ -0,0 +1,26 @@
+package bot
+
+import (
+	"context"
+	"time"
+)
+
+// handlePingCommand reports backend health to admins.
+func (b *Bot) handlePingCommand(ctx context.Context, r *Request) error {
+	health, err := b.grpcClient.HealthCheck(ctx)
+	if err != nil {
+		return b.sendMessage(r.ChatID, b.render(msgPingFailed, errorData{Error: err.Error()}), false)
+	}
+	return b.sendMessage(r.ChatID, b.render(msgPing, pingData{
+		Serving:        health.Serving,
+		State:          health.State.String(),
+		ConnectionOnly: health.ConnectionOnly,
+		Latency:        health.Latency.Round(time.Millisecond),
+	}), false)
+}
+
+// handleVersionCommand shows admins the running build and its feature
+// flags.
+func (b *Bot) handleVersionCommand(ctx context.Context, r *Request) error {
+	return b.sendMessage(r.ChatID, b.render(msgVersion, b.buildInfo()), false)
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"errors"
+	"slices"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTooManyPins = errors.New("pin limit reached")
+
+// isPinned reports whether chatID pinned alias.
+func (b *Bot) isPinned(chatID int64, alias string) bool {
+	return slices.Contains(b.prefs.Get(chatID).Pinned, alias)
+}
+
+// setPinned pins or unpins alias from the stats message it was requested from.
+func (b *Bot) setPinned(to payloadKey, messageID int, alias string, pinned bool, answer *callbackAnswer) error {
+	err := b.prefs.Update(to.chatID, func(p *prefs.Prefs) error {
+		if !pinned {
+			p.Pinned = slices.DeleteFunc(p.Pinned, func(a string) bool { return a == alias })
+			return nil
+		}
+		if slices.Contains(p.Pinned, alias) {
+			return nil
+		}
+		if len(p.Pinned) >= b.config.Prefs.MaxPinned {
+			return errTooManyPins
+		}
+		p.Pinned = append(p.Pinned, alias)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTooManyPins):
+		answer.alert(b.render(msgPinLimitReached, limitData{Limit: b.config.Prefs.MaxPinned}))
+		return nil
+	case err != nil:
+		b.log.Error("failed to save preferences", zap.Error(err))
+		answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+
+	if pinned {
+		answer.toast(b.render(msgToastPinned, nil))
+	} else {
+		answer.toast(b.render(msgToastUnpinned, nil))
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(to.chatID, messageID, b.createStatsKeyboard(to, alias))
+	return b.editMessage(edit, answer, nil)
+}
+
+// unpinDeleted drops pins of aliases that no longer exist. keep reports
+// whether an alias is still present.
+func (b *Bot) unpinDeleted(chatID int64, keep func(alias string) bool) {
+	current := b.prefs.Get(chatID).Pinned
+	if !slices.ContainsFunc(current, func(a string) bool { return !keep(a) }) {
+		return
+	}
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		p.Pinned = slices.DeleteFunc(p.Pinned, func(a string) bool { return !keep(a) })
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+	}
+}
+
+// unpin removes alias from the pins of chatID after the link was deleted.
+func (b *Bot) unpin(chatID int64, alias string) {
+	b.unpinDeleted(chatID, func(a string) bool { return a != alias })
+}
+
+// pinnedLinks fetches the pinned links of chatID, which may be on any page
+// of the link list, in pin order. Pins of deleted links are dropped; links
+// that can't be fetched right now are left out.
+func (b *Bot) pinnedLinks(ctx context.Context, chatID int64) []*shortenerv1.LinkInfo {
+	pins := b.prefs.Get(chatID).Pinned
+	results := fanOut(ctx, fanOutOptions{}, pins, func(ctx context.Context, alias string) (*shortenerv1.GetLinkStatsResponse, error) {
+		return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	})
+	var links []*shortenerv1.LinkInfo
+	deleted := make(map[string]bool)
+	for _, res := range results {
+		switch {
+		case status.Code(res.Err) == codes.NotFound:
+			deleted[res.Item] = true
+		case res.Err != nil:
+			b.log.Debug("failed to fetch pinned link", zap.String("alias", res.Item), zap.Error(res.Err))
+		default:
+			links = append(links, &shortenerv1.LinkInfo{
+				Alias:       res.Item,
+				OriginalUrl: res.Value.GetOriginalUrl(),
+				Title:       res.Value.Title,
+				Domain:      res.Value.Domain,
+				Source:      res.Value.Source,
+			})
+		}
+	}
+	if len(deleted) > 0 {
+		b.unpinDeleted(chatID, func(alias string) bool { return !deleted[alias] })
+	}
+	return links
+}
@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// outputStyle is how messages to a chat are rendered.
+type outputStyle int
+
+const (
+	// styleRich is the default output, with formatting where it helps.
+	styleRich outputStyle = iota
+	// stylePlain is for screen readers: no emoji or monospace blocks,
+	// explicit labels, and keyboards repeated as numbered options the user
+	// can reply with.
+	stylePlain
+)
+
+// outputStyle returns the output style of chatID.
+func (b *Bot) outputStyle(chatID int64) outputStyle {
+	if b.prefs.Get(chatID).PlainOutput {
+		return stylePlain
+	}
+	return styleRich
+}
+
+// replyOptions is the last keyboard sent to a chat in plain output, whose
+// buttons the user can press by replying with their number. The message is
+// kept as rendered before the options were added, so they can be listed
+// anew when only the keyboard changes.
+type replyOptions struct {
+	MessageID int
+	Text      string
+	ParseMode string
+	NoPreview bool
+	Keyboard  tgbotapi.InlineKeyboardMarkup
+}
+
+// optionButtons returns the buttons of keyboard that can be pressed by
+// number, in reading order. Buttons opening URLs or inline mode aren't
+// callbacks and are left out.
+func optionButtons(keyboard tgbotapi.InlineKeyboardMarkup) []tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				buttons = append(buttons, button)
+			}
+		}
+	}
+	return buttons
+}
+
+// plainText turns text rendered for chats in the rich style into plain
+// output: emoji are removed and the buttons of keyboard, if any, are listed
+// as numbered options. isHTML tells whether text is sent as HTML.
+func (b *Bot) plainText(text string, keyboard *tgbotapi.InlineKeyboardMarkup, isHTML bool) string {
+	if keyboard != nil {
+		var data replyOptionsData
+		for i, button := range optionButtons(*keyboard) {
+			data.Options = append(data.Options, replyOption{Number: i + 1, Label: button.Text})
+		}
+		if len(data.Options) > 0 {
+			options := b.render(msgReplyOptions, data)
+			if isHTML {
+				options = html.EscapeString(options)
+			}
+			text += "\n\n" + options
+		}
+	}
+	return stripEmoji(text)
+}
+
+// plainEdit is plainText for edits. A new keyboard replaces the options
+// remembered for the chat. As the options are part of the text, an edit of
+// the keyboard alone becomes an edit of the text when the text is known; on
+// older messages the listed labels stay as they were.
+func (b *Bot) plainEdit(edit tgbotapi.Chattable) tgbotapi.Chattable {
+	switch e := edit.(type) {
+	case tgbotapi.EditMessageTextConfig:
+		if e.ReplyMarkup != nil {
+			b.replyOptions.Set(e.ChatID, replyOptions{
+				MessageID: e.MessageID,
+				Text:      e.Text,
+				ParseMode: e.ParseMode,
+				NoPreview: e.DisableWebPagePreview,
+				Keyboard:  *e.ReplyMarkup,
+			})
+		}
+		e.Text = b.plainText(e.Text, e.ReplyMarkup, e.ParseMode == tgbotapi.ModeHTML)
+		return e
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		if e.ReplyMarkup == nil {
+			return edit
+		}
+		options, ok := b.replyOptions.Get(e.ChatID)
+		if !ok || options.MessageID != e.MessageID {
+			b.replyOptions.Set(e.ChatID, replyOptions{MessageID: e.MessageID, Keyboard: *e.ReplyMarkup})
+			return edit
+		}
+		text := tgbotapi.NewEditMessageTextAndMarkup(e.ChatID, e.MessageID, options.Text, *e.ReplyMarkup)
+		text.ParseMode = options.ParseMode
+		text.DisableWebPagePreview = options.NoPreview
+		return b.plainEdit(text)
+	}
+	return edit
+}
+
+// isEmoji reports whether r is an emoji or a pictograph a screen reader
+// would read out by name, such as arrows.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF,
+		r >= 0x2190 && r <= 0x21FF,
+		r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2600 && r <= 0x27BF,
+		r >= 0x2B00 && r <= 0x2BFF,
+		r == 0x2139, r == 0x200D, r == 0xFE0F:
+		return true
+	}
+	return false
+}
+
+// stripEmoji removes emoji from s. Lines that had some have their spacing
+// normalized, as emoji usually come with a space.
+func stripEmoji(s string) string {
+	if !strings.ContainsFunc(s, isEmoji) {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.ContainsFunc(line, isEmoji) {
+			lines[i] = strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+				if isEmoji(r) {
+					return -1
+				}
+				return r
+			}, line)), " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pressReplyOption handles a number sent in a private chat in plain output
+// as a press of the button with that number on the last keyboard. It
+// reports whether msg was such a number.
+func (b *Bot) pressReplyOption(ctx context.Context, msg *tgbotapi.Message) (bool, error) {
+	chatID := msg.Chat.ID
+	if !msg.Chat.IsPrivate() || b.outputStyle(chatID) != stylePlain {
+		return false, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(msg.Text), "."))
+	if err != nil {
+		return false, nil
+	}
+	options, ok := b.replyOptions.Get(chatID)
+	buttons := optionButtons(options.Keyboard)
+	if !ok || n < 1 || n > len(buttons) {
+		return true, b.reply(chatID, msgNoReplyOption, numberData{Number: n})
+	}
+	keyboard := options.Keyboard
+	return true, b.handleCallbackQuery(ctx, &tgbotapi.CallbackQuery{
+		From: msg.From,
+		Message: &tgbotapi.Message{
+			MessageID:   options.MessageID,
+			Chat:        msg.Chat,
+			ReplyMarkup: &keyboard,
+		},
+		Data: *buttons[n-1].CallbackData,
+	}).asError()
+}
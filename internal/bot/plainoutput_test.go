@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/telegramtest"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the output snapshots in testdata/output")
+
+// snapshot renders a sent message as checked against the snapshots: its
+// parse mode, text and button labels.
+func snapshot(r telegramtest.Request) string {
+	var b strings.Builder
+	b.WriteString("parse_mode: " + r.Param("parse_mode") + "\n\n")
+	b.WriteString(r.Text() + "\n")
+	if buttons := r.Buttons(); len(buttons) > 0 {
+		b.WriteString("\nbuttons:\n")
+		for _, button := range buttons {
+			b.WriteString("  " + button.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+// checkSnapshot compares got with the snapshot in testdata/output/name.txt,
+// or rewrites it with -update.
+func checkSnapshot(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "output", name+".txt")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the tests with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s changed; run the tests with -update if intended.\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestOutputStyles(t *testing.T) {
+	// The snapshots are read relative to the package, not to the temporary
+	// directory the bot runs in
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		// linkStyle is the link style of the user, if set
+		linkStyle string
+		send      string
+		wait      string
+	}{
+		{name: "stats", send: "/stats mine", wait: "mine"},
+		{name: "my_links", send: "/my_links", wait: "Your Links"},
+		{name: "created", send: `/shorten https://example.com/page title="My page"`, wait: "localhost:8080/gen1"},
+		{name: "card", linkStyle: linkStyleCard, send: `/shorten https://example.com/page title="My page"`, wait: "localhost:8080/gen1"},
+	} {
+		for style, plain := range map[string]bool{"rich": false, "plain": true} {
+			t.Run(tt.name+"/"+style, func(t *testing.T) {
+				e := startBot(t, nil)
+				e.backend.Add(
+					backendtest.Link{Alias: "mine", OriginalURL: "https://example.com/mine", Title: "Mine", OwnerID: user, Clicks: 1234},
+					backendtest.Link{Alias: "other", OriginalURL: "https://example.com/other", OwnerID: user},
+				)
+				err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+					p.PlainOutput = plain
+					p.LinkStyle = tt.linkStyle
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				e.tg.SendMessage(user, tt.send)
+				got := snapshot(e.tg.WaitText(user, tt.wait))
+				t.Chdir(dir)
+				checkSnapshot(t, tt.name+"-"+style, got)
+			})
+		}
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	for in, want := range map[string]string{
+		"No emoji here":          "No emoji here",
+		"🔗 Link created ✅":       "Link created",
+		"ℹ️ Details\n  indented": "Details\n  indented",
+		"👨‍👩‍👧 Family":           "Family",
+		"→ example.com":          "example.com",
+	} {
+		if got := stripEmoji(in); got != want {
+			t.Errorf("stripEmoji(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestE2EPlainReplyOption(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{Alias: "mine", OriginalURL: "https://example.com/mine", OwnerID: user})
+	err := e.bot.prefs.Update(user, func(p *prefs.Prefs) error {
+		p.PlainOutput = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.tg.SendMessage(user, "/stats mine")
+	e.tg.WaitText(user, "12. My Links")
+	e.tg.SendMessage(user, "12")
+	e.tg.WaitText(user, "Your Links")
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"encoding/json"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// submitOrPreview creates the link of a request that passed all checks and
+// defaults, or shows it first to users who asked for a preview. The preview
+// shows the options itself, so summary only goes with links created at once.
+func (b *Bot) submitOrPreview(ctx context.Context, to payloadKey, req *shortenerv1.CreateLinkRequest, summary string) (bool, error) {
+	if !b.prefs.Get(req.GetUserTgId()).Defaults.Preview {
+		return b.submitLink(ctx, to, req, summary)
+	}
+	return false, b.previewLink(to, req)
+}
+
+// previewLink shows the exact record req would create. The request waits
+// in the payload store, so the buttons expire with it.
+func (b *Bot) previewLink(to payloadKey, req *shortenerv1.CreateLinkRequest) error {
+	payload, err := json.Marshal(newQueuedLink(to.chatID, req))
+	if err != nil {
+		return err
+	}
+	data := createPreviewData{
+		Locale:           b.formatterFor(to.chatID),
+		URL:              req.GetOriginalUrl(),
+		Title:            req.GetTitle(),
+		Alias:            req.GetCustomAlias(),
+		Domain:           domainHost(b.shortURLOn(req.GetDomain(), "")),
+		MinimalAnalytics: req.GetMinimalAnalytics(),
+	}
+	if style := req.GetAliasStyle(); style != "" && style != b.aliasStyle(b.prefs.Get(req.GetUserTgId())) {
+		data.AliasStyle = style
+	}
+	if req.ExpiresAt != nil {
+		expires := req.ExpiresAt.AsTime()
+		data.ExpiresAt = &expires
+	}
+	if req.NotBefore != nil {
+		notBefore := req.NotBefore.AsTime()
+		data.NotBefore = &notBefore
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Create", actionCreatePreviewed, string(payload)),
+			b.storedPayloadButton(to, "Edit…", actionShortenOptions, req.GetOriginalUrl()),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.storedPayloadButton(to, "Ignore", actionIgnoreURL, req.GetOriginalUrl()),
+		),
+	)
+	msg := tgbotapi.NewMessage(to.chatID, b.render(msgCreatePreview, data))
+	msg.ReplyMarkup = keyboard
+	msg.DisableWebPagePreview = true
+	_, err = b.send(msg)
+	return err
+}
+
+// handleCreatePreviewed creates the link of a confirmed preview exactly as
+// it was shown.
+func (b *Bot) handleCreatePreviewed(ctx context.Context, r *Request) error {
+	var item queuedLink
+	if err := json.Unmarshal([]byte(r.Args), &item); err != nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	_, err := b.submitLink(ctx, r.Payloads(), item.request(), "")
+	return err
+}
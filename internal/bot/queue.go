@@ -0,0 +1,311 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/metrics"
+	"GURLS-Bot/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxQueueOffers caps the offers to queue a link waiting for an answer.
+const maxQueueOffers = 1000
+
+var (
+	errQueueFull      = errors.New("create queue is full")
+	errQueueUserLimit = errors.New("user queue limit reached")
+	errAlreadyQueued  = errors.New("url is already queued")
+)
+
+// queuedLink is a link creation request waiting for the backend to come back.
+type queuedLink struct {
+	ChatID      int64     `json:"chat_id"`
+	OwnerID     int64     `json:"owner_id,omitempty"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	CustomAlias string    `json:"custom_alias,omitempty"`
+	AliasStyle  string    `json:"alias_style,omitempty"`
+	Domain      string    `json:"domain,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	// MinimalAnalytics is nil when the request left it to the backend.
+	MinimalAnalytics *bool `json:"minimal_analytics,omitempty"`
+	// NotBefore is when the link goes live, if scheduled.
+	NotBefore time.Time `json:"not_before,omitzero"`
+	QueuedAt  time.Time `json:"queued_at"`
+}
+
+func newQueuedLink(chatID int64, req *shortenerv1.CreateLinkRequest) *queuedLink {
+	item := &queuedLink{
+		ChatID:           chatID,
+		OwnerID:          req.GetUserTgId(),
+		URL:              req.GetOriginalUrl(),
+		Title:            req.GetTitle(),
+		CustomAlias:      req.GetCustomAlias(),
+		AliasStyle:       req.GetAliasStyle(),
+		Domain:           req.GetDomain(),
+		Source:           req.GetSource(),
+		QueuedAt:         time.Now(),
+		MinimalAnalytics: req.MinimalAnalytics,
+	}
+	if req.ExpiresAt != nil {
+		item.ExpiresAt = req.ExpiresAt.AsTime()
+	}
+	if req.NotBefore != nil {
+		item.NotBefore = req.NotBefore.AsTime()
+	}
+	return item
+}
+
+// owner returns the user the link is created for. Items queued before
+// owners were recorded belong to their chat.
+func (q *queuedLink) owner() int64 {
+	if q.OwnerID != 0 {
+		return q.OwnerID
+	}
+	return q.ChatID
+}
+
+func (q *queuedLink) request() *shortenerv1.CreateLinkRequest {
+	req := &shortenerv1.CreateLinkRequest{OriginalUrl: q.URL, UserTgId: q.OwnerID}
+	if req.UserTgId == 0 {
+		req.UserTgId = q.ChatID
+	}
+	if q.Title != "" {
+		req.Title = &q.Title
+	}
+	if q.CustomAlias != "" {
+		req.CustomAlias = &q.CustomAlias
+	}
+	if q.AliasStyle != "" {
+		req.AliasStyle = &q.AliasStyle
+	}
+	if q.Domain != "" {
+		req.Domain = &q.Domain
+	}
+	if q.Source != "" {
+		req.Source = &q.Source
+	}
+	if !q.ExpiresAt.IsZero() {
+		req.ExpiresAt = timestamppb.New(q.ExpiresAt)
+	}
+	req.MinimalAnalytics = q.MinimalAnalytics
+	req.NotBefore = protoTimestamp(q.NotBefore)
+	return req
+}
+
+// queueNamespace and queueKey hold the create queue in the store, in a
+// single record as its order matters.
+const (
+	queueNamespace = "queue"
+	queueKey       = "items"
+)
+
+// createQueue is a FIFO of link creation requests, kept in the store.
+type createQueue struct {
+	mu    sync.Mutex
+	cfg   config.Queue
+	ns    store.Namespace[[]*queuedLink]
+	items []*queuedLink
+}
+
+func newCreateQueue(cfg config.Queue, db store.Store) (*createQueue, error) {
+	q := &createQueue{cfg: cfg, ns: store.NewNamespace(db, queueNamespace, store.JSON[[]*queuedLink]{})}
+	items, _, err := q.ns.Get(queueKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read create queue: %w", err)
+	}
+	q.items = items
+	metrics.CreateQueueDepth.Set(int64(len(q.items)))
+	return q, nil
+}
+
+// legacyCreateQueue is the create queue file of earlier versions, the
+// queue as it is kept now.
+func legacyCreateQueue(path string) store.Legacy {
+	return store.Legacy{Name: "create queue", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var items []*queuedLink
+		if err := json.Unmarshal(data, &items); err != nil {
+			return 0, err
+		}
+		return len(items), store.NewNamespace(db, queueNamespace, store.JSON[[]*queuedLink]{}).Put(queueKey, items)
+	}}
+}
+
+// Push adds an item to the queue enforcing per-user and global caps. Items
+// count against their owner, whichever chat they were queued from.
+func (q *createQueue) Push(item *queuedLink) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) >= q.cfg.MaxTotal {
+		return errQueueFull
+	}
+	perUser := 0
+	for _, it := range q.items {
+		if it.owner() != item.owner() {
+			continue
+		}
+		if it.URL == item.URL {
+			return errAlreadyQueued
+		}
+		perUser++
+	}
+	if perUser >= q.cfg.MaxPerUser {
+		return errQueueUserLimit
+	}
+
+	q.items = append(q.items, item)
+	return q.saveLocked()
+}
+
+// Peek returns the oldest item without removing it.
+func (q *createQueue) Peek() *queuedLink {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil
+	}
+	return q.items[0]
+}
+
+// Remove deletes the given item from the queue.
+func (q *createQueue) Remove(item *queuedLink) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, it := range q.items {
+		if it == item {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return q.saveLocked()
+		}
+	}
+	return nil
+}
+
+func (q *createQueue) saveLocked() error {
+	metrics.CreateQueueDepth.Set(int64(len(q.items)))
+	return q.ns.Put(queueKey, q.items)
+}
+
+// runCreateQueue retries queued link creations until ctx is cancelled,
+// backing off while the backend stays unavailable.
+func (b *Bot) runCreateQueue(ctx context.Context) {
+	interval := b.config.Queue.RetryInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	reachable := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// After a failure, probe the backend before replaying queued links
+		// so that a backend still down costs a health check, not a create
+		if reachable || b.backendServing(ctx) {
+			reachable = b.processQueuedLinks(ctx)
+		}
+		if reachable {
+			interval = b.config.Queue.RetryInterval
+		} else {
+			interval = min(interval*2, b.config.Queue.MaxRetryInterval)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// backendServing reports whether the backend health probe succeeds.
+func (b *Bot) backendServing(ctx context.Context) bool {
+	health, err := b.grpcClient.HealthCheck(ctx)
+	if err != nil {
+		b.log.Debug("backend health check failed", zap.Error(err))
+		return false
+	}
+	return health.Serving
+}
+
+// processQueuedLinks drains the queue and reports whether the backend was reachable.
+func (b *Bot) processQueuedLinks(ctx context.Context) bool {
+	for {
+		item := b.createQueue.Peek()
+		if item == nil {
+			return true
+		}
+
+		if time.Since(item.QueuedAt) > b.config.Queue.MaxAge {
+			b.finishQueuedLink(item, b.render(msgQueuedLinkFailed, queueFailureData{URL: item.URL, Reason: b.render(msgQueuedLinkExpired, nil)}))
+			continue
+		}
+
+		res, err := b.createLinkWithRetry(ctx, item.request())
+		if err != nil {
+			// Maintenance is the backend down on purpose: the links wait
+			// for it like they wait for an outage
+			var maintenance *maintenanceError
+			if status.Code(err) == codes.Unavailable || errors.As(err, &maintenance) || ctx.Err() != nil {
+				return false
+			}
+			b.log.Warn("queued link creation failed", zap.Int64("chat_id", item.ChatID), zap.Error(err))
+			b.finishQueuedLink(item, b.render(msgQueuedLinkFailed, queueFailureData{URL: item.URL, Reason: b.mapGRPCError(err, item.CustomAlias)}))
+			continue
+		}
+
+		shortURL := b.shortURLOn(item.Domain, res.GetAlias())
+		b.finishQueuedLink(item, b.render(msgQueuedLinkCreated, linkData{ShortURL: shortURL}))
+	}
+}
+
+// finishQueuedLink tells the user the outcome of a queued link. The
+// notification is in the outbox before the item leaves the queue, so a
+// restart in between loses neither.
+func (b *Bot) finishQueuedLink(item *queuedLink, text string) {
+	key := fmt.Sprintf("queued/%d/%d", item.ChatID, item.QueuedAt.UnixNano())
+	if err := b.sendCritical(key, tgbotapi.NewMessage(item.ChatID, text), b.notification(item.ChatID), b.linkContent()); err != nil {
+		b.log.Error("failed to notify user about queued link", zap.Int64("chat_id", item.ChatID), zap.Error(err))
+	}
+	if err := b.createQueue.Remove(item); err != nil {
+		b.log.Error("failed to persist create queue", zap.Error(err))
+	}
+}
+
+// offerQueue keeps the failed request and asks the user whether to queue it.
+func (b *Bot) offerQueue(chatID int64, req *shortenerv1.CreateLinkRequest) error {
+	b.pendingQueue.Set(chatID, newQueuedLink(chatID, req))
+	return b.sendMessageWithKeyboard(chatID, b.render(msgBackendUnavailableQueue, nil), b.createQueueOfferKeyboard())
+}
+
+func (b *Bot) handleQueueCallback(chatID int64, answer *callbackAnswer) error {
+	item, ok := b.pendingQueue.Get(chatID)
+	if !ok {
+		answer.alert(b.render(msgQueueOfferExpired, nil))
+		return nil
+	}
+	b.pendingQueue.Delete(chatID)
+
+	switch err := b.createQueue.Push(item); {
+	case errors.Is(err, errAlreadyQueued):
+		return b.reply(chatID, msgAlreadyQueued, nil)
+	case errors.Is(err, errQueueUserLimit):
+		return b.reply(chatID, msgQueueUserLimit, limitData{Limit: b.config.Queue.MaxPerUser})
+	case errors.Is(err, errQueueFull):
+		return b.reply(chatID, msgQueueFull, nil)
+	case err != nil:
+		b.log.Error("failed to persist create queue", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	answer.toast(b.render(msgToastQueued, nil))
+	return b.reply(chatID, msgLinkQueued, urlData{URL: item.URL})
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/store/storetest"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateQueueLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]*queuedLink](t, "testdata/queue.json")
+
+	stores, records := storetest.Migrate(t, "testdata/queue.json", legacyCreateQueue)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			q, err := newCreateQueue(config.Queue{MaxTotal: 10, MaxPerUser: 10}, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(q.items, want) {
+				t.Errorf("items =\n%+v\nwant\n%+v", q.items, want)
+			}
+			// Items queued before owners were recorded are created for their chat
+			if got := q.items[0].request().GetUserTgId(); got != 1001 {
+				t.Errorf("owner of the old item = %d, want its chat", got)
+			}
+			req := q.items[1].request()
+			if req.GetUserTgId() != 2002 || req.GetCustomAlias() != "new" || !req.GetMinimalAnalytics() || req.NotBefore == nil {
+				t.Errorf("request() = %v", req)
+			}
+		})
+	}
+}
+
+// openCreateQueue opens an empty create queue holding up to maxPerUser links
+// per user.
+func openCreateQueue(t *testing.T, maxPerUser int) *createQueue {
+	t.Helper()
+	db, err := store.OpenDir(filepath.Join(t.TempDir(), "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	q, err := newCreateQueue(config.Queue{MaxTotal: 100, MaxPerUser: maxPerUser}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestCreateQueueCapsPerOwner(t *testing.T) {
+	q := openCreateQueue(t, 2)
+	push := func(chatID, ownerID int64, url string) error {
+		return q.Push(&queuedLink{ChatID: chatID, OwnerID: ownerID, URL: url})
+	}
+
+	// One user queueing from their private chat and from a group
+	if err := push(user, user, "https://example.com/1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := push(-5001, user, "https://example.com/2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := push(-5002, user, "https://example.com/3"); !errors.Is(err, errQueueUserLimit) {
+		t.Errorf("third link from another chat: err = %v, want the user limit", err)
+	}
+	if err := push(-5001, user, "https://example.com/1"); !errors.Is(err, errAlreadyQueued) {
+		t.Errorf("same URL from another chat: err = %v, want already queued", err)
+	}
+
+	// Another member of the group has a cap of their own
+	if err := push(-5001, 2002, "https://example.com/1"); err != nil {
+		t.Errorf("other member of the group: %v", err)
+	}
+	if err := push(-5001, 2002, "https://example.com/4"); err != nil {
+		t.Errorf("other member of the group: %v", err)
+	}
+
+	// Items queued before owners were recorded count against their chat
+	if err := push(3003, 0, "https://example.com/5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := push(3003, 3003, "https://example.com/6"); err != nil {
+		t.Fatal(err)
+	}
+	if err := push(3003, 3003, "https://example.com/7"); !errors.Is(err, errQueueUserLimit) {
+		t.Errorf("err = %v, want the user limit", err)
+	}
+}
+
+func TestQueueOffer(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Queue.RetryInterval = time.Hour
+	e := startBot(t, cfg)
+	e.backend.Fail(shortenerv1.Shortener_CreateLink_FullMethodName, status.Error(codes.Unavailable, "backend down"))
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page")
+	e.press(t, e.tg.WaitText(user, "Want me to create the link once it's back?"), "Queue")
+	e.answer()
+	e.tg.WaitText(user, "Queued. I'll create a short link for https://example.com/page")
+	if item := e.bot.createQueue.Peek(); item == nil || item.OwnerID != user {
+		t.Errorf("queued item = %+v", item)
+	}
+}
+
+func TestQueueOfferExpires(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Queue.OfferTTL = 100 * time.Millisecond
+	e := startBot(t, cfg)
+	e.backend.Fail(shortenerv1.Shortener_CreateLink_FullMethodName, status.Error(codes.Unavailable, "backend down"))
+
+	e.tg.SendMessage(user, "/shorten https://example.com/page")
+	offer := e.tg.WaitText(user, "Want me to create the link once it's back?")
+	time.Sleep(2 * cfg.Queue.OfferTTL)
+
+	e.press(t, offer, "Queue")
+	if alert := e.answer(); alert.Param("text") != "There is nothing to queue. Please send the URL again." {
+		t.Errorf("alert = %q", alert.Param("text"))
+	}
+	if e.bot.createQueue.Peek() != nil {
+		t.Error("expired offer was queued")
+	}
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/prefs"
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// quickActions are the labels of the reply keyboard buttons, in order. Each
+// button sends its rendered label as a plain message.
+var quickActions = []string{msgQuickNewLink, msgQuickMyLinks, msgQuickSummary, msgQuickHide}
+
+// createQuickActionsKeyboard lays out the quick actions two per row.
+func (b *Bot) createQuickActionsKeyboard() tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for i, label := range quickActions {
+		if i%2 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], tgbotapi.NewKeyboardButton(b.render(label, nil)))
+	}
+	keyboard := tgbotapi.NewReplyKeyboard(rows...)
+	keyboard.ResizeKeyboard = true
+	return keyboard
+}
+
+// handleQuickAction runs the quick action whose label is text and reports
+// whether there was one. Labels are only matched for users who turned the
+// keyboard on, and only outside of wizards.
+func (b *Bot) handleQuickAction(ctx context.Context, msg *tgbotapi.Message) (bool, error) {
+	if !msg.Chat.IsPrivate() || msg.Text == "" || !b.prefs.Get(msg.Chat.ID).QuickActions {
+		return false, nil
+	}
+	chatID := msg.Chat.ID
+	switch msg.Text {
+	case b.render(msgQuickNewLink, nil):
+		return true, b.promptNewLink(chatID)
+	case b.render(msgQuickMyLinks, nil):
+		return true, b.handleMyLinksCommand(ctx, messagePayloads(msg))
+	case b.render(msgQuickSummary, nil):
+		return true, b.showLinkSummary(ctx, chatID)
+	case b.render(msgQuickHide, nil):
+		return true, b.hideQuickActions(chatID)
+	}
+	return false, nil
+}
+
+// promptNewLink asks for the URL to shorten.
+func (b *Bot) promptNewLink(chatID int64) error {
+	return b.sendMessageWithKeyboard(chatID, "Send a URL to create a short link:", b.createCreateLinkKeyboard())
+}
+
+// showLinkSummary tells the user how many links and clicks they have.
+func (b *Bot) showLinkSummary(ctx context.Context, chatID int64) error {
+	summary, err := b.welcomeSummary(ctx, chatID)
+	if err != nil {
+		b.log.Error("failed to summarize links", zap.Error(err))
+		return b.replyGRPCError(chatID, err, "")
+	}
+	if summary.Links == 0 {
+		return b.sendMessageWithKeyboard(chatID, b.render(msgNoLinks, nil), b.createMainKeyboard())
+	}
+	data := welcomeData{Locale: b.formatterFor(chatID), Links: summary.Links, Clicks: summary.Clicks, ClicksKnown: summary.ClicksKnown}
+	return b.sendMessageWithKeyboard(chatID, b.render(msgLinkSummary, data), b.createWelcomeKeyboard(chatID, summary.Recent))
+}
+
+// hideQuickActions turns the keyboard off and removes it.
+func (b *Bot) hideQuickActions(chatID int64) error {
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		p.QuickActions = false
+		b.appendHistory(p, prefs.HistoryEntry{Action: historySettings, Setting: "quick actions keyboard"})
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+	}
+	return b.sendQuickActionsKeyboard(chatID, false)
+}
+
+// sendQuickActionsKeyboard shows or removes the quick actions keyboard. A
+// reply keyboard can only come with a new message.
+func (b *Bot) sendQuickActionsKeyboard(chatID int64, show bool) error {
+	msg := tgbotapi.NewMessage(chatID, b.render(msgQuickActionsHidden, nil))
+	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(false)
+	if show {
+		msg.Text = b.render(msgQuickActionsShown, nil)
+		msg.ReplyMarkup = b.createQuickActionsKeyboard()
+	}
+	_, err := b.send(msg)
+	return err
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/ttlmap"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// dailyCountsNamespace holds the link creations of the current day in the
+// store, by user ID.
+const dailyCountsNamespace = "daily_counts"
+
+// dailyCount is the record of the links a user created on Day.
+type dailyCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// dailyCountCacheTTL is how long a count read from the store is kept in
+// memory after its last use.
+const dailyCountCacheTTL = time.Hour
+
+// dailyCountKey addresses the cached count of a user on a UTC day.
+type dailyCountKey struct {
+	day    string
+	userID int64
+}
+
+// dailyCounter counts link creations per user for the current UTC day. The
+// counts are kept in the store, so a restart doesn't reset the daily quota,
+// and cached in memory by day, so counts of an earlier day are never read.
+type dailyCounter struct {
+	mu    sync.Mutex
+	ns    store.Namespace[dailyCount]
+	cache *ttlmap.Map[dailyCountKey, int]
+}
+
+// newDailyCounter opens the counts kept in db. Records of earlier days are
+// dropped.
+func newDailyCounter(db store.Store) (*dailyCounter, error) {
+	c := &dailyCounter{
+		ns:    store.NewNamespace(db, dailyCountsNamespace, store.JSON[dailyCount]{}),
+		cache: ttlmap.New[dailyCountKey, int](dailyCountCacheTTL, 0),
+	}
+	all, err := c.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daily link counts: %w", err)
+	}
+	today := utcDay()
+	for key, count := range all {
+		if count.Day == today {
+			continue
+		}
+		if err := c.ns.Delete(key); err != nil {
+			return nil, fmt.Errorf("failed to drop daily link count: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// utcDay returns the current UTC day.
+func utcDay() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// get returns the count of key, from the cache or else the store; c.mu
+// must be held.
+func (c *dailyCounter) get(key dailyCountKey) (int, error) {
+	if count, ok := c.cache.Get(key); ok {
+		return count, nil
+	}
+	stored, ok, err := c.ns.Get(strconv.FormatInt(key.userID, 10))
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	if ok && stored.Day == key.day {
+		count = stored.Count
+	}
+	c.cache.Set(key, count)
+	return count, nil
+}
+
+// Get returns how many links userID created today.
+func (c *dailyCounter) Get(userID int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(dailyCountKey{day: utcDay(), userID: userID})
+}
+
+// Inc counts a link created by userID. The count isn't raised when it can't
+// be stored.
+func (c *dailyCounter) Inc(userID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := dailyCountKey{day: utcDay(), userID: userID}
+	count, err := c.get(key)
+	if err != nil {
+		return err
+	}
+	count++
+	if err := c.ns.Put(strconv.FormatInt(userID, 10), dailyCount{Day: key.day, Count: count}); err != nil {
+		return err
+	}
+	c.cache.Set(key, count)
+	return nil
+}
+
+// countCreation counts a link created by ownerID against the daily quota.
+func (b *Bot) countCreation(ownerID int64) {
+	if err := b.dailyCreations.Inc(ownerID); err != nil {
+		b.log.Error("failed to save daily link count", zap.Int64("user_id", ownerID), zap.Error(err))
+	}
+}
+
+// quotaHeadroom returns how many more links the user may create right now,
+// or -1 when the user is not limited. When the headroom is zero, the
+// returned message explains which limit was hit.
+func (b *Bot) quotaHeadroom(ctx context.Context, ownerID int64) (int, string, error) {
+	cfg := b.config.Quota
+	if slices.Contains(cfg.ExemptUserIDs, ownerID) || (cfg.MaxActiveLinks <= 0 && cfg.MaxPerDay <= 0) {
+		return -1, "", nil
+	}
+
+	headroom := -1
+	var message string
+	if cfg.MaxPerDay > 0 {
+		created, err := b.dailyCreations.Get(ownerID)
+		if err != nil {
+			return 0, "", err
+		}
+		headroom = max(cfg.MaxPerDay-created, 0)
+		message = b.render(msgDailyQuotaExceeded, quotaData{Count: created, Limit: cfg.MaxPerDay})
+	}
+	if cfg.MaxActiveLinks > 0 {
+		res, err := b.listUserLinks(ctx, ownerID)
+		if err != nil {
+			return 0, "", err
+		}
+		active := len(res.GetLinks())
+		if left := max(cfg.MaxActiveLinks-active, 0); headroom < 0 || left < headroom {
+			headroom = left
+			message = b.render(msgActiveQuotaExceeded, quotaData{Count: active, Limit: cfg.MaxActiveLinks})
+		}
+	}
+	return headroom, message, nil
+}
+
+// checkQuota tells the user when a link limit is reached and reports whether
+// creation may proceed. Failing to count links doesn't block creation; the
+// backend enforces its own limits.
+func (b *Bot) checkQuota(ctx context.Context, chatID, ownerID int64) (bool, error) {
+	headroom, message, err := b.quotaHeadroom(ctx, ownerID)
+	if err != nil {
+		b.log.Warn("failed to check link quota", zap.Int64("user_id", ownerID), zap.Error(err))
+		return true, nil
+	}
+	if headroom != 0 {
+		return true, nil
+	}
+	return false, b.sendMessageWithKeyboard(chatID, message, b.createQuotaKeyboard())
+}
+
+// Create keyboard offering to free up space when a quota is reached
+func (b *Bot) createQuotaKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Manage my links", callbackMyLinks),
+		),
+	)
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/store"
+	"strconv"
+	"testing"
+)
+
+func openDailyCounter(t *testing.T, db store.Store) *dailyCounter {
+	t.Helper()
+	c, err := newDailyCounter(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDailyCounterSurvivesRestart(t *testing.T) {
+	db, err := store.OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	c := openDailyCounter(t, db)
+	for range 2 {
+		if err := c.Inc(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A count of an earlier day doesn't count today
+	ns := store.NewNamespace(db, dailyCountsNamespace, store.JSON[dailyCount]{})
+	stale := strconv.Itoa(user + 1)
+	if err := ns.Put(stale, dailyCount{Day: "2000-01-01", Count: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openDailyCounter(t, db)
+	if got, err := reopened.Get(user); err != nil || got != 2 {
+		t.Errorf("Get() after restart = %d, %v; want 2", got, err)
+	}
+	if got, err := reopened.Get(user + 1); err != nil || got != 0 {
+		t.Errorf("Get() of a stale count = %d, %v; want 0", got, err)
+	}
+	if _, ok, _ := ns.Get(stale); ok {
+		t.Error("stale count kept in the store")
+	}
+}
+
+func TestDailyCounterIgnoresEarlierDays(t *testing.T) {
+	db, err := store.OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	c := openDailyCounter(t, db)
+
+	// Left by a day that ended while the bot was running
+	ns := store.NewNamespace(db, dailyCountsNamespace, store.JSON[dailyCount]{})
+	if err := ns.Put(strconv.Itoa(user), dailyCount{Day: "2000-01-01", Count: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get(user); err != nil || got != 0 {
+		t.Errorf("Get() = %d, %v; want yesterday's count ignored", got, err)
+	}
+	if err := c.Inc(user); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := ns.Get(strconv.Itoa(user)); got.Day != utcDay() || got.Count != 1 {
+		t.Errorf("stored count = %+v, want today's first", got)
+	}
+}
@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/ttlmap"
+	"time"
+)
+
+// rateLimiter is a per-user sliding window limiter. Users who stay quiet for
+// a window are forgotten.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	hits   *ttlmap.Map[int64, []time.Time]
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, hits: ttlmap.New[int64, []time.Time](window, 0)}
+}
+
+// Allow records a hit for userID and reports whether it is within the limit.
+func (l *rateLimiter) Allow(userID int64) bool {
+	allowed := false
+	l.hits.Update(userID, func(hits []time.Time, _ bool) ([]time.Time, bool) {
+		now := time.Now()
+		recent := hits[:0]
+		for _, t := range hits {
+			if now.Sub(t) < l.window {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) >= l.limit {
+			return recent, true
+		}
+		allowed = true
+		return append(recent, now), true
+	})
+	return allowed
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/metrics"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/reconcile"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reconcileCheckInterval is how often the job looks whether a run is due
+// or an interrupted one should go on.
+const reconcileCheckInterval = time.Hour
+
+// errNothingToPrune leaves the preferences of a user untouched.
+var errNothingToPrune = errors.New("nothing to prune")
+
+// runReconciliation periodically removes bot-side records of links deleted
+// outside the bot, until ctx is done.
+func (b *Bot) runReconciliation(ctx context.Context) {
+	ticker := time.NewTicker(reconcileCheckInterval)
+	defer ticker.Stop()
+	for {
+		b.reconcileIfDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileIfDue goes on with the current run or starts a new one once
+// Reconcile.Interval has passed since the last start.
+func (b *Bot) reconcileIfDue(ctx context.Context, now time.Time) {
+	progress := b.reconciliation.Get()
+	if !progress.Running() {
+		if now.Sub(progress.StartedAt) < b.config.Reconcile.Interval {
+			return
+		}
+		progress = reconcile.Progress{StartedAt: now}
+		if err := b.reconciliation.Save(progress); err != nil {
+			b.log.Error("failed to save reconciliation progress", zap.Error(err))
+			return
+		}
+		b.log.Info("reconciliation started")
+	} else {
+		b.log.Info("reconciliation resumed", zap.Int64("after_user_id", progress.Cursor))
+	}
+
+	for _, userID := range b.reconciledUsers() {
+		if userID <= progress.Cursor {
+			continue
+		}
+		removed, err := b.reconcileUser(ctx, userID)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if code := status.Code(err); code == codes.Unavailable || code == codes.DeadlineExceeded {
+				// Picked up again on the next check
+				b.log.Warn("reconciliation paused, backend unavailable", zap.Int64("user_id", userID), zap.Error(err))
+				return
+			}
+			b.log.Warn("failed to reconcile user", zap.Int64("user_id", userID), zap.Error(err))
+		}
+		progress.Cursor = userID
+		progress.Removed += removed
+		if err := b.reconciliation.Save(progress); err != nil {
+			b.log.Error("failed to save reconciliation progress", zap.Error(err))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.config.Reconcile.Delay):
+		}
+	}
+
+	progress.FinishedAt = time.Now()
+	if err := b.reconciliation.Save(progress); err != nil {
+		b.log.Error("failed to save reconciliation progress", zap.Error(err))
+		return
+	}
+	b.log.Info("reconciliation finished",
+		zap.Int("removed", progress.Removed),
+		zap.Duration("took", progress.FinishedAt.Sub(progress.StartedAt)))
+}
+
+// reconciledUsers returns the users with bot-side records of links, in
+// ascending order. Group chats, whose IDs are negative, own no links.
+func (b *Bot) reconciledUsers() []int64 {
+	users := make(map[int64]bool)
+	for _, userID := range b.prefs.Users() {
+		if userID > 0 && len(linkRecords(b.prefs.Get(userID))) > 0 {
+			users[userID] = true
+		}
+	}
+	for _, userID := range b.monitors.Owners() {
+		users[userID] = true
+	}
+	return slices.Sorted(maps.Keys(users))
+}
+
+// linkRecords returns the aliases p keeps records of.
+func linkRecords(p prefs.Prefs) []string {
+	aliases := slices.Clone(p.Pinned)
+	aliases = slices.AppendSeq(aliases, maps.Keys(p.Snapshots))
+	return slices.AppendSeq(aliases, maps.Keys(p.CleanupKept))
+}
+
+// reconcileUser removes the records of userID about links the backend no
+// longer lists for them and returns how many were removed. Users with more
+// links than are fetched are skipped, as missing links may just not have
+// been fetched.
+func (b *Bot) reconcileUser(ctx context.Context, userID int64) (int, error) {
+	existing := make(map[string]bool)
+	complete, err := b.eachUserLinksPage(ctx, userID, func(links []*shortenerv1.LinkInfo) error {
+		for _, link := range links {
+			existing[link.GetAlias()] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !complete {
+		b.log.Info("reconciliation skipped user with too many links", zap.Int64("user_id", userID))
+		return 0, nil
+	}
+	gone := func(alias string) bool { return !existing[alias] }
+
+	// Keyboards acting on the pruned links are removed along
+	pruned := make(map[string]bool)
+	var pins, snapshots, kept int
+	err = b.prefs.Update(userID, func(p *prefs.Prefs) error {
+		for _, alias := range linkRecords(*p) {
+			if gone(alias) {
+				pruned[alias] = true
+			}
+		}
+		if len(pruned) == 0 {
+			return errNothingToPrune
+		}
+		n := len(p.Pinned)
+		p.Pinned = slices.DeleteFunc(p.Pinned, gone)
+		pins = n - len(p.Pinned)
+		n = len(p.Snapshots)
+		maps.DeleteFunc(p.Snapshots, func(alias string, _ prefs.Snapshot) bool { return gone(alias) })
+		snapshots = n - len(p.Snapshots)
+		n = len(p.CleanupKept)
+		maps.DeleteFunc(p.CleanupKept, func(alias string, _ time.Time) bool { return gone(alias) })
+		kept = n - len(p.CleanupKept)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNothingToPrune) {
+		return 0, err
+	}
+
+	monitored := 0
+	for _, entry := range b.monitors.Owned(userID) {
+		if !gone(entry.Alias) {
+			continue
+		}
+		if err := b.monitors.Remove(entry.Alias); err != nil {
+			b.log.Error("failed to save monitored links", zap.Error(err))
+			continue
+		}
+		pruned[entry.Alias] = true
+		monitored++
+	}
+	for alias := range pruned {
+		b.staleKeyboards(alias)
+	}
+
+	removed := pins + snapshots + kept + monitored
+	if removed > 0 {
+		metrics.ReconcileRemoved.Add(int64(removed))
+		b.log.Info("removed records of deleted links",
+			zap.Int64("user_id", userID),
+			zap.Int("pins", pins),
+			zap.Int("snapshots", snapshots),
+			zap.Int("kept", kept),
+			zap.Int("monitored", monitored))
+	}
+	return removed, nil
+}
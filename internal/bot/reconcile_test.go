@@ -0,0 +1,133 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/backendtest"
+	"GURLS-Bot/internal/monitor"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/reconcile"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startReconciledBot runs a bot whose reconciliation is only run by the
+// test.
+func startReconciledBot(t *testing.T) *e2e {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Reconcile.Enabled = false
+	cfg.Reconcile.Delay = time.Millisecond
+	return startBot(t, cfg)
+}
+
+// keepRecords gives userID bot-side records of pinned, snapshots and
+// monitored links.
+func (e *e2e) keepRecords(t *testing.T, userID int64, pinned, snapshots, monitored []string) {
+	t.Helper()
+	err := e.bot.prefs.Update(userID, func(p *prefs.Prefs) error {
+		p.Pinned = pinned
+		p.Snapshots = make(map[string]prefs.Snapshot)
+		for _, alias := range snapshots {
+			p.Snapshots[alias] = prefs.Snapshot{At: time.Now()}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range monitored {
+		// Not checked while the test runs
+		entry := monitor.Entry{Alias: alias, OwnerID: userID, NextCheck: time.Now().Add(time.Hour)}
+		if err := e.bot.monitors.Add(entry, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReconcilePrunesDeletedLinks(t *testing.T) {
+	e := startReconciledBot(t)
+	// The backend lists links the bot has no records of, and lacks links
+	// the bot keeps records of
+	e.backend.Add(
+		backendtest.Link{Alias: "kept", OriginalURL: "https://example.com/kept", OwnerID: user},
+		backendtest.Link{Alias: "extra", OriginalURL: "https://example.com/extra", OwnerID: user},
+	)
+	e.keepRecords(t, user, []string{"gone", "kept"}, []string{"kept", "gone-snapshot"}, []string{"kept", "gone-monitored"})
+
+	e.bot.reconcileIfDue(context.Background(), time.Now())
+
+	p := e.bot.prefs.Get(user)
+	if !slices.Equal(p.Pinned, []string{"kept"}) {
+		t.Errorf("pinned = %v, want [kept]", p.Pinned)
+	}
+	if _, ok := p.Snapshots["gone-snapshot"]; ok || len(p.Snapshots) != 1 {
+		t.Errorf("snapshots = %v, want only kept", p.Snapshots)
+	}
+	if e.bot.monitors.Has("gone-monitored") || !e.bot.monitors.Has("kept") {
+		t.Errorf("monitored = %v, want only kept", e.bot.monitors.Owned(user))
+	}
+	// Links only the backend knows get no records
+	if slices.Contains(p.Pinned, "extra") || e.bot.monitors.Has("extra") {
+		t.Error("records made up for a link the bot didn't know")
+	}
+	progress := e.bot.reconciliation.Get()
+	if progress.Running() || progress.Removed != 3 {
+		t.Errorf("progress = %+v, want a finished run removing 3 records", progress)
+	}
+
+	// The next run isn't due for another interval
+	e.bot.reconcileIfDue(context.Background(), time.Now().Add(time.Hour))
+	if calls := e.backend.Calls(shortenerv1.Shortener_ListUserLinks_FullMethodName); len(calls) != 1 {
+		t.Errorf("%d link lists fetched, want no second run", len(calls))
+	}
+}
+
+func TestReconcileResumes(t *testing.T) {
+	e := startReconciledBot(t)
+	other := int64(user + 1)
+	e.keepRecords(t, user, []string{"gone"}, nil, nil)
+	e.keepRecords(t, other, []string{"gone"}, nil, nil)
+
+	// A run interrupted after user goes on with the next user only
+	err := e.bot.reconciliation.Save(reconcile.Progress{StartedAt: time.Now(), Cursor: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.bot.reconcileIfDue(context.Background(), time.Now())
+
+	if got := e.bot.prefs.Get(user).Pinned; len(got) != 1 {
+		t.Errorf("user before the cursor reconciled again: pinned = %v", got)
+	}
+	if got := e.bot.prefs.Get(other).Pinned; len(got) != 0 {
+		t.Errorf("user after the cursor not reconciled: pinned = %v", got)
+	}
+	if progress := e.bot.reconciliation.Get(); progress.Running() {
+		t.Errorf("progress = %+v, want the run finished", progress)
+	}
+}
+
+func TestReconcilePausesWhileBackendUnavailable(t *testing.T) {
+	e := startReconciledBot(t)
+	e.keepRecords(t, user, []string{"gone"}, nil, nil)
+	e.backend.Fail(shortenerv1.Shortener_ListUserLinks_FullMethodName, status.Error(codes.Unavailable, "backend down"))
+
+	e.bot.reconcileIfDue(context.Background(), time.Now())
+	progress := e.bot.reconciliation.Get()
+	if !progress.Running() || progress.Cursor != 0 {
+		t.Fatalf("progress = %+v, want the run paused before user", progress)
+	}
+	if got := e.bot.prefs.Get(user).Pinned; len(got) != 1 {
+		t.Errorf("pinned = %v, want records kept while the backend is down", got)
+	}
+
+	// The next check picks the run up again
+	e.bot.reconcileIfDue(context.Background(), time.Now())
+	if got := e.bot.prefs.Get(user).Pinned; len(got) != 0 {
+		t.Errorf("pinned = %v after the backend is back, want pruned", got)
+	}
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/users"
+	"context"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// touchUser records the sender of an update in the user registry, keeping
+// up with name changes. Messages
+// are stamped with their send time so that a user's very first message has
+// FirstSeen equal to its date.
+func (b *Bot) touchUser(update tgbotapi.Update) {
+	from := update.SentFrom()
+	if from == nil || from.IsBot {
+		return
+	}
+	at := time.Now()
+	if update.Message != nil {
+		at = update.Message.Time()
+	}
+	name := users.Name{Username: from.UserName, FirstName: from.FirstName, LastName: from.LastName}
+	if prev, existed := b.users.Touch(from.ID, name, from.LanguageCode, at); existed && prev.Name != name {
+		b.log.Info("user changed name",
+			zap.Int64("user_id", from.ID),
+			zap.String("old_username", prev.Username),
+			zap.String("username", name.Username))
+	}
+	// Only a user who unblocked the bot can write to it in private
+	if chat := update.FromChat(); chat != nil && chat.IsPrivate() {
+		b.users.Unblock(from.ID)
+	}
+}
+
+// handleStartCommand shows the main menu, greeting users who talked to the
+// bot before this message. Those with links get a summary of them when it
+// can be fetched in time. A URL sent over from inline mode is shortened
+// instead.
+func (b *Bot) handleStartCommand(ctx context.Context, r *Request) error {
+	if ok, err := b.startShortening(ctx, r); ok {
+		return err
+	}
+	text := b.mainMenuText(r.ChatID)
+	u, ok := b.users.Get(r.UserID)
+	if !ok || !u.FirstSeen.Before(r.Message.Time()) {
+		return b.sendMessageWithKeyboard(r.ChatID, text, b.createMainKeyboard())
+	}
+	if b.config.Welcome.Personalized {
+		summary, err := b.welcomeSummary(ctx, r.UserID)
+		if err != nil {
+			b.log.Warn("failed to summarize links for /start", zap.Error(err))
+		} else if summary.Links > 0 {
+			data := welcomeData{Locale: b.formatterFor(r.ChatID), Name: u.FirstName, Links: summary.Links, Clicks: summary.Clicks, ClicksKnown: summary.ClicksKnown}
+			text = b.render(msgWelcomeSummary, data) + "\n\n" + text
+			return b.sendMessageWithKeyboard(r.ChatID, text, b.createWelcomeKeyboard(r.ChatID, summary.Recent))
+		}
+	}
+	text = b.render(msgWelcomeBack, nameData{Name: u.FirstName}) + "\n\n" + text
+	return b.sendMessageWithKeyboard(r.ChatID, text, b.createMainKeyboard())
+}
+
+// welcomeSummary is what /start tells a returning user about their links.
+type welcomeSummary struct {
+	Links  int
+	Clicks int64
+	// ClicksKnown is false when the stats of some links didn't arrive in time.
+	ClicksKnown bool
+	// Recent is the alias of the newest link.
+	Recent string
+}
+
+// welcomeSummary counts the links of userID and their clicks within the
+// configured timeout. The link list comes from the inline mode cache when it
+// is fresh.
+func (b *Bot) welcomeSummary(ctx context.Context, userID int64) (welcomeSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Welcome.Timeout)
+	defer cancel()
+	links, err := b.linkLists.Get(userID, func() ([]*shortenerv1.LinkInfo, error) {
+		res, err := b.listUserLinks(ctx, userID)
+		return res.GetLinks(), err
+	})
+	if err != nil || len(links) == 0 {
+		return welcomeSummary{}, err
+	}
+
+	summary := welcomeSummary{Links: len(links), ClicksKnown: true, Recent: links[len(links)-1].Alias}
+	results := fanOut(ctx, fanOutOptions{Deadline: b.config.Welcome.Timeout}, links,
+		func(ctx context.Context, link *shortenerv1.LinkInfo) (*shortenerv1.GetLinkStatsResponse, error) {
+			return b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: link.Alias})
+		})
+	var newest time.Time
+	for _, res := range results {
+		if res.Err != nil {
+			summary.ClicksKnown = false
+			continue
+		}
+		summary.Clicks += res.Value.GetClickCount()
+		if at := protoTime(res.Value.GetCreatedAt()); at != nil && at.After(newest) {
+			newest = *at
+			summary.Recent = res.Item.Alias
+		}
+	}
+	return summary, nil
+}
+
+// Create keyboard under the personalized welcome
+func (b *Bot) createWelcomeKeyboard(chatID int64, recent string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(chatPayloads(chatID), "Stats: "+recent, actionStats, recent),
+			b.callbackButton("My Links", callbackMyLinks),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Create Link", callbackCreateLink),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Settings", callbackSettings),
+			b.callbackButton("Help", callbackHelp),
+		),
+	)
+}
+
+// Create keyboard confirming /forget_me
+func (b *Bot) createForgetMeKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Delete my data", callbackForgetMe),
+			b.callbackButton("Cancel", callbackCancel),
+		),
+	)
+}
+
+// forgetUser deletes the registry record and preferences of a user. Links
+// stay with the backend.
+func (b *Bot) forgetUser(userID, chatID int64, messageID int) error {
+	if err := b.prefs.Delete(userID); err != nil {
+		b.log.Error("failed to delete preferences", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	if err := b.users.Forget(userID); err != nil {
+		b.log.Error("failed to delete user record", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	if err := b.monitors.RemoveOwner(userID); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	b.resetUserState(chatID)
+	b.utmDefaults.Forget(userID)
+	b.keyboards.ForgetChat(chatID)
+	return b.editMessageText(chatID, messageID, b.render(msgForgetMeDone, nil))
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startRename asks for the new alias of alias.
+func (b *Bot) startRename(chatID int64, alias string) error {
+	if err := b.startDialog(chatID, UserState{State: StateWaitingForNewAlias, Payload: renamePayload{Alias: alias}}); err != nil {
+		return err
+	}
+	data := newAliasData{ShortURL: displayURL(b.shortURL(alias)), aliasRulesData: b.aliasRules().data()}
+	return b.replyWithKeyboard(chatID, msgSendNewAlias, data, b.createCancelKeyboard())
+}
+
+// handleNewAliasInput renames alias to the alias sent. The user stays at
+// the prompt when the alias is invalid or taken.
+func (b *Bot) handleNewAliasInput(ctx context.Context, to payloadKey, alias, text string) error {
+	newAlias := strings.TrimSpace(text)
+	if ok, err := b.validateCustomAlias(to.chatID, newAlias); !ok {
+		return err
+	}
+	if newAlias == alias {
+		return b.reply(to.chatID, msgSameAlias, nil)
+	}
+
+	req := &shortenerv1.RenameLinkRequest{Alias: alias, NewAlias: newAlias, UserTgId: to.chatID}
+	res, err := b.grpcClient.RenameLink(ctx, req)
+	if status.Code(err) == codes.AlreadyExists {
+		return b.reply(to.chatID, msgAliasTaken, aliasData{Alias: newAlias})
+	}
+	b.resetUserState(to.chatID)
+	if err != nil {
+		b.log.Error("gRPC RenameLink failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(to.chatID, err, alias)
+	}
+
+	b.renamePin(to.chatID, alias, res.GetAlias())
+	b.renameInCampaigns(to.chatID, alias, res.GetAlias())
+	b.staleKeyboards(alias)
+	if err := b.monitors.Rename(alias, res.GetAlias()); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	b.recordHistory(to.chatID, prefs.HistoryEntry{Action: historyRenamed, Alias: alias, NewAlias: res.GetAlias()})
+	text = b.render(msgLinkRenamed, renameData{
+		OldURL:    displayURL(b.shortURLOn(res.GetDomain(), alias)),
+		NewURL:    b.shortURLOn(res.GetDomain(), res.GetAlias()),
+		Redirects: res.GetOldAliasRedirects(),
+	})
+	return b.sendMessageWithKeyboard(to.chatID, text, b.createStatsKeyboard(to, res.GetAlias()), b.linkContent())
+}
+
+// renamePin keeps a pinned link pinned under its new alias.
+func (b *Bot) renamePin(chatID int64, alias, newAlias string) {
+	if !b.isPinned(chatID, alias) {
+		return
+	}
+	err := b.prefs.Update(chatID, func(p *prefs.Prefs) error {
+		for i, a := range p.Pinned {
+			if a == alias {
+				p.Pinned[i] = newAlias
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+	}
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/audit"
+	"GURLS-Bot/internal/reports"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// reportRateLimit caps the abuse reports of a user, as anyone may send
+	// them and each one reaches the admins.
+	reportRateLimit       = 3
+	reportRateLimitWindow = time.Hour
+	// maxReportReason caps the length of a report's reason, in characters.
+	maxReportReason = 500
+)
+
+// handleReportCommand takes an abuse report of a short link from any user
+// and passes it on to the admins. The reporter is thanked the same way
+// whatever the link, so reporting reveals nothing about it or its owner.
+func (b *Bot) handleReportCommand(ctx context.Context, r *Request) error {
+	arg, reason, _ := strings.Cut(strings.TrimSpace(r.Args), " ")
+	reason = strings.TrimSpace(reason)
+	if arg == "" || reason == "" {
+		return b.reply(r.ChatID, msgReportUsage, nil)
+	}
+	alias, err := b.resolveAlias(arg)
+	if err != nil {
+		return b.replyAliasError(r.ChatID, err, "report")
+	}
+	if runes := []rune(reason); len(runes) > maxReportReason {
+		reason = string(runes[:maxReportReason]) + "…"
+	}
+
+	now := time.Now()
+	if err := b.abuseReports.Expire(now.Add(-b.config.Reports.MaxAge)); err != nil {
+		b.log.Error("failed to save abuse reports", zap.Error(err))
+	}
+	entry, err := b.abuseReports.Add(alias, reports.Report{ReporterID: r.UserID, Reason: reason, At: now}, b.config.Reports.Window)
+	if err != nil {
+		b.log.Error("failed to save abuse report", zap.Error(err), zap.String("alias", alias))
+		return b.reply(r.ChatID, msgInternalError, nil)
+	}
+	// The report is kept either way; the audit log is its copy for review
+	record := audit.Entry{At: now, ReporterID: r.UserID, Action: audit.Report, Alias: alias, Reason: reason}
+	if err := b.audit.Record(record); err != nil {
+		b.log.Error("failed to write audit log", zap.Error(err), zap.String("action", audit.Report), zap.String("alias", alias))
+	}
+	b.log.Info("abuse report", zap.String("alias", alias), zap.Int64("reporter_id", r.UserID), zap.Int("count", entry.Count))
+
+	if len(entry.Notices) == 0 {
+		b.notifyReport(entry)
+	} else {
+		b.updateReportNotices(entry)
+	}
+	return b.reply(r.ChatID, msgReportReceived, nil)
+}
+
+// notifyReport tells the admins about the first report of a link in the
+// window, keeping the messages to update on further reports.
+func (b *Bot) notifyReport(entry reports.Entry) {
+	var notices []reports.Notice
+	for _, chatID := range b.config.Telegram.AdminChatIDs {
+		msg := tgbotapi.NewMessage(chatID, b.render(msgAdminAbuseReport, b.newReportData(chatID, entry)))
+		msg.ReplyMarkup = b.createReportKeyboard(chatID, entry)
+		msg.DisableWebPagePreview = true
+		sent, err := b.send(msg)
+		if err != nil {
+			b.log.Debug("failed to notify admin", zap.Int64("chat_id", chatID), zap.Error(err))
+			continue
+		}
+		notices = append(notices, reports.Notice{ChatID: chatID, MessageID: sent.MessageID})
+	}
+	if err := b.abuseReports.SetNotices(entry.Alias, notices); err != nil {
+		b.log.Error("failed to save abuse reports", zap.Error(err))
+	}
+}
+
+// updateReportNotices brings the admin notifications of a link up to date
+// with a further report.
+func (b *Bot) updateReportNotices(entry reports.Entry) {
+	for _, n := range entry.Notices {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(n.ChatID, n.MessageID,
+			b.render(msgAdminAbuseReport, b.newReportData(n.ChatID, entry)),
+			b.createReportKeyboard(n.ChatID, entry))
+		edit.DisableWebPagePreview = true
+		if err := b.editMessage(edit, nil, nil); err != nil {
+			b.log.Debug("failed to update abuse report", zap.Int64("chat_id", n.ChatID), zap.Error(err))
+		}
+	}
+}
+
+// createReportKeyboard offers the admin tools for a reported link: Inspect,
+// and Disable through the /inspect confirmation. Both are audited with the
+// latest reason as theirs.
+func (b *Bot) createReportKeyboard(chatID int64, entry reports.Entry) tgbotapi.InlineKeyboardMarkup {
+	reason := "abuse report: " + entry.Reports[len(entry.Reports)-1].Reason
+	disable, err := json.Marshal(inspectAction{Action: audit.Disable, Alias: entry.Alias, Reason: reason})
+	if err != nil {
+		b.log.Error("failed to encode report action", zap.Error(err))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.storedPayloadButton(chatPayloads(chatID), "Inspect", actionReportInspect, entry.Alias+"\n"+reason),
+		b.storedPayloadButton(chatPayloads(chatID), "Disable", actionInspectAsk, string(disable)),
+	))
+}
+
+// inspectReported inspects a reported link from its admin notification.
+func (b *Bot) inspectReported(ctx context.Context, r *Request) error {
+	alias, reason, ok := strings.Cut(r.Args, "\n")
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	return b.inspectLink(ctx, r.ChatID, r.UserID, alias, reason)
+}
+
+// newReportData describes entry for the admins in chatID.
+func (b *Bot) newReportData(chatID int64, entry reports.Entry) reportData {
+	data := reportData{
+		Locale:   b.formatterFor(chatID),
+		Alias:    entry.Alias,
+		ShortURL: displayURL(b.shortURL(entry.Alias)),
+		Count:    entry.Count,
+		Since:    entry.FirstAt,
+	}
+	for _, rep := range entry.Reports {
+		reporter := strconv.FormatInt(rep.ReporterID, 10)
+		if u, ok := b.users.Get(rep.ReporterID); ok {
+			reporter = peerLabel(u)
+		}
+		data.Reports = append(data.Reports, reportReasonData{Reporter: reporter, Reason: rep.Reason, At: rep.At})
+	}
+	return data
+}
@@ -0,0 +1,327 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/client"
+	"context"
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Request is a routed command or callback query.
+type Request struct {
+	ChatID int64
+	UserID int64
+	// Args holds the command arguments or the decoded callback payload.
+	Args string
+	// Message is the command message, or the message the pressed button belongs to.
+	Message *tgbotapi.Message
+	// Callback is set for callback queries only.
+	Callback *tgbotapi.CallbackQuery
+	// Answer collects the callback answer; nil for commands.
+	Answer *callbackAnswer
+	// Route is the matched route.
+	Route *Route
+}
+
+// IsPrivate reports whether the request comes from a private chat.
+func (r *Request) IsPrivate() bool {
+	return r.Message != nil && r.Message.Chat.IsPrivate()
+}
+
+// Payloads returns the payloadKey of buttons answering the request, which
+// only its user can press.
+func (r *Request) Payloads() payloadKey {
+	return payloadKey{chatID: r.ChatID, userID: r.UserID}
+}
+
+// HandlerFunc handles a routed request.
+type HandlerFunc func(ctx context.Context, req *Request) error
+
+// Middleware wraps a handler with cross-cutting behavior.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Route is a registered handler together with its metadata.
+type Route struct {
+	Name        string
+	Handler     HandlerFunc
+	AdminOnly   bool
+	PrivateOnly bool
+	GroupOnly   bool
+	RateLimit   *rateLimiter
+	// Feature names the feature the route needs, if any; see supports.
+	Feature string
+	// Mutates marks routes that create or change links, which are refused
+	// during backend maintenance.
+	Mutates bool
+	// Description is shown in the Telegram command menu; commands without
+	// one are left out of it.
+	Description string
+	// Expired handles presses of the route's buttons whose stored payload
+	// expired; without it the user is told the button expired.
+	Expired HandlerFunc
+}
+
+// RouteOption configures route metadata.
+type RouteOption func(*Route)
+
+// adminOnly restricts a route to the configured admin chats.
+func adminOnly() RouteOption {
+	return func(r *Route) { r.AdminOnly = true }
+}
+
+// privateOnly restricts a route to private chats.
+func privateOnly() RouteOption {
+	return func(r *Route) { r.PrivateOnly = true }
+}
+
+// groupOnly restricts a route to group chats.
+func groupOnly() RouteOption {
+	return func(r *Route) { r.GroupOnly = true }
+}
+
+// needs marks a route as unavailable while feature is turned off or the
+// backend lacks it.
+func needs(feature string) RouteOption {
+	return func(r *Route) { r.Feature = feature }
+}
+
+// mutates marks a route as creating or changing links.
+func mutates() RouteOption {
+	return func(r *Route) { r.Mutates = true }
+}
+
+// describe sets the command menu description of a route.
+func describe(text string) RouteOption {
+	return func(r *Route) { r.Description = text }
+}
+
+// onExpired sets the handler for buttons whose payload expired, typically
+// falling back to the flow the button was a shortcut into.
+func onExpired(h HandlerFunc) RouteOption {
+	return func(r *Route) { r.Expired = h }
+}
+
+// rateLimit limits how often a single user may invoke a route.
+func rateLimit(limit int, window time.Duration) RouteOption {
+	return func(r *Route) { r.RateLimit = newRateLimiter(limit, window) }
+}
+
+// Router dispatches commands by name and callbacks by action code through a
+// shared middleware chain.
+type Router struct {
+	commands        map[string]*Route
+	commandOrder    []string
+	callbacks       map[string]*Route
+	middleware      []Middleware
+	unknownCommand  HandlerFunc
+	unknownCallback HandlerFunc
+}
+
+// NewRouter creates a router whose unknown-route handlers do nothing.
+func NewRouter() *Router {
+	noop := func(context.Context, *Request) error { return nil }
+	return &Router{
+		commands:        make(map[string]*Route),
+		callbacks:       make(map[string]*Route),
+		unknownCommand:  noop,
+		unknownCallback: noop,
+	}
+}
+
+// Use appends middleware; the first registered middleware runs outermost.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Command registers a handler for /name.
+func (r *Router) Command(name string, h HandlerFunc, opts ...RouteOption) {
+	if _, ok := r.commands[name]; !ok {
+		r.commandOrder = append(r.commandOrder, name)
+	}
+	r.commands[name] = newRoute(name, h, opts)
+}
+
+// Callback registers a handler for the callback action code.
+func (r *Router) Callback(action string, h HandlerFunc, opts ...RouteOption) {
+	r.callbacks[action] = newRoute(action, h, opts)
+}
+
+// UnknownCommand sets the handler used when no command route matches.
+func (r *Router) UnknownCommand(h HandlerFunc) {
+	r.unknownCommand = h
+}
+
+// UnknownCallback sets the handler used when no callback route matches.
+func (r *Router) UnknownCallback(h HandlerFunc) {
+	r.unknownCallback = h
+}
+
+// Commands returns the registered command routes in registration order.
+func (r *Router) Commands() []*Route {
+	routes := make([]*Route, len(r.commandOrder))
+	for i, name := range r.commandOrder {
+		routes[i] = r.commands[name]
+	}
+	return routes
+}
+
+// Callbacks returns the registered callback routes ordered by action code.
+func (r *Router) Callbacks() []*Route {
+	routes := make([]*Route, 0, len(r.callbacks))
+	for _, action := range slices.Sorted(maps.Keys(r.callbacks)) {
+		routes = append(routes, r.callbacks[action])
+	}
+	return routes
+}
+
+// HandleCommand dispatches a command request.
+func (r *Router) HandleCommand(ctx context.Context, name string, req *Request) Outcome {
+	return outcomeOf(r.dispatch(ctx, r.commands[name], r.unknownCommand, req))
+}
+
+// HandleCallback dispatches a callback request.
+func (r *Router) HandleCallback(ctx context.Context, action string, req *Request) Outcome {
+	return outcomeOf(r.dispatch(ctx, r.callbacks[action], r.unknownCallback, req))
+}
+
+// HandleExpiredCallback dispatches a callback request whose payload expired
+// to the Expired handler of its route, reporting false when there is none.
+func (r *Router) HandleExpiredCallback(ctx context.Context, action string, req *Request) (bool, Outcome) {
+	route := r.callbacks[action]
+	if route == nil || route.Expired == nil {
+		return false, Outcome{}
+	}
+	req.Route = route
+	return true, outcomeOf(r.chain(route.Expired)(ctx, req))
+}
+
+func (r *Router) dispatch(ctx context.Context, route *Route, fallback HandlerFunc, req *Request) error {
+	if route == nil {
+		return r.chain(fallback)(ctx, req)
+	}
+	req.Route = route
+	return r.chain(route.Handler)(ctx, req)
+}
+
+func (r *Router) chain(h HandlerFunc) HandlerFunc {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h
+}
+
+func newRoute(name string, h HandlerFunc, opts []RouteOption) *Route {
+	route := &Route{Name: name, Handler: h}
+	for _, opt := range opts {
+		opt(route)
+	}
+	return route
+}
+
+// recoverMiddleware turns handler panics into errors.
+func (b *Bot) recoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				b.log.Error("handler panicked", zap.Any("panic", p), zap.ByteString("stack", debug.Stack()))
+				err = fmt.Errorf("handler panicked: %v", p)
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+// logMiddleware logs every routed request.
+func (b *Bot) logMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		route := "unknown"
+		if req.Route != nil {
+			route = req.Route.Name
+		}
+		b.log.Debug("handling request",
+			zap.String("route", route),
+			zap.Int64("chat_id", req.ChatID),
+			zap.String("request_id", client.RequestIDFrom(ctx)),
+		)
+		return next(ctx, req)
+	}
+}
+
+// accessMiddleware enforces route metadata. Admin routes are invisible to
+// other users, who get the unknown command reply. They are only offered in
+// the admins' own chats, see publishCommands, and are refused elsewhere even
+// to admins, so the menus and what can be run always agree.
+func (b *Bot) accessMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		if req.Route == nil {
+			return next(ctx, req)
+		}
+		if req.Route.AdminOnly && !(b.isAdmin(req.UserID) && b.isAdmin(req.ChatID)) {
+			if req.Callback != nil {
+				return nil
+			}
+			return b.reply(req.ChatID, msgUnknownCommand, nil)
+		}
+		if req.Route.Feature != "" && !b.features.Enabled(req.Route.Feature) {
+			// Commands of features turned off don't exist
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgFeatureDisabled, nil))
+				return nil
+			}
+			return b.reply(req.ChatID, msgUnknownCommand, nil)
+		}
+		if req.Route.Feature != "" && !b.supports(req.Route.Feature) {
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgFeatureUnavailable, nil))
+				return nil
+			}
+			return b.reply(req.ChatID, msgFeatureUnavailable, nil)
+		}
+		if w, ok := b.activeMaintenance(); ok && req.Route.Mutates {
+			data := b.maintenanceData(req.ChatID, w)
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgMaintenance, data))
+				return nil
+			}
+			return b.reply(req.ChatID, msgMaintenance, data)
+		}
+		if req.Route.RateLimit != nil && !req.Route.RateLimit.Allow(req.UserID) {
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgRateLimited, nil))
+				return nil
+			}
+			return b.reply(req.ChatID, msgRateLimited, nil)
+		}
+		if req.Route.PrivateOnly && !req.IsPrivate() {
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgPrivateChatOnly, nil))
+				return nil
+			}
+			return b.reply(req.ChatID, msgPrivateChatOnly, nil)
+		}
+		if req.Route.GroupOnly && req.IsPrivate() {
+			if req.Callback != nil {
+				req.Answer.alert(b.render(msgGroupChatOnly, nil))
+				return nil
+			}
+			return b.reply(req.ChatID, msgGroupChatOnly, nil)
+		}
+		return next(ctx, req)
+	}
+}
+
+// isAdmin reports whether userID is listed in the configured admin chats.
+func (b *Bot) isAdmin(userID int64) bool {
+	for _, id := range b.config.Telegram.AdminChatIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
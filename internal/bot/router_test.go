@@ -0,0 +1,321 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tracing returns a middleware appending name to trace on the way in and
+// "/"+name on the way out.
+func tracing(trace *[]string, name string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *Request) error {
+			*trace = append(*trace, name)
+			err := next(ctx, req)
+			*trace = append(*trace, "/"+name)
+			return err
+		}
+	}
+}
+
+func TestRouterMiddlewareOrder(t *testing.T) {
+	var trace []string
+	r := NewRouter()
+	r.Use(tracing(&trace, "a"), tracing(&trace, "b"))
+	r.Use(tracing(&trace, "c"))
+	r.Command("start", func(context.Context, *Request) error {
+		trace = append(trace, "handler")
+		return nil
+	})
+
+	r.HandleCommand(context.Background(), "start", &Request{})
+	want := []string{"a", "b", "c", "handler", "/c", "/b", "/a"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestRouterMiddlewareSeesRoute(t *testing.T) {
+	r := NewRouter()
+	var seen *Route
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req *Request) error {
+			seen = req.Route
+			return next(ctx, req)
+		}
+	})
+	r.Callback("x", func(context.Context, *Request) error { return nil }, adminOnly(), mutates())
+
+	r.HandleCallback(context.Background(), "x", &Request{})
+	if seen == nil || seen.Name != "x" || !seen.AdminOnly || !seen.Mutates {
+		t.Errorf("middleware saw route %+v", seen)
+	}
+}
+
+func TestRouterUnknownRoutes(t *testing.T) {
+	var trace []string
+	r := NewRouter()
+	r.Use(tracing(&trace, "mw"))
+	r.Command("start", func(context.Context, *Request) error {
+		t.Error("known command handler called")
+		return nil
+	})
+	unknown := errors.New("unknown")
+	r.UnknownCommand(func(_ context.Context, req *Request) error {
+		if req.Route != nil {
+			t.Errorf("unknown command has route %q", req.Route.Name)
+		}
+		trace = append(trace, "unknown command")
+		return unknown
+	})
+	r.UnknownCallback(func(context.Context, *Request) error {
+		trace = append(trace, "unknown callback")
+		return nil
+	})
+
+	if o := r.HandleCommand(context.Background(), "nope", &Request{}); !errors.Is(o.Err, unknown) {
+		t.Errorf("outcome = %+v, want the fallback error", o)
+	}
+	r.HandleCallback(context.Background(), "start", &Request{})
+	// Fallbacks run through the middleware like any route
+	want := []string{"mw", "unknown command", "/mw", "mw", "unknown callback", "/mw"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestRouterUnknownRoutesDefault(t *testing.T) {
+	r := NewRouter()
+	if o := r.HandleCommand(context.Background(), "nope", &Request{}); o.Err != nil || o.DeliveryErr != nil {
+		t.Errorf("outcome = %+v, want none", o)
+	}
+	if o := r.HandleCallback(context.Background(), "nope", &Request{}); o.Err != nil || o.DeliveryErr != nil {
+		t.Errorf("outcome = %+v, want none", o)
+	}
+}
+
+func TestRouterExpiredCallback(t *testing.T) {
+	r := NewRouter()
+	r.Callback("plain", func(context.Context, *Request) error { return nil })
+	var expired bool
+	r.Callback("fallback", func(context.Context, *Request) error { return nil }, onExpired(func(_ context.Context, req *Request) error {
+		expired = req.Route.Name == "fallback"
+		return nil
+	}))
+
+	if handled, _ := r.HandleExpiredCallback(context.Background(), "plain", &Request{}); handled {
+		t.Error("route without an expired handler handled the press")
+	}
+	if handled, _ := r.HandleExpiredCallback(context.Background(), "missing", &Request{}); handled {
+		t.Error("unknown route handled the press")
+	}
+	if handled, _ := r.HandleExpiredCallback(context.Background(), "fallback", &Request{}); !handled || !expired {
+		t.Errorf("handled = %v, expired handler ran = %v", handled, expired)
+	}
+}
+
+func TestRouterRouteOrder(t *testing.T) {
+	r := NewRouter()
+	h := func(context.Context, *Request) error { return nil }
+	r.Command("b", h)
+	r.Command("a", h, describe("first"))
+	r.Command("b", h, describe("again"))
+	r.Callback("y", h)
+	r.Callback("x", h)
+
+	var names []string
+	for _, route := range r.Commands() {
+		names = append(names, route.Name)
+	}
+	if !slices.Equal(names, []string{"b", "a"}) {
+		t.Errorf("commands = %v, want registration order", names)
+	}
+	if r.Commands()[0].Description != "again" {
+		t.Error("registering a command again did not replace it")
+	}
+	names = nil
+	for _, route := range r.Callbacks() {
+		names = append(names, route.Name)
+	}
+	if !slices.Equal(names, []string{"x", "y"}) {
+		t.Errorf("callbacks = %v, want sorted", names)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	b := &Bot{log: zap.NewNop()}
+	r := NewRouter()
+	r.Use(b.recoverMiddleware)
+	r.Command("boom", func(context.Context, *Request) error { panic("boom") })
+
+	o := r.HandleCommand(context.Background(), "boom", &Request{})
+	if o.Err == nil || !strings.Contains(o.Err.Error(), "boom") {
+		t.Errorf("outcome = %+v, want the panic as an error", o)
+	}
+}
+
+// group is the group chat the router tests post in.
+const group = -5001
+
+// sendGroupMessage enqueues text posted by user in the test group.
+func (e *e2e) sendGroupMessage(text string) {
+	msg := &tgbotapi.Message{
+		MessageID: int(time.Now().UnixNano() % 1e6),
+		From:      &tgbotapi.User{ID: user, FirstName: "User", LanguageCode: "en"},
+		Chat:      &tgbotapi.Chat{ID: group, Type: "group", Title: "Test group"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	e.tg.Enqueue(tgbotapi.Update{Message: msg})
+}
+
+func TestAccessAdminOnly(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/blocklist")
+	e.tg.WaitText(user, "Unknown command")
+}
+
+func TestAccessAdminOnlyAdmin(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+
+	e.tg.SendMessage(user, "/blocklist")
+	e.tg.WaitText(user, "The blocklist is empty")
+}
+
+func TestAccessAdminOnlyOutsideAdminChat(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+
+	// Admins are refused admin commands in chats that are not theirs
+	e.sendGroupMessage("/blocklist")
+	e.tg.WaitText(group, "Unknown command")
+}
+
+func TestAccessPrivateOnly(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.sendGroupMessage("/export_settings")
+	e.tg.WaitText(group, "only available in a private chat")
+}
+
+func TestAccessGroupOnly(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/autoshorten on")
+	e.tg.WaitText(user, "works in group chats only")
+}
+
+func TestOutcomeOf(t *testing.T) {
+	failed, lost := errors.New("backend down"), errors.New("connection reset")
+	for _, tt := range []struct {
+		name                  string
+		err                   error
+		wantErr, wantDelivery error
+	}{
+		{name: "success"},
+		{name: "request failed", err: failed, wantErr: failed},
+		{name: "reply undelivered", err: undelivered(lost), wantDelivery: lost},
+		{name: "both", err: errors.Join(failed, undelivered(lost)), wantErr: failed, wantDelivery: lost},
+		{name: "joined nothing", err: errors.Join(nil, undelivered(nil))},
+	} {
+		o := outcomeOf(tt.err)
+		if !errors.Is(o.Err, tt.wantErr) || (o.Err == nil) != (tt.wantErr == nil) {
+			t.Errorf("%s: Err = %v, want %v", tt.name, o.Err, tt.wantErr)
+		}
+		if !errors.Is(o.DeliveryErr, tt.wantDelivery) || (o.DeliveryErr == nil) != (tt.wantDelivery == nil) {
+			t.Errorf("%s: DeliveryErr = %v, want %v", tt.name, o.DeliveryErr, tt.wantDelivery)
+		}
+		if o.Retryable() != (tt.wantErr != nil) {
+			t.Errorf("%s: Retryable() = %v", tt.name, o.Retryable())
+		}
+		// Passing an outcome on keeps it
+		if again := outcomeOf(o.asError()); again.Retryable() != o.Retryable() || (again.DeliveryErr == nil) != (o.DeliveryErr == nil) {
+			t.Errorf("%s: outcome passed on = %+v, was %+v", tt.name, again, o)
+		}
+	}
+}
+
+// privateMessage returns text sent by user in their private chat, as
+// Telegram would deliver it.
+func privateMessage(text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: int(time.Now().UnixNano() % 1e6),
+		From:      &tgbotapi.User{ID: user, FirstName: "User", LanguageCode: "en"},
+		Chat:      &tgbotapi.Chat{ID: user, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	return msg
+}
+
+func TestOutcomeBackendOKSendFailed(t *testing.T) {
+	e := startBot(t, nil)
+	e.tg.Fail("sendMessage", http.StatusForbidden, "Forbidden: bot was blocked by the user")
+
+	o := e.bot.handleCommand(context.Background(), privateMessage("/shorten https://example.com/a"))
+	if o.Err != nil || o.DeliveryErr == nil || o.Retryable() {
+		t.Errorf("outcome = %+v, want only the reply undelivered and no retry", o)
+	}
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 1 {
+		t.Errorf("%d links created, want 1", len(calls))
+	}
+
+	// Editing the message doesn't create the link again
+	msg := privateMessage("https://example.com/b")
+	if o := outcomeOf(e.bot.handleMessage(context.Background(), msg)); o.Retryable() {
+		t.Errorf("outcome = %+v, want no retry", o)
+	}
+	msg.Text = "https://example.com/b?edited"
+	e.bot.handleEditedMessage(context.Background(), msg)
+	if calls := e.backend.Calls(shortenerv1.Shortener_CreateLink_FullMethodName); len(calls) != 2 {
+		t.Errorf("%d links created, want the edit not to create one", len(calls))
+	}
+}
+
+func TestOutcomeBackendFailedSendOK(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Fail(shortenerv1.Shortener_CreateLink_FullMethodName, status.Error(codes.Internal, "database locked"))
+
+	o := e.bot.handleCommand(context.Background(), privateMessage("/shorten https://example.com/a"))
+	if o.Err == nil || o.DeliveryErr != nil || !o.Retryable() {
+		t.Errorf("outcome = %+v, want the request failed, the reply delivered and a retry allowed", o)
+	}
+	e.tg.WaitText(user, "Internal error")
+
+	// Editing the message tries again
+	e.backend.Fail(shortenerv1.Shortener_CreateLink_FullMethodName, status.Error(codes.Internal, "database locked"))
+	msg := privateMessage("https://example.com/b")
+	if o := outcomeOf(e.bot.handleMessage(context.Background(), msg)); !o.Retryable() {
+		t.Errorf("outcome = %+v, want a retry allowed", o)
+	}
+	msg.Text = "https://example.com/b?edited"
+	if o := outcomeOf(e.bot.handleEditedMessage(context.Background(), msg)); o.Err != nil || o.DeliveryErr != nil {
+		t.Errorf("edit outcome = %+v, want success", o)
+	}
+	if req := e.lastCreated(t); req.GetOriginalUrl() != msg.Text {
+		t.Errorf("created %q, want the edited URL", req.GetOriginalUrl())
+	}
+}
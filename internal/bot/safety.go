@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/ttlmap"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// bansNamespace holds the banned users in the store, by user ID.
+const bansNamespace = "bans"
+
+// abuseFlagTTL is how long a flagged URL counts towards a ban.
+const abuseFlagTTL = 7 * 24 * time.Hour
+
+// abuseBan is the record of a banned user.
+type abuseBan struct {
+	BannedAt time.Time `json:"banned_at"`
+	Flags    int       `json:"flags"`
+}
+
+// abuseTracker counts flagged URLs per user and bans repeated offenders.
+// Bans are kept in the store and survive restarts. The flags leading up to
+// a ban are only counted in memory, start over on restart and are forgotten
+// abuseFlagTTL after a user's last one. Bans are lifted for everyone while
+// bans are disabled.
+type abuseTracker struct {
+	mu       sync.Mutex
+	banAfter int
+	flags    *ttlmap.Map[int64, int]
+	ns       store.Namespace[abuseBan]
+	bans     map[int64]abuseBan
+}
+
+// newAbuseTracker loads the bans kept in db.
+func newAbuseTracker(banAfter int, db store.Store) (*abuseTracker, error) {
+	t := &abuseTracker{
+		banAfter: banAfter,
+		flags:    ttlmap.New[int64, int](abuseFlagTTL, 0),
+		ns:       store.NewNamespace(db, bansNamespace, store.JSON[abuseBan]{}),
+		bans:     make(map[int64]abuseBan),
+	}
+	all, err := t.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bans: %w", err)
+	}
+	for key, ban := range all {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ban key %q", key)
+		}
+		t.bans[id] = ban
+	}
+	return t, nil
+}
+
+// Flag records a flagged URL and reports whether the user is now banned.
+// The ban is only in effect once it is stored.
+func (t *abuseTracker) Flag(userID int64) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var flags int
+	t.flags.Update(userID, func(n int, _ bool) (int, bool) {
+		flags = n + 1
+		return flags, true
+	})
+	if t.banAfter <= 0 || flags < t.banAfter {
+		return false, nil
+	}
+	ban := abuseBan{BannedAt: time.Now(), Flags: flags}
+	if err := t.ns.Put(strconv.FormatInt(userID, 10), ban); err != nil {
+		return false, err
+	}
+	t.bans[userID] = ban
+	t.flags.Delete(userID)
+	return true, nil
+}
+
+// Banned reports whether the user may no longer create links.
+func (t *abuseTracker) Banned(userID int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, banned := t.bans[userID]
+	return t.banAfter > 0 && banned
+}
+
+// checkURLSafety runs the configured URL checker and tells the user when the
+// URL is refused. It reports whether creation may proceed.
+func (b *Bot) checkURLSafety(ctx context.Context, chatID, ownerID int64, url string) (bool, error) {
+	if b.abuse.Banned(ownerID) {
+		return false, b.reply(chatID, msgUserBanned, nil)
+	}
+	if b.urlChecker == nil {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.config.SafeBrowsing.Timeout)
+	defer cancel()
+
+	verdict, err := b.urlChecker.Check(ctx, url)
+	if err != nil {
+		metrics.URLCheckErrors.Add(1)
+		b.log.Warn("URL safety check failed", zap.Error(err))
+		if b.config.SafeBrowsing.FailOpen {
+			return true, nil
+		}
+		return false, b.reply(chatID, msgURLCheckUnavailable, nil)
+	}
+	if !verdict.Unsafe {
+		return true, nil
+	}
+
+	metrics.UnsafeURLsRefused.Add(1)
+	banned, err := b.abuse.Flag(ownerID)
+	if err != nil {
+		b.log.Error("failed to save ban", zap.Int64("user_id", ownerID), zap.Error(err))
+	}
+	b.log.Warn("refused unsafe URL",
+		zap.Int64("user_id", ownerID),
+		zap.String("url", url),
+		zap.String("threat", verdict.Threat),
+		zap.Bool("banned", banned),
+	)
+	return false, b.reply(chatID, msgUnsafeURL, nil)
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/store"
+	"testing"
+)
+
+func openAbuseTracker(t *testing.T, db store.Store, banAfter int) *abuseTracker {
+	t.Helper()
+	tracker, err := newAbuseTracker(banAfter, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracker
+}
+
+func TestAbuseTrackerBansSurviveRestart(t *testing.T) {
+	db, err := store.OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tracker := openAbuseTracker(t, db, 2)
+	for i, want := range []bool{false, true} {
+		banned, err := tracker.Flag(user)
+		if err != nil || banned != want {
+			t.Fatalf("flag %d: banned = %v, %v; want %v", i+1, banned, err, want)
+		}
+	}
+	if _, err := tracker.Flag(user + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openAbuseTracker(t, db, 2)
+	if !reopened.Banned(user) {
+		t.Error("ban lost on restart")
+	}
+	// Flags short of a ban start over
+	if reopened.Banned(user + 1) {
+		t.Error("flagged user banned after restart")
+	}
+	if banned, _ := reopened.Flag(user + 1); banned {
+		t.Error("flags kept over restart")
+	}
+
+	if openAbuseTracker(t, db, 0).Banned(user) {
+		t.Error("ban in effect with bans disabled")
+	}
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// linkScheduling stands for creating links that go live later through
+// CreateLink among the capabilities. Backends without it ignore not_before
+// and leave it out of the response, which is only noticed on use.
+const linkScheduling = shortenerv1.Shortener_CreateLink_FullMethodName + "#not_before"
+
+// notBeforeLayouts are the ways an activation time can be typed, in the
+// user's time zone. A date alone means its midnight.
+var notBeforeLayouts = []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// errSchedulingUnsupported is returned for links the backend created
+// active instead of scheduling them, and which were deleted again.
+var errSchedulingUnsupported = errors.New("backend doesn't schedule links")
+
+// unscheduledLinkError is a link the backend created active instead of
+// scheduling it, and which couldn't be deleted again.
+type unscheduledLinkError struct {
+	alias string
+	err   error
+}
+
+func (e *unscheduledLinkError) Error() string {
+	return fmt.Sprintf("backend doesn't schedule links, and deleting %s failed: %v", e.alias, e.err)
+}
+
+func (e *unscheduledLinkError) Unwrap() error {
+	return e.err
+}
+
+// parseNotBefore parses an activation time typed in loc.
+func parseNotBefore(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range notBeforeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid activation time %q", value)
+}
+
+// checkNotBefore refuses to create a scheduled link when the backend is
+// known not to schedule links, or when it would go live in the past or
+// only once expired. It reports whether the request may go on.
+func (b *Bot) checkNotBefore(chatID int64, req *shortenerv1.CreateLinkRequest) (bool, error) {
+	if req.NotBefore == nil {
+		return true, nil
+	}
+	if !b.supports(featureScheduling) {
+		return false, b.reply(chatID, msgSchedulingUnsupported, nil)
+	}
+	notBefore := req.NotBefore.AsTime()
+	if !notBefore.After(time.Now()) {
+		return false, b.reply(chatID, msgNotBeforePast, nil)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.AsTime().After(notBefore) {
+		return false, b.reply(chatID, msgNotBeforeAfterExpiry, nil)
+	}
+	return true, nil
+}
+
+// checkScheduled learns from a created link whether the backend scheduled
+// it. A link it created active instead is deleted again, so a launch
+// doesn't go live early.
+func (b *Bot) checkScheduled(ctx context.Context, res *shortenerv1.CreateLinkResponse) error {
+	scheduled := res.NotBefore != nil
+	if b.capabilities.Set(linkScheduling, scheduled) {
+		b.capabilitiesChanged()
+	}
+	if scheduled {
+		return nil
+	}
+	b.log.Warn("backend ignored not_before, deleting the link", zap.String("alias", res.GetAlias()))
+	if err := b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: res.GetAlias()}); err != nil {
+		b.log.Error("failed to delete link created active", zap.Error(err), zap.String("alias", res.GetAlias()))
+		return &unscheduledLinkError{alias: res.GetAlias(), err: err}
+	}
+	return errSchedulingUnsupported
+}
+
+// scheduleKeyboard offers to schedule the link of the custom alias wizard.
+func (b *Bot) scheduleKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Schedule go-live…", callbackScheduleLink),
+	))
+}
+
+// promptNotBefore asks for the activation time of the link of the custom
+// alias wizard, which then waits for the URL again.
+func (b *Bot) promptNotBefore(r *Request) error {
+	link, ok := b.getUserState(r.ChatID).Payload.(linkPayload)
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	if !b.advanceUserState(r.ChatID, UserState{State: StateWaitingForNotBefore, Payload: link}) {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	return b.reply(r.ChatID, msgSendNotBefore, timezoneData{Timezone: b.userLocation(r.ChatID).String()})
+}
+
+// handleNotBeforeInput takes the activation time typed in the custom alias
+// wizard. The user stays at the prompt until a valid one comes.
+func (b *Bot) handleNotBeforeInput(userID int64, link linkPayload, text string) error {
+	notBefore, err := parseNotBefore(text, b.userLocation(userID))
+	if err != nil {
+		return b.reply(userID, msgInvalidNotBefore, nil)
+	}
+	if !notBefore.After(time.Now()) {
+		return b.reply(userID, msgNotBeforePast, nil)
+	}
+	link.NotBefore = notBefore
+	b.advanceUserState(userID, UserState{State: StateWaitingForURL, Payload: link})
+	return b.reply(userID, msgSendURLScheduled, scheduleData{Locale: b.formatterFor(userID), NotBefore: notBefore})
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// selfTestURL is the destination of self-test links.
+const selfTestURL = "https://example.com/"
+
+// selfTestError is a failed step of the self-test.
+type selfTestError struct {
+	Step string
+	Err  error
+}
+
+func (e *selfTestError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Step, e.Err)
+}
+
+func (e *selfTestError) Unwrap() error {
+	return e.Err
+}
+
+// isSelfTestAlias reports whether alias is reserved for self-test links.
+func (b *Bot) isSelfTestAlias(alias string) bool {
+	prefix := b.config.SelfTest.AliasPrefix
+	return len(alias) >= len(prefix) && strings.EqualFold(alias[:len(prefix)], prefix)
+}
+
+// runSelfTest creates a link with a reserved alias on behalf of userID,
+// fetches its stats and deletes it, returning the alias. The link is deleted
+// even when fetching its stats fails. It bypasses quotas and the history.
+func (b *Bot) runSelfTest(ctx context.Context, userID int64) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	alias := b.config.SelfTest.AliasPrefix + hex.EncodeToString(suffix)
+
+	_, err := b.grpcClient.CreateLink(ctx, &shortenerv1.CreateLinkRequest{
+		OriginalUrl: selfTestURL,
+		UserTgId:    userID,
+		CustomAlias: &alias,
+	})
+	if err != nil {
+		return alias, &selfTestError{Step: "CreateLink", Err: err}
+	}
+	_, statsErr := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err := b.grpcClient.DeleteLink(ctx, &shortenerv1.DeleteLinkRequest{Alias: alias}); err != nil {
+		if statsErr == nil {
+			return alias, &selfTestError{Step: "DeleteLink", Err: err}
+		}
+		b.log.Warn("failed to delete self-test link", zap.String("alias", alias), zap.Error(err))
+	}
+	if statsErr != nil {
+		return alias, &selfTestError{Step: "GetLinkStats", Err: statsErr}
+	}
+	return alias, nil
+}
+
+// selfTestReport runs the self-test, logs the outcome and renders it.
+func (b *Bot) selfTestReport(ctx context.Context, userID int64) (string, error) {
+	start := time.Now()
+	alias, err := b.runSelfTest(ctx, userID)
+	data := selfTestData{Alias: alias, Duration: time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		data.Error = err.Error()
+		b.log.Warn("self-test failed", zap.String("alias", alias), zap.Error(err))
+		return b.render(msgSelfTestFailed, data), err
+	}
+	b.log.Info("self-test passed", zap.String("alias", alias), zap.Duration("duration", data.Duration))
+	return b.render(msgSelfTestPassed, data), nil
+}
+
+// startupSelfTest runs the self-test on behalf of the first admin chat, if
+// any, and reports to the admins. The error is only returned in strict mode.
+func (b *Bot) startupSelfTest(ctx context.Context) error {
+	var userID int64
+	if admins := b.config.Telegram.AdminChatIDs; len(admins) > 0 {
+		userID = admins[0]
+	}
+	text, err := b.selfTestReport(ctx, userID)
+	b.notifyAdmins(text)
+	if err != nil && b.config.SelfTest.Strict {
+		return fmt.Errorf("startup self-test failed: %w", err)
+	}
+	return nil
+}
+
+func (b *Bot) handleSelfTestCommand(ctx context.Context, r *Request) error {
+	var text string
+	// A failure is part of the report, not an error of the command
+	_ = b.withChatAction(ctx, r.ChatID, tgbotapi.ChatTyping, func() error {
+		text, _ = b.selfTestReport(ctx, r.UserID)
+		return nil
+	})
+	return b.sendMessage(r.ChatID, text, false)
+}
@@ -0,0 +1,319 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// sendOptions controls how Telegram delivers a message.
+type sendOptions struct {
+	// silent delivers the message without a notification sound.
+	silent bool
+	// protect prevents forwarding and saving of the message.
+	protect bool
+	// replyTo is the message the message replies to.
+	replyTo int
+	// unsolicited skips the message while the user has blocked the bot.
+	unsolicited bool
+	// noWait returns as soon as the message is queued.
+	noWait bool
+}
+
+// sendOption configures sendOptions.
+type sendOption func(*sendOptions)
+
+// silent sends the message without notification when on.
+func silent(on bool) sendOption {
+	return func(o *sendOptions) { o.silent = on }
+}
+
+// protected protects the message content from forwarding when on.
+func protected(on bool) sendOption {
+	return func(o *sendOptions) { o.protect = on }
+}
+
+// replyTo sends the message as a reply to messageID.
+func replyTo(messageID int) sendOption {
+	return func(o *sendOptions) { o.replyTo = messageID }
+}
+
+// noWait queues the message without waiting for it to be sent. Failures
+// are only logged.
+func noWait() sendOption {
+	return func(o *sendOptions) { o.noWait = true }
+}
+
+// linkContent marks a message carrying short links, which is protected when
+// the deployment asks for it.
+func (b *Bot) linkContent() sendOption {
+	return protected(b.config.Telegram.ProtectContent)
+}
+
+// notification marks an unsolicited notification, which is silent unless
+// the user turned notification sounds on and isn't sent at all to users who
+// blocked the bot.
+func (b *Bot) notification(chatID int64) sendOption {
+	sound := b.prefs.Get(chatID).NotificationSound
+	return func(o *sendOptions) {
+		o.silent = !sound
+		o.unsolicited = true
+	}
+}
+
+// send sends msg with the given options through the send queue. Unsolicited
+// notifications may be dropped when the queue is full.
+func (b *Bot) send(msg tgbotapi.MessageConfig, opts ...sendOption) (tgbotapi.Message, error) {
+	var o sendOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.unsolicited && !b.users.Reachable(msg.ChatID) {
+		metrics.BlockedSendsAvoided.Add(1)
+		b.log.Debug("not notifying user who blocked the bot", zap.Int64("chat_id", msg.ChatID))
+		return tgbotapi.Message{}, nil
+	}
+	msg.DisableNotification = o.silent
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if k, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+		if b.users.BasicKeyboards(msg.ChatID) {
+			k, _ = basicKeyboard(k)
+			msg.ReplyMarkup = k
+		}
+		keyboard = &k
+	}
+	plain := b.outputStyle(msg.ChatID) == stylePlain
+	text := msg.Text
+	if plain {
+		msg.Text = b.plainText(msg.Text, keyboard, msg.ParseMode == tgbotapi.ModeHTML)
+	}
+	if o.replyTo != 0 {
+		msg.ReplyToMessageID = o.replyTo
+	}
+
+	done := b.sendQueue.Submit(msg.ChatID, o.unsolicited, func() (tgbotapi.Message, error) {
+		start := time.Now()
+		defer func() { b.timings.addTelegram(msg.ChatID, time.Since(start)) }()
+		deliver := func() (tgbotapi.Message, error) {
+			if o.protect {
+				return b.sendProtected(msg)
+			}
+			return b.api.Send(msg)
+		}
+		sent, err := deliver()
+		if classifyTelegramError(err) == telegramErrorMarkup && keyboard != nil {
+			// Old clients fail on some advanced buttons; plain ones stand
+			// in for them, once
+			if basic, ok := basicKeyboard(*keyboard); ok {
+				b.log.Info("keyboard refused, retrying with plain buttons", zap.Int64("chat_id", msg.ChatID), zap.Error(err))
+				keyboard = &basic
+				msg.ReplyMarkup = basic
+				sent, err = deliver()
+				if err == nil && b.users.MarkBasicKeyboards(msg.ChatID, time.Now()) {
+					b.log.Info("user gets plain buttons from now on", zap.Int64("chat_id", msg.ChatID))
+				}
+			}
+		}
+		if err == nil && keyboard != nil {
+			b.trackKeyboard(msg.ChatID, sent.MessageID, keyboard)
+		}
+		if err == nil && plain && keyboard != nil {
+			b.replyOptions.Set(msg.ChatID, replyOptions{
+				MessageID: sent.MessageID,
+				Text:      text,
+				ParseMode: msg.ParseMode,
+				NoPreview: msg.DisableWebPagePreview,
+				Keyboard:  *keyboard,
+			})
+		}
+		if isBlockedError(err) && b.users.MarkBlocked(msg.ChatID, time.Now()) {
+			b.log.Info("user blocked the bot", zap.Int64("chat_id", msg.ChatID))
+		}
+		if err != nil && o.noWait {
+			b.log.Warn("failed to send message", zap.Int64("chat_id", msg.ChatID), zap.Error(err))
+		}
+		return sent, err
+	})
+	if o.noWait {
+		return tgbotapi.Message{}, nil
+	}
+	res := <-done
+	return res.msg, undelivered(res.err)
+}
+
+// telegramErrorKind is what a failed Bot API call means to the bot.
+type telegramErrorKind int
+
+const (
+	telegramErrorOther telegramErrorKind = iota
+	// telegramErrorNotModified is an edit that would leave the message as it is.
+	telegramErrorNotModified
+	// telegramErrorMessageGone is an edit of a message that was deleted or is
+	// too old to be edited.
+	telegramErrorMessageGone
+	// telegramErrorBlocked is a message to a user who blocked the bot or
+	// deleted their account.
+	telegramErrorBlocked
+	// telegramErrorUnauthorized is any call once the bot token was revoked.
+	telegramErrorUnauthorized
+	// telegramErrorTransient is a Telegram server error or a failed
+	// connection, which may pass when tried again.
+	telegramErrorTransient
+	// telegramErrorMarkup is a message refused for a button of its keyboard
+	// the client of the chat doesn't support.
+	telegramErrorMarkup
+)
+
+// classifyTelegramError tells what err, returned by a Bot API call, means.
+// tgbotapi only exposes the status code and the description, so this is the
+// one place matching on them.
+func classifyTelegramError(err error) telegramErrorKind {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return telegramErrorTransient
+		}
+		return telegramErrorOther
+	}
+	if tgErr.Code >= http.StatusInternalServerError {
+		return telegramErrorTransient
+	}
+	description := strings.ToLower(tgErr.Message)
+	switch tgErr.Code {
+	case http.StatusUnauthorized:
+		return telegramErrorUnauthorized
+	case http.StatusBadRequest:
+		switch {
+		case strings.Contains(description, "message is not modified"):
+			return telegramErrorNotModified
+		case strings.Contains(description, "message to edit not found"),
+			strings.Contains(description, "message can't be edited"),
+			strings.Contains(description, "message_id_invalid"):
+			return telegramErrorMessageGone
+		case strings.Contains(description, "button_type_invalid"),
+			strings.Contains(description, "can't parse inline keyboard button"),
+			strings.Contains(description, "unsupported button"):
+			return telegramErrorMarkup
+		}
+	case http.StatusForbidden:
+		// Other 403s, like being kicked from a group, don't count
+		if strings.Contains(description, "bot was blocked by the user") ||
+			strings.Contains(description, "user is deactivated") {
+			return telegramErrorBlocked
+		}
+	}
+	return telegramErrorOther
+}
+
+// retryAfter returns how long Telegram asks to wait when err is a flood
+// limit error.
+func retryAfter(err error) (time.Duration, bool) {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return time.Duration(max(tgErr.RetryAfter, 1)) * time.Second, true
+}
+
+// retryWait returns how long to wait before trying again a call that failed
+// with err for the attempt-th time, counting from 0, and false when trying
+// again won't help or Telegram asks to wait longer than maxWait.
+func retryWait(err error, attempt int, maxWait time.Duration) (time.Duration, bool) {
+	if wait, ok := retryAfter(err); ok {
+		return wait, wait <= maxWait
+	}
+	if classifyTelegramError(err) != telegramErrorTransient {
+		return 0, false
+	}
+	return min(time.Second<<attempt, maxWait), true
+}
+
+// isBlockedError reports whether err means the user blocked the bot or
+// deleted their account.
+func isBlockedError(err error) bool {
+	return classifyTelegramError(err) == telegramErrorBlocked
+}
+
+// editMessage sends edit, an edit of a message sent before, through the send
+// queue and handles the failures expected of edits. An edit changing nothing is fine, noted on
+// answer if set. When the message is gone or too old to edit, resend sends a
+// new one instead; with a nil resend the edit is dropped. Other errors are
+// returned.
+func (b *Bot) editMessage(edit tgbotapi.Chattable, answer *callbackAnswer, resend func() error) error {
+	chatID := editChatID(edit)
+	if b.outputStyle(chatID) == stylePlain {
+		edit = b.plainEdit(edit)
+	}
+	res := <-b.sendQueue.Submit(chatID, false, func() (tgbotapi.Message, error) {
+		start := time.Now()
+		defer func() { b.timings.addTelegram(chatID, time.Since(start)) }()
+		return b.api.Send(edit)
+	})
+	err := res.err
+	if err == nil {
+		b.trackEdit(edit)
+		return nil
+	}
+	switch classifyTelegramError(err) {
+	case telegramErrorNotModified:
+		answer.note(b.render(msgToastNoChanges, nil))
+		return nil
+	case telegramErrorMessageGone:
+		b.log.Debug("message can't be edited", zap.Error(err))
+		if resend == nil {
+			return nil
+		}
+		return resend()
+	}
+	return undelivered(err)
+}
+
+// editChatID returns the chat of edit, which the send queue orders it in.
+func editChatID(edit tgbotapi.Chattable) int64 {
+	switch e := edit.(type) {
+	case tgbotapi.EditMessageTextConfig:
+		return e.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return e.ChatID
+	case tgbotapi.EditMessageCaptionConfig:
+		return e.ChatID
+	}
+	return 0
+}
+
+// sendProtected sends msg with protect_content, which the Telegram library
+// version in use doesn't support on MessageConfig.
+func (b *Bot) sendProtected(msg tgbotapi.MessageConfig) (tgbotapi.Message, error) {
+	params := tgbotapi.Params{}
+	if err := params.AddFirstValid("chat_id", msg.ChatID, msg.ChannelUsername); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	params.AddNonEmpty("text", msg.Text)
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	params.AddBool("disable_web_page_preview", msg.DisableWebPagePreview)
+	params.AddNonZero("reply_to_message_id", msg.ReplyToMessageID)
+	params.AddBool("disable_notification", msg.DisableNotification)
+	params.AddBool("protect_content", true)
+	if err := params.AddInterface("reply_markup", msg.ReplyMarkup); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	resp, err := b.api.MakeRequest("sendMessage", params)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("failed to decode sent message: %w", err)
+	}
+	return sent, nil
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/telegramtest"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSendProtectsLinkContent(t *testing.T) {
+	for _, protect := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.Telegram.ProtectContent = protect
+		e := startBot(t, cfg)
+
+		e.tg.SendMessage(user, "/shorten https://example.com/a")
+		created := e.tg.WaitText(user, "Link created successfully")
+		if got := created.Param("protect_content") == "true"; got != protect {
+			t.Errorf("protect_content = %v with protect_content set to %v", got, protect)
+		}
+
+		// Messages without links are never protected
+		e.tg.SendMessage(user, "/help")
+		help := e.tg.Wait("sendMessage", nil)
+		if help.Param("protect_content") == "true" {
+			t.Errorf("help protected with protect_content set to %v", protect)
+		}
+	}
+}
+
+func TestSendNotificationsSilentUnlessSoundOn(t *testing.T) {
+	e := startBot(t, nil)
+	notify := func() telegramtest.Request {
+		t.Helper()
+		if _, err := e.bot.send(tgbotapi.NewMessage(user, "Milestone"), e.bot.notification(user)); err != nil {
+			t.Fatal(err)
+		}
+		return e.tg.WaitText(user, "Milestone")
+	}
+
+	if r := notify(); r.Param("disable_notification") != "true" {
+		t.Error("notification not silent by default")
+	}
+
+	e.tg.SendMessage(user, "/settings")
+	settings := e.tg.Wait("sendMessage", func(r telegramtest.Request) bool {
+		_, ok := r.Button("Notification sound: off")
+		return ok
+	})
+	e.press(t, settings, "Notification sound: off")
+	e.tg.Wait("editMessageText", func(r telegramtest.Request) bool {
+		_, ok := r.Button("Notification sound: on")
+		return ok
+	})
+
+	if r := notify(); r.Param("disable_notification") == "true" {
+		t.Error("notification silent with sound turned on")
+	}
+
+	// Answers to the user are never silent
+	e.tg.SendMessage(user, "/shorten https://example.com/a")
+	if r := e.tg.WaitText(user, "Link created successfully"); r.Param("disable_notification") == "true" {
+		t.Error("reply sent silently")
+	}
+}
+
+func TestClassifyTelegramError(t *testing.T) {
+	apiError := func(code int, description string) error {
+		return &tgbotapi.Error{Code: code, Message: description}
+	}
+	for _, tt := range []struct {
+		name string
+		err  error
+		want telegramErrorKind
+	}{
+		{"not modified", apiError(http.StatusBadRequest, "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"), telegramErrorNotModified},
+		{"not found", apiError(http.StatusBadRequest, "Bad Request: message to edit not found"), telegramErrorMessageGone},
+		{"too old", apiError(http.StatusBadRequest, "Bad Request: message can't be edited"), telegramErrorMessageGone},
+		{"invalid id", apiError(http.StatusBadRequest, "Bad Request: MESSAGE_ID_INVALID"), telegramErrorMessageGone},
+		{"unsupported button", apiError(http.StatusBadRequest, "Bad Request: BUTTON_TYPE_INVALID"), telegramErrorMarkup},
+		{"unparsable button", apiError(http.StatusBadRequest, "Bad Request: can't parse inline keyboard button: Text buttons are unallowed in the inline keyboard"), telegramErrorMarkup},
+		{"button unsupported by client", apiError(http.StatusBadRequest, "Bad Request: unsupported button type"), telegramErrorMarkup},
+		{"other bad request", apiError(http.StatusBadRequest, "Bad Request: chat not found"), telegramErrorOther},
+		{"blocked", apiError(http.StatusForbidden, "Forbidden: bot was blocked by the user"), telegramErrorBlocked},
+		{"deactivated", apiError(http.StatusForbidden, "Forbidden: user is deactivated"), telegramErrorBlocked},
+		{"kicked", apiError(http.StatusForbidden, "Forbidden: bot was kicked from the group chat"), telegramErrorOther},
+		{"unauthorized", apiError(http.StatusUnauthorized, "Unauthorized"), telegramErrorUnauthorized},
+		{"server error", apiError(http.StatusBadGateway, "Bad Gateway"), telegramErrorTransient},
+		{"connection", &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: errors.New("connection reset")}, telegramErrorTransient},
+		{"wrapped", fmt.Errorf("edit failed: %w", apiError(http.StatusBadRequest, "Bad Request: message is not modified")), telegramErrorNotModified},
+		{"not from telegram", errors.New("boom"), telegramErrorOther},
+	} {
+		if got := classifyTelegramError(tt.err); got != tt.want {
+			t.Errorf("%s: kind %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// refuseAdvancedButtons makes the fake API refuse messages with inline
+// query buttons, as old clients do, and returns how many it refused.
+func (e *e2e) refuseAdvancedButtons() *atomic.Int32 {
+	var refused atomic.Int32
+	e.tg.Handle("sendMessage", func(r telegramtest.Request) (any, error) {
+		for _, b := range r.Buttons() {
+			if b.SwitchInlineQuery != nil {
+				refused.Add(1)
+				return nil, &telegramtest.APIError{Code: http.StatusBadRequest, Description: "Bad Request: BUTTON_TYPE_INVALID"}
+			}
+		}
+		return e.tg.Default(r)
+	})
+	return &refused
+}
+
+func TestE2EKeyboardFallsBackToPlainButtons(t *testing.T) {
+	e := startBot(t, nil)
+	e.tg.SendMessage(user, "/help")
+	e.tg.Wait("sendMessage", nil)
+	refused := e.refuseAdvancedButtons()
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonSwitch("Share", "abc"),
+		tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc"),
+	))
+	share := func(text string) telegramtest.Request {
+		t.Helper()
+		msg := tgbotapi.NewMessage(user, text)
+		msg.ReplyMarkup = keyboard
+		sent, err := e.bot.send(msg)
+		if err != nil {
+			t.Fatalf("send with a refused keyboard = %v, want it retried", err)
+		}
+		r := e.tg.Wait("sendMessage", func(r telegramtest.Request) bool { return r.Text() == text && r.MessageID != 0 })
+		if sent.MessageID != r.MessageID {
+			t.Errorf("send returned message %d, want %d", sent.MessageID, r.MessageID)
+		}
+		return r
+	}
+
+	first := share("First")
+	if refused.Load() != 1 {
+		t.Errorf("%d messages refused, want the first try", refused.Load())
+	}
+	buttons := first.Buttons()
+	if len(buttons) != 2 || buttons[0].SwitchInlineQuery != nil || buttons[0].URL == nil || *buttons[0].URL != shareURL+"abc" {
+		t.Errorf("retried with %+v, want a share link standing in for the inline query button", buttons)
+	}
+	// Plain buttons are kept as they were
+	if data, ok := first.Button("Stats"); !ok || data != "stats:abc" {
+		t.Errorf("Stats button = %q, %v after the retry", data, ok)
+	}
+	if !e.bot.users.BasicKeyboards(user) {
+		t.Fatal("user not marked for plain buttons")
+	}
+
+	// Later messages get plain buttons without being refused first
+	second := share("Second")
+	if refused.Load() != 1 {
+		t.Errorf("%d messages refused, want plain buttons sent right away", refused.Load())
+	}
+	if buttons := second.Buttons(); len(buttons) != 2 || buttons[0].URL == nil {
+		t.Errorf("second message sent with %+v, want plain buttons", buttons)
+	}
+}
+
+func TestE2EKeyboardRefusedWithoutStandIn(t *testing.T) {
+	e := startBot(t, nil)
+	e.tg.SendMessage(user, "/help")
+	e.tg.Wait("sendMessage", nil)
+	e.tg.Fail("sendMessage", http.StatusBadRequest, "Bad Request: BUTTON_TYPE_INVALID")
+
+	// A keyboard of plain buttons has nothing to fall back on
+	msg := tgbotapi.NewMessage(user, "Plain")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Stats", "stats:abc")))
+	if _, err := e.bot.send(msg); err == nil {
+		t.Error("send of a refused plain keyboard succeeded")
+	}
+	if n := len(e.tg.Requests("sendMessage")); n != 2 {
+		t.Errorf("refused message tried %d times, want once", n-1)
+	}
+	if e.bot.users.BasicKeyboards(user) {
+		t.Error("user marked for plain buttons without a fallback sent")
+	}
+}
+
+func TestEditMessageErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		description string
+		wantErr     bool
+		wantResend  bool
+		wantToast   string
+	}{
+		{name: "not modified", description: "Bad Request: message is not modified", wantToast: "No changes"},
+		{name: "not found", description: "Bad Request: message to edit not found", wantResend: true},
+		{name: "too old", description: "Bad Request: message can't be edited", wantResend: true},
+		{name: "other", description: "Bad Request: chat not found", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := startBot(t, nil)
+			e.tg.Fail("editMessageText", http.StatusBadRequest, tt.description)
+
+			answer := newCallbackAnswer("query")
+			resent := false
+			err := e.bot.editMessage(tgbotapi.NewEditMessageText(user, 1, "Edited"), answer, func() error {
+				resent = true
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("editMessage = %v, want error %v", err, tt.wantErr)
+			}
+			if resent != tt.wantResend {
+				t.Errorf("resent = %v, want %v", resent, tt.wantResend)
+			}
+			if answer.text != tt.wantToast {
+				t.Errorf("toast = %q, want %q", answer.text, tt.wantToast)
+			}
+
+			// Without a way to resend, a gone message is dropped
+			if tt.wantResend {
+				if err := e.bot.editMessage(tgbotapi.NewEditMessageText(user, 1, "Edited"), nil, nil); err != nil {
+					t.Errorf("editMessage without resend = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"errors"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// errSendDropped is the result of a notification dropped from the full send
+// queue.
+var errSendDropped = errors.New("notification dropped from the full send queue")
+
+// sendResult is the outcome of a queued Bot API call.
+type sendResult struct {
+	msg tgbotapi.Message
+	err error
+}
+
+// outgoing is a Bot API call waiting in the send queue.
+type outgoing struct {
+	do func() (tgbotapi.Message, error)
+	// droppable calls are notifications, which give way to replies when the
+	// queue is full
+	droppable bool
+	seq       uint64
+	done      chan sendResult
+}
+
+// sendQueue serializes the Bot API calls for a chat in submission order, so
+// a reply can't overtake the message it follows, and spaces all calls to
+// stay under Telegram's global rate limit. It holds at most size calls:
+// when full, the oldest waiting notification is dropped to make room, a
+// notification is dropped outright when there is none, and a reply waits.
+// Replies failing in a way that may pass are tried up to retries more
+// times, holding back the calls queued after them for the chat, so
+// handlers don't have to.
+type sendQueue struct {
+	mu      sync.Mutex
+	space   *sync.Cond
+	size    int
+	pending int
+	seq     uint64
+	// chats holds the waiting calls of chats with a running drainer
+	chats   map[int64][]*outgoing
+	limiter *sendLimiter
+	retries int
+	maxWait time.Duration
+	log     *zap.Logger
+}
+
+func newSendQueue(size, rate, retries int, maxWait time.Duration, log *zap.Logger) *sendQueue {
+	q := &sendQueue{
+		size:    size,
+		chats:   make(map[int64][]*outgoing),
+		limiter: &sendLimiter{interval: time.Second / time.Duration(rate)},
+		retries: retries,
+		maxWait: maxWait,
+		log:     log,
+	}
+	q.space = sync.NewCond(&q.mu)
+	return q
+}
+
+// Submit queues do for chatID. The result arrives on the returned channel,
+// which is buffered so nobody has to read it.
+func (q *sendQueue) Submit(chatID int64, droppable bool, do func() (tgbotapi.Message, error)) <-chan sendResult {
+	item := &outgoing{do: do, droppable: droppable, done: make(chan sendResult, 1)}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.pending >= q.size && !q.dropOldestLocked() {
+		if droppable {
+			metrics.SendQueueDropped.Add(1)
+			item.done <- sendResult{err: errSendDropped}
+			return item.done
+		}
+		q.space.Wait()
+	}
+
+	q.seq++
+	item.seq = q.seq
+	waiting, draining := q.chats[chatID]
+	q.chats[chatID] = append(waiting, item)
+	q.pending++
+	metrics.SendQueueDepth.Set(int64(q.pending))
+	if !draining {
+		go q.drain(chatID)
+	}
+	return item.done
+}
+
+// dropOldestLocked drops the notification waiting the longest and reports
+// whether there was one.
+func (q *sendQueue) dropOldestLocked() bool {
+	var oldest *outgoing
+	var oldestChat int64
+	var oldestIndex int
+	for chatID, waiting := range q.chats {
+		for i, item := range waiting {
+			if item.droppable && (oldest == nil || item.seq < oldest.seq) {
+				oldest, oldestChat, oldestIndex = item, chatID, i
+			}
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	waiting := q.chats[oldestChat]
+	q.chats[oldestChat] = append(waiting[:oldestIndex:oldestIndex], waiting[oldestIndex+1:]...)
+	q.pending--
+	metrics.SendQueueDropped.Add(1)
+	q.log.Debug("dropped notification from the full send queue", zap.Int64("chat_id", oldestChat))
+	oldest.done <- sendResult{err: errSendDropped}
+	return true
+}
+
+// drain makes the calls waiting for chatID one after another until there
+// are none left.
+func (q *sendQueue) drain(chatID int64) {
+	for {
+		q.mu.Lock()
+		waiting := q.chats[chatID]
+		if len(waiting) == 0 {
+			delete(q.chats, chatID)
+			q.mu.Unlock()
+			return
+		}
+		item := waiting[0]
+		q.chats[chatID] = waiting[1:]
+		q.pending--
+		metrics.SendQueueDepth.Set(int64(q.pending))
+		q.space.Signal()
+		q.mu.Unlock()
+
+		item.done <- q.call(chatID, item)
+	}
+}
+
+// call makes the call of item, retrying replies that failed in a way that
+// may pass.
+func (q *sendQueue) call(chatID int64, item *outgoing) sendResult {
+	q.limiter.Wait()
+	msg, err := item.do()
+	for attempt := 0; err != nil && !item.droppable && attempt < q.retries; attempt++ {
+		wait, ok := retryWait(err, attempt, q.maxWait)
+		if !ok {
+			break
+		}
+		metrics.SendRetries.Add(1)
+		q.log.Debug("retrying failed Bot API call",
+			zap.Int64("chat_id", chatID),
+			zap.Duration("wait", wait),
+			zap.Error(err))
+		time.Sleep(wait)
+		q.limiter.Wait()
+		msg, err = item.do()
+	}
+	return sendResult{msg: msg, err: err}
+}
+
+// sendLimiter spaces calls at least interval apart.
+type sendLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Wait blocks until the next call may be made.
+func (l *sendLimiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
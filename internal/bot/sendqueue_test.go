@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// testSendQueue returns a queue fast enough not to slow tests down.
+func testSendQueue(size, retries int) *sendQueue {
+	return newSendQueue(size, 100000, retries, 10*time.Millisecond, zap.NewNop())
+}
+
+func TestSendQueueChatOrder(t *testing.T) {
+	const (
+		chats      = 8
+		submitters = 4
+		perSubmit  = 50
+	)
+	q := testSendQueue(chats*submitters*perSubmit, 0)
+
+	var mu sync.Mutex
+	// submitted and made hold the call numbers of each chat in the order
+	// they were submitted and made
+	submitted := make(map[int64][]int)
+	made := make(map[int64][]int)
+	running := make(map[int64]bool)
+
+	var wg sync.WaitGroup
+	for s := range submitters {
+		for chat := range int64(chats) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var results []<-chan sendResult
+				for i := range perSubmit {
+					n := s*perSubmit + i
+					do := func() (tgbotapi.Message, error) {
+						mu.Lock()
+						if running[chat] {
+							t.Errorf("chat %d: calls made concurrently", chat)
+						}
+						running[chat] = true
+						made[chat] = append(made[chat], n)
+						mu.Unlock()
+						time.Sleep(time.Microsecond)
+						mu.Lock()
+						running[chat] = false
+						mu.Unlock()
+						return tgbotapi.Message{MessageID: n}, nil
+					}
+					// Submission order is the order Submit takes the
+					// queue lock, which recording under mu pins down
+					mu.Lock()
+					results = append(results, q.Submit(chat, i%3 == 0, do))
+					submitted[chat] = append(submitted[chat], n)
+					mu.Unlock()
+				}
+				for i, res := range results {
+					if r := <-res; r.err != nil || r.msg.MessageID != s*perSubmit+i {
+						t.Errorf("chat %d call %d: result %+v", chat, s*perSubmit+i, r)
+					}
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for chat := range int64(chats) {
+		if !slices.Equal(made[chat], submitted[chat]) {
+			t.Errorf("chat %d: calls made in order %v, submitted %v", chat, made[chat], submitted[chat])
+		}
+	}
+}
+
+func TestSendQueueRetryHoldsBackChat(t *testing.T) {
+	q := testSendQueue(10, 2)
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string, err error) func() (tgbotapi.Message, error) {
+		return func() (tgbotapi.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+			if err != nil && slices.Index(calls, name) == len(calls)-1 {
+				// Only the first attempt fails
+				return tgbotapi.Message{}, err
+			}
+			return tgbotapi.Message{}, nil
+		}
+	}
+
+	flaky := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	first := q.Submit(1, false, record("first", flaky))
+	second := q.Submit(1, false, record("second", nil))
+	if r := <-first; r.err != nil {
+		t.Errorf("first: %v", r.err)
+	}
+	<-second
+
+	want := []string{"first", "first", "second"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestSendQueueNotificationsNotRetried(t *testing.T) {
+	q := testSendQueue(10, 2)
+	attempts := 0
+	res := <-q.Submit(1, true, func() (tgbotapi.Message, error) {
+		attempts++
+		return tgbotapi.Message{}, &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	})
+	if res.err == nil || attempts != 1 {
+		t.Errorf("err = %v after %d attempts, want one failed attempt", res.err, attempts)
+	}
+}
+
+func TestSendQueueFullDropsOldestNotification(t *testing.T) {
+	q := testSendQueue(2, 0)
+	release := make(chan struct{})
+	blocked := q.Submit(1, false, func() (tgbotapi.Message, error) {
+		<-release
+		return tgbotapi.Message{}, nil
+	})
+	// Wait for the drainer to take the blocking call out of the queue
+	for {
+		q.mu.Lock()
+		pending := q.pending
+		q.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The chat's calls now wait behind the blocking one
+	ok := func() (tgbotapi.Message, error) { return tgbotapi.Message{}, nil }
+	oldest := q.Submit(1, true, ok)
+	newer := q.Submit(1, true, ok)
+	// A notification makes room by dropping the oldest one
+	third := q.Submit(1, true, ok)
+	if r := <-oldest; !errors.Is(r.err, errSendDropped) {
+		t.Errorf("oldest notification: err = %v, want dropped", r.err)
+	}
+	// So do replies, never dropped themselves
+	reply := q.Submit(1, false, ok)
+	if r := <-newer; !errors.Is(r.err, errSendDropped) {
+		t.Errorf("newer notification: err = %v, want dropped", r.err)
+	}
+	another := q.Submit(1, false, ok)
+	if r := <-third; !errors.Is(r.err, errSendDropped) {
+		t.Errorf("third notification: err = %v, want dropped", r.err)
+	}
+	// With only replies waiting, a notification is dropped outright
+	if r := <-q.Submit(1, true, ok); !errors.Is(r.err, errSendDropped) {
+		t.Errorf("notification to a queue full of replies: err = %v, want dropped", r.err)
+	}
+
+	close(release)
+	for _, res := range []<-chan sendResult{blocked, reply, another} {
+		if r := <-res; r.err != nil {
+			t.Errorf("err = %v, want sent", r.err)
+		}
+	}
+}
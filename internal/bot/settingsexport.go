@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/locale"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSettingsFileSize caps the files /import_settings accepts.
+	maxSettingsFileSize = 64 << 10
+	// settingsFileTimeout bounds downloading a settings file from Telegram.
+	settingsFileTimeout = 30 * time.Second
+	settingsFileName    = "gurls-settings.json"
+)
+
+// handleExportSettingsCommand sends the user's settings and what the bot
+// keeps about their links as a JSON file for /import_settings.
+func (b *Bot) handleExportSettingsCommand(ctx context.Context, r *Request) error {
+	data, err := json.MarshalIndent(b.prefs.Get(r.ChatID).Export(time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+	doc := tgbotapi.NewDocument(r.ChatID, tgbotapi.FileBytes{Name: settingsFileName, Bytes: data})
+	doc.Caption = b.render(msgSettingsExported, nil)
+	res := <-b.sendQueue.Submit(r.ChatID, false, func() (tgbotapi.Message, error) {
+		return b.api.Send(doc)
+	})
+	return undelivered(res.err)
+}
+
+// handleImportSettingsCommand imports the settings file the command replies
+// to, or else waits for one.
+func (b *Bot) handleImportSettingsCommand(ctx context.Context, r *Request) error {
+	if reply := r.Message.ReplyToMessage; reply != nil && reply.Document != nil {
+		return b.previewSettingsImport(ctx, r.Payloads(), reply.Document)
+	}
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForSettingsFile}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(r.ChatID, msgSendSettingsFile, limitData{Limit: maxSettingsFileSize >> 10}, b.createCancelKeyboard())
+}
+
+// handleSettingsFileInput takes the settings file sent for /import_settings.
+// The user stays at the prompt until a file comes.
+func (b *Bot) handleSettingsFileInput(ctx context.Context, msg *tgbotapi.Message) error {
+	if msg.Document == nil {
+		return b.reply(msg.Chat.ID, msgSendSettingsFile, limitData{Limit: maxSettingsFileSize >> 10})
+	}
+	b.resetUserState(msg.Chat.ID)
+	return b.previewSettingsImport(ctx, messagePayloads(msg), msg.Document)
+}
+
+// previewSettingsImport checks the settings file doc and shows what
+// importing it would change, with a button applying it. Files that are too
+// large, malformed, from a newer version or referring to links of someone
+// else are refused.
+func (b *Bot) previewSettingsImport(ctx context.Context, to payloadKey, doc *tgbotapi.Document) error {
+	if doc.FileSize > maxSettingsFileSize {
+		return b.reply(to.chatID, msgSettingsFileTooLarge, limitData{Limit: maxSettingsFileSize >> 10})
+	}
+	data, err := b.downloadFile(ctx, doc.FileID, maxSettingsFileSize)
+	if errors.Is(err, errFileTooLarge) {
+		return b.reply(to.chatID, msgSettingsFileTooLarge, limitData{Limit: maxSettingsFileSize >> 10})
+	}
+	if err != nil {
+		b.log.Error("failed to download settings file", zap.Error(err))
+		return b.reply(to.chatID, msgInternalError, nil)
+	}
+
+	export, err := prefs.ParseExport(data)
+	if errors.Is(err, prefs.ErrNewerExport) {
+		return b.reply(to.chatID, msgSettingsFileNewer, nil)
+	}
+	if err == nil {
+		err = b.validateExport(export)
+	}
+	if err != nil {
+		return b.reply(to.chatID, msgSettingsFileInvalid, errorData{Error: err.Error()})
+	}
+
+	if aliases := export.Aliases(); len(aliases) > 0 {
+		res, err := b.listUserLinks(ctx, to.chatID)
+		if err != nil {
+			b.log.Error("gRPC ListUserLinks failed", zap.Error(err))
+			return b.replyGRPCError(to.chatID, err, "")
+		}
+		owned := make(map[string]bool, len(res.GetLinks()))
+		for _, link := range res.GetLinks() {
+			owned[link.GetAlias()] = true
+		}
+		foreign := slices.DeleteFunc(aliases, func(alias string) bool { return owned[alias] })
+		if len(foreign) > 0 {
+			return b.reply(to.chatID, msgSettingsFileForeignLinks, foreignLinksData{Aliases: foreign})
+		}
+	}
+
+	current := b.prefs.Get(to.chatID)
+	changes := export.Apply(&current)
+	if changes.Empty() {
+		return b.reply(to.chatID, msgSettingsImportUnchanged, nil)
+	}
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.storedPayloadButton(to, "Import", actionImportSettings, string(payload)),
+		b.callbackButton("Cancel", callbackImportCancel),
+	))
+	return b.replyWithKeyboard(to.chatID, msgSettingsImportPreview, importData{
+		Settings:  changes.Settings,
+		Pins:      changes.PinsAdded,
+		Kept:      changes.KeptAdded,
+		Snapshots: changes.SnapshotsSet,
+		Campaigns: changes.CampaignsSet,
+	}, keyboard)
+}
+
+// importSettings applies the settings file confirmed in the preview.
+func (b *Bot) importSettings(r *Request) error {
+	export, err := prefs.ParseExport([]byte(r.Args))
+	if err != nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	var changes prefs.ImportChanges
+	err = b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		changes = export.Apply(p)
+		b.appendHistory(p, prefs.HistoryEntry{Action: historySettings, Setting: "settings by import"})
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	b.log.Info("settings imported",
+		zap.Int64("user_id", r.ChatID),
+		zap.Int("settings", len(changes.Settings)),
+		zap.Int("pins", changes.PinsAdded))
+	return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgSettingsImported, nil))
+}
+
+// validateExport checks the settings of e against what this deployment
+// offers.
+func (b *Bot) validateExport(e prefs.Export) error {
+	s := e.Settings
+	if s.Expiry != nil && *s.Expiry < 0 {
+		return errors.New("the default expiry is negative")
+	}
+	if s.LinkStyle != nil && *s.LinkStyle != "" && *s.LinkStyle != linkStyleCompact && *s.LinkStyle != linkStyleCard {
+		return fmt.Errorf("unknown link style %q", *s.LinkStyle)
+	}
+	if s.Language != nil && *s.Language != "" && !locale.Supported(*s.Language) {
+		return fmt.Errorf("unknown number and date format %q", *s.Language)
+	}
+	if s.Timezone != nil && *s.Timezone != "" {
+		if _, err := time.LoadLocation(*s.Timezone); err != nil {
+			return fmt.Errorf("unknown time zone %q", *s.Timezone)
+		}
+	}
+	if s.Domain != nil && *s.Domain != "" {
+		if _, ok := b.findDomain(*s.Domain); !ok {
+			return fmt.Errorf("the default domain %s isn't available here", *s.Domain)
+		}
+	}
+	if len(e.Campaigns) > b.config.Campaigns.MaxPerUser {
+		return fmt.Errorf("more than %d campaigns", b.config.Campaigns.MaxPerUser)
+	}
+	for _, c := range e.Campaigns {
+		switch {
+		case !campaignNameRegex.MatchString(c.Name):
+			return fmt.Errorf("invalid campaign name %q", c.Name)
+		case len(c.Aliases) > b.config.Campaigns.MaxLinks:
+			return fmt.Errorf("campaign %s has more than %d links", c.Name, b.config.Campaigns.MaxLinks)
+		}
+	}
+	return nil
+}
+
+// errFileTooLarge is returned by downloadFile for files over the limit.
+var errFileTooLarge = errors.New("file too large")
+
+// downloadFile fetches a file sent to the bot, up to limit bytes.
+func (b *Bot) downloadFile(ctx context.Context, fileID string, limit int64) ([]byte, error) {
+	fileURL, err := b.api.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, settingsFileTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// linkTitleUpdate stands for changing titles through UpdateLink among the
+// capabilities. Backends that can only set titles on creation leave the
+// title out of the response, which is only noticed on use.
+const linkTitleUpdate = shortenerv1.Shortener_UpdateLink_FullMethodName + "#title"
+
+// maxTitleLen caps the titles typed for existing links.
+const maxTitleLen = 100
+
+// shortenFromPayload is the site a "Shorten another" shortcut creates the
+// next link on.
+type shortenFromPayload struct {
+	// Prefix is the scheme and host of the link just created, e.g.
+	// "https://example.com/".
+	Prefix string `json:"p"`
+	// Domain is the short domain the link was created on.
+	Domain string `json:"d,omitempty"`
+}
+
+// createShortcutRows returns the shortcuts offered under a created link
+// for what users commonly do next: adding the title or the expiry it was
+// created without, and shortening another page of the same site. The
+// buttons keep their state in the payload store and fall back to the plain
+// flows once it expired.
+func (b *Bot) createShortcutRows(to payloadKey, alias string, req *shortenerv1.CreateLinkRequest) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var link []tgbotapi.InlineKeyboardButton
+	if req.GetTitle() == "" && b.supports(featureTitleUpdate) {
+		link = append(link, b.storedPayloadButton(to, "Add title", actionAddTitle, alias))
+	}
+	if req.ExpiresAt == nil && b.supports(featureExtend) {
+		link = append(link, b.storedPayloadButton(to, "Set expiry", actionSetExpiry, alias))
+	}
+	if len(link) > 0 {
+		rows = append(rows, link)
+	}
+	if u, err := url.Parse(req.GetOriginalUrl()); err == nil && u.Host != "" {
+		payload, err := json.Marshal(shortenFromPayload{Prefix: u.Scheme + "://" + u.Host + "/", Domain: req.GetDomain()})
+		if err == nil {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				b.storedPayloadButton(to, "Shorten another from "+u.Hostname(), actionShortenFrom, string(payload)),
+			))
+		}
+	}
+	return rows
+}
+
+// startAddTitle asks for the title of the link just created.
+func (b *Bot) startAddTitle(r *Request) error {
+	alias := r.Args
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForTitle, Payload: titlePayload{Alias: alias}}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(r.ChatID, msgSendTitle, linkData{ShortURL: displayURL(b.shortURL(alias))}, b.createCancelKeyboard())
+}
+
+// handleTitleInput sets the title sent as the title of alias.
+func (b *Bot) handleTitleInput(ctx context.Context, to payloadKey, alias, text string) error {
+	title := strings.Join(strings.Fields(text), " ")
+	if title == "" {
+		return b.reply(to.chatID, msgSendTitle, linkData{ShortURL: displayURL(b.shortURL(alias))})
+	}
+	title = truncateRunes(title, maxTitleLen)
+	b.resetUserState(to.chatID)
+
+	res, err := b.grpcClient.UpdateLink(ctx, &shortenerv1.UpdateLinkRequest{Alias: alias, UserTgId: to.chatID, Title: &title})
+	if err != nil {
+		b.log.Error("gRPC UpdateLink failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(to.chatID, err, alias)
+	}
+	if b.capabilities.Set(linkTitleUpdate, res.Title != nil) {
+		b.capabilitiesChanged()
+	}
+	if res.Title == nil {
+		return b.reply(to.chatID, msgTitleCreationOnly, nil)
+	}
+	b.linkLists.Forget(to.chatID)
+	text = b.render(msgTitleSet, titleData{ShortURL: b.shortURL(alias), Title: displayTitle(res.GetTitle())})
+	return b.sendMessageWithKeyboard(to.chatID, text, b.createStatsKeyboard(to, alias), b.linkContent())
+}
+
+// offerExpiry shows the expiries the link just created can be given.
+func (b *Bot) offerExpiry(r *Request) error {
+	alias := r.Args
+	var row []tgbotapi.InlineKeyboardButton
+	for _, preset := range expiryPresets {
+		if preset == expiryNever {
+			continue
+		}
+		row = append(row, b.storedPayloadButton(r.Payloads(), preset, actionSetExpiryTo, preset+"/"+alias))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row, tgbotapi.NewInlineKeyboardRow(b.callbackButton("Cancel", callbackCancel)))
+	return b.replyWithKeyboard(r.ChatID, msgPickLinkExpiry, linkData{ShortURL: displayURL(b.shortURL(alias))}, keyboard)
+}
+
+// setExpiry makes a link expire after the chosen preset, counted from now,
+// and reports the expiry in place of the choice.
+func (b *Bot) setExpiry(ctx context.Context, r *Request) error {
+	preset, alias, ok := strings.Cut(r.Args, "/")
+	expiry, err := parseExpiry(preset)
+	if !ok || err != nil || expiry == 0 {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	expiresAt := time.Now().Add(expiry)
+	res, err := b.grpcClient.SetLinkExpiry(ctx, &shortenerv1.SetLinkExpiryRequest{
+		Alias:     alias,
+		UserTgId:  r.UserID,
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
+	if err != nil {
+		b.log.Error("gRPC SetLinkExpiry failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	if confirmed := protoTime(res.GetExpiresAt()); confirmed != nil {
+		expiresAt = *confirmed
+	}
+	text := b.render(msgLinkExtended, extendData{
+		Locale:    b.formatterFor(r.ChatID),
+		ShortURL:  b.shortURL(alias),
+		ExpiresAt: expiresAt,
+	})
+	edit := tgbotapi.NewEditMessageText(r.ChatID, r.Message.MessageID, text)
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, func() error {
+		return b.sendMessage(r.ChatID, text, false, b.linkContent())
+	})
+}
+
+// startShortenFrom waits for a path on the site of the link just created,
+// shortening it as the next link.
+func (b *Bot) startShortenFrom(r *Request) error {
+	var from shortenFromPayload
+	if err := json.Unmarshal([]byte(r.Args), &from); err != nil || from.Prefix == "" {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	link := linkPayload{Domain: from.Domain, Prefix: from.Prefix}
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForURL, Payload: link}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(r.ChatID, msgSendPath, urlData{URL: from.Prefix}, b.createCancelKeyboard())
+}
+
+// withPrefix completes text, a path sent for a "Shorten another" shortcut,
+// into a URL on prefix. Text holding a URL is left alone.
+func withPrefix(prefix, text string) string {
+	text = strings.TrimSpace(text)
+	if prefix == "" || text == "" || urlRegex.MatchString(text) || strings.ContainsAny(text, " \n") {
+		return text
+	}
+	return prefix + strings.TrimPrefix(text, "/")
+}
+
+// takesPath reports whether msg, which looks like a command, is a path like
+// /blog/post sent for a "Shorten another" shortcut: the user was asked for
+// one and no command goes by its name.
+func (b *Bot) takesPath(msg *tgbotapi.Message) bool {
+	link, ok := b.getUserState(msg.Chat.ID).Payload.(linkPayload)
+	if !ok || link.Prefix == "" {
+		return false
+	}
+	_, known := b.router.commands[msg.Command()]
+	return !known
+}
+
+// linkShortcutExpired falls back to the link list for shortcuts to a link
+// whose state expired, where the link can be managed the usual way.
+func (b *Bot) linkShortcutExpired(ctx context.Context, r *Request) error {
+	r.Answer.toast(b.render(msgShortcutExpired, nil))
+	return b.handleMyLinksCommand(ctx, r.Payloads())
+}
+
+// shortenFromExpired falls back to the create link wizard.
+func (b *Bot) shortenFromExpired(ctx context.Context, r *Request) error {
+	r.Answer.toast(b.render(msgShortcutExpired, nil))
+	return b.promptNewLink(r.ChatID)
+}
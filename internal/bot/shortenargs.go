@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options /shorten understands after the URL.
+const (
+	optTitle     = "title"
+	optAlias     = "alias"
+	optExpiresIn = "expires_in"
+	optAnalytics = "analytics"
+	optNotBefore = "not_before"
+	optStyle     = "style"
+)
+
+// shortenOptionKeys lists the /shorten options in the order they are
+// summarized.
+var shortenOptionKeys = []string{optTitle, optAlias, optStyle, optExpiresIn, optNotBefore, optAnalytics}
+
+// optionKeyRegex matches what looks like the key of a key=value token, so
+// that plain words and URLs with query strings aren't taken for options.
+var optionKeyRegex = regexp.MustCompile(`^[A-Za-z][\w-]*$`)
+
+// shortenArgs is a /shorten message split into its URL and options.
+type shortenArgs struct {
+	// URL is the first URL found; empty when there is none.
+	URL string
+	// Options maps known keys to their values. A key given twice keeps the
+	// last value.
+	Options map[string]string
+	// Warnings tell the user what was ignored or overridden, in message order.
+	Warnings []string
+}
+
+// parseShortenArgs splits s into tokens separated by spaces and picks the
+// URL and the key=value options out of them. Values containing spaces are
+// quoted, key="value with spaces", and quotes within them escaped with a
+// backslash. Curly quotes, as phone keyboards type them, work too. Tokens
+// that are neither are ignored, so URLs can be shortened out of any text.
+func parseShortenArgs(s string) shortenArgs {
+	args := shortenArgs{Options: make(map[string]string)}
+	for _, token := range splitShortenArgs(s) {
+		key, value, isOption := strings.Cut(token, "=")
+		if !isOption || !optionKeyRegex.MatchString(key) {
+			if args.URL == "" {
+				args.URL = urlRegex.FindString(token)
+			}
+			continue
+		}
+		key = strings.ToLower(key)
+		if !slices.Contains(shortenOptionKeys, key) {
+			if suggestion := suggestOption(key); suggestion != "" {
+				args.Warnings = append(args.Warnings, fmt.Sprintf("ignored: %s — did you mean %s?", key, suggestion))
+			} else {
+				args.Warnings = append(args.Warnings, fmt.Sprintf("ignored: %s — unknown option", key))
+			}
+			continue
+		}
+		if value == "" {
+			args.Warnings = append(args.Warnings, fmt.Sprintf("ignored: %s — no value given", key))
+			continue
+		}
+		if key == optAnalytics {
+			value = strings.ToLower(value)
+			if value != "minimal" && value != "full" {
+				args.Warnings = append(args.Warnings, fmt.Sprintf("ignored: analytics=%s — use minimal or full", value))
+				continue
+			}
+		}
+		if key == optStyle {
+			value = strings.ToLower(value)
+			if !slices.Contains(aliasStyles, value) {
+				args.Warnings = append(args.Warnings, fmt.Sprintf("ignored: style=%s — use %s", value, strings.Join(aliasStyles, ", ")))
+				continue
+			}
+		}
+		if _, ok := args.Options[key]; ok {
+			args.Warnings = append(args.Warnings, fmt.Sprintf("%s given more than once, using %s", key, formatOption(key, value)))
+		}
+		args.Options[key] = value
+	}
+	return args
+}
+
+// Summary lists the options given in key=value form, or is empty when there
+// are none.
+func (a shortenArgs) Summary() string {
+	var parts []string
+	for _, key := range shortenOptionKeys {
+		if value, ok := a.Options[key]; ok {
+			parts = append(parts, formatOption(key, value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatOption renders an option the way parseShortenArgs reads it back.
+func formatOption(key, value string) string {
+	if strings.ContainsFunc(value, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }) {
+		value = strconv.Quote(value)
+	}
+	return key + "=" + value
+}
+
+// closingQuotes maps the quotes a value can be opened with to the one that
+// closes it.
+var closingQuotes = map[rune]rune{'"': '"', '“': '”', '„': '“'}
+
+// splitShortenArgs splits s at spaces outside quotes, removing the quotes
+// and the backslashes escaping quotes or backslashes within them. A quote
+// left open is taken literally, so it doesn't swallow the rest of s.
+func splitShortenArgs(s string) []string {
+	tokens, openedAt := splitQuoted(s, -1)
+	if openedAt >= 0 {
+		tokens, _ = splitQuoted(s, openedAt)
+	}
+	return tokens
+}
+
+// splitQuoted does the work of splitShortenArgs, taking the quote at byte
+// offset literal as a plain character. It returns the offset of a quote
+// left open, or -1.
+func splitQuoted(s string, literal int) (tokens []string, openedAt int) {
+	var token strings.Builder
+	inToken := false
+	var closing rune
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			token.WriteRune(r)
+			escaped = false
+		case closing != 0 && r == '\\':
+			escaped = true
+		case closing != 0 && r == closing:
+			closing = 0
+		case closing != 0:
+			token.WriteRune(r)
+		case closingQuotes[r] != 0 && i != literal:
+			closing = closingQuotes[r]
+			openedAt = i
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	if closing == 0 {
+		openedAt = -1
+	}
+	return tokens, openedAt
+}
+
+// suggestOption returns the option key is most likely a typo of, or "" if
+// it's too far from all of them.
+func suggestOption(key string) string {
+	normalized := strings.ReplaceAll(key, "-", "_")
+	best, bestDistance := "", 3
+	for _, known := range shortenOptionKeys {
+		if d := editDistance(normalized, known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseShortenArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		url      string
+		options  map[string]string
+		warnings []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "no URL",
+			in:   "just some words",
+		},
+		{
+			name:    "options without URL",
+			in:      "title=Hello alias=hi",
+			options: map[string]string{"title": "Hello", "alias": "hi"},
+		},
+		{
+			name: "URL only",
+			in:   "https://example.com/page",
+			url:  "https://example.com/page",
+		},
+		{
+			name: "URL within text",
+			in:   "look at this: https://example.com/a, nice",
+			url:  "https://example.com/a,",
+		},
+		{
+			name: "first URL wins",
+			in:   "https://example.com/1 https://example.com/2",
+			url:  "https://example.com/1",
+		},
+		{
+			name: "URL with a query string",
+			in:   "https://example.com/?a=b&c=d",
+			url:  "https://example.com/?a=b&c=d",
+		},
+		{
+			name:    "options before and after the URL",
+			in:      "alias=mine https://example.com expires_in=7d",
+			url:     "https://example.com",
+			options: map[string]string{"alias": "mine", "expires_in": "7d"},
+		},
+		{
+			name:    "key case",
+			in:      "https://example.com TITLE=x",
+			url:     "https://example.com",
+			options: map[string]string{"title": "x"},
+		},
+		{
+			name:    "quoted value",
+			in:      `https://example.com title="My page title"`,
+			url:     "https://example.com",
+			options: map[string]string{"title": "My page title"},
+		},
+		{
+			name:    "quoted key and value",
+			in:      `https://example.com "title=My page"`,
+			url:     "https://example.com",
+			options: map[string]string{"title": "My page"},
+		},
+		{
+			name:    "escaped quotes",
+			in:      `https://example.com title="say \"hi\" now"`,
+			url:     "https://example.com",
+			options: map[string]string{"title": `say "hi" now`},
+		},
+		{
+			name:    "escaped backslash",
+			in:      `https://example.com title="a\\b"`,
+			url:     "https://example.com",
+			options: map[string]string{"title": `a\b`},
+		},
+		{
+			name:    "backslash outside quotes",
+			in:      `https://example.com title=a\b`,
+			url:     "https://example.com",
+			options: map[string]string{"title": `a\b`},
+		},
+		{
+			name:    "curly quotes",
+			in:      "https://example.com title=“My page”",
+			url:     "https://example.com",
+			options: map[string]string{"title": "My page"},
+		},
+		{
+			name:    "low curly quotes",
+			in:      "https://example.com title=„Meine Seite“",
+			url:     "https://example.com",
+			options: map[string]string{"title": "Meine Seite"},
+		},
+		{
+			name:    "quote left open",
+			in:      `https://example.com title="My page alias=x`,
+			url:     "https://example.com",
+			options: map[string]string{"title": `"My`, "alias": "x"},
+		},
+		{
+			name:     "empty quoted value",
+			in:       `https://example.com title=""`,
+			url:      "https://example.com",
+			warnings: []string{"ignored: title — no value given"},
+		},
+		{
+			name:     "duplicate key",
+			in:       "https://example.com alias=one alias=two",
+			url:      "https://example.com",
+			options:  map[string]string{"alias": "two"},
+			warnings: []string{"alias given more than once, using alias=two"},
+		},
+		{
+			name:     "duplicate key with spaces",
+			in:       `https://example.com title=one title="two words"`,
+			url:      "https://example.com",
+			options:  map[string]string{"title": "two words"},
+			warnings: []string{`title given more than once, using title="two words"`},
+		},
+		{
+			name:     "typo",
+			in:       "https://example.com titel=x expires-in=1d",
+			url:      "https://example.com",
+			warnings: []string{"ignored: titel — did you mean title?", "ignored: expires-in — did you mean expires_in?"},
+		},
+		{
+			name:     "unknown key",
+			in:       "https://example.com colour=red",
+			url:      "https://example.com",
+			warnings: []string{"ignored: colour — unknown option"},
+		},
+		{
+			name:    "analytics",
+			in:      "https://example.com analytics=FULL",
+			url:     "https://example.com",
+			options: map[string]string{"analytics": "full"},
+		},
+		{
+			name:     "bad analytics",
+			in:       "https://example.com analytics=some",
+			url:      "https://example.com",
+			warnings: []string{"ignored: analytics=some — use minimal or full"},
+		},
+		{
+			name:     "bad style",
+			in:       "https://example.com style=emoji",
+			url:      "https://example.com",
+			warnings: []string{"ignored: style=emoji — use random, words, numeric"},
+		},
+		{
+			name: "not an option",
+			in:   "https://example.com 1=2 =x",
+			url:  "https://example.com",
+		},
+		{
+			name:    "spacing",
+			in:      "  https://example.com \t alias=x\n",
+			url:     "https://example.com",
+			options: map[string]string{"alias": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseShortenArgs(tt.in)
+			if got.URL != tt.url {
+				t.Errorf("URL = %q, want %q", got.URL, tt.url)
+			}
+			if tt.options == nil {
+				tt.options = map[string]string{}
+			}
+			if !maps.Equal(got.Options, tt.options) {
+				t.Errorf("Options = %v, want %v", got.Options, tt.options)
+			}
+			if !slices.Equal(got.Warnings, tt.warnings) {
+				t.Errorf("Warnings = %q, want %q", got.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+// TestShortenArgsSummaryRoundTrip checks that options read back from their
+// summary are the same.
+func TestShortenArgsSummaryRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		`https://example.com title="My \"quoted\" page" alias=x`,
+		`https://example.com analytics=minimal style=words expires_in=30d`,
+		`https://example.com title=“curly quotes” not_before=2026-01-02`,
+	} {
+		args := parseShortenArgs(in)
+		again := parseShortenArgs(args.Summary())
+		if !maps.Equal(again.Options, args.Options) {
+			t.Errorf("%s: summary %q reads back as %v, want %v", in, args.Summary(), again.Options, args.Options)
+		}
+	}
+}
+
+func TestShortenArgsSummaryOrder(t *testing.T) {
+	args := parseShortenArgs("https://example.com analytics=full alias=x title=t")
+	if got, want := args.Summary(), "title=t alias=x analytics=full"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := parseShortenArgs("https://example.com").Summary(); got != "" {
+		t.Errorf("Summary() without options = %q", got)
+	}
+}
+
+func TestSuggestOption(t *testing.T) {
+	tests := map[string]string{
+		"titel":      "title",
+		"alais":      "alias",
+		"expires-in": "expires_in",
+		"expiresin":  "expires_in",
+		"styel":      "style",
+		"colour":     "",
+		"x":          "",
+	}
+	for key, want := range tests {
+		if got := suggestOption(key); got != want {
+			t.Errorf("suggestOption(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
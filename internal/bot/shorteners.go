@@ -0,0 +1,116 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// shortenerHost returns the matching known shortener domain for rawURL, if any.
+func (b *Bot) shortenerHost(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range b.config.Shorteners.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+func (b *Bot) isKnownShortener(rawURL string) bool {
+	_, ok := b.shortenerHost(rawURL)
+	return ok
+}
+
+// warnShortener asks whether to shorten a third-party short link as is or
+// follow it first. The pending request travels through the payload store.
+func (b *Bot) warnShortener(to payloadKey, req *shortenerv1.CreateLinkRequest) error {
+	payload, err := json.Marshal(newQueuedLink(to.chatID, req))
+	if err != nil {
+		return err
+	}
+	domain, _ := b.shortenerHost(req.GetOriginalUrl())
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(to, "Shorten anyway", actionForceLink, string(payload)),
+			b.payloadButton(to, "Follow redirect", actionFollowLink, string(payload)),
+		),
+	)
+	return b.sendMessageWithKeyboard(to.chatID, b.render(msgThirdPartyShortLink, domainData{Domain: domain}), keyboard)
+}
+
+// handlePendingLink creates a link from a request stored in the callback
+// payload, optionally replacing the URL with its redirect destination.
+func (b *Bot) handlePendingLink(ctx context.Context, r *Request, follow bool) error {
+	var item queuedLink
+	if err := json.Unmarshal([]byte(r.Args), &item); err != nil {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	req := item.request()
+
+	if follow {
+		final, err := b.followRedirects(ctx, req.GetOriginalUrl())
+		if err != nil {
+			b.log.Warn("failed to follow redirects", zap.String("url", req.GetOriginalUrl()), zap.Error(err))
+			r.Answer.alert(b.render(msgFollowRedirectFailed, errorData{Error: err.Error()}))
+			return nil
+		}
+		req.OriginalUrl = final
+	}
+	if !follow {
+		// An explicit "create anyway" always produces a new link
+		b.recentLinks.Forget(recentLinkKey(req.GetUserTgId(), req.GetOriginalUrl(), req.GetCustomAlias()))
+	}
+
+	_, err := b.submitOrPreview(ctx, r.Payloads(), req, "")
+	return err
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// followRedirects resolves the final destination of rawURL, trying HEAD first
+// and falling back to GET for servers that don't support it.
+func (b *Bot) followRedirects(ctx context.Context, rawURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Shorteners.FollowTimeout)
+	defer cancel()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > b.config.Shorteners.MaxRedirects {
+				return errTooManyRedirects
+			}
+			return nil
+		},
+	}
+
+	final := rawURL
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		final = resp.Request.URL.String()
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	return final, nil
+}
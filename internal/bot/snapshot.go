@@ -0,0 +1,106 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// snapshot returns the click snapshot chatID took of alias, unless it aged out.
+func (b *Bot) snapshot(chatID int64, alias string) (prefs.Snapshot, bool) {
+	snap, ok := b.prefs.Get(chatID).Snapshots[alias]
+	if !ok || time.Since(snap.At) > b.config.Prefs.SnapshotMaxAge {
+		return prefs.Snapshot{}, false
+	}
+	return snap, true
+}
+
+// takeSnapshot stores the current clicks of alias, replacing an earlier
+// snapshot. When requested from a stats message, its keyboard is refreshed
+// to offer the comparison.
+func (b *Bot) takeSnapshot(ctx context.Context, r *Request, fromStats bool) error {
+	alias := r.Args
+	res, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+
+	now := time.Now()
+	err = b.prefs.Update(r.ChatID, func(p *prefs.Prefs) error {
+		if p.Snapshots == nil {
+			p.Snapshots = make(map[string]prefs.Snapshot)
+		}
+		maps.DeleteFunc(p.Snapshots, func(_ string, snap prefs.Snapshot) bool {
+			return now.Sub(snap.At) > b.config.Prefs.SnapshotMaxAge
+		})
+		p.Snapshots[alias] = prefs.Snapshot{
+			At:       now,
+			Clicks:   res.GetClickCount(),
+			ByDevice: maps.Clone(res.GetClicksByDevice()),
+		}
+		return nil
+	})
+	if err != nil {
+		b.log.Error("failed to save preferences", zap.Error(err))
+		r.Answer.alert(b.render(msgInternalError, nil))
+		return nil
+	}
+	r.Answer.toast(b.render(msgToastSnapshotSaved, nil))
+	if !fromStats {
+		return nil
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(r.ChatID, r.Message.MessageID, b.createStatsKeyboard(r.Payloads(), alias))
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// compareToSnapshot shows the clicks of alias since the snapshot.
+func (b *Bot) compareToSnapshot(ctx context.Context, r *Request) error {
+	alias := r.Args
+	snap, ok := b.snapshot(r.ChatID, alias)
+	if !ok {
+		r.Answer.alert(b.render(msgNoSnapshot, nil))
+		return nil
+	}
+	res, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+
+	data := snapshotData{
+		Locale: b.formatterFor(r.ChatID),
+		Alias:  alias,
+		Since:  snap.At,
+		Clicks: res.GetClickCount() - snap.Clicks,
+		Total:  res.GetClickCount(),
+	}
+	devices := slices.Sorted(maps.Keys(res.GetClicksByDevice()))
+	for device := range snap.ByDevice {
+		if !slices.Contains(devices, device) {
+			devices = append(devices, device)
+		}
+	}
+	slices.Sort(devices)
+	for _, device := range devices {
+		data.ByDevice = append(data.ByDevice, deviceDelta{
+			Device: device,
+			Delta:  res.GetClicksByDevice()[device] - snap.ByDevice[device],
+		})
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(r.Payloads(), "Replace snapshot", actionReplaceSnapshot, alias),
+		b.payloadButton(r.Payloads(), "Stats", actionStats, alias),
+	))
+	return b.sendMessageWithKeyboard(r.ChatID, b.render(msgSnapshotDelta, data), keyboard, b.linkContent())
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// tweetLimit is the length limit of the Twitter snippet.
+	tweetLimit = 280
+	// tweetURLLength is how long Twitter counts every URL.
+	tweetURLLength = 23
+)
+
+// snippetStyle is a "Copy text" variant rendered by template.
+type snippetStyle struct {
+	Name     string
+	Label    string
+	Template string
+	// Limit is the maximum length, with URLs counted as on Twitter; zero
+	// means unlimited.
+	Limit int
+	// Emoji styles aren't offered in plain output, which drops emoji.
+	Emoji bool
+}
+
+var snippetStyles = []snippetStyle{
+	{Name: "plain", Label: "Plain", Template: msgSnippetPlain},
+	{Name: "twitter", Label: "Twitter", Template: msgSnippetTwitter, Limit: tweetLimit},
+	{Name: "emoji", Label: "Emoji", Template: msgSnippetEmoji, Emoji: true},
+}
+
+// findSnippetStyle returns the style called name.
+func findSnippetStyle(name string) (snippetStyle, bool) {
+	for _, s := range snippetStyles {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return snippetStyle{}, false
+}
+
+// showSnippet shows the snippet of alias in the given style. messageID is
+// the snippet message to edit, or zero to send a new one.
+func (b *Bot) showSnippet(ctx context.Context, to payloadKey, messageID int, alias, styleName string, answer *callbackAnswer) error {
+	style, ok := findSnippetStyle(styleName)
+	if !ok {
+		answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	res, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+
+	// The snippet is shown in a monospace block unless in plain output
+	output := b.outputStyle(to.chatID)
+	text := b.renderSnippet(style, snippetData{Title: displayTitle(res.GetTitle()), ShortURL: b.shortURLOn(res.GetDomain(), alias)})
+	parseMode := ""
+	if output == styleRich {
+		text = b.render(msgSnippet, textData{Text: text})
+		parseMode = tgbotapi.ModeHTML
+	}
+	keyboard := b.createSnippetKeyboard(to, output, alias, style.Name)
+	msg := tgbotapi.NewMessage(to.chatID, text)
+	msg.ParseMode = parseMode
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = keyboard
+	sendNew := func() error {
+		_, err := b.send(msg, b.linkContent())
+		return err
+	}
+	if messageID == 0 {
+		return sendNew()
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(to.chatID, messageID, text, keyboard)
+	edit.ParseMode = parseMode
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, answer, sendNew)
+}
+
+// renderSnippet renders data in style. When the result exceeds the style's
+// limit the title is shortened first, as it is the only part that can go.
+func (b *Bot) renderSnippet(style snippetStyle, data snippetData) string {
+	text := b.render(style.Template, data)
+	if style.Limit == 0 {
+		return text
+	}
+	for {
+		over := tweetLength(text, data.ShortURL) - style.Limit
+		title := []rune(data.Title)
+		if over <= 0 || len(title) == 0 {
+			return text
+		}
+		// One more rune makes room for the ellipsis
+		keep := max(len(title)-over-1, 0)
+		data.Title = ""
+		if keep > 0 {
+			data.Title = strings.TrimSpace(string(title[:keep])) + "…"
+		}
+		text = b.render(style.Template, data)
+	}
+}
+
+// tweetLength returns the length of text as Twitter counts it, with every
+// occurrence of shortURL counted as tweetURLLength.
+func tweetLength(text, shortURL string) int {
+	n := utf8.RuneCountInString(text)
+	if shortURL != "" {
+		n += strings.Count(text, shortURL) * (tweetURLLength - utf8.RuneCountInString(shortURL))
+	}
+	return n
+}
+
+// createSnippetKeyboard offers the snippet styles fit for output, marking
+// the current one.
+func (b *Bot) createSnippetKeyboard(to payloadKey, output outputStyle, alias, current string) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, s := range snippetStyles {
+		if s.Emoji && output == stylePlain {
+			continue
+		}
+		label := s.Label
+		if s.Name == current {
+			label = "* " + label
+		}
+		row = append(row, b.payloadButton(to, label, actionSnippetStyle, s.Name+"/"+alias))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// handleSnippetStyle switches a snippet message to another style.
+func (b *Bot) handleSnippetStyle(ctx context.Context, r *Request) error {
+	style, alias, ok := strings.Cut(r.Args, "/")
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	return b.showSnippet(ctx, r.Payloads(), r.Message.MessageID, alias, style, r.Answer)
+}
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPreviewTitleLen and maxPreviewDescriptionLen cap the custom
+	// preview texts, in characters.
+	maxPreviewTitleLen       = 100
+	maxPreviewDescriptionLen = 300
+	// maxPreviewImageSize caps the custom preview images, in bytes.
+	maxPreviewImageSize = 5 << 20
+)
+
+// previewImageTypes are the image types accepted for custom previews, as
+// sniffed from their contents.
+var previewImageTypes = []string{"image/jpeg", "image/png"}
+
+// startEditPreview asks for the title of the custom preview of a link, the
+// first of the title, description and image steps.
+func (b *Bot) startEditPreview(r *Request) error {
+	alias := r.Args
+	if err := b.startDialog(r.ChatID, UserState{State: StateWaitingForPreviewTitle, Payload: previewPayload{Alias: alias}}); err != nil {
+		return err
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(r.Payloads(), "Remove custom preview", actionRemovePreview, alias),
+		b.callbackButton("Cancel", callbackCancel),
+	))
+	data := previewPromptData{ShortURL: displayURL(b.shortURL(alias)), Limit: maxPreviewTitleLen}
+	return b.replyWithKeyboard(r.ChatID, msgSendPreviewTitle, data, keyboard)
+}
+
+// handlePreviewTitleInput takes the title of the custom preview and asks
+// for its description.
+func (b *Bot) handlePreviewTitleInput(userID int64, preview previewPayload, text string) error {
+	title := strings.Join(strings.Fields(text), " ")
+	if title == "" || utf8.RuneCountInString(title) > maxPreviewTitleLen {
+		data := previewPromptData{ShortURL: displayURL(b.shortURL(preview.Alias)), Limit: maxPreviewTitleLen}
+		return b.reply(userID, msgSendPreviewTitle, data)
+	}
+	preview.Title = title
+	b.advanceUserState(userID, UserState{State: StateWaitingForPreviewDescription, Payload: preview})
+	return b.replyWithKeyboard(userID, msgSendPreviewDescription, limitData{Limit: maxPreviewDescriptionLen}, b.createPreviewSkipKeyboard())
+}
+
+// handlePreviewDescriptionInput takes the description of the custom preview
+// and asks for its image.
+func (b *Bot) handlePreviewDescriptionInput(userID int64, preview previewPayload, text string) error {
+	description := strings.TrimSpace(text)
+	if description == "" || utf8.RuneCountInString(description) > maxPreviewDescriptionLen {
+		return b.reply(userID, msgSendPreviewDescription, limitData{Limit: maxPreviewDescriptionLen})
+	}
+	preview.Description = description
+	return b.askPreviewImage(userID, preview)
+}
+
+func (b *Bot) askPreviewImage(userID int64, preview previewPayload) error {
+	b.advanceUserState(userID, UserState{State: StateWaitingForPreviewImage, Payload: preview})
+	return b.replyWithKeyboard(userID, msgSendPreviewImage, limitData{Limit: maxPreviewImageSize >> 20}, b.createPreviewSkipKeyboard())
+}
+
+// handlePreviewImageInput takes the image of the custom preview, sent as a
+// photo or as a file, and sets the preview. The user stays at the prompt
+// until an image that fits comes.
+func (b *Bot) handlePreviewImageInput(ctx context.Context, msg *tgbotapi.Message, preview previewPayload) error {
+	chatID := msg.Chat.ID
+	var fileID string
+	switch {
+	case len(msg.Photo) > 0:
+		// Photos come in several sizes, smallest first
+		for _, size := range slices.Backward(msg.Photo) {
+			if size.FileSize <= maxPreviewImageSize {
+				fileID = size.FileID
+				break
+			}
+		}
+	case msg.Document != nil && strings.HasPrefix(msg.Document.MimeType, "image/"):
+		if msg.Document.FileSize <= maxPreviewImageSize {
+			fileID = msg.Document.FileID
+		}
+	default:
+		return b.reply(chatID, msgSendPreviewImage, limitData{Limit: maxPreviewImageSize >> 20})
+	}
+	if fileID == "" {
+		return b.reply(chatID, msgPreviewImageTooLarge, limitData{Limit: maxPreviewImageSize >> 20})
+	}
+
+	image, err := b.downloadFile(ctx, fileID, maxPreviewImageSize)
+	if errors.Is(err, errFileTooLarge) {
+		return b.reply(chatID, msgPreviewImageTooLarge, limitData{Limit: maxPreviewImageSize >> 20})
+	}
+	if err != nil {
+		b.log.Error("failed to download preview image", zap.Error(err))
+		return b.reply(chatID, msgInternalError, nil)
+	}
+	imageType := http.DetectContentType(image)
+	if !slices.Contains(previewImageTypes, imageType) {
+		return b.reply(chatID, msgPreviewImageInvalid, nil)
+	}
+	return b.setLinkPreview(ctx, messagePayloads(msg), preview, image, imageType)
+}
+
+// skipPreviewStep leaves out the description or the image of the custom
+// preview, whichever is asked for.
+func (b *Bot) skipPreviewStep(ctx context.Context, r *Request) error {
+	state := b.getUserState(r.ChatID)
+	preview, ok := state.Payload.(previewPayload)
+	if !ok {
+		r.Answer.alert(b.render(msgButtonExpired, nil))
+		return nil
+	}
+	b.dropKeyboard(r.ChatID, r.Message.MessageID)
+	switch state.State {
+	case StateWaitingForPreviewDescription:
+		return b.askPreviewImage(r.ChatID, preview)
+	case StateWaitingForPreviewImage:
+		return b.setLinkPreview(ctx, r.Payloads(), preview, nil, "")
+	}
+	r.Answer.alert(b.render(msgButtonExpired, nil))
+	return nil
+}
+
+// setLinkPreview sets the collected custom preview and sums it up.
+func (b *Bot) setLinkPreview(ctx context.Context, to payloadKey, preview previewPayload, image []byte, imageType string) error {
+	err := b.withChatAction(ctx, to.chatID, tgbotapi.ChatTyping, func() error {
+		return b.grpcClient.SetLinkPreview(ctx, &shortenerv1.SetLinkPreviewRequest{
+			Alias:    preview.Alias,
+			UserTgId: to.chatID,
+			Preview: &shortenerv1.LinkPreview{
+				Title:       preview.Title,
+				Description: preview.Description,
+				Image:       image,
+				ImageType:   imageType,
+			},
+		})
+	})
+	if err != nil {
+		b.log.Error("gRPC SetLinkPreview failed", zap.Error(err), zap.String("alias", preview.Alias))
+		return b.replyGRPCError(to.chatID, err, preview.Alias)
+	}
+	b.resetUserState(to.chatID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(to, "Remove custom preview", actionRemovePreview, preview.Alias),
+		b.payloadButton(to, "Stats", actionStats, preview.Alias),
+	))
+	return b.replyWithKeyboard(to.chatID, msgPreviewSet, customPreviewData{
+		ShortURL:    displayURL(b.shortURL(preview.Alias)),
+		Title:       preview.Title,
+		Description: preview.Description,
+		Image:       len(image) > 0,
+	}, keyboard)
+}
+
+// removeLinkPreview removes the custom preview of a link, so the preview of
+// its destination shows again.
+func (b *Bot) removeLinkPreview(ctx context.Context, r *Request) error {
+	alias := r.Args
+	err := b.grpcClient.SetLinkPreview(ctx, &shortenerv1.SetLinkPreviewRequest{Alias: alias, UserTgId: r.UserID})
+	if err != nil {
+		b.log.Error("gRPC SetLinkPreview failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	// The dialog started from the same link has nothing left to set
+	if preview, ok := b.getUserState(r.ChatID).Payload.(previewPayload); ok && preview.Alias == alias {
+		b.resetUserState(r.ChatID)
+	}
+	return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgPreviewRemoved, linkData{ShortURL: displayURL(b.shortURL(alias))}))
+}
+
+func (b *Bot) createPreviewSkipKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Skip", callbackPreviewSkip),
+		b.callbackButton("Cancel", callbackCancel),
+	))
+}
@@ -0,0 +1,31 @@
+package bot
+
+// Link creation sources reported to the backend on CreateLinkRequest.
+const (
+	sourceBotMessage = "bot_message"
+	sourceBotInline  = "bot_inline"
+	sourceBotImport  = "bot_import"
+	sourceBotChannel = "bot_channel"
+)
+
+// sourceLabels are the human-readable names of known sources.
+var sourceLabels = map[string]string{
+	sourceBotMessage: "Telegram bot",
+	sourceBotInline:  "Telegram inline mode",
+	sourceBotImport:  "Bulk import",
+	sourceBotChannel: "Telegram channel",
+	"web":            "Web dashboard",
+}
+
+func linkSource(source string) *string {
+	return &source
+}
+
+// sourceLabel returns the display name of a source; unknown sources are
+// shown as they are and a missing one as empty.
+func sourceLabel(source string) string {
+	if label, ok := sourceLabels[source]; ok {
+		return label
+	}
+	return source
+}
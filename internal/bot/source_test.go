@@ -0,0 +1,83 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSourceLabel(t *testing.T) {
+	for in, want := range map[string]string{
+		sourceBotMessage: "Telegram bot",
+		sourceBotInline:  "Telegram inline mode",
+		sourceBotImport:  "Bulk import",
+		sourceBotChannel: "Telegram channel",
+		"web":            "Web dashboard",
+		"api":            "api",
+		"":               "",
+	} {
+		if got := sourceLabel(in); got != want {
+			t.Errorf("sourceLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// sendReply enqueues text sent by user in reply to their earlier message
+// replied.
+func (e *e2e) sendReply(text, replied string) {
+	from := &tgbotapi.User{ID: user, FirstName: "User", LanguageCode: "en"}
+	chat := &tgbotapi.Chat{ID: user, Type: "private"}
+	msg := &tgbotapi.Message{
+		MessageID:      int(time.Now().UnixNano() % 1e6),
+		From:           from,
+		Chat:           chat,
+		Date:           int(time.Now().Unix()),
+		Text:           text,
+		Entities:       []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(text)}},
+		ReplyToMessage: &tgbotapi.Message{MessageID: 1, From: from, Chat: chat, Text: replied},
+	}
+	e.tg.Enqueue(tgbotapi.Update{Message: msg})
+}
+
+func TestE2ELinkSource(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		send func(e *e2e)
+	}{
+		{name: "shorten command", send: func(e *e2e) { e.tg.SendMessage(user, "/shorten https://example.com/a") }},
+		{name: "pasted URL", send: func(e *e2e) { e.tg.SendMessage(user, "https://example.com/a") }},
+		{name: "custom alias", send: func(e *e2e) { e.tg.SendMessage(user, "/shorten https://example.com/a alias=mine") }},
+		{name: "reply", send: func(e *e2e) { e.sendReply("/shorten", "see https://example.com/a") }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := startBot(t, nil)
+
+			tt.send(e)
+			e.tg.WaitText(user, "Link created successfully")
+			if got := e.lastCreated(t).GetSource(); got != sourceBotMessage {
+				t.Errorf("source = %q, want %q", got, sourceBotMessage)
+			}
+		})
+	}
+}
+
+func TestE2EStatsShowSource(t *testing.T) {
+	e := startBot(t, nil)
+
+	e.tg.SendMessage(user, "/shorten https://example.com/a")
+	e.tg.WaitText(user, "Link created successfully")
+	e.tg.SendMessage(user, "/stats gen1")
+	if stats := e.tg.WaitText(user, "Link Statistics: gen1"); !strings.Contains(stats.Text(), "Created via: Telegram bot") {
+		t.Errorf("stats %q lack the source", stats.Text())
+	}
+}
+
+func TestQueuedLinkKeepsSource(t *testing.T) {
+	req := &shortenerv1.CreateLinkRequest{OriginalUrl: "https://example.com/a", UserTgId: user, Source: linkSource(sourceBotImport)}
+	if got := newQueuedLink(user, req).request().GetSource(); got != sourceBotImport {
+		t.Errorf("replayed source = %q, want %q", got, sourceBotImport)
+	}
+}
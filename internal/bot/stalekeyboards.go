@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/keyboards"
+	"context"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// aliasActions are the actions whose inline payload is an alias.
+var aliasActions = map[string]bool{
+	actionStats:           true,
+	actionDelete:          true,
+	actionListDelete:      true,
+	actionPin:             true,
+	actionUnpin:           true,
+	actionRename:          true,
+	actionCopyText:        true,
+	actionSnapshot:        true,
+	actionReplaceSnapshot: true,
+	actionCompareSnapshot: true,
+	actionExtend:          true,
+	actionRefreshStats:    true,
+	actionMonitor:         true,
+	actionUnmonitor:       true,
+	actionNewDestination:  true,
+	actionDisableLink:     true,
+	actionToggleAnalytics: true,
+	actionTransfer:        true,
+	actionCleanupKeep:     true,
+	actionCleanupDelete:   true,
+}
+
+// keyboardEntry describes what the keyboard of a message refers to. It
+// reports false for keyboards that can't go stale. Keyboards acting on
+// several links, such as link lists, are only tracked for their stored
+// payloads: one deleted link doesn't make the rest of the list useless.
+func keyboardEntry(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) (keyboards.Entry, bool) {
+	entry := keyboards.Entry{ChatID: chatID, MessageID: messageID}
+	aliases := make(map[string]bool)
+	for _, button := range optionButtons(keyboard) {
+		// Split like decodeCallbackData does
+		data := *button.CallbackData
+		i := strings.IndexAny(data, callbackPayloadSep+callbackTokenSep)
+		if i < 0 {
+			continue
+		}
+		action, rest := data[:i], data[i+1:]
+		switch {
+		case data[i:i+1] == callbackTokenSep && entry.Token == "":
+			entry.Token = rest
+			entry.ExpiresAt = time.Now().Add(payloadTTL)
+		case data[i:i+1] == callbackPayloadSep && aliasActions[action]:
+			aliases[rest] = true
+		}
+	}
+	if len(aliases) == 1 {
+		entry.Kind = keyboards.KindLink
+		for alias := range aliases {
+			entry.Alias = alias
+		}
+		return entry, true
+	}
+	entry.Kind = keyboards.KindPayload
+	return entry, entry.Token != ""
+}
+
+// trackKeyboard records the keyboard a message was sent or edited with, so
+// it can be removed once it goes stale. A nil keyboard was removed.
+func (b *Bot) trackKeyboard(chatID int64, messageID int, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if keyboard != nil {
+		if entry, ok := keyboardEntry(chatID, messageID, *keyboard); ok {
+			b.keyboards.Track(entry)
+			return
+		}
+	}
+	b.keyboards.Forget(chatID, messageID)
+}
+
+// trackEdit is trackKeyboard for an edit that went through.
+func (b *Bot) trackEdit(edit tgbotapi.Chattable) {
+	switch e := edit.(type) {
+	case tgbotapi.EditMessageTextConfig:
+		b.trackKeyboard(e.ChatID, e.MessageID, e.ReplyMarkup)
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		b.trackKeyboard(e.ChatID, e.MessageID, e.ReplyMarkup)
+	}
+}
+
+// staleKeyboards marks the keyboards acting on alias for removal, as the
+// link was deleted or renamed or is no longer the user's.
+func (b *Bot) staleKeyboards(alias string) {
+	if n := b.keyboards.MarkStale(alias); n > 0 {
+		b.log.Debug("keyboards went stale", zap.String("alias", alias), zap.Int("count", n))
+	}
+}
+
+// runKeyboardCleanup removes stale keyboards every Keyboards.Interval until
+// ctx is done.
+func (b *Bot) runKeyboardCleanup(ctx context.Context) {
+	ticker := time.NewTicker(b.config.Keyboards.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.stripStaleKeyboards(ctx)
+		}
+	}
+}
+
+// stripStaleKeyboards removes up to Keyboards.Batch stale keyboards. The
+// edits are queued as notifications, which give way to replies, and the
+// rest waits for the next round once one is dropped or rate limited.
+func (b *Bot) stripStaleKeyboards(ctx context.Context) {
+	for _, entry := range b.keyboards.Due(time.Now(), b.config.Keyboards.Batch) {
+		if ctx.Err() != nil {
+			return
+		}
+		edit := tgbotapi.NewEditMessageReplyMarkup(entry.ChatID, entry.MessageID, tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+		})
+		res := <-b.sendQueue.Submit(entry.ChatID, true, func() (tgbotapi.Message, error) {
+			return b.api.Send(edit)
+		})
+		if res.err == errSendDropped {
+			return
+		}
+		if _, limited := retryAfter(res.err); limited {
+			b.log.Debug("rate limited removing stale keyboards", zap.Error(res.err))
+			return
+		}
+		switch classifyTelegramError(res.err) {
+		case telegramErrorOther:
+			if res.err != nil {
+				b.log.Warn("failed to remove stale keyboard",
+					zap.Int64("chat_id", entry.ChatID),
+					zap.Int("message_id", entry.MessageID),
+					zap.Error(res.err))
+				continue
+			}
+		case telegramErrorBlocked:
+			b.users.MarkBlocked(entry.ChatID, time.Now())
+		}
+		// Gone or unchanged messages have no keyboard to remove either
+		b.keyboards.MarkStripped(entry.ChatID, entry.MessageID)
+		b.log.Debug("removed stale keyboard",
+			zap.Int64("chat_id", entry.ChatID),
+			zap.Int("message_id", entry.MessageID),
+			zap.String("kind", entry.Kind))
+	}
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"maps"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protoTime converts ts, treating the zero timestamp sent for missing values
+// like a missing one.
+func protoTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil || (ts.GetSeconds() == 0 && ts.GetNanos() == 0) {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// newStatsData prepares the stats of alias for rendering and reports whether
+// they are complete. Every link has a destination, so a response without one
+// comes from a backend replica that hasn't caught up with a new link yet.
+func newStatsData(alias string, res *shortenerv1.GetLinkStatsResponse) (statsData, bool) {
+	data := statsData{
+		Alias:       alias,
+		Title:       displayTitle(res.GetTitle()),
+		OriginalURL: res.GetOriginalUrl(),
+		Clicks:      res.GetClickCount(),
+		ExpiresAt:   protoTime(res.GetExpiresAt()),
+		LastClickAt: protoTime(res.GetLastClickAt()),
+		Source:      sourceLabel(res.GetSource()),
+		// Breakdowns a backend still sends for such links are left out
+		MinimalAnalytics: res.GetMinimalAnalytics(),
+	}
+	if notBefore := protoTime(res.GetNotBefore()); notBefore != nil && notBefore.After(time.Now()) {
+		data.NotBefore = notBefore
+		data.GoesLiveIn = formatRemaining(time.Until(*notBefore))
+	}
+	// Devices without clicks would make an empty section
+	devices := maps.Clone(res.GetClicksByDevice())
+	maps.DeleteFunc(devices, func(_ string, count int64) bool { return count <= 0 })
+	if len(devices) > 0 && !data.MinimalAnalytics {
+		data.ClicksByDevice = devices
+	}
+	return data, data.OriginalURL != ""
+}
+
+// renderStats renders the stats of alias in style with their keyboard, or a
+// notice with a Refresh button while they are incomplete.
+func (b *Bot) renderStats(to payloadKey, style outputStyle, alias string, res *shortenerv1.GetLinkStatsResponse) (string, tgbotapi.InlineKeyboardMarkup) {
+	data, complete := newStatsData(alias, res)
+	if !complete {
+		return b.render(msgStatsPending, aliasData{Alias: alias}), tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			b.payloadButton(to, "Refresh", actionRefreshStats, alias),
+		))
+	}
+	data.Locale = b.formatterFor(to.chatID)
+	name := msgLinkStats
+	if style == stylePlain {
+		name = msgLinkStatsPlain
+		data.ShortURL = b.shortURLOn(res.GetDomain(), alias)
+	}
+	return b.render(name, data), b.createStatsKeyboard(to, alias)
+}
+
+// refreshStats shows the current stats of alias in place of the message the
+// button is on.
+func (b *Bot) refreshStats(ctx context.Context, r *Request) error {
+	alias := r.Args
+	res, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		r.Answer.alert(b.mapGRPCError(err, alias))
+		return nil
+	}
+	b.linkStats.Set(alias, res)
+	text, keyboard := b.renderStats(r.Payloads(), b.outputStyle(r.ChatID), alias, res)
+	edit := tgbotapi.NewEditMessageTextAndMarkup(r.ChatID, r.Message.MessageID, text, keyboard)
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, func() error {
+		return b.sendMessageWithKeyboard(r.ChatID, text, keyboard, b.linkContent())
+	})
+}
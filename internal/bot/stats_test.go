@@ -0,0 +1,96 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRenderStatsPartialResponses(t *testing.T) {
+	const pending = "Statistics for abc are still being prepared"
+	zero := &timestamppb.Timestamp{}
+	later := timestamppb.New(time.Now().Add(48 * time.Hour))
+	e := startBot(t, nil)
+	for _, tt := range []struct {
+		name string
+		res  *shortenerv1.GetLinkStatsResponse
+		// want and avoid are text the rendering must and mustn't contain
+		want, avoid []string
+	}{
+		{
+			name:  "complete",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ClickCount: 3, ClicksByDevice: map[string]int64{"mobile": 2, "desktop": 1}},
+			want:  []string{"Original URL: https://example.com/", "Total Clicks: 3", "Expires: Never", "By Device:", "- mobile: 2"},
+			avoid: []string{pending, "Last Click"},
+		},
+		{
+			name:  "empty response",
+			res:   &shortenerv1.GetLinkStatsResponse{},
+			want:  []string{pending},
+			avoid: []string{"Original URL", "Expires"},
+		},
+		{
+			name:  "missing URL",
+			res:   &shortenerv1.GetLinkStatsResponse{ClickCount: 1, ExpiresAt: later},
+			want:  []string{pending},
+			avoid: []string{"Original URL", "Expires"},
+		},
+		{
+			name:  "zero timestamps",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ExpiresAt: zero, LastClickAt: zero, NotBefore: zero},
+			want:  []string{"Expires: Never"},
+			avoid: []string{pending, "Last Click", "1970", "Scheduled"},
+		},
+		{
+			name:  "expiry set",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ExpiresAt: later},
+			want:  []string{"Expires: " + later.AsTime().UTC().Format("2006")},
+			avoid: []string{"Expires: Never"},
+		},
+		{
+			name:  "nil device map",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ClickCount: 4},
+			avoid: []string{"By Device"},
+		},
+		{
+			name:  "devices without clicks",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ClicksByDevice: map[string]int64{"mobile": 0, "tablet": 0}},
+			avoid: []string{"By Device", "mobile"},
+		},
+		{
+			name:  "some devices without clicks",
+			res:   &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/", ClicksByDevice: map[string]int64{"mobile": 5, "tablet": 0}},
+			want:  []string{"By Device:", "- mobile: 5"},
+			avoid: []string{"tablet"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			text, keyboard := e.bot.renderStats(payloadKey{chatID: user, userID: user}, styleRich, "abc", tt.res)
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("stats lack %q:\n%s", want, text)
+				}
+			}
+			for _, avoid := range tt.avoid {
+				if strings.Contains(text, avoid) {
+					t.Errorf("stats contain %q:\n%s", avoid, text)
+				}
+			}
+
+			// Incomplete stats only offer to refresh them
+			var buttons []string
+			for _, row := range keyboard.InlineKeyboard {
+				for _, button := range row {
+					buttons = append(buttons, button.Text)
+				}
+			}
+			isPending := strings.Contains(text, pending)
+			if onlyRefresh := len(buttons) == 1 && buttons[0] == "Refresh"; onlyRefresh != isPending {
+				t.Errorf("buttons = %v with pending %v", buttons, isPending)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/broadcast"
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/keyboards"
+	"GURLS-Bot/internal/maintenance"
+	"GURLS-Bot/internal/monitor"
+	"GURLS-Bot/internal/outbox"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/reconcile"
+	"GURLS-Bot/internal/reports"
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/usage"
+	"GURLS-Bot/internal/users"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// openStore opens the store the bot keeps its state in and imports the
+// files earlier versions kept it in, logging what was imported.
+func openStore(cfg *config.Config, log *zap.Logger) (store.Store, error) {
+	db, err := store.Open(cfg.Storage.Backend, cfg.Storage.Path)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := store.Migrate(db, []store.Legacy{
+		legacyCreateQueue(cfg.Queue.Path),
+		prefs.Legacy(cfg.Prefs.Path),
+		users.Legacy(cfg.Users.Path),
+		broadcast.Legacy(cfg.Broadcast.Path),
+		monitor.Legacy(cfg.Monitor.Path),
+		keyboards.Legacy(cfg.Keyboards.Path),
+		reconcile.Legacy(cfg.Reconcile.Path),
+		outbox.Legacy(cfg.Outbox.Path),
+		reports.Legacy(cfg.Reports.Path),
+		usage.Legacy(cfg.Usage.Path),
+		maintenance.Legacy(cfg.Maintenance.Path),
+	})
+	for _, m := range migrated {
+		log.Info("imported legacy file into the store",
+			zap.String("name", m.Name),
+			zap.String("path", m.Path),
+			zap.Int("records", m.Records))
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// logQuarantined warns about the records the stores opened since set aside
+// because they could not be decoded.
+func logQuarantined(db store.Store, since time.Time, log *zap.Logger) {
+	records, err := store.QuarantinedSince(db, since)
+	if err != nil {
+		log.Error("failed to list quarantined store records", zap.Error(err))
+		return
+	}
+	for _, q := range records {
+		log.Warn("store record could not be decoded, moved to quarantine",
+			zap.String("namespace", q.Namespace),
+			zap.String("key", q.Key),
+			zap.String("error", q.Error))
+	}
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDisplayTitleLen caps the titles from the backend shown in messages, in
+// runes.
+const maxDisplayTitleLen = 100
+
+// customEmojiRegexes match the custom emoji placeholders titles written on
+// the dashboard may carry: the HTML and the MarkdownV2 form of Telegram's
+// custom emoji entities. The first group is the plain emoji they fall back
+// to.
+var customEmojiRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`(?s)<tg-emoji\b[^>]*>(.*?)</tg-emoji>`),
+	regexp.MustCompile(`!\[([^\]]*)\]\(tg://emoji\?id=\d+\)`),
+}
+
+// displayTitle makes a title from the backend fit for echoing in a message.
+// Custom emoji placeholders become their fallback emoji, private use
+// characters that render as boxes are dropped, and so are control and bidi
+// override characters, which could garble or reverse the surrounding text.
+// Runs of whitespace, newlines included, collapse into one space, and long
+// titles are cut to maxDisplayTitleLen runes. Zero-width joiners inside
+// emoji sequences and right-to-left text are kept.
+func displayTitle(title string) string {
+	for _, re := range customEmojiRegexes {
+		title = re.ReplaceAllString(title, "$1")
+	}
+	var builder strings.Builder
+	space := false
+	for _, r := range title {
+		switch {
+		case unicode.IsSpace(r):
+			space = builder.Len() > 0
+			continue
+		case r == utf8.RuneError, unicode.Is(unicode.Co, r), unicode.IsControl(r), isBidiOverride(r):
+			continue
+		}
+		if space {
+			builder.WriteByte(' ')
+			space = false
+		}
+		builder.WriteRune(r)
+	}
+	return truncateRunes(builder.String(), maxDisplayTitleLen)
+}
+
+// truncateRunes cuts s to at most limit runes, ending it with an ellipsis
+// when cut. A cut doesn't leave a dangling joiner or variation selector.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	cut := strings.TrimRightFunc(string(runes[:limit-1]), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\u200d' || unicode.Is(unicode.Variation_Selector, r)
+	})
+	return cut + "…"
+}
+
+// isBidiOverride reports whether r is a bidi embedding, override or isolate
+// character. The implicit marks, U+200E and U+200F, are left alone.
+func isBidiOverride(r rune) bool {
+	return r >= '\u202a' && r <= '\u202e' || r >= '\u2066' && r <= '\u2069'
+}
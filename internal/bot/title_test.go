@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/backendtest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// family is an emoji sequence of three people held by zero-width joiners.
+const family = "👨\u200d👩\u200d👧"
+
+func TestDisplayTitle(t *testing.T) {
+	for _, tt := range []struct {
+		name, title, want string
+	}{
+		{name: "plain", title: "Launch notes", want: "Launch notes"},
+		{name: "empty", title: "", want: ""},
+		{name: "html custom emoji", title: `Sale <tg-emoji emoji-id="5368324170671202286">🔥</tg-emoji> today`, want: "Sale 🔥 today"},
+		{name: "markdown custom emoji", title: "Sale ![🔥](tg://emoji?id=5368324170671202286) today", want: "Sale 🔥 today"},
+		{name: "custom emoji without fallback", title: `Sale <tg-emoji emoji-id="1"></tg-emoji>`, want: "Sale"},
+		{name: "private use placeholder", title: "Sale \ue000 today", want: "Sale today"},
+		{name: "newlines and tabs", title: "  First line\n\n\tsecond  line \r\n", want: "First line second line"},
+		{name: "control characters", title: "Bell\a and\x00 escape\x1b", want: "Bell and escape"},
+		{name: "invalid UTF-8", title: "Broken \xff title", want: "Broken title"},
+		{name: "bidi override", title: "invoice \u202efdp.exe", want: "invoice fdp.exe"},
+		{name: "bidi isolate", title: "\u2067שלום\u2069 world", want: "שלום world"},
+		{name: "right-to-left text", title: "مرحبا بالعالم", want: "مرحبا بالعالم"},
+		{name: "implicit direction marks", title: "abc\u200fשלום\u200e", want: "abc\u200fשלום\u200e"},
+		{name: "zero-width joiner sequence", title: "Family " + family, want: "Family " + family},
+		{name: "keycap and variation selector", title: "Step 1\ufe0f\u20e3 ❤\ufe0f", want: "Step 1\ufe0f\u20e3 ❤\ufe0f"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayTitle(tt.title); got != tt.want {
+				t.Errorf("displayTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayTitleLength(t *testing.T) {
+	for _, title := range []string{
+		strings.Repeat("a", maxDisplayTitleLen),
+		strings.Repeat("é", maxDisplayTitleLen+1),
+		strings.Repeat("שלום ", 40),
+		strings.Repeat(family, 40),
+		strings.Repeat("word\n", 40),
+	} {
+		got := displayTitle(title)
+		if n := utf8.RuneCountInString(got); n > maxDisplayTitleLen {
+			t.Errorf("displayTitle of %d runes kept %d", utf8.RuneCountInString(title), n)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("displayTitle(%q) = %q, not valid UTF-8", title, got)
+		}
+	}
+	if got := displayTitle(strings.Repeat("a", maxDisplayTitleLen)); strings.HasSuffix(got, "…") {
+		t.Error("title of exactly the limit cut")
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{name: "short", s: "abc", limit: 5, want: "abc"},
+		{name: "at limit", s: "abcde", limit: 5, want: "abcde"},
+		{name: "cut", s: "abcdef", limit: 5, want: "abcd…"},
+		{name: "multi-byte", s: "ééééééé", limit: 5, want: "éééé…"},
+		{name: "trailing space", s: "abc def", limit: 5, want: "abc…"},
+		{name: "dangling joiner", s: "ab👨\u200d👩x", limit: 5, want: "ab👨…"},
+		{name: "dangling variation selector", s: "ab❤\ufe0fcd", limit: 5, want: "ab❤…"},
+		{name: "right-to-left", s: "שלום עולם", limit: 5, want: "שלום…"},
+	} {
+		if got := truncateRunes(tt.s, tt.limit); got != tt.want {
+			t.Errorf("%s: truncateRunes(%q, %d) = %q, want %q", tt.name, tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestE2ETitlesSanitized(t *testing.T) {
+	e := startBot(t, nil)
+	e.backend.Add(backendtest.Link{
+		Alias:       "abc",
+		OriginalURL: "https://example.com/",
+		Title:       "Big <tg-emoji emoji-id=\"5368324170671202286\">🔥</tg-emoji>\nsale\u202e now",
+		OwnerID:     user,
+	})
+
+	e.tg.SendMessage(user, "/stats abc")
+	if got := e.tg.WaitText(user, "Link Statistics: abc").Text(); !strings.Contains(got, "Title: Big 🔥 sale now\n") {
+		t.Errorf("/stats = %q, want the title sanitized", got)
+	}
+	e.tg.SendMessage(user, "/my_links")
+	if got := e.tg.WaitText(user, "Your Links").Text(); !strings.Contains(got, "1. Big 🔥 sale now\n") {
+		t.Errorf("/my_links = %q, want the title sanitized", got)
+	}
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/config"
+	"GURLS-Bot/internal/metrics"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// ErrTokenRevoked is returned once Telegram rejects the bot token, at
+// startup or for longer than Telegram.RevokedGrace while running.
+var ErrTokenRevoked = errors.New("telegram rejected the bot token")
+
+// tokenClient watches Bot API responses for a rejected token and lets the
+// token be replaced while running. tgbotapi puts the token it was created
+// with into every request URL, so a new one is swapped in here.
+type tokenClient struct {
+	next tgbotapi.HTTPClient
+	// initial is the token tgbotapi was created with.
+	initial string
+	current atomic.Pointer[string]
+	// rejected is set while Telegram answers with 401 Unauthorized.
+	rejected atomic.Bool
+	// changed is signalled whenever rejected flips.
+	changed chan struct{}
+}
+
+func newTokenClient(next tgbotapi.HTTPClient, token string) *tokenClient {
+	c := &tokenClient{next: next, initial: token, changed: make(chan struct{}, 1)}
+	c.current.Store(&token)
+	return c
+}
+
+func (c *tokenClient) Do(req *http.Request) (*http.Response, error) {
+	if token := *c.current.Load(); token != c.initial {
+		req.URL.Path = strings.Replace(req.URL.Path, c.initial, token, 1)
+		req.URL.RawPath = ""
+	}
+	res, err := c.next.Do(req)
+	if err != nil {
+		return res, err
+	}
+	// Any other answer means the token was accepted
+	c.setRejected(res.StatusCode == http.StatusUnauthorized)
+	return res, nil
+}
+
+func (c *tokenClient) setRejected(rejected bool) {
+	if !c.rejected.CompareAndSwap(!rejected, rejected) {
+		return
+	}
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Set makes requests use token from now on and reports whether it differs
+// from the one in use.
+func (c *tokenClient) Set(token string) bool {
+	return *c.current.Swap(&token) != token
+}
+
+// SetToken replaces the Bot API token, as read again from the configuration
+// on SIGHUP. Whether Telegram accepts it shows with the next request.
+func (b *Bot) SetToken(token string) {
+	if b.tokens == nil || token == "" {
+		return
+	}
+	if b.tokens.Set(token) {
+		b.log.Info("telegram token replaced")
+	}
+}
+
+// watchToken follows whether Telegram accepts the bot token. Once it is
+// rejected, the bot is reported not ready through the telegram_authorized
+// metric and, unless configured to wait for a new token, gives up after
+// Telegram.RevokedGrace so the orchestrator restarts it with the current
+// secret. Requests keep going during the grace period, as the getUpdates
+// loop retries on its own, and a token accepted again cancels the exit.
+func (b *Bot) watchToken(ctx context.Context) error {
+	metrics.TelegramAuthorized.Set(1)
+	var grace <-chan time.Time
+	var rejectedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-grace:
+			b.log.Error("exiting, telegram keeps rejecting the bot token",
+				zap.Duration("grace", b.config.Telegram.RevokedGrace))
+			return ErrTokenRevoked
+		case <-b.tokens.changed:
+		}
+
+		if !b.tokens.rejected.Load() {
+			if rejectedAt.IsZero() {
+				continue
+			}
+			grace = nil
+			metrics.TelegramAuthorized.Set(1)
+			b.log.Info("telegram accepts the bot token again")
+			b.notifyAdmins(b.render(msgAdminTokenRestored, backendDownData{For: time.Since(rejectedAt).Round(time.Second)}))
+			rejectedAt = time.Time{}
+			continue
+		}
+		if !rejectedAt.IsZero() {
+			continue
+		}
+		rejectedAt = time.Now()
+		metrics.TelegramAuthorized.Set(0)
+		if b.config.Telegram.OnRevoked == config.OnRevokedWait {
+			b.log.Error("telegram rejected the bot token, waiting for a new one to be reloaded with SIGHUP")
+			continue
+		}
+		b.log.Error("telegram rejected the bot token",
+			zap.Duration("exit_in", b.config.Telegram.RevokedGrace))
+		grace = time.After(b.config.Telegram.RevokedGrace)
+	}
+}
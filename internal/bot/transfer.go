@@ -0,0 +1,205 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/prefs"
+	"GURLS-Bot/internal/users"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// transferOffer is a link handed over to another user, waiting for their
+// answer. Offers live in memory; unanswered ones expire with the map.
+type transferOffer struct {
+	Alias  string
+	URL    string
+	FromID int64
+	ToID   int64
+}
+
+// startTransfer asks for the user to hand alias over to.
+func (b *Bot) startTransfer(chatID int64, alias string) error {
+	if err := b.startDialog(chatID, UserState{State: StateWaitingForTransferRecipient, Payload: transferPayload{Alias: alias}}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(chatID, msgSendTransferRecipient, linkData{ShortURL: displayURL(b.shortURL(alias))}, b.createCancelKeyboard())
+}
+
+// transferRecipient finds the user msg points at: a shared contact, the
+// sender of a forwarded message, an @username or a numeric ID. Only users
+// known to the bot can be messaged, so others aren't found.
+func (b *Bot) transferRecipient(msg *tgbotapi.Message) (users.User, bool) {
+	var id int64
+	text := strings.TrimSpace(msg.Text)
+	switch {
+	case msg.Contact != nil:
+		id = msg.Contact.UserID
+	case msg.ForwardFrom != nil:
+		id = msg.ForwardFrom.ID
+	case strings.HasPrefix(text, "@"):
+		return b.users.FindByUsername(text)
+	default:
+		id, _ = strconv.ParseInt(text, 10, 64)
+	}
+	if id == 0 {
+		return users.User{}, false
+	}
+	return b.users.Get(id)
+}
+
+// formerNameHint is how long after a username change the former one is
+// mentioned along with the new one.
+const formerNameHint = 30 * 24 * time.Hour
+
+// userLabel names a user for the other side of a transfer.
+func userLabel(u users.User) string {
+	switch {
+	case u.Username != "":
+		return "@" + u.Username
+	case u.FirstName != "":
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
+
+// peerLabel is userLabel hinting at a recent username change, so the other
+// side of a transfer still recognizes the user.
+func peerLabel(u users.User) string {
+	if former, ok := u.FormerUsername(time.Now().Add(-formerNameHint)); ok {
+		return userLabel(u) + " (formerly @" + former + ")"
+	}
+	return userLabel(u)
+}
+
+// handleTransferRecipientInput offers the link to the recipient sent. The
+// user stays at the prompt until a known user is sent.
+func (b *Bot) handleTransferRecipientInput(ctx context.Context, msg *tgbotapi.Message, alias string) error {
+	fromID := msg.Chat.ID
+	to, ok := b.transferRecipient(msg)
+	switch {
+	case !ok:
+		return b.reply(fromID, msgTransferRecipientUnknown, nil)
+	case to.ID == fromID:
+		return b.reply(fromID, msgTransferToSelf, nil)
+	}
+	b.resetUserState(fromID)
+
+	stats, err := b.grpcClient.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: alias})
+	if err != nil {
+		b.log.Error("gRPC GetLinkStats failed", zap.Error(err), zap.String("alias", alias))
+		return b.replyGRPCError(fromID, err, alias)
+	}
+	offer := &transferOffer{Alias: alias, URL: stats.GetOriginalUrl(), FromID: fromID, ToID: to.ID}
+	id := newPayloadToken()
+	b.transfers.Set(id, offer)
+
+	from, _ := b.transferParties(offer)
+	data := transferData{
+		ShortURL:  displayURL(b.shortURLOn(stats.GetDomain(), alias)),
+		URL:       offer.URL,
+		Peer:      peerLabel(from),
+		ExpiresIn: formatRemaining(b.config.Transfer.OfferTTL),
+	}
+	offerMsg := tgbotapi.NewMessage(to.ID, b.render(msgTransferOffer, data))
+	offerMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		b.payloadButton(chatPayloads(to.ID), "Accept", actionTransferAccept, id),
+		b.payloadButton(chatPayloads(to.ID), "Decline", actionTransferDecline, id),
+	))
+	offerMsg.DisableWebPagePreview = true
+	data.Peer = peerLabel(to)
+	if _, err := b.send(offerMsg, b.notification(to.ID)); err != nil {
+		b.log.Warn("failed to send transfer offer", zap.Int64("recipient_id", to.ID), zap.Error(err))
+		b.transfers.Delete(id)
+		return b.reply(fromID, msgTransferUnreachable, data)
+	}
+	b.log.Info("link transfer offered", zap.String("alias", alias), zap.Int64("from_id", fromID), zap.Int64("to_id", to.ID))
+	return b.reply(fromID, msgTransferOffered, data)
+}
+
+// takeTransferOffer removes and returns the offer a button of r belongs to.
+// Buttons of expired or answered offers, and of other users, close the
+// message instead.
+func (b *Bot) takeTransferOffer(r *Request) (*transferOffer, bool, error) {
+	offer, ok := b.transfers.Get(r.Args)
+	if !ok || offer.ToID != r.ChatID {
+		return nil, false, b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgTransferExpired, nil))
+	}
+	b.transfers.Delete(r.Args)
+	return offer, true, nil
+}
+
+// acceptTransfer moves the offered link to the recipient and tells both.
+func (b *Bot) acceptTransfer(ctx context.Context, r *Request) error {
+	offer, ok, err := b.takeTransferOffer(r)
+	if !ok {
+		return err
+	}
+	res, err := b.grpcClient.TransferLink(ctx, &shortenerv1.TransferLinkRequest{
+		Alias:        offer.Alias,
+		UserTgId:     offer.FromID,
+		NewOwnerTgId: offer.ToID,
+	})
+	if err != nil {
+		b.log.Error("gRPC TransferLink failed", zap.Error(err), zap.String("alias", offer.Alias))
+		r.Answer.alert(b.mapGRPCError(err, offer.Alias))
+		b.dropKeyboard(r.ChatID, r.Message.MessageID)
+		return nil
+	}
+
+	b.unpin(offer.FromID, offer.Alias)
+	b.staleKeyboards(offer.Alias)
+	if err := b.monitors.Remove(offer.Alias); err != nil {
+		b.log.Error("failed to save monitored links", zap.Error(err))
+	}
+	from, to := b.transferParties(offer)
+	b.recordHistory(offer.FromID, prefs.HistoryEntry{Action: historyTransferred, Alias: offer.Alias, Peer: userLabel(to)})
+	b.recordHistory(offer.ToID, prefs.HistoryEntry{Action: historyReceived, Alias: offer.Alias, Peer: userLabel(from)})
+	b.log.Info("link transferred", zap.String("alias", offer.Alias), zap.Int64("from_id", offer.FromID), zap.Int64("to_id", offer.ToID))
+
+	data := transferData{ShortURL: displayURL(b.shortURLOn(res.GetDomain(), offer.Alias)), URL: offer.URL}
+	b.notifyTransferSender(offer, msgTransferCompleted, data, to)
+	text := b.render(msgTransferAccepted, data)
+	edit := tgbotapi.NewEditMessageTextAndMarkup(r.ChatID, r.Message.MessageID, text, b.createStatsKeyboard(r.Payloads(), offer.Alias))
+	edit.DisableWebPagePreview = true
+	return b.editMessage(edit, r.Answer, nil)
+}
+
+// declineTransfer drops the offer and tells the sender.
+func (b *Bot) declineTransfer(r *Request) error {
+	offer, ok, err := b.takeTransferOffer(r)
+	if !ok {
+		return err
+	}
+	_, to := b.transferParties(offer)
+	data := transferData{ShortURL: displayURL(b.shortURL(offer.Alias)), URL: offer.URL}
+	b.notifyTransferSender(offer, msgTransferRejected, data, to)
+	return b.editMessageText(r.ChatID, r.Message.MessageID, b.render(msgTransferDeclined, data))
+}
+
+// transferParties returns the registry records of both sides of offer,
+// falling back to bare IDs.
+func (b *Bot) transferParties(offer *transferOffer) (from, to users.User) {
+	from, _ = b.users.Get(offer.FromID)
+	from.ID = offer.FromID
+	to, _ = b.users.Get(offer.ToID)
+	to.ID = offer.ToID
+	return from, to
+}
+
+// notifyTransferSender tells the sender of offer how it was answered. It is
+// best effort; the answer stands either way.
+func (b *Bot) notifyTransferSender(offer *transferOffer, name string, data transferData, to users.User) {
+	data.Peer = peerLabel(to)
+	msg := tgbotapi.NewMessage(offer.FromID, b.render(name, data))
+	msg.DisableWebPagePreview = true
+	key := fmt.Sprintf("transfer/%s/%d/%d", offer.Alias, offer.FromID, offer.ToID)
+	if err := b.sendCritical(key, msg, b.notification(offer.FromID)); err != nil {
+		b.log.Warn("failed to notify transfer sender", zap.Int64("sender_id", offer.FromID), zap.Error(err))
+	}
+}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// updatesPollTimeout is how long a getUpdates long poll waits for
+	// updates, in seconds. A healthy poll answers at least this often.
+	updatesPollTimeout = 60
+	// updatesPollGrace is how much longer than updatesPollTimeout a poll
+	// may take before it is given up, so a hung connection can't stall
+	// the updates for good.
+	updatesPollGrace = 30 * time.Second
+	// updatesRestartAfter is the number of failed polls in a row after
+	// which the poll is started over on a fresh request.
+	updatesRestartAfter = 3
+	// updatesCheckInterval is how often the update stream is checked.
+	updatesCheckInterval = 10 * time.Second
+)
+
+// pollClient watches the getUpdates long polls tgbotapi makes through it.
+// tgbotapi retries failed polls on its own but tells nobody, and a poll
+// stuck on a dead connection never returns. Every poll here is bounded and
+// can be cut short, which makes tgbotapi start over with a new one.
+type pollClient struct {
+	next tgbotapi.HTTPClient
+	// lastOK is when a poll last succeeded, in Unix nanoseconds.
+	lastOK atomic.Int64
+	// failures counts the polls failed in a row.
+	failures atomic.Int64
+	lastErr  atomic.Pointer[string]
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newPollClient(next tgbotapi.HTTPClient) *pollClient {
+	c := &pollClient{next: next}
+	c.lastOK.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *pollClient) Do(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/getUpdates") {
+		return c.next.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), updatesPollTimeout*time.Second+updatesPollGrace)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	res, err := c.next.Do(req.WithContext(ctx))
+	switch {
+	case err != nil:
+		cancel()
+		c.failed(err.Error())
+		return nil, err
+	case res.StatusCode != http.StatusOK:
+		c.failed(res.Status)
+	default:
+		c.lastOK.Store(time.Now().UnixNano())
+		c.failures.Store(0)
+	}
+	// The body is read after Do returns; the poll ends once it is closed
+	res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+func (c *pollClient) failed(reason string) {
+	c.failures.Add(1)
+	c.lastErr.Store(&reason)
+}
+
+// Restart cuts the poll in flight short, so that tgbotapi makes a new one.
+func (c *pollClient) Restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// LastError returns why the last failed poll failed.
+func (c *pollClient) LastError() string {
+	if err := c.lastErr.Load(); err != nil {
+		return *err
+	}
+	return ""
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// updatesReceived notes that an update just came in.
+func (b *Bot) updatesReceived() {
+	b.lastUpdate.Store(time.Now().UnixNano())
+}
+
+// watchUpdates follows the health of the update stream, checking it every
+// updatesCheckInterval.
+func (b *Bot) watchUpdates(ctx context.Context) {
+	ticker := time.NewTicker(updatesCheckInterval)
+	defer ticker.Stop()
+	var w updatesWatch
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.checkUpdates(&w, time.Now())
+		}
+	}
+}
+
+// updatesWatch is the state checkUpdates keeps between checks.
+type updatesWatch struct {
+	stalledSince, restartedAt time.Time
+}
+
+// checkUpdates checks the update stream at now. Once no poll has succeeded
+// for Telegram.UpdatesStallAfter, the admins are alerted, and again when
+// polls succeed again. A poll failing repeatedly or running stalled is
+// started over, at most once per Telegram.UpdatesStallAfter. The time since
+// the last poll and the last update are published as metrics for external
+// alerting.
+func (b *Bot) checkUpdates(w *updatesWatch, now time.Time) {
+	stallAfter := b.config.Telegram.UpdatesStallAfter
+	lastOK := time.Unix(0, b.polls.lastOK.Load())
+	silence := now.Sub(lastOK)
+	metrics.SecondsSinceLastPoll.Set(int64(silence.Seconds()))
+	if last := b.lastUpdate.Load(); last != 0 {
+		metrics.SecondsSinceLastUpdate.Set(int64(now.Sub(time.Unix(0, last)).Seconds()))
+	}
+
+	stalled := silence >= stallAfter
+	switch {
+	case stalled && w.stalledSince.IsZero():
+		w.stalledSince = lastOK
+		b.log.Error("telegram updates stalled",
+			zap.Duration("for", silence),
+			zap.Int64("failures", b.polls.failures.Load()),
+			zap.String("last_error", b.polls.LastError()))
+		b.notifyAdmins(b.render(msgAdminUpdatesStalled, backendDownData{
+			For:   silence.Round(time.Second),
+			Error: b.polls.LastError(),
+		}))
+	case !stalled && !w.stalledSince.IsZero():
+		b.log.Info("telegram updates recovered", zap.Duration("after", lastOK.Sub(w.stalledSince)))
+		b.notifyAdmins(b.render(msgAdminUpdatesRecovered, backendDownData{For: lastOK.Sub(w.stalledSince).Round(time.Second)}))
+		w.stalledSince = time.Time{}
+	}
+
+	if (stalled || b.polls.failures.Load() >= updatesRestartAfter) && now.Sub(w.restartedAt) >= stallAfter {
+		w.restartedAt = now
+		metrics.UpdatesRestarts.Add(1)
+		b.log.Warn("restarting telegram updates",
+			zap.Duration("since_last_poll", silence),
+			zap.Int64("failures", b.polls.failures.Load()),
+			zap.String("last_error", b.polls.LastError()))
+		b.polls.Restart()
+	}
+}
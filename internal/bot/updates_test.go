@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/metrics"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// httpFunc is a tgbotapi.HTTPClient answering with a function.
+type httpFunc func(*http.Request) (*http.Response, error)
+
+func (f httpFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func newPoll(t *testing.T, method string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "https://api.telegram.org/botTOKEN/"+method, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func pollAnswer(code int) *http.Response {
+	return &http.Response{StatusCode: code, Status: http.StatusText(code), Body: io.NopCloser(strings.NewReader(`{"ok":true,"result":[]}`))}
+}
+
+func TestPollClientCountsFailures(t *testing.T) {
+	var next func() (*http.Response, error)
+	c := newPollClient(httpFunc(func(*http.Request) (*http.Response, error) { return next() }))
+	poll := func(method string) {
+		t.Helper()
+		if res, err := c.Do(newPoll(t, method)); err == nil {
+			res.Body.Close()
+		}
+	}
+
+	next = func() (*http.Response, error) { return pollAnswer(http.StatusBadGateway), nil }
+	poll("getUpdates")
+	next = func() (*http.Response, error) { return nil, errors.New("connection reset") }
+	poll("getUpdates")
+	if got := c.failures.Load(); got != 2 || c.LastError() != "connection reset" {
+		t.Errorf("failures = %d, last error %q; want 2, the connection reset", got, c.LastError())
+	}
+	// Other methods aren't polls
+	poll("sendMessage")
+	if got := c.failures.Load(); got != 2 {
+		t.Errorf("failures = %d after a failed sendMessage, want 2", got)
+	}
+
+	before := c.lastOK.Load()
+	next = func() (*http.Response, error) { return pollAnswer(http.StatusOK), nil }
+	poll("getUpdates")
+	if c.failures.Load() != 0 || c.lastOK.Load() <= before {
+		t.Errorf("failures = %d, last success not moved; want a success noted", c.failures.Load())
+	}
+}
+
+func TestPollClientRestart(t *testing.T) {
+	polling := make(chan struct{})
+	c := newPollClient(httpFunc(func(req *http.Request) (*http.Response, error) {
+		close(polling)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do(newPoll(t, "getUpdates"))
+		done <- err
+	}()
+	<-polling
+	c.Restart()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("poll ended with %v, want it cancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Restart didn't cut the poll short")
+	}
+	if c.failures.Load() != 1 {
+		t.Errorf("failures = %d, want the cut poll counted", c.failures.Load())
+	}
+}
+
+func TestPollClientBodyOutlivesDo(t *testing.T) {
+	var ctx context.Context
+	c := newPollClient(httpFunc(func(req *http.Request) (*http.Response, error) {
+		ctx = req.Context()
+		return pollAnswer(http.StatusOK), nil
+	}))
+	res, err := c.Do(newPoll(t, "getUpdates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tgbotapi reads the body after Do returns
+	if ctx.Err() != nil {
+		t.Fatal("poll cancelled before its body was read")
+	}
+	res.Body.Close()
+	if ctx.Err() == nil {
+		t.Error("poll not released once its body was closed")
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stallAlerts counts the update stall alerts sent to user.
+func (e *e2e) stallAlerts() int {
+	n := 0
+	for _, r := range e.tg.Requests("sendMessage") {
+		if r.ChatID() == user && strings.HasPrefix(r.Text(), "No updates have come from Telegram") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestE2EUpdatesStall(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+	stallAfter := cfg.Telegram.UpdatesStallAfter
+	var w updatesWatch
+
+	// A healthy stream is left alone
+	restarts := metrics.UpdatesRestarts.Value()
+	e.bot.checkUpdates(&w, time.Now())
+	if metrics.UpdatesRestarts.Value() != restarts {
+		t.Error("healthy poll restarted")
+	}
+
+	e.tg.Stall()
+	waitFor(t, "a poll to hang", func() bool { return e.tg.Hung() == 1 })
+	later := time.Now().Add(stallAfter)
+	e.bot.checkUpdates(&w, later)
+	e.tg.WaitText(user, "No updates have come from Telegram for")
+	if got := metrics.UpdatesRestarts.Value(); got != restarts+1 {
+		t.Errorf("%d restarts, want 1", got-restarts)
+	}
+	if got := metrics.SecondsSinceLastPoll.Value(); got < int64(stallAfter.Seconds()) {
+		t.Errorf("seconds since last poll = %d, want at least %v", got, stallAfter)
+	}
+	// The hung poll is given up
+	waitFor(t, "the hung poll to be cut", func() bool { return e.tg.Hung() == 0 && e.bot.polls.failures.Load() > 0 })
+
+	// Neither the alert nor the restart repeat while the stall lasts
+	e.bot.checkUpdates(&w, later.Add(updatesCheckInterval))
+	if got := metrics.UpdatesRestarts.Value(); got != restarts+1 {
+		t.Errorf("%d restarts within one stall interval, want 1", got-restarts)
+	}
+
+	e.tg.Resume()
+	stalled := time.Now()
+	waitFor(t, "a poll to succeed", func() bool { return e.bot.polls.lastOK.Load() > stalled.UnixNano() })
+	e.bot.checkUpdates(&w, time.Now())
+	e.tg.WaitText(user, "Updates from Telegram are flowing again")
+	if n := e.stallAlerts(); n != 1 {
+		t.Errorf("stall alerted %d times, want once", n)
+	}
+}
+
+func TestE2EUpdatesRestartAfterFailures(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+	var w updatesWatch
+
+	restarts := metrics.UpdatesRestarts.Value()
+	e.bot.polls.failures.Store(updatesRestartAfter - 1)
+	e.bot.checkUpdates(&w, time.Now())
+	if metrics.UpdatesRestarts.Value() != restarts {
+		t.Errorf("restarted after %d failures, want %d", updatesRestartAfter-1, updatesRestartAfter)
+	}
+	e.bot.polls.failures.Store(updatesRestartAfter)
+	e.bot.checkUpdates(&w, time.Now())
+	if metrics.UpdatesRestarts.Value() != restarts+1 {
+		t.Errorf("not restarted after %d failures", updatesRestartAfter)
+	}
+	// Failing polls that still succeed now and then don't alert
+	if n := e.stallAlerts(); n != 0 {
+		t.Errorf("stall alerted %d times without a stall", n)
+	}
+}
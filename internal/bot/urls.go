@@ -0,0 +1,92 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxURLsPerMessage caps how many URLs are taken from a single message.
+const maxURLsPerMessage = 5
+
+// extractURLs returns the unique URLs found in the message text and caption.
+// URL and text_link entities are preferred; the URL regex is a fallback for
+// messages without entities.
+func extractURLs(msg *tgbotapi.Message) []string {
+	if msg == nil {
+		return nil
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(u string) {
+		if u == "" || seen[u] || len(urls) >= maxURLsPerMessage {
+			return
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+
+	for _, part := range []struct {
+		text     string
+		entities []tgbotapi.MessageEntity
+	}{
+		{msg.Text, msg.Entities},
+		{msg.Caption, msg.CaptionEntities},
+	} {
+		if len(part.entities) == 0 {
+			for _, u := range urlRegex.FindAllString(part.text, -1) {
+				add(u)
+			}
+			continue
+		}
+		for _, e := range part.entities {
+			switch e.Type {
+			case "text_link":
+				add(e.URL)
+			case "url":
+				// Telegram also detects bare hosts like example.com/page
+				u := entityText(part.text, e)
+				if u != "" && !strings.Contains(u, "://") {
+					u = "https://" + u
+				}
+				add(u)
+			}
+		}
+	}
+	return urls
+}
+
+// entityText returns the text covered by e; entity offsets are in UTF-16 code units.
+func entityText(text string, e tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+}
+
+// shortenReplied shortens every URL in the replied-to message on behalf of
+// the user who issued the command.
+func (b *Bot) shortenReplied(ctx context.Context, to payloadKey, ownerID int64, replied *tgbotapi.Message) error {
+	urls := extractURLs(replied)
+	if len(urls) == 0 {
+		return b.reply(to.chatID, msgReplyHasNoURL, nil)
+	}
+	if ownerID == 0 {
+		ownerID = to.chatID
+	}
+	// A URL failing doesn't stop the others
+	var errs error
+	for _, u := range urls {
+		req := &shortenerv1.CreateLinkRequest{OriginalUrl: u, UserTgId: ownerID, Source: linkSource(sourceBotMessage)}
+		if _, err := b.createLink(ctx, to, req, createOptions{}); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// usageSummaryCheckInterval is how often the usage summary is checked
+	// for being due.
+	usageSummaryCheckInterval = time.Hour
+	// usageTopRoutes caps the routes listed in /admin_stats and the summary.
+	usageTopRoutes = 15
+	// usageStatsDays is the span of days /admin_stats shows usage for.
+	usageStatsDays = 7
+)
+
+// usageMiddleware counts every routed request that got past the access
+// checks by route alone, when usage analytics are turned on. Who made the
+// request and its arguments are never recorded.
+func (b *Bot) usageMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		if b.usage != nil && req.Route != nil {
+			b.usage.Add(routeKey(req), time.Now())
+		}
+		return next(ctx, req)
+	}
+}
+
+// usageStats returns the usage of the last usageStatsDays days, today
+// included, for /admin_stats.
+func (b *Bot) usageStats(now time.Time) []usageData {
+	today := b.usage.StartOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	todays := make(map[string]int64)
+	for _, c := range b.usage.Totals(today, tomorrow) {
+		todays[c.Route] = c.Count
+	}
+	totals := b.usage.Totals(today.AddDate(0, 0, 1-usageStatsDays), tomorrow)
+	data := make([]usageData, 0, min(len(totals), usageTopRoutes))
+	for _, c := range totals[:min(len(totals), usageTopRoutes)] {
+		data = append(data, usageData{Route: c.Route, Count: c.Count, Today: todays[c.Route]})
+	}
+	return data
+}
+
+// runUsageSummaries sends admins a summary of the usage of the whole days
+// since the last one, every Usage.SummaryInterval. The first interval
+// starts with the first run.
+func (b *Bot) runUsageSummaries(ctx context.Context) {
+	ticker := time.NewTicker(usageSummaryCheckInterval)
+	defer ticker.Stop()
+	for {
+		b.sendUsageSummary(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendUsageSummary sends the usage summary when it is due.
+func (b *Bot) sendUsageSummary(now time.Time) {
+	last := b.usage.SummaryAt()
+	if !last.IsZero() && now.Sub(last) < b.config.Usage.SummaryInterval {
+		return
+	}
+	from, to := b.usage.StartOfDay(last), b.usage.StartOfDay(now)
+	if !last.IsZero() {
+		if !to.After(from) {
+			// No whole day to sum up yet
+			return
+		}
+		totals := b.usage.Totals(from, to)
+		data := usageSummaryData{
+			From: from.Format(time.DateOnly),
+			To:   to.AddDate(0, 0, -1).Format(time.DateOnly),
+		}
+		for _, c := range totals {
+			data.Total += c.Count
+		}
+		for _, c := range totals[:min(len(totals), usageTopRoutes)] {
+			data.Routes = append(data.Routes, usageData{Route: c.Route, Count: c.Count})
+		}
+		data.More = len(totals) - len(data.Routes)
+		b.notifyAdmins(b.render(msgAdminUsageSummary, data))
+	}
+	if err := b.usage.SetSummaryAt(now); err != nil {
+		b.log.Error("failed to save usage rollups", zap.Error(err))
+	}
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"GURLS-Bot/internal/grpc/backendtest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startUsageBot runs a bot counting usage, with user as its admin, once the
+// first summary run has noted its start.
+func startUsageBot(t *testing.T) *e2e {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	cfg.Usage.Enabled = true
+	e := startBot(t, cfg)
+	waitFor(t, "the first usage summary run", func() bool { return !e.bot.usage.SummaryAt().IsZero() })
+	return e
+}
+
+func TestE2EUsageCounted(t *testing.T) {
+	e := startUsageBot(t)
+	e.backend.Add(backendtest.Link{Alias: "abc", OriginalURL: "https://example.com/private", OwnerID: user})
+
+	e.tg.SendMessage(user, "/stats abc")
+	stats := e.tg.WaitText(user, "Link Statistics: abc")
+	e.tg.SendMessage(user, "/stats abc")
+	e.tg.WaitText(user, "Link Statistics: abc")
+	e.press(t, stats, "My Links")
+	e.tg.WaitText(user, "Your Links")
+
+	now := time.Now()
+	today := e.bot.usage.StartOfDay(now)
+	totals := e.bot.usage.Totals(today, today.AddDate(0, 0, 1))
+	var routes []string
+	for _, c := range totals {
+		routes = append(routes, c.Route)
+		// Only the route is counted, never who used it or with what
+		if strings.Contains(c.Route, "abc") || strings.Contains(c.Route, strconv.Itoa(user)) || strings.Contains(c.Route, "example.com") {
+			t.Errorf("route %q counted with its arguments", c.Route)
+		}
+	}
+	if i := slices.Index(routes, "/stats"); i < 0 || totals[i].Count != 2 {
+		t.Errorf("usage = %v, want /stats counted twice", totals)
+	}
+	if !slices.ContainsFunc(routes, func(r string) bool { return strings.HasPrefix(r, "callback:") }) {
+		t.Errorf("usage = %v, want the button counted", totals)
+	}
+
+	e.tg.SendMessage(user, "/admin_stats")
+	got := e.tg.WaitText(user, "Most used over the last 7 days (today):").Text()
+	if !strings.Contains(got, "/stats: 2 (2)") {
+		t.Errorf("/admin_stats = %q, want /stats listed", got)
+	}
+}
+
+func TestE2EUsageOff(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Telegram.AdminChatIDs = []int64{user}
+	e := startBot(t, cfg)
+
+	e.tg.SendMessage(user, "/admin_stats")
+	if got := e.tg.WaitText(user, "Handler latency").Text(); strings.Contains(got, "Most used") || strings.Contains(got, "Nothing used") {
+		t.Errorf("/admin_stats = %q, want no usage with analytics off", got)
+	}
+	if e.bot.usage != nil {
+		t.Error("usage counted with analytics off")
+	}
+}
+
+func TestUsageSummary(t *testing.T) {
+	e := startUsageBot(t)
+	start := e.bot.usage.SummaryAt()
+	day := e.bot.usage.StartOfDay(start)
+	week := e.bot.config.Usage.SummaryInterval
+
+	// Counts of the day the last summary was sent in are summed up, those of
+	// the day the next one is sent in aren't yet
+	e.bot.usage.Add("/help", day.Add(time.Minute))
+	e.bot.usage.Add("/help", day.AddDate(0, 0, 3))
+	e.bot.usage.Add("/stats", day.AddDate(0, 0, 6))
+	e.bot.usage.Add("/stats", start.Add(week))
+
+	e.bot.sendUsageSummary(start.Add(week - time.Second))
+	if n := e.summaries(); n != 0 {
+		t.Fatalf("%d summaries sent before they were due", n)
+	}
+	e.bot.sendUsageSummary(start.Add(week))
+	got := e.tg.WaitText(user, "Command and button usage from").Text()
+	want := "Command and button usage from " + day.Format(time.DateOnly) + " to " + day.AddDate(0, 0, 6).Format(time.DateOnly) + ", 3 in total:\n/help: 2\n/stats: 1"
+	if got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	if at := e.bot.usage.SummaryAt(); !at.Equal(start.Add(week)) {
+		t.Errorf("SummaryAt() = %v, want the summary noted", at)
+	}
+
+	// The next one waits for another interval
+	e.bot.sendUsageSummary(start.Add(week + time.Hour))
+	if n := e.summaries(); n != 1 {
+		t.Errorf("%d summaries sent, want 1", n)
+	}
+}
+
+func TestUsageSummaryTopRoutes(t *testing.T) {
+	e := startUsageBot(t)
+	start := e.bot.usage.SummaryAt()
+	day := e.bot.usage.StartOfDay(start)
+	for i := range usageTopRoutes + 2 {
+		for range i + 1 {
+			e.bot.usage.Add("/r"+strconv.Itoa(i), day)
+		}
+	}
+
+	e.bot.sendUsageSummary(start.Add(e.bot.config.Usage.SummaryInterval))
+	got := e.tg.WaitText(user, "Command and button usage from").Text()
+	if lines := strings.Count(got, "\n"); lines != usageTopRoutes+1 {
+		t.Errorf("summary of %d lines, want %d routes and the rest counted:\n%s", lines+1, usageTopRoutes, got)
+	}
+	// The least used are left out
+	if !strings.HasSuffix(got, "\nand 2 more.") || strings.Contains(got, "/r0:") || !strings.Contains(got, "/r16: 17") {
+		t.Errorf("summary = %q, want the most used listed", got)
+	}
+}
+
+// summaries counts the usage summaries sent to user.
+func (e *e2e) summaries() int {
+	n := 0
+	for _, r := range e.tg.Requests("sendMessage") {
+		if r.ChatID() == user && strings.HasPrefix(r.Text(), "Command and button usage from") {
+			n++
+		}
+	}
+	return n
+}
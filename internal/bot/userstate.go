@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errInvalidPayload is returned for a dialog state given a payload of
+// another state.
+var errInvalidPayload = errors.New("payload doesn't match the dialog state")
+
+// DialogState is the step of a dialog a user is at.
+type DialogState int
+
+const (
+	StateNormal DialogState = iota
+	StateWaitingForAlias
+	StateWaitingForURL
+	StateWaitingForUTMURL
+	StateWaitingForUTMSource
+	StateWaitingForUTMMedium
+	StateWaitingForUTMCampaign
+	StateConfirmUTM
+	StateWaitingForNewAlias
+	StateWaitingForShortenOptions
+	StateWaitingForNewDestination
+	StateWaitingForTransferRecipient
+	StateWaitingForTitle
+	StateWaitingForSettingsFile
+	StateWaitingForNotBefore
+	StateWaitingForPreviewTitle
+	StateWaitingForPreviewDescription
+	StateWaitingForPreviewImage
+	StateWaitingForCompareAlias
+	StateWaitingForConfirmPhrase
+)
+
+var dialogStateNames = map[DialogState]string{
+	StateNormal:                       "normal",
+	StateWaitingForAlias:              "waiting_for_alias",
+	StateWaitingForURL:                "waiting_for_url",
+	StateWaitingForUTMURL:             "waiting_for_utm_url",
+	StateWaitingForUTMSource:          "waiting_for_utm_source",
+	StateWaitingForUTMMedium:          "waiting_for_utm_medium",
+	StateWaitingForUTMCampaign:        "waiting_for_utm_campaign",
+	StateConfirmUTM:                   "confirm_utm",
+	StateWaitingForNewAlias:           "waiting_for_new_alias",
+	StateWaitingForShortenOptions:     "waiting_for_shorten_options",
+	StateWaitingForNewDestination:     "waiting_for_new_destination",
+	StateWaitingForTransferRecipient:  "waiting_for_transfer_recipient",
+	StateWaitingForTitle:              "waiting_for_title",
+	StateWaitingForSettingsFile:       "waiting_for_settings_file",
+	StateWaitingForNotBefore:          "waiting_for_not_before",
+	StateWaitingForPreviewTitle:       "waiting_for_preview_title",
+	StateWaitingForPreviewDescription: "waiting_for_preview_description",
+	StateWaitingForPreviewImage:       "waiting_for_preview_image",
+	StateWaitingForCompareAlias:       "waiting_for_compare_alias",
+	StateWaitingForConfirmPhrase:      "waiting_for_confirm_phrase",
+}
+
+func (s DialogState) String() string {
+	if name, ok := dialogStateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// UserState is the dialog step a user is at, with what the dialog collected
+// so far.
+type UserState struct {
+	State DialogState
+	// Payload is of the type userStateSpecs lists for State; nil for states
+	// without one.
+	Payload any
+}
+
+// Payloads of the dialog states. The UTM wizard carries its *utmDraft.
+type (
+	// linkPayload is the link waiting for its URL.
+	linkPayload struct {
+		CustomAlias string
+		// Domain is the host of the short domain picked for the link.
+		Domain string
+		// Prefix completes a path sent instead of a URL, see withPrefix.
+		Prefix string
+		// NotBefore is when the link goes live, if scheduled.
+		NotBefore time.Time
+	}
+	// renamePayload is the link being renamed.
+	renamePayload struct{ Alias string }
+	// shortenOptionsPayload is the URL waiting for its /shorten options.
+	shortenOptionsPayload struct{ URL string }
+	// destinationPayload is the link getting a new destination.
+	destinationPayload struct{ Alias string }
+	// transferPayload is the link being handed over.
+	transferPayload struct{ Alias string }
+	// titlePayload is the link getting a title.
+	titlePayload struct{ Alias string }
+	// previewPayload is the link getting a custom preview, with the parts
+	// of it collected so far.
+	previewPayload struct {
+		Alias       string
+		Title       string
+		Description string
+	}
+	// comparePayload is the link waiting for the one to compare it with.
+	comparePayload struct{ Alias string }
+	// confirmPayload is the admin command waiting for its phrase, by its
+	// ID in Bot.confirmations.
+	confirmPayload struct{ ID string }
+)
+
+// stateSpec describes a dialog state.
+type stateSpec struct {
+	// payload is the type of the payload of the state; nil for none.
+	payload reflect.Type
+	// next lists the states the state can move on to. Going back to
+	// StateNormal is always allowed.
+	next []DialogState
+}
+
+// userStateSpecs is the transition table of the dialogs. Every dialog starts
+// from StateNormal.
+var userStateSpecs = map[DialogState]stateSpec{
+	StateNormal: {next: []DialogState{
+		StateWaitingForAlias,
+		StateWaitingForURL,
+		StateWaitingForUTMURL,
+		StateWaitingForNewAlias,
+		StateWaitingForShortenOptions,
+		StateWaitingForNewDestination,
+		StateWaitingForTransferRecipient,
+		StateWaitingForTitle,
+		StateWaitingForSettingsFile,
+		StateWaitingForPreviewTitle,
+		StateWaitingForCompareAlias,
+		StateWaitingForConfirmPhrase,
+	}},
+	StateWaitingForAlias: {next: []DialogState{StateWaitingForURL}},
+	// Picking a domain keeps the custom alias sent before
+	StateWaitingForURL:                {payload: reflect.TypeFor[linkPayload](), next: []DialogState{StateWaitingForURL, StateWaitingForNotBefore}},
+	StateWaitingForNotBefore:          {payload: reflect.TypeFor[linkPayload](), next: []DialogState{StateWaitingForURL}},
+	StateWaitingForUTMURL:             {next: []DialogState{StateWaitingForUTMSource}},
+	StateWaitingForUTMSource:          {payload: reflect.TypeFor[*utmDraft](), next: []DialogState{StateWaitingForUTMMedium}},
+	StateWaitingForUTMMedium:          {payload: reflect.TypeFor[*utmDraft](), next: []DialogState{StateWaitingForUTMCampaign}},
+	StateWaitingForUTMCampaign:        {payload: reflect.TypeFor[*utmDraft](), next: []DialogState{StateConfirmUTM}},
+	StateConfirmUTM:                   {payload: reflect.TypeFor[*utmDraft]()},
+	StateWaitingForNewAlias:           {payload: reflect.TypeFor[renamePayload]()},
+	StateWaitingForShortenOptions:     {payload: reflect.TypeFor[shortenOptionsPayload]()},
+	StateWaitingForNewDestination:     {payload: reflect.TypeFor[destinationPayload]()},
+	StateWaitingForTransferRecipient:  {payload: reflect.TypeFor[transferPayload]()},
+	StateWaitingForTitle:              {payload: reflect.TypeFor[titlePayload]()},
+	StateWaitingForSettingsFile:       {},
+	StateWaitingForPreviewTitle:       {payload: reflect.TypeFor[previewPayload](), next: []DialogState{StateWaitingForPreviewDescription}},
+	StateWaitingForPreviewDescription: {payload: reflect.TypeFor[previewPayload](), next: []DialogState{StateWaitingForPreviewImage}},
+	StateWaitingForPreviewImage:       {payload: reflect.TypeFor[previewPayload]()},
+	StateWaitingForCompareAlias:       {payload: reflect.TypeFor[comparePayload]()},
+	StateWaitingForConfirmPhrase:      {payload: reflect.TypeFor[confirmPayload]()},
+}
+
+// valid reports whether s is a known state carrying the payload it should.
+func (s UserState) valid() bool {
+	spec, ok := userStateSpecs[s.State]
+	return ok && reflect.TypeOf(s.Payload) == spec.payload
+}
+
+// canMoveTo reports whether a dialog at s may move on to next.
+func (s UserState) canMoveTo(next DialogState) bool {
+	return next == StateNormal || slices.Contains(userStateSpecs[s.State].next, next)
+}
+
+// getUserState returns the dialog step userID is at.
+func (b *Bot) getUserState(userID int64) UserState {
+	if state, exists := b.userStates.Get(userID); exists {
+		return state
+	}
+	return UserState{State: StateNormal}
+}
+
+// advanceUserState moves userID on to next within the dialog they're at and
+// reports whether that's a legal transition. Illegal ones, such as a button
+// of an abandoned dialog, leave the state alone.
+func (b *Bot) advanceUserState(userID int64, next UserState) bool {
+	current := b.getUserState(userID)
+	if !current.canMoveTo(next.State) || !next.valid() {
+		b.log.Debug("illegal dialog transition",
+			zap.Int64("user_id", userID),
+			zap.Stringer("from", current.State),
+			zap.Stringer("to", next.State))
+		return false
+	}
+	b.setUserState(userID, next)
+	return true
+}
+
+// startDialog moves userID to next, the first step of a dialog. A dialog
+// left unfinished is reset and the user told so, instead of its input being
+// silently dropped.
+func (b *Bot) startDialog(userID int64, next UserState) error {
+	if b.advanceUserState(userID, next) {
+		return nil
+	}
+	if !next.valid() {
+		return errInvalidPayload
+	}
+	b.setUserState(userID, next)
+	return b.reply(userID, msgDialogReset, nil)
+}
+
+// setUserState stores next; StateNormal is stored as no state at all.
+func (b *Bot) setUserState(userID int64, next UserState) {
+	if next.State == StateNormal {
+		b.resetUserState(userID)
+		return
+	}
+	b.userStates.Set(userID, next)
+}
+
+// resetUserState ends the dialog userID is at, which is always allowed.
+func (b *Bot) resetUserState(userID int64) {
+	b.userStates.Delete(userID)
+}
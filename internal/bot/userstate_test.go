@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// samplePayloads holds a payload of each payload type of the dialogs.
+var samplePayloads = map[reflect.Type]any{
+	reflect.TypeFor[linkPayload]():           linkPayload{CustomAlias: "a"},
+	reflect.TypeFor[*utmDraft]():             &utmDraft{URL: "https://example.com/"},
+	reflect.TypeFor[renamePayload]():         renamePayload{Alias: "a"},
+	reflect.TypeFor[shortenOptionsPayload](): shortenOptionsPayload{URL: "https://example.com/"},
+	reflect.TypeFor[destinationPayload]():    destinationPayload{Alias: "a"},
+	reflect.TypeFor[transferPayload]():       transferPayload{Alias: "a"},
+	reflect.TypeFor[titlePayload]():          titlePayload{Alias: "a"},
+	reflect.TypeFor[previewPayload]():        previewPayload{Alias: "a"},
+	reflect.TypeFor[comparePayload]():        comparePayload{Alias: "a"},
+	reflect.TypeFor[confirmPayload]():        confirmPayload{ID: "1"},
+}
+
+// samplePayload returns the payload state carries, nil for none.
+func samplePayload(state DialogState) any {
+	if typ := userStateSpecs[state].payload; typ != nil {
+		return samplePayloads[typ]
+	}
+	return nil
+}
+
+func TestUserStateSpecs(t *testing.T) {
+	for state, name := range dialogStateNames {
+		spec, ok := userStateSpecs[state]
+		if !ok {
+			t.Errorf("state %s has no spec", name)
+			continue
+		}
+		if spec.payload != nil && samplePayloads[spec.payload] == nil {
+			t.Errorf("state %s: no sample of payload %v", name, spec.payload)
+		}
+		if !(UserState{State: state, Payload: samplePayload(state)}).valid() {
+			t.Errorf("state %s: sample payload invalid", name)
+		}
+		for _, next := range spec.next {
+			if _, ok := userStateSpecs[next]; !ok {
+				t.Errorf("state %s moves on to unknown state %d", name, next)
+			}
+		}
+	}
+
+	// Every state can be reached from the normal one
+	reached := map[DialogState]bool{StateNormal: true}
+	frontier := []DialogState{StateNormal}
+	for len(frontier) > 0 {
+		state := frontier[0]
+		frontier = frontier[1:]
+		for _, next := range userStateSpecs[state].next {
+			if !reached[next] {
+				reached[next] = true
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	for state, name := range dialogStateNames {
+		if !reached[state] {
+			t.Errorf("state %s can't be reached", name)
+		}
+	}
+}
+
+func TestUserStateValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		state UserState
+		want  bool
+	}{
+		{"normal", UserState{State: StateNormal}, true},
+		{"normal with payload", UserState{State: StateNormal, Payload: renamePayload{}}, false},
+		{"matching payload", UserState{State: StateWaitingForNewAlias, Payload: renamePayload{Alias: "a"}}, true},
+		{"other dialog's payload", UserState{State: StateWaitingForNewAlias, Payload: titlePayload{Alias: "a"}}, false},
+		{"missing payload", UserState{State: StateWaitingForNewAlias}, false},
+		{"pointer instead of value", UserState{State: StateWaitingForNewAlias, Payload: &renamePayload{}}, false},
+		{"value instead of pointer", UserState{State: StateWaitingForUTMSource, Payload: utmDraft{}}, false},
+		{"unknown state", UserState{State: DialogState(1000)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.valid(); got != tt.want {
+				t.Errorf("valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUserStateRandomWalk drives a user through random dialog events, legal
+// or not, with right or wrong payloads, and checks after each one that the
+// state carries the payload it should, that illegal moves change nothing,
+// that the user is told of every dialog reset and that they can always
+// start over.
+func TestUserStateRandomWalk(t *testing.T) {
+	cfg := testConfig(t)
+	// Reset notices are many; don't space them out
+	cfg.SendQueue.Rate = 100000
+	e := startBot(t, cfg)
+	b := e.bot
+	states := make([]DialogState, 0, len(dialogStateNames))
+	for state := range dialogStateNames {
+		states = append(states, state)
+	}
+	slices.Sort(states)
+	// Payloads are listed in a fixed order so that the seed replays a walk
+	payloads := make([]any, len(states))
+	for i, state := range states {
+		payloads[i] = samplePayload(state)
+	}
+
+	seed := rand.Uint64()
+	t.Logf("seed %d", seed)
+	rnd := rand.New(rand.NewPCG(seed, 0))
+	resets := 0
+	for step := range 2000 {
+		next := UserState{State: states[rnd.IntN(len(states))]}
+		// Mostly the right payload, sometimes any
+		if rnd.IntN(4) > 0 {
+			next.Payload = samplePayload(next.State)
+		} else {
+			next.Payload = payloads[rnd.IntN(len(payloads))]
+		}
+		before := b.getUserState(user)
+
+		switch event := rnd.IntN(5); {
+		case event < 3:
+			ok := b.advanceUserState(user, next)
+			if want := before.canMoveTo(next.State) && next.valid(); ok != want {
+				t.Fatalf("step %d: advance %s -> %s with %T = %v, want %v", step, before.State, next.State, next.Payload, ok, want)
+			}
+			if !ok && !reflect.DeepEqual(b.getUserState(user), before) {
+				t.Fatalf("step %d: illegal move %s -> %s changed the state", step, before.State, next.State)
+			}
+		case event < 4:
+			err := b.startDialog(user, next)
+			if !next.valid() {
+				if !errors.Is(err, errInvalidPayload) {
+					t.Fatalf("step %d: start %s with %T: err = %v", step, next.State, next.Payload, err)
+				}
+				if !reflect.DeepEqual(b.getUserState(user), before) {
+					t.Fatalf("step %d: invalid start changed the state", step)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("step %d: start %s: %v", step, next.State, err)
+			}
+			if !before.canMoveTo(next.State) {
+				resets++
+			}
+		default:
+			b.resetUserState(user)
+			if got := b.getUserState(user); got.State != StateNormal {
+				t.Fatalf("step %d: reset left %s", step, got.State)
+			}
+		}
+
+		if got := b.getUserState(user); !got.valid() {
+			t.Fatalf("step %d: state %s carries %T", step, got.State, got.Payload)
+		}
+		// Never stuck: going back to normal is always allowed
+		if !b.getUserState(user).canMoveTo(StateNormal) {
+			t.Fatalf("step %d: can't leave %s", step, b.getUserState(user).State)
+		}
+	}
+
+	notices := 0
+	for _, r := range e.tg.Requests("sendMessage") {
+		if strings.Contains(r.Text(), "I've reset your previous action") {
+			notices++
+		}
+	}
+	if notices != resets {
+		t.Errorf("%d reset notices sent for %d resets", notices, resets)
+	}
+}
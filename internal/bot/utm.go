@@ -0,0 +1,217 @@
+package bot
+
+import (
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// UTM parameter keys in wizard order.
+const (
+	utmSource   = "utm_source"
+	utmMedium   = "utm_medium"
+	utmCampaign = "utm_campaign"
+)
+
+// utmQuickPicks are the suggested values offered for each UTM parameter.
+var utmQuickPicks = map[string][]string{
+	utmSource: {"telegram", "google", "facebook", "newsletter"},
+	utmMedium: {"social", "cpc", "email", "referral"},
+}
+
+// utmDraft collects UTM parameters while the user walks through the wizard.
+type utmDraft struct {
+	URL    string
+	Params map[string]string
+}
+
+// utmDefaults remembers each user's last used source and medium.
+type utmDefaults struct {
+	mu     sync.Mutex
+	values map[int64]map[string]string
+}
+
+func newUTMDefaults() *utmDefaults {
+	return &utmDefaults{values: make(map[int64]map[string]string)}
+}
+
+func (d *utmDefaults) Get(userID int64, key string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.values[userID][key]
+}
+
+// Forget drops the remembered values of userID.
+func (d *utmDefaults) Forget(userID int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.values, userID)
+}
+
+func (d *utmDefaults) Remember(userID int64, params map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range []string{utmSource, utmMedium} {
+		if params[key] == "" {
+			continue
+		}
+		if d.values[userID] == nil {
+			d.values[userID] = make(map[string]string)
+		}
+		d.values[userID][key] = params[key]
+	}
+}
+
+// applyUTM sets the UTM parameters on rawURL, keeping other query parameters
+// in their original order and replacing existing utm_* keys that are set.
+func applyUTM(rawURL string, params map[string]string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	if u.RawQuery != "" {
+		for _, part := range strings.Split(u.RawQuery, "&") {
+			key, _, _ := strings.Cut(part, "=")
+			if name, err := url.QueryUnescape(key); err == nil && params[name] != "" {
+				continue
+			}
+			parts = append(parts, part)
+		}
+	}
+	for _, key := range []string{utmSource, utmMedium, utmCampaign} {
+		if value := params[key]; value != "" {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	u.RawQuery = strings.Join(parts, "&")
+	return u.String(), nil
+}
+
+// startUTMWizard asks for the URL to tag.
+func (b *Bot) startUTMWizard(chatID int64) error {
+	if err := b.startDialog(chatID, UserState{State: StateWaitingForUTMURL}); err != nil {
+		return err
+	}
+	return b.replyWithKeyboard(chatID, msgUTMSendURL, nil, b.createCancelKeyboard())
+}
+
+// handleUTMURLInput starts tagging the URL sent.
+func (b *Bot) handleUTMURLInput(to payloadKey, text string) error {
+	u := urlRegex.FindString(strings.TrimSpace(text))
+	if u == "" {
+		return b.reply(to.chatID, msgInvalidShortenFormat, nil)
+	}
+	return b.promptUTM(to, &utmDraft{URL: u, Params: make(map[string]string)}, StateWaitingForUTMSource)
+}
+
+// utmSteps maps the steps of the wizard to the parameter they set.
+var utmSteps = map[DialogState]string{
+	StateWaitingForUTMSource:   utmSource,
+	StateWaitingForUTMMedium:   utmMedium,
+	StateWaitingForUTMCampaign: utmCampaign,
+}
+
+// setUTMValue stores value for the current step, typed or picked, and moves
+// to the next one. An empty value skips the step.
+func (b *Bot) setUTMValue(to payloadKey, value string) error {
+	state := b.getUserState(to.chatID)
+	key, ok := utmSteps[state.State]
+	if !ok {
+		// A button of a wizard that is over
+		return b.reply(to.chatID, msgButtonExpired, nil)
+	}
+	if value != "" && !utmValueRegex.MatchString(value) {
+		return b.reply(to.chatID, msgUTMInvalidValue, nil)
+	}
+
+	draft := state.Payload.(*utmDraft)
+	draft.Params[key] = value
+	switch state.State {
+	case StateWaitingForUTMSource:
+		return b.promptUTM(to, draft, StateWaitingForUTMMedium)
+	case StateWaitingForUTMMedium:
+		return b.promptUTM(to, draft, StateWaitingForUTMCampaign)
+	}
+	return b.confirmUTM(to.chatID, draft)
+}
+
+func (b *Bot) promptUTM(to payloadKey, draft *utmDraft, next DialogState) error {
+	if !b.advanceUserState(to.chatID, UserState{State: next, Payload: draft}) {
+		return b.reply(to.chatID, msgButtonExpired, nil)
+	}
+
+	key, prompt := utmSteps[next], msgUTMSource
+	switch next {
+	case StateWaitingForUTMMedium:
+		prompt = msgUTMMedium
+	case StateWaitingForUTMCampaign:
+		prompt = msgUTMCampaign
+	}
+
+	// The last used value comes first so it can be reused with one tap
+	picks := slices.Clone(utmQuickPicks[key])
+	if last := b.utmDefaults.Get(to.chatID, key); last != "" {
+		picks = slices.DeleteFunc(picks, func(v string) bool { return v == last })
+		picks = append([]string{last}, picks...)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, pick := range picks {
+		row = append(row, b.payloadButton(to, pick, actionUTMValue, pick))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		b.callbackButton("Skip", callbackUTMSkip),
+		b.callbackButton("Cancel", callbackCancel),
+	))
+	return b.replyWithKeyboard(to.chatID, prompt, nil, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows})
+}
+
+// confirmUTM echoes the tagged URL and asks for confirmation.
+func (b *Bot) confirmUTM(chatID int64, draft *utmDraft) error {
+	tagged, err := applyUTM(draft.URL, draft.Params)
+	if err != nil {
+		b.resetUserState(chatID)
+		return b.reply(chatID, msgInvalidShortenFormat, nil)
+	}
+	draft.URL = tagged
+	if !b.advanceUserState(chatID, UserState{State: StateConfirmUTM, Payload: draft}) {
+		return b.reply(chatID, msgButtonExpired, nil)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			b.callbackButton("Create", callbackUTMCreate),
+			b.callbackButton("Cancel", callbackCancel),
+		),
+	)
+	return b.sendMessageWithKeyboard(chatID, b.render(msgUTMConfirm, urlData{URL: tagged}), keyboard)
+}
+
+// createUTMLink shortens the confirmed tagged URL.
+func (b *Bot) createUTMLink(ctx context.Context, to payloadKey) error {
+	state := b.getUserState(to.chatID)
+	if state.State != StateConfirmUTM {
+		return b.reply(to.chatID, msgButtonExpired, nil)
+	}
+	b.resetUserState(to.chatID)
+	draft := state.Payload.(*utmDraft)
+	b.utmDefaults.Remember(to.chatID, draft.Params)
+
+	_, err := b.createLink(ctx, to, &shortenerv1.CreateLinkRequest{OriginalUrl: draft.URL, UserTgId: to.chatID, Source: linkSource(sourceBotMessage)}, createOptions{})
+	return err
+}
@@ -0,0 +1,146 @@
+package broadcast
+
+import (
+	"GURLS-Bot/internal/store"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Job is a message sent to a snapshot of the users.
+type Job struct {
+	ID          string `json:"id"`
+	AdminChatID int64  `json:"admin_chat_id"`
+	// ProgressMessageID is the message in the admin chat showing progress.
+	ProgressMessageID int     `json:"progress_message_id"`
+	Text              string  `json:"text"`
+	Targets           []int64 `json:"targets"`
+	// Cursor is the index of the next target to send to.
+	Cursor    int       `json:"cursor"`
+	Sent      int       `json:"sent"`
+	Blocked   int       `json:"blocked"`
+	Failed    int       `json:"failed"`
+	Cancelled bool      `json:"cancelled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Done reports whether every target was handled.
+func (j *Job) Done() bool {
+	return j.Cursor >= len(j.Targets)
+}
+
+// namespace holds the unfinished jobs in the store, by ID.
+const namespace = "broadcasts"
+
+// Store is the list of unfinished jobs, oldest first, kept in the store one
+// record per job.
+type Store struct {
+	mu   sync.Mutex
+	ns   store.Namespace[Job]
+	jobs []Job
+}
+
+// Open loads the jobs kept in db.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Job]{})}
+	all, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read broadcasts: %w", err)
+	}
+	for _, job := range all {
+		s.jobs = append(s.jobs, job)
+	}
+	slices.SortFunc(s.jobs, func(a, b Job) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return s, nil
+}
+
+// Legacy is the broadcasts file of earlier versions, a list of jobs.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "broadcasts", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var jobs []Job
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return 0, err
+		}
+		records := make(map[string]Job, len(jobs))
+		for _, job := range jobs {
+			records[job.ID] = job
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[Job]{}).PutAll(records)
+	}}
+}
+
+// Add stores a new job.
+func (s *Store) Add(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ns.Put(job.ID, job); err != nil {
+		return err
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Next returns the oldest job.
+func (s *Store) Next() (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.jobs) == 0 {
+		return Job{}, false
+	}
+	return s.jobs[0], true
+}
+
+// Checkpoint saves the progress of job and reports whether it was cancelled
+// meanwhile. The progress is kept in memory when saving fails.
+func (s *Store) Checkpoint(job Job) (cancelled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexLocked(job.ID)
+	if i < 0 {
+		return true, nil
+	}
+	job.Cancelled = s.jobs[i].Cancelled
+	s.jobs[i] = job
+	return job.Cancelled, s.ns.Put(job.ID, job)
+}
+
+// Cancel marks a job cancelled; its worker stops at the next checkpoint. It
+// reports whether the job exists.
+func (s *Store) Cancel(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexLocked(id)
+	if i < 0 {
+		return false, nil
+	}
+	s.jobs[i].Cancelled = true
+	return true, s.ns.Put(id, s.jobs[i])
+}
+
+// Remove deletes a finished job.
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexLocked(id)
+	if i < 0 {
+		return nil
+	}
+	if err := s.ns.Delete(id); err != nil {
+		return err
+	}
+	s.jobs = slices.Delete(s.jobs, i, i+1)
+	return nil
+}
+
+func (s *Store) indexLocked(id string) int {
+	return slices.IndexFunc(s.jobs, func(j Job) bool { return j.ID == id })
+}
@@ -0,0 +1,31 @@
+package broadcast
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"reflect"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]Job](t, "testdata/broadcasts.json")
+
+	stores, records := storetest.Migrate(t, "testdata/broadcasts.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Jobs are kept oldest first, by ID within the same second
+			if order := []Job{want[2], want[1], want[0]}; !reflect.DeepEqual(s.jobs, order) {
+				t.Errorf("jobs =\n%+v\nwant\n%+v", s.jobs, order)
+			}
+			if job, ok := s.Next(); !ok || job.ID != "b0" {
+				t.Errorf("Next() = %+v, %v; want b0", job, ok)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -11,30 +12,472 @@ import (
 
 // Config holds all the configuration for the application.
 type Config struct {
-	Env        string `yaml:"env" env:"ENV" env-default:"production"`
-	Telegram   `yaml:"telegram"`
-	GRPCClient `yaml:"grpc_client"`
-	HTTPServer `yaml:"http_server"`
+	Env string `yaml:"env" env:"ENV" env-default:"production"`
+	// ShutdownTimeout bounds how long components may take to stop.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	Telegram        `yaml:"telegram"`
+	GRPCClient      `yaml:"grpc_client"`
+	HTTPServer      `yaml:"http_server"`
+	Storage         `yaml:"storage"`
+	Queue           `yaml:"queue"`
+	Shorteners      `yaml:"shorteners"`
+	SafeBrowsing    `yaml:"safe_browsing"`
+	Blocklist       `yaml:"blocklist"`
+	Quota           `yaml:"quota"`
+	Prefs           `yaml:"prefs"`
+	Users           `yaml:"users"`
+	Messages        `yaml:"messages"`
+	AutoDelete      `yaml:"auto_delete"`
+	Cleanup         `yaml:"cleanup"`
+	Expiring        `yaml:"expiring"`
+	SelfTest        `yaml:"self_test"`
+	Broadcast       `yaml:"broadcast"`
+	Welcome         `yaml:"welcome"`
+	SendQueue       `yaml:"send_queue"`
+	Caches          `yaml:"caches"`
+	Links           `yaml:"links"`
+	Monitor         `yaml:"monitor"`
+	Transfer        `yaml:"transfer"`
+	Keyboards       `yaml:"keyboards"`
+	Reconcile       `yaml:"reconcile"`
+	Audit           `yaml:"audit"`
+	Outbox          `yaml:"outbox"`
+	Campaigns       `yaml:"campaigns"`
+	Reports         `yaml:"reports"`
+	Usage           `yaml:"usage"`
+	Maintenance     `yaml:"maintenance"`
+	Confirm         `yaml:"confirm"`
+	// Features turns features on or off for this deployment, by flag name;
+	// flags left out keep their defaults. The env form is
+	// "inline:false,monitor:true".
+	Features map[string]bool `yaml:"features" env:"FEATURES" env-separator:","`
 }
 
 // Telegram holds Telegram specific configuration.
 type Telegram struct {
 	Token string `yaml:"token" env:"TELEGRAM_TOKEN" env-required:"true"`
+	// APIEndpoint is the Bot API URL format, taking the token and the method
+	// name. It points at a local Bot API server or a fake one in tests.
+	APIEndpoint  string        `yaml:"api_endpoint" env:"TELEGRAM_API_ENDPOINT" env-default:"https://api.telegram.org/bot%s/%s"`
+	AdminChatIDs []int64       `yaml:"admin_chat_ids" env:"TELEGRAM_ADMIN_CHAT_IDS" env-separator:","`
+	EditMaxAge   time.Duration `yaml:"edit_max_age" env:"TELEGRAM_EDIT_MAX_AGE" env-default:"10m"`
+	DedupWindow  time.Duration `yaml:"dedup_window" env:"TELEGRAM_DEDUP_WINDOW" env-default:"30s"`
+	// BackendAlertAfter is how long backend calls must fail without a
+	// success before admins are alerted.
+	BackendAlertAfter time.Duration `yaml:"backend_alert_after" env:"TELEGRAM_BACKEND_ALERT_AFTER" env-default:"2m"`
+	// ProtectContent prevents forwarding and saving of messages carrying short links.
+	ProtectContent bool `yaml:"protect_content" env:"TELEGRAM_PROTECT_CONTENT" env-default:"false"`
+	// SlowHandlerThreshold is how long a command or callback handler may
+	// take before it is logged as slow.
+	SlowHandlerThreshold time.Duration `yaml:"slow_handler_threshold" env:"TELEGRAM_SLOW_HANDLER_THRESHOLD" env-default:"3s"`
+	// UpdatesStallAfter is how long getUpdates may go without a successful
+	// poll before admins are alerted and the poll is started over. Polls
+	// answer at least once a minute, even without updates.
+	UpdatesStallAfter time.Duration `yaml:"updates_stall_after" env:"TELEGRAM_UPDATES_STALL_AFTER" env-default:"3m"`
+	// OnRevoked is what the bot does once Telegram rejects its token:
+	// OnRevokedExit or OnRevokedWait.
+	OnRevoked string `yaml:"on_revoked" env:"TELEGRAM_ON_REVOKED" env-default:"exit"`
+	// RevokedGrace is how long the token may be rejected before the bot
+	// exits with OnRevokedExit.
+	RevokedGrace time.Duration `yaml:"revoked_grace" env:"TELEGRAM_REVOKED_GRACE" env-default:"1m"`
 }
 
+// What the bot does once Telegram rejects its token.
+const (
+	// OnRevokedExit exits so the orchestrator restarts the bot with the
+	// current secret.
+	OnRevokedExit = "exit"
+	// OnRevokedWait keeps running until a new token is put in the config
+	// file and reloaded with SIGHUP.
+	OnRevokedWait = "wait"
+)
+
 // GRPCClient holds gRPC client specific configuration.
 type GRPCClient struct {
+	// BackendAddress is a host:port, a comma-separated list of replicas or a
+	// gRPC target such as dns:///backend:50051.
 	BackendAddress string        `yaml:"backend_address" env:"GRPC_BACKEND_ADDRESS" env-default:"localhost:50051"`
 	Timeout        time.Duration `yaml:"timeout" env:"GRPC_CLIENT_TIMEOUT" env-default:"5s"`
+	// HealthTimeout bounds a single backend health probe.
+	HealthTimeout time.Duration `yaml:"health_timeout" env:"GRPC_HEALTH_TIMEOUT" env-default:"1s"`
+	// CapabilityRefresh is how often the backend is asked again which
+	// optional methods it implements.
+	CapabilityRefresh time.Duration `yaml:"capability_refresh" env:"GRPC_CAPABILITY_REFRESH" env-default:"10m"`
+	// SlowCallThreshold is how long a backend call may take before it is
+	// logged as slow and listed in /admin_stats; zero turns that off.
+	SlowCallThreshold time.Duration `yaml:"slow_call_threshold" env:"GRPC_SLOW_CALL_THRESHOLD" env-default:"1s"`
 }
 
 // HTTPServer holds HTTP server configuration (for base URL generation).
 type HTTPServer struct {
 	BaseURL string `yaml:"base_url" env:"BASE_URL" env-default:"http://localhost:8080"`
+	// DetectOwnLinks shows stats for pasted short links instead of re-shortening them.
+	DetectOwnLinks bool `yaml:"detect_own_links" env:"DETECT_OWN_LINKS" env-default:"true"`
+	// Domains lists the branded short domains users can pick from. When empty,
+	// BaseURL is the only domain.
+	Domains []ShortDomain `yaml:"domains"`
+}
+
+// ShortDomain is a short link domain served by the backend.
+type ShortDomain struct {
+	Label   string `yaml:"label"`
+	BaseURL string `yaml:"base_url"`
+}
+
+// Storage holds configuration of the store the bot keeps its state in.
+type Storage struct {
+	// Backend is "json", a directory of JSON files, or "bolt", a bbolt
+	// database file.
+	Backend string `yaml:"backend" env:"STORAGE_BACKEND" env-default:"json"`
+	// Path is the directory or the database file of the store.
+	Path string `yaml:"path" env:"STORAGE_PATH" env-default:"data/store"`
+}
+
+// Queue holds configuration of the link creation queue used while the backend is unavailable.
+type Queue struct {
+	// Path is where the create queue was kept before the store; a file
+	// found there is imported at startup.
+	Path             string        `yaml:"path" env:"QUEUE_PATH" env-default:"data/create_queue.json"`
+	MaxPerUser       int           `yaml:"max_per_user" env:"QUEUE_MAX_PER_USER" env-default:"5"`
+	MaxTotal         int           `yaml:"max_total" env:"QUEUE_MAX_TOTAL" env-default:"1000"`
+	RetryInterval    time.Duration `yaml:"retry_interval" env:"QUEUE_RETRY_INTERVAL" env-default:"5s"`
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" env:"QUEUE_MAX_RETRY_INTERVAL" env-default:"5m"`
+	MaxAge           time.Duration `yaml:"max_age" env:"QUEUE_MAX_AGE" env-default:"24h"`
+	// OfferTTL is how long the offer to queue a link can be accepted.
+	OfferTTL time.Duration `yaml:"offer_ttl" env:"QUEUE_OFFER_TTL" env-default:"1h"`
+}
+
+// Shorteners holds the known third-party URL shortener domains.
+type Shorteners struct {
+	Domains       []string      `yaml:"domains" env:"SHORTENER_DOMAINS" env-separator:"," env-default:"bit.ly,t.co,tinyurl.com,goo.gl,ow.ly,is.gd,buff.ly,rebrand.ly,cutt.ly,shorturl.at"`
+	MaxRedirects  int           `yaml:"max_redirects" env:"SHORTENER_MAX_REDIRECTS" env-default:"5"`
+	FollowTimeout time.Duration `yaml:"follow_timeout" env:"SHORTENER_FOLLOW_TIMEOUT" env-default:"5s"`
+}
+
+// SafeBrowsing holds configuration of the malware and phishing check run before shortening.
+type SafeBrowsing struct {
+	Enabled  bool          `yaml:"enabled" env:"SAFE_BROWSING_ENABLED" env-default:"false"`
+	APIKey   string        `yaml:"api_key" env:"SAFE_BROWSING_API_KEY"`
+	Timeout  time.Duration `yaml:"timeout" env:"SAFE_BROWSING_TIMEOUT" env-default:"2s"`
+	FailOpen bool          `yaml:"fail_open" env:"SAFE_BROWSING_FAIL_OPEN" env-default:"true"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"SAFE_BROWSING_CACHE_TTL" env-default:"10m"`
+	// BanAfter bans users from creating links after this many flagged URLs within a week; 0 disables bans.
+	// Bans are kept in the store, the flags leading up to one only in memory.
+	BanAfter int `yaml:"ban_after" env:"SAFE_BROWSING_BAN_AFTER" env-default:"3"`
+}
+
+// Blocklist holds domains that must not be shortened. Entries are exact hosts
+// or suffix patterns like *.example.com; File is reloaded on SIGHUP and
+// stores entries added by admins at runtime.
+type Blocklist struct {
+	Hosts []string `yaml:"hosts" env:"BLOCKLIST_HOSTS" env-separator:","`
+	File  string   `yaml:"file" env:"BLOCKLIST_FILE" env-default:"data/blocklist.txt"`
+}
+
+// Quota holds per-user link limits; zero disables a limit.
+type Quota struct {
+	MaxActiveLinks int     `yaml:"max_active_links" env:"QUOTA_MAX_ACTIVE_LINKS" env-default:"0"`
+	MaxPerDay      int     `yaml:"max_per_day" env:"QUOTA_MAX_PER_DAY" env-default:"0"`
+	ExemptUserIDs  []int64 `yaml:"exempt_user_ids" env:"QUOTA_EXEMPT_USER_IDS" env-separator:","`
+}
+
+// Prefs holds configuration of the persistent per-user preferences.
+type Prefs struct {
+	// Path is where the preferences were kept before the store; a file
+	// found there is imported at startup.
+	Path      string `yaml:"path" env:"PREFS_PATH" env-default:"data/prefs.json"`
+	MaxPinned int    `yaml:"max_pinned" env:"PREFS_MAX_PINNED" env-default:"5"`
+	// SnapshotMaxAge is how long click snapshots are kept.
+	SnapshotMaxAge time.Duration `yaml:"snapshot_max_age" env:"PREFS_SNAPSHOT_MAX_AGE" env-default:"2160h"`
+	// HistorySize is how many recent actions /history keeps per user; zero
+	// turns the history off.
+	HistorySize int `yaml:"history_size" env:"PREFS_HISTORY_SIZE" env-default:"50"`
+}
+
+// Users holds configuration of the registry of users who talked to the bot.
+type Users struct {
+	// Path is where the user registry was kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"USERS_PATH" env-default:"data/users.json"`
+	// FlushInterval is how often activity updates are written to the store.
+	FlushInterval time.Duration `yaml:"flush_interval" env:"USERS_FLUSH_INTERVAL" env-default:"30s"`
+}
+
+// Messages holds configuration of the user-facing message templates.
+type Messages struct {
+	// TemplateFile redefines some or all of the built-in message templates.
+	TemplateFile string `yaml:"template_file" env:"MESSAGES_TEMPLATE_FILE"`
+	// LinkStyle is the default way created links are shown: "compact" or
+	// "card" with title, destination and expiry. Users can change it.
+	LinkStyle string `yaml:"link_style" env:"MESSAGES_LINK_STYLE" env-default:"compact"`
+}
+
+// AutoDelete holds configuration of the deletion of transient bot messages
+// such as error nags and wizard prompts. Deletion is best-effort.
+type AutoDelete struct {
+	Enabled bool          `yaml:"enabled" env:"AUTO_DELETE_ENABLED" env-default:"false"`
+	After   time.Duration `yaml:"after" env:"AUTO_DELETE_AFTER" env-default:"60s"`
+	Errors  bool          `yaml:"errors" env:"AUTO_DELETE_ERRORS" env-default:"true"`
+	Prompts bool          `yaml:"prompts" env:"AUTO_DELETE_PROMPTS" env-default:"true"`
+	Notices bool          `yaml:"notices" env:"AUTO_DELETE_NOTICES" env-default:"true"`
+}
+
+// Cleanup holds configuration of the cleanup suggestions users can opt in
+// to in /settings.
+type Cleanup struct {
+	// Interval is how often an opted-in user gets suggestions.
+	Interval time.Duration `yaml:"interval" env:"CLEANUP_INTERVAL" env-default:"168h"`
+	// IdleAfter is how long a link must go without clicks after its
+	// creation before it is suggested.
+	IdleAfter time.Duration `yaml:"idle_after" env:"CLEANUP_IDLE_AFTER" env-default:"720h"`
+	// KeepFor is how long a link the user chose to keep is not suggested again.
+	KeepFor time.Duration `yaml:"keep_for" env:"CLEANUP_KEEP_FOR" env-default:"2160h"`
+	// MaxListed caps the links listed in one suggestion.
+	MaxListed int `yaml:"max_listed" env:"CLEANUP_MAX_LISTED" env-default:"10"`
+	// Workers bounds the concurrent stats lookups of a scan.
+	Workers int `yaml:"workers" env:"CLEANUP_WORKERS" env-default:"4"`
+}
+
+// Expiring holds configuration of the "Expiring soon" view of /my_links.
+type Expiring struct {
+	// Window is how far ahead a link's expiry must be to be listed.
+	Window time.Duration `yaml:"window" env:"EXPIRING_WINDOW" env-default:"168h"`
+	// Workers bounds the concurrent stats lookups of the view, and the
+	// concurrent updates of its bulk actions.
+	Workers int `yaml:"workers" env:"EXPIRING_WORKERS" env-default:"4"`
+}
+
+// SelfTest holds configuration of the smoke test creating, reading and
+// deleting a link through the backend.
+type SelfTest struct {
+	// OnStartup runs the test when the bot starts.
+	OnStartup bool `yaml:"on_startup" env:"SELF_TEST_ON_STARTUP" env-default:"false"`
+	// Strict stops the bot from starting when the startup test fails;
+	// otherwise a failure is only reported.
+	Strict bool `yaml:"strict" env:"SELF_TEST_STRICT" env-default:"false"`
+	// AliasPrefix starts the aliases of test links. Users can't create
+	// aliases with it and links with it are left out of their listings.
+	AliasPrefix string `yaml:"alias_prefix" env:"SELF_TEST_ALIAS_PREFIX" env-default:"selftest-"`
+}
+
+// Broadcast holds configuration of the messages admins send to all users.
+type Broadcast struct {
+	// Path is where the unfinished broadcasts were kept before the
+	// store; a file found there is imported at startup.
+	Path string `yaml:"path" env:"BROADCAST_PATH" env-default:"data/broadcasts.json"`
+	// Rate is how many messages per second a broadcast sends; Telegram
+	// allows about 30.
+	Rate int `yaml:"rate" env:"BROADCAST_RATE" env-default:"25"`
+	// CheckpointEvery is after how many messages the progress is saved and
+	// cancellation is checked for.
+	CheckpointEvery int `yaml:"checkpoint_every" env:"BROADCAST_CHECKPOINT_EVERY" env-default:"50"`
+	// ProgressInterval is how often the admin's progress message is updated.
+	ProgressInterval time.Duration `yaml:"progress_interval" env:"BROADCAST_PROGRESS_INTERVAL" env-default:"10s"`
+}
+
+// SendQueue holds configuration of the queue all messages to Telegram go
+// through.
+type SendQueue struct {
+	// Rate is how many calls per second are made across all chats; Telegram
+	// allows about 30.
+	Rate int `yaml:"rate" env:"SEND_QUEUE_RATE" env-default:"30"`
+	// Size bounds the calls waiting to be made. When it is reached,
+	// notifications are dropped and replies wait.
+	Size int `yaml:"size" env:"SEND_QUEUE_SIZE" env-default:"1000"`
+	// Retries is how many more times a reply is tried after a failure that
+	// may pass: a flood limit, a Telegram server error or a lost connection.
+	// Notifications aren't retried.
+	Retries int `yaml:"retries" env:"SEND_QUEUE_RETRIES" env-default:"3"`
+	// MaxRetryWait caps the wait before a retry; flood limits asking for
+	// longer aren't waited out.
+	MaxRetryWait time.Duration `yaml:"max_retry_wait" env:"SEND_QUEUE_MAX_RETRY_WAIT" env-default:"10s"`
+}
+
+// Caches holds configuration of the in-memory caches and dialog states.
+type Caches struct {
+	// SweepInterval is how often expired entries are removed.
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"CACHES_SWEEP_INTERVAL" env-default:"1m"`
+	// WarnSize is the number of entries past which a cache is logged as
+	// possibly leaking.
+	WarnSize int `yaml:"warn_size" env:"CACHES_WARN_SIZE" env-default:"50000"`
+}
+
+// Links holds configuration of fetching the link lists of users and of the
+// aliases generated for new links.
+type Links struct {
+	// PageSize is how many links a /my_links page shows.
+	PageSize int `yaml:"page_size" env:"LINKS_PAGE_SIZE" env-default:"10"`
+	// FetchPageSize is the page size used when all links of a user are
+	// needed, as for the /start summary or cleanup suggestions.
+	FetchPageSize int `yaml:"fetch_page_size" env:"LINKS_FETCH_PAGE_SIZE" env-default:"100"`
+	// MaxPages caps the pages fetched for all links of a user; links past
+	// the cap are left out.
+	MaxPages int `yaml:"max_pages" env:"LINKS_MAX_PAGES" env-default:"50"`
+	// AliasStyle is the style of generated aliases for users who haven't
+	// picked one: random, words or numeric.
+	AliasStyle string `yaml:"alias_style" env:"LINKS_ALIAS_STYLE" env-default:"random"`
+}
+
+// Monitor holds configuration of the checks of link destinations users
+// asked to monitor.
+type Monitor struct {
+	// Path is where the monitored links were kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"MONITOR_PATH" env-default:"data/monitor.json"`
+	// Interval is how often a healthy destination is checked.
+	Interval time.Duration `yaml:"interval" env:"MONITOR_INTERVAL" env-default:"1h"`
+	// Poll is how often links due for a check are looked for.
+	Poll time.Duration `yaml:"poll" env:"MONITOR_POLL" env-default:"1m"`
+	// Workers is how many hosts are checked in parallel.
+	Workers int `yaml:"workers" env:"MONITOR_WORKERS" env-default:"4"`
+	// HostDelay is the pause between two checks on the same host.
+	HostDelay time.Duration `yaml:"host_delay" env:"MONITOR_HOST_DELAY" env-default:"2s"`
+	// Timeout bounds a single check.
+	Timeout time.Duration `yaml:"timeout" env:"MONITOR_TIMEOUT" env-default:"10s"`
+	// Failures is after how many failed checks in a row the owner is told.
+	Failures int `yaml:"failures" env:"MONITOR_FAILURES" env-default:"3"`
+	// MaxBackoff caps the check interval of a failing destination, which
+	// doubles with every failed check past Failures.
+	MaxBackoff time.Duration `yaml:"max_backoff" env:"MONITOR_MAX_BACKOFF" env-default:"24h"`
+	// MaxPerUser caps the links a user can monitor.
+	MaxPerUser int `yaml:"max_per_user" env:"MONITOR_MAX_PER_USER" env-default:"10"`
+}
+
+// Transfer holds configuration of handing links over to other users.
+type Transfer struct {
+	// OfferTTL is how long the recipient has to accept a transfer.
+	OfferTTL time.Duration `yaml:"offer_ttl" env:"TRANSFER_OFFER_TTL" env-default:"24h"`
+}
+
+// Keyboards holds configuration of the removal of inline keyboards whose
+// buttons refer to deleted links or expired payloads.
+type Keyboards struct {
+	// Path is where the tracked messages were kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"KEYBOARDS_PATH" env-default:"data/keyboards.json"`
+	// Size is how many messages are tracked; the oldest are forgotten first.
+	Size int `yaml:"size" env:"KEYBOARDS_SIZE" env-default:"10000"`
+	// Interval is how often stale keyboards are looked for, and tracked
+	// messages written to disk.
+	Interval time.Duration `yaml:"interval" env:"KEYBOARDS_INTERVAL" env-default:"1m"`
+	// Batch caps the keyboards removed per interval, so the removal stays
+	// in the background of regular traffic.
+	Batch int `yaml:"batch" env:"KEYBOARDS_BATCH" env-default:"20"`
+}
+
+// Reconcile holds configuration of the job removing bot-side records, such
+// as pins and snapshots, of links deleted outside the bot.
+type Reconcile struct {
+	Enabled bool `yaml:"enabled" env:"RECONCILE_ENABLED" env-default:"true"`
+	// Path is where the reconciliation progress was kept before the
+	// store; a file found there is imported at startup.
+	Path string `yaml:"path" env:"RECONCILE_PATH" env-default:"data/reconcile.json"`
+	// Interval is the time between the starts of two runs.
+	Interval time.Duration `yaml:"interval" env:"RECONCILE_INTERVAL" env-default:"168h"`
+	// Delay is the pause between two users, sparing the backend.
+	Delay time.Duration `yaml:"delay" env:"RECONCILE_DELAY" env-default:"2s"`
+}
+
+// Audit holds configuration of the log of admin actions on other users'
+// links.
+type Audit struct {
+	// Path is the file the actions are appended to, one JSON object per line.
+	Path string `yaml:"path" env:"AUDIT_PATH" env-default:"data/audit.log"`
+}
+
+// Outbox holds configuration of the on-disk outbox of notifications that
+// must survive a restart, such as transfer outcomes and monitor alerts.
+type Outbox struct {
+	// Path is where the outbox was kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"OUTBOX_PATH" env-default:"data/outbox.json"`
+	// Size caps the notifications kept, delivered ones included, which are
+	// remembered so a notification is never sent twice.
+	Size int `yaml:"size" env:"OUTBOX_SIZE" env-default:"1000"`
+	// MaxAge is how long a notification is kept; undelivered ones older
+	// than that are stale and dropped.
+	MaxAge time.Duration `yaml:"max_age" env:"OUTBOX_MAX_AGE" env-default:"24h"`
+	// RetryInterval is how often undelivered notifications are sent again.
+	RetryInterval time.Duration `yaml:"retry_interval" env:"OUTBOX_RETRY_INTERVAL" env-default:"1m"`
+}
+
+// Campaigns holds configuration of the named groups of links users report
+// on together.
+type Campaigns struct {
+	// MaxPerUser caps the campaigns of a user.
+	MaxPerUser int `yaml:"max_per_user" env:"CAMPAIGNS_MAX_PER_USER" env-default:"10"`
+	// MaxLinks caps the links of a campaign.
+	MaxLinks int `yaml:"max_links" env:"CAMPAIGNS_MAX_LINKS" env-default:"50"`
+	// Workers bounds the concurrent stats lookups of a report.
+	Workers int `yaml:"workers" env:"CAMPAIGNS_WORKERS" env-default:"4"`
+}
+
+// Reports holds configuration of the abuse reports users send with /report.
+type Reports struct {
+	// Path is where the abuse reports were kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"REPORTS_PATH" env-default:"data/reports.json"`
+	// Window is how long further reports of a link update the admin
+	// notification of the first instead of sending new ones.
+	Window time.Duration `yaml:"window" env:"REPORTS_WINDOW" env-default:"24h"`
+	// MaxAge is how long reports are kept.
+	MaxAge time.Duration `yaml:"max_age" env:"REPORTS_MAX_AGE" env-default:"720h"`
+}
+
+// Usage holds configuration of the command and button usage analytics,
+// counted per day in aggregate only.
+type Usage struct {
+	Enabled bool `yaml:"enabled" env:"USAGE_ENABLED" env-default:"false"`
+	// Path is where the daily rollups were kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"USAGE_PATH" env-default:"data/usage.json"`
+	// FlushInterval is how often the rollups are written to the store.
+	FlushInterval time.Duration `yaml:"flush_interval" env:"USAGE_FLUSH_INTERVAL" env-default:"1m"`
+	// RetentionDays is how many days of rollups are kept.
+	RetentionDays int `yaml:"retention_days" env:"USAGE_RETENTION_DAYS" env-default:"90"`
+	// SummaryInterval is how often admins get a usage summary; zero turns
+	// it off.
+	SummaryInterval time.Duration `yaml:"summary_interval" env:"USAGE_SUMMARY_INTERVAL" env-default:"168h"`
+}
+
+// Maintenance holds configuration of the backend maintenance mode set with
+// /maintenance.
+type Maintenance struct {
+	// Path is where the maintenance window was kept before the store; a file
+	// found there is imported at startup.
+	Path string `yaml:"path" env:"MAINTENANCE_PATH" env-default:"data/maintenance.json"`
+	// AnnounceActiveWithin is how recently users must have been seen to be
+	// told about planned maintenance.
+	AnnounceActiveWithin time.Duration `yaml:"announce_active_within" env:"MAINTENANCE_ANNOUNCE_ACTIVE_WITHIN" env-default:"720h"`
+}
+
+// Confirm holds configuration of the confirmation of dangerous admin
+// commands.
+type Confirm struct {
+	// Commands lists the admin commands, without the slash, that only run
+	// once the admin types back a random phrase.
+	Commands []string `yaml:"commands" env:"CONFIRM_COMMANDS" env-separator:"," env-default:"broadcast,maintenance"`
+	// Timeout is how long the admin has to type the phrase back.
+	Timeout time.Duration `yaml:"timeout" env:"CONFIRM_TIMEOUT" env-default:"60s"`
+	// SecondAdmin additionally requires another admin to approve a
+	// confirmed command.
+	SecondAdmin bool `yaml:"second_admin" env:"CONFIRM_SECOND_ADMIN" env-default:"false"`
+	// ApprovalTimeout is how long the other admins have to approve.
+	ApprovalTimeout time.Duration `yaml:"approval_timeout" env:"CONFIRM_APPROVAL_TIMEOUT" env-default:"15m"`
 }
 
 // MustLoad loads the application configuration.
 func MustLoad() *Config {
+	cfg, err := Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+// Load reads the configuration from the file named by CONFIG_PATH (default
+// config/local.yml) or, when it doesn't exist, from the environment.
+func Load() (*Config, error) {
 	// Try to load .env file (ignore error in production)
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, reading from environment variables")
@@ -51,15 +494,25 @@ func MustLoad() *Config {
 	// Try to load config file
 	if _, err := os.Stat(configPath); err == nil {
 		if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-			log.Fatalf("cannot read config: %s", err)
+			return nil, fmt.Errorf("cannot read config: %w", err)
 		}
 	} else {
 		// If config file doesn't exist, use environment variables only
 		log.Println("Config file not found, using environment variables only")
 		if err := cleanenv.ReadEnv(&cfg); err != nil {
-			log.Fatalf("cannot read config from environment: %s", err)
+			return nil, fmt.Errorf("cannot read config from environment: %w", err)
 		}
 	}
 
-	return &cfg
-}
\ No newline at end of file
+	return &cfg, nil
+}
+
+// Welcome holds configuration of the greeting /start shows returning users.
+type Welcome struct {
+	// Personalized greets users who have links with a summary of them.
+	// Turn it off where showing link counts on /start is unwanted.
+	Personalized bool `yaml:"personalized" env:"WELCOME_PERSONALIZED" env-default:"true"`
+	// Timeout bounds fetching the summary; the generic greeting is shown
+	// when it runs out.
+	Timeout time.Duration `yaml:"timeout" env:"WELCOME_TIMEOUT" env-default:"1500ms"`
+}
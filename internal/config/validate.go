@@ -0,0 +1,255 @@
+package config
+
+import (
+	"GURLS-Bot/internal/features"
+	"GURLS-Bot/internal/store"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// aliasChars are the characters the bot allows in aliases unless the backend
+// tells otherwise.
+const aliasChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-"
+
+// Validate checks the configuration for values the bot can't work with. It
+// reports every problem found, joined into one error, and neither touches
+// the network nor Telegram.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if strings.TrimSpace(c.Telegram.Token) == "" {
+		add("telegram.token is empty")
+	}
+	if e := c.Telegram.APIEndpoint; strings.Count(e, "%s") != 2 || !strings.HasPrefix(e, "http") {
+		add("telegram.api_endpoint must be an http(s) URL with two %%s for the token and the method, got %q", e)
+	}
+	if c.Telegram.EditMaxAge < 0 {
+		add("telegram.edit_max_age must not be negative")
+	}
+	if c.Telegram.DedupWindow < 0 {
+		add("telegram.dedup_window must not be negative")
+	}
+	if c.Telegram.BackendAlertAfter <= 0 {
+		add("telegram.backend_alert_after must be positive")
+	}
+	if c.Telegram.SlowHandlerThreshold <= 0 {
+		add("telegram.slow_handler_threshold must be positive")
+	}
+	switch c.Telegram.OnRevoked {
+	case OnRevokedExit:
+		if c.Telegram.RevokedGrace < 0 {
+			add("telegram.revoked_grace must not be negative")
+		}
+	case OnRevokedWait:
+	default:
+		add("telegram.on_revoked must be %s or %s", OnRevokedExit, OnRevokedWait)
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("shutdown_timeout must be positive")
+	}
+
+	if _, err := BackendAddresses(c.GRPCClient.BackendAddress); err != nil {
+		add("grpc_client.backend_address: %w", err)
+	}
+	if c.GRPCClient.Timeout <= 0 {
+		add("grpc_client.timeout must be positive")
+	}
+	if c.GRPCClient.HealthTimeout <= 0 {
+		add("grpc_client.health_timeout must be positive")
+	}
+	if c.GRPCClient.CapabilityRefresh <= 0 {
+		add("grpc_client.capability_refresh must be positive")
+	}
+
+	if err := validBaseURL(c.HTTPServer.BaseURL); err != nil {
+		add("http_server.base_url: %w", err)
+	}
+	labels := make(map[string]bool)
+	for i, d := range c.HTTPServer.Domains {
+		if d.Label == "" {
+			add("http_server.domains[%d].label is empty", i)
+		} else if labels[d.Label] {
+			add("http_server.domains[%d].label %q is used twice", i, d.Label)
+		}
+		labels[d.Label] = true
+		if err := validBaseURL(d.BaseURL); err != nil {
+			add("http_server.domains[%d].base_url: %w", i, err)
+		}
+	}
+
+	if c.Queue.MaxPerUser <= 0 || c.Queue.MaxTotal <= 0 {
+		add("queue.max_per_user and queue.max_total must be positive")
+	}
+	if c.Queue.RetryInterval <= 0 {
+		add("queue.retry_interval must be positive")
+	}
+	if c.Queue.MaxRetryInterval < c.Queue.RetryInterval {
+		add("queue.max_retry_interval must not be less than queue.retry_interval")
+	}
+	if c.Queue.OfferTTL <= 0 {
+		add("queue.offer_ttl must be positive")
+	}
+
+	if c.SafeBrowsing.Enabled && c.SafeBrowsing.APIKey == "" {
+		add("safe_browsing.api_key is required when safe browsing is enabled")
+	}
+	if c.Quota.MaxActiveLinks < 0 || c.Quota.MaxPerDay < 0 {
+		add("quota limits must not be negative")
+	}
+	if c.Prefs.MaxPinned < 0 {
+		add("prefs.max_pinned must not be negative")
+	}
+	if c.Prefs.SnapshotMaxAge <= 0 {
+		add("prefs.snapshot_max_age must be positive")
+	}
+	if c.Prefs.HistorySize < 0 {
+		add("prefs.history_size must not be negative")
+	}
+	if c.Users.FlushInterval <= 0 {
+		add("users.flush_interval must be positive")
+	}
+	if c.Cleanup.Interval <= 0 || c.Cleanup.IdleAfter <= 0 || c.Cleanup.KeepFor <= 0 {
+		add("cleanup.interval, cleanup.idle_after and cleanup.keep_for must be positive")
+	}
+	if c.Cleanup.MaxListed <= 0 || c.Cleanup.Workers <= 0 {
+		add("cleanup.max_listed and cleanup.workers must be positive")
+	}
+	if c.Expiring.Window <= 0 || c.Expiring.Workers <= 0 {
+		add("expiring.window and expiring.workers must be positive")
+	}
+	if p := c.SelfTest.AliasPrefix; p == "" || strings.Trim(p, aliasChars) != "" {
+		add("self_test.alias_prefix must be non-empty letters, digits and '-', got %q", p)
+	}
+	if c.Broadcast.Rate <= 0 || c.Broadcast.CheckpointEvery <= 0 || c.Broadcast.ProgressInterval <= 0 {
+		add("broadcast.rate, broadcast.checkpoint_every and broadcast.progress_interval must be positive")
+	}
+	if c.Caches.SweepInterval <= 0 || c.Caches.WarnSize <= 0 {
+		add("caches.sweep_interval and caches.warn_size must be positive")
+	}
+	if c.Links.PageSize <= 0 || c.Links.FetchPageSize <= 0 || c.Links.MaxPages <= 0 {
+		add("links.page_size, links.fetch_page_size and links.max_pages must be positive")
+	}
+	if c.Monitor.Interval <= 0 || c.Monitor.Poll <= 0 || c.Monitor.Timeout <= 0 || c.Monitor.HostDelay < 0 {
+		add("monitor.interval, monitor.poll and monitor.timeout must be positive and monitor.host_delay not negative")
+	}
+	if c.Monitor.Workers <= 0 || c.Monitor.Failures <= 0 || c.Monitor.MaxPerUser <= 0 {
+		add("monitor.workers, monitor.failures and monitor.max_per_user must be positive")
+	}
+	if c.Monitor.MaxBackoff < c.Monitor.Interval {
+		add("monitor.max_backoff must not be shorter than monitor.interval")
+	}
+	if c.Transfer.OfferTTL <= 0 {
+		add("transfer.offer_ttl must be positive")
+	}
+	if c.Keyboards.Size <= 0 || c.Keyboards.Interval <= 0 || c.Keyboards.Batch <= 0 {
+		add("keyboards.size, keyboards.interval and keyboards.batch must be positive")
+	}
+	if c.Reconcile.Enabled && (c.Reconcile.Interval <= 0 || c.Reconcile.Delay < 0) {
+		add("reconcile.interval must be positive and reconcile.delay not negative when reconciliation is enabled")
+	}
+	if c.Outbox.Size <= 0 || c.Outbox.MaxAge <= 0 || c.Outbox.RetryInterval <= 0 {
+		add("outbox.size, outbox.max_age and outbox.retry_interval must be positive")
+	}
+	if c.SendQueue.Rate <= 0 || c.SendQueue.Size <= 0 {
+		add("send_queue.rate and send_queue.size must be positive")
+	}
+	if c.Welcome.Personalized && c.Welcome.Timeout <= 0 {
+		add("welcome.timeout must be positive when the personalized welcome is enabled")
+	}
+	if c.AutoDelete.Enabled && c.AutoDelete.After <= 0 {
+		add("auto_delete.after must be positive when auto-delete is enabled")
+	}
+	if c.Confirm.Timeout <= 0 || c.Confirm.ApprovalTimeout <= 0 {
+		add("confirm.timeout and confirm.approval_timeout must be positive")
+	}
+
+	if !slices.Contains(store.Backends, c.Storage.Backend) {
+		add("storage.backend must be one of %v", store.Backends)
+	}
+	if strings.TrimSpace(c.Storage.Path) == "" {
+		add("storage.path is empty")
+	}
+
+	for name := range c.Features {
+		if !features.Known(name) {
+			add("features: unknown flag %q", name)
+		}
+	}
+
+	if c.Messages.LinkStyle != "compact" && c.Messages.LinkStyle != "card" {
+		add("messages.link_style must be compact or card")
+	}
+
+	// Files the bot reads at startup must be readable; files it creates may
+	// be missing
+	if c.Messages.TemplateFile != "" {
+		if _, err := os.ReadFile(c.Messages.TemplateFile); err != nil {
+			add("messages.template_file: %w", err)
+		}
+	}
+	if c.Blocklist.File != "" {
+		if _, err := os.ReadFile(c.Blocklist.File); err != nil && !errors.Is(err, os.ErrNotExist) {
+			add("blocklist.file: %w", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// BackendAddresses splits a backend address into host:port pairs. gRPC
+// targets with a scheme are returned as they are, except dns:/// targets,
+// whose host:port is returned.
+func BackendAddresses(address string) ([]string, error) {
+	if strings.TrimSpace(address) == "" {
+		return nil, errors.New("address is empty")
+	}
+	if scheme, rest, ok := strings.Cut(address, "://"); ok {
+		endpoint, isDefaultDNS := strings.CutPrefix(rest, "/")
+		if scheme != "dns" || !isDefaultDNS {
+			return []string{address}, nil
+		}
+		address = endpoint
+	}
+
+	var addrs []string
+	for _, addr := range strings.Split(address, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("%q must be host:port", addr)
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("address is empty")
+	}
+	return addrs, nil
+}
+
+func validBaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
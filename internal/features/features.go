@@ -0,0 +1,72 @@
+package features
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Flags of features that can be turned on or off per deployment.
+const (
+	// Inline is answering "@bot <query>" in any chat.
+	Inline = "inline"
+	// Monitor is checking the destinations of links users monitor.
+	Monitor = "monitor"
+	// Transfer is handing links over to other users.
+	Transfer = "transfer"
+	// SafeBrowsing is checking URLs against Safe Browsing before shortening
+	// them, when safe_browsing is configured.
+	SafeBrowsing = "safe_browsing"
+)
+
+// defaults are the flags of a deployment that doesn't set them. Features
+// shipped dark default to false.
+var defaults = map[string]bool{
+	Inline:       true,
+	Monitor:      true,
+	Transfer:     true,
+	SafeBrowsing: true,
+}
+
+// Known reports whether name is a feature flag.
+func Known(name string) bool {
+	_, ok := defaults[name]
+	return ok
+}
+
+// Set is the feature flags of a deployment.
+type Set struct {
+	flags map[string]bool
+}
+
+// New returns the flags set in flags, with the others at their defaults.
+// Unknown names are ignored; config validation rejects them.
+func New(flags map[string]bool) Set {
+	s := Set{flags: maps.Clone(defaults)}
+	for name, on := range flags {
+		if Known(name) {
+			s.flags[name] = on
+		}
+	}
+	return s
+}
+
+// Enabled reports whether the feature name is on. Names that aren't flags
+// are always on.
+func (s Set) Enabled(name string) bool {
+	on, ok := s.flags[name]
+	return on || !ok
+}
+
+// String lists the flags as "name=on" or "name=off", sorted by name.
+func (s Set) String() string {
+	parts := make([]string, 0, len(s.flags))
+	for _, name := range slices.Sorted(maps.Keys(s.flags)) {
+		state := "off"
+		if s.flags[name] {
+			state = "on"
+		}
+		parts = append(parts, name+"="+state)
+	}
+	return strings.Join(parts, " ")
+}
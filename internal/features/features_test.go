@@ -0,0 +1,58 @@
+package features
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		flags map[string]bool
+		want  string
+	}{
+		{name: "defaults", want: "inline=on monitor=on safe_browsing=on transfer=on"},
+		{
+			name:  "overrides",
+			flags: map[string]bool{Monitor: false, SafeBrowsing: false, Inline: true},
+			want:  "inline=on monitor=off safe_browsing=off transfer=on",
+		},
+		{
+			// Validation rejects them before the set is made
+			name:  "unknown ignored",
+			flags: map[string]bool{"teleport": false, Transfer: false},
+			want:  "inline=on monitor=on safe_browsing=on transfer=off",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.flags).String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	s := New(map[string]bool{Monitor: false, "teleport": false})
+	for name, want := range map[string]bool{
+		Inline:  true,
+		Monitor: false,
+		// Names that aren't flags are always on
+		"teleport": true,
+		"":         true,
+	} {
+		if got := s.Enabled(name); got != want {
+			t.Errorf("Enabled(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestKnown(t *testing.T) {
+	for _, name := range []string{Inline, Monitor, Transfer, SafeBrowsing} {
+		if !Known(name) {
+			t.Errorf("Known(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"teleport", "Inline", ""} {
+		if Known(name) {
+			t.Errorf("Known(%q) = true", name)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Package backendtest runs an in-memory shortener backend over an
+// in-process connection, for tests of the bot and of the gRPC client. It
+// keeps links in memory, fails calls on demand and can play an older
+// backend missing some methods, down to its gRPC reflection.
+package backendtest
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"GURLS-Bot/internal/grpc/client"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Link is a link kept by the backend.
+type Link struct {
+	Alias       string
+	OriginalURL string
+	Title       string
+	OwnerID     int64
+	Clicks      int64
+	Source      string
+	ExpiresAt   *timestamppb.Timestamp
+	CreatedAt   time.Time
+}
+
+// Backend is an in-memory Shortener service. Its zero value is not usable;
+// use Start.
+type Backend struct {
+	shortenerv1.UnimplementedShortenerServer
+
+	mu    sync.Mutex
+	links []*Link
+	// generated counts the aliases made up so far
+	generated int
+	// generate makes up aliases; nil for the default sequence
+	generate func(n int) string
+	// failures are the errors the next calls of a method fail with, in
+	// order
+	failures map[string][]error
+	// missing are the methods the backend plays not to implement
+	missing map[string]bool
+	// calls records the requests received by method
+	calls map[string][]any
+	// metadata records the incoming metadata of the calls, by method
+	metadata map[string][]metadata.MD
+	// delays are how long calls of a method take before being handled
+	delays map[string]time.Duration
+	// slowCallThreshold turns on the slow call log of the client
+	slowCallThreshold time.Duration
+}
+
+// Option configures a Backend.
+type Option func(*Backend)
+
+// Without makes the backend lack methods, given by full name: calls fail
+// with Unimplemented and reflection leaves them out of the service.
+func Without(methods ...string) Option {
+	return func(b *Backend) {
+		for _, m := range methods {
+			b.missing[m] = true
+		}
+	}
+}
+
+// Delay makes calls of methods, given by full name, take d before being
+// handled.
+func Delay(d time.Duration, methods ...string) Option {
+	return func(b *Backend) {
+		for _, m := range methods {
+			b.delays[m] = d
+		}
+	}
+}
+
+// SlowCalls makes the client log the calls taking threshold or longer, as
+// BackendClient.LogSlowCalls.
+func SlowCalls(threshold time.Duration) Option {
+	return func(b *Backend) { b.slowCallThreshold = threshold }
+}
+
+// Aliases makes the backend generate aliases with fn, given how many it
+// generated before. Aliases colliding with existing ones fail creation
+// with AlreadyExists, as a real backend would.
+func Aliases(fn func(n int) string) Option {
+	return func(b *Backend) { b.generate = fn }
+}
+
+// Start serves a new backend in process and returns it with a client
+// connected to it. Both are stopped when the test ends.
+func Start(t testing.TB, opts ...Option) (*Backend, *client.BackendClient) {
+	t.Helper()
+	b := &Backend{
+		failures: make(map[string][]error),
+		missing:  make(map[string]bool),
+		calls:    make(map[string][]any),
+		metadata: make(map[string][]metadata.MD),
+		delays:   make(map[string]time.Duration),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	srv := grpc.NewServer(grpc.UnaryInterceptor(b.intercept))
+	shortenerv1.RegisterShortenerServer(srv, b)
+	registerReflection(srv, b)
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	c, err := client.NewBackendClient("passthrough:///backendtest", 5*time.Second, time.Second, zap.NewNop(), grpc.WithContextDialer(dialer))
+	if err != nil {
+		t.Fatalf("backendtest: failed to connect: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.LogSlowCalls(b.slowCallThreshold, zap.NewNop())
+	return b, c
+}
+
+// intercept records calls and delays and fails them as configured.
+func (b *Backend) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	b.mu.Lock()
+	b.calls[info.FullMethod] = append(b.calls[info.FullMethod], req)
+	md, _ := metadata.FromIncomingContext(ctx)
+	b.metadata[info.FullMethod] = append(b.metadata[info.FullMethod], md)
+	if b.missing[info.FullMethod] {
+		b.mu.Unlock()
+		return nil, status.Errorf(codes.Unimplemented, "method %s not implemented", info.FullMethod)
+	}
+	if errs := b.failures[info.FullMethod]; len(errs) > 0 {
+		b.failures[info.FullMethod] = errs[1:]
+		b.mu.Unlock()
+		return nil, errs[0]
+	}
+	delay := b.delays[info.FullMethod]
+	b.mu.Unlock()
+	time.Sleep(delay)
+	return handler(ctx, req)
+}
+
+// Fail makes the next calls of method, given by full name, fail with errs,
+// one call per error.
+func (b *Backend) Fail(method string, errs ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[method] = append(b.failures[method], errs...)
+}
+
+// Calls returns the requests received for method, given by full name.
+func (b *Backend) Calls(method string) []any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.calls[method])
+}
+
+// Metadata returns the incoming metadata of the calls received for method,
+// given by full name, in the order of Calls.
+func (b *Backend) Metadata(method string) []metadata.MD {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.metadata[method])
+}
+
+// Add stores links as if they had been created, in order.
+func (b *Backend) Add(links ...Link) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, l := range links {
+		if l.CreatedAt.IsZero() {
+			l.CreatedAt = time.Now()
+		}
+		b.links = append(b.links, &l)
+	}
+}
+
+// Link returns the link of alias, if there is one.
+func (b *Backend) Link(alias string) (Link, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if l := b.find(alias); l != nil {
+		return *l, true
+	}
+	return Link{}, false
+}
+
+// find returns the link of alias; b.mu must be held.
+func (b *Backend) find(alias string) *Link {
+	for _, l := range b.links {
+		if l.Alias == alias {
+			return l
+		}
+	}
+	return nil
+}
+
+func (b *Backend) CreateLink(_ context.Context, req *shortenerv1.CreateLinkRequest) (*shortenerv1.CreateLinkResponse, error) {
+	if req.GetOriginalUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "original_url is required")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	alias := req.GetCustomAlias()
+	if req.CustomAlias == nil {
+		b.generated++
+		if b.generate != nil {
+			alias = b.generate(b.generated)
+		} else {
+			alias = "gen" + strconv.Itoa(b.generated)
+		}
+	}
+	if b.find(alias) != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "alias %q is taken", alias)
+	}
+	b.links = append(b.links, &Link{
+		Alias:       alias,
+		OriginalURL: req.GetOriginalUrl(),
+		Title:       req.GetTitle(),
+		OwnerID:     req.GetUserTgId(),
+		Source:      req.GetSource(),
+		ExpiresAt:   req.GetExpiresAt(),
+		CreatedAt:   time.Now(),
+	})
+	// Generated aliases come in the style asked for
+	res := &shortenerv1.CreateLinkResponse{Alias: alias}
+	if req.CustomAlias == nil {
+		res.AliasStyle = req.AliasStyle
+	}
+	return res, nil
+}
+
+func (b *Backend) GetLinkStats(_ context.Context, req *shortenerv1.GetLinkStatsRequest) (*shortenerv1.GetLinkStatsResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l := b.find(req.GetAlias())
+	if l == nil {
+		return nil, status.Errorf(codes.NotFound, "link %q not found", req.GetAlias())
+	}
+	res := &shortenerv1.GetLinkStatsResponse{
+		OriginalUrl: l.OriginalURL,
+		ClickCount:  l.Clicks,
+		ExpiresAt:   l.ExpiresAt,
+		CreatedAt:   timestamppb.New(l.CreatedAt),
+		OwnerTgId:   &l.OwnerID,
+	}
+	if l.Title != "" {
+		res.Title = &l.Title
+	}
+	if l.Source != "" {
+		res.Source = &l.Source
+	}
+	return res, nil
+}
+
+func (b *Backend) DeleteLink(_ context.Context, req *shortenerv1.DeleteLinkRequest) (*emptypb.Empty, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := slices.IndexFunc(b.links, func(l *Link) bool { return l.Alias == req.GetAlias() })
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "link %q not found", req.GetAlias())
+	}
+	b.links = slices.Delete(b.links, i, i+1)
+	return &emptypb.Empty{}, nil
+}
+
+// ListUserLinks lists the links of a user in creation order. Page tokens
+// are the index of the first link of the page.
+func (b *Backend) ListUserLinks(_ context.Context, req *shortenerv1.ListUserLinksRequest) (*shortenerv1.ListUserLinksResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var links []*shortenerv1.LinkInfo
+	for _, l := range b.links {
+		if l.OwnerID != req.GetUserTgId() {
+			continue
+		}
+		info := &shortenerv1.LinkInfo{Alias: l.Alias, OriginalUrl: l.OriginalURL}
+		if l.Title != "" {
+			info.Title = &l.Title
+		}
+		links = append(links, info)
+	}
+	if req.GetPageSize() <= 0 {
+		return &shortenerv1.ListUserLinksResponse{Links: links}, nil
+	}
+
+	start := 0
+	if req.GetPageToken() != "" {
+		n, err := strconv.Atoi(req.GetPageToken())
+		if err != nil || n < 0 || n > len(links) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token %q", req.GetPageToken())
+		}
+		start = n
+	}
+	end := min(start+int(req.GetPageSize()), len(links))
+	res := &shortenerv1.ListUserLinksResponse{Links: links[start:end]}
+	if end < len(links) {
+		res.NextPageToken = strconv.Itoa(end)
+	}
+	return res, nil
+}
+
+func (b *Backend) ResolveLink(_ context.Context, req *shortenerv1.ResolveLinkRequest) (*shortenerv1.ResolveLinkResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l := b.find(req.GetAlias())
+	if l == nil {
+		return nil, status.Errorf(codes.NotFound, "link %q not found", req.GetAlias())
+	}
+	return &shortenerv1.ResolveLinkResponse{OriginalUrl: l.OriginalURL, ExpiresAt: l.ExpiresAt, Active: true}, nil
+}
+
+func (b *Backend) RenameLink(_ context.Context, req *shortenerv1.RenameLinkRequest) (*shortenerv1.RenameLinkResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l := b.find(req.GetAlias())
+	if l == nil || l.OwnerID != req.GetUserTgId() {
+		return nil, status.Errorf(codes.NotFound, "link %q not found", req.GetAlias())
+	}
+	if b.find(req.GetNewAlias()) != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "alias %q is taken", req.GetNewAlias())
+	}
+	l.Alias = req.GetNewAlias()
+	return &shortenerv1.RenameLinkResponse{Alias: l.Alias}, nil
+}
+
+func (b *Backend) GetAliasRules(context.Context, *shortenerv1.GetAliasRulesRequest) (*shortenerv1.GetAliasRulesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "no alias rules")
+}
@@ -0,0 +1,69 @@
+package backendtest
+
+import (
+	"slices"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// reflection serves the descriptor of the Shortener service without the
+// methods the backend lacks, as the reflection of a backend built from an
+// older proto would. Only FileContainingSymbol is answered.
+type reflection struct {
+	reflectionpb.UnimplementedServerReflectionServer
+	backend *Backend
+}
+
+func registerReflection(srv *grpc.Server, b *Backend) {
+	reflectionpb.RegisterServerReflectionServer(srv, reflection{backend: b})
+}
+
+func (r reflection) ServerReflectionInfo(stream reflectionpb.ServerReflection_ServerReflectionInfoServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			// The client closing the stream ends it
+			return nil
+		}
+		if err := stream.Send(r.answer(req)); err != nil {
+			return err
+		}
+	}
+}
+
+func (r reflection) answer(req *reflectionpb.ServerReflectionRequest) *reflectionpb.ServerReflectionResponse {
+	res := &reflectionpb.ServerReflectionResponse{OriginalRequest: req}
+	service := shortenerv1.Shortener_ServiceDesc.ServiceName
+	if req.GetFileContainingSymbol() != service {
+		res.MessageResponse = &reflectionpb.ServerReflectionResponse_ErrorResponse{
+			ErrorResponse: &reflectionpb.ErrorResponse{ErrorCode: int32(codes.NotFound), ErrorMessage: "symbol not found"},
+		}
+		return res
+	}
+
+	file := protodesc.ToFileDescriptorProto(shortenerv1.File_v1_shortener_proto)
+	r.backend.mu.Lock()
+	for _, s := range file.GetService() {
+		s.Method = slices.DeleteFunc(s.Method, func(m *descriptorpb.MethodDescriptorProto) bool {
+			return r.backend.missing["/"+service+"/"+m.GetName()]
+		})
+	}
+	r.backend.mu.Unlock()
+	raw, err := proto.Marshal(file)
+	if err != nil {
+		res.MessageResponse = &reflectionpb.ServerReflectionResponse_ErrorResponse{
+			ErrorResponse: &reflectionpb.ErrorResponse{ErrorCode: int32(codes.Internal), ErrorMessage: err.Error()},
+		}
+		return res
+	}
+	res.MessageResponse = &reflectionpb.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &reflectionpb.FileDescriptorResponse{FileDescriptorProto: [][]byte{raw}},
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// serviceConfig spreads calls over all resolved backend addresses. Without
+// it gRPC uses pick_first and sends everything to a single replica.
+const serviceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// staticScheme is the resolver scheme used for comma-separated address lists.
+const staticScheme = "gurls-static"
+
+// dialTarget turns the configured backend address into a gRPC target.
+//
+// Targets with a scheme, such as dns:///backend:50051, are passed through and
+// resolved by gRPC itself. A single host:port is dialed as is. A
+// comma-separated list of host:port pairs gets a manual resolver, registered
+// only on this connection, whose initial state lists every address; the
+// round_robin policy then keeps one sub-connection per address and skips
+// the ones that are down.
+func dialTarget(address string) (target string, addrs []string, opts []grpc.DialOption) {
+	opts = []grpc.DialOption{grpc.WithDefaultServiceConfig(serviceConfig)}
+	if strings.Contains(address, ":///") || !strings.Contains(address, ",") {
+		return address, []string{address}, opts
+	}
+
+	var state resolver.State
+	for _, addr := range strings.Split(address, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		addrs = append(addrs, addr)
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+	r := manual.NewBuilderWithScheme(staticScheme)
+	r.InitialState(state)
+	opts = append(opts, grpc.WithResolvers(r))
+	return staticScheme + ":///backend", addrs, opts
+}
+
+// logStateChanges logs transitions of the connection until ctx is done. With
+// round_robin the connection is READY while at least one sub-connection is,
+// and TRANSIENT_FAILURE only once all of them have failed, so the addresses
+// behind it are logged alongside.
+func (c *BackendClient) logStateChanges(ctx context.Context, addrs []string) {
+	state := c.conn.GetState()
+	for c.conn.WaitForStateChange(ctx, state) {
+		prev := state
+		state = c.conn.GetState()
+		fields := []zap.Field{
+			zap.Stringer("from", prev),
+			zap.Stringer("to", state),
+			zap.Strings("addresses", addrs),
+		}
+		if state == connectivity.TransientFailure {
+			c.log.Warn("backend connection failing on all addresses", fields...)
+			continue
+		}
+		c.log.Info("backend connection state changed", fields...)
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestDialTarget(t *testing.T) {
+	for _, tt := range []struct {
+		address    string
+		wantTarget string
+		wantAddrs  []string
+	}{
+		{address: "backend:50051", wantTarget: "backend:50051", wantAddrs: []string{"backend:50051"}},
+		{address: "dns:///backend:50051", wantTarget: "dns:///backend:50051", wantAddrs: []string{"dns:///backend:50051"}},
+		{address: "a:1, b:2,", wantTarget: staticScheme + ":///backend", wantAddrs: []string{"a:1", "b:2"}},
+	} {
+		target, addrs, _ := dialTarget(tt.address)
+		if target != tt.wantTarget || !reflect.DeepEqual(addrs, tt.wantAddrs) {
+			t.Errorf("dialTarget(%q) = %q, %v; want %q, %v", tt.address, target, addrs, tt.wantTarget, tt.wantAddrs)
+		}
+	}
+}
+
+// replica is a backend replica counting the calls it answered.
+type replica struct {
+	shortenerv1.UnimplementedShortenerServer
+	srv   *grpc.Server
+	lis   *bufconn.Listener
+	calls atomic.Int32
+}
+
+func (r *replica) GetAliasRules(context.Context, *shortenerv1.GetAliasRulesRequest) (*shortenerv1.GetAliasRulesResponse, error) {
+	r.calls.Add(1)
+	return &shortenerv1.GetAliasRulesResponse{}, nil
+}
+
+func startReplica(t *testing.T) *replica {
+	t.Helper()
+	r := &replica{srv: grpc.NewServer(), lis: bufconn.Listen(1 << 20)}
+	shortenerv1.RegisterShortenerServer(r.srv, r)
+	go r.srv.Serve(r.lis)
+	t.Cleanup(r.srv.Stop)
+	return r
+}
+
+func TestRoundRobinFailover(t *testing.T) {
+	replicas := map[string]*replica{"a:1": startReplica(t), "b:1": startReplica(t)}
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) { return replicas[addr].lis.DialContext(ctx) }
+	c, err := NewBackendClient("a:1,b:1", time.Second, time.Second, zap.NewNop(), grpc.WithContextDialer(dialer))
+	if err != nil {
+		t.Fatalf("NewBackendClient: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	call := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := c.GetAliasRules(ctx)
+		return err
+	}
+
+	// Both replicas take calls once their sub-connections are up
+	deadline := time.Now().Add(5 * time.Second)
+	for replicas["a:1"].calls.Load() == 0 || replicas["b:1"].calls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("calls not spread: a=%d b=%d", replicas["a:1"].calls.Load(), replicas["b:1"].calls.Load())
+		}
+		if err := call(); err != nil {
+			t.Fatalf("call with both replicas up: %v", err)
+		}
+	}
+
+	// Once the stopped replica is noticed, every call goes to the other
+	replicas["a:1"].srv.Stop()
+	for {
+		if time.Now().After(deadline) {
+			t.Fatal("calls still failing after one replica stopped")
+		}
+		if call() == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	before := replicas["b:1"].calls.Load()
+	for i := range 10 {
+		if err := call(); err != nil {
+			t.Fatalf("call %d with one replica down: %v", i, err)
+		}
+	}
+	if got := replicas["b:1"].calls.Load() - before; got != 10 {
+		t.Errorf("remaining replica answered %d of 10 calls", got)
+	}
+}
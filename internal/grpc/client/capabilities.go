@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ErrNoReflection is returned by Methods for a backend that doesn't serve
+// gRPC reflection; its methods are then only learned from regular calls.
+var ErrNoReflection = errors.New("backend does not serve gRPC reflection")
+
+// optionalMethods are the methods older backends may lack, which Methods
+// is asked about.
+var optionalMethods = []string{
+	shortenerv1.Shortener_ResolveLink_FullMethodName,
+	shortenerv1.Shortener_GenerateLinkToken_FullMethodName,
+	shortenerv1.Shortener_GetLinkTokenStatus_FullMethodName,
+	shortenerv1.Shortener_DisconnectDashboard_FullMethodName,
+	shortenerv1.Shortener_RenameLink_FullMethodName,
+	shortenerv1.Shortener_SetLinkExpiry_FullMethodName,
+	shortenerv1.Shortener_UpdateLink_FullMethodName,
+	shortenerv1.Shortener_TransferLink_FullMethodName,
+	shortenerv1.Shortener_SetLinkPreview_FullMethodName,
+}
+
+// ProbedMethods returns the full names of the optional methods, whose
+// presence Methods tells.
+func ProbedMethods() []string {
+	return slices.Clone(optionalMethods)
+}
+
+// Methods returns the full names of the methods the backend declares for
+// its Shortener service. They are read through gRPC server reflection, so
+// asking has no side effects on the backend; ErrNoReflection means it
+// can't be asked this way. Any other error means the backend could not
+// tell, e.g. because it is unreachable.
+func (c *BackendClient) Methods(ctx context.Context) (map[string]bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(c.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+	service := shortenerv1.Shortener_ServiceDesc.ServiceName
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+	stream.CloseSend()
+
+	methods := make(map[string]bool)
+	if e := resp.GetErrorResponse(); e != nil {
+		if codes.Code(e.GetErrorCode()) == codes.NotFound {
+			// The backend serves no Shortener service at all
+			return methods, nil
+		}
+		return nil, fmt.Errorf("backend reflection failed: %s", e.GetErrorMessage())
+	}
+	for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		var file descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("invalid descriptor from backend reflection: %w", err)
+		}
+		for _, s := range file.GetService() {
+			if file.GetPackage()+"."+s.GetName() != service {
+				continue
+			}
+			for _, m := range s.GetMethod() {
+				methods["/"+service+"/"+m.GetName()] = true
+			}
+		}
+	}
+	return methods, nil
+}
+
+func reflectionError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return ErrNoReflection
+	}
+	return err
+}
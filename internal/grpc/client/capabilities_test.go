@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// dialServer serves srv in process and returns a client connected to it.
+func dialServer(t *testing.T, srv *grpc.Server) *BackendClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	c, err := NewBackendClient("passthrough:///bufnet", time.Second, time.Second, zap.NewNop(), grpc.WithContextDialer(dialer))
+	if err != nil {
+		t.Fatalf("NewBackendClient: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestMethodsFromReflection(t *testing.T) {
+	srv := grpc.NewServer()
+	shortenerv1.RegisterShortenerServer(srv, shortenerv1.UnimplementedShortenerServer{})
+	reflection.Register(srv)
+	c := dialServer(t, srv)
+
+	methods, err := c.Methods(context.Background())
+	if err != nil {
+		t.Fatalf("Methods: %v", err)
+	}
+	for _, method := range ProbedMethods() {
+		if !methods[method] {
+			t.Errorf("method %s not reported", method)
+		}
+	}
+}
+
+func TestMethodsWithoutReflection(t *testing.T) {
+	srv := grpc.NewServer()
+	shortenerv1.RegisterShortenerServer(srv, shortenerv1.UnimplementedShortenerServer{})
+	c := dialServer(t, srv)
+
+	if _, err := c.Methods(context.Background()); !errors.Is(err, ErrNoReflection) {
+		t.Fatalf("Methods = %v, want ErrNoReflection", err)
+	}
+}
+
+// olderBackend answers reflection with a Shortener service declaring only
+// some methods, as a backend built from an older proto would.
+type olderBackend struct {
+	reflectionpb.UnimplementedServerReflectionServer
+	methods []string
+}
+
+func (o olderBackend) ServerReflectionInfo(stream reflectionpb.ServerReflection_ServerReflectionInfoServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("Shortener")}
+	for _, m := range o.methods {
+		service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{Name: proto.String(m)})
+	}
+	raw, err := proto.Marshal(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shortener/v1/shortener.proto"),
+		Package: proto.String("shortener.v1"),
+		Service: []*descriptorpb.ServiceDescriptorProto{service},
+	})
+	if err != nil {
+		return err
+	}
+	return stream.Send(&reflectionpb.ServerReflectionResponse{
+		MessageResponse: &reflectionpb.ServerReflectionResponse_FileDescriptorResponse{
+			FileDescriptorResponse: &reflectionpb.FileDescriptorResponse{FileDescriptorProto: [][]byte{raw}},
+		},
+	})
+}
+
+func TestMethodsOfOlderBackend(t *testing.T) {
+	srv := grpc.NewServer()
+	reflectionpb.RegisterServerReflectionServer(srv, olderBackend{methods: []string{"CreateLink", "ResolveLink", "RenameLink"}})
+	c := dialServer(t, srv)
+
+	methods, err := c.Methods(context.Background())
+	if err != nil {
+		t.Fatalf("Methods: %v", err)
+	}
+	for method, want := range map[string]bool{
+		shortenerv1.Shortener_ResolveLink_FullMethodName:  true,
+		shortenerv1.Shortener_RenameLink_FullMethodName:   true,
+		shortenerv1.Shortener_UpdateLink_FullMethodName:   false,
+		shortenerv1.Shortener_TransferLink_FullMethodName: false,
+	} {
+		if methods[method] != want {
+			t.Errorf("methods[%s] = %v, want %v", method, methods[method], want)
+		}
+	}
+}
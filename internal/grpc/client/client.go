@@ -9,39 +9,64 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type BackendClient struct {
-	conn   *grpc.ClientConn
-	client shortenerv1.ShortenerClient
-	log    *zap.Logger
+	conn          *grpc.ClientConn
+	client        shortenerv1.ShortenerClient
+	health        healthpb.HealthClient
+	healthTimeout time.Duration
+	stopWatch     context.CancelFunc
+	observer      func(ctx context.Context, method string, latency time.Duration, err error)
+	slow          *slowCalls
+	refuse        func() error
+	log           *zap.Logger
 }
 
-func NewBackendClient(address string, timeout time.Duration, log *zap.Logger) (*BackendClient, error) {
+// NewBackendClient connects to the backend. address is a host:port, a
+// comma-separated list of them, or a gRPC target such as dns:///host:port;
+// calls are balanced round-robin over all resolved addresses. extra dial
+// options come last, e.g. to dial an in-process backend in tests.
+func NewBackendClient(address string, timeout, healthTimeout time.Duration, log *zap.Logger, extra ...grpc.DialOption) (*BackendClient, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, address, 
+	target, addrs, opts := dialTarget(address)
+	opts = append(opts,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
 	)
+	c := &BackendClient{
+		healthTimeout: healthTimeout,
+		log:           log,
+	}
+	opts = append(opts, grpc.WithUnaryInterceptor(c.requestIDInterceptor))
+	opts = append(opts, extra...)
+	conn, err := grpc.DialContext(ctx, target, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to backend: %w", err)
 	}
+	c.conn = conn
+	c.client = shortenerv1.NewShortenerClient(conn)
+	c.health = healthpb.NewHealthClient(conn)
 
-	client := shortenerv1.NewShortenerClient(conn)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	c.stopWatch = stopWatch
+	go c.logStateChanges(watchCtx, addrs)
+	return c, nil
+}
 
-	return &BackendClient{
-		conn:   conn,
-		client: client,
-		log:    log,
-	}, nil
+// Observe registers fn to be called with the context, latency and outcome of
+// every call. It must be called before the client is used concurrently.
+func (c *BackendClient) Observe(fn func(ctx context.Context, method string, latency time.Duration, err error)) {
+	c.observer = fn
 }
 
 func (c *BackendClient) CreateLink(ctx context.Context, req *shortenerv1.CreateLinkRequest) (*shortenerv1.CreateLinkResponse, error) {
 	resp, err := c.client.CreateLink(ctx, req)
 	if err != nil {
-		c.log.Error("failed to create link via backend", zap.Error(err))
+		c.log.Error("failed to create link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
 		return nil, err
 	}
 	return resp, nil
@@ -50,7 +75,7 @@ func (c *BackendClient) CreateLink(ctx context.Context, req *shortenerv1.CreateL
 func (c *BackendClient) GetLinkStats(ctx context.Context, req *shortenerv1.GetLinkStatsRequest) (*shortenerv1.GetLinkStatsResponse, error) {
 	resp, err := c.client.GetLinkStats(ctx, req)
 	if err != nil {
-		c.log.Error("failed to get link stats via backend", zap.Error(err))
+		c.log.Error("failed to get link stats via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
 		return nil, err
 	}
 	return resp, nil
@@ -59,7 +84,7 @@ func (c *BackendClient) GetLinkStats(ctx context.Context, req *shortenerv1.GetLi
 func (c *BackendClient) DeleteLink(ctx context.Context, req *shortenerv1.DeleteLinkRequest) error {
 	_, err := c.client.DeleteLink(ctx, req)
 	if err != nil {
-		c.log.Error("failed to delete link via backend", zap.Error(err))
+		c.log.Error("failed to delete link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
 		return err
 	}
 	return nil
@@ -68,12 +93,124 @@ func (c *BackendClient) DeleteLink(ctx context.Context, req *shortenerv1.DeleteL
 func (c *BackendClient) ListUserLinks(ctx context.Context, req *shortenerv1.ListUserLinksRequest) (*shortenerv1.ListUserLinksResponse, error) {
 	resp, err := c.client.ListUserLinks(ctx, req)
 	if err != nil {
-		c.log.Error("failed to list user links via backend", zap.Error(err))
+		c.log.Error("failed to list user links via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListUserLinksPage lists up to pageSize links of userID starting at
+// pageToken, "" for the first page, and returns the token of the next page,
+// "" after the last one. Backends without pagination return all links and
+// no token, whatever pageSize is.
+func (c *BackendClient) ListUserLinksPage(ctx context.Context, userID int64, pageSize int32, pageToken string) ([]*shortenerv1.LinkInfo, string, error) {
+	resp, err := c.ListUserLinks(ctx, &shortenerv1.ListUserLinksRequest{UserTgId: userID, PageSize: pageSize, PageToken: pageToken})
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.GetLinks(), resp.GetNextPageToken(), nil
+}
+
+func (c *BackendClient) ResolveLink(ctx context.Context, req *shortenerv1.ResolveLinkRequest) (*shortenerv1.ResolveLinkResponse, error) {
+	resp, err := c.client.ResolveLink(ctx, req)
+	if err != nil {
+		c.log.Error("failed to resolve link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateLinkToken requests a dashboard linking token. The token is a
+// credential and must not be logged.
+func (c *BackendClient) GenerateLinkToken(ctx context.Context, req *shortenerv1.GenerateLinkTokenRequest) (*shortenerv1.GenerateLinkTokenResponse, error) {
+	resp, err := c.client.GenerateLinkToken(ctx, req)
+	if err != nil {
+		c.log.Error("failed to generate link token via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BackendClient) GetLinkTokenStatus(ctx context.Context, req *shortenerv1.GetLinkTokenStatusRequest) (*shortenerv1.GetLinkTokenStatusResponse, error) {
+	resp, err := c.client.GetLinkTokenStatus(ctx, req)
+	if err != nil {
+		c.log.Error("failed to get link token status via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *BackendClient) DisconnectDashboard(ctx context.Context, req *shortenerv1.DisconnectDashboardRequest) (*shortenerv1.DisconnectDashboardResponse, error) {
+	resp, err := c.client.DisconnectDashboard(ctx, req)
+	if err != nil {
+		c.log.Error("failed to disconnect dashboard via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenameLink moves a link to a new alias.
+func (c *BackendClient) RenameLink(ctx context.Context, req *shortenerv1.RenameLinkRequest) (*shortenerv1.RenameLinkResponse, error) {
+	resp, err := c.client.RenameLink(ctx, req)
+	if err != nil {
+		c.log.Error("failed to rename link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetLinkExpiry changes when a link expires.
+func (c *BackendClient) SetLinkExpiry(ctx context.Context, req *shortenerv1.SetLinkExpiryRequest) (*shortenerv1.SetLinkExpiryResponse, error) {
+	resp, err := c.client.SetLinkExpiry(ctx, req)
+	if err != nil {
+		c.log.Error("failed to set link expiry via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateLink points a link at a new destination.
+func (c *BackendClient) UpdateLink(ctx context.Context, req *shortenerv1.UpdateLinkRequest) (*shortenerv1.UpdateLinkResponse, error) {
+	resp, err := c.client.UpdateLink(ctx, req)
+	if err != nil {
+		c.log.Error("failed to update link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// TransferLink hands a link over to another user.
+func (c *BackendClient) TransferLink(ctx context.Context, req *shortenerv1.TransferLinkRequest) (*shortenerv1.TransferLinkResponse, error) {
+	resp, err := c.client.TransferLink(ctx, req)
+	if err != nil {
+		c.log.Error("failed to transfer link via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetLinkPreview sets or, with no preview, removes the custom preview of a
+// link.
+func (c *BackendClient) SetLinkPreview(ctx context.Context, req *shortenerv1.SetLinkPreviewRequest) error {
+	_, err := c.client.SetLinkPreview(ctx, req)
+	if err != nil {
+		c.log.Error("failed to set link preview via backend", zap.String("request_id", RequestID(err)), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetAliasRules returns the custom alias rules of the backend.
+func (c *BackendClient) GetAliasRules(ctx context.Context) (*shortenerv1.GetAliasRulesResponse, error) {
+	resp, err := c.client.GetAliasRules(ctx, &shortenerv1.GetAliasRulesRequest{})
+	if err != nil {
+		c.log.Warn("failed to get alias rules from backend", zap.String("request_id", RequestID(err)), zap.Error(err))
 		return nil, err
 	}
 	return resp, nil
 }
 
 func (c *BackendClient) Close() error {
+	c.stopWatch()
 	return c.conn.Close()
-}
\ No newline at end of file
+}
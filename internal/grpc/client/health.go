@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Health is the result of a backend health probe.
+type Health struct {
+	// Serving reports whether the backend can take requests.
+	Serving bool
+	// State is the state of the underlying connection.
+	State connectivity.State
+	// ConnectionOnly is set when the backend does not implement the health
+	// service and Serving was derived from State alone.
+	ConnectionOnly bool
+	// Latency is the round trip of the probe.
+	Latency time.Duration
+}
+
+// HealthCheck asks the standard grpc.health.v1 service whether the shortener
+// service is serving. It is cheap enough for readiness probes and retry
+// loops. Backends without the health service are judged by connection state.
+func (c *BackendClient) HealthCheck(ctx context.Context) (Health, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.healthTimeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: shortenerv1.Shortener_ServiceDesc.ServiceName,
+	})
+	h := Health{State: c.conn.GetState(), Latency: time.Since(start)}
+	if status.Code(err) == codes.Unimplemented {
+		h.ConnectionOnly = true
+		h.Serving = h.State == connectivity.Ready
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	h.Serving = res.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	return h, nil
+}
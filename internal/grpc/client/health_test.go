@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// hangingHealth is a health service that never answers.
+type hangingHealth struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (hangingHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestHealthCheck(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status healthpb.HealthCheckResponse_ServingStatus
+		want   bool
+	}{
+		{name: "serving", status: healthpb.HealthCheckResponse_SERVING, want: true},
+		{name: "not serving", status: healthpb.HealthCheckResponse_NOT_SERVING, want: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := grpc.NewServer()
+			hs := health.NewServer()
+			hs.SetServingStatus(shortenerv1.Shortener_ServiceDesc.ServiceName, tt.status)
+			healthpb.RegisterHealthServer(srv, hs)
+			c := dialServer(t, srv)
+
+			h, err := c.HealthCheck(context.Background())
+			if err != nil {
+				t.Fatalf("HealthCheck: %v", err)
+			}
+			if h.Serving != tt.want || h.ConnectionOnly || h.State != connectivity.Ready {
+				t.Errorf("HealthCheck = %+v, want serving %v from the health service", h, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheckWithoutHealthService(t *testing.T) {
+	srv := grpc.NewServer()
+	shortenerv1.RegisterShortenerServer(srv, shortenerv1.UnimplementedShortenerServer{})
+	c := dialServer(t, srv)
+
+	h, err := c.HealthCheck(context.Background())
+	if err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if !h.ConnectionOnly || !h.Serving || h.State != connectivity.Ready {
+		t.Errorf("HealthCheck = %+v, want serving judged by the ready connection", h)
+	}
+}
+
+func TestHealthCheckUnknownService(t *testing.T) {
+	// A health service that doesn't know the shortener is an error, not a
+	// missing health service
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	c := dialServer(t, srv)
+
+	if _, err := c.HealthCheck(context.Background()); status.Code(err) != codes.NotFound {
+		t.Errorf("HealthCheck error = %v, want NotFound", err)
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hangingHealth{})
+	c := dialServer(t, srv)
+
+	if _, err := c.HealthCheck(context.Background()); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("HealthCheck error = %v, want the probe timeout", err)
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+)
+
+// mutatingMethods are the methods that change links on the backend.
+var mutatingMethods = map[string]bool{
+	shortenerv1.Shortener_CreateLink_FullMethodName:     true,
+	shortenerv1.Shortener_DeleteLink_FullMethodName:     true,
+	shortenerv1.Shortener_RenameLink_FullMethodName:     true,
+	shortenerv1.Shortener_SetLinkExpiry_FullMethodName:  true,
+	shortenerv1.Shortener_UpdateLink_FullMethodName:     true,
+	shortenerv1.Shortener_TransferLink_FullMethodName:   true,
+	shortenerv1.Shortener_SetLinkPreview_FullMethodName: true,
+}
+
+// RefuseMutations registers fn to be asked before every call that changes
+// links; when it returns an error, the call fails with it without reaching
+// the backend. It must be called before the client is used concurrently.
+func (c *BackendClient) RefuseMutations(fn func() error) {
+	c.refuse = fn
+}
+
+// refused returns the error a call of method is refused with, if any.
+func (c *BackendClient) refused(ctx context.Context, method string) error {
+	if c.refuse == nil || !mutatingMethods[method] {
+		return nil
+	}
+	return c.refuse()
+}
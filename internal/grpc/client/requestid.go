@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"GURLS-Bot/internal/version"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys attached to every outgoing call.
+const (
+	requestIDKey  = "x-request-id"
+	botVersionKey = "x-bot-version"
+	// adminKey carries the Telegram ID of the admin a call is made for. The
+	// backend skips its ownership checks for such calls.
+	adminKey = "x-admin-tg-id"
+)
+
+type (
+	requestIDKeyType struct{}
+	adminKeyType     struct{}
+)
+
+// NewRequestID returns a random UUIDv4.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WithRequestID makes all calls made with ctx share id, so that every RPC
+// caused by one Telegram update carries the same ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKeyType{}, id)
+}
+
+// RequestIDFrom returns the request ID stored in ctx, if any.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKeyType{}).(string)
+	return id
+}
+
+// AsAdmin makes calls made with ctx act for adminID, on any user's link.
+// Only admin commands may use it.
+func AsAdmin(ctx context.Context, adminID int64) context.Context {
+	return context.WithValue(ctx, adminKeyType{}, adminID)
+}
+
+// callError is a failed call annotated with its request ID. It implements
+// GRPCStatus itself, so status.Code and status.FromError see the original
+// status unchanged.
+type callError struct {
+	err       error
+	requestID string
+}
+
+func (e *callError) Error() string              { return e.err.Error() }
+func (e *callError) Unwrap() error              { return e.err }
+func (e *callError) GRPCStatus() *status.Status { return status.Convert(e.err) }
+
+// RequestID returns the request ID of the call that failed with err.
+func RequestID(err error) string {
+	var ce *callError
+	if errors.As(err, &ce) {
+		return ce.requestID
+	}
+	return ""
+}
+
+// requestIDInterceptor attaches the request ID, the bot version and the
+// admin, if any, to outgoing calls and logs each call with its latency,
+// slow ones to the slow call log too. Calls refused by RefuseMutations
+// fail right away.
+func (c *BackendClient) requestIDInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := c.refused(ctx, method); err != nil {
+		return err
+	}
+	id := RequestIDFrom(ctx)
+	if id == "" {
+		id = NewRequestID()
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, requestIDKey, id, botVersionKey, version.String())
+	if adminID, ok := ctx.Value(adminKeyType{}).(int64); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, adminKey, strconv.FormatInt(adminID, 10))
+	}
+
+	var p peer.Peer
+	opts = append(opts, grpc.Peer(&p))
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	latency := time.Since(start)
+	c.log.Debug("backend call",
+		zap.String("method", method),
+		zap.String("request_id", id),
+		zap.Duration("latency", latency),
+		zap.Stringer("code", status.Code(err)),
+	)
+	c.slow.observe(ctx, method, id, req, &p, start, latency, err)
+	if c.observer != nil {
+		c.observer(ctx, method, latency, err)
+	}
+	if err != nil {
+		return &callError{err: err, requestID: id}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxSlowCalls caps the slow calls kept in memory.
+const maxSlowCalls = 50
+
+// SlowCall is a backend call that took at least the slow call threshold.
+type SlowCall struct {
+	Method   string
+	Duration time.Duration
+	// Request summarizes the request by alias and user ID; URLs and other
+	// contents are left out.
+	Request   string
+	RequestID string
+	// Peer is the address of the backend replica that served the call, if
+	// one was picked.
+	Peer string
+	Code codes.Code
+	At   time.Time
+}
+
+// slowCalls logs slow calls and keeps the latest ones in a ring.
+type slowCalls struct {
+	threshold time.Duration
+	log       *zap.Logger
+
+	mu    sync.Mutex
+	calls []SlowCall
+	next  int
+}
+
+// LogSlowCalls makes calls taking threshold or longer be logged to log and
+// kept for SlowCalls; a zero threshold turns that off. It must be called
+// before the client is used concurrently.
+func (c *BackendClient) LogSlowCalls(threshold time.Duration, log *zap.Logger) {
+	if threshold <= 0 {
+		c.slow = nil
+		return
+	}
+	c.slow = &slowCalls{threshold: threshold, log: log}
+}
+
+// SlowCallThreshold returns the duration from which calls count as slow,
+// or zero when they aren't tracked.
+func (c *BackendClient) SlowCallThreshold() time.Duration {
+	if c.slow == nil {
+		return 0
+	}
+	return c.slow.threshold
+}
+
+// SlowCalls returns the latest slow calls, slowest first.
+func (c *BackendClient) SlowCalls() []SlowCall {
+	if c.slow == nil {
+		return nil
+	}
+	c.slow.mu.Lock()
+	calls := slices.Clone(c.slow.calls)
+	c.slow.mu.Unlock()
+	slices.SortFunc(calls, func(a, b SlowCall) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), b.At.Compare(a.At))
+	})
+	return calls
+}
+
+// observe records a finished call when it was slow.
+func (s *slowCalls) observe(ctx context.Context, method, requestID string, req any, p *peer.Peer, start time.Time, latency time.Duration, err error) {
+	if s == nil || latency < s.threshold {
+		return
+	}
+	call := SlowCall{
+		Method:    method,
+		Duration:  latency,
+		Request:   summarizeRequest(req),
+		RequestID: requestID,
+		Code:      status.Code(err),
+		At:        start,
+	}
+	if p != nil && p.Addr != nil {
+		call.Peer = p.Addr.String()
+	}
+	s.log.Warn("slow backend call",
+		zap.String("method", call.Method),
+		zap.Duration("duration", call.Duration),
+		zap.String("request", call.Request),
+		zap.String("request_id", call.RequestID),
+		zap.String("peer", call.Peer),
+		zap.Stringer("code", call.Code),
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.calls) < maxSlowCalls {
+		s.calls = append(s.calls, call)
+		return
+	}
+	s.calls[s.next] = call
+	s.next = (s.next + 1) % maxSlowCalls
+}
+
+// summarizeRequest names the link and user a request is about, never
+// anything else it carries.
+func summarizeRequest(req any) string {
+	var fields []string
+	if r, ok := req.(interface{ GetAlias() string }); ok && r.GetAlias() != "" {
+		fields = append(fields, "alias="+r.GetAlias())
+	}
+	if r, ok := req.(interface{ GetUserTgId() int64 }); ok && r.GetUserTgId() != 0 {
+		fields = append(fields, "user_tg_id="+strconv.FormatInt(r.GetUserTgId(), 10))
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	shortenerv1 "GURLS-Bot/gen/go/shortener/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// delayedBackend answers after a delay set per alias, and per user for
+// calls without one.
+type delayedBackend struct {
+	shortenerv1.UnimplementedShortenerServer
+	delays map[string]time.Duration
+}
+
+func (d delayedBackend) GetLinkStats(_ context.Context, req *shortenerv1.GetLinkStatsRequest) (*shortenerv1.GetLinkStatsResponse, error) {
+	time.Sleep(d.delays[req.GetAlias()])
+	if strings.HasPrefix(req.GetAlias(), "missing") {
+		return nil, status.Error(codes.NotFound, "no such link")
+	}
+	return &shortenerv1.GetLinkStatsResponse{OriginalUrl: "https://example.com/"}, nil
+}
+
+func (d delayedBackend) CreateLink(_ context.Context, req *shortenerv1.CreateLinkRequest) (*shortenerv1.CreateLinkResponse, error) {
+	time.Sleep(d.delays["create"])
+	return &shortenerv1.CreateLinkResponse{}, nil
+}
+
+func dialDelayed(t *testing.T, delays map[string]time.Duration) *BackendClient {
+	t.Helper()
+	srv := grpc.NewServer()
+	shortenerv1.RegisterShortenerServer(srv, delayedBackend{delays: delays})
+	return dialServer(t, srv)
+}
+
+func TestSlowCallLog(t *testing.T) {
+	c := dialDelayed(t, map[string]time.Duration{
+		"slow":         100 * time.Millisecond,
+		"missing-slow": 100 * time.Millisecond,
+		"create":       200 * time.Millisecond,
+	})
+	core, logs := observer.New(zap.WarnLevel)
+	c.LogSlowCalls(50*time.Millisecond, zap.New(core))
+	if got := c.SlowCallThreshold(); got != 50*time.Millisecond {
+		t.Errorf("SlowCallThreshold() = %v", got)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	if _, err := c.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: "fast"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: "slow"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetLinkStats(ctx, &shortenerv1.GetLinkStatsRequest{Alias: "missing-slow"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetLinkStats error = %v, want NotFound", err)
+	}
+	_, err := c.CreateLink(ctx, &shortenerv1.CreateLinkRequest{OriginalUrl: "https://example.com/?token=secret", UserTgId: 42, Title: proto.String("Private")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.FilterMessage("slow backend call").All()
+	if len(entries) != 3 {
+		t.Fatalf("%d slow calls logged, want 3: %v", len(entries), logs.All())
+	}
+	first := entries[0].ContextMap()
+	if first["method"] != shortenerv1.Shortener_GetLinkStats_FullMethodName || first["request"] != "alias=slow" ||
+		first["request_id"] != "req-1" || first["code"] != "OK" || first["peer"] == "" {
+		t.Errorf("slow call logged as %v", first)
+	}
+	if code := entries[1].ContextMap()["code"]; code != "NotFound" {
+		t.Errorf("failed slow call logged with code %v", code)
+	}
+	// Only the user is told of a link creation, never its contents
+	for _, entry := range entries {
+		for key, value := range entry.ContextMap() {
+			if s, ok := value.(string); ok && (strings.Contains(s, "secret") || strings.Contains(s, "Private")) {
+				t.Errorf("slow call log leaks the request in %s: %q", key, s)
+			}
+		}
+	}
+
+	calls := c.SlowCalls()
+	if len(calls) != 3 {
+		t.Fatalf("%d slow calls kept, want 3", len(calls))
+	}
+	if calls[0].Method != shortenerv1.Shortener_CreateLink_FullMethodName || calls[0].Request != "user_tg_id=42" {
+		t.Errorf("slowest call = %+v, want the link creation", calls[0])
+	}
+	for i, call := range calls {
+		if call.Duration < 50*time.Millisecond || call.RequestID != "req-1" {
+			t.Errorf("call %d = %+v", i, call)
+		}
+		if i > 0 && call.Duration > calls[i-1].Duration {
+			t.Errorf("calls not slowest first: %v after %v", call.Duration, calls[i-1].Duration)
+		}
+	}
+}
+
+func TestSlowCallLogOff(t *testing.T) {
+	c := dialDelayed(t, map[string]time.Duration{"slow": 20 * time.Millisecond})
+	core, logs := observer.New(zap.WarnLevel)
+	c.LogSlowCalls(0, zap.New(core))
+
+	if _, err := c.GetLinkStats(context.Background(), &shortenerv1.GetLinkStatsRequest{Alias: "slow"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.SlowCallThreshold() != 0 || c.SlowCalls() != nil || logs.Len() != 0 {
+		t.Errorf("slow calls tracked with a zero threshold: %v, %v", c.SlowCalls(), logs.All())
+	}
+}
+
+func TestSlowCallRing(t *testing.T) {
+	c := &BackendClient{}
+	c.LogSlowCalls(time.Millisecond, zap.NewNop())
+	start := time.Now()
+	for i := range maxSlowCalls + 10 {
+		c.slow.observe(context.Background(), "/m", "", nil, nil, start.Add(time.Duration(i)), time.Duration(i+1)*time.Millisecond, nil)
+	}
+	// Faster calls aren't kept
+	c.slow.observe(context.Background(), "/m", "", nil, nil, start, time.Millisecond-1, nil)
+
+	calls := c.SlowCalls()
+	if len(calls) != maxSlowCalls {
+		t.Fatalf("%d slow calls kept, want %d", len(calls), maxSlowCalls)
+	}
+	// The oldest, and here fastest, calls were overwritten
+	if first, last := calls[0].Duration, calls[len(calls)-1].Duration; first != 60*time.Millisecond || last != 11*time.Millisecond {
+		t.Errorf("kept calls from %v to %v, want 60ms to 11ms", first, last)
+	}
+}
+
+func TestSummarizeRequest(t *testing.T) {
+	for _, tt := range []struct {
+		req  any
+		want string
+	}{
+		{req: &shortenerv1.GetLinkStatsRequest{Alias: "abc"}, want: "alias=abc"},
+		{req: &shortenerv1.RenameLinkRequest{Alias: "abc", NewAlias: "def", UserTgId: 7}, want: "alias=abc user_tg_id=7"},
+		{req: &shortenerv1.CreateLinkRequest{OriginalUrl: "https://example.com/", CustomAlias: proto.String("abc"), UserTgId: 7}, want: "user_tg_id=7"},
+		{req: &shortenerv1.GetAliasRulesRequest{}, want: ""},
+		{req: nil, want: ""},
+	} {
+		if got := summarizeRequest(tt.req); got != tt.want {
+			t.Errorf("summarizeRequest(%T) = %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
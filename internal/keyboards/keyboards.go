@@ -0,0 +1,243 @@
+package keyboards
+
+import (
+	"GURLS-Bot/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Kinds of tracked keyboards.
+const (
+	// KindLink is a keyboard acting on a single link.
+	KindLink = "link"
+	// KindPayload is a keyboard whose buttons carry stored payloads only.
+	KindPayload = "payload"
+)
+
+// Entry is a message of the bot whose inline keyboard refers to state that
+// can go away.
+type Entry struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int    `json:"message_id"`
+	Kind      string `json:"kind"`
+	// Alias is the link the keyboard acts on, for KindLink.
+	Alias string `json:"alias,omitempty"`
+	// Token is a payload store token of one of the buttons.
+	Token string `json:"token,omitempty"`
+	// ExpiresAt is when the stored payloads of the buttons expire; zero
+	// without any.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// Stale is set once the link the keyboard acts on is gone.
+	Stale bool `json:"stale,omitempty"`
+	// Stripped is set once the keyboard was removed from the message.
+	Stripped bool `json:"stripped,omitempty"`
+}
+
+// Due reports whether the keyboard of e should be removed at now.
+func (e Entry) Due(now time.Time) bool {
+	if e.Stripped {
+		return false
+	}
+	return e.Stale || (!e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now))
+}
+
+// namespace and key hold the ring in the store, in a single record as it
+// is always written out whole.
+const (
+	namespace = "keyboards"
+	key       = "ring"
+)
+
+// Store is a ring of the most recent tracked messages, kept in the store.
+// Changes are kept in memory and written out by Run.
+type Store struct {
+	mu      sync.Mutex
+	ns      store.Namespace[[]Entry]
+	size    int
+	entries []Entry
+	dirty   bool
+}
+
+// Open loads the ring kept in db, keeping at most size entries.
+func Open(db store.Store, size int) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[[]Entry]{}), size: size}
+	entries, _, err := s.ns.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tracked keyboards: %w", err)
+	}
+	s.entries = entries
+	if over := len(s.entries) - size; over > 0 {
+		s.entries = s.entries[over:]
+	}
+	return s, nil
+}
+
+// Legacy is the tracked keyboards file of earlier versions, the ring as it
+// is kept now.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "tracked keyboards", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return 0, err
+		}
+		return len(entries), store.NewNamespace(db, namespace, store.JSON[[]Entry]{}).Put(key, entries)
+	}}
+}
+
+// Track records the keyboard of a message, replacing what was recorded for
+// it before. When the ring is full the oldest entry is forgotten.
+func (s *Store) Track(entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteLocked(entry.ChatID, entry.MessageID)
+	if len(s.entries) >= s.size {
+		s.entries = slices.Delete(s.entries, 0, len(s.entries)-s.size+1)
+	}
+	s.entries = append(s.entries, entry)
+	s.dirty = true
+}
+
+// Forget drops the record of a message, as its keyboard is gone.
+func (s *Store) Forget(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleteLocked(chatID, messageID) {
+		s.dirty = true
+	}
+}
+
+// ForgetChat drops the records of all messages in chatID.
+func (s *Store) ForgetChat(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.entries)
+	s.entries = slices.DeleteFunc(s.entries, func(e Entry) bool { return e.ChatID == chatID })
+	if len(s.entries) != n {
+		s.dirty = true
+	}
+}
+
+// MarkStale marks the keyboards acting on alias for removal and returns how
+// many there are.
+func (s *Store) MarkStale(alias string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for i, e := range s.entries {
+		if e.Kind == KindLink && e.Alias == alias && !e.Stale && !e.Stripped {
+			s.entries[i].Stale = true
+			n++
+		}
+	}
+	if n > 0 {
+		s.dirty = true
+	}
+	return n
+}
+
+// Due returns up to limit entries whose keyboards should be removed at now,
+// oldest first.
+func (s *Store) Due(now time.Time, limit int) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Entry
+	for _, e := range s.entries {
+		if len(due) == limit {
+			break
+		}
+		if e.Due(now) {
+			due = append(due, e)
+		}
+	}
+	return due
+}
+
+// MarkStripped records that the keyboard of a message was removed.
+func (s *Store) MarkStripped(chatID int64, messageID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.indexLocked(chatID, messageID); i >= 0 {
+		s.entries[i].Stripped = true
+		s.dirty = true
+	}
+}
+
+// Expired reports whether the keyboard of a message was removed or is about
+// to be, as the link it acts on is gone.
+func (s *Store) Expired(chatID int64, messageID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexLocked(chatID, messageID)
+	return i >= 0 && (s.entries[i].Stripped || s.entries[i].Stale)
+}
+
+// Len returns the number of tracked messages.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Flush writes pending changes to the store.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// Run flushes pending changes every interval until ctx is done, then flushes
+// once more. Errors are passed to onError.
+func (s *Store) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (s *Store) indexLocked(chatID int64, messageID int) int {
+	// Recent messages are the likely ones
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].ChatID == chatID && s.entries[i].MessageID == messageID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) deleteLocked(chatID int64, messageID int) bool {
+	i := s.indexLocked(chatID, messageID)
+	if i < 0 {
+		return false
+	}
+	s.entries = slices.Delete(s.entries, i, i+1)
+	return true
+}
+
+func (s *Store) saveLocked() error {
+	if err := s.ns.Put(key, s.entries); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
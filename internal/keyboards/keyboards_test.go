@@ -0,0 +1,42 @@
+package keyboards
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]Entry](t, "testdata/keyboards.json")
+
+	stores, records := storetest.Migrate(t, "testdata/keyboards.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.entries, want) {
+				t.Errorf("entries =\n%+v\nwant\n%+v", s.entries, want)
+			}
+			now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			due := s.Due(now, 10)
+			if len(due) != 2 || due[0].MessageID != 11 || due[1].MessageID != 7 {
+				t.Errorf("Due() = %+v, want the expired and the stale keyboard", due)
+			}
+
+			// A smaller ring keeps the newest entries
+			s, err = Open(db, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.entries, want[2:]) {
+				t.Errorf("entries of a ring of 2 = %+v", s.entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package locale
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// format is how a language writes numbers and times.
+type format struct {
+	// group separates groups of three digits.
+	group string
+	// date and clock are time layouts of a date and of a time of day.
+	date  string
+	clock string
+}
+
+// formats holds the languages with locale data, by ISO 639-1 code.
+var formats = map[string]format{
+	"en": {group: ",", date: "Jan 2, 2006", clock: "3:04 PM"},
+	"de": {group: ".", date: "02.01.2006", clock: "15:04"},
+	"ru": {group: "\u00a0", date: "02.01.2006", clock: "15:04"},
+}
+
+// Languages lists the languages with locale data.
+var Languages = []string{"en", "de", "ru"}
+
+// Plain layouts, used without locale data.
+const (
+	plainDate  = "2006-01-02"
+	plainClock = "15:04"
+)
+
+// Formatter formats numbers and times for a user. The zero Formatter
+// writes them plainly, digits ungrouped and dates as 2006-01-02, in the
+// location of the time given.
+type Formatter struct {
+	format *format
+	loc    *time.Location
+}
+
+// New returns the formatter for a language, as an IETF tag like "de" or
+// "en-US", and an IANA time zone like "Europe/Berlin". A language without
+// locale data formats plainly and an unknown or empty time zone leaves
+// times in their own location, so New never fails.
+func New(language, timezone string) Formatter {
+	var f Formatter
+	if lf, ok := formats[Normalize(language)]; ok {
+		f.format = &lf
+	}
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			f.loc = loc
+		}
+	}
+	return f
+}
+
+// Normalize reduces an IETF language tag to the language code formats are
+// kept under, e.g. "en-US" to "en".
+func Normalize(language string) string {
+	language, _, _ = strings.Cut(strings.ToLower(language), "-")
+	language, _, _ = strings.Cut(language, "_")
+	return language
+}
+
+// Supported reports whether there is locale data for language.
+func Supported(language string) bool {
+	_, ok := formats[Normalize(language)]
+	return ok
+}
+
+// Number formats an integer with the language's digit grouping. Values
+// that aren't integers are formatted as they are.
+func (f Formatter) Number(n any) string {
+	var s string
+	switch v := n.(type) {
+	case int:
+		s = strconv.Itoa(v)
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	default:
+		return fmt.Sprint(n)
+	}
+	if f.format == nil {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	var sb strings.Builder
+	for i, d := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteString(f.format.group)
+		}
+		sb.WriteRune(d)
+	}
+	return sign + sb.String()
+}
+
+// Date formats the date of t.
+func (f Formatter) Date(t time.Time) string {
+	return f.in(t).Format(f.layout(plainDate, func(lf *format) string { return lf.date }))
+}
+
+// Clock formats the time of day of t with its time zone.
+func (f Formatter) Clock(t time.Time) string {
+	return f.in(t).Format(f.layout(plainClock, func(lf *format) string { return lf.clock }) + " MST")
+}
+
+// DateTime formats the date and time of day of t with its time zone.
+func (f Formatter) DateTime(t time.Time) string {
+	return f.Date(t) + " " + f.Clock(t)
+}
+
+func (f Formatter) in(t time.Time) time.Time {
+	if f.loc == nil {
+		return t
+	}
+	return t.In(f.loc)
+}
+
+func (f Formatter) layout(plain string, of func(*format) string) string {
+	if f.format == nil {
+		return plain
+	}
+	return of(f.format)
+}
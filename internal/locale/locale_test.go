@@ -0,0 +1,91 @@
+package locale
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFormatter(t *testing.T) {
+	at := time.Date(2026, 3, 5, 14, 7, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		language, timezone string
+		// number is 1234567 formatted; date and clock are at formatted
+		number, date, clock string
+	}{
+		{language: "en", timezone: "Europe/Berlin", number: "1,234,567", date: "Mar 5, 2026", clock: "3:07 PM CET"},
+		{language: "de", timezone: "Europe/Berlin", number: "1.234.567", date: "05.03.2026", clock: "15:07 CET"},
+		{language: "ru", timezone: "Europe/Moscow", number: "1\u00a0234\u00a0567", date: "05.03.2026", clock: "17:07 MSK"},
+		{language: "en-US", timezone: "America/New_York", number: "1,234,567", date: "Mar 5, 2026", clock: "9:07 AM EST"},
+		{language: "de_AT", number: "1.234.567", date: "05.03.2026", clock: "14:07 UTC"},
+		// Without locale data numbers and dates are written plainly
+		{language: "fr", timezone: "Europe/Paris", number: "1234567", date: "2026-03-05", clock: "15:07 CET"},
+		{language: "", number: "1234567", date: "2026-03-05", clock: "14:07 UTC"},
+		// An unknown time zone leaves times in their own
+		{language: "en", timezone: "Mars/Olympus", number: "1,234,567", date: "Mar 5, 2026", clock: "2:07 PM UTC"},
+	} {
+		f := New(tt.language, tt.timezone)
+		if got := f.Number(1234567); got != tt.number {
+			t.Errorf("%s: Number = %q, want %q", tt.language, got, tt.number)
+		}
+		if got := f.Date(at); got != tt.date {
+			t.Errorf("%s in %s: Date = %q, want %q", tt.language, tt.timezone, got, tt.date)
+		}
+		if got := f.Clock(at); got != tt.clock {
+			t.Errorf("%s in %s: Clock = %q, want %q", tt.language, tt.timezone, got, tt.clock)
+		}
+		if got, want := f.DateTime(at), tt.date+" "+tt.clock; got != want {
+			t.Errorf("%s in %s: DateTime = %q, want %q", tt.language, tt.timezone, got, want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	en := New("en", "")
+	for _, tt := range []struct {
+		n    any
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 999, want: "999"},
+		{n: 1000, want: "1,000"},
+		{n: int64(-1234567), want: "-1,234,567"},
+		{n: int32(-999), want: "-999"},
+		{n: uint64(123456), want: "123,456"},
+		{n: uint32(12345), want: "12,345"},
+		// Values that aren't integers are written as they are
+		{n: 1234.5, want: "1234.5"},
+		{n: "1234", want: "1234"},
+	} {
+		if got := en.Number(tt.n); got != tt.want {
+			t.Errorf("Number(%#v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if got := (Formatter{}).Number(-1234567); got != "-1234567" {
+		t.Errorf("zero Formatter: Number = %q, want plain digits", got)
+	}
+}
+
+func TestShippedLanguages(t *testing.T) {
+	for _, language := range Languages {
+		lf, ok := formats[language]
+		if !ok {
+			t.Errorf("%s is listed but has no locale data", language)
+			continue
+		}
+		if lf.group == "" || lf.date == "" || lf.clock == "" {
+			t.Errorf("%s has incomplete locale data %+v", language, lf)
+		}
+		if !Supported(language) || !Supported(language+"-XX") {
+			t.Errorf("Supported(%s) = false", language)
+		}
+	}
+	for language := range formats {
+		if !slices.Contains(Languages, language) {
+			t.Errorf("%s has locale data but isn't listed", language)
+		}
+	}
+	if Supported("fr") || Supported("") {
+		t.Error("Supported reports a language without locale data")
+	}
+}
@@ -0,0 +1,101 @@
+// Package maintenance keeps the planned backend maintenance window, during
+// which the bot refuses to change links.
+package maintenance
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Window is a maintenance period, current or planned.
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Message is shown to users along with the end of the window.
+	Message string `json:"message,omitempty"`
+	// AdminID is the admin who set the window.
+	AdminID int64 `json:"admin_id"`
+	// Started is set once admins were told the window started.
+	Started bool `json:"started,omitempty"`
+}
+
+// Active reports whether now falls within the window.
+func (w Window) Active(now time.Time) bool {
+	return !now.Before(w.Start) && now.Before(w.End)
+}
+
+// namespace and key hold the window in the store.
+const (
+	namespace = "maintenance"
+	key       = "window"
+)
+
+// Store is the maintenance window, at most one at a time, kept in the
+// store.
+type Store struct {
+	mu     sync.Mutex
+	ns     store.Namespace[Window]
+	window *Window
+}
+
+// Open loads the window kept in db, if any.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Window]{})}
+	w, ok, err := s.ns.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance window: %w", err)
+	}
+	if ok {
+		s.window = &w
+	}
+	return s, nil
+}
+
+// Legacy is the maintenance window file of earlier versions.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "maintenance window", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var w *Window
+		if err := json.Unmarshal(data, &w); err != nil {
+			return 0, err
+		}
+		if w == nil {
+			return 0, nil
+		}
+		return 1, store.NewNamespace(db, namespace, store.JSON[Window]{}).Put(key, *w)
+	}}
+}
+
+// Get returns the window, if one is set; it may be over already.
+func (s *Store) Get() (Window, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.window == nil {
+		return Window{}, false
+	}
+	return *s.window, true
+}
+
+// Set replaces the window.
+func (s *Store) Set(w Window) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ns.Put(key, w); err != nil {
+		return err
+	}
+	s.window = &w
+	return nil
+}
+
+// Clear removes the window, reporting whether there was one.
+func (s *Store) Clear() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.window == nil {
+		return false, nil
+	}
+	s.window = nil
+	return true, s.ns.Delete(key)
+}
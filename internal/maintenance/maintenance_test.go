@@ -0,0 +1,44 @@
+package maintenance
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[Window](t, "testdata/maintenance.json")
+
+	stores, records := storetest.Migrate(t, "testdata/maintenance.json", Legacy)
+	if records != 1 {
+		t.Errorf("imported %d records, want 1", records)
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := s.Get(); !ok || got != want {
+				t.Errorf("Get() = %+v, %v; want %+v", got, ok, want)
+			}
+		})
+	}
+}
+
+// TestLegacyMigrationNone migrates the file earlier versions left once a
+// window was cleared.
+func TestLegacyMigrationNone(t *testing.T) {
+	stores, records := storetest.Migrate(t, "testdata/none.json", Legacy)
+	if records != 0 {
+		t.Errorf("imported %d records, want none", records)
+	}
+	for backend, db := range stores {
+		s, err := Open(db)
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		if w, ok := s.Get(); ok {
+			t.Errorf("%s: Get() = %+v, want no window", backend, w)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the Histogram buckets.
+var latencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// recentSamples is how many of the latest durations a Histogram keeps for
+// percentiles.
+const recentSamples = 512
+
+// Histogram counts durations in fixed buckets and keeps the most recent
+// ones for percentiles. It is an expvar.Var.
+type Histogram struct {
+	mu     sync.Mutex
+	counts []int64
+	count  int64
+	sum    time.Duration
+	recent []time.Duration
+	next   int
+}
+
+// NewHistogram returns an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, len(latencyBuckets)+1)}
+}
+
+// Observe records d.
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i, _ := slices.BinarySearch(latencyBuckets, d)
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if len(h.recent) < recentSamples {
+		h.recent = append(h.recent, d)
+	} else {
+		h.recent[h.next] = d
+		h.next = (h.next + 1) % recentSamples
+	}
+}
+
+// Count returns how many durations were observed.
+func (h *Histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Percentile returns the p-th percentile, 0 to 100, of the recent durations.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.mu.Lock()
+	sorted := slices.Clone(h.recent)
+	h.mu.Unlock()
+	if len(sorted) == 0 {
+		return 0
+	}
+	slices.Sort(sorted)
+	i := int(float64(len(sorted)-1) * p / 100)
+	return sorted[i]
+}
+
+// String renders the histogram as JSON for expvar.
+func (h *Histogram) String() string {
+	p50, p95 := h.Percentile(50), h.Percentile(95)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make(map[string]int64, len(h.counts))
+	for i, n := range h.counts {
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = strconv.FormatInt(latencyBuckets[i].Milliseconds(), 10)
+		}
+		buckets[le] = n
+	}
+	data, _ := json.Marshal(map[string]any{
+		"count":      h.count,
+		"sum_ms":     h.sum.Milliseconds(),
+		"buckets_ms": buckets,
+		"p50_ms":     p50.Milliseconds(),
+		"p95_ms":     p95.Milliseconds(),
+	})
+	return string(data)
+}
+
+var handlerLatencyMu sync.Mutex
+
+// HandlerLatency returns the latency histogram of a command or callback
+// route, published under handler_latency.
+func HandlerLatency(route string) *Histogram {
+	handlerLatencyMu.Lock()
+	defer handlerLatencyMu.Unlock()
+	if h, ok := handlerLatency.Get(route).(*Histogram); ok {
+		return h
+	}
+	h := NewHistogram()
+	handlerLatency.Set(route, h)
+	return h
+}
+
+// HandlerLatencies calls fn with every route's latency histogram.
+func HandlerLatencies(fn func(route string, h *Histogram)) {
+	handlerLatency.Do(func(kv expvar.KeyValue) {
+		fn(kv.Key, kv.Value.(*Histogram))
+	})
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import "expvar"
+
+// Bot metrics published via expvar.
+var (
+	// CreateQueueDepth is the number of link creation requests waiting for the backend.
+	CreateQueueDepth = expvar.NewInt("create_queue_depth")
+
+	// UnsafeURLsRefused counts URLs refused by the safe browsing check.
+	UnsafeURLsRefused = expvar.NewInt("unsafe_urls_refused")
+	// URLCheckErrors counts failed safe browsing checks.
+	URLCheckErrors = expvar.NewInt("url_check_errors")
+
+	// BlockedSendsAvoided counts notifications not sent because the user
+	// blocked the bot.
+	BlockedSendsAvoided = expvar.NewInt("blocked_sends_avoided")
+
+	// SendQueueDepth is the number of Bot API calls waiting to be made.
+	SendQueueDepth = expvar.NewInt("send_queue_depth")
+	// SendQueueDropped counts notifications dropped from the full send queue.
+	SendQueueDropped = expvar.NewInt("send_queue_dropped")
+	// SendRetries counts Bot API calls tried again after a transient failure.
+	SendRetries = expvar.NewInt("send_retries")
+	// UndeliveredReplies counts handled requests whose reply couldn't be
+	// delivered even after retries.
+	UndeliveredReplies = expvar.NewInt("undelivered_replies")
+
+	// ReconcileRemoved counts bot-side records of links that no longer
+	// exist on the backend, removed by the reconciliation job.
+	ReconcileRemoved = expvar.NewInt("reconcile_removed")
+
+	// TelegramAuthorized is 1 while Telegram accepts the bot token and 0
+	// once it was rejected, for readiness checks.
+	TelegramAuthorized = expvar.NewInt("telegram_authorized")
+
+	// OutboxDepth is the number of notifications in the outbox waiting to be
+	// delivered.
+	OutboxDepth = expvar.NewInt("outbox_depth")
+	// OutboxReplayed counts notifications delivered from the outbox after
+	// their first send failed or was cut off by a restart.
+	OutboxReplayed = expvar.NewInt("outbox_replayed")
+	// OutboxExpired counts notifications dropped from the outbox undelivered.
+	OutboxExpired = expvar.NewInt("outbox_expired")
+
+	// SecondsSinceLastPoll is how long ago a getUpdates long poll last
+	// succeeded, with or without updates. Healthy polls answer at least
+	// every minute.
+	SecondsSinceLastPoll = expvar.NewInt("seconds_since_last_poll")
+	// SecondsSinceLastUpdate is how long ago the last update came in. It
+	// also grows while nobody writes to the bot.
+	SecondsSinceLastUpdate = expvar.NewInt("seconds_since_last_update")
+	// UpdatesRestarts counts getUpdates polls started over after failing
+	// repeatedly or stalling.
+	UpdatesRestarts = expvar.NewInt("updates_restarts")
+
+	// CacheSizes holds the number of entries of each in-memory cache.
+	CacheSizes = expvar.NewMap("cache_size")
+
+	// handlerLatency holds a Histogram per command and callback route.
+	handlerLatency = expvar.NewMap("handler_latency")
+)
@@ -0,0 +1,303 @@
+package monitor
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrLimit is returned by Add when the owner monitors too many links.
+var ErrLimit = errors.New("monitor limit reached")
+
+// Entry is a link whose destination is checked periodically.
+type Entry struct {
+	Alias   string `json:"alias"`
+	OwnerID int64  `json:"owner_id"`
+	// URL is the destination as last seen on the backend.
+	URL string `json:"url"`
+	// Failures counts the consecutive failed checks.
+	Failures int `json:"failures,omitempty"`
+	// LastStatus is the HTTP status of the last check; zero when the
+	// destination could not be reached.
+	LastStatus int `json:"last_status,omitempty"`
+	// LastCheck is when the destination was last checked; zero before the
+	// first check.
+	LastCheck time.Time `json:"last_check"`
+	NextCheck time.Time `json:"next_check"`
+	// Alerted is set once the owner was told about the current problem, so
+	// it is reported once until the destination recovers.
+	Alerted bool `json:"alerted,omitempty"`
+}
+
+// Health is the state of a destination as of its last check.
+type Health string
+
+const (
+	// HealthUnknown is a destination not checked yet.
+	HealthUnknown Health = "unknown"
+	HealthOK      Health = "ok"
+	// HealthBroken is a destination whose last check failed.
+	HealthBroken Health = "broken"
+)
+
+// Health returns the state of the destination of e.
+func (e Entry) Health() Health {
+	switch {
+	case e.LastCheck.IsZero():
+		return HealthUnknown
+	case e.Failures > 0:
+		return HealthBroken
+	}
+	return HealthOK
+}
+
+// namespace holds the monitored links in the store, by alias.
+const namespace = "monitor"
+
+// Store is a set of monitored links, keyed by alias, kept in the store one
+// record per link.
+type Store struct {
+	mu      sync.Mutex
+	ns      store.Namespace[Entry]
+	entries map[string]Entry
+	// dirty holds the links whose checks were recorded since the last save
+	dirty map[string]bool
+}
+
+// Open loads the monitored links kept in db.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Entry]{}), dirty: make(map[string]bool)}
+	entries, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitored links: %w", err)
+	}
+	s.entries = entries
+	return s, nil
+}
+
+// Legacy is the monitored links file of earlier versions, a list of
+// entries.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "monitored links", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return 0, err
+		}
+		records := make(map[string]Entry, len(entries))
+		for _, e := range entries {
+			records[e.Alias] = e
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[Entry]{}).PutAll(records)
+	}}
+}
+
+// Add starts monitoring a link unless its owner already monitors maxPerUser
+// links. Adding a monitored link again keeps its state.
+func (s *Store) Add(entry Entry, maxPerUser int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[entry.Alias]; ok {
+		return nil
+	}
+	if s.countLocked(entry.OwnerID) >= maxPerUser {
+		return ErrLimit
+	}
+	if err := s.ns.Put(entry.Alias, entry); err != nil {
+		return err
+	}
+	s.entries[entry.Alias] = entry
+	return nil
+}
+
+// Remove stops monitoring alias.
+func (s *Store) Remove(alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[alias]; !ok {
+		return nil
+	}
+	return s.deleteLocked(alias)
+}
+
+// RemoveOwner stops monitoring all links of ownerID.
+func (s *Store) RemoveOwner(ownerID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for alias, e := range s.entries {
+		if e.OwnerID != ownerID {
+			continue
+		}
+		if err := s.deleteLocked(alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Has reports whether alias is monitored.
+func (s *Store) Has(alias string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[alias]
+	return ok
+}
+
+// Get returns the entry of alias.
+func (s *Store) Get(alias string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[alias]
+	return e, ok
+}
+
+// HealthOf returns the destination health of the links ownerID monitors,
+// by alias.
+func (s *Store) HealthOf(ownerID int64) map[string]Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := make(map[string]Health)
+	for _, e := range s.entries {
+		if e.OwnerID == ownerID {
+			health[e.Alias] = e.Health()
+		}
+	}
+	return health
+}
+
+// Owned returns the entries of ownerID.
+func (s *Store) Owned(ownerID int64) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned []Entry
+	for _, e := range s.entries {
+		if e.OwnerID == ownerID {
+			owned = append(owned, e)
+		}
+	}
+	return owned
+}
+
+// Owners returns the IDs of all users monitoring links.
+func (s *Store) Owners() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int64]bool)
+	var owners []int64
+	for _, e := range s.entries {
+		if !seen[e.OwnerID] {
+			seen[e.OwnerID] = true
+			owners = append(owners, e.OwnerID)
+		}
+	}
+	return owners
+}
+
+// Rename moves the monitoring of alias to newAlias.
+func (s *Store) Rename(alias, newAlias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[alias]
+	if !ok {
+		return nil
+	}
+	e.Alias = newAlias
+	if err := s.ns.Put(newAlias, e); err != nil {
+		return err
+	}
+	s.entries[newAlias] = e
+	return s.deleteLocked(alias)
+}
+
+// Retarget records a new destination of alias and starts its checks over,
+// the first one at next.
+func (s *Store) Retarget(alias, url string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[alias]
+	if !ok {
+		return nil
+	}
+	e = Entry{Alias: alias, OwnerID: e.OwnerID, URL: url, NextCheck: next}
+	if err := s.ns.Put(alias, e); err != nil {
+		return err
+	}
+	s.entries[alias] = e
+	delete(s.dirty, alias)
+	return nil
+}
+
+// Due returns the entries whose next check is not after now, most overdue
+// first.
+func (s *Store) Due(now time.Time) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Entry
+	for _, e := range s.entries {
+		if !e.NextCheck.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextCheck.Before(due[j].NextCheck) })
+	return due
+}
+
+// Update records the outcome of a check in memory; Save writes it out.
+// Entries removed during the check stay removed.
+func (s *Store) Update(entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[entry.Alias]; ok {
+		s.entries[entry.Alias] = entry
+		s.dirty[entry.Alias] = true
+	}
+}
+
+// Save writes the checks recorded by Update to the store.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.dirty) == 0 {
+		return nil
+	}
+	records := make(map[string]Entry, len(s.dirty))
+	for alias := range s.dirty {
+		records[alias] = s.entries[alias]
+	}
+	if err := s.ns.PutAll(records); err != nil {
+		return err
+	}
+	clear(s.dirty)
+	return nil
+}
+
+func (s *Store) countLocked(ownerID int64) int {
+	n := 0
+	for _, e := range s.entries {
+		if e.OwnerID == ownerID {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Store) deleteLocked(alias string) error {
+	if err := s.ns.Delete(alias); err != nil {
+		return err
+	}
+	delete(s.entries, alias)
+	delete(s.dirty, alias)
+	return nil
+}
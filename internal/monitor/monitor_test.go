@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]Entry](t, "testdata/monitor.json")
+
+	stores, records := storetest.Migrate(t, "testdata/monitor.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range want {
+				if got, ok := s.Get(e.Alias); !ok || !reflect.DeepEqual(got, e) {
+					t.Errorf("Get(%s) =\n%+v, %v\nwant\n%+v", e.Alias, got, ok, e)
+				}
+			}
+			if got := slices.Sorted(slices.Values(s.Owners())); !slices.Equal(got, []int64{1001, 2002}) {
+				t.Errorf("Owners() = %v", got)
+			}
+			health := map[string]Health{"docs": HealthOK, "shop": HealthBroken}
+			if got := s.HealthOf(1001); !reflect.DeepEqual(got, health) {
+				t.Errorf("HealthOf(1001) = %v, want %v", got, health)
+			}
+		})
+	}
+}
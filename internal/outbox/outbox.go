@@ -0,0 +1,211 @@
+package outbox
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrFull is returned by Add when the outbox holds as many undelivered
+// messages as it may.
+var ErrFull = errors.New("outbox full")
+
+// Message is a notification as it is sent.
+type Message struct {
+	ChatID    int64  `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+	NoPreview bool   `json:"no_preview,omitempty"`
+	// Protect prevents forwarding and saving of the message.
+	Protect  bool                           `json:"protect,omitempty"`
+	Keyboard *tgbotapi.InlineKeyboardMarkup `json:"keyboard,omitempty"`
+}
+
+// Entry is a message in the outbox.
+type Entry struct {
+	// Key identifies the notification, e.g. "transfer/<alias>/<from>/<to>".
+	// A key is sent at most once while its entry is kept.
+	Key      string    `json:"key"`
+	Message  Message   `json:"message,omitzero"`
+	QueuedAt time.Time `json:"queued_at"`
+	// DeliveredAt is set once the message was sent. Delivered entries are
+	// kept until they expire so their key isn't sent again.
+	DeliveredAt time.Time `json:"delivered_at,omitzero"`
+}
+
+// namespace holds the outbox in the store, by key.
+const namespace = "outbox"
+
+// Store is an outbox of notifications that must survive a restart, kept in
+// the store one record per entry. Messages are added before they are sent
+// and marked delivered after, so the ones a restart interrupted are found
+// undelivered.
+type Store struct {
+	mu      sync.Mutex
+	ns      store.Namespace[Entry]
+	size    int
+	entries map[string]Entry
+	// claimed holds the keys of undelivered entries being sent; it is not
+	// persisted, so all of them are claimable after a restart.
+	claimed map[string]bool
+}
+
+// Open loads the outbox kept in db. size caps the entries kept, delivered
+// ones included.
+func Open(db store.Store, size int) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Entry]{}), size: size, claimed: make(map[string]bool)}
+	entries, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	s.entries = entries
+	return s, nil
+}
+
+// Legacy is the outbox file of earlier versions, a list of entries.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "outbox", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return 0, err
+		}
+		records := make(map[string]Entry, len(entries))
+		for _, e := range entries {
+			records[e.Key] = e
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[Entry]{}).PutAll(records)
+	}}
+}
+
+// Add persists msg under key and claims it for the caller, who sends it
+// and then calls Delivered or Release. It reports false, adding nothing,
+// when key is already in the outbox, delivered or not. When the outbox is
+// full the oldest delivered entry makes room; without one, ErrFull is
+// returned.
+func (s *Store) Add(key string, msg Message, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; ok {
+		return false, nil
+	}
+	if len(s.entries) >= s.size {
+		evicted, err := s.evictDeliveredLocked()
+		if err != nil {
+			return false, err
+		}
+		if !evicted {
+			return false, ErrFull
+		}
+	}
+	e := Entry{Key: key, Message: msg, QueuedAt: now}
+	if err := s.ns.Put(key, e); err != nil {
+		return false, err
+	}
+	s.entries[key] = e
+	s.claimed[key] = true
+	return true, nil
+}
+
+func (s *Store) evictDeliveredLocked() (bool, error) {
+	oldest := ""
+	for key, e := range s.entries {
+		if !e.DeliveredAt.IsZero() && (oldest == "" || e.QueuedAt.Before(s.entries[oldest].QueuedAt)) {
+			oldest = key
+		}
+	}
+	if oldest == "" {
+		return false, nil
+	}
+	if err := s.ns.Delete(oldest); err != nil {
+		return false, err
+	}
+	delete(s.entries, oldest)
+	return true, nil
+}
+
+// Claim returns the undelivered entries nobody is sending, oldest first,
+// and claims them for the caller.
+func (s *Store) Claim() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []Entry
+	for key, e := range s.entries {
+		if e.DeliveredAt.IsZero() && !s.claimed[key] {
+			s.claimed[key] = true
+			pending = append(pending, e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].QueuedAt.Before(pending[j].QueuedAt) })
+	return pending
+}
+
+// Release gives up the claim on key after a failed send, so the entry is
+// claimed again later.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claimed, key)
+}
+
+// Delivered marks the message of key sent.
+func (s *Store) Delivered(key string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, key)
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	e.DeliveredAt = now
+	e.Message = Message{}
+	if err := s.ns.Put(key, e); err != nil {
+		return err
+	}
+	s.entries[key] = e
+	return nil
+}
+
+// Expire drops the entries queued before before, delivered or not, and
+// returns how many undelivered ones were dropped.
+func (s *Store) Expire(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undelivered := 0
+	for key, e := range s.entries {
+		if !e.QueuedAt.Before(before) || s.claimed[key] {
+			continue
+		}
+		if err := s.ns.Delete(key); err != nil {
+			return undelivered, err
+		}
+		delete(s.entries, key)
+		if e.DeliveredAt.IsZero() {
+			undelivered++
+		}
+	}
+	return undelivered, nil
+}
+
+// Depth returns the number of undelivered entries.
+func (s *Store) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, e := range s.entries {
+		if e.DeliveredAt.IsZero() {
+			n++
+		}
+	}
+	return n
+}
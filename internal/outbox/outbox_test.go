@@ -0,0 +1,199 @@
+package outbox
+
+import (
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/store/storetest"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// openTestStore returns an outbox of size in a new store, and the store.
+func openTestStore(t *testing.T, size int) (*Store, store.Store) {
+	t.Helper()
+	db, err := store.Open(store.BackendJSON, filepath.Join(t.TempDir(), "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := Open(db, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, db
+}
+
+// mustAdd adds key queued at now and fails the test unless it was added.
+func mustAdd(t *testing.T, s *Store, key string, now time.Time) {
+	t.Helper()
+	if added, err := s.Add(key, Message{ChatID: 1001, Text: key}, now); err != nil || !added {
+		t.Fatalf("Add(%s) = %v, %v", key, added, err)
+	}
+}
+
+// keys returns the keys of entries.
+func keys(entries []Entry) []string {
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestAddOnce(t *testing.T) {
+	s, _ := openTestStore(t, 10)
+	now := time.Now()
+	mustAdd(t, s, "a", now)
+	if added, err := s.Add("a", Message{ChatID: 1001}, now); err != nil || added {
+		t.Errorf("Add(a) again = %v, %v; want it refused", added, err)
+	}
+	if err := s.Delivered("a", now); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := s.Add("a", Message{ChatID: 1001}, now); err != nil || added {
+		t.Errorf("Add(a) after delivery = %v, %v; want it refused", added, err)
+	}
+	if s.Depth() != 0 {
+		t.Errorf("Depth() = %d, want 0", s.Depth())
+	}
+}
+
+func TestRestartReplaysUndelivered(t *testing.T) {
+	s, db := openTestStore(t, 10)
+	start := time.Now()
+	mustAdd(t, s, "sent", start)
+	if err := s.Delivered("sent", start); err != nil {
+		t.Fatal(err)
+	}
+	// The process exits between adding these and sending them
+	mustAdd(t, s, "second", start.Add(time.Second))
+	mustAdd(t, s, "first", start)
+	if pending := s.Claim(); len(pending) != 0 {
+		t.Errorf("Claim() before the restart = %v, want the claimed entries left alone", keys(pending))
+	}
+
+	restarted, err := Open(db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := restarted.Claim()
+	if got := keys(pending); !slices.Equal(got, []string{"first", "second"}) {
+		t.Fatalf("Claim() after the restart = %v, want the undelivered entries oldest first", got)
+	}
+	if pending[0].Message.Text != "first" {
+		t.Errorf("replayed message = %+v", pending[0].Message)
+	}
+	// Each is sent by one claimant only
+	if again := restarted.Claim(); len(again) != 0 {
+		t.Errorf("Claim() again = %v, want nothing", keys(again))
+	}
+	for _, key := range []string{"sent", "first"} {
+		if added, err := restarted.Add(key, Message{ChatID: 1001}, start); err != nil || added {
+			t.Errorf("Add(%s) after the restart = %v, %v; want it refused", key, added, err)
+		}
+	}
+
+	// A failed send is retried later; a delivered one isn't
+	restarted.Release("first")
+	if err := restarted.Delivered("second", start); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(restarted.Claim()); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("Claim() after a failed send = %v, want [first]", got)
+	}
+	if restarted.Depth() != 1 {
+		t.Errorf("Depth() = %d, want 1", restarted.Depth())
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	s, _ := openTestStore(t, 2)
+	start := time.Now()
+	mustAdd(t, s, "a", start)
+	mustAdd(t, s, "b", start.Add(time.Second))
+	if _, err := s.Add("c", Message{ChatID: 1001}, start); !errors.Is(err, ErrFull) {
+		t.Fatalf("Add to a full outbox = %v, want ErrFull", err)
+	}
+
+	// The oldest delivered entry makes room
+	for _, key := range []string{"b", "a"} {
+		if err := s.Delivered(key, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustAdd(t, s, "c", start)
+	if _, ok := s.entries["a"]; ok {
+		t.Error("a kept, want the oldest delivered entry evicted")
+	}
+	if _, ok := s.entries["b"]; !ok {
+		t.Error("b evicted, want only the oldest delivered entry evicted")
+	}
+}
+
+func TestExpire(t *testing.T) {
+	s, db := openTestStore(t, 10)
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	mustAdd(t, s, "old", old)
+	s.Release("old")
+	mustAdd(t, s, "old-delivered", old)
+	if err := s.Delivered("old-delivered", old); err != nil {
+		t.Fatal(err)
+	}
+	// Being sent right now
+	mustAdd(t, s, "old-claimed", old)
+	mustAdd(t, s, "new", now)
+	s.Release("new")
+
+	expired, err := s.Expire(now.Add(-time.Hour))
+	if err != nil || expired != 1 {
+		t.Errorf("Expire() = %d, %v; want 1 undelivered entry dropped", expired, err)
+	}
+	reopened, err := Open(db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(reopened.Claim()); !slices.Equal(got, []string{"old-claimed", "new"}) {
+		t.Errorf("entries left = %v, want [old-claimed new]", got)
+	}
+	if added, err := reopened.Add("old-delivered", Message{ChatID: 1001}, now); err != nil || !added {
+		t.Errorf("Add of an expired key = %v, %v; want it sent again", added, err)
+	}
+}
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]Entry](t, "testdata/outbox.json")
+
+	stores, records := storetest.Migrate(t, "testdata/outbox.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range want {
+				if got := s.entries[e.Key]; !reflect.DeepEqual(got, e) {
+					t.Errorf("entry %s =\n%+v\nwant\n%+v", e.Key, got, e)
+				}
+			}
+			if s.Depth() != 2 {
+				t.Errorf("Depth() = %d, want 2", s.Depth())
+			}
+			// Undelivered messages are sent again, oldest first
+			pending := s.Claim()
+			if !reflect.DeepEqual(pending, []Entry{want[1], want[0]}) {
+				t.Errorf("Claim() =\n%+v", pending)
+			}
+			// A delivered key is still not sent twice
+			if added, err := s.Add(want[2].Key, Message{ChatID: 1001}, want[2].QueuedAt); err != nil || added {
+				t.Errorf("Add(%s) = %v, %v; want it refused", want[2].Key, added, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package prefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ExportVersion is the version of the export format written by Export.
+// Fields are only ever added; a new field needs no new version, as exports
+// without it leave the setting alone on import.
+const ExportVersion = 1
+
+// ErrNewerExport is returned by ParseExport for exports written by a newer
+// version of the bot, which may mean something this one can't apply.
+var ErrNewerExport = errors.New("export is from a newer version")
+
+// Export is the portable part of a user's preferences: settings and what
+// the bot keeps about their links, but not the history or bookkeeping.
+// Settings are pointers so that an export without one, e.g. from before
+// it existed, leaves it as it is.
+type Export struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Settings   Settings  `json:"settings"`
+	// Pinned lists pinned aliases in pin order.
+	Pinned []string `json:"pinned,omitempty"`
+	// CleanupKept maps aliases kept from cleanup suggestions to when.
+	CleanupKept map[string]time.Time `json:"cleanup_kept,omitempty"`
+	Snapshots   map[string]Snapshot  `json:"snapshots,omitempty"`
+	Campaigns   []Campaign           `json:"campaigns,omitempty"`
+}
+
+// Settings are the exported settings, see Prefs and CreationDefaults.
+type Settings struct {
+	Expiry            *time.Duration `json:"expiry,omitempty"`
+	AutoTitle         *bool          `json:"auto_title,omitempty"`
+	AskExpiry         *bool          `json:"ask_expiry,omitempty"`
+	Preview           *bool          `json:"preview,omitempty"`
+	Domain            *string        `json:"domain,omitempty"`
+	MinimalAnalytics  *bool          `json:"minimal_analytics,omitempty"`
+	AliasStyle        *string        `json:"alias_style,omitempty"`
+	NotificationSound *bool          `json:"notification_sound,omitempty"`
+	LinkStyle         *string        `json:"link_style,omitempty"`
+	ConfirmShorten    *bool          `json:"confirm_shorten,omitempty"`
+	QuickActions      *bool          `json:"quick_actions,omitempty"`
+	PlainOutput       *bool          `json:"plain_output,omitempty"`
+	Language          *string        `json:"language,omitempty"`
+	Timezone          *string        `json:"timezone,omitempty"`
+	Cleanup           *bool          `json:"cleanup,omitempty"`
+}
+
+// ImportChanges is what applying an export changes.
+type ImportChanges struct {
+	// Settings names the settings that get a different value.
+	Settings []string
+	// PinsAdded counts the aliases pinned that weren't.
+	PinsAdded int
+	// KeptAdded counts the aliases newly kept from cleanup suggestions.
+	KeptAdded int
+	// SnapshotsSet counts the snapshots added or replaced.
+	SnapshotsSet int
+	// CampaignsSet counts the campaigns added or replaced.
+	CampaignsSet int
+}
+
+// Empty reports whether nothing changes.
+func (c ImportChanges) Empty() bool {
+	return len(c.Settings) == 0 && c.PinsAdded == 0 && c.KeptAdded == 0 && c.SnapshotsSet == 0 && c.CampaignsSet == 0
+}
+
+// Export returns the portable part of p.
+func (p Prefs) Export(now time.Time) Export {
+	p = p.clone()
+	d := p.Defaults
+	return Export{
+		Version:    ExportVersion,
+		ExportedAt: now,
+		Settings: Settings{
+			Expiry:            &d.Expiry,
+			AutoTitle:         &d.AutoTitle,
+			AskExpiry:         &d.AskExpiry,
+			Preview:           &d.Preview,
+			Domain:            &d.Domain,
+			MinimalAnalytics:  &d.MinimalAnalytics,
+			AliasStyle:        &d.AliasStyle,
+			NotificationSound: &p.NotificationSound,
+			LinkStyle:         &p.LinkStyle,
+			ConfirmShorten:    &p.ConfirmShorten,
+			QuickActions:      &p.QuickActions,
+			PlainOutput:       &p.PlainOutput,
+			Language:          &p.Language,
+			Timezone:          &p.Timezone,
+			Cleanup:           &p.Cleanup,
+		},
+		Pinned:      p.Pinned,
+		CleanupKept: p.CleanupKept,
+		Snapshots:   p.Snapshots,
+		Campaigns:   p.Campaigns,
+	}
+}
+
+// ParseExport decodes an export, refusing those of a newer version.
+// Unknown fields are ignored.
+func ParseExport(data []byte) (Export, error) {
+	var e Export
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Export{}, fmt.Errorf("invalid export: %w", err)
+	}
+	switch {
+	case e.Version < 1:
+		return Export{}, errors.New("invalid export: version missing")
+	case e.Version > ExportVersion:
+		return Export{}, ErrNewerExport
+	}
+	return e, nil
+}
+
+// Aliases returns the aliases e refers to, sorted and without duplicates.
+func (e Export) Aliases() []string {
+	aliases := slices.Clone(e.Pinned)
+	aliases = slices.AppendSeq(aliases, maps.Keys(e.CleanupKept))
+	aliases = slices.AppendSeq(aliases, maps.Keys(e.Snapshots))
+	for _, c := range e.Campaigns {
+		aliases = append(aliases, c.Aliases...)
+	}
+	slices.Sort(aliases)
+	return slices.Compact(aliases)
+}
+
+// Apply applies e to p: the settings in e overwrite those of p, pins are
+// added after the existing ones, and kept links and snapshots are merged
+// in, those in e replacing the ones for the same aliases. Campaigns replace
+// those of the same name or are added after the existing ones. It returns
+// what changed.
+func (e Export) Apply(p *Prefs) ImportChanges {
+	var c ImportChanges
+	s := e.Settings
+	d := &p.Defaults
+	set(&c, "default expiry", s.Expiry, &d.Expiry)
+	set(&c, "auto title", s.AutoTitle, &d.AutoTitle)
+	set(&c, "ask for expiry", s.AskExpiry, &d.AskExpiry)
+	set(&c, "preview before create", s.Preview, &d.Preview)
+	set(&c, "default domain", s.Domain, &d.Domain)
+	set(&c, "minimal analytics", s.MinimalAnalytics, &d.MinimalAnalytics)
+	set(&c, "alias style", s.AliasStyle, &d.AliasStyle)
+	set(&c, "notification sound", s.NotificationSound, &p.NotificationSound)
+	set(&c, "link style", s.LinkStyle, &p.LinkStyle)
+	set(&c, "confirm before shortening", s.ConfirmShorten, &p.ConfirmShorten)
+	set(&c, "quick actions keyboard", s.QuickActions, &p.QuickActions)
+	set(&c, "plain output", s.PlainOutput, &p.PlainOutput)
+	set(&c, "number and date format", s.Language, &p.Language)
+	set(&c, "time zone", s.Timezone, &p.Timezone)
+	set(&c, "cleanup suggestions", s.Cleanup, &p.Cleanup)
+
+	for _, alias := range e.Pinned {
+		if !slices.Contains(p.Pinned, alias) {
+			p.Pinned = append(p.Pinned, alias)
+			c.PinsAdded++
+		}
+	}
+	for alias, at := range e.CleanupKept {
+		if p.CleanupKept == nil {
+			p.CleanupKept = make(map[string]time.Time)
+		}
+		if _, ok := p.CleanupKept[alias]; !ok {
+			c.KeptAdded++
+		}
+		p.CleanupKept[alias] = at
+	}
+	for alias, snap := range e.Snapshots {
+		if p.Snapshots == nil {
+			p.Snapshots = make(map[string]Snapshot)
+		}
+		snap.ByDevice = maps.Clone(snap.ByDevice)
+		p.Snapshots[alias] = snap
+		c.SnapshotsSet++
+	}
+	for _, campaign := range e.Campaigns {
+		campaign.Aliases = slices.Clone(campaign.Aliases)
+		i := slices.IndexFunc(p.Campaigns, func(existing Campaign) bool {
+			return strings.EqualFold(existing.Name, campaign.Name)
+		})
+		if i < 0 {
+			p.Campaigns = append(p.Campaigns, campaign)
+		} else {
+			p.Campaigns[i] = campaign
+		}
+		c.CampaignsSet++
+	}
+	return c
+}
+
+// set overwrites *dst with *src, if given, recording name when it changes.
+func set[T comparable](c *ImportChanges, name string, src *T, dst *T) {
+	if src == nil || *src == *dst {
+		return
+	}
+	*dst = *src
+	c.Settings = append(c.Settings, name)
+}
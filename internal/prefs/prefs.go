@@ -0,0 +1,244 @@
+package prefs
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Prefs holds the persistent settings of a single user.
+type Prefs struct {
+	// Pinned lists aliases shown first in my_links, in pin order.
+	Pinned []string `json:"pinned,omitempty"`
+	// Defaults are applied to every new link unless overridden explicitly.
+	Defaults CreationDefaults `json:"defaults"`
+	// NotificationSound turns on sound for notifications the bot sends on
+	// its own, such as digests; they are silent by default.
+	NotificationSound bool `json:"notification_sound,omitempty"`
+	// LinkStyle is how created links are shown, "compact" or "card"; empty
+	// uses the deployment default.
+	LinkStyle string `json:"link_style,omitempty"`
+	// AutoShorten shortens every URL posted in a group chat; it is set on
+	// the group's chat ID.
+	AutoShorten bool `json:"auto_shorten,omitempty"`
+	// Channel turns on shortening of the URLs in a channel's posts; it is
+	// set on the channel's chat ID.
+	Channel *ChannelShortening `json:"channel,omitempty"`
+	// ConfirmShorten previews pasted URLs with a Shorten button instead of
+	// shortening them right away; /shorten is never previewed.
+	ConfirmShorten bool `json:"confirm_shorten,omitempty"`
+	// QuickActions shows a reply keyboard with the most used actions.
+	QuickActions bool `json:"quick_actions,omitempty"`
+	// PlainOutput renders messages for screen readers: without emoji and
+	// formatting, with keyboards repeated as numbered options.
+	PlainOutput bool `json:"plain_output,omitempty"`
+	// Language is the language numbers and dates are written in, as an
+	// ISO 639-1 code; empty follows the user's Telegram client.
+	Language string `json:"language,omitempty"`
+	// Timezone is the IANA time zone times are shown in; empty shows them
+	// in UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Cleanup turns on periodic suggestions to delete dead links.
+	Cleanup bool `json:"cleanup,omitempty"`
+	// CleanupAt is when cleanup suggestions were last looked for.
+	CleanupAt time.Time `json:"cleanup_at,omitzero"`
+	// CleanupKept maps aliases the user chose to keep to when they did.
+	CleanupKept map[string]time.Time `json:"cleanup_kept,omitempty"`
+	// Snapshots holds one click count snapshot per alias.
+	Snapshots map[string]Snapshot `json:"snapshots,omitempty"`
+	// History lists the user's recent actions, oldest first.
+	History []HistoryEntry `json:"history,omitempty"`
+	// Campaigns lists the user's campaigns in creation order.
+	Campaigns []Campaign `json:"campaigns,omitempty"`
+}
+
+// ChannelShortening is how the URLs of a channel's posts are shortened.
+// Channel posts have no author, so the links are created on behalf of the
+// administrator who turned it on.
+type ChannelShortening struct {
+	OwnerID int64 `json:"owner_id"`
+	// Mode is "edit" to append the short links to the post or "reply" to
+	// reply with them in the discussion group.
+	Mode string `json:"mode"`
+	// DiscussionID is the chat ID of the channel's discussion group.
+	DiscussionID int64 `json:"discussion_id,omitempty"`
+}
+
+// Campaign is a named group of links reported on together, such as the
+// links of a marketing campaign.
+type Campaign struct {
+	Name string `json:"name"`
+	// Start and End bound the clicks reported; zero values leave the
+	// range open on that side. End is exclusive.
+	Start time.Time `json:"start,omitzero"`
+	End   time.Time `json:"end,omitzero"`
+	// Aliases lists the links of the campaign in the order they were added.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Snapshot is the click count of a link at a point in time, kept to show
+// clicks since then.
+type Snapshot struct {
+	At       time.Time        `json:"at"`
+	Clicks   int64            `json:"clicks"`
+	ByDevice map[string]int64 `json:"by_device,omitempty"`
+}
+
+// HistoryEntry is an action of the user kept for the activity history.
+type HistoryEntry struct {
+	At     time.Time `json:"at"`
+	Action string    `json:"action"`
+	// Alias is the link acted on; for renames, its alias before the rename.
+	Alias string `json:"alias,omitempty"`
+	// NewAlias is the alias a link was renamed to.
+	NewAlias string `json:"new_alias,omitempty"`
+	// Setting names the setting changed.
+	Setting string `json:"setting,omitempty"`
+	// Peer names the other user of a transfer.
+	Peer string `json:"peer,omitempty"`
+}
+
+// CreationDefaults holds settings applied when creating links.
+type CreationDefaults struct {
+	// Expiry is the default link lifetime; zero means links never expire.
+	Expiry time.Duration `json:"expiry,omitempty"`
+	// AutoTitle fills in the page title when no title is given.
+	AutoTitle bool `json:"auto_title,omitempty"`
+	// AskExpiry asks for an expiry on every link without an explicit one.
+	AskExpiry bool `json:"ask_expiry,omitempty"`
+	// Preview shows the final request with a Create button instead of
+	// creating the link right away.
+	Preview bool `json:"preview,omitempty"`
+	// Domain is the host of the short domain new links are created on.
+	Domain string `json:"domain,omitempty"`
+	// MinimalAnalytics creates links counting clicks only, without
+	// per-device or per-country breakdowns.
+	MinimalAnalytics bool `json:"minimal_analytics,omitempty"`
+	// AliasStyle is the style of generated aliases; empty means the
+	// deployment's default.
+	AliasStyle string `json:"alias_style,omitempty"`
+}
+
+func (p Prefs) clone() Prefs {
+	p.Pinned = slices.Clone(p.Pinned)
+	p.CleanupKept = maps.Clone(p.CleanupKept)
+	p.History = slices.Clone(p.History)
+	if p.Channel != nil {
+		channel := *p.Channel
+		p.Channel = &channel
+	}
+	if p.Campaigns != nil {
+		campaigns := make([]Campaign, len(p.Campaigns))
+		for i, c := range p.Campaigns {
+			c.Aliases = slices.Clone(c.Aliases)
+			campaigns[i] = c
+		}
+		p.Campaigns = campaigns
+	}
+	if p.Snapshots != nil {
+		snapshots := make(map[string]Snapshot, len(p.Snapshots))
+		for alias, snap := range p.Snapshots {
+			snap.ByDevice = maps.Clone(snap.ByDevice)
+			snapshots[alias] = snap
+		}
+		p.Snapshots = snapshots
+	}
+	return p
+}
+
+// namespace holds the preferences in the store, by user ID.
+const namespace = "prefs"
+
+// Store is a map of user preferences, kept in the store one record per
+// user.
+type Store struct {
+	mu    sync.Mutex
+	ns    store.Namespace[Prefs]
+	users map[int64]Prefs
+}
+
+// Open loads the preferences kept in db.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Prefs]{}), users: make(map[int64]Prefs)}
+	all, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+	for key, p := range all {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preferences key %q", key)
+		}
+		s.users[id] = p
+	}
+	return s, nil
+}
+
+// Legacy is the preferences file of earlier versions, an object of
+// preferences by user ID.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "preferences", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var users map[int64]Prefs
+		if err := json.Unmarshal(data, &users); err != nil {
+			return 0, err
+		}
+		records := make(map[string]Prefs, len(users))
+		for id, p := range users {
+			records[strconv.FormatInt(id, 10)] = p
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[Prefs]{}).PutAll(records)
+	}}
+}
+
+// Get returns a copy of the preferences of userID.
+func (s *Store) Get(userID int64) Prefs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[userID].clone()
+}
+
+// Users returns the IDs of all users with preferences, in ascending order.
+func (s *Store) Users() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := slices.Collect(maps.Keys(s.users))
+	slices.Sort(ids)
+	return ids
+}
+
+// Update applies fn to the preferences of userID and persists the result.
+// Nothing is changed when fn returns an error.
+func (s *Store) Update(userID int64, fn func(p *Prefs) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.users[userID].clone()
+	if err := fn(&p); err != nil {
+		return err
+	}
+	if err := s.ns.Put(strconv.FormatInt(userID, 10), p); err != nil {
+		return err
+	}
+	s.users[userID] = p
+	return nil
+}
+
+// Delete removes all preferences of userID.
+func (s *Store) Delete(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, existed := s.users[userID]; !existed {
+		return nil
+	}
+	if err := s.ns.Delete(strconv.FormatInt(userID, 10)); err != nil {
+		return err
+	}
+	delete(s.users, userID)
+	return nil
+}
@@ -0,0 +1,33 @@
+package prefs
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[map[int64]Prefs](t, "testdata/prefs.json")
+	stores, records := storetest.Migrate(t, "testdata/prefs.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Users(); !slices.Equal(got, slices.Sorted(maps.Keys(want))) {
+				t.Errorf("Users() = %v", got)
+			}
+			for id, p := range want {
+				if got := s.Get(id); !reflect.DeepEqual(got, p) {
+					t.Errorf("Get(%d) =\n%+v\nwant\n%+v", id, got, p)
+				}
+			}
+		})
+	}
+}
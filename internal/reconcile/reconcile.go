@@ -0,0 +1,82 @@
+package reconcile
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Progress is the state of the reconciliation runs. A run is in progress
+// while StartedAt is after FinishedAt.
+type Progress struct {
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	// Cursor is the last user reconciled in the current run; users are
+	// reconciled in ascending ID order.
+	Cursor int64 `json:"cursor,omitempty"`
+	// Removed counts the records removed in the current run.
+	Removed int `json:"removed,omitempty"`
+}
+
+// Running reports whether a run was started and hasn't finished.
+func (p Progress) Running() bool {
+	return p.StartedAt.After(p.FinishedAt)
+}
+
+// namespace and key hold the progress in the store.
+const (
+	namespace = "reconcile"
+	key       = "progress"
+)
+
+// Store is the progress of the reconciliation runs, kept in the store so a
+// run interrupted by a restart resumes where it stopped.
+type Store struct {
+	mu       sync.Mutex
+	ns       store.Namespace[Progress]
+	progress Progress
+}
+
+// Open loads the progress kept in db; none yields a store before the first
+// run.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Progress]{})}
+	progress, _, err := s.ns.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reconciliation progress: %w", err)
+	}
+	s.progress = progress
+	return s, nil
+}
+
+// Legacy is the reconciliation progress file of earlier versions.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "reconciliation progress", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var p Progress
+		if err := json.Unmarshal(data, &p); err != nil {
+			return 0, err
+		}
+		return 1, store.NewNamespace(db, namespace, store.JSON[Progress]{}).Put(key, p)
+	}}
+}
+
+// Get returns the current progress.
+func (s *Store) Get() Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.progress
+}
+
+// Save records p and writes it to the store.
+func (s *Store) Save(p Progress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ns.Put(key, p); err != nil {
+		return err
+	}
+	s.progress = p
+	return nil
+}
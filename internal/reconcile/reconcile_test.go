@@ -0,0 +1,31 @@
+package reconcile
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"testing"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[Progress](t, "testdata/reconcile.json")
+
+	stores, records := storetest.Migrate(t, "testdata/reconcile.json", Legacy)
+	if records != 1 {
+		t.Errorf("imported %d records, want 1", records)
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := s.Get()
+			if got != want {
+				t.Errorf("Get() = %+v, want %+v", got, want)
+			}
+			// The interrupted run resumes after the migration
+			if !got.Running() {
+				t.Error("run in progress not resumed")
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package reports
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxReasons caps the reasons kept per entry; the count goes on.
+const maxReasons = 5
+
+// Report is one user reporting a link as abusive.
+type Report struct {
+	ReporterID int64     `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	At         time.Time `json:"at"`
+}
+
+// Notice is the message admins were notified with in one admin chat.
+type Notice struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int   `json:"message_id"`
+}
+
+// Entry gathers the reports of a link made within a window, which admins
+// are notified about once.
+type Entry struct {
+	Alias   string    `json:"alias"`
+	FirstAt time.Time `json:"first_at"`
+	// Count is the number of reports, including those whose reasons were
+	// dropped.
+	Count int `json:"count"`
+	// Reports lists the latest reports, oldest first.
+	Reports []Report `json:"reports"`
+	// Notices lists the admin notifications, to update on further reports.
+	Notices []Notice `json:"notices,omitempty"`
+}
+
+// namespace holds the abuse reports in the store, by alias.
+const namespace = "reports"
+
+// Store is a set of abuse reports, keyed by alias, kept in the store one
+// record per link.
+type Store struct {
+	mu      sync.Mutex
+	ns      store.Namespace[Entry]
+	entries map[string]Entry
+}
+
+// Open loads the abuse reports kept in db.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[Entry]{})}
+	entries, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read abuse reports: %w", err)
+	}
+	s.entries = entries
+	return s, nil
+}
+
+// Legacy is the abuse reports file of earlier versions, a list of entries.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "abuse reports", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return 0, err
+		}
+		records := make(map[string]Entry, len(entries))
+		for _, e := range entries {
+			records[e.Alias] = e
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[Entry]{}).PutAll(records)
+	}}
+}
+
+// Add records r against alias and returns the entry it went to. A report
+// within window of the first one of the current entry is added to it;
+// otherwise a new entry starts, without notices.
+func (s *Store) Add(alias string, r Report, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[alias]
+	if !ok || r.At.Sub(e.FirstAt) >= window {
+		e = Entry{Alias: alias, FirstAt: r.At}
+	}
+	e.Count++
+	e.Reports = append(e.Reports, r)
+	if len(e.Reports) > maxReasons {
+		e.Reports = e.Reports[len(e.Reports)-maxReasons:]
+	}
+	if err := s.ns.Put(alias, e); err != nil {
+		return e, err
+	}
+	s.entries[alias] = e
+	return e, nil
+}
+
+// SetNotices records the admin notifications sent for the entry of alias.
+func (s *Store) SetNotices(alias string, notices []Notice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[alias]
+	if !ok {
+		return nil
+	}
+	e.Notices = notices
+	if err := s.ns.Put(alias, e); err != nil {
+		return err
+	}
+	s.entries[alias] = e
+	return nil
+}
+
+// Expire drops the entries started before before.
+func (s *Store) Expire(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for alias, e := range s.entries {
+		if !e.FirstAt.Before(before) {
+			continue
+		}
+		if err := s.ns.Delete(alias); err != nil {
+			return err
+		}
+		delete(s.entries, alias)
+	}
+	return nil
+}
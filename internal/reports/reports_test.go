@@ -0,0 +1,39 @@
+package reports
+
+import (
+	"GURLS-Bot/internal/store/storetest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[[]Entry](t, "testdata/reports.json")
+
+	stores, records := storetest.Migrate(t, "testdata/reports.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range want {
+				if got := s.entries[e.Alias]; !reflect.DeepEqual(got, e) {
+					t.Errorf("entry %s =\n%+v\nwant\n%+v", e.Alias, got, e)
+				}
+			}
+			// Further reports join the migrated entry and keep its notices
+			at := want[0].FirstAt.Add(time.Hour)
+			e, err := s.Add("phish", Report{ReporterID: 6006, Reason: "malware", At: at}, 24*time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Count != want[0].Count+1 || !reflect.DeepEqual(e.Notices, want[0].Notices) {
+				t.Errorf("Add() = %+v", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout is how long opening waits for another process holding
+// the database, such as a bot still shutting down.
+const boltOpenTimeout = 5 * time.Second
+
+// Bolt is a store in a bbolt database, a namespace being a bucket.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens the database at path, creating it if needed.
+func OpenBolt(path string) (*Bolt, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store database: %w", err)
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Get(ns, key string) ([]byte, error) {
+	if err := checkNamespace(ns); err != nil {
+		return nil, err
+	}
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(ns))
+		if bucket == nil {
+			return ErrNotFound
+		}
+		v := bucket.Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		// Values are only valid within the transaction
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	return value, err
+}
+
+func (b *Bolt) Put(ns, key string, value []byte) error {
+	if err := checkNamespace(ns); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(ns))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), value)
+	})
+}
+
+func (b *Bolt) PutAll(ns string, values map[string][]byte) error {
+	if err := checkNamespace(ns); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(ns))
+		if err != nil {
+			return err
+		}
+		for key, value := range values {
+			if err := bucket.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *Bolt) Delete(ns, key string) error {
+	if err := checkNamespace(ns); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(ns))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
+
+// ForEach goes through the keys in byte order, within a single read
+// transaction.
+func (b *Bolt) ForEach(ns string, fn func(key string, value []byte) error) error {
+	if err := checkNamespace(ns); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(ns))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codec turns values of type T into bytes and back.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSON is the Codec of values kept as JSON.
+type JSON[T any] struct{}
+
+func (JSON[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSON[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Namespace is a namespace of a store holding values of type T.
+type Namespace[T any] struct {
+	store Store
+	name  string
+	codec Codec[T]
+}
+
+// NewNamespace returns the namespace name of s, holding values encoded with
+// codec.
+func NewNamespace[T any](s Store, name string, codec Codec[T]) Namespace[T] {
+	return Namespace[T]{store: s, name: name, codec: codec}
+}
+
+// Get returns the value of key and whether there is one. A value that
+// can't be decoded is moved to QuarantineNamespace and reported missing.
+func (n Namespace[T]) Get(key string) (T, bool, error) {
+	var zero T
+	data, err := n.store.Get(n.name, key)
+	if errors.Is(err, ErrNotFound) {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	v, err := n.codec.Decode(data)
+	if err != nil {
+		if err := quarantine(n.store, n.name, key, data, err); err != nil {
+			return zero, false, fmt.Errorf("failed to quarantine %s/%s: %w", n.name, key, err)
+		}
+		return zero, false, nil
+	}
+	return v, true, nil
+}
+
+// Put sets the value of key.
+func (n Namespace[T]) Put(key string, v T) error {
+	data, err := n.codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return n.store.Put(n.name, key, data)
+}
+
+// PutAll sets the values of several keys at once.
+func (n Namespace[T]) PutAll(values map[string]T) error {
+	encoded := make(map[string][]byte, len(values))
+	for key, v := range values {
+		data, err := n.codec.Encode(v)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+	return n.store.PutAll(n.name, encoded)
+}
+
+// Delete removes key.
+func (n Namespace[T]) Delete(key string) error {
+	return n.store.Delete(n.name, key)
+}
+
+// All returns every value of the namespace by key. Records that can't be
+// decoded are moved to QuarantineNamespace rather than failing the whole
+// namespace; only a failure to move one is returned.
+func (n Namespace[T]) All() (map[string]T, error) {
+	values := make(map[string]T)
+	corrupt := make(map[string][]byte)
+	causes := make(map[string]error)
+	err := n.store.ForEach(n.name, func(key string, data []byte) error {
+		v, err := n.codec.Decode(data)
+		if err != nil {
+			// Moved once the iteration is over, as some backends can't
+			// write while going through a namespace
+			corrupt[key] = append([]byte(nil), data...)
+			causes[key] = err
+			return nil
+		}
+		values[key] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range corrupt {
+		if err := quarantine(n.store, n.name, key, data, causes[key]); err != nil {
+			return nil, fmt.Errorf("failed to quarantine %s/%s: %w", n.name, key, err)
+		}
+	}
+	return values, nil
+}
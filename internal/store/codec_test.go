@@ -0,0 +1,91 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type record struct {
+	Name string `json:"name"`
+}
+
+// backends opens a fresh store of every backend in a temporary directory.
+func backends(t *testing.T) map[string]Store {
+	t.Helper()
+	stores := make(map[string]Store)
+	for _, backend := range Backends {
+		path := filepath.Join(t.TempDir(), "store")
+		s, err := Open(backend, path)
+		if err != nil {
+			t.Fatalf("Open(%s): %v", backend, err)
+		}
+		t.Cleanup(func() { s.Close() })
+		stores[backend] = s
+	}
+	return stores
+}
+
+func TestNamespaceAllQuarantinesUndecodable(t *testing.T) {
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			start := time.Now()
+			ns := NewNamespace(s, "records", JSON[record]{})
+			if err := ns.Put("good", record{Name: "kept"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put("records", "bad", []byte("{not json")); err != nil {
+				t.Fatal(err)
+			}
+
+			all, err := ns.All()
+			if err != nil {
+				t.Fatalf("All: %v", err)
+			}
+			if len(all) != 1 || all["good"].Name != "kept" {
+				t.Fatalf("All = %v, want only the good record", all)
+			}
+			if _, err := s.Get("records", "bad"); err != ErrNotFound {
+				t.Fatalf("undecodable record left in place: %v", err)
+			}
+
+			quarantined, err := QuarantinedSince(s, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(quarantined) != 1 {
+				t.Fatalf("quarantined %d records, want 1", len(quarantined))
+			}
+			q := quarantined[0]
+			if q.Namespace != "records" || q.Key != "bad" || string(q.Value) != "{not json" || q.Error == "" {
+				t.Errorf("quarantined record = %+v", q)
+			}
+
+			later, err := QuarantinedSince(s, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(later) != 0 {
+				t.Errorf("QuarantinedSince later = %v, want none", later)
+			}
+		})
+	}
+}
+
+func TestNamespaceGetQuarantinesUndecodable(t *testing.T) {
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			ns := NewNamespace(s, "records", JSON[record]{})
+			if err := s.Put("records", "bad", []byte("[]")); err != nil {
+				t.Fatal(err)
+			}
+			_, ok, err := ns.Get("bad")
+			if err != nil || ok {
+				t.Fatalf("Get = %v, %v; want missing without error", ok, err)
+			}
+			if _, err := s.Get(QuarantineNamespace, "records/bad"); err != nil {
+				t.Errorf("record not quarantined: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir is a store keeping every value in a file of its own: a namespace is a
+// directory and a key a file named after it, with a .json extension.
+type Dir struct {
+	path string
+}
+
+// OpenDir opens the store in the directory at path, creating it if needed.
+func OpenDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &Dir{path: path}, nil
+}
+
+// file returns the file of key in ns. Keys are escaped, so any key makes a
+// single file name; the escaping leaves aliases and IDs as they are.
+func (d *Dir) file(ns, key string) (string, error) {
+	if err := checkNamespace(ns); err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", errors.New("empty store key")
+	}
+	name := url.PathEscape(key)
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:]
+	}
+	return filepath.Join(d.path, ns, name+".json"), nil
+}
+
+func (d *Dir) Get(ns, key string) ([]byte, error) {
+	file, err := d.file(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put writes the value to a temporary file first, syncs it and renames it
+// over the previous one, then syncs the directory, so a crash or a power
+// loss never leaves a value half written.
+func (d *Dir) Put(ns, key string, value []byte) error {
+	file, err := d.file(ns, key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes the entries of the directory at path, such as a file just
+// renamed into it, durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// PutAll writes the values one by one; each is replaced atomically, but
+// not all of them together.
+func (d *Dir) PutAll(ns string, values map[string][]byte) error {
+	for key, value := range values {
+		if err := d.Put(ns, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dir) Delete(ns, key string) error {
+	file, err := d.file(ns, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ForEach goes through the keys in the order of their file names. Files
+// other than values, such as temporary ones, are skipped.
+func (d *Dir) ForEach(ns string, fn func(key string, value []byte) error) error {
+	if err := checkNamespace(ns); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(d.path, ns))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		key, err := url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.path, ns, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted meanwhile
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dir) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MigratedSuffix is appended to the names of imported legacy files.
+const MigratedSuffix = ".migrated"
+
+// Legacy is a single-file store of an earlier version of the bot, imported
+// into the store at startup.
+type Legacy struct {
+	// Name describes the file in logs and errors, e.g. "preferences".
+	Name string
+	Path string
+	// Import puts the records of the file, read whole, into s and returns
+	// how many there were.
+	Import func(s Store, data []byte) (int, error)
+}
+
+// Migrated is a legacy file imported by Migrate.
+type Migrated struct {
+	Name    string
+	Path    string
+	Records int
+}
+
+// Migrate imports the legacy files found into s and renames each one with
+// MigratedSuffix, so later starts leave it alone. Records keep the keys
+// they always had, so a migration cut short before the rename is simply
+// done again. A legacy file found next to its migrated copy was put back by
+// hand; it is refused rather than imported over newer state. The first
+// failure stops the migration.
+func Migrate(s Store, files []Legacy) ([]Migrated, error) {
+	var migrated []Migrated
+	for _, f := range files {
+		data, err := os.ReadFile(f.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return migrated, fmt.Errorf("failed to read legacy %s: %w", f.Name, err)
+		}
+		if _, err := os.Stat(f.Path + MigratedSuffix); err == nil {
+			return migrated, fmt.Errorf("legacy %s %s was already migrated; remove it or %s", f.Name, f.Path, f.Path+MigratedSuffix)
+		}
+		n, err := f.Import(s, data)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to import legacy %s: %w", f.Name, err)
+		}
+		if err := os.Rename(f.Path, f.Path+MigratedSuffix); err != nil {
+			return migrated, fmt.Errorf("failed to rename migrated %s: %w", f.Name, err)
+		}
+		migrated = append(migrated, Migrated{Name: f.Name, Path: f.Path, Records: n})
+	}
+	return migrated, nil
+}
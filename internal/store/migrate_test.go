@@ -0,0 +1,164 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// legacyRecords is a legacy file holding a JSON object of records, imported
+// into the namespace "records".
+func legacyRecords(path string) Legacy {
+	return Legacy{Name: "records", Path: path, Import: func(s Store, data []byte) (int, error) {
+		var records map[string]record
+		if err := json.Unmarshal(data, &records); err != nil {
+			return 0, err
+		}
+		return len(records), NewNamespace(s, "records", JSON[record]{}).PutAll(records)
+	}}
+}
+
+// writeLegacy writes a legacy file of records to dir and returns its path.
+func writeLegacy(t *testing.T, dir, name string, records map[string]record) string {
+	t.Helper()
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, s Store) map[string]record {
+	t.Helper()
+	all, err := NewNamespace(s, "records", JSON[record]{}).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestMigrate(t *testing.T) {
+	records := map[string]record{"a": {Name: "alice"}, "b": {Name: "bob"}}
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeLegacy(t, dir, "records.json", records)
+			missing := filepath.Join(dir, "missing.json")
+
+			migrated, err := Migrate(s, []Legacy{legacyRecords(missing), legacyRecords(path)})
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			want := []Migrated{{Name: "records", Path: path, Records: 2}}
+			if len(migrated) != 1 || migrated[0] != want[0] {
+				t.Errorf("migrated = %+v, want %+v", migrated, want)
+			}
+			if got := readAll(t, s); !maps.Equal(got, records) {
+				t.Errorf("records = %v, want %v", got, records)
+			}
+			if exists(path) || !exists(path+MigratedSuffix) {
+				t.Error("legacy file not renamed")
+			}
+			if exists(missing + MigratedSuffix) {
+				t.Error("missing legacy file got a migrated copy")
+			}
+
+			// Later starts find nothing to do
+			migrated, err = Migrate(s, []Legacy{legacyRecords(path)})
+			if err != nil || len(migrated) != 0 {
+				t.Errorf("second Migrate = %+v, %v; want nothing", migrated, err)
+			}
+			if got := readAll(t, s); !maps.Equal(got, records) {
+				t.Errorf("records after second Migrate = %v", got)
+			}
+		})
+	}
+}
+
+// TestMigrateCutShort runs a migration again whose rename didn't happen, over
+// a store changed since: the same keys are imported again, others are left.
+func TestMigrateCutShort(t *testing.T) {
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			path := writeLegacy(t, t.TempDir(), "records.json", map[string]record{"a": {Name: "alice"}})
+			if err := NewNamespace(s, "records", JSON[record]{}).PutAll(map[string]record{
+				"a": {Name: "alice"},
+				"c": {Name: "carol"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Migrate(s, []Legacy{legacyRecords(path)}); err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			want := map[string]record{"a": {Name: "alice"}, "c": {Name: "carol"}}
+			if got := readAll(t, s); !maps.Equal(got, want) {
+				t.Errorf("records = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMigrateRefusesFileBesideMigratedCopy(t *testing.T) {
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			first := writeLegacy(t, dir, "first.json", map[string]record{"a": {Name: "alice"}})
+			restored := writeLegacy(t, dir, "restored.json", map[string]record{"b": {Name: "old bob"}})
+			writeLegacy(t, dir, "restored.json"+MigratedSuffix, map[string]record{"b": {Name: "bob"}})
+			last := writeLegacy(t, dir, "last.json", map[string]record{"c": {Name: "carol"}})
+
+			migrated, err := Migrate(s, []Legacy{legacyRecords(first), legacyRecords(restored), legacyRecords(last)})
+			if err == nil || !strings.Contains(err.Error(), "already migrated") {
+				t.Fatalf("err = %v, want a refusal", err)
+			}
+			// Files before the refused one are migrated, none after it
+			if len(migrated) != 1 || migrated[0].Path != first {
+				t.Errorf("migrated = %+v, want only %s", migrated, first)
+			}
+			want := map[string]record{"a": {Name: "alice"}}
+			if got := readAll(t, s); !maps.Equal(got, want) {
+				t.Errorf("records = %v, want %v", got, want)
+			}
+			if !exists(restored) || !exists(last) {
+				t.Error("refused or later legacy file was moved")
+			}
+		})
+	}
+}
+
+func TestMigrateImportFailure(t *testing.T) {
+	for backend, s := range backends(t) {
+		t.Run(backend, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "records.json")
+			if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			boom := errors.New("boom")
+			failing := Legacy{Name: "failing", Path: path, Import: func(Store, []byte) (int, error) { return 0, boom }}
+
+			if _, err := Migrate(s, []Legacy{legacyRecords(path)}); err == nil {
+				t.Error("undecodable legacy file imported")
+			}
+			if _, err := Migrate(s, []Legacy{failing}); !errors.Is(err, boom) {
+				t.Errorf("err = %v, want the import error", err)
+			}
+			if !exists(path) || exists(path+MigratedSuffix) {
+				t.Error("legacy file renamed though not imported")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// QuarantineNamespace holds the records that could no longer be decoded,
+// set aside by Namespace.All so the rest of their namespace still loads.
+const QuarantineNamespace = "quarantine"
+
+// Quarantined is a record set aside because it could not be decoded.
+type Quarantined struct {
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Error     string    `json:"error"`
+	At        time.Time `json:"at"`
+	// Value is the record as it was found, for a look by hand
+	Value []byte `json:"value"`
+}
+
+// quarantine moves the value of key in ns, which failed to decode with
+// cause, to QuarantineNamespace. A record quarantined again replaces the
+// earlier copy.
+func quarantine(s Store, ns, key string, value []byte, cause error) error {
+	q := Quarantined{Namespace: ns, Key: key, Error: cause.Error(), At: time.Now(), Value: value}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(QuarantineNamespace, ns+"/"+key, data); err != nil {
+		return err
+	}
+	return s.Delete(ns, key)
+}
+
+// QuarantinedSince returns the records quarantined at or after since.
+func QuarantinedSince(s Store, since time.Time) ([]Quarantined, error) {
+	var records []Quarantined
+	err := s.ForEach(QuarantineNamespace, func(key string, data []byte) error {
+		var q Quarantined
+		if err := json.Unmarshal(data, &q); err != nil {
+			return fmt.Errorf("invalid quarantine record %s: %w", key, err)
+		}
+		if !q.At.Before(since) {
+			records = append(records, q)
+		}
+		return nil
+	})
+	return records, err
+}
@@ -0,0 +1,68 @@
+// Package store keeps the persistent state of the bot: values by key,
+// grouped in namespaces, one or more per feature. Values are bytes to a
+// backend; Namespace puts typed values through a Codec.
+package store
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrNotFound is returned for a key without a value.
+var ErrNotFound = errors.New("key not found")
+
+// Backends of a store.
+const (
+	// BackendJSON keeps every value in a JSON file of its own, in a
+	// directory per namespace. Values are easy to inspect and back up.
+	BackendJSON = "json"
+	// BackendBolt keeps all values in a single bbolt database file.
+	BackendBolt = "bolt"
+)
+
+// Backends lists the available backends.
+var Backends = []string{BackendJSON, BackendBolt}
+
+// Store holds values by namespace and key. A namespace comes into being
+// with its first value. Implementations are safe for concurrent use; each
+// call is atomic on its own.
+type Store interface {
+	// Get returns the value of key in ns, or ErrNotFound.
+	Get(ns, key string) ([]byte, error)
+	// Put sets the value of key in ns.
+	Put(ns, key string, value []byte) error
+	// PutAll sets the values of several keys of ns at once, in a single
+	// transaction where the backend has them.
+	PutAll(ns string, values map[string][]byte) error
+	// Delete removes key from ns; a missing key is not an error.
+	Delete(ns, key string) error
+	// ForEach calls fn with every key of ns and its value, stopping at the
+	// first error, which it returns. value is only valid during the call.
+	ForEach(ns string, fn func(key string, value []byte) error) error
+	// Close releases the store.
+	Close() error
+}
+
+// Open opens the store of backend at path, a directory for BackendJSON and
+// a database file for BackendBolt, creating it if needed.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case BackendJSON:
+		return OpenDir(path)
+	case BackendBolt:
+		return OpenBolt(path)
+	}
+	return nil, fmt.Errorf("unknown store backend %q", backend)
+}
+
+// namespaceRegex matches valid namespace names, which double as directory
+// names of BackendJSON.
+var namespaceRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func checkNamespace(ns string) error {
+	if !namespaceRegex.MatchString(ns) {
+		return fmt.Errorf("invalid store namespace %q", ns)
+	}
+	return nil
+}
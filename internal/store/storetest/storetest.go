@@ -0,0 +1,78 @@
+// Package storetest imports legacy fixture files into a fresh store, for
+// tests of the migration of each feature store.
+package storetest
+
+import (
+	"GURLS-Bot/internal/store"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Migrate copies the legacy file at fixture into a temporary directory and
+// imports it, through the Legacy that legacy returns for the copy, into a
+// new store of every backend. It checks that the copy was renamed and that
+// migrating again imports nothing, and returns the stores by backend with
+// the number of records imported, which must be the same for all.
+func Migrate(t testing.TB, fixture string, legacy func(path string) store.Legacy) (map[string]store.Store, int) {
+	t.Helper()
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("storetest: failed to read fixture: %v", err)
+	}
+
+	stores := make(map[string]store.Store)
+	records := -1
+	for _, backend := range store.Backends {
+		dir := t.TempDir()
+		path := filepath.Join(dir, filepath.Base(fixture))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		db, err := store.Open(backend, filepath.Join(dir, "store"))
+		if err != nil {
+			t.Fatalf("storetest: failed to open %s store: %v", backend, err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		migrated, err := store.Migrate(db, []store.Legacy{legacy(path)})
+		if err != nil {
+			t.Fatalf("storetest: %s: Migrate: %v", backend, err)
+		}
+		if len(migrated) != 1 {
+			t.Fatalf("storetest: %s: migrated %+v, want the fixture", backend, migrated)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("storetest: %s: legacy file left in place", backend)
+		}
+		if _, err := os.Stat(path + store.MigratedSuffix); err != nil {
+			t.Errorf("storetest: %s: legacy file not renamed: %v", backend, err)
+		}
+		if again, err := store.Migrate(db, []store.Legacy{legacy(path)}); err != nil || len(again) != 0 {
+			t.Errorf("storetest: %s: migrating again = %+v, %v; want nothing", backend, again, err)
+		}
+
+		if records >= 0 && migrated[0].Records != records {
+			t.Errorf("storetest: %s: imported %d records, others %d", backend, migrated[0].Records, records)
+		}
+		records = migrated[0].Records
+		stores[backend] = db
+	}
+	return stores, records
+}
+
+// Decode returns the legacy file at fixture decoded as T, what the store
+// is expected to hold after migrating it.
+func Decode[T any](t testing.TB, fixture string) T {
+	t.Helper()
+	var v T
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("storetest: failed to read fixture: %v", err)
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("storetest: failed to decode fixture: %v", err)
+	}
+	return v
+}
@@ -0,0 +1,533 @@
+// Package telegramtest runs a fake Telegram Bot API server for end-to-end
+// tests of the bot. It serves getMe and getUpdates, answers the methods
+// sending and editing messages with plausible results, records every
+// request for assertions and lets tests script the answer of any method.
+//
+// A bot is pointed at the server through its API endpoint, see Endpoint.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token is the bot token the server accepts.
+const Token = "123456:test-token"
+
+// BotID and BotUsername describe the bot as getMe reports it.
+const (
+	BotID       = 123456
+	BotUsername = "test_bot"
+)
+
+// pollWait is how long getUpdates waits for an update before answering
+// with none. It is kept short so bots stop quickly at the end of a test.
+const pollWait = 50 * time.Millisecond
+
+// waitTimeout is how long Wait waits for an expected request.
+const waitTimeout = 5 * time.Second
+
+// Request is a request the bot made.
+type Request struct {
+	Method string
+	Params url.Values
+	// Files holds the uploaded files by field name.
+	Files map[string][]byte
+	// MessageID is the ID of the message the request sent or edited, if
+	// any.
+	MessageID int
+}
+
+// Param returns the value of the parameter name.
+func (r Request) Param(name string) string {
+	return r.Params.Get(name)
+}
+
+// ChatID returns the chat_id parameter.
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Param("chat_id"), 10, 64)
+	return id
+}
+
+// Text returns the text or, failing that, the caption of a sent message.
+func (r Request) Text() string {
+	if text := r.Param("text"); text != "" {
+		return text
+	}
+	return r.Param("caption")
+}
+
+// Buttons returns the inline keyboard buttons of the request, row by row
+// flattened into one list.
+func (r Request) Buttons() []tgbotapi.InlineKeyboardButton {
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(r.Param("reply_markup")), &markup); err != nil {
+		return nil
+	}
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, row := range markup.InlineKeyboard {
+		buttons = append(buttons, row...)
+	}
+	return buttons
+}
+
+// Button returns the callback data of the first button whose text
+// contains text.
+func (r Request) Button(text string) (string, bool) {
+	for _, b := range r.Buttons() {
+		if b.CallbackData != nil && strings.Contains(b.Text, text) {
+			return *b.CallbackData, true
+		}
+	}
+	return "", false
+}
+
+// Handler answers a request with the result to return, or with an error
+// made by APIError.
+type Handler func(r Request) (any, error)
+
+// APIError is an error answer of the Bot API.
+type APIError struct {
+	Code        int
+	Description string
+	// RetryAfter is the flood wait, in seconds, of a 429.
+	RetryAfter int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Description)
+}
+
+// Server is a fake Bot API server.
+type Server struct {
+	t   testing.TB
+	srv *httptest.Server
+
+	mu sync.Mutex
+	// updated is closed and replaced whenever an update is enqueued or a
+	// request recorded
+	updated  chan struct{}
+	updates  []tgbotapi.Update
+	updateID int
+	// messageID numbers the messages sent by the bot and by users
+	messageID int
+	requests  []Request
+	// cursors is, by method, how many of its requests Wait consumed
+	cursors  map[string]int
+	handlers map[string]Handler
+	// stall, while not nil, holds getUpdates polls until it is closed
+	stall chan struct{}
+	// hung counts the polls stall holds
+	hung int
+}
+
+// NewServer starts a fake Bot API server, closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		t:        t,
+		updated:  make(chan struct{}),
+		cursors:  make(map[string]int),
+		handlers: make(map[string]Handler),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// Endpoint returns the API endpoint format to configure the bot with.
+func (s *Server) Endpoint() string {
+	return s.srv.URL + "/bot%s/%s"
+}
+
+// Handle scripts the answer of method, replacing the default one.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Default answers r as the server does unless scripted, for handlers
+// scripting only some calls of their method.
+func (s *Server) Default(r Request) (any, error) {
+	return s.defaultHandler(r)
+}
+
+// Fail makes every call of method fail with code and description.
+func (s *Server) Fail(method string, code int, description string) {
+	s.Handle(method, func(Request) (any, error) {
+		return nil, &APIError{Code: code, Description: description}
+	})
+}
+
+// Stall makes getUpdates hang, as during a Telegram outage: polls answer
+// nothing until they are given up or Resume is called.
+func (s *Server) Stall() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stall == nil {
+		s.stall = make(chan struct{})
+	}
+	s.notify()
+}
+
+// Resume ends a Stall; the polls held answer as usual.
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stall != nil {
+		close(s.stall)
+		s.stall = nil
+	}
+	s.notify()
+}
+
+// Hung returns how many getUpdates polls a Stall holds.
+func (s *Server) Hung() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hung
+}
+
+// notify wakes the goroutines waiting for updates or requests; s.mu must
+// be held.
+func (s *Server) notify() {
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+// Enqueue adds update to those returned by getUpdates, numbering it.
+func (s *Server) Enqueue(update tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateID++
+	update.UpdateID = s.updateID
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// user returns the Telegram user of userID.
+func user(userID int64) *tgbotapi.User {
+	return &tgbotapi.User{ID: userID, FirstName: "User" + strconv.FormatInt(userID, 10), LanguageCode: "en"}
+}
+
+// SendMessage enqueues a message of userID in their private chat with the
+// bot and returns it. Commands get their entity, as Telegram adds it.
+func (s *Server) SendMessage(userID int64, text string) *tgbotapi.Message {
+	s.mu.Lock()
+	s.messageID++
+	msg := &tgbotapi.Message{
+		MessageID: s.messageID,
+		From:      user(userID),
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	s.mu.Unlock()
+	if strings.HasPrefix(text, "/") {
+		length, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(length)}}
+	}
+	s.Enqueue(tgbotapi.Update{Message: msg})
+	return msg
+}
+
+// PressButton enqueues userID pressing the button with data under the bot
+// message sent by req, and returns the callback query ID.
+func (s *Server) PressButton(userID int64, req Request, data string) string {
+	s.mu.Lock()
+	s.messageID++
+	id := "cb" + strconv.Itoa(s.messageID)
+	s.mu.Unlock()
+	msg := &tgbotapi.Message{
+		MessageID: req.MessageID,
+		From:      &tgbotapi.User{ID: BotID, IsBot: true, UserName: BotUsername},
+		Chat:      &tgbotapi.Chat{ID: req.ChatID(), Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      req.Text(),
+	}
+	s.Enqueue(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      id,
+		From:    user(userID),
+		Message: msg,
+		Data:    data,
+	}})
+	return id
+}
+
+// Requests returns the requests made so far of the given methods, or of
+// all methods when none is given, getUpdates left out.
+func (s *Server) Requests(methods ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, r := range s.requests {
+		if len(methods) == 0 || slices.Contains(methods, r.Method) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// Wait waits for the next request of method matching match, nil matching
+// any, and returns it. Requests of method before the match are skipped and
+// not returned by later calls. The test fails after a few seconds without
+// one.
+func (s *Server) Wait(method string, match func(Request) bool) Request {
+	s.t.Helper()
+	deadline := time.NewTimer(waitTimeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		for i := s.cursors[method]; i < len(s.requests); i++ {
+			r := s.requests[i]
+			if r.Method != method {
+				continue
+			}
+			if match == nil || match(r) {
+				s.cursors[method] = i + 1
+				s.mu.Unlock()
+				return r
+			}
+		}
+		updated := s.updated
+		s.mu.Unlock()
+
+		select {
+		case <-updated:
+		case <-deadline.C:
+			s.t.Fatalf("telegramtest: no %s request matched within %s; requests: %s", method, waitTimeout, s.describe())
+			return Request{}
+		}
+	}
+}
+
+// WaitText waits for the next message sent or edited in chatID whose text
+// contains text.
+func (s *Server) WaitText(chatID int64, text string) Request {
+	s.t.Helper()
+	return s.waitAny([]string{"sendMessage", "editMessageText"}, func(r Request) bool {
+		return r.ChatID() == chatID && strings.Contains(r.Text(), text)
+	})
+}
+
+// waitAny is Wait over several methods, in the order requests came.
+func (s *Server) waitAny(methods []string, match func(Request) bool) Request {
+	s.t.Helper()
+	deadline := time.NewTimer(waitTimeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		start := len(s.requests)
+		for _, m := range methods {
+			start = min(start, s.cursors[m])
+		}
+		for i := start; i < len(s.requests); i++ {
+			r := s.requests[i]
+			if !slices.Contains(methods, r.Method) || i < s.cursors[r.Method] {
+				continue
+			}
+			if match(r) {
+				s.cursors[r.Method] = i + 1
+				s.mu.Unlock()
+				return r
+			}
+		}
+		updated := s.updated
+		s.mu.Unlock()
+
+		select {
+		case <-updated:
+		case <-deadline.C:
+			s.t.Fatalf("telegramtest: no %v request matched within %s; requests: %s", methods, waitTimeout, s.describe())
+			return Request{}
+		}
+	}
+}
+
+// describe lists the requests made, for failure messages.
+func (s *Server) describe() string {
+	var b strings.Builder
+	for _, r := range s.Requests() {
+		fmt.Fprintf(&b, "\n  %s chat=%s %q", r.Method, r.Param("chat_id"), r.Text())
+	}
+	return b.String()
+}
+
+// response is the envelope of every Bot API answer.
+type response struct {
+	OK          bool                         `json:"ok"`
+	Result      any                          `json:"result,omitempty"`
+	ErrorCode   int                          `json:"error_code,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Parameters  *tgbotapi.ResponseParameters `json:"parameters,omitempty"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		writeJSON(w, http.StatusUnauthorized, response{ErrorCode: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+	req, err := parseRequest(method, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, response{ErrorCode: http.StatusBadRequest, Description: err.Error()})
+		return
+	}
+
+	if method == "getUpdates" {
+		writeJSON(w, http.StatusOK, response{OK: true, Result: s.getUpdates(r, req)})
+		return
+	}
+
+	s.mu.Lock()
+	h := s.handlers[method]
+	s.mu.Unlock()
+	if h == nil {
+		h = s.defaultHandler
+	}
+	result, err := h(req)
+	if msg, ok := result.(tgbotapi.Message); ok {
+		req.MessageID = msg.MessageID
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.notify()
+	s.mu.Unlock()
+	if err != nil {
+		apiErr, ok := err.(*APIError)
+		if !ok {
+			apiErr = &APIError{Code: http.StatusInternalServerError, Description: err.Error()}
+		}
+		res := response{ErrorCode: apiErr.Code, Description: apiErr.Description}
+		if apiErr.RetryAfter > 0 {
+			res.Parameters = &tgbotapi.ResponseParameters{RetryAfter: apiErr.RetryAfter}
+		}
+		writeJSON(w, apiErr.Code, res)
+		return
+	}
+	writeJSON(w, http.StatusOK, response{OK: true, Result: result})
+}
+
+func writeJSON(w http.ResponseWriter, code int, res response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
+}
+
+// parseRequest reads the parameters and files of a form or multipart
+// request.
+func parseRequest(method string, r *http.Request) (Request, error) {
+	req := Request{Method: method, Params: url.Values{}, Files: make(map[string][]byte)}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return req, err
+		}
+		for name, values := range r.MultipartForm.Value {
+			req.Params[name] = values
+		}
+		for name, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return req, err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return req, err
+			}
+			req.Files[name] = data
+		}
+		return req, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return req, err
+	}
+	req.Params = r.PostForm
+	return req, nil
+}
+
+// getUpdates returns the updates from the requested offset on, waiting a
+// little for one when there are none, and for as long as a Stall lasts.
+func (s *Server) getUpdates(r *http.Request, req Request) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(req.Param("offset"))
+	timer := time.NewTimer(pollWait)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		if stall := s.stall; stall != nil {
+			s.hung++
+			s.mu.Unlock()
+			select {
+			case <-stall:
+			case <-r.Context().Done():
+			}
+			s.mu.Lock()
+			s.hung--
+			s.mu.Unlock()
+			if r.Context().Err() != nil {
+				return []tgbotapi.Update{}
+			}
+			continue
+		}
+		var pending []tgbotapi.Update
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		updated := s.updated
+		s.mu.Unlock()
+		if len(pending) > 0 {
+			return pending
+		}
+		select {
+		case <-updated:
+		case <-timer.C:
+			return []tgbotapi.Update{}
+		case <-r.Context().Done():
+			return []tgbotapi.Update{}
+		}
+	}
+}
+
+// defaultHandler answers getMe with the bot, the methods sending or
+// editing messages with the message, and anything else with true.
+func (s *Server) defaultHandler(r Request) (any, error) {
+	switch r.Method {
+	case "getMe":
+		return tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: BotUsername}, nil
+	case "sendMessage", "sendPhoto", "sendDocument", "editMessageText", "editMessageCaption", "editMessageReplyMarkup", "copyMessage":
+		return s.message(r), nil
+	}
+	return true, nil
+}
+
+// message returns the message r sends or edits.
+func (s *Server) message(r Request) tgbotapi.Message {
+	id, _ := strconv.Atoi(r.Param("message_id"))
+	if id == 0 {
+		s.mu.Lock()
+		s.messageID++
+		id = s.messageID
+		s.mu.Unlock()
+	}
+	return tgbotapi.Message{
+		MessageID: id,
+		From:      &tgbotapi.User{ID: BotID, IsBot: true, UserName: BotUsername},
+		Chat:      &tgbotapi.Chat{ID: r.ChatID(), Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      r.Text(),
+	}
+}
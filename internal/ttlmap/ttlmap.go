@@ -0,0 +1,147 @@
+// Package ttlmap provides a concurrency-safe map whose entries expire.
+package ttlmap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// Map is a map whose entries expire ttl after they were last set. Expired
+// entries are invisible right away and removed by Sweep, which Run calls
+// periodically. The zero value is not usable; use New.
+type Map[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxLen  int
+	entries map[K]entry[V]
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+// New returns an empty map whose entries live for ttl. A positive maxLen
+// caps the entries; setting a new key in a full map evicts the entry closest
+// to expiry.
+func New[K comparable, V any](ttl time.Duration, maxLen int) *Map[K, V] {
+	return &Map[K, V]{ttl: ttl, maxLen: maxLen, entries: make(map[K]entry[V]), now: time.Now}
+}
+
+// Get returns the value of key unless it is missing or expired.
+func (m *Map[K, V]) Get(key K) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key for the map's ttl.
+func (m *Map[K, V]) Set(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(key, value, m.now())
+}
+
+// Update replaces the value of key with what fn returns for the current one,
+// atomically, and restarts its ttl. fn gets ok false and the zero value when
+// the key is missing or expired. When fn returns keep false the key is
+// deleted instead.
+func (m *Map[K, V]) Update(key K, fn func(value V, ok bool) (updated V, keep bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	e, ok := m.entries[key]
+	if ok && !now.Before(e.expires) {
+		ok = false
+		e = entry[V]{}
+	}
+	updated, keep := fn(e.value, ok)
+	if !keep {
+		delete(m.entries, key)
+		return
+	}
+	m.setLocked(key, updated, now)
+}
+
+func (m *Map[K, V]) setLocked(key K, value V, now time.Time) {
+	if _, ok := m.entries[key]; !ok && m.maxLen > 0 && len(m.entries) >= m.maxLen {
+		m.sweepLocked(now)
+		if len(m.entries) >= m.maxLen {
+			m.evictLocked()
+		}
+	}
+	m.entries[key] = entry[V]{value: value, expires: now.Add(m.ttl)}
+}
+
+// evictLocked deletes the entry closest to expiry.
+func (m *Map[K, V]) evictLocked() {
+	var victim K
+	var first time.Time
+	found := false
+	for key, e := range m.entries {
+		if !found || e.expires.Before(first) {
+			victim, first, found = key, e.expires, true
+		}
+	}
+	if found {
+		delete(m.entries, victim)
+	}
+}
+
+// Delete removes key.
+func (m *Map[K, V]) Delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// Len returns the number of entries, including expired ones not swept yet.
+func (m *Map[K, V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Sweep removes the expired entries and returns how many there were.
+func (m *Map[K, V]) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sweepLocked(m.now())
+}
+
+func (m *Map[K, V]) sweepLocked(now time.Time) int {
+	removed := 0
+	for key, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Run sweeps the map every interval until ctx is done. After each sweep,
+// swept, if not nil, is called with the number of entries left.
+func (m *Map[K, V]) Run(ctx context.Context, interval time.Duration, swept func(size int)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep()
+			if swept != nil {
+				swept(m.Len())
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ttlmap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock is a manual clock for a map.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestMap returns a map of ttl and maxLen on a manual clock.
+func newTestMap(ttl time.Duration, maxLen int) (*Map[string, int], *clock) {
+	c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := New[string, int](ttl, maxLen)
+	m.now = c.Now
+	return m, c
+}
+
+func TestExpiry(t *testing.T) {
+	m, c := newTestMap(time.Minute, 0)
+	m.Set("a", 1)
+	c.Advance(30 * time.Second)
+	m.Set("b", 2)
+
+	c.Advance(30*time.Second - time.Nanosecond)
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) just before expiry = %v, %v", v, ok)
+	}
+	c.Advance(time.Nanosecond)
+	if _, ok := m.Get("a"); ok {
+		t.Error("a visible at its expiry")
+	}
+	if v, ok := m.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(b) = %v, %v; want it alive", v, ok)
+	}
+
+	// Expired entries are only removed by Sweep
+	if m.Len() != 2 {
+		t.Errorf("Len() = %d before sweeping, want 2", m.Len())
+	}
+	if removed := m.Sweep(); removed != 1 || m.Len() != 1 {
+		t.Errorf("Sweep() = %d leaving %d, want 1 leaving 1", removed, m.Len())
+	}
+}
+
+func TestSetRestartsTTL(t *testing.T) {
+	m, c := newTestMap(time.Minute, 0)
+	m.Set("a", 1)
+	c.Advance(45 * time.Second)
+	m.Set("a", 2)
+	c.Advance(45 * time.Second)
+	if v, ok := m.Get("a"); !ok || v != 2 {
+		t.Errorf("Get(a) = %v, %v; want the ttl restarted by Set", v, ok)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	m, c := newTestMap(time.Minute, 0)
+	inc := func(v int, ok bool) (int, bool) { return v + 1, true }
+
+	m.Update("a", inc)
+	m.Update("a", inc)
+	if v, _ := m.Get("a"); v != 2 {
+		t.Errorf("Get(a) = %d after two increments, want 2", v)
+	}
+
+	// An expired value is seen as missing
+	c.Advance(time.Minute)
+	m.Update("a", func(v int, ok bool) (int, bool) {
+		if ok || v != 0 {
+			t.Errorf("Update saw expired value %d, %v", v, ok)
+		}
+		return 7, true
+	})
+	c.Advance(59 * time.Second)
+	if v, ok := m.Get("a"); !ok || v != 7 {
+		t.Errorf("Get(a) = %v, %v; want the ttl restarted by Update", v, ok)
+	}
+
+	m.Update("a", func(int, bool) (int, bool) { return 0, false })
+	if _, ok := m.Get("a"); ok || m.Len() != 0 {
+		t.Error("key kept after Update returned keep false")
+	}
+}
+
+func TestMaxLenEvictsClosestToExpiry(t *testing.T) {
+	m, c := newTestMap(time.Minute, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		m.Set(key, 0)
+		c.Advance(time.Second)
+	}
+	// Refreshing a makes b the closest to expiry
+	m.Set("a", 1)
+	m.Set("d", 0)
+	if _, ok := m.Get("b"); ok {
+		t.Error("b kept, want it evicted as the closest to expiry")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := m.Get(key); !ok {
+			t.Errorf("%s evicted", key)
+		}
+	}
+
+	// Updating a key of a full map doesn't evict
+	m.Set("c", 1)
+	if m.Len() != 3 {
+		t.Errorf("Len() = %d after updating a key, want 3", m.Len())
+	}
+
+	// A full map sweeps the expired entries rather than evicting one
+	c.Advance(time.Minute)
+	m.Set("e", 0)
+	if m.Len() != 1 {
+		t.Errorf("Len() = %d, want the expired entries swept", m.Len())
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	m := New[int, int](time.Minute, 0)
+	const workers, rounds = 8, 1000
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				m.Update(i%10, func(v int, _ bool) (int, bool) { return v + 1, true })
+				m.Set(100+w, i)
+				m.Get(i % 10)
+				m.Sweep()
+				m.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for key := range 10 {
+		v, _ := m.Get(key)
+		total += v
+	}
+	if total != workers*rounds {
+		t.Errorf("%d increments counted, want %d", total, workers*rounds)
+	}
+	for w := range workers {
+		if v, ok := m.Get(100 + w); !ok || v != rounds-1 {
+			t.Errorf("Get(%d) = %v, %v; want the last value set", 100+w, v, ok)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	m := New[string, int](time.Millisecond, 0)
+	m.Set("a", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	sizes := make(chan int, 1)
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx, time.Millisecond, func(size int) {
+			select {
+			case sizes <- size:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	for size := range sizes {
+		if size == 0 {
+			break
+		}
+	}
+	cancel()
+	<-done
+	if m.Len() != 0 {
+		t.Errorf("Len() = %d after sweeps, want 0", m.Len())
+	}
+}
@@ -0,0 +1,144 @@
+package urlcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const safeBrowsingEndpoint = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// Verdict is the result of checking a URL.
+type Verdict struct {
+	Unsafe bool
+	// Threat describes the threat type for unsafe URLs, e.g. MALWARE.
+	Threat string
+}
+
+// Checker decides whether a URL is safe to shorten.
+type Checker interface {
+	Check(ctx context.Context, url string) (Verdict, error)
+}
+
+// SafeBrowsing checks URLs with the Google Safe Browsing Lookup API (v4).
+type SafeBrowsing struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+func NewSafeBrowsing(apiKey string) *SafeBrowsing {
+	return &SafeBrowsing{
+		apiKey:   apiKey,
+		endpoint: safeBrowsingEndpoint,
+		client:   &http.Client{},
+	}
+}
+
+type threatEntry struct {
+	URL string `json:"url"`
+}
+
+type findRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string      `json:"threatTypes"`
+		PlatformTypes    []string      `json:"platformTypes"`
+		ThreatEntryTypes []string      `json:"threatEntryTypes"`
+		ThreatEntries    []threatEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type findResponse struct {
+	Matches []struct {
+		ThreatType string `json:"threatType"`
+	} `json:"matches"`
+}
+
+func (s *SafeBrowsing) Check(ctx context.Context, url string) (Verdict, error) {
+	var body findRequest
+	body.Client.ClientID = "gurls-bot"
+	body.Client.ClientVersion = "1.0"
+	body.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	body.ThreatInfo.ThreatEntries = []threatEntry{{URL: url}}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"?key="+s.apiKey, bytes.NewReader(data))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("safe browsing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("safe browsing returned status %d", resp.StatusCode)
+	}
+
+	var res findResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode safe browsing response: %w", err)
+	}
+	if len(res.Matches) == 0 {
+		return Verdict{}, nil
+	}
+	return Verdict{Unsafe: true, Threat: res.Matches[0].ThreatType}, nil
+}
+
+type cachedVerdict struct {
+	verdict Verdict
+	expires time.Time
+}
+
+// Cached remembers verdicts of another checker for a short time. Errors are
+// not cached.
+type Cached struct {
+	next    Checker
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedVerdict
+}
+
+func NewCached(next Checker, ttl time.Duration) *Cached {
+	return &Cached{next: next, ttl: ttl, entries: make(map[string]cachedVerdict)}
+}
+
+func (c *Cached) Check(ctx context.Context, url string) (Verdict, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if entry, ok := c.entries[url]; ok && now.Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.verdict, nil
+	}
+	c.mu.Unlock()
+
+	verdict, err := c.next.Check(ctx, url)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[url] = cachedVerdict{verdict: verdict, expires: now.Add(c.ttl)}
+	return verdict, nil
+}
@@ -0,0 +1,192 @@
+// Package usage counts how often each command and button is used, per day
+// and in aggregate only: no user IDs, chats or arguments are recorded.
+package usage
+
+import (
+	"GURLS-Bot/internal/store"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// dayLayout names the days of the rollups.
+const dayLayout = "2006-01-02"
+
+// Count is how often a route was used over a span of days.
+type Count struct {
+	Route string
+	Count int64
+}
+
+type file struct {
+	// Days maps a day in the store's time zone to the uses of every route.
+	Days map[string]map[string]int64 `json:"days"`
+	// SummaryAt is when the last summary was sent.
+	SummaryAt time.Time `json:"summary_at,omitzero"`
+}
+
+// namespace and key hold the rollups in the store, in a single record as
+// they are always written out whole.
+const (
+	namespace = "usage"
+	key       = "rollups"
+)
+
+// Store keeps the daily rollups in memory and writes them to the store on
+// Flush. Days start at midnight in the store's time zone.
+type Store struct {
+	ns  store.Namespace[file]
+	loc *time.Location
+
+	mu    sync.Mutex
+	data  file
+	dirty bool
+}
+
+// Open loads the rollups kept in db.
+func Open(db store.Store, loc *time.Location) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[file]{}), loc: loc}
+	data, _, err := s.ns.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage rollups: %w", err)
+	}
+	s.data = data
+	if s.data.Days == nil {
+		s.data.Days = make(map[string]map[string]int64)
+	}
+	return s, nil
+}
+
+// Legacy is the usage rollups file of earlier versions, the rollups as they
+// are kept now.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "usage rollups", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var f file
+		if err := json.Unmarshal(data, &f); err != nil {
+			return 0, err
+		}
+		return len(f.Days), store.NewNamespace(db, namespace, store.JSON[file]{}).Put(key, f)
+	}}
+}
+
+// day names the day at falls on.
+func (s *Store) day(at time.Time) string {
+	return at.In(s.loc).Format(dayLayout)
+}
+
+// Add counts one use of route at the given time.
+func (s *Store) Add(route string, at time.Time) {
+	day := s.day(at)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.data.Days[day]
+	if !ok {
+		counts = make(map[string]int64)
+		s.data.Days[day] = counts
+	}
+	counts[route]++
+	s.dirty = true
+}
+
+// Totals adds up the uses of every route over the days from the one from
+// falls on up to, but not including, the one to falls on, most used first.
+func (s *Store) Totals(from, to time.Time) []Count {
+	first, last := s.day(from), s.day(to)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totals := make(map[string]int64)
+	for day, counts := range s.data.Days {
+		if day < first || day >= last {
+			continue
+		}
+		for route, n := range counts {
+			totals[route] += n
+		}
+	}
+	counts := make([]Count, 0, len(totals))
+	for route, n := range totals {
+		counts = append(counts, Count{Route: route, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b Count) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Route, b.Route))
+	})
+	return counts
+}
+
+// Prune drops the days more than days before the one now falls on.
+func (s *Store) Prune(now time.Time, days int) {
+	now = now.In(s.loc)
+	oldest := time.Date(now.Year(), now.Month(), now.Day()-days, 0, 0, 0, 0, s.loc).Format(dayLayout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for day := range s.data.Days {
+		if day < oldest {
+			delete(s.data.Days, day)
+			s.dirty = true
+		}
+	}
+}
+
+// SummaryAt returns when the last summary was sent, or the zero time.
+func (s *Store) SummaryAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.SummaryAt
+}
+
+// SetSummaryAt records when the last summary was sent and saves the store.
+func (s *Store) SetSummaryAt(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.SummaryAt = at
+	return s.saveLocked()
+}
+
+// StartOfDay returns the midnight starting the day at falls on.
+func (s *Store) StartOfDay(at time.Time) time.Time {
+	at = at.In(s.loc)
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, s.loc)
+}
+
+// Flush writes the rollups to the store if they changed since the last write.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// Run flushes the store every interval and once more when ctx is done,
+// pruning the days older than retention days first.
+func (s *Store) Run(ctx context.Context, interval time.Duration, retention int, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		case now := <-ticker.C:
+			s.Prune(now, retention)
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (s *Store) saveLocked() error {
+	if err := s.ns.Put(key, s.data); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
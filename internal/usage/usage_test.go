@@ -0,0 +1,224 @@
+package usage
+
+import (
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/store/storetest"
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// openTestStore returns rollups counted in loc in a new store, and the
+// store.
+func openTestStore(t *testing.T, loc *time.Location) (*Store, store.Store) {
+	t.Helper()
+	db, err := store.Open(store.BackendJSON, filepath.Join(t.TempDir(), "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := Open(db, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, db
+}
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[file](t, "testdata/usage.json")
+
+	stores, records := storetest.Migrate(t, "testdata/usage.json", Legacy)
+	if records != len(want.Days) {
+		t.Errorf("imported %d records, want %d", records, len(want.Days))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.data, want) {
+				t.Errorf("data =\n%+v\nwant\n%+v", s.data, want)
+			}
+			if !s.SummaryAt().Equal(want.SummaryAt) {
+				t.Errorf("SummaryAt() = %v, want %v", s.SummaryAt(), want.SummaryAt)
+			}
+			from := time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC)
+			got := s.Totals(from, from.AddDate(0, 0, 3))
+			totals := []Count{{"/shorten", 16}, {"cb:links:page", 7}, {"/stats", 5}, {"/help", 1}}
+			if !reflect.DeepEqual(got, totals) {
+				t.Errorf("Totals() = %v, want %v", got, totals)
+			}
+		})
+	}
+}
+
+func TestDayBoundaries(t *testing.T) {
+	// Days start at midnight in the store's zone, 21:00 UTC the day before
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	s, _ := openTestStore(t, loc)
+	lastMinute := time.Date(2026, 3, 1, 20, 59, 59, 0, time.UTC)
+	midnight := time.Date(2026, 3, 1, 21, 0, 0, 0, time.UTC)
+	s.Add("/help", lastMinute)
+	s.Add("/help", midnight)
+	s.Add("/stats", midnight.Add(23*time.Hour+59*time.Minute))
+	s.Add("/stats", midnight.Add(24*time.Hour))
+
+	day := s.StartOfDay(midnight)
+	if !day.Equal(midnight) || day.Location() != loc {
+		t.Fatalf("StartOfDay(%v) = %v, want local midnight", midnight, day)
+	}
+	if got := s.StartOfDay(lastMinute); !got.Equal(midnight.AddDate(0, 0, -1)) {
+		t.Errorf("StartOfDay(%v) = %v, want the day before", lastMinute, got)
+	}
+	for _, tt := range []struct {
+		name     string
+		from, to time.Time
+		want     []Count
+	}{
+		{name: "day before", from: day.AddDate(0, 0, -1), to: day, want: []Count{{"/help", 1}}},
+		{name: "day", from: day, to: day.AddDate(0, 0, 1), want: []Count{{"/help", 1}, {"/stats", 1}}},
+		{name: "day after", from: day.AddDate(0, 0, 1), to: day.AddDate(0, 0, 2), want: []Count{{"/stats", 1}}},
+		{name: "all days", from: day.AddDate(0, 0, -1), to: day.AddDate(0, 0, 2), want: []Count{{"/help", 2}, {"/stats", 2}}},
+		// Bounds fall on their days wherever in the day they are
+		{name: "mid-day bounds", from: midnight.Add(-time.Hour), to: midnight.Add(time.Hour), want: []Count{{"/help", 1}}},
+		{name: "empty span", from: day, to: day, want: []Count{}},
+	} {
+		if got := s.Totals(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Totals() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDaylightSavingDays(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := openTestStore(t, loc)
+	// Clocks go forward on 2026-03-29, a day of 23 hours
+	day := time.Date(2026, 3, 29, 0, 0, 0, 0, loc)
+	s.Add("/help", day.Add(30*time.Minute))
+	s.Add("/help", day.Add(22*time.Hour+30*time.Minute))
+	s.Add("/stats", day.Add(23*time.Hour))
+
+	next := s.StartOfDay(day.Add(23 * time.Hour))
+	if want := time.Date(2026, 3, 30, 0, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("StartOfDay after 23 hours = %v, want %v", next, want)
+	}
+	if got := s.Totals(day, next); !reflect.DeepEqual(got, []Count{{"/help", 2}}) {
+		t.Errorf("Totals() of the short day = %v, want both /help", got)
+	}
+}
+
+func TestTotalsOrder(t *testing.T) {
+	s, _ := openTestStore(t, time.UTC)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for route, n := range map[string]int{"/stats": 2, "/help": 2, "callback:page": 3, "/shorten": 1} {
+		for range n {
+			s.Add(route, now)
+		}
+	}
+	got := s.Totals(now, now.AddDate(0, 0, 1))
+	want := []Count{{"callback:page", 3}, {"/help", 2}, {"/stats", 2}, {"/shorten", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Totals() = %v, want most used first, then by name", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	s, db := openTestStore(t, loc)
+	now := time.Date(2026, 3, 10, 1, 0, 0, 0, loc)
+	for days := range 5 {
+		s.Add("/help", now.AddDate(0, 0, -days))
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Today and the 2 days before are kept
+	s.Prune(now, 2)
+	start := s.StartOfDay(now)
+	if got := s.Totals(start.AddDate(0, 0, -4), start.AddDate(0, 0, 1)); !reflect.DeepEqual(got, []Count{{"/help", 3}}) {
+		t.Errorf("Totals() after pruning = %v, want 3 days left", got)
+	}
+	if got := len(s.data.Days); got != 3 {
+		t.Errorf("%d days kept, want 3", got)
+	}
+
+	// Pruning saves on the next flush
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(db, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reopened.data.Days); got != 3 {
+		t.Errorf("%d days stored after pruning, want 3", got)
+	}
+
+	// Nothing left to prune changes nothing
+	s.Prune(now, 2)
+	if s.dirty {
+		t.Error("pruning nothing marked the rollups changed")
+	}
+}
+
+func TestFlushAndReopen(t *testing.T) {
+	s, db := openTestStore(t, time.UTC)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.Add("/help", now)
+	s.Add("/help", now)
+
+	reopened, err := Open(db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Totals(now, now.AddDate(0, 0, 1)); len(got) != 0 {
+		t.Errorf("counts stored before Flush: %v", got)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetSummaryAt(now); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err = Open(db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Totals(now, now.AddDate(0, 0, 1)); !reflect.DeepEqual(got, []Count{{"/help", 2}}) {
+		t.Errorf("Totals() after reopening = %v", got)
+	}
+	if !reopened.SummaryAt().Equal(now) {
+		t.Errorf("SummaryAt() after reopening = %v, want %v", reopened.SummaryAt(), now)
+	}
+}
+
+func TestRunFlushesOnStop(t *testing.T) {
+	s, db := openTestStore(t, time.UTC)
+	now := time.Now()
+	s.Add("/help", now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx, time.Hour, 90, func(err error) { t.Error(err) })
+	}()
+	cancel()
+	<-done
+
+	reopened, err := Open(db, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Totals(now, now.AddDate(0, 0, 1)); !reflect.DeepEqual(got, []Count{{"/help", 1}}) {
+		t.Errorf("Totals() after Run stopped = %v, want the count flushed", got)
+	}
+}
@@ -0,0 +1,315 @@
+package users
+
+import (
+	"GURLS-Bot/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFormerNames is how many previous names are kept per user.
+const maxFormerNames = 5
+
+// Name is how a user is called on Telegram.
+type Name struct {
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+// FormerName is a name a user went by until they changed it.
+type FormerName struct {
+	Name
+	Until time.Time `json:"until"`
+}
+
+// User is a Telegram user who has interacted with the bot.
+type User struct {
+	ID int64 `json:"id"`
+	Name
+	// FormerNames are the previous names of the user, oldest first.
+	FormerNames []FormerName `json:"former_names,omitempty"`
+	FirstSeen   time.Time    `json:"first_seen"`
+	LastSeen    time.Time    `json:"last_seen"`
+	// LanguageCode is the IETF language tag of the user's Telegram client,
+	// as of their last update.
+	LanguageCode string `json:"language_code,omitempty"`
+	// BlockedAt is when a message to the user first failed because they
+	// blocked the bot or deleted their account; zero while reachable.
+	BlockedAt time.Time `json:"blocked_at,omitzero"`
+	// BasicKeyboardsAt is when a keyboard with advanced buttons first
+	// failed to show on the user's client; from then on they get plain
+	// buttons only. Zero while advanced buttons work.
+	BasicKeyboardsAt time.Time `json:"basic_keyboards_at,omitzero"`
+}
+
+// FormerUsername returns the username the user went by before their current
+// one, if they changed it after since.
+func (u User) FormerUsername(since time.Time) (string, bool) {
+	for i := len(u.FormerNames) - 1; i >= 0; i-- {
+		former := u.FormerNames[i]
+		if former.Until.Before(since) {
+			break
+		}
+		if former.Username != "" && former.Username != u.Username {
+			return former.Username, true
+		}
+	}
+	return "", false
+}
+
+// namespace holds the registry in the store, by user ID.
+const namespace = "users"
+
+// Store is a registry of users, kept in the store one record per user.
+// Touches are kept in memory and written out in batches by Run; Forget is
+// written immediately.
+type Store struct {
+	mu    sync.Mutex
+	ns    store.Namespace[User]
+	users map[int64]User
+	// dirty holds the users changed since the last flush
+	dirty map[int64]bool
+}
+
+// Open loads the registry kept in db.
+func Open(db store.Store) (*Store, error) {
+	s := &Store{ns: store.NewNamespace(db, namespace, store.JSON[User]{}), users: make(map[int64]User), dirty: make(map[int64]bool)}
+	all, err := s.ns.All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user registry: %w", err)
+	}
+	for key, u := range all {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user registry key %q", key)
+		}
+		s.users[id] = u
+	}
+	return s, nil
+}
+
+// Legacy is the registry file of earlier versions, an object of users by
+// ID. A file that can't be parsed imports no one and is moved aside all
+// the same, the registry starting empty as it always did.
+func Legacy(path string) store.Legacy {
+	return store.Legacy{Name: "user registry", Path: path, Import: func(db store.Store, data []byte) (int, error) {
+		var users map[int64]User
+		if err := json.Unmarshal(data, &users); err != nil {
+			return 0, nil
+		}
+		records := make(map[string]User, len(users))
+		for id, u := range users {
+			records[strconv.FormatInt(id, 10)] = u
+		}
+		return len(records), store.NewNamespace(db, namespace, store.JSON[User]{}).PutAll(records)
+	}}
+}
+
+// Touch records activity of a user at time at under name, with the language
+// of their client, creating the record on first sight. A changed name is
+// recorded with the previous one kept as a former name; an empty language
+// keeps the one known. It returns the record as it was before the call and
+// whether one existed.
+func (s *Store) Touch(id int64, name Name, language string, at time.Time) (prev User, existed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed = s.users[id]
+	u := prev
+	if !existed {
+		u = User{ID: id, Name: name, FirstSeen: at}
+	}
+	changed := !existed
+	if u.Name != name {
+		// Copied, as records handed out share the slice
+		u.FormerNames = append(slices.Clone(u.FormerNames), FormerName{Name: u.Name, Until: at})
+		if over := len(u.FormerNames) - maxFormerNames; over > 0 {
+			u.FormerNames = u.FormerNames[over:]
+		}
+		u.Name = name
+		changed = true
+	}
+	if language != "" && language != u.LanguageCode {
+		u.LanguageCode = language
+		changed = true
+	}
+	if at.After(u.LastSeen) {
+		u.LastSeen = at
+		changed = true
+	}
+	if changed {
+		s.users[id] = u
+		s.dirty[id] = true
+	}
+	return prev, existed
+}
+
+// MarkBlocked records that messages to a known user fail since at because
+// they blocked the bot. It reports whether the user was reachable before.
+func (s *Store) MarkBlocked(id int64, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || !u.BlockedAt.IsZero() {
+		return false
+	}
+	u.BlockedAt = at
+	s.users[id] = u
+	s.dirty[id] = true
+	return true
+}
+
+// Unblock marks a user reachable again, as they talked to the bot in private.
+func (s *Store) Unblock(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.BlockedAt.IsZero() {
+		return
+	}
+	u.BlockedAt = time.Time{}
+	s.users[id] = u
+	s.dirty[id] = true
+}
+
+// Reachable reports whether messages can be sent to a user; unknown users
+// are assumed reachable.
+func (s *Store) Reachable(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[id].BlockedAt.IsZero()
+}
+
+// MarkBasicKeyboards records that advanced buttons fail on the client of a
+// known user, reporting whether that is news.
+func (s *Store) MarkBasicKeyboards(id int64, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || !u.BasicKeyboardsAt.IsZero() {
+		return false
+	}
+	u.BasicKeyboardsAt = at
+	s.users[id] = u
+	s.dirty[id] = true
+	return true
+}
+
+// BasicKeyboards reports whether the user should only get plain buttons.
+func (s *Store) BasicKeyboards(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.users[id].BasicKeyboardsAt.IsZero()
+}
+
+// Get returns the record of a user.
+func (s *Store) Get(id int64) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	return u, ok
+}
+
+// FindByUsername returns the user with username, matched without the
+// leading @ and case-insensitively, as Telegram usernames are.
+func (s *Store) FindByUsername(username string) (User, bool) {
+	username = strings.TrimPrefix(username, "@")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Username != "" && strings.EqualFold(u.Username, username) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// List returns all users ordered by ID.
+func (s *Store) List() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	slices.SortFunc(list, func(a, b User) int {
+		switch {
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		}
+		return 0
+	})
+	return list
+}
+
+// Count returns the number of known users.
+func (s *Store) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+// Forget deletes the record of a user and persists the change right away.
+func (s *Store) Forget(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, existed := s.users[id]; !existed {
+		return nil
+	}
+	if err := s.ns.Delete(strconv.FormatInt(id, 10)); err != nil {
+		return err
+	}
+	delete(s.users, id)
+	delete(s.dirty, id)
+	return nil
+}
+
+// Flush writes pending changes to the store.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.dirty) == 0 {
+		return nil
+	}
+	records := make(map[string]User, len(s.dirty))
+	for id := range s.dirty {
+		records[strconv.FormatInt(id, 10)] = s.users[id]
+	}
+	if err := s.ns.PutAll(records); err != nil {
+		return err
+	}
+	clear(s.dirty)
+	return nil
+}
+
+// Run flushes pending changes every interval until ctx is done, then flushes
+// once more. Errors are passed to onError.
+func (s *Store) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
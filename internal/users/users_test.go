@@ -0,0 +1,128 @@
+package users
+
+import (
+	"GURLS-Bot/internal/store"
+	"GURLS-Bot/internal/store/storetest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOpenSkipsCorruptRecord(t *testing.T) {
+	db, err := store.OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := User{ID: 1, Name: Name{Username: "alice"}, FirstSeen: time.Unix(100, 0).UTC()}
+	if err := store.NewNamespace(db, namespace, store.JSON[User]{}).Put("1", good); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(namespace, "2", []byte(`{"id":"two"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(db)
+	if err != nil {
+		t.Fatalf("Open with a corrupt record: %v", err)
+	}
+	if u, ok := s.Get(1); !ok || u.Username != "alice" {
+		t.Errorf("Get(1) = %+v, %v; want alice", u, ok)
+	}
+	if _, ok := s.Get(2); ok {
+		t.Error("corrupt record 2 was loaded")
+	}
+}
+
+func TestLegacyMigration(t *testing.T) {
+	want := storetest.Decode[map[int64]User](t, "testdata/users.json")
+	stores, records := storetest.Migrate(t, "testdata/users.json", Legacy)
+	if records != len(want) {
+		t.Errorf("imported %d records, want %d", records, len(want))
+	}
+	for backend, db := range stores {
+		t.Run(backend, func(t *testing.T) {
+			s, err := Open(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Count() != len(want) {
+				t.Errorf("Count() = %d, want %d", s.Count(), len(want))
+			}
+			for id, u := range want {
+				if got, ok := s.Get(id); !ok || !reflect.DeepEqual(got, u) {
+					t.Errorf("Get(%d) =\n%+v, %v\nwant\n%+v", id, got, ok, u)
+				}
+			}
+			if u, ok := s.FindByUsername("@Alice"); !ok || u.ID != 1001 {
+				t.Errorf("FindByUsername(@Alice) = %+v, %v", u, ok)
+			}
+			if s.Reachable(2002) || !s.Reachable(1001) {
+				t.Error("blocked state not migrated")
+			}
+			if !s.BasicKeyboards(3003) {
+				t.Error("basic keyboards state not migrated")
+			}
+		})
+	}
+}
+
+// TestLegacyMigrationCorrupt migrates a registry earlier versions could not
+// read either: it is set aside, renamed, and the registry starts empty.
+func TestLegacyMigrationCorrupt(t *testing.T) {
+	stores, records := storetest.Migrate(t, "testdata/corrupt.json", Legacy)
+	if records != 0 {
+		t.Errorf("imported %d records from a corrupt file", records)
+	}
+	for backend, db := range stores {
+		s, err := Open(db)
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		if s.Count() != 0 {
+			t.Errorf("%s: Count() = %d, want 0", backend, s.Count())
+		}
+	}
+}
+
+func TestMarkBasicKeyboards(t *testing.T) {
+	db, err := store.OpenDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(100, 0).UTC()
+
+	// Unknown users aren't recorded
+	if s.MarkBasicKeyboards(1, at) || s.BasicKeyboards(1) {
+		t.Error("basic keyboards marked for an unknown user")
+	}
+
+	s.Touch(1, Name{Username: "alice"}, "en", at)
+	if s.BasicKeyboards(1) {
+		t.Error("new user gets basic keyboards")
+	}
+	if !s.MarkBasicKeyboards(1, at) {
+		t.Error("first MarkBasicKeyboards not reported as news")
+	}
+	if s.MarkBasicKeyboards(1, at.Add(time.Hour)) {
+		t.Error("second MarkBasicKeyboards reported as news")
+	}
+	if !s.BasicKeyboards(1) {
+		t.Error("BasicKeyboards() = false after marking")
+	}
+
+	// The first refusal is kept across restarts
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := reopened.Get(1); !u.BasicKeyboardsAt.Equal(at) {
+		t.Errorf("BasicKeyboardsAt after reopening = %v, want %v", u.BasicKeyboardsAt, at)
+	}
+}
@@ -0,0 +1,23 @@
+package version
+
+import "runtime/debug"
+
+// Version is the bot version. Release builds set it with
+// -ldflags "-X GURLS-Bot/internal/version.Version=v1.2.3".
+var Version = ""
+
+// String returns Version, falling back to the VCS revision embedded by the
+// Go toolchain, or "dev".
+func String() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+				return s.Value[:12]
+			}
+		}
+	}
+	return "dev"
+}